@@ -0,0 +1,147 @@
+//go:build bench
+
+// Package bench provides reusable benchmarks for repository operations against
+// a real MongoDB deployment.
+//
+// The package is build-tagged so it never affects regular builds or test runs:
+//
+//	go test -tags bench -bench . -benchmem ./bench/
+//
+// Each benchmark reports allocations and an ops/s metric in addition to ns/op,
+// so regressions in the repository layer (extra allocations, slower filter or
+// update translation) show up when comparing runs with benchstat.
+//
+// The helpers accept any repository.Repository, so applications can run the
+// same benchmarks against their own document types:
+//
+//	func BenchmarkUserInsert(b *testing.B) {
+//	    repo := mongorepo.New[User](coll)
+//	    bench.InsertOne(b, repo, func(i int) *User { return &User{Name: fmt.Sprint(i)} })
+//	}
+package bench
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/repository"
+)
+
+// reportOpsPerSec adds an ops/s metric computed from the benchmark's elapsed time.
+func reportOpsPerSec(b *testing.B, opsPerIteration int) {
+	b.Helper()
+	if elapsed := b.Elapsed().Seconds(); elapsed > 0 {
+		b.ReportMetric(float64(b.N*opsPerIteration)/elapsed, "ops/s")
+	}
+}
+
+// InsertOne benchmarks single-document inserts. newDoc builds the i-th document.
+func InsertOne[T any](b *testing.B, repo repository.Repository[T], newDoc func(i int) *T) {
+	b.Helper()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := repo.InsertOne(ctx, newDoc(i)); err != nil {
+			b.Fatalf("InsertOne: %v", err)
+		}
+	}
+
+	b.StopTimer()
+	reportOpsPerSec(b, 1)
+}
+
+// InsertMany benchmarks batched inserts of batchSize documents per iteration.
+// The reported ops/s counts documents, not batches.
+func InsertMany[T any](b *testing.B, repo repository.Repository[T], batchSize int, newDoc func(i int) *T) {
+	b.Helper()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		docs := make([]*T, batchSize)
+		for j := range docs {
+			docs[j] = newDoc(i*batchSize + j)
+		}
+		b.StartTimer()
+
+		if _, err := repo.InsertMany(ctx, docs); err != nil {
+			b.Fatalf("InsertMany: %v", err)
+		}
+	}
+
+	b.StopTimer()
+	reportOpsPerSec(b, batchSize)
+}
+
+// FindOne benchmarks single-document lookups with the given filter.
+// The collection should be seeded so the filter matches a document.
+func FindOne[T any](b *testing.B, repo repository.Repository[T], filter any) {
+	b.Helper()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FindOne(ctx, filter); err != nil {
+			b.Fatalf("FindOne: %v", err)
+		}
+	}
+
+	b.StopTimer()
+	reportOpsPerSec(b, 1)
+}
+
+// Find benchmarks multi-document queries with the given filter and options.
+func Find[T any](b *testing.B, repo repository.Repository[T], filter any, opts ...repository.FindOption) {
+	b.Helper()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Find(ctx, filter, opts...); err != nil {
+			b.Fatalf("Find: %v", err)
+		}
+	}
+
+	b.StopTimer()
+	reportOpsPerSec(b, 1)
+}
+
+// UpdateOne benchmarks single-document updates with the given filter and update.
+func UpdateOne[T any](b *testing.B, repo repository.Repository[T], filter, update any) {
+	b.Helper()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, _, err := repo.UpdateOne(ctx, filter, update); err != nil {
+			b.Fatalf("UpdateOne: %v", err)
+		}
+	}
+
+	b.StopTimer()
+	reportOpsPerSec(b, 1)
+}
+
+// Aggregate benchmarks an aggregation pipeline decoded into raw documents.
+func Aggregate[T any](b *testing.B, repo repository.Repository[T], pipeline any) {
+	b.Helper()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.AggregateRaw(ctx, pipeline); err != nil {
+			b.Fatalf("AggregateRaw: %v", err)
+		}
+	}
+
+	b.StopTimer()
+	reportOpsPerSec(b, 1)
+}
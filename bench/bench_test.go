@@ -0,0 +1,127 @@
+//go:build bench
+
+package bench_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/bench"
+	"github.com/dElCIoGio/mongox/document"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+type Event struct {
+	document.Base `bson:",inline"`
+
+	Kind    string `bson:"kind"`
+	Account string `bson:"account"`
+	Amount  int    `bson:"amount"`
+}
+
+var client *mongo.Client
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "start mongodb container: %v\n", err)
+		os.Exit(1)
+	}
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "get mongodb uri: %v\n", err)
+		os.Exit(1)
+	}
+
+	client, err = mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "connect mongo: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+
+	_ = client.Disconnect(ctx)
+	_ = container.Terminate(ctx)
+	os.Exit(code)
+}
+
+func newEvent(i int) *Event {
+	return &Event{
+		Kind:    []string{"deposit", "withdrawal", "transfer"}[i%3],
+		Account: fmt.Sprintf("acct-%d", i%100),
+		Amount:  i % 1000,
+	}
+}
+
+// seededRepo returns a repository over a fresh collection containing n events.
+func seededRepo(b *testing.B, name string, n int) *mongorepo.MongoRepository[Event] {
+	b.Helper()
+	ctx := context.Background()
+
+	coll := client.Database("bench").Collection(name)
+	if err := coll.Drop(ctx); err != nil {
+		b.Fatalf("drop collection: %v", err)
+	}
+	repo := mongorepo.New[Event](coll)
+
+	docs := make([]*Event, n)
+	for i := range docs {
+		docs[i] = newEvent(i)
+	}
+	if n > 0 {
+		if _, err := repo.InsertMany(ctx, docs); err != nil {
+			b.Fatalf("seed: %v", err)
+		}
+	}
+	return repo
+}
+
+func BenchmarkInsertOne(b *testing.B) {
+	repo := seededRepo(b, "insert_one", 0)
+	bench.InsertOne(b, repo, newEvent)
+}
+
+func BenchmarkInsertMany(b *testing.B) {
+	repo := seededRepo(b, "insert_many", 0)
+	bench.InsertMany(b, repo, 100, newEvent)
+}
+
+func BenchmarkFindOne(b *testing.B) {
+	repo := seededRepo(b, "find_one", 1000)
+	bench.FindOne(b, repo, spec.Eq("account", "acct-42"))
+}
+
+func BenchmarkFind(b *testing.B) {
+	repo := seededRepo(b, "find", 1000)
+	bench.Find(b, repo, spec.And(
+		spec.Eq("kind", "deposit"),
+		spec.Gte("amount", 500),
+	))
+}
+
+func BenchmarkUpdateOne(b *testing.B) {
+	repo := seededRepo(b, "update_one", 1000)
+	bench.UpdateOne(b, repo, spec.Eq("account", "acct-7"), spec.Inc("amount", 1))
+}
+
+func BenchmarkAggregate(b *testing.B) {
+	repo := seededRepo(b, "aggregate", 1000)
+	bench.Aggregate(b, repo, spec.NewPipeline().
+		Match(spec.Ne("kind", "transfer")).
+		GroupBy("$account", bson.M{"total": spec.Sum("$amount")}).
+		SortBy("total", -1).
+		Limit(10))
+}
@@ -20,6 +20,9 @@ import (
 	"context"
 	"time"
 
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -28,9 +31,10 @@ import (
 // Client wraps a MongoDB client with connection management and
 // convenient repository creation methods.
 type Client struct {
-	client *mongo.Client
-	db     *mongo.Database
-	dbName string
+	client   *mongo.Client
+	db       *mongo.Database
+	dbName   string
+	registry *mongorepo.Registry
 }
 
 // Option configures a Client.
@@ -176,9 +180,10 @@ func Connect(ctx context.Context, uri string, opts ...Option) (*Client, error) {
 	}
 
 	return &Client{
-		client: client,
-		db:     client.Database(cfg.database),
-		dbName: cfg.database,
+		client:   client,
+		db:       client.Database(cfg.database),
+		dbName:   cfg.database,
+		registry: mongorepo.NewRegistry(),
 	}, nil
 }
 
@@ -207,6 +212,53 @@ func (c *Client) Collection(name string) *mongo.Collection {
 	return c.db.Collection(name)
 }
 
+// NewRepository creates a repository for T on its conventional collection in
+// c's default database; see mongorepo.NewInDatabase. The repository is added
+// to c's Registry.
+//
+// Example:
+//
+//	users := client.NewRepository[User](c) // collection "users"
+func NewRepository[T any](c *Client, opts ...mongorepo.Option) *mongorepo.MongoRepository[T] {
+	opts = append([]mongorepo.Option{mongorepo.WithRegistry(c.registry)}, opts...)
+	return mongorepo.NewInDatabase[T](c.db, opts...)
+}
+
+// Registry returns the registry of the repositories created with
+// NewRepository. Pass mongorepo.WithRegistry(c.Registry()) to add others.
+//
+// Example:
+//
+//	if err := c.Registry().EnsureIndexes(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+func (c *Client) Registry() *mongorepo.Registry {
+	return c.registry
+}
+
+// IndexBuildProgress reports the index builds running on the server; see
+// mongorepo.IndexBuildProgress.
+//
+// Example:
+//
+//	builds, err := c.IndexBuildProgress(ctx)
+//	for _, b := range builds {
+//	    log.Printf("%s %v: %.0f%%", b.Namespace, b.Indexes, b.Percent())
+//	}
+func (c *Client) IndexBuildProgress(ctx context.Context) ([]mongorepo.IndexBuild, error) {
+	return mongorepo.IndexBuildProgress(ctx, c.client)
+}
+
+// CreateCappedCollection creates the capped collection name in the default
+// database; see mongorepo.CreateCapped.
+//
+// Example:
+//
+//	err := c.CreateCappedCollection(ctx, "audit_log", 64<<20, 100_000)
+func (c *Client) CreateCappedCollection(ctx context.Context, name string, sizeBytes, maxDocs int64) error {
+	return mongorepo.CreateCapped(ctx, c.db, name, sizeBytes, maxDocs)
+}
+
 // MongoClient returns the underlying mongo.Client for advanced operations.
 func (c *Client) MongoClient() *mongo.Client {
 	return c.client
@@ -227,6 +279,47 @@ func (c *Client) UseSession(ctx context.Context, fn func(mongo.SessionContext) e
 	return c.client.UseSession(ctx, fn)
 }
 
+// CausalSession starts a causally consistent session and returns ctx bound to it,
+// so a write followed by a read observes the write even if the read is served by
+// another replica set member. Pass tokens to continue the causal chain of an
+// earlier session, e.g. one from a previous request to a load-balanced service.
+//
+// Example:
+//
+//	sctx, sess, err := c.CausalSession(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	defer sess.End(ctx)
+//
+//	_ = userRepo.InsertOne(sctx, user)
+//	u, _ := userRepo.FindOne(sctx, spec.Eq("_id", user.ID)) // sees the insert
+//	w.Header().Set("X-Causal-Token", sess.Token().String())
+func (c *Client) CausalSession(ctx context.Context, tokens ...mongorepo.CausalToken) (context.Context, *mongorepo.CausalSession, error) {
+	sess, err := mongorepo.StartCausalSession(c.client, tokens...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sess.Context(ctx), sess, nil
+}
+
+// Watch delivers change events for every collection in the default database
+// until ctx is cancelled. See mongorepo.WatchDatabase for the options; use a
+// repository's Watch for typed events of one collection.
+//
+// Example:
+//
+//	events, err := c.Watch(ctx, mongorepo.WithOperations(mongorepo.OpTypeInsert))
+//	if err != nil {
+//	    return err
+//	}
+//	for ev := range events {
+//	    log.Printf("%s inserted into %s", ev.ID, ev.Collection)
+//	}
+func (c *Client) Watch(ctx context.Context, opts ...mongorepo.WatchOption) (<-chan mongorepo.ChangeEvent[bson.M], error) {
+	return mongorepo.WatchDatabase(ctx, c.db, opts...)
+}
+
 // ListDatabaseNames returns a list of database names.
 func (c *Client) ListDatabaseNames(ctx context.Context) ([]string, error) {
 	return c.client.ListDatabaseNames(ctx, map[string]any{})
@@ -0,0 +1,99 @@
+package compat
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Aggregate runs pipeline on coll as the service described by p can execute it.
+//
+// Behavior:
+//   - The pipeline is rewritten with Rewrite, then checked with Validate
+//   - Unsupported features that remain fail with an error matching ErrUnsupported
+//     before anything is sent to the server
+//   - If p lacks $facet and the pipeline ends with one, each facet runs as its
+//     own aggregation after the preceding stages, and the results are assembled
+//     into the single document $facet would have produced
+func Aggregate(ctx context.Context, coll *mongo.Collection, p Profile, pipeline []bson.M, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	pipeline = p.Rewrite(pipeline)
+
+	if n := len(pipeline); n > 0 && !p.Supports("$facet") && len(pipeline[n-1]) == 1 {
+		if spec, ok := pipeline[n-1]["$facet"]; ok {
+			return aggregateFacets(ctx, coll, p, pipeline[:n-1], spec, opts)
+		}
+	}
+
+	if err := p.Validate(pipeline); err != nil {
+		return nil, err
+	}
+	return coll.Aggregate(ctx, pipeline, opts...)
+}
+
+// aggregateFacets emulates a trailing $facet stage with one aggregation per facet.
+func aggregateFacets(ctx context.Context, coll *mongo.Collection, p Profile, prefix []bson.M, spec any, opts []*options.AggregateOptions) (*mongo.Cursor, error) {
+	facets, err := facetPipelines(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelines := make(map[string][]bson.M, len(facets))
+	for name, sub := range facets {
+		full := append(append([]bson.M{}, prefix...), p.Rewrite(sub)...)
+		if err := p.Validate(full); err != nil {
+			return nil, err
+		}
+		pipelines[name] = full
+	}
+
+	result := bson.M{}
+	for name, full := range pipelines {
+		cur, err := coll.Aggregate(ctx, full, opts...)
+		if err != nil {
+			return nil, err
+		}
+		docs := []bson.Raw{}
+		if err := cur.All(ctx, &docs); err != nil {
+			return nil, err
+		}
+		result[name] = docs
+	}
+	return mongo.NewCursorFromDocuments([]any{result}, nil, nil)
+}
+
+// facetPipelines decodes a $facet specification into named sub-pipelines.
+func facetPipelines(spec any) (map[string][]bson.M, error) {
+	var named map[string]any
+	switch s := spec.(type) {
+	case bson.M:
+		named = s
+	case map[string]any:
+		named = s
+	case bson.D:
+		named = make(map[string]any, len(s))
+		for _, e := range s {
+			named[e.Key] = e.Value
+		}
+	default:
+		return nil, fmt.Errorf("compat: unsupported $facet specification of type %T", spec)
+	}
+
+	out := make(map[string][]bson.M, len(named))
+	for name, raw := range named {
+		b, err := bson.Marshal(bson.M{"p": raw})
+		if err != nil {
+			return nil, fmt.Errorf("compat: facet %q: %w", name, err)
+		}
+		var holder struct {
+			P []bson.M `bson:"p"`
+		}
+		if err := bson.Unmarshal(b, &holder); err != nil {
+			return nil, fmt.Errorf("compat: facet %q: %w", name, err)
+		}
+		out[name] = holder.P
+	}
+	return out, nil
+}
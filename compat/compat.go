@@ -0,0 +1,263 @@
+// Package compat adapts aggregation pipelines to MongoDB-compatible services
+// such as Amazon DocumentDB and Azure Cosmos DB for MongoDB.
+//
+// A Profile lists the stages and operators a service does not support. Rewrite
+// replaces unsupported stages that have a supported equivalent ($set becomes
+// $addFields, $replaceWith becomes $replaceRoot, ...), Validate reports what is
+// left, and Aggregate runs a pipeline with both applied, emulating a trailing
+// $facet stage with one aggregation per facet when the service lacks it.
+//
+// The built-in profiles reflect the documented gaps of each service at the time
+// of writing. Services evolve; copy a profile and adjust it with Without and
+// With when your cluster version differs.
+//
+// Divergent behaviors that cannot be rewritten and are worth knowing about:
+//
+//   - DocumentDB ignores collation, so case-insensitive indexes and queries
+//     must be modeled with normalized fields
+//   - DocumentDB orders mixed-type values differently from MongoDB in some sorts
+//   - Cosmos DB rejects requests that exceed provisioned throughput with error
+//     16500 (TooManyRequests); retry with backoff
+//   - Cosmos DB only allows unique indexes to be created on empty collections
+//
+// Example:
+//
+//	repo := mongorepo.New[Order](coll, mongorepo.WithCompatibility(compat.DocumentDB))
+//
+//	// $facet is emulated, $sortByCount is rewritten to $group + $sort.
+//	out, err := repo.AggregateRaw(ctx, pipeline)
+package compat
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrUnsupported matches errors returned when a pipeline uses features the
+// selected profile does not support.
+var ErrUnsupported = errors.New("compat: unsupported feature")
+
+// Profile describes the features a MongoDB-compatible service lacks.
+// The zero value supports everything.
+type Profile struct {
+	// Name identifies the profile in error messages.
+	Name string
+
+	// UnsupportedStages maps aggregation stage names (e.g. "$facet") to a note
+	// explaining the gap.
+	UnsupportedStages map[string]string
+
+	// UnsupportedOperators maps query and expression operators (e.g. "$where")
+	// to a note explaining the gap.
+	UnsupportedOperators map[string]string
+}
+
+// Built-in profiles.
+var (
+	// MongoDB supports every feature.
+	MongoDB = Profile{Name: "mongodb"}
+
+	// DocumentDB targets Amazon DocumentDB with MongoDB 5.0 compatibility.
+	DocumentDB = Profile{
+		Name: "documentdb",
+		UnsupportedStages: map[string]string{
+			"$facet":           "emulated by Aggregate when it is the last stage",
+			"$bucketAuto":      "use $bucket with explicit boundaries",
+			"$sortByCount":     "rewritten to $group + $sort",
+			"$replaceWith":     "rewritten to $replaceRoot",
+			"$set":             "rewritten to $addFields",
+			"$unset":           "rewritten to $project",
+			"$setWindowFields": "not available",
+			"$densify":         "not available",
+			"$fill":            "not available",
+			"$documents":       "not available",
+			"$planCacheStats":  "not available",
+			"$listSessions":    "not available",
+		},
+		UnsupportedOperators: map[string]string{
+			"$where":       "server-side JavaScript is not available",
+			"$function":    "server-side JavaScript is not available",
+			"$accumulator": "server-side JavaScript is not available",
+		},
+	}
+
+	// CosmosDB targets Azure Cosmos DB for MongoDB (RU) with server version 4.2.
+	CosmosDB = Profile{
+		Name: "cosmosdb",
+		UnsupportedStages: map[string]string{
+			"$unionWith":       "requires server 4.4",
+			"$setWindowFields": "not available",
+			"$densify":         "not available",
+			"$fill":            "not available",
+			"$documents":       "not available",
+			"$currentOp":       "not available",
+			"$listSessions":    "not available",
+			"$planCacheStats":  "not available",
+			"$collStats":       "not available",
+		},
+		UnsupportedOperators: map[string]string{
+			"$where":       "server-side JavaScript is not available",
+			"$function":    "server-side JavaScript is not available",
+			"$accumulator": "server-side JavaScript is not available",
+		},
+	}
+)
+
+// Supports reports whether the profile supports the stage or operator name.
+func (p Profile) Supports(name string) bool {
+	_, stage := p.UnsupportedStages[name]
+	_, op := p.UnsupportedOperators[name]
+	return !stage && !op
+}
+
+// Without returns a copy of the profile that treats the given stages and
+// operators as supported, for clusters newer than the profile assumes.
+func (p Profile) Without(names ...string) Profile {
+	out := p.clone()
+	for _, n := range names {
+		delete(out.UnsupportedStages, n)
+		delete(out.UnsupportedOperators, n)
+	}
+	return out
+}
+
+// With returns a copy of the profile that treats the given stages as unsupported.
+func (p Profile) With(stages ...string) Profile {
+	out := p.clone()
+	for _, s := range stages {
+		out.UnsupportedStages[s] = "disabled"
+	}
+	return out
+}
+
+func (p Profile) clone() Profile {
+	out := Profile{
+		Name:                 p.Name,
+		UnsupportedStages:    make(map[string]string, len(p.UnsupportedStages)),
+		UnsupportedOperators: make(map[string]string, len(p.UnsupportedOperators)),
+	}
+	for k, v := range p.UnsupportedStages {
+		out.UnsupportedStages[k] = v
+	}
+	for k, v := range p.UnsupportedOperators {
+		out.UnsupportedOperators[k] = v
+	}
+	return out
+}
+
+// Issue is one unsupported feature found in a pipeline or filter.
+type Issue struct {
+	// Stage is the index of the offending stage, or -1 for filters.
+	Stage int
+
+	// Name is the unsupported stage or operator.
+	Name string
+
+	// Note explains the gap, as recorded in the profile.
+	Note string
+}
+
+// UnsupportedError lists the unsupported features found by Validate.
+type UnsupportedError struct {
+	Profile string
+	Issues  []Issue
+}
+
+func (e *UnsupportedError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		where := "filter"
+		if is.Stage >= 0 {
+			where = fmt.Sprintf("stage %d", is.Stage)
+		}
+		parts[i] = fmt.Sprintf("%s in %s (%s)", is.Name, where, is.Note)
+	}
+	return fmt.Sprintf("compat: %s does not support %s", e.Profile, strings.Join(parts, "; "))
+}
+
+// Unwrap returns ErrUnsupported to allow errors.Is(err, ErrUnsupported) to work.
+func (e *UnsupportedError) Unwrap() error {
+	return ErrUnsupported
+}
+
+// Validate checks every stage and nested operator of pipeline against the
+// profile and returns an *UnsupportedError listing all issues, or nil.
+func (p Profile) Validate(pipeline []bson.M) error {
+	var issues []Issue
+	for i, stage := range pipeline {
+		for name, arg := range stage {
+			if note, ok := p.UnsupportedStages[name]; ok {
+				issues = append(issues, Issue{Stage: i, Name: name, Note: note})
+			}
+			issues = p.walk(arg, i, issues)
+		}
+	}
+	return p.result(issues)
+}
+
+// ValidateFilter checks the operators used in a query filter.
+// Filters that are not bson.M or bson.D are accepted without inspection.
+func (p Profile) ValidateFilter(filter any) error {
+	return p.result(p.walk(filter, -1, nil))
+}
+
+func (p Profile) result(issues []Issue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Stage != issues[j].Stage {
+			return issues[i].Stage < issues[j].Stage
+		}
+		return issues[i].Name < issues[j].Name
+	})
+	return &UnsupportedError{Profile: p.Name, Issues: issues}
+}
+
+// walk collects unsupported operators nested anywhere in v.
+func (p Profile) walk(v any, stage int, issues []Issue) []Issue {
+	visit := func(key string, val any) {
+		// Stage names show up nested in $facet and $lookup sub-pipelines.
+		if note, ok := p.UnsupportedOperators[key]; ok {
+			issues = append(issues, Issue{Stage: stage, Name: key, Note: note})
+		} else if note, ok := p.UnsupportedStages[key]; ok {
+			issues = append(issues, Issue{Stage: stage, Name: key, Note: note})
+		}
+		issues = p.walk(val, stage, issues)
+	}
+	switch t := v.(type) {
+	case bson.M:
+		for k, val := range t {
+			visit(k, val)
+		}
+	case map[string]any:
+		for k, val := range t {
+			visit(k, val)
+		}
+	case bson.D:
+		for _, e := range t {
+			visit(e.Key, e.Value)
+		}
+	case bson.A:
+		for _, val := range t {
+			issues = p.walk(val, stage, issues)
+		}
+	case []any:
+		for _, val := range t {
+			issues = p.walk(val, stage, issues)
+		}
+	case []bson.M:
+		for _, val := range t {
+			issues = p.walk(val, stage, issues)
+		}
+	case []bson.D:
+		for _, val := range t {
+			issues = p.walk(val, stage, issues)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,91 @@
+//go:build integration
+
+package compat_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/compat"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestAggregate_EmulatesFacet(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("compat_orders")
+	if _, err := coll.InsertMany(ctx, []any{
+		bson.M{"country": "PT", "total": 10},
+		bson.M{"country": "PT", "total": 20},
+		bson.M{"country": "US", "total": 30},
+		bson.M{"country": "US", "total": 0},
+	}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"total": bson.M{"$gt": 0}}},
+		{"$facet": bson.M{
+			"byCountry": bson.A{bson.M{"$sortByCount": "$country"}},
+			"count":     bson.A{bson.M{"$count": "n"}},
+		}},
+	}
+
+	run := func(p compat.Profile) bson.M {
+		t.Helper()
+		cur, err := compat.Aggregate(ctx, coll, p, pipeline)
+		if err != nil {
+			t.Fatalf("Aggregate(%s): %v", p.Name, err)
+		}
+		var out []bson.M
+		if err := cur.All(ctx, &out); err != nil {
+			t.Fatalf("All(%s): %v", p.Name, err)
+		}
+		if len(out) != 1 {
+			t.Fatalf("expected one facet document from %s, got %d", p.Name, len(out))
+		}
+		return out[0]
+	}
+
+	native, emulated := run(compat.MongoDB), run(compat.DocumentDB)
+	for _, key := range []string{"byCountry", "count"} {
+		if got, want := len(emulated[key].(bson.A)), len(native[key].(bson.A)); got != want {
+			t.Fatalf("facet %q: emulated has %d results, native %d", key, got, want)
+		}
+	}
+	first := emulated["byCountry"].(bson.A)[0].(bson.M)
+	if first["_id"] != "PT" || first["count"] != int32(2) {
+		t.Fatalf("unexpected first group: %v", first)
+	}
+}
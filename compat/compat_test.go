@@ -0,0 +1,90 @@
+package compat_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/compat"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRewrite_DocumentDB(t *testing.T) {
+	in := []bson.M{
+		{"$match": bson.M{"status": "active"}},
+		{"$set": bson.M{"total": bson.M{"$sum": "$items.price"}}},
+		{"$unset": bson.A{"items", "internal"}},
+		{"$replaceWith": "$customer"},
+		{"$sortByCount": "$country"},
+	}
+	got := compat.DocumentDB.Rewrite(in)
+	want := []bson.M{
+		{"$match": bson.M{"status": "active"}},
+		{"$addFields": bson.M{"total": bson.M{"$sum": "$items.price"}}},
+		{"$project": bson.M{"items": 0, "internal": 0}},
+		{"$replaceRoot": bson.M{"newRoot": "$customer"}},
+		{"$group": bson.M{"_id": "$country", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.M{"count": -1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Rewrite mismatch:\n got  %v\n want %v", got, want)
+	}
+	if err := compat.DocumentDB.Validate(got); err != nil {
+		t.Fatalf("expected rewritten pipeline to validate, got %v", err)
+	}
+	if _, ok := in[1]["$set"]; !ok {
+		t.Fatal("Rewrite must not modify its input")
+	}
+}
+
+func TestRewrite_KeepsSupportedStages(t *testing.T) {
+	in := []bson.M{{"$set": bson.M{"a": 1}}}
+	if got := compat.MongoDB.Rewrite(in); !reflect.DeepEqual(got, in) {
+		t.Fatalf("expected MongoDB profile to keep $set, got %v", got)
+	}
+}
+
+func TestValidate_ReportsNestedIssues(t *testing.T) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"$where": "this.a > 1"}},
+		{"$facet": bson.M{
+			"windows": bson.A{bson.M{"$setWindowFields": bson.M{}}},
+		}},
+	}
+	err := compat.DocumentDB.Validate(pipeline)
+	if !errors.Is(err, compat.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	var ue *compat.UnsupportedError
+	if !errors.As(err, &ue) {
+		t.Fatalf("expected *UnsupportedError, got %T", err)
+	}
+	var names []string
+	for _, is := range ue.Issues {
+		names = append(names, is.Name)
+	}
+	want := []string{"$where", "$facet", "$setWindowFields"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("issues: got %v, want %v", names, want)
+	}
+}
+
+func TestValidateFilter(t *testing.T) {
+	if err := compat.CosmosDB.ValidateFilter(bson.D{{Key: "$where", Value: "true"}}); !errors.Is(err, compat.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported for $where, got %v", err)
+	}
+	if err := compat.CosmosDB.ValidateFilter(bson.M{"age": bson.M{"$gte": 18}}); err != nil {
+		t.Fatalf("expected plain filter to validate, got %v", err)
+	}
+}
+
+func TestProfile_WithAndWithout(t *testing.T) {
+	p := compat.DocumentDB.Without("$facet").With("$lookup")
+	if !p.Supports("$facet") || p.Supports("$lookup") {
+		t.Fatalf("unexpected profile: %+v", p)
+	}
+	if compat.DocumentDB.Supports("$facet") || !compat.DocumentDB.Supports("$lookup") {
+		t.Fatal("With and Without must not modify the original profile")
+	}
+}
@@ -0,0 +1,86 @@
+package compat
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// rewriters replace a stage by equivalent stages built from older operators.
+var rewriters = map[string]func(arg any) ([]bson.M, bool){
+	"$set": func(arg any) ([]bson.M, bool) {
+		return []bson.M{{"$addFields": arg}}, true
+	},
+	"$replaceWith": func(arg any) ([]bson.M, bool) {
+		return []bson.M{{"$replaceRoot": bson.M{"newRoot": arg}}}, true
+	},
+	"$sortByCount": func(arg any) ([]bson.M, bool) {
+		return []bson.M{
+			{"$group": bson.M{"_id": arg, "count": bson.M{"$sum": 1}}},
+			{"$sort": bson.M{"count": -1}},
+		}, true
+	},
+	"$unset": func(arg any) ([]bson.M, bool) {
+		proj := bson.M{}
+		switch f := arg.(type) {
+		case string:
+			proj[f] = 0
+		case []string:
+			for _, s := range f {
+				proj[s] = 0
+			}
+		case bson.A:
+			for _, v := range f {
+				s, ok := v.(string)
+				if !ok {
+					return nil, false
+				}
+				proj[s] = 0
+			}
+		case []any:
+			for _, v := range f {
+				s, ok := v.(string)
+				if !ok {
+					return nil, false
+				}
+				proj[s] = 0
+			}
+		default:
+			return nil, false
+		}
+		return []bson.M{{"$project": proj}}, true
+	},
+}
+
+// Rewrite returns pipeline with unsupported top-level stages replaced by
+// supported equivalents where one exists:
+//
+//	$set          -> $addFields
+//	$unset        -> $project with exclusions
+//	$replaceWith  -> $replaceRoot
+//	$sortByCount  -> $group + $sort
+//
+// Other stages are kept as they are; run Validate on the result to find what
+// is still unsupported. The input is not modified.
+func (p Profile) Rewrite(pipeline []bson.M) []bson.M {
+	out := make([]bson.M, 0, len(pipeline))
+	for _, stage := range pipeline {
+		out = append(out, p.rewriteStage(stage)...)
+	}
+	return out
+}
+
+func (p Profile) rewriteStage(stage bson.M) []bson.M {
+	if len(stage) != 1 {
+		return []bson.M{stage}
+	}
+	for name, arg := range stage {
+		if _, unsupported := p.UnsupportedStages[name]; !unsupported {
+			break
+		}
+		if rw, ok := rewriters[name]; ok {
+			if stages, ok := rw(arg); ok {
+				return stages
+			}
+		}
+	}
+	return []bson.M{stage}
+}
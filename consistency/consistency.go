@@ -0,0 +1,291 @@
+// Package consistency audits data consistency across related collections.
+//
+// Given declared relations between parent and child collections, Audit finds
+// orphaned children (documents whose referenced parent no longer exists) and
+// produces a report, optionally deleting or flagging the orphans. It is intended
+// to run as a scheduled maintenance job.
+//
+// Example:
+//
+//	relations := consistency.FromReferences(db.Collection("users"), userRefs...)
+//	report, err := consistency.Audit(ctx, relations, consistency.WithAction(consistency.FlagOrphans))
+//	if err != nil {
+//	    return err
+//	}
+//	log.Print(report)
+package consistency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrCrossDatabase is returned when a relation's collections live in different
+// databases, which $lookup cannot join.
+var ErrCrossDatabase = errors.New("consistency: parent and child must be in the same database")
+
+// Relation declares that documents in Child reference documents in Parent.
+type Relation struct {
+	// Name identifies the relation in reports. Defaults to "child.foreign_key".
+	Name string
+
+	// Parent holds the referenced documents.
+	Parent *mongo.Collection
+
+	// ParentKey is the referenced field in Parent. Defaults to "_id".
+	ParentKey string
+
+	// Child holds the referencing documents.
+	Child *mongo.Collection
+
+	// ForeignKey is the referencing field in Child.
+	ForeignKey string
+}
+
+func (rel Relation) name() string {
+	if rel.Name != "" {
+		return rel.Name
+	}
+	return rel.Child.Name() + "." + rel.ForeignKey
+}
+
+func (rel Relation) parentKey() string {
+	if rel.ParentKey == "" {
+		return "_id"
+	}
+	return rel.ParentKey
+}
+
+// FromReferences converts references declared with mongorepo.WithReferences
+// into relations against the given parent collection.
+func FromReferences(parent *mongo.Collection, refs ...mongorepo.Reference) []Relation {
+	relations := make([]Relation, len(refs))
+	for i, ref := range refs {
+		relations[i] = Relation{
+			Parent:     parent,
+			ParentKey:  ref.LocalKey,
+			Child:      ref.Collection,
+			ForeignKey: ref.ForeignKey,
+		}
+	}
+	return relations
+}
+
+// Action determines what Audit does with the orphans it finds.
+type Action int
+
+const (
+	// ReportOnly only counts orphans.
+	ReportOnly Action = iota
+
+	// DeleteOrphans deletes orphaned documents.
+	DeleteOrphans
+
+	// FlagOrphans sets a timestamp field (see WithFlagField) on orphaned documents.
+	FlagOrphans
+)
+
+// Option configures Audit.
+type Option func(*config)
+
+type config struct {
+	action     Action
+	flagField  string
+	sampleSize int
+	batchSize  int
+}
+
+// WithAction sets what Audit does with orphans. The default is ReportOnly.
+func WithAction(action Action) Option {
+	return func(c *config) { c.action = action }
+}
+
+// WithFlagField sets the field stamped on orphans by FlagOrphans. The default is "orphaned_at".
+func WithFlagField(field string) Option {
+	return func(c *config) { c.flagField = field }
+}
+
+// WithSampleSize sets how many orphan IDs are kept per relation in the report. The default is 10.
+func WithSampleSize(n int) Option {
+	return func(c *config) { c.sampleSize = n }
+}
+
+// WithBatchSize sets how many orphans are deleted or flagged per write. The default is 1000.
+func WithBatchSize(n int) Option {
+	return func(c *config) { c.batchSize = n }
+}
+
+// Report summarizes an Audit run.
+type Report struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Relations  []RelationReport
+}
+
+// RelationReport holds the audit result for one relation.
+type RelationReport struct {
+	Name string
+
+	// Orphans is the number of child documents whose parent is missing.
+	Orphans int64
+
+	// SampleIDs holds up to the configured sample size of orphan _id values.
+	SampleIDs []any
+
+	// Deleted is the number of orphans deleted (DeleteOrphans only).
+	Deleted int64
+
+	// Flagged is the number of orphans flagged (FlagOrphans only).
+	Flagged int64
+}
+
+// Orphans returns the total number of orphans across all relations.
+func (r *Report) Orphans() int64 {
+	var n int64
+	for _, rel := range r.Relations {
+		n += rel.Orphans
+	}
+	return n
+}
+
+// String returns a summary with one line per relation.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "consistency audit: %d orphans in %s", r.Orphans(), r.FinishedAt.Sub(r.StartedAt).Round(time.Millisecond))
+	for _, rel := range r.Relations {
+		fmt.Fprintf(&b, "\n  %s: %d orphans", rel.Name, rel.Orphans)
+		if rel.Deleted > 0 {
+			fmt.Fprintf(&b, ", %d deleted", rel.Deleted)
+		}
+		if rel.Flagged > 0 {
+			fmt.Fprintf(&b, ", %d flagged", rel.Flagged)
+		}
+	}
+	return b.String()
+}
+
+// Audit scans each relation for orphaned child documents.
+//
+// Behavior:
+//   - Children with a missing or null foreign key are not orphans
+//   - Array foreign keys are orphans only if none of the referenced parents exist
+//   - Relations are processed in order; on error the partial report is returned with it
+//
+// MongoDB equivalent (per relation):
+//
+//	db.child.aggregate([
+//	    {$match: {fk: {$ne: null}}},
+//	    {$lookup: {from: "parent", localField: "fk", foreignField: "_id", as: "_parent"}},
+//	    {$match: {_parent: {$size: 0}}},
+//	    {$project: {_id: 1}},
+//	])
+func Audit(ctx context.Context, relations []Relation, opts ...Option) (*Report, error) {
+	c := config{flagField: "orphaned_at", sampleSize: 10, batchSize: 1000}
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&c)
+		}
+	}
+	if c.batchSize <= 0 {
+		c.batchSize = 1000
+	}
+
+	report := &Report{StartedAt: time.Now().UTC()}
+	for _, rel := range relations {
+		rr, err := auditRelation(ctx, rel, c)
+		report.Relations = append(report.Relations, rr)
+		if err != nil {
+			report.FinishedAt = time.Now().UTC()
+			return report, fmt.Errorf("consistency: relation %s: %w", rr.Name, err)
+		}
+	}
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
+
+// OrphanPipeline returns the aggregation pipeline Audit runs against rel.Child
+// to select the _id of each orphan.
+func OrphanPipeline(rel Relation) []bson.M {
+	return []bson.M{
+		{"$match": bson.M{rel.ForeignKey: bson.M{"$ne": nil}}},
+		{"$lookup": bson.M{
+			"from":         rel.Parent.Name(),
+			"localField":   rel.ForeignKey,
+			"foreignField": rel.parentKey(),
+			"as":           "_parent",
+		}},
+		{"$match": bson.M{"_parent": bson.M{"$size": 0}}},
+		{"$project": bson.M{"_id": 1}},
+	}
+}
+
+func auditRelation(ctx context.Context, rel Relation, c config) (RelationReport, error) {
+	rr := RelationReport{Name: rel.name()}
+	if rel.Parent.Database().Name() != rel.Child.Database().Name() {
+		return rr, ErrCrossDatabase
+	}
+
+	cur, err := rel.Child.Aggregate(ctx, OrphanPipeline(rel))
+	if err != nil {
+		return rr, err
+	}
+	defer cur.Close(ctx)
+
+	batch := make([]any, 0, c.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		filter := bson.M{"_id": bson.M{"$in": batch}}
+		switch c.action {
+		case DeleteOrphans:
+			res, err := rel.Child.DeleteMany(ctx, filter)
+			if err != nil {
+				return err
+			}
+			rr.Deleted += res.DeletedCount
+		case FlagOrphans:
+			res, err := rel.Child.UpdateMany(ctx, filter, bson.M{"$set": bson.M{c.flagField: time.Now().UTC()}})
+			if err != nil {
+				return err
+			}
+			rr.Flagged += res.ModifiedCount
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	for cur.Next(ctx) {
+		var doc struct {
+			ID any `bson:"_id"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return rr, err
+		}
+		rr.Orphans++
+		if len(rr.SampleIDs) < c.sampleSize {
+			rr.SampleIDs = append(rr.SampleIDs, doc.ID)
+		}
+		if c.action == ReportOnly {
+			continue
+		}
+		batch = append(batch, doc.ID)
+		if len(batch) >= c.batchSize {
+			if err := flush(); err != nil {
+				return rr, err
+			}
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return rr, err
+	}
+	return rr, flush()
+}
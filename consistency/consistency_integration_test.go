@@ -0,0 +1,87 @@
+//go:build integration
+
+package consistency_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/consistency"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestAudit_FindsAndDeletesOrphans(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	users := db.Collection("users")
+	orders := db.Collection("orders")
+
+	res, err := users.InsertOne(ctx, bson.M{"name": "Ada"})
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if _, err := orders.InsertMany(ctx, []any{
+		bson.M{"user_id": res.InsertedID},
+		bson.M{"user_id": "missing-1"},
+		bson.M{"user_id": "missing-2"},
+		bson.M{"note": "no user"},
+	}); err != nil {
+		t.Fatalf("insert orders: %v", err)
+	}
+
+	relations := []consistency.Relation{{Parent: users, Child: orders, ForeignKey: "user_id"}}
+
+	report, err := consistency.Audit(ctx, relations)
+	if err != nil {
+		t.Fatalf("Audit failed: %v", err)
+	}
+	if report.Orphans() != 2 {
+		t.Fatalf("expected 2 orphans, got %d", report.Orphans())
+	}
+	if n, _ := orders.CountDocuments(ctx, bson.M{}); n != 4 {
+		t.Fatalf("ReportOnly must not modify documents, got %d orders", n)
+	}
+
+	report, err = consistency.Audit(ctx, relations, consistency.WithAction(consistency.DeleteOrphans), consistency.WithBatchSize(1))
+	if err != nil {
+		t.Fatalf("Audit failed: %v", err)
+	}
+	if report.Relations[0].Deleted != 2 {
+		t.Fatalf("expected 2 deleted, got %d", report.Relations[0].Deleted)
+	}
+	if n, _ := orders.CountDocuments(ctx, bson.M{}); n != 2 {
+		t.Fatalf("expected 2 orders left, got %d", n)
+	}
+}
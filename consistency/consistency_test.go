@@ -0,0 +1,84 @@
+package consistency_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/consistency"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDB returns a database handle without connecting; collection names and
+// pipelines can be built offline.
+func testDB(t *testing.T) *mongo.Database {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client.Database("app")
+}
+
+func TestFromReferences(t *testing.T) {
+	db := testDB(t)
+	users := db.Collection("users")
+	orders := db.Collection("orders")
+
+	got := consistency.FromReferences(users, mongorepo.Reference{Collection: orders, ForeignKey: "user_id"})
+	if len(got) != 1 {
+		t.Fatalf("expected 1 relation, got %d", len(got))
+	}
+	if got[0].Parent != users || got[0].Child != orders || got[0].ForeignKey != "user_id" {
+		t.Fatalf("unexpected relation: %#v", got[0])
+	}
+}
+
+func TestOrphanPipeline(t *testing.T) {
+	db := testDB(t)
+	rel := consistency.Relation{Parent: db.Collection("users"), Child: db.Collection("orders"), ForeignKey: "user_id"}
+
+	want := []bson.M{
+		{"$match": bson.M{"user_id": bson.M{"$ne": nil}}},
+		{"$lookup": bson.M{"from": "users", "localField": "user_id", "foreignField": "_id", "as": "_parent"}},
+		{"$match": bson.M{"_parent": bson.M{"$size": 0}}},
+		{"$project": bson.M{"_id": 1}},
+	}
+	if got := consistency.OrphanPipeline(rel); !reflect.DeepEqual(got, want) {
+		t.Fatalf("OrphanPipeline mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestReportString(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := &consistency.Report{
+		StartedAt:  start,
+		FinishedAt: start.Add(1500 * time.Millisecond),
+		Relations: []consistency.RelationReport{
+			{Name: "orders.user_id", Orphans: 3, Deleted: 3},
+			{Name: "sessions.user_id", Orphans: 1, Flagged: 1},
+		},
+	}
+
+	if report.Orphans() != 4 {
+		t.Fatalf("Orphans() = %d, want 4", report.Orphans())
+	}
+
+	got := report.String()
+	for _, want := range []string{
+		"4 orphans in 1.5s",
+		"orders.user_id: 3 orphans, 3 deleted",
+		"sessions.user_id: 1 orphans, 1 flagged",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("String() missing %q:\n%s", want, got)
+		}
+	}
+}
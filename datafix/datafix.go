@@ -0,0 +1,118 @@
+// Package datafix provides declarative, batched field migrations for large collections.
+//
+// Operations such as RenameField, ConvertType, and SetDefaultWhereMissing are
+// executed by Run in _id order, one batch of documents at a time, so they never
+// hold long locks or build huge update sets. Progress is reported after every
+// batch together with a Checkpoint that can be persisted and passed back to Run
+// to resume after a crash.
+//
+// Example:
+//
+//	err := datafix.Run(ctx, db.Collection("users"),
+//	    []datafix.Operation{
+//	        datafix.RenameField("fullname", "name"),
+//	        datafix.ConvertType("age", datafix.Int),
+//	        datafix.SetDefaultWhereMissing("status", "active"),
+//	    },
+//	    datafix.WithBatchSize(500),
+//	    datafix.WithProgress(func(p datafix.Progress) {
+//	        log.Printf("%s: %d/%d", p.Operation, p.Processed, p.Total)
+//	        saveCheckpoint(p.Checkpoint)
+//	    }),
+//	)
+package datafix
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Operation is a single declarative data fix.
+type Operation interface {
+	// Name describes the operation in progress reports.
+	Name() string
+
+	// Filter selects the documents that still need the fix.
+	Filter() bson.M
+
+	// Update returns the update document or pipeline applied to selected documents.
+	Update() any
+}
+
+type operation struct {
+	name   string
+	filter bson.M
+	update any
+}
+
+func (o operation) Name() string   { return o.name }
+func (o operation) Filter() bson.M { return o.filter }
+func (o operation) Update() any    { return o.update }
+
+// Custom creates an operation from an arbitrary filter and update.
+// The filter should stop matching documents once they are fixed.
+func Custom(name string, filter bson.M, update any) Operation {
+	return operation{name: name, filter: filter, update: update}
+}
+
+// RenameField renames a field on every document that has it.
+//
+// MongoDB equivalent: updateMany({from: {$exists: true}}, {$rename: {from: to}})
+func RenameField(from, to string) Operation {
+	return operation{
+		name:   "rename " + from + " -> " + to,
+		filter: bson.M{from: bson.M{"$exists": true}},
+		update: bson.M{"$rename": bson.M{from: to}},
+	}
+}
+
+// SetDefaultWhereMissing sets field to value on every document that lacks it.
+//
+// MongoDB equivalent: updateMany({field: {$exists: false}}, {$set: {field: value}})
+func SetDefaultWhereMissing(field string, value any) Operation {
+	return operation{
+		name:   "default " + field,
+		filter: bson.M{field: bson.M{"$exists": false}},
+		update: bson.M{"$set": bson.M{field: value}},
+	}
+}
+
+// TargetType is a BSON type that ConvertType can convert to.
+type TargetType string
+
+// Supported conversion targets, named after their $convert type aliases.
+const (
+	Int      TargetType = "int"
+	Long     TargetType = "long"
+	Double   TargetType = "double"
+	Decimal  TargetType = "decimal"
+	String   TargetType = "string"
+	Bool     TargetType = "bool"
+	Date     TargetType = "date"
+	ObjectID TargetType = "objectId"
+)
+
+// ConvertType converts field to the target type on every document where it
+// currently has a different, non-null type. Values that cannot be converted are
+// left unchanged.
+//
+// MongoDB equivalent:
+//
+//	updateMany(
+//	    {field: {$exists: true, $ne: null, $not: {$type: to}}},
+//	    [{$set: {field: {$convert: {input: "$field", to: to, onError: "$field"}}}}],
+//	)
+func ConvertType(field string, to TargetType) Operation {
+	return operation{
+		name: "convert " + field + " to " + string(to),
+		filter: bson.M{field: bson.M{
+			"$exists": true,
+			"$ne":     nil,
+			"$not":    bson.M{"$type": string(to)},
+		}},
+		update: []bson.M{{"$set": bson.M{field: bson.M{"$convert": bson.M{
+			"input":   "$" + field,
+			"to":      string(to),
+			"onError": "$" + field,
+		}}}}},
+	}
+}
@@ -0,0 +1,119 @@
+//go:build integration
+
+package datafix_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/datafix"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestRun_AppliesOperationsInBatches(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("datafix_users")
+
+	docs := make([]any, 0, 25)
+	for i := 0; i < 25; i++ {
+		docs = append(docs, bson.M{"_id": i, "fullname": "user", "age": "42"})
+	}
+	docs = append(docs, bson.M{"_id": 100, "name": "already", "age": 7, "status": "banned"})
+	if _, err := coll.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	var last datafix.Progress
+	batches := 0
+	err := datafix.Run(ctx, coll,
+		[]datafix.Operation{
+			datafix.RenameField("fullname", "name"),
+			datafix.ConvertType("age", datafix.Int),
+			datafix.SetDefaultWhereMissing("status", "active"),
+		},
+		datafix.WithBatchSize(10),
+		datafix.WithProgress(func(p datafix.Progress) {
+			batches++
+			last = p
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if batches != 9 {
+		t.Fatalf("expected 9 batches (3 per operation), got %d", batches)
+	}
+	if last.Step != 2 || last.Processed != 25 || last.Total != 25 {
+		t.Fatalf("unexpected final progress: %+v", last)
+	}
+
+	if n, _ := coll.CountDocuments(ctx, bson.M{"fullname": bson.M{"$exists": true}}); n != 0 {
+		t.Fatalf("expected fullname renamed everywhere, %d left", n)
+	}
+	if n, _ := coll.CountDocuments(ctx, bson.M{"age": bson.M{"$type": "int"}}); n != 26 {
+		t.Fatalf("expected all ages to be int, got %d", n)
+	}
+	if n, _ := coll.CountDocuments(ctx, bson.M{"status": "active"}); n != 25 {
+		t.Fatalf("expected 25 defaulted statuses, got %d", n)
+	}
+}
+
+func TestRun_ResumesFromCheckpoint(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("datafix_resume")
+
+	docs := make([]any, 0, 10)
+	for i := 0; i < 10; i++ {
+		docs = append(docs, bson.M{"_id": i})
+	}
+	if _, err := coll.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	err := datafix.Run(ctx, coll,
+		[]datafix.Operation{datafix.SetDefaultWhereMissing("status", "active")},
+		datafix.WithResume(datafix.Checkpoint{Step: 0, LastID: 4}),
+	)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if n, _ := coll.CountDocuments(ctx, bson.M{"status": "active"}); n != 5 {
+		t.Fatalf("expected only documents after the checkpoint to be fixed, got %d", n)
+	}
+}
@@ -0,0 +1,54 @@
+package datafix_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/datafix"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRenameField(t *testing.T) {
+	op := datafix.RenameField("fullname", "name")
+
+	if got, want := op.Filter(), (bson.M{"fullname": bson.M{"$exists": true}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Filter mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+	if got, want := op.Update(), (bson.M{"$rename": bson.M{"fullname": "name"}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Update mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestSetDefaultWhereMissing(t *testing.T) {
+	op := datafix.SetDefaultWhereMissing("status", "active")
+
+	if got, want := op.Filter(), (bson.M{"status": bson.M{"$exists": false}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Filter mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+	if got, want := op.Update(), (bson.M{"$set": bson.M{"status": "active"}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Update mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestConvertType(t *testing.T) {
+	op := datafix.ConvertType("age", datafix.Int)
+
+	wantFilter := bson.M{"age": bson.M{"$exists": true, "$ne": nil, "$not": bson.M{"$type": "int"}}}
+	if got := op.Filter(); !reflect.DeepEqual(got, wantFilter) {
+		t.Fatalf("Filter mismatch.\n got: %#v\nwant: %#v", got, wantFilter)
+	}
+
+	wantUpdate := []bson.M{{"$set": bson.M{"age": bson.M{"$convert": bson.M{
+		"input":   "$age",
+		"to":      "int",
+		"onError": "$age",
+	}}}}}
+	if got := op.Update(); !reflect.DeepEqual(got, wantUpdate) {
+		t.Fatalf("Update mismatch.\n got: %#v\nwant: %#v", got, wantUpdate)
+	}
+
+	if op.Name() != "convert age to int" {
+		t.Fatalf("unexpected name %q", op.Name())
+	}
+}
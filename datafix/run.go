@@ -0,0 +1,144 @@
+package datafix
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dElCIoGio/mongox/longop"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Checkpoint records how far Run has progressed. Persist it from the progress
+// callback and pass it to WithResume to continue after an interruption.
+type Checkpoint struct {
+	// Step is the index of the operation in progress.
+	Step int `bson:"step" json:"step"`
+
+	// LastID is the _id of the last document processed by that operation,
+	// or nil if it has not processed any yet.
+	LastID any `bson:"last_id" json:"last_id"`
+}
+
+// Progress is reported after every batch.
+type Progress struct {
+	// Step is the index of the current operation, and Operation its name.
+	Step      int
+	Operation string
+
+	// Processed is the number of documents selected so far by the current operation.
+	Processed int64
+
+	// Modified is the number of documents modified so far by the current operation.
+	Modified int64
+
+	// Total is the number of documents the operation needed to fix when it started
+	// (or resumed).
+	Total int64
+
+	// Checkpoint is where Run would resume from.
+	Checkpoint Checkpoint
+}
+
+// Option configures Run.
+type Option func(*config)
+
+type config struct {
+	batchSize int
+	progress  func(Progress)
+	resume    *Checkpoint
+	rate      float64
+}
+
+// WithBatchSize sets how many documents are updated per batch. The default is 1000.
+func WithBatchSize(n int) Option {
+	return func(c *config) { c.batchSize = n }
+}
+
+// WithProgress registers a callback invoked after every batch.
+func WithProgress(fn func(Progress)) Option {
+	return func(c *config) { c.progress = fn }
+}
+
+// WithRateLimit caps throughput at docsPerSecond so large fixes don't starve
+// other traffic. A value of 0 (the default) disables the limit.
+func WithRateLimit(docsPerSecond float64) Option {
+	return func(c *config) { c.rate = docsPerSecond }
+}
+
+// WithResume continues a previous run from the given checkpoint, skipping
+// completed operations and documents already processed.
+func WithResume(cp Checkpoint) Option {
+	return func(c *config) { c.resume = &cp }
+}
+
+// Run applies the operations to coll in order.
+//
+// Behavior:
+//   - Each operation walks matching documents in ascending _id order, in batches
+//   - Every batch is a single UpdateMany restricted to the batch's _id values
+//   - Cancelling ctx stops after the current batch with ctx.Err()
+//   - Operations are not transactional; re-running (or resuming) is safe because
+//     each operation's filter only matches documents that still need fixing
+func Run(ctx context.Context, coll *mongo.Collection, ops []Operation, opts ...Option) error {
+	c := config{batchSize: 1000}
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&c)
+		}
+	}
+	if c.batchSize <= 0 {
+		c.batchSize = 1000
+	}
+
+	start := 0
+	var lastID any
+	if c.resume != nil {
+		start, lastID = c.resume.Step, c.resume.LastID
+	}
+
+	for step := start; step < len(ops); step++ {
+		if err := runStep(ctx, coll, ops[step], step, lastID, c); err != nil {
+			return fmt.Errorf("datafix: %s: %w", ops[step].Name(), err)
+		}
+		lastID = nil
+	}
+	return nil
+}
+
+func runStep(ctx context.Context, coll *mongo.Collection, op Operation, step int, lastID any, c config) error {
+	p := Progress{Step: step, Operation: op.Name(), Checkpoint: Checkpoint{Step: step, LastID: lastID}}
+
+	opts := []longop.Option{
+		longop.WithRateLimit(c.rate),
+		longop.WithProgress(func(lp longop.Progress) {
+			p.Processed = lp.Processed
+			p.Total = lp.Total
+			p.Checkpoint.LastID = lp.Checkpoint.LastID
+			if c.progress != nil {
+				c.progress(p)
+			}
+		}),
+	}
+	if lastID != nil {
+		opts = append(opts, longop.WithResumeFrom(longop.Checkpoint{LastID: lastID}))
+	}
+
+	scan := longop.Scan{
+		Job:        op.Name(),
+		Collection: coll,
+		Filter:     op.Filter(),
+		Projection: bson.M{"_id": 1},
+		BatchSize:  c.batchSize,
+	}
+	return longop.Run(ctx, scan, func(ctx context.Context, batch []bson.Raw) error {
+		filter := bson.M{"$and": []bson.M{op.Filter(), {"_id": bson.M{"$in": longop.IDs(batch)}}}}
+		res, err := coll.UpdateMany(ctx, filter, op.Update())
+		if err != nil {
+			return err
+		}
+		p.Modified += res.ModifiedCount
+		return nil
+	}, opts...)
+}
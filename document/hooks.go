@@ -55,3 +55,95 @@ type BeforeSave interface {
 type AfterLoad interface {
 	AfterLoad(ctx context.Context) error
 }
+
+// UpdateEvent describes a filter-based update passed to the BeforeUpdate and
+// AfterUpdate hooks.
+//
+// Before the update runs, a hook may replace Filter or Update, e.g. to add a
+// tenant condition or an audit field. Matched and Modified are set only for
+// AfterUpdate.
+type UpdateEvent struct {
+	// Op is the operation name, e.g. "update_one" or "update_many".
+	Op string
+
+	Filter any
+	Update any
+
+	Matched  int64
+	Modified int64
+}
+
+// DeleteEvent describes a filter-based delete passed to the BeforeDelete and
+// AfterDelete hooks. A BeforeDelete hook may replace Filter; Deleted is set
+// only for AfterDelete.
+type DeleteEvent struct {
+	// Op is the operation name, e.g. "delete_one" or "delete_many".
+	Op string
+
+	Filter any
+
+	Deleted int64
+}
+
+// BeforeUpdate is an optional interface that documents can implement to
+// inspect or rewrite filter-based updates before they are sent to MongoDB.
+//
+// The BeforeUpdate hook is called automatically by the repository before:
+//   - UpdateOne and UpsertOne
+//   - UpdateMany
+//
+// Filter-based updates never load the documents they change, so the hook is
+// called on a zero value of the document type and should only use the event.
+// If BeforeUpdate returns an error, the operation is aborted and the error is
+// returned.
+//
+// Example:
+//
+//	func (*Order) BeforeUpdate(ctx context.Context, e *document.UpdateEvent) error {
+//	    if user, ok := auth.UserFrom(ctx); ok {
+//	        e.Update = spec.Combine(e.Update.(spec.Update), spec.Set("updated_by", user.ID))
+//	    }
+//	    return nil
+//	}
+type BeforeUpdate interface {
+	BeforeUpdate(ctx context.Context, e *UpdateEvent) error
+}
+
+// AfterUpdate is an optional interface that documents can implement to react
+// to filter-based updates, e.g. to write an audit entry.
+//
+// The AfterUpdate hook is called on a zero value of the document type after a
+// successful UpdateOne, UpsertOne or UpdateMany. If it returns an error, the
+// operation returns the error; the update itself is not rolled back.
+type AfterUpdate interface {
+	AfterUpdate(ctx context.Context, e *UpdateEvent) error
+}
+
+// BeforeDelete is an optional interface that documents can implement to
+// inspect or restrict filter-based deletes before they are sent to MongoDB.
+//
+// The BeforeDelete hook is called on a zero value of the document type before
+// DeleteOne and DeleteMany. If it returns an error, the operation is aborted
+// and the error is returned.
+//
+// Example:
+//
+//	func (*Invoice) BeforeDelete(ctx context.Context, e *document.DeleteEvent) error {
+//	    if !auth.IsAdmin(ctx) {
+//	        return errors.New("only admins may delete invoices")
+//	    }
+//	    return nil
+//	}
+type BeforeDelete interface {
+	BeforeDelete(ctx context.Context, e *DeleteEvent) error
+}
+
+// AfterDelete is an optional interface that documents can implement to react
+// to filter-based deletes.
+//
+// The AfterDelete hook is called on a zero value of the document type after a
+// successful DeleteOne or DeleteMany. If it returns an error, the operation
+// returns the error; the delete itself is not rolled back.
+type AfterDelete interface {
+	AfterDelete(ctx context.Context, e *DeleteEvent) error
+}
@@ -0,0 +1,184 @@
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// ToJSON encodes v, a document or a slice of documents, as the JSON an API
+// returns to its clients. It uses the bson tags, so a type needs no json tags:
+//
+//   - field names are the bson field names, in the same order
+//   - "_id" is renamed to "id"
+//   - ObjectIDs are 24-character hex strings
+//   - dates are RFC 3339 strings in UTC, e.g. "2026-01-02T15:04:05.123Z"
+//   - Decimal128 values are strings, to keep their precision
+//   - binary data is a base64 string
+//
+// Fields omitted from BSON, such as zero timestamps of Base tagged omitempty,
+// are omitted from the JSON too.
+//
+// Example:
+//
+//	user, err := repo.FindByID(ctx, id)
+//	...
+//	body, err := document.ToJSON(user)
+//	// {"id":"65a1...","created_at":"2026-01-02T15:04:05Z","updated_at":"...","name":"Ada"}
+func ToJSON(v any) ([]byte, error) {
+	raw, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		return nil, fmt.Errorf("document: ToJSON: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, bson.Raw(raw).Lookup("v")); err != nil {
+		return nil, fmt.Errorf("document: ToJSON: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// FromJSON decodes JSON in the format written by ToJSON into v, which must be
+// a pointer to a document or to a slice of documents. "id" is renamed to
+// "_id", and hex strings and RFC 3339 strings are accepted for ObjectID and
+// time.Time fields.
+//
+// Example:
+//
+//	var user User
+//	if err := document.FromJSON(body, &user); err != nil {
+//	    return err
+//	}
+func FromJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return fmt.Errorf("document: FromJSON: %w", err)
+	}
+	raw, err := bson.Marshal(bson.M{"v": fromJSONValue(val)})
+	if err != nil {
+		return fmt.Errorf("document: FromJSON: %w", err)
+	}
+	if err := bson.Raw(raw).Lookup("v").Unmarshal(v); err != nil {
+		return fmt.Errorf("document: FromJSON: %w", err)
+	}
+	return nil
+}
+
+// writeJSON writes the JSON form of a BSON value to buf.
+func writeJSON(buf *bytes.Buffer, v bson.RawValue) error {
+	switch v.Type {
+	case bsontype.EmbeddedDocument:
+		elems, err := v.Document().Elements()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, e := range elems {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key := e.Key()
+			if key == "_id" {
+				key = "id"
+			}
+			writeString(buf, key)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, e.Value()); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case bsontype.Array:
+		vals, err := v.Array().Values()
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('[')
+		for i, e := range vals {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case bsontype.ObjectID:
+		writeString(buf, v.ObjectID().Hex())
+	case bsontype.DateTime:
+		writeString(buf, time.UnixMilli(v.DateTime()).UTC().Format(time.RFC3339Nano))
+	case bsontype.Timestamp:
+		t, _ := v.Timestamp()
+		writeString(buf, time.Unix(int64(t), 0).UTC().Format(time.RFC3339))
+	case bsontype.String:
+		writeString(buf, v.StringValue())
+	case bsontype.Decimal128:
+		writeString(buf, v.Decimal128().String())
+	case bsontype.Int32:
+		buf.WriteString(strconv.FormatInt(int64(v.Int32()), 10))
+	case bsontype.Int64:
+		buf.WriteString(strconv.FormatInt(v.Int64(), 10))
+	case bsontype.Double:
+		b, err := json.Marshal(v.Double())
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	case bsontype.Boolean:
+		buf.WriteString(strconv.FormatBool(v.Boolean()))
+	case bsontype.Null, bsontype.Undefined:
+		buf.WriteString("null")
+	case bsontype.Binary:
+		_, data := v.Binary()
+		b, _ := json.Marshal(data)
+		buf.Write(b)
+	default:
+		return fmt.Errorf("BSON type %s has no JSON form", v.Type)
+	}
+	return nil
+}
+
+// writeString writes s to buf as a JSON string.
+func writeString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+// fromJSONValue converts a value decoded with json.Decoder.UseNumber into one
+// that marshals to BSON: objects become documents with "id" renamed to "_id",
+// and numbers become int64 when they are whole and float64 otherwise.
+func fromJSONValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		doc := make(bson.M, len(v))
+		for k, e := range v {
+			if k == "id" {
+				if _, ok := v["_id"]; !ok {
+					k = "_id"
+				}
+			}
+			doc[k] = fromJSONValue(e)
+		}
+		return doc
+	case []any:
+		arr := make(bson.A, len(v))
+		for i, e := range v {
+			arr[i] = fromJSONValue(e)
+		}
+		return arr
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
@@ -0,0 +1,90 @@
+package document_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type apiUser struct {
+	document.Base `bson:",inline"`
+	Name          string             `bson:"name"`
+	Age           int                `bson:"age"`
+	Score         float64            `bson:"score"`
+	ManagerID     primitive.ObjectID `bson:"manager_id,omitempty"`
+	LastLogin     *time.Time         `bson:"last_login"`
+	Tags          []string           `bson:"tags"`
+	Address       struct {
+		City string `bson:"city"`
+	} `bson:"address"`
+}
+
+func TestToJSON(t *testing.T) {
+	id, _ := primitive.ObjectIDFromHex("65a1f0c2e4b0a1b2c3d4e5f6")
+	u := apiUser{Name: "Ada", Age: 36, Score: 9.5, Tags: []string{"admin"}}
+	u.ID = id
+	u.CreatedAt = time.Date(2026, 1, 2, 15, 4, 5, 123e6, time.FixedZone("X", 3600))
+	u.Address.City = "London"
+
+	got, err := document.ToJSON(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"65a1f0c2e4b0a1b2c3d4e5f6","created_at":"2026-01-02T14:04:05.123Z",` +
+		`"name":"Ada","age":36,"score":9.5,"last_login":null,"tags":["admin"],"address":{"city":"London"}}`
+	if string(got) != want {
+		t.Fatalf("unexpected JSON.\n got: %s\nwant: %s", got, want)
+	}
+
+	list, err := document.ToJSON([]apiUser{u, u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(list) != "["+want+","+want+"]" {
+		t.Fatalf("unexpected JSON array: %s", list)
+	}
+}
+
+func TestFromJSON_RoundTrip(t *testing.T) {
+	login := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	u := apiUser{Name: "Ada", Age: 36, Score: 2, ManagerID: primitive.NewObjectID(), LastLogin: &login, Tags: []string{"a", "b"}}
+	u.TouchForInsert(time.Date(2026, 1, 2, 15, 4, 5, 123e6, time.UTC))
+	u.Address.City = "London"
+
+	data, err := document.ToJSON(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got apiUser
+	if err := document.FromJSON(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, u) {
+		t.Fatalf("round trip mismatch.\n got: %+v\nwant: %+v", got, u)
+	}
+
+	var list []apiUser
+	if err := document.FromJSON([]byte("["+string(data)+"]"), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != u.ID {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+}
+
+func TestFromJSON_Errors(t *testing.T) {
+	var u apiUser
+	if err := document.FromJSON([]byte(`{"id":"not-an-id"}`), &u); err == nil {
+		t.Fatal("expected an error for an invalid id")
+	}
+	if err := document.FromJSON([]byte(`{"created_at":"yesterday"}`), &u); err == nil {
+		t.Fatal("expected an error for an invalid timestamp")
+	}
+	if err := document.FromJSON([]byte(`{`), &u); err == nil {
+		t.Fatal("expected an error for malformed JSON")
+	}
+}
@@ -0,0 +1,72 @@
+package document
+
+import (
+	"context"
+	"strings"
+)
+
+// Localized is a text field with one value per locale, stored as a
+// subdocument keyed by locale tag, e.g. {"en": "Shoes", "pt-BR": "Sapatos"}.
+//
+// Resolve a value for the locale of the current request in an AfterLoad hook,
+// with the locales set on the context by WithLocale:
+//
+//	type Product struct {
+//	    Base `bson:",inline"`
+//	    Name Localized `bson:"name"`
+//
+//	    DisplayName string `bson:"-"`
+//	}
+//
+//	func (p *Product) AfterLoad(ctx context.Context) error {
+//	    p.DisplayName = p.Name.Resolve(ctx)
+//	    return nil
+//	}
+//
+//	products, err := repo.Find(document.WithLocale(ctx, "pt-BR", "en"), filter)
+//
+// Use spec.LocaleField to query a single locale.
+type Localized map[string]string
+
+// Get returns the value for the first of locales that has one. For each
+// locale, an exact match is tried first, then its base language ("pt" for
+// "pt-BR"). It returns "" if no locale has a value.
+func (l Localized) Get(locales ...string) string {
+	v, _ := l.Lookup(locales...)
+	return v
+}
+
+// Lookup is like Get but also reports whether a value was found.
+func (l Localized) Lookup(locales ...string) (string, bool) {
+	for _, loc := range locales {
+		if v, ok := l[loc]; ok {
+			return v, true
+		}
+		if base, _, ok := strings.Cut(loc, "-"); ok {
+			if v, ok := l[base]; ok {
+				return v, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Resolve returns the value for the locales of ctx, as set by WithLocale.
+// It returns "" if ctx has no locales or none of them has a value.
+func (l Localized) Resolve(ctx context.Context) string {
+	return l.Get(LocalesFromContext(ctx)...)
+}
+
+type localeKey struct{}
+
+// WithLocale returns a context carrying locales in order of preference, e.g.
+// from an Accept-Language header followed by the application default.
+func WithLocale(ctx context.Context, locales ...string) context.Context {
+	return context.WithValue(ctx, localeKey{}, append([]string(nil), locales...))
+}
+
+// LocalesFromContext returns the locales set by WithLocale, or nil.
+func LocalesFromContext(ctx context.Context) []string {
+	locales, _ := ctx.Value(localeKey{}).([]string)
+	return locales
+}
@@ -0,0 +1,48 @@
+package document_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+)
+
+func TestLocalized_Get(t *testing.T) {
+	name := document.Localized{"en": "Shoes", "pt": "Sapatos", "fr-CA": "Souliers"}
+
+	tests := []struct {
+		locales []string
+		want    string
+	}{
+		{[]string{"pt"}, "Sapatos"},
+		{[]string{"pt-BR"}, "Sapatos"},
+		{[]string{"de", "en"}, "Shoes"},
+		{[]string{"fr-CA"}, "Souliers"},
+		{[]string{"fr"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := name.Get(tt.locales...); got != tt.want {
+			t.Errorf("Get(%v) = %q, want %q", tt.locales, got, tt.want)
+		}
+	}
+
+	if _, ok := name.Lookup("de"); ok {
+		t.Error("Lookup(de) reported a value")
+	}
+}
+
+func TestLocalized_Resolve(t *testing.T) {
+	name := document.Localized{"en": "Shoes", "pt": "Sapatos"}
+
+	if got := name.Resolve(context.Background()); got != "" {
+		t.Fatalf("Resolve without locales = %q, want empty", got)
+	}
+	ctx := document.WithLocale(context.Background(), "pt-BR", "en")
+	if got := name.Resolve(ctx); got != "Sapatos" {
+		t.Fatalf("Resolve = %q, want Sapatos", got)
+	}
+	if got := document.LocalesFromContext(ctx); len(got) != 2 || got[0] != "pt-BR" {
+		t.Fatalf("LocalesFromContext = %v", got)
+	}
+}
@@ -0,0 +1,102 @@
+package document
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// CollectionNamer can be implemented by documents to choose their collection
+// name instead of the naming convention.
+//
+// Example:
+//
+//	func (Person) CollectionName() string { return "people" }
+type CollectionNamer interface {
+	CollectionName() string
+}
+
+var collectionNames sync.Map // reflect.Type -> string
+
+// RegisterCollectionName makes CollectionName return name for T. Use it for
+// types from other packages, which cannot implement CollectionNamer. It takes
+// precedence over CollectionNamer and is safe for concurrent use.
+//
+// Example:
+//
+//	document.RegisterCollectionName[billing.Invoice]("billing_invoices")
+func RegisterCollectionName[T any](name string) {
+	collectionNames.Store(reflect.TypeOf((*T)(nil)).Elem(), name)
+}
+
+// CollectionName returns the collection that stores documents of type T: the
+// name registered with RegisterCollectionName, else the result of T's
+// CollectionName method, else DefaultCollectionName of T's type name.
+//
+// Example:
+//
+//	document.CollectionName[User]()        // "users"
+//	document.CollectionName[OrderItem]()   // "order_items"
+//	document.CollectionName[Person]()      // "people", via CollectionNamer
+func CollectionName[T any]() string {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if name, ok := collectionNames.Load(t); ok {
+		return name.(string)
+	}
+	if n, ok := any(new(T)).(CollectionNamer); ok {
+		return n.CollectionName()
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return DefaultCollectionName(t.Name())
+}
+
+// DefaultCollectionName applies the naming convention to a Go type name: it is
+// converted to snake_case and its last word pluralized. Type parameters of
+// generic types are dropped.
+//
+// Example:
+//
+//	DefaultCollectionName("User")        // "users"
+//	DefaultCollectionName("HTTPRequest") // "http_requests"
+//	DefaultCollectionName("Category")    // "categories"
+func DefaultCollectionName(typeName string) string {
+	if i := strings.IndexByte(typeName, '['); i >= 0 {
+		typeName = typeName[:i]
+	}
+	return pluralize(snakeCase(typeName))
+}
+
+// snakeCase converts a CamelCase name to snake_case, keeping acronyms together.
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// pluralize applies the regular English plural rules to the last word of s.
+func pluralize(s string) string {
+	switch {
+	case s == "":
+		return s
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	default:
+		return s + "s"
+	}
+}
@@ -0,0 +1,58 @@
+package document_test
+
+import (
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+)
+
+type OrderItem struct{}
+
+type Person struct{}
+
+func (Person) CollectionName() string { return "people" }
+
+type auditEntry struct{}
+
+type Box[T any] struct{ Value T }
+
+func TestDefaultCollectionName(t *testing.T) {
+	tests := map[string]string{
+		"User":        "users",
+		"OrderItem":   "order_items",
+		"HTTPRequest": "http_requests",
+		"UserID":      "user_ids",
+		"Category":    "categories",
+		"Day":         "days",
+		"Address":     "addresses",
+		"Box":         "boxes",
+		"Match":       "matches",
+		"Page[int]":   "pages",
+		"":            "",
+	}
+	for in, want := range tests {
+		if got := document.DefaultCollectionName(in); got != want {
+			t.Errorf("DefaultCollectionName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCollectionName(t *testing.T) {
+	if got := document.CollectionName[OrderItem](); got != "order_items" {
+		t.Errorf("convention: got %q", got)
+	}
+	if got := document.CollectionName[*OrderItem](); got != "order_items" {
+		t.Errorf("pointer type: got %q", got)
+	}
+	if got := document.CollectionName[Person](); got != "people" {
+		t.Errorf("CollectionNamer: got %q", got)
+	}
+	if got := document.CollectionName[Box[int]](); got != "boxes" {
+		t.Errorf("generic type: got %q", got)
+	}
+
+	document.RegisterCollectionName[auditEntry]("audit_log")
+	if got := document.CollectionName[auditEntry](); got != "audit_log" {
+		t.Errorf("registered: got %q", got)
+	}
+}
@@ -0,0 +1,321 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidSchemaTag is returned by JSONSchema for malformed schema tags.
+var ErrInvalidSchemaTag = errors.New("document: invalid schema tag")
+
+// JSONSchema derives a MongoDB $jsonSchema validator from the stored fields of
+// T, so the server enforces the same shape as the Go type. Use it with
+// MongoRepository.EnsureValidator, or pass it to CreateCollection or collMod.
+//
+// Field names follow the driver's rules (bson tag or lowercased Go name,
+// inline structs flattened), and each field is constrained to the BSON types
+// its Go type is stored as. Pointers, slices, and maps also allow null, since
+// nil values are stored as null. Fields of interface types and of types with
+// custom BSON marshalers are not type-checked. Fields not in T are allowed.
+//
+// Further constraints come from the schema tag, a comma-separated list of:
+//   - required: the field must be present
+//   - enum=a|b|c: the value must be one of the listed values
+//   - min=n, max=n: numeric bounds (minimum, maximum)
+//   - minLength=n, maxLength=n: string length bounds
+//   - pattern=re: a regular expression strings must match; it must come last
+//     and may contain commas
+//
+// Example:
+//
+//	type User struct {
+//	    document.Base `bson:",inline"`
+//	    Email  string `bson:"email" schema:"required,pattern=^[^@]+@[^@]+$"`
+//	    Age    int    `bson:"age" schema:"min=0,max=150"`
+//	    Status string `bson:"status" schema:"required,enum=active|banned"`
+//	}
+//
+//	validator, err := document.JSONSchema[User]()
+//	// {"$jsonSchema": {"bsonType": "object", "required": ["email", "status"], "properties": {...}}}
+func JSONSchema[T any]() (bson.M, error) {
+	schema, err := objectSchema(reflect.TypeOf((*T)(nil)).Elem(), map[reflect.Type]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$jsonSchema": schema}, nil
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	dateTimeType   = reflect.TypeOf(primitive.DateTime(0))
+	objectIDType   = reflect.TypeOf(primitive.ObjectID{})
+	decimalType    = reflect.TypeOf(primitive.Decimal128{})
+	marshalerType  = reflect.TypeOf((*bson.Marshaler)(nil)).Elem()
+	valueMarshaler = reflect.TypeOf((*bson.ValueMarshaler)(nil)).Elem()
+)
+
+// objectSchema returns the schema of struct type t. seen stops recursive
+// types, whose nested occurrences are only checked to be objects.
+func objectSchema(t reflect.Type, seen map[reflect.Type]bool) (bson.M, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schema := bson.M{"bsonType": "object"}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return schema, nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	props := bson.M{}
+	var required []string
+	if err := addProperties(t, seen, props, &required); err != nil {
+		return nil, err
+	}
+	if len(props) > 0 {
+		schema["properties"] = props
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+// addProperties adds the stored fields of struct type t, including those of
+// inline structs, to props.
+func addProperties(t reflect.Type, seen map[reflect.Type]bool, props bson.M, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("bson")
+		if tag == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(","+opts+",", ",inline,") {
+			ft := sf.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addProperties(ft, seen, props, required); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		prop, err := valueSchema(sf.Type, seen)
+		if err != nil {
+			return err
+		}
+		isRequired, err := applySchemaTag(prop, sf)
+		if err != nil {
+			return err
+		}
+		props[name] = prop
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// valueSchema returns the schema of a value of type t.
+func valueSchema(t reflect.Type, seen map[reflect.Type]bool) (bson.M, error) {
+	if t.Implements(marshalerType) || t.Implements(valueMarshaler) ||
+		reflect.PointerTo(t).Implements(marshalerType) || reflect.PointerTo(t).Implements(valueMarshaler) {
+		return bson.M{}, nil
+	}
+	switch t {
+	case timeType, dateTimeType:
+		return bson.M{"bsonType": "date"}, nil
+	case objectIDType:
+		return bson.M{"bsonType": "objectId"}, nil
+	case decimalType:
+		return bson.M{"bsonType": "decimal"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s, err := valueSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return nullable(s), nil
+	case reflect.String:
+		return bson.M{"bsonType": "string"}, nil
+	case reflect.Bool:
+		return bson.M{"bsonType": "bool"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return bson.M{"bsonType": "int"}, nil
+	case reflect.Int:
+		return bson.M{"bsonType": bson.A{"int", "long"}}, nil // int32 when the value fits
+	case reflect.Int64, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return bson.M{"bsonType": "long"}, nil
+	case reflect.Float32, reflect.Float64:
+		return bson.M{"bsonType": "double"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return nullable(bson.M{"bsonType": "binData"}), nil
+		}
+		items, err := valueSchema(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		s := bson.M{"bsonType": "array"}
+		if len(items) > 0 {
+			s["items"] = items
+		}
+		if t.Kind() == reflect.Slice {
+			return nullable(s), nil
+		}
+		return s, nil
+	case reflect.Map:
+		return nullable(bson.M{"bsonType": "object"}), nil
+	case reflect.Struct:
+		return objectSchema(t, seen)
+	default:
+		return bson.M{}, nil
+	}
+}
+
+// nullable adds null to the allowed types of s.
+func nullable(s bson.M) bson.M {
+	switch bt := s["bsonType"].(type) {
+	case string:
+		s["bsonType"] = bson.A{bt, "null"}
+	case bson.A:
+		s["bsonType"] = append(bt, "null")
+	}
+	return s
+}
+
+// SchemaRules are the constraints of a field's schema tag; see JSONSchema for
+// the syntax.
+type SchemaRules struct {
+	Required             bool
+	Enum                 []any // values of the field's type
+	Minimum, Maximum     any   // int64 or float64; nil if not set
+	MinLength, MaxLength *int64
+	Pattern              string
+}
+
+// ParseSchemaTag parses the schema tag of sf. It is used by JSONSchema and by
+// generators of other schema formats, so they share one syntax.
+func ParseSchemaTag(sf reflect.StructField) (SchemaRules, error) {
+	var rules SchemaRules
+	tag, ok := sf.Tag.Lookup("schema")
+	if !ok || tag == "" {
+		return rules, nil
+	}
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "pattern=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		key, value, hasValue := strings.Cut(part, "=")
+		var err error
+		switch {
+		case key == "required" && !hasValue:
+			rules.Required = true
+		case key == "enum" && hasValue:
+			rules.Enum, err = enumValues(sf.Type, value)
+		case key == "min" && hasValue:
+			rules.Minimum, err = parseNumber(value)
+		case key == "max" && hasValue:
+			rules.Maximum, err = parseNumber(value)
+		case (key == "minLength" || key == "maxLength") && hasValue:
+			var n int64
+			n, err = strconv.ParseInt(value, 10, 64)
+			if key == "minLength" {
+				rules.MinLength = &n
+			} else {
+				rules.MaxLength = &n
+			}
+		case key == "pattern" && hasValue:
+			rules.Pattern = value
+		default:
+			err = errors.New("unknown option")
+		}
+		if err != nil {
+			return SchemaRules{}, fmt.Errorf("%w: field %s: %q: %v", ErrInvalidSchemaTag, sf.Name, part, err)
+		}
+	}
+	return rules, nil
+}
+
+// applySchemaTag adds the constraints of sf's schema tag to prop and reports
+// whether the field is required.
+func applySchemaTag(prop bson.M, sf reflect.StructField) (bool, error) {
+	rules, err := ParseSchemaTag(sf)
+	if err != nil {
+		return false, err
+	}
+	if rules.Enum != nil {
+		prop["enum"] = bson.A(rules.Enum)
+	}
+	if rules.Minimum != nil {
+		prop["minimum"] = rules.Minimum
+	}
+	if rules.Maximum != nil {
+		prop["maximum"] = rules.Maximum
+	}
+	if rules.MinLength != nil {
+		prop["minLength"] = *rules.MinLength
+	}
+	if rules.MaxLength != nil {
+		prop["maxLength"] = *rules.MaxLength
+	}
+	if rules.Pattern != "" {
+		prop["pattern"] = rules.Pattern
+	}
+	return rules.Required, nil
+}
+
+// enumValues parses a |-separated enum as values of the field's type.
+func enumValues(t reflect.Type, list string) ([]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var out []any
+	for _, v := range strings.Split(list, "|") {
+		switch t.Kind() {
+		case reflect.String:
+			out = append(out, v)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			n, err := parseNumber(v)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, n)
+		default:
+			return nil, fmt.Errorf("enum is not supported for %s", t)
+		}
+	}
+	return out, nil
+}
+
+// parseNumber parses an integer as int64 and other numbers as float64.
+func parseNumber(s string) (any, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
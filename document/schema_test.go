@@ -0,0 +1,81 @@
+package document_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type schemaAddress struct {
+	City string `bson:"city" schema:"required"`
+}
+
+type schemaUser struct {
+	document.Base `bson:",inline"`
+	Email         string            `bson:"email" schema:"required,pattern=^[a-z]{1,3},x$"`
+	Age           int               `bson:"age" schema:"min=0,max=150"`
+	Status        string            `bson:"status" schema:"required,enum=active|banned"`
+	Score         float64           `bson:"score,omitempty"`
+	Tags          []string          `bson:"tags"`
+	Address       *schemaAddress    `bson:"address,omitempty"`
+	Attrs         map[string]string `bson:"attrs"`
+	LastLogin     *time.Time        `bson:"last_login"`
+	Nickname      string            `schema:"minLength=2,maxLength=20"`
+	Extra         any               `bson:"extra"`
+	Secret        string            `bson:"-"`
+}
+
+func TestJSONSchema(t *testing.T) {
+	got, err := document.JSONSchema[schemaUser]()
+	if err != nil {
+		t.Fatalf("JSONSchema: %v", err)
+	}
+	want := bson.M{"$jsonSchema": bson.M{
+		"bsonType": "object",
+		"required": []string{"email", "status"},
+		"properties": bson.M{
+			"_id":        bson.M{"bsonType": "objectId"},
+			"created_at": bson.M{"bsonType": "date"},
+			"updated_at": bson.M{"bsonType": "date"},
+			"email":      bson.M{"bsonType": "string", "pattern": "^[a-z]{1,3},x$"},
+			"age":        bson.M{"bsonType": bson.A{"int", "long"}, "minimum": int64(0), "maximum": int64(150)},
+			"status":     bson.M{"bsonType": "string", "enum": bson.A{"active", "banned"}},
+			"score":      bson.M{"bsonType": "double"},
+			"tags":       bson.M{"bsonType": bson.A{"array", "null"}, "items": bson.M{"bsonType": "string"}},
+			"address": bson.M{
+				"bsonType":   bson.A{"object", "null"},
+				"required":   []string{"city"},
+				"properties": bson.M{"city": bson.M{"bsonType": "string"}},
+			},
+			"attrs":      bson.M{"bsonType": bson.A{"object", "null"}},
+			"last_login": bson.M{"bsonType": bson.A{"date", "null"}},
+			"nickname":   bson.M{"bsonType": "string", "minLength": int64(2), "maxLength": int64(20)},
+			"extra":      bson.M{},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("JSONSchema mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+type schemaBadTag struct {
+	Status string `bson:"status" schema:"requird"`
+}
+
+type schemaBadEnum struct {
+	Level int `bson:"level" schema:"enum=1|two"`
+}
+
+func TestJSONSchema_InvalidTags(t *testing.T) {
+	if _, err := document.JSONSchema[schemaBadTag](); !errors.Is(err, document.ErrInvalidSchemaTag) {
+		t.Fatalf("unknown option: err = %v", err)
+	}
+	if _, err := document.JSONSchema[schemaBadEnum](); !errors.Is(err, document.ErrInvalidSchemaTag) {
+		t.Fatalf("non-numeric enum: err = %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package document
+
+// VersionField is the BSON field that stores the version of Versioned documents.
+const VersionField = "version"
+
+// Versioned can be embedded in documents to enable optimistic locking.
+// Repositories start the version at 1 on insert and increment it on every
+// update, and ReplaceOne only replaces the document if its stored version still
+// matches, so an editor working on a stale copy gets
+// repository.ErrVersionConflict instead of silently overwriting newer changes.
+//
+// Example:
+//
+//	type Article struct {
+//	    document.Base      `bson:",inline"`
+//	    document.Versioned `bson:",inline"`
+//	    Title string `bson:"title"`
+//	}
+type Versioned struct {
+	Version int64 `bson:"version" json:"version"`
+}
+
+// CurrentVersion returns the version the document was loaded with.
+func (v *Versioned) CurrentVersion() int64 {
+	if v == nil {
+		return 0
+	}
+	return v.Version
+}
+
+// SetVersion sets the version. Repositories call it after a successful write;
+// application code normally doesn't.
+func (v *Versioned) SetVersion(n int64) {
+	if v == nil {
+		return
+	}
+	v.Version = n
+}
+
+// VersionedDoc is an interface for documents that support optimistic locking.
+type VersionedDoc interface {
+	CurrentVersion() int64
+	SetVersion(n int64)
+}
@@ -0,0 +1,28 @@
+package document_test
+
+import (
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+)
+
+func TestVersioned(t *testing.T) {
+	t.Run("set and current", func(t *testing.T) {
+		v := &document.Versioned{}
+		if v.CurrentVersion() != 0 {
+			t.Fatalf("expected version 0, got %d", v.CurrentVersion())
+		}
+		v.SetVersion(3)
+		if v.CurrentVersion() != 3 {
+			t.Fatalf("expected version 3, got %d", v.CurrentVersion())
+		}
+	})
+
+	t.Run("nil receiver", func(t *testing.T) {
+		var v *document.Versioned
+		v.SetVersion(1)
+		if v.CurrentVersion() != 0 {
+			t.Fatal("expected CurrentVersion() to be 0 for nil receiver")
+		}
+	})
+}
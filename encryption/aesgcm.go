@@ -0,0 +1,124 @@
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownKey is returned when a key provider has no key with the
+// requested ID.
+var ErrUnknownKey = errors.New("encryption: unknown key")
+
+// ErrDecrypt is returned when a value cannot be decrypted because it is
+// malformed, was encrypted with another key or additional data, or was
+// tampered with.
+var ErrDecrypt = errors.New("encryption: cannot decrypt value")
+
+// KeyProvider supplies the AES keys of NewAESGCM by ID. Keys are 16, 24, or
+// 32 bytes long, for AES-128, AES-192, or AES-256. Implementations must be
+// safe for concurrent use.
+type KeyProvider interface {
+	// CurrentKey returns the key new values are encrypted with, and its ID.
+	CurrentKey(ctx context.Context) (id string, key []byte, err error)
+
+	// Key returns the key with the given ID, for decrypting values written
+	// with it, or an error matching ErrUnknownKey.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// aesGCM is the Encryptor returned by NewAESGCM.
+type aesGCM struct {
+	keys  KeyProvider
+	aeads sync.Map // key ID -> cipher.AEAD
+}
+
+// NewAESGCM returns an Encryptor using AES in GCM mode, which also detects
+// tampering. Each value records the ID of the key it was encrypted with, so
+// keys can be rotated by changing the provider's current key while keeping
+// the old ones for reading.
+func NewAESGCM(keys KeyProvider) Encryptor {
+	return &aesGCM{keys: keys}
+}
+
+// Encrypted values are laid out as: version (1 byte), key ID length (1 byte),
+// key ID, nonce, and the sealed plaintext. The additional data is
+// authenticated with GCM but not stored.
+const aesGCMVersion = 1
+
+func (e *aesGCM) Encrypt(ctx context.Context, plaintext, additionalData []byte) ([]byte, error) {
+	id, key, err := e.keys.CurrentKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("encryption: key ID %q is longer than 255 bytes", id)
+	}
+	aead, err := e.aead(id, key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 2+len(id)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out = append(out, aesGCMVersion, byte(len(id)))
+	out = append(out, id...)
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, additionalData), nil
+}
+
+func (e *aesGCM) Decrypt(ctx context.Context, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < 2 || ciphertext[0] != aesGCMVersion || len(ciphertext) < 2+int(ciphertext[1]) {
+		return nil, ErrDecrypt
+	}
+	idLen := int(ciphertext[1])
+	id := string(ciphertext[2 : 2+idLen])
+	rest := ciphertext[2+idLen:]
+
+	aead, err := e.aead(id, nil)
+	if err != nil {
+		key, kerr := e.keys.Key(ctx, id)
+		if kerr != nil {
+			return nil, kerr
+		}
+		if aead, err = e.aead(id, key); err != nil {
+			return nil, err
+		}
+	}
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	pt, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return pt, nil
+}
+
+// aead returns the cached cipher of key ID id, creating it from key if it is
+// not nil.
+func (e *aesGCM) aead(id string, key []byte) (cipher.AEAD, error) {
+	if a, ok := e.aeads.Load(id); ok {
+		return a.(cipher.AEAD), nil
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: key %s: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	e.aeads.Store(id, aead)
+	return aead, nil
+}
@@ -0,0 +1,358 @@
+// Package encryption encrypts document fields on the client, so values such
+// as personal data are stored as ciphertext that the database, its backups,
+// and its operators cannot read.
+//
+// Mark string or []byte fields with the mongox:"encrypt" tag and configure the
+// repository with mongorepo.WithEncryption. Marked fields are encrypted after
+// BeforeSave when documents are written and decrypted before AfterLoad when
+// they are read; the caller's documents keep their plaintext.
+//
+// Encrypted values are stored as BSON binary values of subtype Subtype, which
+// no string or []byte field encodes to, so a caller's value is always
+// encrypted and never mistaken for ciphertext. Each value is bound to its
+// field path and, when the document's _id is known as it is written, to that
+// _id: ciphertext copied to another field or document fails to decrypt.
+// Values written by updates are bound to their field only.
+//
+// Encrypted values use a random nonce, so equal plaintexts give different
+// ciphertexts: marked fields cannot be queried, sorted, or indexed. The
+// repository encrypts the values $set and $setOnInsert write to marked fields
+// and rejects other update operators on them. Values written before a field
+// was marked are read back unchanged.
+//
+// Example:
+//
+//	type Patient struct {
+//	    document.Base `bson:",inline"`
+//	    Name  string `bson:"name"`
+//	    SSN   string `bson:"ssn" mongox:"encrypt"`
+//	    Notes []byte `bson:"notes" mongox:"encrypt"`
+//	}
+//
+//	enc := encryption.NewAESGCM(encryption.StaticKeys("2026-01", map[string][]byte{
+//	    "2026-01": key, // 32 random bytes from a secret manager
+//	}))
+//	repo := mongorepo.New[Patient](coll, mongorepo.WithEncryption(enc))
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Encryptor encrypts and decrypts field values. The additional data is
+// authenticated but not encrypted: a value only decrypts with the additional
+// data it was encrypted with. Implementations must be safe for concurrent use.
+type Encryptor interface {
+	Encrypt(ctx context.Context, plaintext, additionalData []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// ErrUnsupportedField is returned for a field marked for encryption whose
+// type is not string, *string, or []byte.
+var ErrUnsupportedField = errors.New("encryption: only string and []byte fields can be encrypted")
+
+// Subtype is the BSON binary subtype encrypted values are stored as, from the
+// range reserved for user-defined subtypes.
+const Subtype byte = 0x80
+
+// Stored values are laid out as: the BSON type of the plaintext (1 byte),
+// whether the value is bound to the document's _id (1 byte), and the
+// ciphertext.
+const (
+	headerLen = 2
+	unbound   = 0
+	bound     = 1
+)
+
+// EncryptValue encrypts v, a string, *string, or []byte written to the marked
+// field at path, into the form the field is stored in, for use in update
+// operators. The value is bound to path, but not to a document. Nil pointers
+// and nil slices are returned as nil.
+//
+// Example:
+//
+//	ssn, err := encryption.EncryptValue(ctx, enc, "ssn", "123-45-6789")
+func EncryptValue(ctx context.Context, enc Encryptor, path string, v any) (any, error) {
+	return encrypt(ctx, enc, path, nil, v)
+}
+
+// EncryptDocument encrypts, in place, the values of doc at paths, the marked
+// fields of its type as returned by Paths. Values are bound to their path and
+// to the _id of doc, if it has one. Missing and null values are left as they
+// are.
+func EncryptDocument(ctx context.Context, enc Encryptor, doc bson.D, paths []string) error {
+	id := lookup(doc, "_id")
+	for _, p := range paths {
+		e := lookup(doc, p)
+		if e == nil {
+			continue
+		}
+		var idv any
+		if id != nil {
+			idv = id.Value
+		}
+		v, err := encrypt(ctx, enc, p, idv, e.Value)
+		if err != nil {
+			return fmt.Errorf("encryption: field %s: %w", p, err)
+		}
+		e.Value = v
+	}
+	return nil
+}
+
+// DecryptDocument decrypts, in place, the values of doc at paths, the marked
+// fields of its type as returned by Paths. Values that are not encrypted, such
+// as those written before a field was marked, are left as they are.
+func DecryptDocument(ctx context.Context, enc Encryptor, doc bson.D, paths []string) error {
+	id := lookup(doc, "_id")
+	for _, p := range paths {
+		e := lookup(doc, p)
+		if e == nil {
+			continue
+		}
+		b, ok := e.Value.(primitive.Binary)
+		if !ok || b.Subtype != Subtype {
+			continue
+		}
+		if len(b.Data) < headerLen {
+			return fmt.Errorf("encryption: field %s: %w", p, ErrDecrypt)
+		}
+		var idv any
+		if b.Data[1] == bound {
+			if id == nil {
+				return fmt.Errorf("encryption: field %s: %w: the value is bound to the document _id, which is missing", p, ErrDecrypt)
+			}
+			idv = id.Value
+		}
+		ad, err := additionalData(b.Data[:headerLen], p, idv)
+		if err != nil {
+			return fmt.Errorf("encryption: field %s: %w", p, err)
+		}
+		pt, err := enc.Decrypt(ctx, b.Data[headerLen:], ad)
+		if err != nil {
+			return fmt.Errorf("encryption: field %s: %w", p, err)
+		}
+		switch bsontype.Type(b.Data[0]) {
+		case bsontype.String:
+			e.Value = string(pt)
+		case bsontype.Binary:
+			e.Value = primitive.Binary{Data: pt}
+		default:
+			return fmt.Errorf("encryption: field %s: %w", p, ErrDecrypt)
+		}
+	}
+	return nil
+}
+
+// encrypt encrypts v, the value at path, binding it to path and, if it is not
+// nil, to the document _id id.
+func encrypt(ctx context.Context, enc Encryptor, path string, id, v any) (any, error) {
+	var (
+		kind bsontype.Type
+		pt   []byte
+	)
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		kind, pt = bsontype.String, []byte(v)
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		kind, pt = bsontype.String, []byte(*v)
+	case []byte:
+		if v == nil {
+			return nil, nil
+		}
+		kind, pt = bsontype.Binary, v
+	case primitive.Binary:
+		if v.Subtype != bsontype.BinaryGeneric {
+			return nil, fmt.Errorf("%w: got binary subtype %#x", ErrUnsupportedField, v.Subtype)
+		}
+		kind, pt = bsontype.Binary, v.Data
+	default:
+		return nil, fmt.Errorf("%w: got %T", ErrUnsupportedField, v)
+	}
+
+	header := []byte{byte(kind), unbound}
+	if id != nil {
+		header[1] = bound
+	}
+	ad, err := additionalData(header, path, id)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := enc.Encrypt(ctx, pt, ad)
+	if err != nil {
+		return nil, err
+	}
+	return primitive.Binary{Subtype: Subtype, Data: append(header, ct...)}, nil
+}
+
+// additionalData returns the data a stored value is bound to: its header,
+// its path, and the encoded _id of its document if id is not nil.
+func additionalData(header []byte, path string, id any) ([]byte, error) {
+	ad := append(append([]byte(nil), header...), path...)
+	if id == nil {
+		return ad, nil
+	}
+	t, data, err := bson.MarshalValue(id)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: encode _id: %w", err)
+	}
+	ad = append(ad, 0, byte(t))
+	return append(ad, data...), nil
+}
+
+// lookup returns the element of doc at the dotted path, or nil if there is
+// none.
+func lookup(doc bson.D, path string) *bson.E {
+	key, rest, deeper := strings.Cut(path, ".")
+	for i := range doc {
+		if doc[i].Key != key {
+			continue
+		}
+		if !deeper {
+			return &doc[i]
+		}
+		if sub, ok := doc[i].Value.(bson.D); ok {
+			return lookup(sub, rest)
+		}
+		return nil
+	}
+	return nil
+}
+
+// Paths returns the dotted BSON paths of the fields of t (a struct or a
+// pointer to one) marked for encryption, including those of nested structs.
+func Paths(t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if !HasEncryptedFields(t) {
+		return nil
+	}
+	if v, ok := markedPaths.Load(t); ok {
+		return v.([]string)
+	}
+	var paths []string
+	appendPaths(&paths, t, "", map[reflect.Type]bool{})
+	markedPaths.Store(t, paths)
+	return paths
+}
+
+// markedPaths caches Paths by type.
+var markedPaths sync.Map
+
+// appendPaths adds the paths of the marked fields of struct type t, each
+// prefixed with prefix, to paths. seen guards against recursive types.
+func appendPaths(paths *[]string, t reflect.Type, prefix string, seen map[reflect.Type]bool) {
+	if seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, inline, skip := bsonName(sf)
+		if skip {
+			continue
+		}
+		if marked(sf) {
+			*paths = append(*paths, prefix+name)
+			continue
+		}
+		nt := nested(sf.Type)
+		if nt == nil || !(sf.IsExported() || sf.Anonymous) || !HasEncryptedFields(nt) {
+			continue
+		}
+		if inline {
+			appendPaths(paths, nt, prefix, seen)
+		} else {
+			appendPaths(paths, nt, prefix+name+".", seen)
+		}
+	}
+}
+
+// bsonName returns the key of sf in BSON documents as the driver encodes it,
+// whether it is inlined, and whether it is not encoded at all.
+func bsonName(sf reflect.StructField) (name string, inline, skip bool) {
+	tag := sf.Tag.Get("bson")
+	if tag == "-" || !sf.IsExported() && !sf.Anonymous {
+		return "", false, true
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "inline" {
+			inline = true
+		}
+	}
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+	return name, inline, false
+}
+
+// HasEncryptedFields reports whether values of type t (a struct or a pointer
+// to one) have fields marked for encryption, directly or in nested structs.
+func HasEncryptedFields(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	if v, ok := markedTypes.Load(t); ok {
+		return v.(bool)
+	}
+	markedTypes.Store(t, false) // for recursive types
+	has := false
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if marked(sf) || (sf.IsExported() || sf.Anonymous) && nested(sf.Type) != nil && HasEncryptedFields(sf.Type) {
+			has = true
+			break
+		}
+	}
+	markedTypes.Store(t, has)
+	return has
+}
+
+// markedTypes caches HasEncryptedFields by type.
+var markedTypes sync.Map
+
+// marked reports whether sf has the mongox:"encrypt" tag.
+func marked(sf reflect.StructField) bool {
+	for _, opt := range strings.Split(sf.Tag.Get("mongox"), ",") {
+		if opt == "encrypt" {
+			return sf.IsExported()
+		}
+	}
+	return false
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// nested returns the struct type of a struct or pointer-to-struct field
+// whose fields are walked, or nil.
+func nested(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,352 @@
+package encryption_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/encryption"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type address struct {
+	Street string `bson:"street" mongox:"encrypt"`
+	City   string `bson:"city"`
+}
+
+type patient struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"`
+	Name    string             `bson:"name"`
+	SSN     string             `bson:"ssn" mongox:"encrypt"`
+	Phone   *string            `bson:"phone" mongox:"encrypt"`
+	Notes   []byte             `bson:"notes" mongox:"encrypt"`
+	Address *address           `bson:"address"`
+}
+
+func key(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+
+func newEncryptor() encryption.Encryptor {
+	return encryption.NewAESGCM(encryption.StaticKeys("k1", map[string][]byte{"k1": key(1)}))
+}
+
+func TestAESGCM_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	enc := newEncryptor()
+
+	a, err := enc.Encrypt(ctx, []byte("secret"), []byte("ssn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := enc.Encrypt(ctx, []byte("secret"), []byte("ssn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a, b) {
+		t.Fatal("expected different ciphertexts for equal plaintexts")
+	}
+	pt, err := enc.Decrypt(ctx, a, []byte("ssn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pt) != "secret" {
+		t.Fatalf("expected secret, got %q", pt)
+	}
+
+	if _, err := enc.Decrypt(ctx, a, []byte("phone")); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for other additional data, got %v", err)
+	}
+	a[len(a)-1] ^= 1
+	if _, err := enc.Decrypt(ctx, a, []byte("ssn")); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a tampered value, got %v", err)
+	}
+	if _, err := enc.Decrypt(ctx, []byte("x"), nil); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a malformed value, got %v", err)
+	}
+}
+
+func TestAESGCM_KeyRotation(t *testing.T) {
+	ctx := context.Background()
+	old := newEncryptor()
+	ct, err := old.Encrypt(ctx, []byte("before"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := encryption.NewAESGCM(encryption.StaticKeys("k2", map[string][]byte{"k1": key(1), "k2": key(2)}))
+	pt, err := rotated.Decrypt(ctx, ct, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pt) != "before" {
+		t.Fatalf("expected before, got %q", pt)
+	}
+
+	ct2, err := rotated.Encrypt(ctx, []byte("after"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Decrypt(ctx, ct2, nil); !errors.Is(err, encryption.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey for a value of a newer key, got %v", err)
+	}
+}
+
+// toDoc encodes v as a document, as the repository does before encrypting it.
+func toDoc(t *testing.T, v any) bson.D {
+	t.Helper()
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// fromDoc decodes doc into out.
+func fromDoc(t *testing.T, doc bson.D, out any) {
+	t.Helper()
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bson.Unmarshal(raw, out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// encrypted reports whether v is stored in encrypted form.
+func encrypted(v any) bool {
+	b, ok := v.(primitive.Binary)
+	return ok && b.Subtype == encryption.Subtype
+}
+
+func TestEncryptDocument(t *testing.T) {
+	ctx := context.Background()
+	enc := newEncryptor()
+	paths := encryption.Paths(reflect.TypeFor[patient]())
+	phone := "555-0100"
+	p := patient{
+		ID:      primitive.NewObjectID(),
+		Name:    "Ada",
+		SSN:     "123-45-6789",
+		Phone:   &phone,
+		Notes:   []byte("allergic"),
+		Address: &address{Street: "1 Main St", City: "London"},
+	}
+
+	doc := toDoc(t, p)
+	if err := encryption.EncryptDocument(ctx, enc, doc, paths); err != nil {
+		t.Fatal(err)
+	}
+	m := doc.Map()
+	addr := m["address"].(bson.D).Map()
+	if !encrypted(m["ssn"]) || !encrypted(m["phone"]) || !encrypted(m["notes"]) || !encrypted(addr["street"]) {
+		t.Fatalf("expected marked fields to be encrypted: %v", doc)
+	}
+	if m["name"] != "Ada" || addr["city"] != "London" {
+		t.Fatalf("expected unmarked fields to be unchanged: %v", doc)
+	}
+
+	if err := encryption.DecryptDocument(ctx, enc, doc, paths); err != nil {
+		t.Fatal(err)
+	}
+	var got patient
+	fromDoc(t, doc, &got)
+	if !reflect.DeepEqual(got, p) {
+		t.Fatalf("unexpected decrypted document: %+v", got)
+	}
+}
+
+func TestEncryptDocument_PrefixedPlaintext(t *testing.T) {
+	ctx := context.Background()
+	enc := newEncryptor()
+	paths := encryption.Paths(reflect.TypeFor[patient]())
+
+	// Values that look like a marker of some earlier format are still
+	// caller values, and are encrypted like any other.
+	p := patient{ID: primitive.NewObjectID(), SSN: "enc:123-45-6789", Notes: []byte("enc:allergic")}
+	doc := toDoc(t, p)
+	if err := encryption.EncryptDocument(ctx, enc, doc, paths); err != nil {
+		t.Fatal(err)
+	}
+	if m := doc.Map(); !encrypted(m["ssn"]) || !encrypted(m["notes"]) {
+		t.Fatalf("expected prefixed values to be encrypted: %v", doc)
+	}
+	if err := encryption.DecryptDocument(ctx, enc, doc, paths); err != nil {
+		t.Fatal(err)
+	}
+	var got patient
+	fromDoc(t, doc, &got)
+	if got.SSN != p.SSN || string(got.Notes) != string(p.Notes) {
+		t.Fatalf("unexpected round trip: %+v", got)
+	}
+}
+
+func TestDecryptDocument_Bound(t *testing.T) {
+	ctx := context.Background()
+	enc := newEncryptor()
+	paths := encryption.Paths(reflect.TypeFor[patient]())
+	phone := "555-0100"
+
+	doc := toDoc(t, patient{ID: primitive.NewObjectID(), SSN: "123-45-6789", Phone: &phone})
+	if err := encryption.EncryptDocument(ctx, enc, doc, paths); err != nil {
+		t.Fatal(err)
+	}
+	m := doc.Map()
+
+	otherField := bson.D{{Key: "_id", Value: m["_id"]}, {Key: "phone", Value: m["ssn"]}}
+	if err := encryption.DecryptDocument(ctx, enc, otherField, paths); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a value copied to another field, got %v", err)
+	}
+	otherDoc := bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "ssn", Value: m["ssn"]}}
+	if err := encryption.DecryptDocument(ctx, enc, otherDoc, paths); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a value copied to another document, got %v", err)
+	}
+	noID := bson.D{{Key: "ssn", Value: m["ssn"]}}
+	if err := encryption.DecryptDocument(ctx, enc, noID, paths); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a value read without its _id, got %v", err)
+	}
+}
+
+func TestDecryptDocument_Plaintext(t *testing.T) {
+	p := patient{SSN: "123-45-6789", Notes: []byte("legacy")}
+	doc := toDoc(t, p)
+	if err := encryption.DecryptDocument(context.Background(), newEncryptor(), doc, encryption.Paths(reflect.TypeFor[patient]())); err != nil {
+		t.Fatal(err)
+	}
+	var got patient
+	fromDoc(t, doc, &got)
+	if got.SSN != "123-45-6789" || string(got.Notes) != "legacy" {
+		t.Fatalf("expected values written before encryption to be unchanged: %+v", got)
+	}
+}
+
+func TestEncryptDocument_UnsupportedType(t *testing.T) {
+	type bad struct {
+		Age int `bson:"age" mongox:"encrypt"`
+	}
+	doc := toDoc(t, bad{Age: 3})
+	err := encryption.EncryptDocument(context.Background(), newEncryptor(), doc, encryption.Paths(reflect.TypeFor[bad]()))
+	if !errors.Is(err, encryption.ErrUnsupportedField) {
+		t.Fatalf("expected ErrUnsupportedField, got %v", err)
+	}
+}
+
+func TestHasEncryptedFields(t *testing.T) {
+	type plain struct {
+		Name string `bson:"name"`
+	}
+	if !encryption.HasEncryptedFields(reflect.TypeFor[*patient]()) {
+		t.Fatal("expected patient to have encrypted fields")
+	}
+	if encryption.HasEncryptedFields(reflect.TypeFor[plain]()) {
+		t.Fatal("expected plain to have no encrypted fields")
+	}
+}
+
+func TestPaths(t *testing.T) {
+	type inlined struct {
+		Token string `bson:"token" mongox:"encrypt"`
+	}
+	type account struct {
+		inlined `bson:",inline"`
+		Owner   patient
+		Ignored string `bson:"-" mongox:"encrypt"`
+	}
+	got := encryption.Paths(reflect.TypeFor[*account]())
+	want := []string{"token", "owner.ssn", "owner.phone", "owner.notes", "owner.address.street"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Paths = %v, want %v", got, want)
+	}
+}
+
+func TestEncryptValue(t *testing.T) {
+	ctx := context.Background()
+	enc := newEncryptor()
+	paths := encryption.Paths(reflect.TypeFor[patient]())
+
+	v, err := encryption.EncryptValue(ctx, enc, "ssn", "123-45-6789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !encrypted(v) {
+		t.Fatalf("expected an encrypted value, got %v", v)
+	}
+	if _, err := encryption.EncryptValue(ctx, enc, "ssn", v); !errors.Is(err, encryption.ErrUnsupportedField) {
+		t.Fatalf("expected ErrUnsupportedField for an encrypted value, got %v", err)
+	}
+
+	// Values written by updates are not bound to a document.
+	doc := bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "ssn", Value: v}}
+	if err := encryption.DecryptDocument(ctx, enc, doc, paths); err != nil {
+		t.Fatal(err)
+	}
+	if doc[1].Value != "123-45-6789" {
+		t.Fatalf("unexpected round trip: %v", doc[1].Value)
+	}
+
+	b, err := encryption.EncryptValue(ctx, enc, "notes", []byte("allergic"))
+	if err != nil || !encrypted(b) {
+		t.Fatalf("expected encrypted bytes, got %v, %v", b, err)
+	}
+	doc = bson.D{{Key: "notes", Value: b}}
+	if err := encryption.DecryptDocument(ctx, enc, doc, paths); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := doc[0].Value.(primitive.Binary); !ok || string(got.Data) != "allergic" {
+		t.Fatalf("unexpected round trip: %v", doc[0].Value)
+	}
+
+	if v, err := encryption.EncryptValue(ctx, enc, "phone", (*string)(nil)); v != nil || err != nil {
+		t.Fatalf("expected a nil pointer to stay nil, got %v, %v", v, err)
+	}
+	if _, err := encryption.EncryptValue(ctx, enc, "ssn", 42); !errors.Is(err, encryption.ErrUnsupportedField) {
+		t.Fatalf("expected ErrUnsupportedField, got %v", err)
+	}
+}
+
+type fakeKMS struct{ calls int }
+
+func (k *fakeKMS) Decrypt(_ context.Context, wrapped []byte) ([]byte, error) {
+	k.calls++
+	out := make([]byte, len(wrapped))
+	for i, b := range wrapped {
+		out[i] = b ^ 0xff
+	}
+	return out, nil
+}
+
+func TestKMSKeys(t *testing.T) {
+	ctx := context.Background()
+	kms := &fakeKMS{}
+	wrapped := bytes.Repeat([]byte{0xfe}, 32) // unwraps to key(1)
+	keys := encryption.KMSKeys(kms, "k1", map[string][]byte{"k1": wrapped})
+
+	enc := encryption.NewAESGCM(keys)
+	ct, err := enc.Encrypt(ctx, []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, err := newEncryptor().Decrypt(ctx, ct, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(pt) != "secret" {
+		t.Fatalf("expected secret, got %q", pt)
+	}
+	if _, _, err := keys.CurrentKey(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if kms.calls != 1 {
+		t.Fatalf("expected the key to be unwrapped once, got %d calls", kms.calls)
+	}
+	if _, err := keys.Key(ctx, "k9"); !errors.Is(err, encryption.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
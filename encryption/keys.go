@@ -0,0 +1,97 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+)
+
+// staticKeys is the KeyProvider returned by StaticKeys.
+type staticKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+// StaticKeys returns a KeyProvider holding keys in memory, e.g. loaded from a
+// secret manager at startup. New values are encrypted with the key with ID
+// current; the other keys are kept to read values written before a rotation.
+//
+// Example:
+//
+//	keys := encryption.StaticKeys("2026-02", map[string][]byte{
+//	    "2026-01": oldKey,
+//	    "2026-02": newKey,
+//	})
+func StaticKeys(current string, keys map[string][]byte) KeyProvider {
+	return &staticKeys{current: current, keys: maps.Clone(keys)}
+}
+
+func (p *staticKeys) CurrentKey(ctx context.Context) (string, []byte, error) {
+	key, err := p.Key(ctx, p.current)
+	return p.current, key, err
+}
+
+func (p *staticKeys) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// KMS unwraps data keys with a key management service such as AWS KMS,
+// Google Cloud KMS, Azure Key Vault, or HashiCorp Vault. Implement it with
+// the service's SDK; the master key never leaves the service.
+type KMS interface {
+	// Decrypt returns the plaintext data key of a wrapped (encrypted) one.
+	Decrypt(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// kmsKeys is the KeyProvider returned by KMSKeys.
+type kmsKeys struct {
+	kms     KMS
+	current string
+	wrapped map[string][]byte
+
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+// KMSKeys returns a KeyProvider for envelope encryption: data keys are kept
+// wrapped by a master key in the KMS, e.g. in configuration, and unwrapped by
+// the KMS on first use, then cached in memory. New values are encrypted with
+// the key with ID current.
+//
+// Example:
+//
+//	keys := encryption.KMSKeys(awsKMS{client}, "2026-01", map[string][]byte{
+//	    "2026-01": wrappedKey, // from GenerateDataKey, stored with the app config
+//	})
+//	enc := encryption.NewAESGCM(keys)
+func KMSKeys(kms KMS, current string, wrapped map[string][]byte) KeyProvider {
+	return &kmsKeys{kms: kms, current: current, wrapped: maps.Clone(wrapped), keys: map[string][]byte{}}
+}
+
+func (p *kmsKeys) CurrentKey(ctx context.Context) (string, []byte, error) {
+	key, err := p.Key(ctx, p.current)
+	return p.current, key, err
+}
+
+func (p *kmsKeys) Key(ctx context.Context, id string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[id]; ok {
+		return key, nil
+	}
+	wrapped, ok := p.wrapped[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+	key, err := p.kms.Decrypt(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("encryption: unwrap key %s: %w", id, err)
+	}
+	p.keys[id] = key
+	return key, nil
+}
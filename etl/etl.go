@@ -0,0 +1,284 @@
+// Package etl copies data from SQL databases into mongox repositories, to ease
+// migrations from Postgres, MySQL, and other database/sql sources.
+//
+// Copy streams the rows of a user-supplied query, maps each row to a document
+// with a user-supplied function, runs optional transforms, and inserts the
+// documents in batches. Rows are never all held in memory.
+//
+// Example:
+//
+//	res, err := etl.Copy(ctx, pg, usersRepo, etl.Job[User]{
+//	    Query: `SELECT id, email, name, created_at FROM users WHERE active`,
+//	    Map: func(rows *sql.Rows) (*User, error) {
+//	        var u User
+//	        var legacyID int64
+//	        if err := rows.Scan(&legacyID, &u.Email, &u.Name, &u.CreatedAt); err != nil {
+//	            return nil, err
+//	        }
+//	        u.LegacyID = legacyID
+//	        return &u, nil
+//	    },
+//	    Transforms: []etl.Transform[User]{
+//	        func(ctx context.Context, u *User) (*User, error) {
+//	            u.Email = strings.ToLower(u.Email)
+//	            return u, nil
+//	        },
+//	    },
+//	},
+//	    etl.WithBatchSize(500),
+//	    etl.WithSkipDuplicates(),
+//	    etl.WithProgress(func(p etl.Progress) {
+//	        log.Printf("read %d, inserted %d (%.0f rows/s)", p.Read, p.Inserted, p.Rate)
+//	    }),
+//	)
+package etl
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Querier runs a query. *sql.DB, *sql.Tx, and *sql.Conn implement it.
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Inserter is the part of a repository Copy writes to.
+// Every repository.Repository[T] implements it.
+type Inserter[T any] interface {
+	InsertOne(ctx context.Context, doc *T) error
+	InsertMany(ctx context.Context, docs []*T) ([]primitive.ObjectID, error)
+}
+
+// unorderedInserter is implemented by repositories that can report duplicates
+// per document, such as MongoRepository.
+type unorderedInserter[T any] interface {
+	InsertManyUnordered(ctx context.Context, docs []*T) (*repository.InsertManyResult, error)
+}
+
+// MapFunc converts the current row to a document. It must call rows.Scan and
+// must not call rows.Next.
+type MapFunc[T any] func(rows *sql.Rows) (*T, error)
+
+// Transform adjusts a mapped document before it is inserted. Returning a nil
+// document skips the row.
+type Transform[T any] func(ctx context.Context, doc *T) (*T, error)
+
+// Job describes what Copy reads and how rows become documents.
+type Job[T any] struct {
+	// Query selects the rows to copy.
+	Query string
+
+	// Args are the query arguments.
+	Args []any
+
+	// Map converts each row to a document.
+	Map MapFunc[T]
+
+	// Transforms run in order on every mapped document.
+	Transforms []Transform[T]
+}
+
+// Progress is reported after every batch and returned by Copy.
+type Progress struct {
+	// Read is the number of rows read from the source.
+	Read int64
+
+	// Inserted is the number of documents written to the repository.
+	Inserted int64
+
+	// Skipped is the number of rows a transform dropped.
+	Skipped int64
+
+	// Duplicates is the number of documents rejected as duplicates.
+	// Only counted with WithSkipDuplicates.
+	Duplicates int64
+
+	// Elapsed is the time since Copy started.
+	Elapsed time.Duration
+
+	// Rate is the observed throughput in rows read per second.
+	Rate float64
+}
+
+// RowError reports which row a mapping or transform failure came from.
+type RowError struct {
+	// Row is the 1-based position of the row in the query result.
+	Row int64
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("etl: row %d: %v", e.Row, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Option configures Copy.
+type Option func(*config)
+
+type config struct {
+	batchSize      int
+	skipDuplicates bool
+	progress       func(Progress)
+}
+
+// WithBatchSize sets the number of documents per insert. Defaults to 1000.
+func WithBatchSize(n int) Option {
+	return func(c *config) { c.batchSize = n }
+}
+
+// WithSkipDuplicates counts documents that violate a unique index instead of
+// failing, so an interrupted copy can simply be run again. Repositories with an
+// InsertManyUnordered method insert each batch in one call; others fall back to
+// one InsertOne per document.
+func WithSkipDuplicates() Option {
+	return func(c *config) { c.skipDuplicates = true }
+}
+
+// WithProgress registers a callback invoked after every batch.
+func WithProgress(fn func(Progress)) Option {
+	return func(c *config) { c.progress = fn }
+}
+
+// Copy streams the rows of job.Query from src into dst.
+//
+// Behavior:
+//   - Documents are inserted in batches; a failed batch stops the copy and the
+//     returned Progress counts only what was inserted before it
+//   - Mapping and transform errors are returned as *RowError
+//   - Cancelling ctx stops before the next row with ctx.Err()
+func Copy[T any](ctx context.Context, src Querier, dst Inserter[T], job Job[T], opts ...Option) (Progress, error) {
+	c := config{batchSize: 1000}
+	for _, o := range opts {
+		if o != nil {
+			o(&c)
+		}
+	}
+	if c.batchSize <= 0 {
+		c.batchSize = 1000
+	}
+	if job.Map == nil {
+		return Progress{}, errors.New("etl: job has no Map function")
+	}
+
+	started := time.Now()
+	var p Progress
+	stamp := func() {
+		p.Elapsed = time.Since(started)
+		if p.Elapsed > 0 {
+			p.Rate = float64(p.Read) / p.Elapsed.Seconds()
+		}
+	}
+	report := func() {
+		stamp()
+		if c.progress != nil {
+			c.progress(p)
+		}
+	}
+
+	rows, err := src.QueryContext(ctx, job.Query, job.Args...)
+	if err != nil {
+		return p, fmt.Errorf("etl: query: %w", err)
+	}
+	defer rows.Close()
+
+	batch := make([]*T, 0, c.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		inserted, dups, err := insertBatch(ctx, dst, batch, c.skipDuplicates)
+		p.Inserted += inserted
+		p.Duplicates += dups
+		batch = batch[:0]
+		if err != nil {
+			return err
+		}
+		report()
+		return nil
+	}
+
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return p, err
+		}
+		p.Read++
+
+		doc, err := job.Map(rows)
+		if err != nil {
+			return p, &RowError{Row: p.Read, Err: err}
+		}
+		for _, t := range job.Transforms {
+			if doc == nil {
+				break
+			}
+			if doc, err = t(ctx, doc); err != nil {
+				return p, &RowError{Row: p.Read, Err: err}
+			}
+		}
+		if doc == nil {
+			p.Skipped++
+			continue
+		}
+
+		batch = append(batch, doc)
+		if len(batch) == c.batchSize {
+			if err := flush(); err != nil {
+				return p, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return p, fmt.Errorf("etl: read rows: %w", err)
+	}
+	if err := flush(); err != nil {
+		return p, err
+	}
+	stamp()
+	return p, nil
+}
+
+// insertBatch writes docs and returns how many were inserted and how many were
+// skipped as duplicates.
+func insertBatch[T any](ctx context.Context, dst Inserter[T], docs []*T, skipDuplicates bool) (int64, int64, error) {
+	if !skipDuplicates {
+		ids, err := dst.InsertMany(ctx, docs)
+		if err != nil {
+			return 0, 0, err
+		}
+		return int64(len(ids)), 0, nil
+	}
+
+	if u, ok := dst.(unorderedInserter[T]); ok {
+		res, err := u.InsertManyUnordered(ctx, docs)
+		if res == nil {
+			return 0, 0, err
+		}
+		return int64(len(res.InsertedIDs)), int64(len(res.Duplicates)), err
+	}
+
+	var inserted, dups int64
+	for _, doc := range docs {
+		err := dst.InsertOne(ctx, doc)
+		switch {
+		case errors.Is(err, repository.ErrDuplicateKey):
+			dups++
+		case err != nil:
+			return inserted, dups, err
+		default:
+			inserted++
+		}
+	}
+	return inserted, dups, nil
+}
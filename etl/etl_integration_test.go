@@ -0,0 +1,82 @@
+//go:build integration
+
+package etl_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/etl"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestCopy_IntoMongoRepository(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("etl_customers")
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "legacy_id", Value: 1}},
+		Options: mopt.Index().SetUnique(true),
+	}); err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+	repo := mongorepo.New[Customer](coll)
+
+	const query = "SELECT id, email, country FROM customers -- integration"
+	db := openTable(t, query, customers(30))
+	job := etl.Job[Customer]{Query: query, Map: mapCustomer}
+
+	res, err := etl.Copy(ctx, db, repo, job, etl.WithBatchSize(8))
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if res.Inserted != 30 {
+		t.Fatalf("expected 30 inserted, got %+v", res)
+	}
+
+	// Re-running uses InsertManyUnordered and skips every row on the unique index.
+	res, err = etl.Copy(ctx, db, repo, job, etl.WithBatchSize(8), etl.WithSkipDuplicates())
+	if err != nil {
+		t.Fatalf("second Copy: %v", err)
+	}
+	if res.Inserted != 0 || res.Duplicates != 30 {
+		t.Fatalf("expected 30 duplicates, got %+v", res)
+	}
+
+	n, err := coll.CountDocuments(ctx, bson.M{})
+	if err != nil || n != 30 {
+		t.Fatalf("expected 30 documents, got %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,234 @@
+package etl_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/etl"
+	embeddedrepo "github.com/dElCIoGio/mongox/repository/embedded"
+	"github.com/dElCIoGio/mongox/spec"
+)
+
+// ---- fake database/sql driver ----
+
+// tables maps a query string to the rows it returns; the first row holds column names.
+var (
+	tablesMu sync.Mutex
+	tables   = map[string][][]driver.Value{}
+)
+
+func init() {
+	sql.Register("etlfake", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct{ query string }
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return -1 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("not supported") }
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+	t, ok := tables[s.query]
+	if !ok {
+		return nil, fmt.Errorf("unknown query %q", s.query)
+	}
+	cols := make([]string, len(t[0]))
+	for i, c := range t[0] {
+		cols[i] = c.(string)
+	}
+	return &fakeRows{cols: cols, rows: t[1:]}, nil
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// openTable registers rows for query and returns a database serving them.
+func openTable(t *testing.T, query string, rows [][]driver.Value) *sql.DB {
+	t.Helper()
+	tablesMu.Lock()
+	tables[query] = rows
+	tablesMu.Unlock()
+	db, err := sql.Open("etlfake", "")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// ---- tests ----
+
+type Customer struct {
+	document.Base `bson:",inline"`
+
+	LegacyID int64  `bson:"legacy_id"`
+	Email    string `bson:"email"`
+	Country  string `bson:"country"`
+}
+
+func customers(n int) [][]driver.Value {
+	rows := [][]driver.Value{{"id", "email", "country"}}
+	for i := 1; i <= n; i++ {
+		country := "PT"
+		if i%2 == 0 {
+			country = "BR"
+		}
+		rows = append(rows, []driver.Value{int64(i), fmt.Sprintf("User%d@Example.com", i), country})
+	}
+	return rows
+}
+
+func mapCustomer(rows *sql.Rows) (*Customer, error) {
+	var c Customer
+	if err := rows.Scan(&c.LegacyID, &c.Email, &c.Country); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func newRepo(t *testing.T) *embeddedrepo.Repository[Customer] {
+	t.Helper()
+	coll, err := embeddedrepo.Memory().Collection("customers")
+	if err != nil {
+		t.Fatalf("Collection: %v", err)
+	}
+	return embeddedrepo.New[Customer](coll)
+}
+
+func TestCopy_BatchesTransformsAndProgress(t *testing.T) {
+	ctx := context.Background()
+	const query = "SELECT id, email, country FROM customers"
+	db := openTable(t, query, customers(25))
+	repo := newRepo(t)
+
+	var reports []etl.Progress
+	res, err := etl.Copy(ctx, db, repo, etl.Job[Customer]{
+		Query: query,
+		Map:   mapCustomer,
+		Transforms: []etl.Transform[Customer]{
+			func(_ context.Context, c *Customer) (*Customer, error) {
+				if c.LegacyID == 7 {
+					return nil, nil
+				}
+				return c, nil
+			},
+			func(_ context.Context, c *Customer) (*Customer, error) {
+				c.Email = strings.ToLower(c.Email)
+				return c, nil
+			},
+		},
+	},
+		etl.WithBatchSize(10),
+		etl.WithProgress(func(p etl.Progress) { reports = append(reports, p) }),
+	)
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	if res.Read != 25 || res.Inserted != 24 || res.Skipped != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(reports) != 3 || reports[0].Inserted != 10 || reports[2].Inserted != 24 {
+		t.Fatalf("unexpected progress reports: %+v", reports)
+	}
+
+	n, _ := repo.Count(ctx, spec.Eq("email", "user3@example.com"))
+	if n != 1 {
+		t.Fatal("expected transformed email to be stored")
+	}
+	if n, _ := repo.Count(ctx, spec.Eq("legacy_id", 7)); n != 0 {
+		t.Fatal("expected skipped row not to be inserted")
+	}
+}
+
+func TestCopy_SkipDuplicatesOnRerun(t *testing.T) {
+	ctx := context.Background()
+	const query = "SELECT id, email, country FROM customers ORDER BY id"
+	db := openTable(t, query, customers(5))
+	repo := newRepo(t)
+
+	// Deterministic _ids make the copy idempotent.
+	job := etl.Job[Customer]{
+		Query: query,
+		Map: func(rows *sql.Rows) (*Customer, error) {
+			c, err := mapCustomer(rows)
+			if err != nil {
+				return nil, err
+			}
+			c.ID[11] = byte(c.LegacyID)
+			return c, nil
+		},
+	}
+
+	if _, err := etl.Copy(ctx, db, repo, job); err != nil {
+		t.Fatalf("first Copy: %v", err)
+	}
+	res, err := etl.Copy(ctx, db, repo, job, etl.WithSkipDuplicates())
+	if err != nil {
+		t.Fatalf("second Copy: %v", err)
+	}
+	if res.Inserted != 0 || res.Duplicates != 5 {
+		t.Fatalf("expected every row to be a duplicate, got %+v", res)
+	}
+
+	if _, err := etl.Copy(ctx, db, repo, job); !errors.Is(err, embeddedrepo.ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey without WithSkipDuplicates, got %v", err)
+	}
+}
+
+func TestCopy_MapErrorReportsRow(t *testing.T) {
+	ctx := context.Background()
+	const query = "SELECT broken"
+	db := openTable(t, query, customers(3))
+
+	boom := errors.New("boom")
+	_, err := etl.Copy(ctx, db, newRepo(t), etl.Job[Customer]{
+		Query: query,
+		Map: func(rows *sql.Rows) (*Customer, error) {
+			c, err := mapCustomer(rows)
+			if err == nil && c.LegacyID == 2 {
+				return nil, boom
+			}
+			return c, err
+		},
+	})
+
+	var rowErr *etl.RowError
+	if !errors.As(err, &rowErr) || rowErr.Row != 2 || !errors.Is(err, boom) {
+		t.Fatalf("expected RowError for row 2, got %v", err)
+	}
+}
@@ -101,15 +101,10 @@ func main() {
 
 	// Sales by month
 	pipeline = spec.NewPipeline().
-		Group(bson.M{
-			"_id": bson.M{
-				"year":  bson.M{"$year": "$sale_date"},
-				"month": bson.M{"$month": "$sale_date"},
-			},
+		GroupByMonth("sale_date", bson.M{
 			"totalSales": spec.Sum("$total"),
 			"orderCount": spec.Sum(1),
-		}).
-		Sort(bson.D{{"_id.year", 1}, {"_id.month", 1}})
+		})
 
 	results, _ = repo.AggregateRaw(ctx, pipeline)
 
@@ -0,0 +1,127 @@
+// Example: Generating Schema Documentation
+//
+// This example registers document types with the schemadoc package and
+// writes their documentation as Markdown, JSON, or a Mermaid or Graphviz
+// entity-relationship diagram (-format markdown|json|mermaid|dot). In an
+// application, keep a program like this next to the models and run it from a
+// go:generate directive so the docs are regenerated with the code:
+//
+//	//go:generate go run ./cmd/schemadoc -o SCHEMA.md
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+	"github.com/dElCIoGio/mongox/schemadoc"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Customer is a registered customer.
+type Customer struct {
+	document.Base `bson:",inline"`
+
+	Name  string `bson:"name" doc:"Full name"`
+	Email string `bson:"email" doc:"Unique login address"`
+}
+
+func (Customer) Indexes() []document.Index {
+	return []document.Index{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Unique: true, Name: "unique_email"},
+	}
+}
+
+func (c *Customer) Validate() error {
+	var errs document.MultiValidationError
+	if c.Name == "" {
+		errs = append(errs, document.ValidationError{Field: "name", Message: "name is required"})
+	}
+	if c.Email == "" {
+		errs = append(errs, document.ValidationError{Field: "email", Message: "email is required"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// LineItem is one product in an order.
+type LineItem struct {
+	SKU      string               `bson:"sku"`
+	Quantity int                  `bson:"quantity"`
+	Price    primitive.Decimal128 `bson:"price"`
+}
+
+// Order is a customer's order.
+type Order struct {
+	document.Base          `bson:",inline"`
+	document.SoftDeletable `bson:",inline"`
+
+	CustomerID primitive.ObjectID `bson:"customer_id"`
+	Items      []LineItem         `bson:"items"`
+	PaidAt     *time.Time         `bson:"paid_at,omitempty" doc:"Set once payment clears"`
+}
+
+func (Order) Indexes() []document.Index {
+	return []document.Index{
+		{Keys: bson.D{{Key: "customer_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	}
+}
+
+func main() {
+	out := flag.String("o", "", "output file (default stdout)")
+	format := flag.String("format", "markdown", "output format: markdown, json, mermaid, or dot")
+	flag.Parse()
+
+	// The references mirror those passed to mongorepo.WithReferences on the
+	// customers repository. Only collection names are read, so the client
+	// never needs to reach a server.
+	client, err := mongo.Connect(context.Background(), options.Client())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+	orders := client.Database("shop").Collection("orders")
+
+	reg := schemadoc.New()
+	schemadoc.Register[Customer](reg, "customers",
+		schemadoc.WithDescription("People who place orders."),
+		schemadoc.WithReferences(mongorepo.Reference{Collection: orders, ForeignKey: "customer_id", OnDelete: mongorepo.OnDeleteRestrict}),
+	)
+	schemadoc.Register[Order](reg, "orders")
+
+	writers := map[string]func(io.Writer) error{
+		"markdown": reg.WriteMarkdown,
+		"json":     reg.WriteJSON,
+		"mermaid":  reg.WriteMermaid,
+		"dot":      reg.WriteDOT,
+	}
+	write, ok := writers[*format]
+	if !ok {
+		log.Fatalf("unknown format %q", *format)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := write(w); err != nil {
+		log.Fatal(err)
+	}
+}
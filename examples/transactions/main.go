@@ -85,8 +85,8 @@ func main() {
 	}
 
 	// Verify balances
-	alice, _ = accountRepo.FindOne(ctx, spec.Eq("_id", alice.ID))
-	bob, _ = accountRepo.FindOne(ctx, spec.Eq("_id", bob.ID))
+	alice, _ = accountRepo.FindByID(ctx, alice.ID)
+	bob, _ = accountRepo.FindByID(ctx, bob.ID)
 	fmt.Println("After successful transfer:")
 	fmt.Printf("  Alice: $%.2f\n", alice.Balance)
 	fmt.Printf("  Bob: $%.2f\n\n", bob.Balance)
@@ -101,8 +101,8 @@ func main() {
 	}
 
 	// Verify balances unchanged
-	alice, _ = accountRepo.FindOne(ctx, spec.Eq("_id", alice.ID))
-	bob, _ = accountRepo.FindOne(ctx, spec.Eq("_id", bob.ID))
+	alice, _ = accountRepo.FindByID(ctx, alice.ID)
+	bob, _ = accountRepo.FindByID(ctx, bob.ID)
 	fmt.Println("After failed transfer (balances unchanged):")
 	fmt.Printf("  Alice: $%.2f\n", alice.Balance)
 	fmt.Printf("  Bob: $%.2f\n\n", bob.Balance)
@@ -112,11 +112,11 @@ func main() {
 
 	err = mongorepo.RunInTransaction(ctx, client, func(txCtx context.Context) error {
 		// Multiple operations in one transaction
-		_, _, err := accountRepo.UpdateOne(txCtx, spec.Eq("_id", alice.ID), spec.Inc("balance", 50))
+		_, _, err := accountRepo.UpdateByID(txCtx, alice.ID, spec.Inc("balance", 50))
 		if err != nil {
 			return err
 		}
-		_, _, err = accountRepo.UpdateOne(txCtx, spec.Eq("_id", bob.ID), spec.Inc("balance", 50))
+		_, _, err = accountRepo.UpdateByID(txCtx, bob.ID, spec.Inc("balance", 50))
 		return err
 	})
 
@@ -127,8 +127,8 @@ func main() {
 	}
 
 	// Final balances
-	alice, _ = accountRepo.FindOne(ctx, spec.Eq("_id", alice.ID))
-	bob, _ = accountRepo.FindOne(ctx, spec.Eq("_id", bob.ID))
+	alice, _ = accountRepo.FindByID(ctx, alice.ID)
+	bob, _ = accountRepo.FindByID(ctx, bob.ID)
 	fmt.Println("Final balances:")
 	fmt.Printf("  Alice: $%.2f\n", alice.Balance)
 	fmt.Printf("  Bob: $%.2f\n\n", bob.Balance)
@@ -154,7 +154,7 @@ func transferMoney(
 ) error {
 	return tm.WithTransaction(ctx, func(txCtx context.Context) error {
 		// 1. Get source account
-		from, err := accountRepo.FindOne(txCtx, spec.Eq("_id", fromID))
+		from, err := accountRepo.FindByID(txCtx, fromID)
 		if err != nil {
 			return fmt.Errorf("source account not found: %w", err)
 		}
@@ -165,22 +165,19 @@ func transferMoney(
 		}
 
 		// 3. Debit source account
-		_, _, err = accountRepo.UpdateOne(txCtx,
-			spec.Eq("_id", fromID),
-			spec.Inc("balance", -amount),
-		)
+		_, _, err = accountRepo.UpdateByID(txCtx, fromID, spec.Inc("balance", -amount))
 		if err != nil {
 			return fmt.Errorf("failed to debit account: %w", err)
 		}
 
 		// 4. Credit destination account
-		_, _, err = accountRepo.UpdateOne(txCtx,
-			spec.Eq("_id", toID),
-			spec.Inc("balance", amount),
-		)
+		matched, _, err := accountRepo.UpdateByID(txCtx, toID, spec.Inc("balance", amount))
 		if err != nil {
 			return fmt.Errorf("failed to credit account: %w", err)
 		}
+		if matched == 0 {
+			return errors.New("destination account not found")
+		}
 
 		// 5. Record transaction
 		txn := &Transaction{
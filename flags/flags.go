@@ -0,0 +1,260 @@
+// Package flags stores feature flags and small configuration values in a
+// MongoDB collection and serves them from an in-memory cache that is kept up
+// to date with a change stream.
+//
+// Each flag is one document, {_id: key, value: ..., updated_at: ...}, so flags
+// can be edited with any MongoDB tool. Reads never touch the database: Get and
+// Flag decode the cached value into the requested type and fall back to a
+// default when the flag is missing or has another type. Run keeps the cache
+// current by watching the collection; on deployments without change streams
+// (standalone servers) it polls instead.
+//
+// Example:
+//
+//	store := flags.New(db.Collection("flags"))
+//	if err := store.Load(ctx); err != nil {
+//	    return err
+//	}
+//	go store.Run(ctx)
+//
+//	newCheckout := flags.NewFlag(store, "checkout.v2", false)
+//	limit := flags.NewFlag(store, "api.rate_limit", 100)
+//
+//	if newCheckout.Get() {
+//	    ...
+//	}
+//	_ = store.Set(ctx, "api.rate_limit", 250)
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotFound is returned by Value for flags that do not exist.
+var ErrNotFound = errors.New("flags: flag not found")
+
+// Option configures a Store.
+type Option func(*config)
+
+type config struct {
+	poll    time.Duration
+	retry   time.Duration
+	onError func(err error)
+}
+
+// WithPollInterval sets how often Run reloads every flag when change streams
+// are unavailable. Defaults to 30s.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *config) { c.poll = d }
+}
+
+// WithErrorHandler registers a callback for errors while watching or polling.
+// Run keeps serving the last known values and retries after such errors.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) { c.onError = fn }
+}
+
+// flagDoc is the stored form of a flag.
+type flagDoc struct {
+	Key   string        `bson:"_id"`
+	Value bson.RawValue `bson:"value"`
+}
+
+// Store caches the flags of a collection.
+// It is safe for concurrent use.
+type Store struct {
+	coll *mongo.Collection
+	cfg  config
+
+	mu       sync.RWMutex
+	values   map[string]bson.RawValue
+	watchers []func(key string)
+}
+
+// New creates a Store for coll. The cache is empty until Load or Run is called.
+func New(coll *mongo.Collection, opts ...Option) *Store {
+	cfg := config{poll: 30 * time.Second, retry: time.Second}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.poll <= 0 {
+		cfg.poll = 30 * time.Second
+	}
+	return &Store{coll: coll, cfg: cfg, values: make(map[string]bson.RawValue)}
+}
+
+// Load replaces the cache with every flag in the collection.
+func (s *Store) Load(ctx context.Context) error {
+	cur, err := s.coll.Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("flags: load: %w", err)
+	}
+	var docs []flagDoc
+	if err := cur.All(ctx, &docs); err != nil {
+		return fmt.Errorf("flags: load: %w", err)
+	}
+
+	values := make(map[string]bson.RawValue, len(docs))
+	for _, d := range docs {
+		values[d.Key] = d.Value
+	}
+
+	s.mu.Lock()
+	old := s.values
+	s.values = values
+	s.mu.Unlock()
+
+	for key, v := range values {
+		if prev, ok := old[key]; !ok || !prev.Equal(v) {
+			s.notify(key)
+		}
+	}
+	for key := range old {
+		if _, ok := values[key]; !ok {
+			s.notify(key)
+		}
+	}
+	return nil
+}
+
+// OnChange registers fn to be called with the key of every flag that is added,
+// changed, or removed. fn runs on the goroutine that applied the change.
+func (s *Store) OnChange(fn func(key string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watchers = append(s.watchers, fn)
+}
+
+func (s *Store) notify(key string) {
+	s.mu.RLock()
+	watchers := s.watchers
+	s.mu.RUnlock()
+	for _, fn := range watchers {
+		fn(key)
+	}
+}
+
+// apply stores or removes one cached value and notifies watchers.
+func (s *Store) apply(key string, v bson.RawValue, deleted bool) {
+	s.mu.Lock()
+	prev, existed := s.values[key]
+	if deleted {
+		delete(s.values, key)
+	} else {
+		s.values[key] = v
+	}
+	s.mu.Unlock()
+
+	if deleted && existed || !deleted && (!existed || !prev.Equal(v)) {
+		s.notify(key)
+	}
+}
+
+// Set stores value under key and updates the cache immediately.
+func (s *Store) Set(ctx context.Context, key string, value any) error {
+	_, err := s.coll.UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{"$set": bson.M{"value": value, "updated_at": time.Now().UTC()}},
+		mopt.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("flags: set %q: %w", key, err)
+	}
+
+	raw, err := encode(value)
+	if err != nil {
+		return err
+	}
+	s.apply(key, raw, false)
+	return nil
+}
+
+// Delete removes the flag and updates the cache immediately.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	if _, err := s.coll.DeleteOne(ctx, bson.M{"_id": key}); err != nil {
+		return fmt.Errorf("flags: delete %q: %w", key, err)
+	}
+	s.apply(key, bson.RawValue{}, true)
+	return nil
+}
+
+// Keys returns the keys of the cached flags, in no particular order.
+func (s *Store) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Value decodes the cached value of key into V. It returns ErrNotFound for
+// missing flags and a decoding error when the value does not fit V.
+func Value[V any](s *Store, key string) (V, error) {
+	var out V
+	s.mu.RLock()
+	raw, ok := s.values[key]
+	s.mu.RUnlock()
+	if !ok {
+		return out, fmt.Errorf("%w: %q", ErrNotFound, key)
+	}
+	if err := raw.Unmarshal(&out); err != nil {
+		return out, fmt.Errorf("flags: decode %q: %w", key, err)
+	}
+	return out, nil
+}
+
+// Get returns the cached value of key as V, or def when the flag is missing or
+// does not decode into V.
+func Get[V any](s *Store, key string, def V) V {
+	v, err := Value[V](s, key)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// Enabled reports whether the boolean flag key is true. Missing and
+// non-boolean flags are disabled.
+func (s *Store) Enabled(key string) bool {
+	return Get(s, key, false)
+}
+
+// Flag is a typed handle to one flag with a default value.
+type Flag[V any] struct {
+	store *Store
+	key   string
+	def   V
+}
+
+// NewFlag returns a handle to key that reads values as V, falling back to def.
+func NewFlag[V any](s *Store, key string, def V) Flag[V] {
+	return Flag[V]{store: s, key: key, def: def}
+}
+
+// Key returns the flag's key.
+func (f Flag[V]) Key() string { return f.key }
+
+// Get returns the current value, or the default when the flag is missing or
+// does not decode into V.
+func (f Flag[V]) Get() V { return Get(f.store, f.key, f.def) }
+
+// encode converts a Go value to the RawValue stored for it.
+func encode(value any) (bson.RawValue, error) {
+	b, err := bson.Marshal(bson.M{"value": value})
+	if err != nil {
+		return bson.RawValue{}, fmt.Errorf("flags: encode: %w", err)
+	}
+	return bson.Raw(b).Lookup("value"), nil
+}
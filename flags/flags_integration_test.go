@@ -0,0 +1,155 @@
+//go:build integration
+
+package flags_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/flags"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/testcontainers/testcontainers-go"
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T, opts ...testcontainers.ContainerCustomizer) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7", opts...)
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri).SetDirect(true))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+type Banner struct {
+	Text  string `bson:"text"`
+	Color string `bson:"color"`
+}
+
+func TestStore_TypedAccess(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("flags")
+	if _, err := coll.InsertMany(ctx, []any{
+		bson.M{"_id": "checkout.v2", "value": true},
+		bson.M{"_id": "api.rate_limit", "value": 250},
+		bson.M{"_id": "banner", "value": bson.M{"text": "Sale!", "color": "red"}},
+	}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	s := flags.New(coll)
+	if err := s.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if !s.Enabled("checkout.v2") {
+		t.Fatal("expected checkout.v2 to be enabled")
+	}
+	if got := flags.Get(s, "api.rate_limit", 100); got != 250 {
+		t.Fatalf("rate limit = %d, want 250", got)
+	}
+	if got := flags.Get(s, "api.rate_limit", "x"); got != "x" {
+		t.Fatalf("expected the default for a mistyped read, got %q", got)
+	}
+	if b := flags.NewFlag(s, "banner", Banner{}).Get(); b.Text != "Sale!" || b.Color != "red" {
+		t.Fatalf("unexpected banner: %+v", b)
+	}
+
+	if err := s.Set(ctx, "api.rate_limit", 500); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if got := flags.Get(s, "api.rate_limit", 100); got != 500 {
+		t.Fatalf("expected Set to update the cache, got %d", got)
+	}
+	if err := s.Delete(ctx, "checkout.v2"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if s.Enabled("checkout.v2") {
+		t.Fatal("expected a deleted flag to be disabled")
+	}
+}
+
+func TestRun_HotReloads(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []testcontainers.ContainerCustomizer
+	}{
+		{"change stream", []testcontainers.ContainerCustomizer{mongodb.WithReplicaSet("rs0")}},
+		{"polling fallback", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := setupMongo(t, tc.opts...)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			coll := client.Database("testdb").Collection("flags")
+
+			s := flags.New(coll, flags.WithPollInterval(50*time.Millisecond))
+			var mu sync.Mutex
+			changed := map[string]bool{}
+			s.OnChange(func(key string) {
+				mu.Lock()
+				changed[key] = true
+				mu.Unlock()
+			})
+			go func() { _ = s.Run(ctx) }()
+
+			// A second process edits the flag directly.
+			if _, err := coll.InsertOne(ctx, bson.M{"_id": "checkout.v2", "value": true}); err != nil {
+				t.Fatalf("InsertOne: %v", err)
+			}
+			waitFor(t, func() bool { return s.Enabled("checkout.v2") })
+
+			if _, err := coll.UpdateOne(ctx, bson.M{"_id": "checkout.v2"}, bson.M{"$set": bson.M{"value": false}}); err != nil {
+				t.Fatalf("UpdateOne: %v", err)
+			}
+			waitFor(t, func() bool { return !s.Enabled("checkout.v2") && len(s.Keys()) == 1 })
+
+			if _, err := coll.DeleteOne(ctx, bson.M{"_id": "checkout.v2"}); err != nil {
+				t.Fatalf("DeleteOne: %v", err)
+			}
+			waitFor(t, func() bool { return len(s.Keys()) == 0 })
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !changed["checkout.v2"] {
+				t.Fatal("expected OnChange to be called")
+			}
+		})
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 5s")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
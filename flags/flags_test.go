@@ -0,0 +1,28 @@
+package flags_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/flags"
+	"github.com/dElCIoGio/mongox/internal/testutil"
+)
+
+func TestGet_DefaultsBeforeLoad(t *testing.T) {
+	s := flags.New(testutil.LazyCollection(t, "flags"))
+
+	if _, err := flags.Value[bool](s, "checkout.v2"); !errors.Is(err, flags.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if got := flags.Get(s, "api.rate_limit", 100); got != 100 {
+		t.Fatalf("Get = %d, want the default 100", got)
+	}
+	if s.Enabled("checkout.v2") {
+		t.Fatal("expected a missing flag to be disabled")
+	}
+
+	f := flags.NewFlag(s, "banner.text", "hello")
+	if f.Key() != "banner.text" || f.Get() != "hello" {
+		t.Fatalf("unexpected flag handle %q = %q", f.Key(), f.Get())
+	}
+}
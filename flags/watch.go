@@ -0,0 +1,121 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Server error codes returned when change streams are unavailable.
+const (
+	codeChangeStreamNotSupported = 40573 // standalone server
+	codeCommandNotSupported      = 115
+)
+
+// changeEvent is the part of a change stream event used to update the cache.
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID string `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *flagDoc `bson:"fullDocument"`
+}
+
+// Run loads every flag and then keeps the cache up to date until ctx is
+// cancelled, returning ctx.Err(). It watches the collection with a change
+// stream, reopening it and reloading after errors, and reloads every poll
+// interval instead when the deployment does not support change streams.
+func (s *Store) Run(ctx context.Context) error {
+	for {
+		err := s.watch(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if changeStreamsUnsupported(err) {
+			return s.poll(ctx)
+		}
+		s.reportError(err)
+		if !sleep(ctx, s.cfg.retry) {
+			return ctx.Err()
+		}
+	}
+}
+
+// watch opens a change stream, reloads the cache so no change between the last
+// load and the stream start is missed, and applies events until an error.
+func (s *Store) watch(ctx context.Context) error {
+	cs, err := s.coll.Watch(ctx, mongo.Pipeline{}, mopt.ChangeStream().SetFullDocument(mopt.UpdateLookup))
+	if err != nil {
+		return err
+	}
+	defer cs.Close(ctx)
+
+	if err := s.Load(ctx); err != nil {
+		return err
+	}
+
+	for cs.Next(ctx) {
+		var ev changeEvent
+		if err := cs.Decode(&ev); err != nil {
+			return err
+		}
+		switch ev.OperationType {
+		case "insert", "update", "replace":
+			if ev.FullDocument == nil {
+				// Deleted before the lookup; a delete event follows.
+				continue
+			}
+			s.apply(ev.FullDocument.Key, ev.FullDocument.Value, false)
+		case "delete":
+			s.apply(ev.DocumentKey.ID, bson.RawValue{}, true)
+		case "drop", "rename", "dropDatabase", "invalidate":
+			if err := s.Load(ctx); err != nil {
+				return err
+			}
+			return errors.New("flags: change stream invalidated")
+		}
+	}
+	return cs.Err()
+}
+
+// poll reloads every flag each poll interval until ctx is cancelled.
+func (s *Store) poll(ctx context.Context) error {
+	for {
+		if err := s.Load(ctx); err != nil && ctx.Err() == nil {
+			s.reportError(err)
+		}
+		if !sleep(ctx, s.cfg.poll) {
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Store) reportError(err error) {
+	if err != nil && s.cfg.onError != nil {
+		s.cfg.onError(err)
+	}
+}
+
+func changeStreamsUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	return cmdErr.Code == codeChangeStreamNotSupported || cmdErr.Code == codeCommandNotSupported
+}
+
+// sleep waits for d and reports false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,214 @@
+// Package inbox stores per-user notifications on top of a repository.
+//
+// Notifications are appended to a user's inbox, listed newest first in pages,
+// and marked read one at a time or all at once. Unread counts are answered
+// from the {user_id, read, created_at} index declared by Notification, so they
+// do not read any documents; create it with EnsureIndexes or
+// mongorepo.NewWithIndexes.
+//
+// Any repository.Repository[Notification] works, so tests can use memoryrepo.
+//
+// Example:
+//
+//	repo, err := mongorepo.NewWithIndexes[inbox.Notification](ctx, db.Collection("notifications"))
+//	if err != nil {
+//	    return err
+//	}
+//	box := inbox.New(repo)
+//
+//	_ = box.Append(ctx, &inbox.Notification{UserID: "u1", Kind: "comment", Title: "Ada replied"})
+//	unread, _ := box.UnreadCount(ctx, "u1")
+//	page, _ := box.List(ctx, "u1", 1, 20, inbox.UnreadOnly())
+package inbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrMissingUser is returned when a notification or query has no user ID.
+var ErrMissingUser = errors.New("inbox: user ID is required")
+
+// Notification is one entry in a user's inbox.
+type Notification struct {
+	document.Base `bson:",inline"`
+
+	UserID string     `bson:"user_id"`
+	Kind   string     `bson:"kind,omitempty"`
+	Title  string     `bson:"title"`
+	Body   string     `bson:"body,omitempty"`
+	Data   bson.M     `bson:"data,omitempty"`
+	Read   bool       `bson:"read"`
+	ReadAt *time.Time `bson:"read_at,omitempty"`
+}
+
+// Indexes implements document.Indexed. The first index serves unread counts
+// and unread listings; the second serves full listings.
+func (Notification) Indexes() []document.Index {
+	return []document.Index{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "read", Value: 1}, {Key: "created_at", Value: -1}}, Name: "inbox_user_read_created"},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}, Name: "inbox_user_created"},
+	}
+}
+
+// Inbox manages notifications stored in a repository. It is safe for
+// concurrent use if the repository is.
+type Inbox struct {
+	repo repository.Repository[Notification]
+}
+
+// New creates an Inbox backed by repo.
+func New(repo repository.Repository[Notification]) *Inbox {
+	return &Inbox{repo: repo}
+}
+
+// EnsureIndexes creates the indexes declared by Notification when the
+// repository supports it, as mongorepo.MongoRepository does.
+func (b *Inbox) EnsureIndexes(ctx context.Context) error {
+	if ix, ok := b.repo.(interface{ EnsureIndexes(context.Context) error }); ok {
+		return ix.EnsureIndexes(ctx)
+	}
+	return nil
+}
+
+// Append adds n to its user's inbox as unread.
+func (b *Inbox) Append(ctx context.Context, n *Notification) error {
+	if n == nil {
+		return repository.ErrNilDocument
+	}
+	if n.UserID == "" {
+		return ErrMissingUser
+	}
+	n.Read, n.ReadAt = false, nil
+	return b.repo.InsertOne(ctx, n)
+}
+
+// ListOption configures List.
+type ListOption func(*listConfig)
+
+type listConfig struct {
+	unreadOnly bool
+	kind       string
+}
+
+// UnreadOnly restricts List to unread notifications.
+func UnreadOnly() ListOption {
+	return func(c *listConfig) { c.unreadOnly = true }
+}
+
+// OfKind restricts List to notifications of the given kind.
+func OfKind(kind string) ListOption {
+	return func(c *listConfig) { c.kind = kind }
+}
+
+// List returns one page of userID's notifications, newest first. page is
+// 1-indexed; perPage is normalized as in repository.PaginationOptions.
+func (b *Inbox) List(ctx context.Context, userID string, page, perPage int, opts ...ListOption) (*repository.Page[Notification], error) {
+	if userID == "" {
+		return nil, ErrMissingUser
+	}
+	var cfg listConfig
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+
+	filters := []spec.Filter{spec.Eq("user_id", userID)}
+	if cfg.unreadOnly {
+		filters = append(filters, spec.Eq("read", false))
+	}
+	if cfg.kind != "" {
+		filters = append(filters, spec.Eq("kind", cfg.kind))
+	}
+	filter := spec.And(filters...)
+
+	pag := repository.PaginationOptions{Page: page, PerPage: perPage}
+	pag.Normalize()
+
+	total, err := b.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	items, err := b.repo.Find(ctx, filter,
+		repository.WithSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}),
+		repository.WithSkip(pag.Skip()),
+		repository.WithLimit(pag.Limit()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := repository.CalculateTotalPages(total, pag.PerPage)
+	return &repository.Page[Notification]{
+		Items:      items,
+		Total:      total,
+		Page:       pag.Page,
+		PerPage:    pag.PerPage,
+		TotalPages: totalPages,
+		HasNext:    pag.Page < totalPages,
+		HasPrev:    pag.Page > 1,
+	}, nil
+}
+
+// UnreadCount returns the number of unread notifications of userID.
+func (b *Inbox) UnreadCount(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, ErrMissingUser
+	}
+	return b.repo.Count(ctx, spec.And(spec.Eq("user_id", userID), spec.Eq("read", false)))
+}
+
+// MarkRead marks notification id of userID as read. It reports false if the
+// notification does not exist, belongs to another user, or is already read.
+func (b *Inbox) MarkRead(ctx context.Context, userID string, id primitive.ObjectID) (bool, error) {
+	if userID == "" {
+		return false, ErrMissingUser
+	}
+	_, modified, err := b.repo.UpdateOne(ctx,
+		spec.And(spec.Eq("_id", id), spec.Eq("user_id", userID), spec.Eq("read", false)),
+		markRead(),
+	)
+	return modified > 0, err
+}
+
+// MarkAllRead marks every unread notification of userID as read and returns
+// how many were marked.
+func (b *Inbox) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	if userID == "" {
+		return 0, ErrMissingUser
+	}
+	_, modified, err := b.repo.UpdateMany(ctx,
+		spec.And(spec.Eq("user_id", userID), spec.Eq("read", false)),
+		markRead(),
+	)
+	return modified, err
+}
+
+// Delete removes notification id of userID. It reports false if there was no
+// such notification.
+func (b *Inbox) Delete(ctx context.Context, userID string, id primitive.ObjectID) (bool, error) {
+	if userID == "" {
+		return false, ErrMissingUser
+	}
+	n, err := b.repo.DeleteOne(ctx, spec.And(spec.Eq("_id", id), spec.Eq("user_id", userID)))
+	return n > 0, err
+}
+
+// PruneRead deletes read notifications of all users created before the given
+// time, and returns how many were deleted.
+func (b *Inbox) PruneRead(ctx context.Context, before time.Time) (int64, error) {
+	return b.repo.DeleteMany(ctx, spec.And(spec.Eq("read", true), spec.Lt("created_at", before)))
+}
+
+func markRead() spec.Update {
+	return spec.SetFields(bson.M{"read": true, "read_at": time.Now().UTC()})
+}
@@ -0,0 +1,79 @@
+//go:build integration
+
+package inbox_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/inbox"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestInbox_MongoIndexesAndCounts(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("notifications")
+	box := inbox.New(mongorepo.New[inbox.Notification](coll))
+	if err := box.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes: %v", err)
+	}
+
+	for _, title := range []string{"a", "b", "c"} {
+		if err := box.Append(ctx, &inbox.Notification{UserID: "u1", Title: title}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if n, err := box.MarkAllRead(ctx, "u1"); err != nil || n != 3 {
+		t.Fatalf("MarkAllRead = %d, %v; want 3", n, err)
+	}
+	if err := box.Append(ctx, &inbox.Notification{UserID: "u1", Title: "d"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if n, err := box.UnreadCount(ctx, "u1"); err != nil || n != 1 {
+		t.Fatalf("UnreadCount = %d, %v; want 1", n, err)
+	}
+
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		t.Fatalf("ListSpecifications: %v", err)
+	}
+	found := false
+	for _, s := range specs {
+		found = found || s.Name == "inbox_user_read_created"
+	}
+	if !found {
+		t.Fatal("expected the unread-count index to be created")
+	}
+}
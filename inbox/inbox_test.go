@@ -0,0 +1,97 @@
+package inbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/inbox"
+	memoryrepo "github.com/dElCIoGio/mongox/repository/memory"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestInbox_AppendListAndMarkRead(t *testing.T) {
+	ctx := context.Background()
+	box := inbox.New(memoryrepo.New[inbox.Notification]())
+
+	var ids []primitive.ObjectID
+	for i, title := range []string{"first", "second", "third"} {
+		n := &inbox.Notification{UserID: "u1", Kind: "comment", Title: title}
+		if i == 2 {
+			n.Kind = "mention"
+		}
+		if err := box.Append(ctx, n); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		ids = append(ids, n.ID)
+		time.Sleep(2 * time.Millisecond) // distinct created_at for ordering
+	}
+	if err := box.Append(ctx, &inbox.Notification{UserID: "u2", Title: "other"}); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	if n, err := box.UnreadCount(ctx, "u1"); err != nil || n != 3 {
+		t.Fatalf("UnreadCount = %d, %v; want 3", n, err)
+	}
+
+	page, err := box.List(ctx, "u1", 1, 2)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != 3 || page.TotalPages != 2 || !page.HasNext || len(page.Items) != 2 || page.Items[0].Title != "third" {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+
+	if ok, err := box.MarkRead(ctx, "u2", ids[0]); err != nil || ok {
+		t.Fatalf("MarkRead by another user = %v, %v; want false", ok, err)
+	}
+	if ok, err := box.MarkRead(ctx, "u1", ids[0]); err != nil || !ok {
+		t.Fatalf("MarkRead = %v, %v; want true", ok, err)
+	}
+	if ok, _ := box.MarkRead(ctx, "u1", ids[0]); ok {
+		t.Fatal("MarkRead of a read notification must report false")
+	}
+
+	unread, err := box.List(ctx, "u1", 1, 10, inbox.UnreadOnly())
+	if err != nil || unread.Total != 2 {
+		t.Fatalf("List unread total = %v, %v; want 2", unread, err)
+	}
+	mentions, err := box.List(ctx, "u1", 1, 10, inbox.OfKind("mention"))
+	if err != nil || mentions.Total != 1 || mentions.Items[0].Title != "third" {
+		t.Fatalf("List mentions = %+v, %v", mentions, err)
+	}
+
+	if n, err := box.MarkAllRead(ctx, "u1"); err != nil || n != 2 {
+		t.Fatalf("MarkAllRead = %d, %v; want 2", n, err)
+	}
+	if n, _ := box.UnreadCount(ctx, "u1"); n != 0 {
+		t.Fatalf("UnreadCount after MarkAllRead = %d, want 0", n)
+	}
+	if n, _ := box.UnreadCount(ctx, "u2"); n != 1 {
+		t.Fatalf("UnreadCount of u2 = %d, want 1", n)
+	}
+
+	if n, err := box.PruneRead(ctx, time.Now().Add(time.Second)); err != nil || n != 3 {
+		t.Fatalf("PruneRead = %d, %v; want 3", n, err)
+	}
+	if ok, err := box.Delete(ctx, "u1", ids[1]); err != nil || ok {
+		t.Fatalf("Delete of a pruned notification = %v, %v; want false", ok, err)
+	}
+}
+
+func TestInbox_RequiresUser(t *testing.T) {
+	ctx := context.Background()
+	box := inbox.New(memoryrepo.New[inbox.Notification]())
+
+	if err := box.Append(ctx, &inbox.Notification{Title: "x"}); !errors.Is(err, inbox.ErrMissingUser) {
+		t.Fatalf("Append err = %v, want ErrMissingUser", err)
+	}
+	if _, err := box.UnreadCount(ctx, ""); !errors.Is(err, inbox.ErrMissingUser) {
+		t.Fatalf("UnreadCount err = %v, want ErrMissingUser", err)
+	}
+	if _, err := box.List(ctx, "", 1, 10); !errors.Is(err, inbox.ErrMissingUser) {
+		t.Fatalf("List err = %v, want ErrMissingUser", err)
+	}
+}
@@ -0,0 +1,453 @@
+package docstore
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dElCIoGio/mongox/internal/match"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Aggregate runs pipeline over the collection. It supports the stages most
+// repository code uses:
+//
+//	$match, $sort, $skip, $limit, $count, $unwind (path form),
+//	$project, $addFields / $set, $unset, $replaceRoot,
+//	$group with $sum, $avg, $min, $max, $first, $last, $push, $addToSet
+//
+// Expressions are limited to literals and "$field" paths. Other stages and
+// expression operators return an error wrapping match.ErrUnsupported.
+func (c *Collection) Aggregate(pipeline []bson.M) ([]bson.M, error) {
+	docs := c.All()
+	for i, stage := range pipeline {
+		if len(stage) != 1 {
+			return nil, fmt.Errorf("docstore: stage %d must have exactly one key", i)
+		}
+		for name, arg := range stage {
+			var err error
+			docs, err = runStage(docs, name, arg)
+			if err != nil {
+				return nil, fmt.Errorf("docstore: stage %d (%s): %w", i, name, err)
+			}
+		}
+	}
+	return docs, nil
+}
+
+func runStage(docs []bson.M, name string, arg any) ([]bson.M, error) {
+	switch name {
+	case "$match":
+		out := docs[:0:0]
+		for _, d := range docs {
+			ok, err := match.Matches(arg, d)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out = append(out, d)
+			}
+		}
+		return out, nil
+	case "$sort":
+		return docs, Sort(docs, arg)
+	case "$skip", "$limit":
+		n, ok := match.ToFloat(arg)
+		if !ok || n < 0 {
+			return nil, fmt.Errorf("argument must be a non-negative number")
+		}
+		if name == "$skip" {
+			return page(docs, int64(n), 0), nil
+		}
+		return page(docs, 0, int64(n)), nil
+	case "$count":
+		field, ok := arg.(string)
+		if !ok || field == "" {
+			return nil, fmt.Errorf("argument must be a field name")
+		}
+		if len(docs) == 0 {
+			return []bson.M{}, nil
+		}
+		return []bson.M{{field: int32(len(docs))}}, nil
+	case "$unwind":
+		return unwind(docs, arg)
+	case "$project":
+		return project(docs, arg)
+	case "$addFields", "$set":
+		fields, ok := match.ToMap(arg)
+		if !ok {
+			return nil, fmt.Errorf("argument must be a document")
+		}
+		for _, d := range docs {
+			for path, expr := range fields {
+				v, err := eval(expr, d)
+				if err != nil {
+					return nil, err
+				}
+				if err := match.SetPath(d, path, v); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return docs, nil
+	case "$unset":
+		var paths []any
+		if s, ok := arg.(string); ok {
+			paths = []any{s}
+		} else if s, ok := match.ToSlice(arg); ok {
+			paths = s
+		}
+		for _, d := range docs {
+			for _, p := range paths {
+				if s, ok := p.(string); ok {
+					match.UnsetPath(d, s)
+				}
+			}
+		}
+		return docs, nil
+	case "$replaceRoot":
+		spec, ok := match.ToMap(arg)
+		if !ok {
+			return nil, fmt.Errorf("argument must be a document")
+		}
+		out := make([]bson.M, 0, len(docs))
+		for _, d := range docs {
+			v, err := eval(spec["newRoot"], d)
+			if err != nil {
+				return nil, err
+			}
+			m, ok := match.ToMap(v)
+			if !ok {
+				return nil, fmt.Errorf("newRoot must evaluate to a document")
+			}
+			out = append(out, m)
+		}
+		return out, nil
+	case "$group":
+		return group(docs, arg)
+	default:
+		return nil, match.ErrUnsupported
+	}
+}
+
+// eval evaluates an aggregation expression: a "$path" reference, a document of
+// expressions, an array of expressions, or a literal.
+func eval(expr any, doc bson.M) (any, error) {
+	switch e := expr.(type) {
+	case string:
+		if strings.HasPrefix(e, "$$") {
+			if e == "$$ROOT" {
+				return doc, nil
+			}
+			return nil, fmt.Errorf("variable %s: %w", e, match.ErrUnsupported)
+		}
+		if strings.HasPrefix(e, "$") {
+			v, _ := match.Lookup(doc, e[1:])
+			return v, nil
+		}
+		return e, nil
+	}
+	if m, ok := match.ToMap(expr); ok {
+		out := bson.M{}
+		for k, v := range m {
+			if strings.HasPrefix(k, "$") {
+				if k == "$literal" {
+					return v, nil
+				}
+				return nil, fmt.Errorf("expression %s: %w", k, match.ErrUnsupported)
+			}
+			ev, err := eval(v, doc)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = ev
+		}
+		return out, nil
+	}
+	if s, ok := match.ToSlice(expr); ok {
+		out := make(bson.A, len(s))
+		for i, v := range s {
+			ev, err := eval(v, doc)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = ev
+		}
+		return out, nil
+	}
+	return expr, nil
+}
+
+func unwind(docs []bson.M, arg any) ([]bson.M, error) {
+	path, ok := arg.(string)
+	if m, isDoc := match.ToMap(arg); isDoc {
+		path, ok = m["path"].(string)
+	}
+	if !ok || !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("path must be a \"$field\" string")
+	}
+	path = path[1:]
+
+	var out []bson.M
+	for _, d := range docs {
+		v, exists := match.Lookup(d, path)
+		items, isArr := match.ToSlice(v)
+		if !exists || v == nil || (isArr && len(items) == 0) {
+			continue
+		}
+		if !isArr {
+			out = append(out, d)
+			continue
+		}
+		for _, item := range items {
+			cp := clone(d)
+			if err := match.SetPath(cp, path, item); err != nil {
+				return nil, err
+			}
+			out = append(out, cp)
+		}
+	}
+	return out, nil
+}
+
+func project(docs []bson.M, arg any) ([]bson.M, error) {
+	spec, ok := match.ToMap(arg)
+	if !ok {
+		return nil, fmt.Errorf("argument must be a document")
+	}
+
+	exclude := true
+	keepID := true
+	for k, v := range spec {
+		if n, ok := projFlag(v); ok {
+			if k == "_id" {
+				keepID = n
+				continue
+			}
+			if n {
+				exclude = false
+			}
+		} else {
+			exclude = false
+		}
+	}
+
+	out := make([]bson.M, 0, len(docs))
+	for _, d := range docs {
+		if exclude {
+			for k := range spec {
+				if k != "_id" {
+					match.UnsetPath(d, k)
+				}
+			}
+			if !keepID {
+				delete(d, "_id")
+			}
+			out = append(out, d)
+			continue
+		}
+
+		p := bson.M{}
+		if keepID {
+			if id, ok := d["_id"]; ok {
+				p["_id"] = id
+			}
+		}
+		for k, v := range spec {
+			if k == "_id" {
+				if _, isFlag := projFlag(v); isFlag {
+					continue
+				}
+			}
+			if n, ok := projFlag(v); ok {
+				if n {
+					if val, exists := match.Lookup(d, k); exists {
+						if err := match.SetPath(p, k, val); err != nil {
+							return nil, err
+						}
+					}
+				}
+				continue
+			}
+			val, err := eval(v, d)
+			if err != nil {
+				return nil, err
+			}
+			if err := match.SetPath(p, k, val); err != nil {
+				return nil, err
+			}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// projFlag interprets 0/1/true/false projection values.
+func projFlag(v any) (bool, bool) {
+	if b, ok := v.(bool); ok {
+		return b, true
+	}
+	if n, ok := match.ToFloat(v); ok {
+		return n != 0, true
+	}
+	return false, false
+}
+
+type groupAcc struct {
+	key    any
+	values bson.M
+	counts map[string]int
+}
+
+func group(docs []bson.M, arg any) ([]bson.M, error) {
+	spec, ok := match.ToMap(arg)
+	if !ok {
+		return nil, fmt.Errorf("argument must be a document")
+	}
+	idExpr, ok := spec["_id"]
+	if !ok {
+		return nil, fmt.Errorf("an _id expression is required")
+	}
+
+	type accSpec struct {
+		field, op string
+		expr      any
+	}
+	var accs []accSpec
+	for field, raw := range spec {
+		if field == "_id" {
+			continue
+		}
+		m, ok := match.ToMap(raw)
+		if !ok || len(m) != 1 {
+			return nil, fmt.Errorf("field %q must be an accumulator document", field)
+		}
+		for op, expr := range m {
+			switch op {
+			case "$sum", "$avg", "$min", "$max", "$first", "$last", "$push", "$addToSet":
+			default:
+				return nil, fmt.Errorf("accumulator %s: %w", op, match.ErrUnsupported)
+			}
+			accs = append(accs, accSpec{field: field, op: op, expr: expr})
+		}
+	}
+
+	var groups []*groupAcc
+	byKey := map[string]*groupAcc{}
+	for _, d := range docs {
+		key, err := eval(idExpr, d)
+		if err != nil {
+			return nil, err
+		}
+		k, err := idKey(key)
+		if err != nil {
+			return nil, err
+		}
+		g, ok := byKey[k]
+		if !ok {
+			g = &groupAcc{key: key, values: bson.M{}, counts: map[string]int{}}
+			byKey[k] = g
+			groups = append(groups, g)
+		}
+		for _, a := range accs {
+			v, err := eval(a.expr, d)
+			if err != nil {
+				return nil, err
+			}
+			accumulate(g, a.field, a.op, v)
+		}
+	}
+
+	out := make([]bson.M, 0, len(groups))
+	for _, g := range groups {
+		row := bson.M{"_id": g.key}
+		for _, a := range accs {
+			v := g.values[a.field]
+			if a.op == "$avg" {
+				if n := g.counts[a.field]; n > 0 {
+					sum, _ := match.ToFloat(v)
+					v = sum / float64(n)
+				} else {
+					v = nil
+				}
+			}
+			if v == nil && (a.op == "$push" || a.op == "$addToSet") {
+				v = bson.A{}
+			}
+			if v == nil && a.op == "$sum" {
+				v = int32(0)
+			}
+			row[a.field] = v
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+func accumulate(g *groupAcc, field, op string, v any) {
+	cur, seen := g.values[field]
+	switch op {
+	case "$sum", "$avg":
+		if _, ok := match.ToFloat(v); !ok {
+			return
+		}
+		g.counts[field]++
+		if !seen {
+			g.values[field] = v
+			return
+		}
+		g.values[field] = sumValues(cur, v)
+	case "$min", "$max":
+		if v == nil {
+			return
+		}
+		c := CompareValues(v, cur)
+		if !seen || cur == nil || (op == "$min" && c < 0) || (op == "$max" && c > 0) {
+			g.values[field] = v
+		}
+	case "$first":
+		if !seen {
+			g.values[field] = v
+		}
+	case "$last":
+		g.values[field] = v
+	case "$push", "$addToSet":
+		arr, _ := cur.(bson.A)
+		if op == "$addToSet" {
+			for _, existing := range arr {
+				if match.Equal(existing, v) {
+					return
+				}
+			}
+		}
+		g.values[field] = append(arr, v)
+	}
+}
+
+// sumValues adds two numbers, keeping integers as integers.
+func sumValues(a, b any) any {
+	ai, aInt := asInt(a)
+	bi, bInt := asInt(b)
+	if aInt && bInt {
+		s := ai + bi
+		if s >= -1<<31 && s < 1<<31 {
+			return int32(s)
+		}
+		return s
+	}
+	af, _ := match.ToFloat(a)
+	bf, _ := match.ToFloat(b)
+	return af + bf
+}
+
+func asInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	}
+	return 0, false
+}
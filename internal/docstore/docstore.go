@@ -0,0 +1,468 @@
+// Package docstore is an in-memory document collection with MongoDB query,
+// update, sort, and (a subset of) aggregation semantics. It backs the embedded
+// and in-memory repositories; filters and updates are evaluated by package match.
+package docstore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dElCIoGio/mongox/internal/match"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrDuplicateKey is returned when an insert or upsert reuses an existing _id.
+var ErrDuplicateKey = errors.New("docstore: duplicate _id")
+
+// Collection holds documents in insertion order. It is safe for concurrent use.
+type Collection struct {
+	mu   sync.RWMutex
+	docs []bson.M
+	ids  map[string]int // _id key -> index in docs
+}
+
+// New creates an empty collection.
+func New() *Collection {
+	return &Collection{ids: make(map[string]int)}
+}
+
+// Canonical converts v to the types MongoDB would store and return: structs and
+// typed values become bson.M, ints become int32/int64, times become
+// primitive.DateTime, and so on. Filters and updates are canonicalized before
+// evaluation so they compare like stored values.
+func Canonical(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if raw, ok := v.(bson.Raw); ok {
+		var m bson.M
+		err := bson.Unmarshal(raw, &m)
+		return m, err
+	}
+	b, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		return nil, err
+	}
+	// Decoding into bson.M makes nested documents bson.M as well.
+	var out bson.M
+	if err := bson.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out["v"], nil
+}
+
+// CanonicalDoc canonicalizes a document-like value to bson.M.
+func CanonicalDoc(v any) (bson.M, error) {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func idKey(id any) (string, error) {
+	b, err := bson.Marshal(bson.M{"_id": id})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// clone returns a deep copy of doc.
+func clone(doc bson.M) bson.M {
+	out, err := CanonicalDoc(doc)
+	if err != nil {
+		// Documents in the store are canonical, so this cannot fail.
+		panic(fmt.Sprintf("docstore: clone: %v", err))
+	}
+	return out
+}
+
+// Insert adds documents, assigning an ObjectID to those without _id.
+// It returns the _id of every document. Documents before the first duplicate are kept.
+func (c *Collection) Insert(docs ...bson.M) ([]any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := make([]any, 0, len(docs))
+	for _, doc := range docs {
+		doc = clone(doc)
+		if _, ok := doc["_id"]; !ok {
+			doc["_id"] = primitive.NewObjectID()
+		}
+		if err := c.insertLocked(doc); err != nil {
+			return ids, err
+		}
+		ids = append(ids, doc["_id"])
+	}
+	return ids, nil
+}
+
+func (c *Collection) insertLocked(doc bson.M) error {
+	key, err := idKey(doc["_id"])
+	if err != nil {
+		return err
+	}
+	if _, dup := c.ids[key]; dup {
+		return fmt.Errorf("%w: %v", ErrDuplicateKey, doc["_id"])
+	}
+	c.ids[key] = len(c.docs)
+	c.docs = append(c.docs, doc)
+	return nil
+}
+
+// FindOptions controls Find.
+type FindOptions struct {
+	Sort       any
+	Skip       int64
+	Limit      int64
+	Projection any
+}
+
+// Find returns copies of the documents matching filter.
+func (c *Collection) Find(filter any, opts FindOptions) ([]bson.M, error) {
+	c.mu.RLock()
+	idx, err := c.matchLocked(filter, -1)
+	var out []bson.M
+	if err == nil {
+		out = make([]bson.M, len(idx))
+		for i, j := range idx {
+			out[i] = clone(c.docs[j])
+		}
+	}
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Sort != nil {
+		if err := Sort(out, opts.Sort); err != nil {
+			return nil, err
+		}
+	}
+	out = page(out, opts.Skip, opts.Limit)
+	if opts.Projection != nil {
+		return project(out, opts.Projection)
+	}
+	return out, nil
+}
+
+// Count returns the number of documents matching filter.
+func (c *Collection) Count(filter any) (int64, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	idx, err := c.matchLocked(filter, -1)
+	return int64(len(idx)), err
+}
+
+// All returns copies of all documents in insertion order.
+func (c *Collection) All() []bson.M {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make([]bson.M, len(c.docs))
+	for i, d := range c.docs {
+		out[i] = clone(d)
+	}
+	return out
+}
+
+// UpdateResult reports the outcome of Update and Replace.
+type UpdateResult struct {
+	Matched    int64
+	Modified   int64
+	UpsertedID any
+}
+
+// Update applies update to the first (or, with many, every) document matching
+// filter. With upsert and no match, a document is built from the filter's
+// equality conditions and the update, then inserted.
+func (c *Collection) Update(filter, update any, many, upsert bool) (UpdateResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	limit := 1
+	if many {
+		limit = -1
+	}
+	idx, err := c.matchLocked(filter, limit)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	var res UpdateResult
+	for _, j := range idx {
+		doc := clone(c.docs[j])
+		if err := match.Apply(doc, update, false); err != nil {
+			return res, err
+		}
+		if !match.Equal(doc["_id"], c.docs[j]["_id"]) {
+			return res, fmt.Errorf("docstore: the _id field cannot be changed")
+		}
+		res.Matched++
+		if !match.Equal(doc, c.docs[j]) {
+			c.docs[j] = clone(doc)
+			res.Modified++
+		}
+	}
+	if res.Matched > 0 || !upsert {
+		return res, nil
+	}
+
+	doc := equalityFields(filter)
+	if err := match.Apply(doc, update, true); err != nil {
+		return res, err
+	}
+	if _, ok := doc["_id"]; !ok {
+		doc["_id"] = primitive.NewObjectID()
+	}
+	doc = clone(doc)
+	if err := c.insertLocked(doc); err != nil {
+		return res, err
+	}
+	res.UpsertedID = doc["_id"]
+	return res, nil
+}
+
+// Replace replaces the first document matching filter, keeping its _id.
+func (c *Collection) Replace(filter any, replacement bson.M, upsert bool) (UpdateResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idx, err := c.matchLocked(filter, 1)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	doc := clone(replacement)
+	if len(idx) == 0 {
+		if !upsert {
+			return UpdateResult{}, nil
+		}
+		if _, ok := doc["_id"]; !ok {
+			if id, ok := equalityFields(filter)["_id"]; ok {
+				doc["_id"] = id
+			} else {
+				doc["_id"] = primitive.NewObjectID()
+			}
+		}
+		if err := c.insertLocked(doc); err != nil {
+			return UpdateResult{}, err
+		}
+		return UpdateResult{UpsertedID: doc["_id"]}, nil
+	}
+
+	j := idx[0]
+	if id, ok := doc["_id"]; ok && !match.Equal(id, c.docs[j]["_id"]) {
+		return UpdateResult{}, fmt.Errorf("docstore: the _id field cannot be changed")
+	}
+	doc["_id"] = c.docs[j]["_id"]
+	res := UpdateResult{Matched: 1}
+	if !match.Equal(doc, c.docs[j]) {
+		c.docs[j] = doc
+		res.Modified = 1
+	}
+	return res, nil
+}
+
+// Delete removes the first (or, with many, every) document matching filter.
+func (c *Collection) Delete(filter any, many bool) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	limit := 1
+	if many {
+		limit = -1
+	}
+	idx, err := c.matchLocked(filter, limit)
+	if err != nil || len(idx) == 0 {
+		return 0, err
+	}
+
+	drop := make(map[int]bool, len(idx))
+	for _, j := range idx {
+		drop[j] = true
+	}
+	kept := c.docs[:0]
+	for j, d := range c.docs {
+		if !drop[j] {
+			kept = append(kept, d)
+		}
+	}
+	c.docs = kept
+	c.reindexLocked()
+	return int64(len(idx)), nil
+}
+
+// Load replaces the contents of the collection.
+func (c *Collection) Load(docs []bson.M) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs = nil
+	c.ids = make(map[string]int, len(docs))
+	for _, d := range docs {
+		if err := c.insertLocked(clone(d)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Collection) reindexLocked() {
+	c.ids = make(map[string]int, len(c.docs))
+	for j, d := range c.docs {
+		key, _ := idKey(d["_id"])
+		c.ids[key] = j
+	}
+}
+
+// matchLocked returns the indexes of up to limit matching documents (all if limit < 0).
+func (c *Collection) matchLocked(filter any, limit int) ([]int, error) {
+	var idx []int
+	for j, d := range c.docs {
+		ok, err := match.Matches(filter, d)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			idx = append(idx, j)
+			if limit > 0 && len(idx) == limit {
+				break
+			}
+		}
+	}
+	return idx, nil
+}
+
+// equalityFields extracts the fields a filter pins to a single value, the way
+// MongoDB seeds an upserted document.
+func equalityFields(filter any) bson.M {
+	out := bson.M{}
+	f, ok := match.ToMap(filter)
+	if !ok {
+		return out
+	}
+	for k, v := range f {
+		if k == "$and" {
+			if subs, ok := match.ToSlice(v); ok {
+				for _, sub := range subs {
+					for sk, sv := range equalityFields(sub) {
+						out[sk] = sv
+					}
+				}
+			}
+			continue
+		}
+		if len(k) > 0 && k[0] == '$' {
+			continue
+		}
+		if m, ok := match.ToMap(v); ok {
+			if eq, ok := m["$eq"]; ok {
+				_ = match.SetPath(out, k, eq)
+			}
+			continue
+		}
+		_ = match.SetPath(out, k, v)
+	}
+	return out
+}
+
+func page(docs []bson.M, skip, limit int64) []bson.M {
+	if skip > 0 {
+		if skip >= int64(len(docs)) {
+			return []bson.M{}
+		}
+		docs = docs[skip:]
+	}
+	if limit > 0 && limit < int64(len(docs)) {
+		docs = docs[:limit]
+	}
+	return docs
+}
+
+// Sort orders docs in place by a sort specification such as
+// bson.D{{"created_at", -1}}. A bson.M with more than one key has no defined
+// order and is rejected.
+func Sort(docs []bson.M, spec any) error {
+	var keys bson.D
+	switch s := spec.(type) {
+	case bson.D:
+		keys = s
+	case bson.M:
+		if len(s) > 1 {
+			return fmt.Errorf("docstore: sort with several keys must be a bson.D")
+		}
+		for k, v := range s {
+			keys = bson.D{{Key: k, Value: v}}
+		}
+	default:
+		return fmt.Errorf("docstore: unsupported sort specification %T", spec)
+	}
+
+	dirs := make([]int, len(keys))
+	for i, k := range keys {
+		n, ok := match.ToFloat(k.Value)
+		if !ok || (n != 1 && n != -1) {
+			return fmt.Errorf("docstore: sort direction for %q must be 1 or -1", k.Key)
+		}
+		dirs[i] = int(n)
+	}
+
+	sort.SliceStable(docs, func(a, b int) bool {
+		for i, k := range keys {
+			av, _ := match.Lookup(docs[a], k.Key)
+			bv, _ := match.Lookup(docs[b], k.Key)
+			if c := CompareValues(av, bv) * dirs[i]; c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// CompareValues orders any two BSON values, following MongoDB's cross-type
+// order (null < numbers < strings < documents < arrays < ObjectIDs < booleans < dates).
+func CompareValues(a, b any) int {
+	if c, ok := match.Compare(a, b); ok {
+		return c
+	}
+	ra, rb := typeRank(a), typeRank(b)
+	switch {
+	case ra < rb:
+		return -1
+	case ra > rb:
+		return 1
+	}
+	return 0
+}
+
+func typeRank(v any) int {
+	if v == nil {
+		return 0
+	}
+	if _, ok := match.ToFloat(v); ok {
+		return 1
+	}
+	switch v.(type) {
+	case string:
+		return 2
+	case bson.M, bson.D, map[string]any:
+		return 3
+	case bson.A, []any:
+		return 4
+	case primitive.ObjectID:
+		return 5
+	case bool:
+		return 6
+	case primitive.DateTime:
+		return 7
+	}
+	return 8
+}
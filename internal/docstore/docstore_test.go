@@ -0,0 +1,174 @@
+package docstore
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/internal/match"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func seed(t *testing.T) *Collection {
+	t.Helper()
+	c := New()
+	_, err := c.Insert(
+		bson.M{"_id": 1, "name": "ada", "team": "core", "score": 7, "tags": bson.A{"go", "db"}},
+		bson.M{"_id": 2, "name": "bob", "team": "web", "score": 3, "tags": bson.A{"js"}},
+		bson.M{"_id": 3, "name": "cy", "team": "core", "score": 5},
+	)
+	if err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	return c
+}
+
+func TestInsert_DuplicateID(t *testing.T) {
+	c := seed(t)
+	_, err := c.Insert(bson.M{"_id": 2})
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+	if n, _ := c.Count(nil); n != 3 {
+		t.Fatalf("expected 3 documents, got %d", n)
+	}
+}
+
+func TestFind_SortSkipLimit(t *testing.T) {
+	c := seed(t)
+	docs, err := c.Find(bson.M{"team": "core"}, FindOptions{Sort: bson.D{{Key: "score", Value: 1}}})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(docs) != 2 || docs[0]["name"] != "cy" || docs[1]["name"] != "ada" {
+		t.Fatalf("unexpected result: %v", docs)
+	}
+
+	docs, err = c.Find(nil, FindOptions{Sort: bson.M{"score": -1}, Skip: 1, Limit: 1})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(docs) != 1 || docs[0]["name"] != "cy" {
+		t.Fatalf("unexpected page: %v", docs)
+	}
+}
+
+func TestFind_ReturnsCopies(t *testing.T) {
+	c := seed(t)
+	docs, _ := c.Find(bson.M{"_id": 1}, FindOptions{})
+	docs[0]["name"] = "changed"
+	again, _ := c.Find(bson.M{"_id": 1}, FindOptions{})
+	if again[0]["name"] != "ada" {
+		t.Fatal("mutating a result changed the stored document")
+	}
+}
+
+func TestUpdate_ManyAndModifiedCount(t *testing.T) {
+	c := seed(t)
+	res, err := c.Update(bson.M{"team": "core"}, bson.M{"$set": bson.M{"score": 5}}, true, false)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if res.Matched != 2 || res.Modified != 1 {
+		t.Fatalf("expected 2 matched / 1 modified, got %+v", res)
+	}
+}
+
+func TestUpdate_UpsertSeedsFromFilter(t *testing.T) {
+	c := seed(t)
+	res, err := c.Update(bson.M{"name": "dee", "team": bson.M{"$eq": "ops"}}, bson.M{"$inc": bson.M{"score": 1}}, false, true)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if res.UpsertedID == nil {
+		t.Fatal("expected an upserted _id")
+	}
+	docs, _ := c.Find(bson.M{"name": "dee"}, FindOptions{})
+	if len(docs) != 1 || docs[0]["team"] != "ops" || !match.Equal(docs[0]["score"], 1) {
+		t.Fatalf("unexpected upserted document: %v", docs)
+	}
+}
+
+func TestUpdate_RejectsIDChange(t *testing.T) {
+	c := seed(t)
+	if _, err := c.Update(bson.M{"_id": 1}, bson.M{"$set": bson.M{"_id": 9}}, false, false); err == nil {
+		t.Fatal("expected error when changing _id")
+	}
+}
+
+func TestReplace_KeepsID(t *testing.T) {
+	c := seed(t)
+	res, err := c.Replace(bson.M{"name": "bob"}, bson.M{"name": "bobby"}, false)
+	if err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if res.Matched != 1 || res.Modified != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	docs, _ := c.Find(bson.M{"_id": 2}, FindOptions{})
+	if len(docs) != 1 || docs[0]["name"] != "bobby" || docs[0]["team"] != nil {
+		t.Fatalf("unexpected replacement: %v", docs)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	c := seed(t)
+	n, err := c.Delete(bson.M{"team": "core"}, true)
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 deleted, got %d (%v)", n, err)
+	}
+	if _, err := c.Insert(bson.M{"_id": 1}); err != nil {
+		t.Fatalf("expected deleted _id to be reusable: %v", err)
+	}
+}
+
+func TestAggregate_GroupSortProject(t *testing.T) {
+	c := seed(t)
+	out, err := c.Aggregate([]bson.M{
+		{"$match": bson.M{"score": bson.M{"$gte": 3}}},
+		{"$group": bson.M{
+			"_id":   "$team",
+			"total": bson.M{"$sum": "$score"},
+			"avg":   bson.M{"$avg": "$score"},
+			"names": bson.M{"$push": "$name"},
+			"n":     bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.D{{Key: "total", Value: -1}}},
+		{"$project": bson.M{"team": "$_id", "total": 1, "avg": 1, "n": 1, "_id": 0}},
+	})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 groups, got %v", out)
+	}
+	core := out[0]
+	if core["team"] != "core" || !match.Equal(core["total"], 12) || !match.Equal(core["avg"], 6.0) || !match.Equal(core["n"], 2) {
+		t.Fatalf("unexpected core group: %v", core)
+	}
+	if _, ok := core["_id"]; ok {
+		t.Fatal("expected _id to be excluded")
+	}
+}
+
+func TestAggregate_UnwindCount(t *testing.T) {
+	c := seed(t)
+	out, err := c.Aggregate([]bson.M{
+		{"$unwind": "$tags"},
+		{"$count": "n"},
+	})
+	if err != nil {
+		t.Fatalf("Aggregate: %v", err)
+	}
+	if len(out) != 1 || !match.Equal(out[0]["n"], 3) {
+		t.Fatalf("unexpected count: %v", out)
+	}
+}
+
+func TestAggregate_UnsupportedStage(t *testing.T) {
+	c := seed(t)
+	_, err := c.Aggregate([]bson.M{{"$lookup": bson.M{}}})
+	if !errors.Is(err, match.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
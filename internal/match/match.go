@@ -0,0 +1,500 @@
+// Package match evaluates MongoDB query filters and update documents against
+// in-memory documents. It implements the subset of query and update semantics
+// produced by the spec package and is shared by the test helpers and the
+// in-memory repository.
+package match
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUnsupported is returned when a filter or update uses an operator the
+// matcher does not implement.
+var ErrUnsupported = errors.New("match: unsupported operator")
+
+// Matches reports whether doc satisfies filter.
+// The filter may be bson.M, bson.D, or map[string]any; a nil filter matches everything.
+func Matches(filter any, doc bson.M) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+	f, ok := ToMap(filter)
+	if !ok {
+		return false, fmt.Errorf("match: filter must be a document, got %T", filter)
+	}
+	return matchDoc(f, doc)
+}
+
+func matchDoc(filter bson.M, doc bson.M) (bool, error) {
+	for key, cond := range filter {
+		ok, err := matchKey(key, cond, doc)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func matchKey(key string, cond any, doc bson.M) (bool, error) {
+	switch key {
+	case "$and", "$or", "$nor":
+		subs, ok := ToSlice(cond)
+		if !ok {
+			return false, fmt.Errorf("match: %s requires an array", key)
+		}
+		for _, sub := range subs {
+			m, ok := ToMap(sub)
+			if !ok {
+				return false, fmt.Errorf("match: %s entries must be documents", key)
+			}
+			matched, err := matchDoc(m, doc)
+			if err != nil {
+				return false, err
+			}
+			switch {
+			case key == "$and" && !matched:
+				return false, nil
+			case key == "$or" && matched:
+				return true, nil
+			case key == "$nor" && matched:
+				return false, nil
+			}
+		}
+		return key != "$or", nil
+	}
+	if strings.HasPrefix(key, "$") {
+		return false, fmt.Errorf("%w: %s", ErrUnsupported, key)
+	}
+
+	value, exists := Lookup(doc, key)
+	if ops, ok := ToMap(cond); ok && isOperatorDoc(ops) {
+		for op, arg := range ops {
+			if op == "$options" {
+				continue
+			}
+			matched, err := matchOp(op, arg, ops, value, exists)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return equalsOrContains(value, exists, cond), nil
+}
+
+func matchOp(op string, arg any, ops bson.M, value any, exists bool) (bool, error) {
+	switch op {
+	case "$eq":
+		return equalsOrContains(value, exists, arg), nil
+	case "$ne":
+		return !equalsOrContains(value, exists, arg), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		if !exists {
+			return false, nil
+		}
+		return anyCandidate(value, func(v any) bool {
+			c, ok := Compare(v, arg)
+			if !ok {
+				return false
+			}
+			switch op {
+			case "$gt":
+				return c > 0
+			case "$gte":
+				return c >= 0
+			case "$lt":
+				return c < 0
+			default:
+				return c <= 0
+			}
+		}), nil
+	case "$in", "$nin":
+		values, ok := ToSlice(arg)
+		if !ok {
+			return false, fmt.Errorf("match: %s requires an array", op)
+		}
+		found := false
+		for _, want := range values {
+			if equalsOrContains(value, exists, want) {
+				found = true
+				break
+			}
+		}
+		if op == "$in" {
+			return found, nil
+		}
+		return !found, nil
+	case "$exists":
+		want, _ := arg.(bool)
+		return exists == want, nil
+	case "$regex":
+		pattern, ok := arg.(string)
+		if !ok {
+			return false, fmt.Errorf("match: $regex requires a string pattern")
+		}
+		if opts, _ := ops["$options"].(string); opts != "" {
+			flags := strings.Map(func(r rune) rune {
+				if strings.ContainsRune("ims", r) {
+					return r
+				}
+				return -1
+			}, opts)
+			if flags != "" {
+				pattern = "(?" + flags + ")" + pattern
+			}
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return false, nil
+		}
+		return anyCandidate(value, func(v any) bool {
+			s, ok := v.(string)
+			return ok && re.MatchString(s)
+		}), nil
+	case "$all":
+		values, ok := ToSlice(arg)
+		if !ok {
+			return false, fmt.Errorf("match: $all requires an array")
+		}
+		if !exists || len(values) == 0 {
+			return false, nil
+		}
+		for _, want := range values {
+			if !equalsOrContains(value, exists, want) {
+				return false, nil
+			}
+		}
+		return true, nil
+	case "$size":
+		arr, ok := ToSlice(value)
+		if !exists || !ok {
+			return false, nil
+		}
+		n, ok := toFloat(arg)
+		return ok && float64(len(arr)) == n, nil
+	case "$elemMatch":
+		arr, ok := ToSlice(value)
+		if !exists || !ok {
+			return false, nil
+		}
+		cond, ok := ToMap(arg)
+		if !ok {
+			return false, fmt.Errorf("match: $elemMatch requires a document")
+		}
+		for _, elem := range arr {
+			var matched bool
+			var err error
+			if isOperatorDoc(cond) {
+				matched, err = matchKey("v", cond, bson.M{"v": elem})
+			} else if m, ok := ToMap(elem); ok {
+				matched, err = matchDoc(cond, m)
+			}
+			if err != nil {
+				return false, err
+			}
+			if matched {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$not":
+		cond, ok := ToMap(arg)
+		if !ok {
+			return false, fmt.Errorf("match: $not requires a document")
+		}
+		matched, err := matchKey("v", cond, wrap(value, exists))
+		return !matched, err
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnsupported, op)
+	}
+}
+
+func wrap(value any, exists bool) bson.M {
+	if !exists {
+		return bson.M{}
+	}
+	return bson.M{"v": value}
+}
+
+func isOperatorDoc(m bson.M) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for k := range m {
+		if !strings.HasPrefix(k, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// equalsOrContains implements MongoDB equality: a field matches a value if it
+// equals the value or, for arrays, if any element equals it.
+// A nil value also matches missing fields.
+func equalsOrContains(value any, exists bool, want any) bool {
+	if !exists {
+		return want == nil
+	}
+	if Equal(value, want) {
+		return true
+	}
+	if arr, ok := ToSlice(value); ok {
+		for _, elem := range arr {
+			if Equal(elem, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyCandidate(value any, fn func(any) bool) bool {
+	if fn(value) {
+		return true
+	}
+	if arr, ok := ToSlice(value); ok {
+		for _, elem := range arr {
+			if fn(elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Lookup resolves a dotted path in doc. Paths traverse embedded documents and,
+// for non-numeric segments, the documents inside arrays (collecting the values).
+func Lookup(doc bson.M, path string) (any, bool) {
+	return lookup(doc, strings.Split(path, "."))
+}
+
+func lookup(current any, parts []string) (any, bool) {
+	if len(parts) == 0 {
+		return current, true
+	}
+	if m, ok := ToMap(current); ok {
+		next, exists := m[parts[0]]
+		if !exists {
+			return nil, false
+		}
+		return lookup(next, parts[1:])
+	}
+	if arr, ok := ToSlice(current); ok {
+		var idx int
+		if _, err := fmt.Sscanf(parts[0], "%d", &idx); err == nil && fmt.Sprint(idx) == parts[0] {
+			if idx < 0 || idx >= len(arr) {
+				return nil, false
+			}
+			return lookup(arr[idx], parts[1:])
+		}
+		var collected primitive.A
+		for _, elem := range arr {
+			if v, ok := lookup(elem, parts); ok {
+				collected = append(collected, v)
+			}
+		}
+		if len(collected) == 0 {
+			return nil, false
+		}
+		return collected, true
+	}
+	return nil, false
+}
+
+// Equal reports whether two BSON values are equal, treating all numeric types
+// as comparable and documents/arrays structurally.
+func Equal(a, b any) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if c, ok := Compare(a, b); ok {
+		return c == 0
+	}
+	if am, ok := ToMap(a); ok {
+		bm, ok := ToMap(b)
+		if !ok || len(am) != len(bm) {
+			return false
+		}
+		for k, av := range am {
+			bv, exists := bm[k]
+			if !exists || !Equal(av, bv) {
+				return false
+			}
+		}
+		return true
+	}
+	if as, ok := ToSlice(a); ok {
+		bs, ok := ToSlice(b)
+		if !ok || len(as) != len(bs) {
+			return false
+		}
+		for i := range as {
+			if !Equal(as[i], bs[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Compare orders two scalar BSON values of the same type class.
+// It returns false when the values are not comparable (e.g. a string and a number).
+func Compare(a, b any) (int, bool) {
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		if !ok {
+			return 0, false
+		}
+		return cmp(af < bf, af > bf), true
+	}
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av, bv), true
+	case bool:
+		bv, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		return cmp(!av && bv, av && !bv), true
+	case primitive.ObjectID:
+		bv, ok := b.(primitive.ObjectID)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(av.Hex(), bv.Hex()), true
+	}
+	if at, ok := toTime(a); ok {
+		bt, ok := toTime(b)
+		if !ok {
+			return 0, false
+		}
+		return cmp(at.Before(bt), at.After(bt)), true
+	}
+	return 0, false
+}
+
+func cmp(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ToFloat converts any numeric BSON value to float64.
+func ToFloat(v any) (float64, bool) {
+	return toFloat(v)
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case primitive.DateTime:
+		return t.Time(), true
+	case *time.Time:
+		if t == nil {
+			return time.Time{}, false
+		}
+		return *t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// ToMap converts document-like values (bson.M, bson.D, map[string]any) to bson.M.
+func ToMap(v any) (bson.M, bool) {
+	switch m := v.(type) {
+	case bson.M:
+		return m, true
+	case map[string]any:
+		return bson.M(m), true
+	case bson.D:
+		out := make(bson.M, len(m))
+		for _, e := range m {
+			out[e.Key] = e.Value
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+// ToSlice converts any slice or array value (except []byte) to []any.
+func ToSlice(v any) ([]any, bool) {
+	switch s := v.(type) {
+	case nil:
+		return nil, false
+	case []any:
+		return s, true
+	case primitive.A:
+		return s, true
+	case []bson.M:
+		out := make([]any, len(s))
+		for i := range s {
+			out[i] = s[i]
+		}
+		return out, true
+	case []byte, bson.D:
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	out := make([]any, rv.Len())
+	for i := range out {
+		out[i] = rv.Index(i).Interface()
+	}
+	return out, true
+}
@@ -0,0 +1,253 @@
+package match
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Apply applies an update document (operators such as $set and $inc) to doc in place.
+// When inserting is true, $setOnInsert fields are applied as well.
+func Apply(doc bson.M, update any, inserting bool) error {
+	u, ok := ToMap(update)
+	if !ok {
+		return fmt.Errorf("match: update must be a document, got %T", update)
+	}
+	for op, raw := range u {
+		fields, ok := ToMap(raw)
+		if !ok {
+			return fmt.Errorf("match: %s requires a document", op)
+		}
+		for path, arg := range fields {
+			if err := applyOp(doc, op, path, arg, inserting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func applyOp(doc bson.M, op, path string, arg any, inserting bool) error {
+	current, exists := Lookup(doc, path)
+
+	switch op {
+	case "$set":
+		return SetPath(doc, path, arg)
+	case "$setOnInsert":
+		if !inserting {
+			return nil
+		}
+		return SetPath(doc, path, arg)
+	case "$unset":
+		UnsetPath(doc, path)
+		return nil
+	case "$inc", "$mul":
+		if _, ok := toFloat(arg); !ok {
+			return fmt.Errorf("match: %s requires a numeric argument", op)
+		}
+		if !exists {
+			if op == "$mul" {
+				return SetPath(doc, path, multiply(arg, 0))
+			}
+			return SetPath(doc, path, arg)
+		}
+		if _, ok := toFloat(current); !ok {
+			return fmt.Errorf("match: cannot apply %s to non-numeric field %q", op, path)
+		}
+		if op == "$inc" {
+			return SetPath(doc, path, add(current, arg))
+		}
+		return SetPath(doc, path, multiply(current, arg))
+	case "$min", "$max":
+		if !exists {
+			return SetPath(doc, path, arg)
+		}
+		c, ok := Compare(arg, current)
+		if ok && ((op == "$min" && c < 0) || (op == "$max" && c > 0)) {
+			return SetPath(doc, path, arg)
+		}
+		return nil
+	case "$push", "$addToSet":
+		items := []any{arg}
+		if m, ok := ToMap(arg); ok {
+			if each, ok := m["$each"]; ok {
+				items, _ = ToSlice(each)
+			}
+		}
+		var arr primitive.A
+		if exists {
+			existing, ok := ToSlice(current)
+			if !ok {
+				return fmt.Errorf("match: cannot apply %s to non-array field %q", op, path)
+			}
+			arr = append(arr, existing...)
+		}
+		for _, item := range items {
+			if op == "$addToSet" && containsEqual(arr, item) {
+				continue
+			}
+			arr = append(arr, item)
+		}
+		return SetPath(doc, path, arr)
+	case "$pull":
+		if !exists {
+			return nil
+		}
+		existing, ok := ToSlice(current)
+		if !ok {
+			return fmt.Errorf("match: cannot apply $pull to non-array field %q", path)
+		}
+		kept := primitive.A{}
+		for _, elem := range existing {
+			remove := Equal(elem, arg)
+			if cond, ok := ToMap(arg); ok && !remove {
+				var err error
+				if isOperatorDoc(cond) {
+					remove, err = matchKey("v", cond, bson.M{"v": elem})
+				} else if m, ok := ToMap(elem); ok {
+					remove, err = matchDoc(cond, m)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			if !remove {
+				kept = append(kept, elem)
+			}
+		}
+		return SetPath(doc, path, kept)
+	case "$pop":
+		if !exists {
+			return nil
+		}
+		existing, ok := ToSlice(current)
+		if !ok {
+			return fmt.Errorf("match: cannot apply $pop to non-array field %q", path)
+		}
+		if len(existing) == 0 {
+			return nil
+		}
+		pos, _ := toFloat(arg)
+		if pos < 0 {
+			return SetPath(doc, path, primitive.A(existing[1:]))
+		}
+		return SetPath(doc, path, primitive.A(existing[:len(existing)-1]))
+	case "$rename":
+		target, ok := arg.(string)
+		if !ok {
+			return fmt.Errorf("match: $rename requires a string target")
+		}
+		if !exists {
+			return nil
+		}
+		UnsetPath(doc, path)
+		return SetPath(doc, target, current)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupported, op)
+	}
+}
+
+func containsEqual(arr []any, item any) bool {
+	for _, elem := range arr {
+		if Equal(elem, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// add sums two numbers, keeping integer types when both operands are integers.
+func add(a, b any) any {
+	ai, aInt := toInt(a)
+	bi, bInt := toInt(b)
+	if aInt && bInt {
+		return ai + bi
+	}
+	af, _ := toFloat(a)
+	bf, _ := toFloat(b)
+	return af + bf
+}
+
+func multiply(a, b any) any {
+	ai, aInt := toInt(a)
+	bi, bInt := toInt(b)
+	if aInt && bInt {
+		return ai * bi
+	}
+	af, _ := toFloat(a)
+	bf, _ := toFloat(b)
+	return af * bf
+}
+
+func toInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+// SetPath sets a dotted path in doc, creating intermediate documents as needed.
+func SetPath(doc bson.M, path string, value any) error {
+	parts := strings.Split(path, ".")
+	current := doc
+	for i, part := range parts[:len(parts)-1] {
+		next, exists := current[part]
+		if !exists || next == nil {
+			child := bson.M{}
+			current[part] = child
+			current = child
+			continue
+		}
+		if arr, ok := next.(primitive.A); ok {
+			idx, err := strconv.Atoi(parts[i+1])
+			if err != nil || idx < 0 || idx >= len(arr) {
+				return fmt.Errorf("match: cannot traverse array at %q", strings.Join(parts[:i+1], "."))
+			}
+			if i+2 == len(parts) {
+				arr[idx] = value
+				return nil
+			}
+			m, ok := ToMap(arr[idx])
+			if !ok {
+				return fmt.Errorf("match: cannot traverse non-document at %q", path)
+			}
+			arr[idx] = m
+			return SetPath(m, strings.Join(parts[i+2:], "."), value)
+		}
+		m, ok := ToMap(next)
+		if !ok {
+			return fmt.Errorf("match: cannot traverse non-document at %q", strings.Join(parts[:i+1], "."))
+		}
+		current[part] = m
+		current = m
+	}
+	current[parts[len(parts)-1]] = value
+	return nil
+}
+
+// UnsetPath removes a dotted path from doc. Missing paths are ignored.
+func UnsetPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	current := doc
+	for _, part := range parts[:len(parts)-1] {
+		m, ok := ToMap(current[part])
+		if !ok {
+			return
+		}
+		current = m
+	}
+	delete(current, parts[len(parts)-1])
+}
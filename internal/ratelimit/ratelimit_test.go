@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBucket_BurstThenRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(10, 3)
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if !b.Allow() {
+			t.Fatalf("expected burst token %d to be allowed", i)
+		}
+	}
+	if b.Allow() {
+		t.Fatal("expected bucket to be empty after burst")
+	}
+
+	now = now.Add(100 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("expected one token after 100ms at 10/s")
+	}
+	if b.Allow() {
+		t.Fatal("expected bucket empty again")
+	}
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if !b.Allow() {
+			t.Fatalf("expected refill capped at burst, token %d denied", i)
+		}
+	}
+	if b.Allow() {
+		t.Fatal("expected refill to be capped at burst")
+	}
+}
+
+func TestBucket_Reserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := New(10, 1)
+	b.now = func() time.Time { return now }
+
+	if d := b.Reserve(); d != 0 {
+		t.Fatalf("first reservation should not wait, got %v", d)
+	}
+	if d := b.Reserve(); d != 100*time.Millisecond {
+		t.Fatalf("second reservation should wait 100ms, got %v", d)
+	}
+	if d := b.Reserve(); d != 200*time.Millisecond {
+		t.Fatalf("third reservation should wait 200ms, got %v", d)
+	}
+}
+
+func TestBucket_WaitDeadline(t *testing.T) {
+	b := New(1, 1)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if d := b.Reserve(); d > time.Second+10*time.Millisecond {
+		t.Fatalf("expected cancelled reservation to be returned, next wait %v", d)
+	}
+}
@@ -0,0 +1,53 @@
+// Package singleflight coalesces concurrent calls with the same key into one.
+package singleflight
+
+import (
+	"context"
+	"sync"
+)
+
+// call is an in-flight or completed Do call.
+type call struct {
+	done chan struct{}
+	val  any
+	err  error
+}
+
+// Group runs at most one function per key at a time. The zero value is ready
+// to use; a Group is safe for concurrent use.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// Do runs fn unless a call with the same key is in flight, in which case it
+// waits for that call and returns its result. shared reports whether the
+// result was produced by another caller's fn. A waiting caller returns
+// ctx.Err() if ctx is done first; the in-flight call is not affected.
+func (g *Group) Do(ctx context.Context, key string, fn func() (any, error)) (v any, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.val, c.err, true
+		case <-ctx.Done():
+			return nil, ctx.Err(), false
+		}
+	}
+	c := &call{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err, false
+}
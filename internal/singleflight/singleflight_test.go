@@ -0,0 +1,82 @@
+package singleflight
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDo_CoalescesConcurrentCalls(t *testing.T) {
+	var g Group
+	var calls atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	go func() {
+		_, _, _ = g.Do(context.Background(), "k", func() (any, error) {
+			calls.Add(1)
+			close(started)
+			<-release
+			return 42, nil
+		})
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do(context.Background(), "k", func() (any, error) {
+				calls.Add(1)
+				return 0, nil
+			})
+			if v != 42 || err != nil || !shared {
+				t.Errorf("Do = %v, %v, %v; want the leader's result", v, err, shared)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // let the followers start waiting
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("fn ran %d times, want 1", n)
+	}
+}
+
+func TestDo_RunsAgainAfterCompletion(t *testing.T) {
+	var g Group
+	errBoom := errors.New("boom")
+	if _, err, shared := g.Do(context.Background(), "k", func() (any, error) { return nil, errBoom }); err != errBoom || shared {
+		t.Fatalf("first Do = %v, %v", err, shared)
+	}
+	v, err, shared := g.Do(context.Background(), "k", func() (any, error) { return "fresh", nil })
+	if v != "fresh" || err != nil || shared {
+		t.Fatalf("second Do = %v, %v, %v; want a new call", v, err, shared)
+	}
+}
+
+func TestDo_WaiterHonorsContext(t *testing.T) {
+	var g Group
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_, _, _ = g.Do(context.Background(), "k", func() (any, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err, _ := g.Do(ctx, "k", func() (any, error) { return nil, nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the waiter's deadline, got %v", err)
+	}
+}
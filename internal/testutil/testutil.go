@@ -0,0 +1,23 @@
+// Package testutil holds helpers shared by the tests of several packages.
+package testutil
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LazyCollection returns the collection name of a database on a client that
+// never connects; use it where no server round trip happens. The client is
+// disconnected when the test ends.
+func LazyCollection(t testing.TB, name string) *mongo.Collection {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client.Database("testdb").Collection(name)
+}
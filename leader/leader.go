@@ -0,0 +1,229 @@
+// Package leader runs periodic background jobs on exactly one instance of a
+// fleet.
+//
+// Every instance creates a Runner with the same name and registers the same
+// jobs; the instance that holds the runner's distributed lock (see package
+// lock) is the leader and runs them, refreshing its lease on every heartbeat.
+// The others wait and take over when the leader releases the lock on shutdown
+// or its lease expires after a crash. Jobs that must never overlap should also
+// tolerate a second run shortly after a takeover, since a leader that stalls
+// for longer than the lease TTL loses the lock while its job may still run.
+//
+// Example:
+//
+//	locker := lock.New(db.Collection("locks"))
+//	runner := leader.New(locker, "maintenance")
+//	_ = runner.Register("purge-sessions", time.Hour, func(ctx context.Context) error {
+//	    _, err := sessions.DeleteMany(ctx, spec.Lt("expires_at", time.Now()))
+//	    return err
+//	})
+//	_ = runner.Register("outbox-relay", 5*time.Second, relay.Flush)
+//	go runner.Run(ctx)
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dElCIoGio/mongox/lock"
+)
+
+var (
+	// ErrDuplicateJob is returned by Register for a job name already registered.
+	ErrDuplicateJob = errors.New("leader: job already registered")
+
+	// ErrInvalidInterval is returned by Register for intervals that are not positive.
+	ErrInvalidInterval = errors.New("leader: interval must be positive")
+)
+
+// Job is a periodic task run by the leader. Its context is cancelled when the
+// instance stops being the leader.
+type Job func(ctx context.Context) error
+
+// Option configures a Runner.
+type Option func(*config)
+
+type config struct {
+	heartbeat time.Duration
+	onError   func(err error)
+	onChange  func(leading bool)
+}
+
+// WithHeartbeat sets how often the leader refreshes its lease and followers
+// try to take over. Defaults to a third of the locker's TTL.
+func WithHeartbeat(d time.Duration) Option {
+	return func(c *config) { c.heartbeat = d }
+}
+
+// WithErrorHandler registers a callback for job errors and lock errors.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) { c.onError = fn }
+}
+
+// OnLeadershipChange registers a callback invoked with true when the instance
+// becomes the leader and with false when it stops being the leader.
+func OnLeadershipChange(fn func(leading bool)) Option {
+	return func(c *config) { c.onChange = fn }
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Job
+}
+
+// Runner elects a leader among the instances sharing its name and runs the
+// registered jobs on it. It is safe for concurrent use.
+type Runner struct {
+	locker *lock.Locker
+	name   string
+	cfg    config
+
+	mu   sync.Mutex
+	jobs []job
+
+	leading atomic.Bool
+}
+
+// New creates a Runner whose leader holds the lock name.
+func New(locker *lock.Locker, name string, opts ...Option) *Runner {
+	cfg := config{}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.heartbeat <= 0 {
+		cfg.heartbeat = locker.TTL() / 3
+	}
+	return &Runner{locker: locker, name: name, cfg: cfg}
+}
+
+// Register adds a job run every interval while this instance is the leader,
+// starting as soon as it becomes the leader. Jobs registered while the runner is
+// leading start at the next election.
+func (r *Runner) Register(name string, interval time.Duration, fn Job) error {
+	if interval <= 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidInterval, name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, j := range r.jobs {
+		if j.name == name {
+			return fmt.Errorf("%w: %q", ErrDuplicateJob, name)
+		}
+	}
+	r.jobs = append(r.jobs, job{name: name, interval: interval, fn: fn})
+	return nil
+}
+
+// IsLeader reports whether this instance currently runs the jobs.
+func (r *Runner) IsLeader() bool { return r.leading.Load() }
+
+// Run competes for leadership until ctx is cancelled and returns ctx.Err().
+// While leading it runs the jobs and refreshes the lease every heartbeat; on
+// cancellation it stops the jobs and releases the lock so another instance can
+// take over at once.
+func (r *Runner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.cfg.heartbeat)
+	defer ticker.Stop()
+	for {
+		lk, err := r.locker.TryAcquire(ctx, r.name)
+		switch {
+		case err == nil:
+			r.lead(ctx, lk, ticker)
+		case !errors.Is(err, lock.ErrNotAcquired) && ctx.Err() == nil:
+			r.reportError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs the jobs until the lease is lost, cannot be refreshed in time, or
+// ctx is cancelled.
+func (r *Runner) lead(ctx context.Context, lk *lock.Lock, ticker *time.Ticker) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	r.mu.Lock()
+	for _, j := range r.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.runJob(jobCtx, j)
+		}()
+	}
+	r.mu.Unlock()
+	r.setLeading(true)
+
+	defer func() {
+		cancel()
+		wg.Wait()
+		r.setLeading(false)
+	}()
+
+	refreshed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancelRelease()
+			cancel()
+			wg.Wait()
+			if err := lk.Release(releaseCtx); err != nil {
+				r.reportError(err)
+			}
+			return
+		case <-ticker.C:
+			err := lk.Refresh(ctx)
+			if err == nil {
+				refreshed = time.Now()
+				continue
+			}
+			if ctx.Err() == nil {
+				r.reportError(err)
+			}
+			// Step down before the lease can expire under us; another
+			// instance may take over once it does.
+			if errors.Is(err, lock.ErrLost) || time.Since(refreshed) >= r.locker.TTL()-r.cfg.heartbeat {
+				return
+			}
+		}
+	}
+}
+
+// runJob runs j immediately and then every interval until ctx is cancelled.
+func (r *Runner) runJob(ctx context.Context, j job) {
+	t := time.NewTicker(j.interval)
+	defer t.Stop()
+	for {
+		if err := j.fn(ctx); err != nil && ctx.Err() == nil {
+			r.reportError(fmt.Errorf("leader: job %q: %w", j.name, err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (r *Runner) setLeading(leading bool) {
+	if r.leading.Swap(leading) != leading && r.cfg.onChange != nil {
+		r.cfg.onChange(leading)
+	}
+}
+
+func (r *Runner) reportError(err error) {
+	if err != nil && r.cfg.onError != nil {
+		r.cfg.onError(err)
+	}
+}
@@ -0,0 +1,108 @@
+//go:build integration
+
+package leader_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/leader"
+	"github.com/dElCIoGio/mongox/lock"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestRunner_SingleLeaderAndHandover(t *testing.T) {
+	client := setupMongo(t)
+	coll := client.Database("testdb").Collection("locks")
+
+	var running, overlaps atomic.Int32
+	ran := map[string]*atomic.Int32{"a": {}, "b": {}}
+
+	newRunner := func(owner string) *leader.Runner {
+		r := leader.New(lock.New(coll, lock.WithOwner(owner), lock.WithTTL(time.Second)), "maintenance")
+		err := r.Register("purge", 20*time.Millisecond, func(ctx context.Context) error {
+			if running.Add(1) > 1 {
+				overlaps.Add(1)
+			}
+			defer running.Add(-1)
+			ran[owner].Add(1)
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		return r
+	}
+	a, b := newRunner("a"), newRunner("b")
+
+	ctxA, stopA := context.WithCancel(context.Background())
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); _ = a.Run(ctxA) }()
+	time.Sleep(200 * time.Millisecond)
+	go func() { defer wg.Done(); _ = b.Run(ctxB) }()
+	time.Sleep(500 * time.Millisecond)
+
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("expected a to lead alone, got a=%v b=%v", a.IsLeader(), b.IsLeader())
+	}
+	if ran["b"].Load() != 0 {
+		t.Fatalf("follower ran the job %d times", ran["b"].Load())
+	}
+
+	// Stopping the leader releases the lock; b takes over on its next heartbeat.
+	stopA()
+	deadline := time.Now().Add(3 * time.Second)
+	for !b.IsLeader() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !b.IsLeader() {
+		t.Fatal("expected b to take over after a stopped")
+	}
+	time.Sleep(100 * time.Millisecond)
+	stopB()
+	wg.Wait()
+
+	if ran["b"].Load() == 0 {
+		t.Fatal("expected the new leader to run the job")
+	}
+	if overlaps.Load() != 0 {
+		t.Fatalf("job ran concurrently %d times", overlaps.Load())
+	}
+}
@@ -0,0 +1,30 @@
+package leader_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	"github.com/dElCIoGio/mongox/leader"
+	"github.com/dElCIoGio/mongox/lock"
+)
+
+func TestRegister_Validates(t *testing.T) {
+	r := leader.New(lock.New(testutil.LazyCollection(t, "locks")), "maintenance")
+	noop := func(context.Context) error { return nil }
+
+	if err := r.Register("purge", time.Minute, noop); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := r.Register("purge", time.Hour, noop); !errors.Is(err, leader.ErrDuplicateJob) {
+		t.Fatalf("expected ErrDuplicateJob, got %v", err)
+	}
+	if err := r.Register("relay", 0, noop); !errors.Is(err, leader.ErrInvalidInterval) {
+		t.Fatalf("expected ErrInvalidInterval, got %v", err)
+	}
+	if r.IsLeader() {
+		t.Fatal("expected a runner that never ran not to be the leader")
+	}
+}
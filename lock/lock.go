@@ -0,0 +1,239 @@
+// Package lock provides distributed locks backed by a MongoDB collection.
+//
+// A lock is a lease: one document per lock name records the current holder and
+// when the lease expires. The holder must Refresh the lease before it expires;
+// if it crashes, the lease lapses and another process can acquire the lock.
+// Expiry is checked against the server clock, so the processes' clocks do not
+// need to agree. Every acquisition increments a fencing token that writers can
+// store alongside their data to reject writes from a holder that lost the lock.
+//
+// Example:
+//
+//	locker := lock.New(db.Collection("locks"))
+//
+//	l, err := locker.TryAcquire(ctx, "nightly-report")
+//	if errors.Is(err, lock.ErrNotAcquired) {
+//	    return nil // another instance is running it
+//	}
+//	if err != nil {
+//	    return err
+//	}
+//	defer l.Release(context.WithoutCancel(ctx))
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrNotAcquired is returned by TryAcquire when another holder has the lock.
+	ErrNotAcquired = errors.New("lock: held by another owner")
+
+	// ErrLost is returned by Refresh and Release when the lease expired and the
+	// lock was taken by another holder or removed.
+	ErrLost = errors.New("lock: lease lost")
+)
+
+// Option configures a Locker.
+type Option func(*config)
+
+type config struct {
+	ttl   time.Duration
+	retry time.Duration
+	owner string
+}
+
+// WithTTL sets how long a lease lasts without a Refresh. Defaults to 30s.
+func WithTTL(d time.Duration) Option {
+	return func(c *config) { c.ttl = d }
+}
+
+// WithRetryInterval sets how often Acquire retries while the lock is held
+// elsewhere. Defaults to 1s.
+func WithRetryInterval(d time.Duration) Option {
+	return func(c *config) { c.retry = d }
+}
+
+// WithOwner sets the name recorded as the holder of acquired locks, for
+// diagnostics. Defaults to the host name and process id.
+func WithOwner(owner string) Option {
+	return func(c *config) { c.owner = owner }
+}
+
+// Locker acquires locks stored in one collection.
+// It is safe for concurrent use.
+type Locker struct {
+	coll *mongo.Collection
+	cfg  config
+}
+
+// New creates a Locker that stores locks in coll.
+func New(coll *mongo.Collection, opts ...Option) *Locker {
+	cfg := config{ttl: 30 * time.Second, retry: time.Second}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.ttl <= 0 {
+		cfg.ttl = 30 * time.Second
+	}
+	if cfg.retry <= 0 {
+		cfg.retry = time.Second
+	}
+	if cfg.owner == "" {
+		host, _ := os.Hostname()
+		cfg.owner = fmt.Sprintf("%s/%d", host, os.Getpid())
+	}
+	return &Locker{coll: coll, cfg: cfg}
+}
+
+// TTL returns the lease duration. Holders should refresh well within it.
+func (l *Locker) TTL() time.Duration { return l.cfg.ttl }
+
+// Info describes the current state of a lock.
+type Info struct {
+	Name       string    `bson:"_id"`
+	Owner      string    `bson:"owner"`
+	Token      int64     `bson:"token"`
+	AcquiredAt time.Time `bson:"acquired_at"`
+	ExpiresAt  time.Time `bson:"expires_at"`
+}
+
+// TryAcquire acquires the lock name if it is free or its lease has expired,
+// and returns ErrNotAcquired otherwise.
+func (l *Locker) TryAcquire(ctx context.Context, name string) (*Lock, error) {
+	holder := newHolderID()
+	ttl := l.cfg.ttl.Milliseconds()
+
+	filter := bson.M{
+		"_id":   name,
+		"$expr": bson.M{"$lte": bson.A{"$expires_at", "$$NOW"}},
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"owner":       l.cfg.owner,
+		"holder":      holder,
+		"token":       bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$token", 0}}, 1}},
+		"acquired_at": "$$NOW",
+		"expires_at":  bson.M{"$add": bson.A{"$$NOW", ttl}},
+	}}}}
+
+	var info Info
+	err := l.coll.FindOneAndUpdate(ctx, filter, update,
+		mopt.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(mopt.After),
+	).Decode(&info)
+	if err != nil {
+		// The document exists and its lease is current, so the upsert's
+		// insert collided with it.
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("%w: %q", ErrNotAcquired, name)
+		}
+		return nil, fmt.Errorf("lock: acquire %q: %w", name, err)
+	}
+	return &Lock{locker: l, name: name, holder: holder, token: info.Token}, nil
+}
+
+// Acquire waits until the lock name is acquired or ctx is done, retrying every
+// retry interval.
+func (l *Locker) Acquire(ctx context.Context, name string) (*Lock, error) {
+	for {
+		lk, err := l.TryAcquire(ctx, name)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lk, err
+		}
+		t := time.NewTimer(l.cfg.retry)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Inspect returns the state of the lock name, or nil if it was never acquired.
+// The lease may have expired or been released; compare ExpiresAt.
+func (l *Locker) Inspect(ctx context.Context, name string) (*Info, error) {
+	var info Info
+	err := l.coll.FindOne(ctx, bson.M{"_id": name}).Decode(&info)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Lock is a held lease on a named lock.
+type Lock struct {
+	locker *Locker
+	name   string
+	holder string
+	token  int64
+}
+
+// Name returns the lock's name.
+func (k *Lock) Name() string { return k.name }
+
+// Token returns the fencing token of this acquisition. Tokens increase with
+// every acquisition of the same lock, so a write carrying a lower token than
+// the last one seen comes from a stale holder.
+func (k *Lock) Token() int64 { return k.token }
+
+// Refresh extends the lease by the TTL from now. It returns ErrLost if the lease
+// already expired, even when no one else has taken the lock yet.
+func (k *Lock) Refresh(ctx context.Context) error {
+	ttl := k.locker.cfg.ttl.Milliseconds()
+	res, err := k.locker.coll.UpdateOne(ctx,
+		bson.M{
+			"_id":    k.name,
+			"holder": k.holder,
+			"$expr":  bson.M{"$gt": bson.A{"$expires_at", "$$NOW"}},
+		},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"expires_at": bson.M{"$add": bson.A{"$$NOW", ttl}},
+		}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("lock: refresh %q: %w", k.name, err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %q", ErrLost, k.name)
+	}
+	return nil
+}
+
+// Release gives up the lock so another holder can acquire it immediately.
+// The lock document is kept so fencing tokens keep increasing.
+// It returns ErrLost if the lock was already taken by another holder.
+func (k *Lock) Release(ctx context.Context) error {
+	res, err := k.locker.coll.UpdateOne(ctx,
+		bson.M{"_id": k.name, "holder": k.holder},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"holder": "", "expires_at": "$$NOW"}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("lock: release %q: %w", k.name, err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: %q", ErrLost, k.name)
+	}
+	return nil
+}
+
+// newHolderID returns a random id that distinguishes acquisitions by the same owner.
+func newHolderID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
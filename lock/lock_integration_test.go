@@ -0,0 +1,104 @@
+//go:build integration
+
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/lock"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestLock_ExclusiveAndReleased(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("locks")
+	a := lock.New(coll, lock.WithOwner("a"))
+	b := lock.New(coll, lock.WithOwner("b"))
+
+	la, err := a.TryAcquire(ctx, "report")
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+	if _, err := b.TryAcquire(ctx, "report"); !errors.Is(err, lock.ErrNotAcquired) {
+		t.Fatalf("expected ErrNotAcquired while held, got %v", err)
+	}
+	if err := la.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	info, err := b.Inspect(ctx, "report")
+	if err != nil || info == nil || info.Owner != "a" || info.Token != la.Token() {
+		t.Fatalf("Inspect = %+v, %v; want owner a with token %d", info, err, la.Token())
+	}
+
+	if err := la.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	lb, err := b.TryAcquire(ctx, "report")
+	if err != nil {
+		t.Fatalf("TryAcquire after release: %v", err)
+	}
+	if lb.Token() <= la.Token() {
+		t.Fatalf("expected the fencing token to increase, got %d after %d", lb.Token(), la.Token())
+	}
+	if err := la.Release(ctx); !errors.Is(err, lock.ErrLost) {
+		t.Fatalf("expected ErrLost releasing a lock held by another owner, got %v", err)
+	}
+}
+
+func TestLock_ExpiredLeaseIsTakenOver(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("locks")
+	a := lock.New(coll, lock.WithTTL(200*time.Millisecond))
+	b := lock.New(coll, lock.WithRetryInterval(50*time.Millisecond))
+
+	la, err := a.TryAcquire(ctx, "relay")
+	if err != nil {
+		t.Fatalf("TryAcquire: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := b.Acquire(waitCtx, "relay"); err != nil {
+		t.Fatalf("Acquire after expiry: %v", err)
+	}
+	if err := la.Refresh(ctx); !errors.Is(err, lock.ErrLost) {
+		t.Fatalf("expected ErrLost refreshing an expired lease, got %v", err)
+	}
+}
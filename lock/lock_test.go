@@ -0,0 +1,23 @@
+package lock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	"github.com/dElCIoGio/mongox/lock"
+)
+
+func TestNew_TTL(t *testing.T) {
+	coll := testutil.LazyCollection(t, "locks")
+
+	if got := lock.New(coll).TTL(); got != 30*time.Second {
+		t.Fatalf("default TTL = %v, want 30s", got)
+	}
+	if got := lock.New(coll, lock.WithTTL(5*time.Second)).TTL(); got != 5*time.Second {
+		t.Fatalf("TTL = %v, want 5s", got)
+	}
+	if got := lock.New(coll, lock.WithTTL(-time.Second)).TTL(); got != 30*time.Second {
+		t.Fatalf("TTL for a negative value = %v, want the 30s default", got)
+	}
+}
@@ -0,0 +1,110 @@
+package longop
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Checkpoint records the progress of a job.
+type Checkpoint struct {
+	// LastID is the _id of the last processed document, or nil before the first batch.
+	LastID any `bson:"last_id"`
+
+	// Processed is the number of documents processed so far.
+	Processed int64 `bson:"processed"`
+
+	// UpdatedAt is when the checkpoint was taken.
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// CheckpointStore persists job checkpoints. Implementations must be safe for concurrent use.
+type CheckpointStore interface {
+	// Load returns the checkpoint for job, or nil if there is none.
+	Load(ctx context.Context, job string) (*Checkpoint, error)
+
+	// Save stores the checkpoint for job, replacing any previous one.
+	Save(ctx context.Context, job string, cp Checkpoint) error
+
+	// Clear removes the checkpoint for job.
+	Clear(ctx context.Context, job string) error
+}
+
+// MongoStore stores checkpoints in a collection, one document per job keyed by job name.
+type MongoStore struct {
+	coll *mongo.Collection
+}
+
+// NewMongoStore creates a CheckpointStore backed by coll.
+func NewMongoStore(coll *mongo.Collection) *MongoStore {
+	return &MongoStore{coll: coll}
+}
+
+// Load returns the checkpoint for job, or nil if there is none.
+func (s *MongoStore) Load(ctx context.Context, job string) (*Checkpoint, error) {
+	var cp Checkpoint
+	err := s.coll.FindOne(ctx, bson.M{"_id": job}).Decode(&cp)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// Save stores the checkpoint for job.
+func (s *MongoStore) Save(ctx context.Context, job string, cp Checkpoint) error {
+	_, err := s.coll.ReplaceOne(ctx, bson.M{"_id": job}, cp, mopt.Replace().SetUpsert(true))
+	return err
+}
+
+// Clear removes the checkpoint for job.
+func (s *MongoStore) Clear(ctx context.Context, job string) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": job})
+	return err
+}
+
+// MemoryStore keeps checkpoints in memory. It is useful in tests and for jobs
+// that only need resumption within one process.
+type MemoryStore struct {
+	mu  sync.Mutex
+	cps map[string]Checkpoint
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{cps: make(map[string]Checkpoint)}
+}
+
+// Load returns the checkpoint for job, or nil if there is none.
+func (s *MemoryStore) Load(_ context.Context, job string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.cps[job]
+	if !ok {
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+// Save stores the checkpoint for job.
+func (s *MemoryStore) Save(_ context.Context, job string, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cps[job] = cp
+	return nil
+}
+
+// Clear removes the checkpoint for job.
+func (s *MemoryStore) Clear(_ context.Context, job string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cps, job)
+	return nil
+}
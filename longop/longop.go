@@ -0,0 +1,282 @@
+// Package longop runs long, batched collection scans with progress reporting,
+// rate limiting, and crash-safe resumption.
+//
+// Run walks the documents matching a filter in ascending _id order, hands each
+// batch to a callback, and records the last processed _id in a CheckpointStore.
+// If the process dies, running the same job again picks up after the last
+// completed batch. Migrations, importers, and datafix jobs share this loop.
+//
+// Example:
+//
+//	store := longop.NewMongoStore(db.Collection("job_checkpoints"))
+//	err := longop.Run(ctx, longop.Scan{
+//	    Job:        "backfill-search-names",
+//	    Collection: db.Collection("users"),
+//	    Filter:     bson.M{"search_name": bson.M{"$exists": false}},
+//	}, func(ctx context.Context, batch []bson.Raw) error {
+//	    // ... process batch ...
+//	    return nil
+//	},
+//	    longop.WithStore(store),
+//	    longop.WithRateLimit(2000),
+//	    longop.WithProgress(func(p longop.Progress) {
+//	        log.Printf("%s: %d/%d, eta %s", p.Job, p.Processed, p.Total, p.ETA)
+//	    }),
+//	)
+package longop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Scan describes the documents a job processes.
+type Scan struct {
+	// Job identifies the operation; checkpoints are stored under this name.
+	Job string
+
+	// Collection is scanned in ascending _id order.
+	Collection *mongo.Collection
+
+	// Filter selects the documents to process. Nil matches all documents.
+	Filter any
+
+	// Projection limits the fields loaded per document. Nil loads whole documents.
+	// The _id field is always included.
+	Projection any
+
+	// BatchSize is the number of documents per batch. Defaults to 1000.
+	BatchSize int
+}
+
+// BatchFunc processes one batch of documents.
+type BatchFunc func(ctx context.Context, batch []bson.Raw) error
+
+// Progress is reported after every batch.
+type Progress struct {
+	Job string
+
+	// Processed is the number of documents processed, including those from
+	// before a resume.
+	Processed int64
+
+	// Total is the number of documents matching the filter when the run started.
+	Total int64
+
+	// Elapsed is the time spent in this run.
+	Elapsed time.Duration
+
+	// Rate is the observed throughput of this run, in documents per second.
+	Rate float64
+
+	// ETA estimates the remaining time at the observed rate. Zero when unknown.
+	ETA time.Duration
+
+	// Checkpoint is the position after the batch.
+	Checkpoint Checkpoint
+}
+
+// Option configures Run.
+type Option func(*config)
+
+type config struct {
+	store    CheckpointStore
+	resume   *Checkpoint
+	rate     float64
+	progress func(Progress)
+}
+
+// WithStore persists a checkpoint after every batch and resumes from the stored
+// checkpoint when the job starts. The checkpoint is cleared when the job completes.
+func WithStore(store CheckpointStore) Option {
+	return func(c *config) { c.store = store }
+}
+
+// WithResumeFrom starts after the given checkpoint, for callers that persist
+// checkpoints themselves. It takes precedence over a stored checkpoint.
+func WithResumeFrom(cp Checkpoint) Option {
+	return func(c *config) { c.resume = &cp }
+}
+
+// WithRateLimit caps throughput at docsPerSecond by pausing between batches.
+// A value of 0 disables the limit.
+func WithRateLimit(docsPerSecond float64) Option {
+	return func(c *config) { c.rate = docsPerSecond }
+}
+
+// WithProgress registers a callback invoked after every batch.
+func WithProgress(fn func(Progress)) Option {
+	return func(c *config) { c.progress = fn }
+}
+
+// Run processes the documents described by scan, batch by batch.
+//
+// Behavior:
+//   - The checkpoint only advances after fn succeeds, so a failed batch is retried on resume
+//   - fn must be idempotent: a crash between fn and the checkpoint save replays the batch
+//   - Cancelling ctx stops before the next batch with ctx.Err()
+func Run(ctx context.Context, scan Scan, fn BatchFunc, opts ...Option) error {
+	var c config
+	for _, o := range opts {
+		if o != nil {
+			o(&c)
+		}
+	}
+	if scan.Collection == nil {
+		return errors.New("longop: scan has no collection")
+	}
+	batchSize := scan.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	filter := scan.Filter
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	var cp Checkpoint
+	switch {
+	case c.resume != nil:
+		cp = *c.resume
+	case c.store != nil:
+		stored, err := c.store.Load(ctx, scan.Job)
+		if err != nil {
+			return fmt.Errorf("longop: load checkpoint: %w", err)
+		}
+		if stored != nil {
+			cp = *stored
+		}
+	}
+
+	total, err := scan.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	findOpts := mopt.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(batchSize))
+	if scan.Projection != nil {
+		findOpts.SetProjection(scan.Projection)
+	}
+
+	started := time.Now()
+	resumedAt := cp.Processed
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch, err := nextBatch(ctx, scan.Collection, filter, cp.LastID, findOpts)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		if err := fn(ctx, batch); err != nil {
+			return err
+		}
+
+		last, err := batch[len(batch)-1].LookupErr("_id")
+		if err != nil {
+			return fmt.Errorf("longop: document without _id: %w", err)
+		}
+		var lastID any
+		if err := last.Unmarshal(&lastID); err != nil {
+			return err
+		}
+		cp = Checkpoint{LastID: lastID, Processed: cp.Processed + int64(len(batch)), UpdatedAt: time.Now().UTC()}
+		if c.store != nil {
+			if err := c.store.Save(ctx, scan.Job, cp); err != nil {
+				return fmt.Errorf("longop: save checkpoint: %w", err)
+			}
+		}
+
+		done := cp.Processed - resumedAt
+		if c.progress != nil {
+			c.progress(progressOf(scan.Job, cp, total, done, time.Since(started)))
+		}
+		if err := pace(ctx, c.rate, done, started); err != nil {
+			return err
+		}
+
+		if len(batch) < batchSize {
+			break
+		}
+	}
+
+	if c.store != nil {
+		if err := c.store.Clear(ctx, scan.Job); err != nil {
+			return fmt.Errorf("longop: clear checkpoint: %w", err)
+		}
+	}
+	return nil
+}
+
+func nextBatch(ctx context.Context, coll *mongo.Collection, filter any, lastID any, opts *mopt.FindOptions) ([]bson.Raw, error) {
+	f := filter
+	if lastID != nil {
+		f = bson.M{"$and": []any{filter, bson.M{"_id": bson.M{"$gt": lastID}}}}
+	}
+	cur, err := coll.Find(ctx, f, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var batch []bson.Raw
+	for cur.Next(ctx) {
+		batch = append(batch, append(bson.Raw(nil), cur.Current...))
+	}
+	return batch, cur.Err()
+}
+
+func progressOf(job string, cp Checkpoint, total, done int64, elapsed time.Duration) Progress {
+	p := Progress{Job: job, Processed: cp.Processed, Total: total, Elapsed: elapsed, Checkpoint: cp}
+	if elapsed > 0 {
+		p.Rate = float64(done) / elapsed.Seconds()
+	}
+	if remaining := total - cp.Processed; remaining > 0 && p.Rate > 0 {
+		p.ETA = time.Duration(float64(remaining) / p.Rate * float64(time.Second))
+	}
+	return p
+}
+
+// pace sleeps until done documents fit under the rate limit since started.
+func pace(ctx context.Context, rate float64, done int64, started time.Time) error {
+	if rate <= 0 {
+		return nil
+	}
+	wait := time.Duration(float64(done)/rate*float64(time.Second)) - time.Since(started)
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// IDs returns the _id values of the documents in batch.
+func IDs(batch []bson.Raw) []any {
+	ids := make([]any, 0, len(batch))
+	for _, doc := range batch {
+		var id any
+		if v, err := doc.LookupErr("_id"); err == nil && v.Unmarshal(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,128 @@
+//go:build integration
+
+package longop_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/longop"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestRun_ResumesAfterFailure(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	coll := db.Collection("longop_items")
+	store := longop.NewMongoStore(db.Collection("longop_checkpoints"))
+
+	docs := make([]any, 0, 20)
+	for i := 0; i < 20; i++ {
+		docs = append(docs, bson.M{"_id": i})
+	}
+	if _, err := coll.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	scan := longop.Scan{Job: "touch", Collection: coll, BatchSize: 5}
+	boom := errors.New("boom")
+
+	var seen []any
+	calls := 0
+	err := longop.Run(ctx, scan, func(ctx context.Context, batch []bson.Raw) error {
+		calls++
+		if calls == 3 {
+			return boom
+		}
+		seen = append(seen, longop.IDs(batch)...)
+		return nil
+	}, longop.WithStore(store))
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected batch error, got %v", err)
+	}
+
+	cp, err := store.Load(ctx, "touch")
+	if err != nil || cp == nil || cp.Processed != 10 {
+		t.Fatalf("expected checkpoint after 10 documents, got %+v, %v", cp, err)
+	}
+
+	var last longop.Progress
+	err = longop.Run(ctx, scan, func(ctx context.Context, batch []bson.Raw) error {
+		seen = append(seen, longop.IDs(batch)...)
+		return nil
+	}, longop.WithStore(store), longop.WithProgress(func(p longop.Progress) { last = p }))
+	if err != nil {
+		t.Fatalf("resumed Run failed: %v", err)
+	}
+
+	if len(seen) != 20 {
+		t.Fatalf("expected every document processed exactly once, got %d", len(seen))
+	}
+	if last.Processed != 20 || last.Total != 20 {
+		t.Fatalf("unexpected final progress %+v", last)
+	}
+	if cp, _ := store.Load(ctx, "touch"); cp != nil {
+		t.Fatalf("expected checkpoint cleared on completion, got %+v", cp)
+	}
+}
+
+func TestRun_RateLimit(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("longop_rate")
+
+	docs := make([]any, 0, 20)
+	for i := 0; i < 20; i++ {
+		docs = append(docs, bson.M{"_id": i})
+	}
+	if _, err := coll.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	start := time.Now()
+	err := longop.Run(ctx, longop.Scan{Job: "rate", Collection: coll, BatchSize: 10},
+		func(ctx context.Context, batch []bson.Raw) error { return nil },
+		longop.WithRateLimit(50),
+	)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 350*time.Millisecond {
+		t.Fatalf("expected rate limit to pace 20 docs at 50/s, finished in %v", elapsed)
+	}
+}
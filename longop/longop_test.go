@@ -0,0 +1,53 @@
+package longop_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/longop"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestIDs(t *testing.T) {
+	batch := make([]bson.Raw, 0, 3)
+	for _, doc := range []bson.M{{"_id": "a"}, {"name": "no id"}, {"_id": int32(7)}} {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		batch = append(batch, raw)
+	}
+
+	got := longop.IDs(batch)
+	want := []any{"a", int32(7)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("IDs mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := longop.NewMemoryStore()
+
+	cp, err := store.Load(ctx, "job")
+	if err != nil || cp != nil {
+		t.Fatalf("expected no checkpoint, got %v, %v", cp, err)
+	}
+
+	if err := store.Save(ctx, "job", longop.Checkpoint{LastID: 5, Processed: 10}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	cp, err = store.Load(ctx, "job")
+	if err != nil || cp == nil || cp.LastID != 5 || cp.Processed != 10 {
+		t.Fatalf("unexpected checkpoint %+v, %v", cp, err)
+	}
+
+	if err := store.Clear(ctx, "job"); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	if cp, _ := store.Load(ctx, "job"); cp != nil {
+		t.Fatalf("expected checkpoint cleared, got %+v", cp)
+	}
+}
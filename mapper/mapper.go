@@ -0,0 +1,455 @@
+// Package mapper copies values between document types and the request and
+// response types of an API, so handlers do not assign fields one by one.
+//
+// Fields are matched by name, ignoring case and underscores, so ID matches Id
+// and UserID matches User_ID; fields of embedded structs such as
+// document.Base are matched as if they were declared directly. The mapper tag
+// changes the name a field is matched by, and mapper:"-" excludes it, e.g. to
+// keep clients from setting a document's owner. Matched fields convert:
+//   - between identical or assignable types
+//   - between numeric types, e.g. int32 and int
+//   - between hex strings and primitive.ObjectID
+//   - between T and *T, and between nested structs, slices, and maps of these
+//
+// Fields without a counterpart, or whose types do not convert, are left as
+// they are. The plan of each pair of types is built once and cached.
+//
+// Example:
+//
+//	type CreateUserRequest struct {
+//	    Name  string `json:"name"`
+//	    Email string `json:"email"`
+//	}
+//
+//	type UserResponse struct {
+//	    ID    string `json:"id"` // hex of User.ID
+//	    Name  string `json:"name"`
+//	    Email string `json:"email"`
+//	}
+//
+//	u, err := mapper.Map[User](req)
+//	...
+//	resp, err := mapper.Map[UserResponse](u)
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidTarget is returned when the destination is not a non-nil pointer
+// to a struct, or the source is not a struct or a pointer to one.
+var ErrInvalidTarget = errors.New("mapper: values must be structs or pointers to structs")
+
+// Option configures a copy.
+type Option func(*config)
+
+type config struct {
+	ignoreEmpty bool
+}
+
+// IgnoreEmpty skips source fields that are nil or zero, so only the fields a
+// client sent overwrite the destination. Nested structs are merged field by
+// field. Use it to apply a request with optional fields to a loaded document.
+//
+// Example:
+//
+//	type UpdateUserRequest struct {
+//	    Name  *string `json:"name"`
+//	    Email *string `json:"email"`
+//	}
+//
+//	err := mapper.Copy(user, req, mapper.IgnoreEmpty())
+func IgnoreEmpty() Option {
+	return func(c *config) { c.ignoreEmpty = true }
+}
+
+func applyOptions(opts []Option) *config {
+	c := &config{}
+	for _, o := range opts {
+		if o != nil {
+			o(c)
+		}
+	}
+	return c
+}
+
+// Copy copies the matching fields of src, a struct or a pointer to one, into
+// dst, a pointer to a struct. A nil src leaves dst unchanged.
+func Copy(dst, src any, opts ...Option) error {
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Pointer || d.IsNil() || d.Elem().Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+	s, ok := structValue(src)
+	if !ok {
+		return ErrInvalidTarget
+	}
+	if !s.IsValid() {
+		return nil
+	}
+	d = d.Elem()
+	return planFor(s.Type(), d.Type()).copy(applyOptions(opts), s, d)
+}
+
+// Map returns a new D with the matching fields of src, a struct or a pointer
+// to one. A nil src gives nil.
+func Map[D any](src any, opts ...Option) (*D, error) {
+	s, ok := structValue(src)
+	if !ok || reflect.TypeFor[D]().Kind() != reflect.Struct {
+		return nil, ErrInvalidTarget
+	}
+	if !s.IsValid() {
+		return nil, nil
+	}
+	d := new(D)
+	if err := planFor(s.Type(), reflect.TypeFor[D]()).copy(applyOptions(opts), s, reflect.ValueOf(d).Elem()); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// MapSlice maps each element of src to a D, e.g. for a list response. A nil
+// src gives nil.
+//
+// Example:
+//
+//	users, err := repo.Find(ctx, spec.Eq("status", "active"))
+//	...
+//	resp, err := mapper.MapSlice[UserResponse](users)
+func MapSlice[D, S any](src []S, opts ...Option) ([]D, error) {
+	if src == nil {
+		return nil, nil
+	}
+	st, dt := reflect.TypeFor[S](), reflect.TypeFor[D]()
+	if st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct || dt.Kind() != reflect.Struct {
+		return nil, ErrInvalidTarget
+	}
+	sp, c := planFor(st, dt), applyOptions(opts)
+	out := make([]D, len(src))
+	for i := range src {
+		s := reflect.ValueOf(&src[i]).Elem()
+		if s.Kind() == reflect.Pointer {
+			if s.IsNil() {
+				continue
+			}
+			s = s.Elem()
+		}
+		if err := sp.copy(c, s, reflect.ValueOf(&out[i]).Elem()); err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+	}
+	return out, nil
+}
+
+// structValue returns the struct v holds or points to, or the zero Value if
+// v is nil, and reports whether v is a struct or a pointer to one.
+func structValue(v any) (reflect.Value, bool) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return reflect.Value{}, true
+	}
+	if rv.Kind() == reflect.Pointer {
+		if rv.Type().Elem().Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		if rv.IsNil() {
+			return reflect.Value{}, true
+		}
+		rv = rv.Elem()
+	}
+	return rv, rv.Kind() == reflect.Struct
+}
+
+// convertFunc converts src into the settable dst.
+type convertFunc func(c *config, src, dst reflect.Value) error
+
+// fieldPlan copies one field.
+type fieldPlan struct {
+	name     string
+	src, dst []int
+	convert  convertFunc
+
+	// nested is the plan between the fields' struct types, if both are
+	// structs or pointers to structs.
+	nested *structPlan
+}
+
+// structPlan copies the matching fields of one struct type into another.
+type structPlan struct {
+	fields []fieldPlan
+}
+
+func (sp *structPlan) copy(c *config, src, dst reflect.Value) error {
+	for _, f := range sp.fields {
+		s := src.FieldByIndex(f.src)
+		if c.ignoreEmpty && s.IsZero() {
+			continue
+		}
+		if err := f.convert(c, s, dst.FieldByIndex(f.dst)); err != nil {
+			return fmt.Errorf("mapper: field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+var (
+	// plans caches complete plans by source and destination type.
+	plans sync.Map // [2]reflect.Type -> *structPlan
+
+	// building holds the plans built so far, including the ones still being
+	// built for recursive types; it is guarded by buildMu.
+	buildMu  sync.Mutex
+	building = planner{}
+)
+
+// planFor returns the cached plan copying struct type src into dst.
+func planFor(src, dst reflect.Type) *structPlan {
+	key := [2]reflect.Type{src, dst}
+	if sp, ok := plans.Load(key); ok {
+		return sp.(*structPlan)
+	}
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	sp := building.structPlan(src, dst)
+	plans.Store(key, sp)
+	return sp
+}
+
+// planner builds conversion plans, sharing the plan of each pair of struct
+// types so recursive types terminate.
+type planner map[[2]reflect.Type]*structPlan
+
+func (b planner) structPlan(src, dst reflect.Type) *structPlan {
+	key := [2]reflect.Type{src, dst}
+	if sp, ok := b[key]; ok {
+		return sp
+	}
+	sp := &structPlan{}
+	b[key] = sp
+
+	dstFields := fieldsOf(dst)
+	for name, si := range fieldsOf(src) {
+		di, ok := dstFields[name]
+		if !ok {
+			continue
+		}
+		st, dt := src.FieldByIndex(si).Type, dst.FieldByIndex(di).Type
+		conv := b.converter(st, dt)
+		if conv == nil {
+			continue
+		}
+		f := fieldPlan{name: dst.FieldByIndex(di).Name, src: si, dst: di, convert: conv}
+		if structType(st) != nil && structType(dt) != nil {
+			f.nested = b.structPlan(structType(st), structType(dt))
+		}
+		sp.fields = append(sp.fields, f)
+	}
+	slices.SortFunc(sp.fields, func(a, b fieldPlan) int { return slices.Compare(a.src, b.src) })
+	return sp
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+)
+
+// converter returns how to convert a value of type src to type dst, or nil.
+func (b planner) converter(src, dst reflect.Type) convertFunc {
+	if !src.AssignableTo(dst) {
+		return b.conversion(src, dst)
+	}
+	if structType(src) == nil || structType(dst) == nil {
+		return func(_ *config, s, d reflect.Value) error { d.Set(s); return nil }
+	}
+	// Structs are assigned whole, unexported fields included, unless they
+	// are merged.
+	merge := b.conversion(src, dst)
+	return func(c *config, s, d reflect.Value) error {
+		if c.ignoreEmpty {
+			return merge(c, s, d)
+		}
+		d.Set(s)
+		return nil
+	}
+}
+
+// conversion returns how to convert a value of type src to type dst field by
+// field or element by element, or nil.
+func (b planner) conversion(src, dst reflect.Type) convertFunc {
+	switch {
+	case src.Kind() == reflect.Pointer:
+		elem := b.converter(src.Elem(), dst)
+		if elem == nil {
+			return nil
+		}
+		return func(c *config, s, d reflect.Value) error {
+			if s.IsNil() {
+				d.SetZero()
+				return nil
+			}
+			return elem(c, s.Elem(), d)
+		}
+
+	case dst.Kind() == reflect.Pointer:
+		elem := b.converter(src, dst.Elem())
+		if elem == nil {
+			return nil
+		}
+		return func(c *config, s, d reflect.Value) error {
+			// Merging keeps the existing value so its other fields survive.
+			if d.IsNil() || !c.ignoreEmpty {
+				d.Set(reflect.New(dst.Elem()))
+			}
+			return elem(c, s, d.Elem())
+		}
+
+	case src.Kind() == reflect.String && dst == objectIDType:
+		return func(_ *config, s, d reflect.Value) error {
+			if s.Len() == 0 {
+				d.SetZero()
+				return nil
+			}
+			oid, err := primitive.ObjectIDFromHex(s.String())
+			if err != nil {
+				return err
+			}
+			d.Set(reflect.ValueOf(oid))
+			return nil
+		}
+	case src == objectIDType && dst.Kind() == reflect.String:
+		return func(_ *config, s, d reflect.Value) error {
+			if oid := s.Interface().(primitive.ObjectID); !oid.IsZero() {
+				d.SetString(oid.Hex())
+			} else {
+				d.SetString("")
+			}
+			return nil
+		}
+
+	case isNumeric(src.Kind()) && isNumeric(dst.Kind()):
+		return func(_ *config, s, d reflect.Value) error { d.Set(s.Convert(dst)); return nil }
+
+	case structType(src) != nil && structType(dst) != nil:
+		sp := b.structPlan(src, dst)
+		return func(c *config, s, d reflect.Value) error {
+			if !c.ignoreEmpty {
+				d.SetZero()
+			}
+			return sp.copy(c, s, d)
+		}
+
+	case src.Kind() == reflect.Slice && dst.Kind() == reflect.Slice:
+		elem := b.converter(src.Elem(), dst.Elem())
+		if elem == nil {
+			return nil
+		}
+		return func(c *config, s, d reflect.Value) error {
+			if s.IsNil() {
+				d.SetZero()
+				return nil
+			}
+			out := reflect.MakeSlice(dst, s.Len(), s.Len())
+			for i := range s.Len() {
+				if err := elem(c, s.Index(i), out.Index(i)); err != nil {
+					return fmt.Errorf("index %d: %w", i, err)
+				}
+			}
+			d.Set(out)
+			return nil
+		}
+
+	case src.Kind() == reflect.Map && dst.Kind() == reflect.Map && src.Key().AssignableTo(dst.Key()):
+		elem := b.converter(src.Elem(), dst.Elem())
+		if elem == nil {
+			return nil
+		}
+		return func(c *config, s, d reflect.Value) error {
+			if s.IsNil() {
+				d.SetZero()
+				return nil
+			}
+			out := reflect.MakeMapWithSize(dst, s.Len())
+			for it := s.MapRange(); it.Next(); {
+				v := reflect.New(dst.Elem()).Elem()
+				if err := elem(c, it.Value(), v); err != nil {
+					return fmt.Errorf("key %v: %w", it.Key(), err)
+				}
+				out.SetMapIndex(it.Key(), v)
+			}
+			d.Set(out)
+			return nil
+		}
+	}
+	return nil
+}
+
+// structType returns t if it is a struct type other than time.Time, and nil
+// otherwise. Pointers are handled by the caller.
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || t == objectIDType {
+		return nil
+	}
+	return t
+}
+
+func isNumeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// fieldsOf returns the index paths of the exported fields of t, including
+// those of embedded structs (not struct pointers), keyed by their normalized
+// names. Fields declared directly win over embedded ones.
+func fieldsOf(t reflect.Type) map[string][]int {
+	out := map[string][]int{}
+	depths := map[string]int{}
+	var walk func(t reflect.Type, prefix []int, depth int)
+	walk = func(t reflect.Type, prefix []int, depth int) {
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			tag := sf.Tag.Get("mapper")
+			if tag == "-" {
+				continue
+			}
+			index := append(append([]int(nil), prefix...), i)
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct && tag == "" {
+				walk(sf.Type, index, depth+1)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			name := sf.Name
+			if tag != "" {
+				name = tag
+			}
+			key := strings.ToLower(strings.ReplaceAll(name, "_", ""))
+			if d, ok := depths[key]; ok && d <= depth {
+				continue
+			}
+			depths[key] = depth
+			out[key] = index
+		}
+	}
+	walk(t, nil, 0)
+	return out
+}
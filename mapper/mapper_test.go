@@ -0,0 +1,201 @@
+package mapper_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/mapper"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type address struct {
+	Street string `bson:"street"`
+	City   string `bson:"city"`
+}
+
+type user struct {
+	document.Base `bson:",inline"`
+	Name          string             `bson:"name"`
+	Age           int                `bson:"age"`
+	OwnerID       primitive.ObjectID `bson:"owner_id"`
+	Address       address            `bson:"address"`
+	Billing       *address           `bson:"billing"`
+	Tags          []string           `bson:"tags"`
+	Secret        string             `bson:"secret"`
+}
+
+type addressDTO struct {
+	City *string `json:"city"`
+}
+
+type createUserRequest struct {
+	Name    string      `json:"name"`
+	Age     int32       `json:"age"`
+	Owner   string      `json:"owner" mapper:"OwnerID"`
+	Address addressDTO  `json:"address"`
+	Billing *addressDTO `json:"billing"`
+	Tags    []string    `json:"tags"`
+	Secret  string      `json:"secret" mapper:"-"`
+}
+
+type userResponse struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	Age       int64     `json:"age"`
+	OwnerID   string    `json:"owner_id"`
+	Address   struct {
+		City string `json:"city"`
+	} `json:"address"`
+	Billing *struct {
+		City string `json:"city"`
+	} `json:"billing"`
+}
+
+type updateUserRequest struct {
+	ID      string      `json:"id"`
+	Name    *string     `json:"name"`
+	Age     *int        `json:"age"`
+	Address *addressDTO `json:"address"`
+	Billing *addressDTO `json:"billing"`
+	Tags    []string    `json:"tags"`
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func TestMap(t *testing.T) {
+	owner := primitive.NewObjectID()
+	req := createUserRequest{
+		Name:    "Ada",
+		Age:     36,
+		Owner:   owner.Hex(),
+		Address: addressDTO{City: ptr("London")},
+		Billing: &addressDTO{City: ptr("Paris")},
+		Tags:    []string{"admin"},
+		Secret:  "ignored",
+	}
+
+	u, err := mapper.Map[user](&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "Ada" || u.Age != 36 || u.OwnerID != owner || u.Address.City != "London" ||
+		u.Billing == nil || u.Billing.City != "Paris" || !reflect.DeepEqual(u.Tags, []string{"admin"}) {
+		t.Fatalf("unexpected document: %+v", u)
+	}
+	if u.Secret != "" {
+		t.Fatalf("expected the excluded field to be skipped, got %q", u.Secret)
+	}
+
+	u.ID = primitive.NewObjectID()
+	u.CreatedAt = time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	resp, err := mapper.Map[userResponse](u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != u.ID.Hex() || !resp.CreatedAt.Equal(u.CreatedAt) || resp.Age != 36 || resp.OwnerID != owner.Hex() ||
+		resp.Address.City != "London" || resp.Billing == nil || resp.Billing.City != "Paris" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	if _, err := mapper.Map[user](&createUserRequest{Owner: "not-hex"}); err == nil {
+		t.Fatal("expected an error for an invalid ObjectID")
+	}
+	if got, err := mapper.Map[user]((*createUserRequest)(nil)); got != nil || err != nil {
+		t.Fatalf("expected nil for a nil source, got %v, %v", got, err)
+	}
+}
+
+func TestMapSlice(t *testing.T) {
+	users := []*user{{Name: "Ada"}, nil, {Name: "Grace"}}
+	resp, err := mapper.MapSlice[userResponse](users)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 3 || resp[0].Name != "Ada" || resp[1].Name != "" || resp[2].Name != "Grace" {
+		t.Fatalf("unexpected responses: %+v", resp)
+	}
+}
+
+func TestCopy_IgnoreEmpty(t *testing.T) {
+	u := &user{
+		Name:    "Ada",
+		Age:     36,
+		Address: address{Street: "1 Main St", City: "London"},
+		Billing: &address{Street: "2 High St", City: "Paris"},
+		Tags:    []string{"admin"},
+	}
+	req := updateUserRequest{Age: ptr(37), Address: &addressDTO{City: ptr("Oxford")}, Billing: &addressDTO{}}
+
+	if err := mapper.Copy(u, req, mapper.IgnoreEmpty()); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "Ada" || u.Age != 37 || !reflect.DeepEqual(u.Tags, []string{"admin"}) {
+		t.Fatalf("expected only the sent fields to change: %+v", u)
+	}
+	if u.Address != (address{Street: "1 Main St", City: "Oxford"}) || *u.Billing != (address{Street: "2 High St", City: "Paris"}) {
+		t.Fatalf("expected nested structs to be merged: %+v, %+v", u.Address, *u.Billing)
+	}
+
+	if err := mapper.Copy(u, req); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "" || u.Tags != nil || u.Address.Street != "" || u.Billing.Street != "" {
+		t.Fatalf("expected every matched field to be copied without IgnoreEmpty: %+v", u)
+	}
+}
+
+func TestCopy_InvalidTarget(t *testing.T) {
+	var u user
+	for _, tc := range []struct {
+		dst, src any
+	}{
+		{u, createUserRequest{}},
+		{&u, 3},
+		{(*user)(nil), createUserRequest{}},
+	} {
+		if err := mapper.Copy(tc.dst, tc.src); !errors.Is(err, mapper.ErrInvalidTarget) {
+			t.Fatalf("Copy(%T, %T): expected ErrInvalidTarget, got %v", tc.dst, tc.src, err)
+		}
+	}
+}
+
+func TestUpdateFromStruct(t *testing.T) {
+	req := updateUserRequest{
+		ID:      primitive.NewObjectID().Hex(),
+		Name:    ptr("Ada"),
+		Address: &addressDTO{City: ptr("London")},
+		Billing: &addressDTO{City: ptr("Paris")},
+	}
+
+	update, err := mapper.UpdateFromStruct[user](req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bson.M{"$set": bson.M{
+		"name":         "Ada",
+		"address.city": "London",
+		"billing":      &address{City: "Paris"},
+	}}
+	if got := update.ToBsonUpdate(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected update.\n got: %v\nwant: %v", got, want)
+	}
+
+	update, err = mapper.UpdateFromStruct[user](updateUserRequest{Age: ptr(0)}, mapper.IgnoreEmpty())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := update.ToBsonUpdate(); !reflect.DeepEqual(got, bson.M{"$set": bson.M{"age": 0}}) {
+		t.Fatalf("expected a set pointer to a zero value to be kept, got %v", got)
+	}
+
+	update, err = mapper.UpdateFromStruct[user](updateUserRequest{})
+	if err != nil || update != nil {
+		t.Fatalf("expected nil for an empty request, got %v, %v", update, err)
+	}
+}
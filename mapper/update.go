@@ -0,0 +1,136 @@
+package mapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// UpdateFromStruct returns a $set of the fields of src, a request struct or a
+// pointer to one, that match fields of the document type T. Fields are named
+// by T's bson names and converted to T's field types, so the update stores
+// what saving a T would.
+//
+// Behavior:
+//   - Nil pointers, slices, and maps are not set, so optional fields a client
+//     left out keep their stored values
+//   - With IgnoreEmpty, zero values are not set either
+//   - Nested structs are set field by field with dotted paths, except those
+//     behind pointers in T, which may be stored as null and are set whole
+//   - _id is never set
+//   - Returns nil if no field is set
+//
+// Example:
+//
+//	type UpdateUserRequest struct {
+//	    Name    *string `json:"name"`
+//	    Address *struct {
+//	        City *string `json:"city"`
+//	    } `json:"address"`
+//	}
+//
+//	update, err := mapper.UpdateFromStruct[User](req)
+//	// {"$set": {"name": "Ada", "address.city": "London"}}
+//	_, _, err = repo.UpdateOne(ctx, spec.Eq("_id", id), update)
+func UpdateFromStruct[T any](src any, opts ...Option) (spec.Update, error) {
+	dt := reflect.TypeFor[T]()
+	s, ok := structValue(src)
+	if !ok || dt.Kind() != reflect.Struct {
+		return nil, ErrInvalidTarget
+	}
+	if !s.IsValid() {
+		return nil, nil
+	}
+	set := bson.M{}
+	if err := addSets(set, "", applyOptions(opts), planFor(s.Type(), dt), s, dt); err != nil {
+		return nil, err
+	}
+	if len(set) == 0 {
+		return nil, nil
+	}
+	return spec.SetFields(set), nil
+}
+
+// addSets adds the fields of src that sp copies into struct type dst to set,
+// under the dotted path prefix.
+func addSets(set bson.M, prefix string, c *config, sp *structPlan, src reflect.Value, dst reflect.Type) error {
+	for _, f := range sp.fields {
+		s := src.FieldByIndex(f.src)
+		if isNil(s) || c.ignoreEmpty && s.IsZero() {
+			continue
+		}
+		name, stored := bsonPath(dst, f.dst)
+		if !stored {
+			continue
+		}
+		path := joinPath(prefix, name)
+		if path == "_id" {
+			continue
+		}
+
+		df := dst.FieldByIndex(f.dst)
+		if f.nested != nil && df.Type.Kind() == reflect.Struct {
+			if s.Kind() == reflect.Pointer {
+				s = s.Elem()
+			}
+			if err := addSets(set, path, c, f.nested, s, df.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		if path == "" {
+			continue
+		}
+		v := reflect.New(df.Type).Elem()
+		if err := f.convert(c, s, v); err != nil {
+			return fmt.Errorf("mapper: field %s: %w", f.name, err)
+		}
+		set[path] = v.Interface()
+	}
+	return nil
+}
+
+// bsonPath returns the dotted path of the field of t at index, following the
+// driver's naming rules, and reports whether the field is stored. Inline
+// structs add no segment.
+func bsonPath(t reflect.Type, index []int) (string, bool) {
+	var segs []string
+	for _, i := range index {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("bson")
+		if tag == "-" {
+			return "", false
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if !strings.Contains(","+opts+",", ",inline,") {
+			if name == "" {
+				name = strings.ToLower(sf.Name)
+			}
+			segs = append(segs, name)
+		}
+		t = sf.Type
+	}
+	return strings.Join(segs, "."), true
+}
+
+func joinPath(prefix, name string) string {
+	switch {
+	case prefix == "":
+		return name
+	case name == "":
+		return prefix
+	}
+	return prefix + "." + name
+}
+
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
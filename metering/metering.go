@@ -0,0 +1,267 @@
+// Package metering records per-tenant repository usage for SaaS billing and
+// quota tracking.
+//
+// Wrap decorates a repository so that every operation made with a tenant in
+// its context (see multitenancy.WithTenant) is counted: operations, documents
+// read, written, and deleted, and the BSON bytes read and written. Counts are
+// buffered in memory by a Meter and added to a usage collection with one $inc
+// per tenant, collection, and UTC day on every flush, so metering costs no
+// extra round trip per operation.
+//
+// Example:
+//
+//	meter := metering.NewMeter(db.Collection("usage"))
+//	go meter.Run(ctx)
+//
+//	orders := metering.Wrap[Order](mongorepo.New[Order](db.Collection("orders")), meter, "orders")
+//	_, err := orders.Find(multitenancy.WithTenant(ctx, "acme"), filter)
+//
+//	month, err := meter.Usage(ctx, "acme", firstOfMonth, time.Now())
+//	if month.BytesRead > quota {
+//	    ...
+//	}
+package metering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dElCIoGio/mongox/multitenancy"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Usage is the metered activity of a tenant. Documents in the usage collection
+// hold one Usage per tenant, collection, and UTC day.
+type Usage struct {
+	Tenant       string    `bson:"tenant"`
+	Collection   string    `bson:"collection,omitempty"`
+	Day          time.Time `bson:"day,omitempty"`
+	Ops          int64     `bson:"ops"`
+	DocsRead     int64     `bson:"docs_read"`
+	DocsWritten  int64     `bson:"docs_written"`
+	DocsDeleted  int64     `bson:"docs_deleted"`
+	BytesRead    int64     `bson:"bytes_read"`
+	BytesWritten int64     `bson:"bytes_written"`
+}
+
+func (u *Usage) add(o Usage) {
+	u.Ops += o.Ops
+	u.DocsRead += o.DocsRead
+	u.DocsWritten += o.DocsWritten
+	u.DocsDeleted += o.DocsDeleted
+	u.BytesRead += o.BytesRead
+	u.BytesWritten += o.BytesWritten
+}
+
+// Option configures a Meter.
+type Option func(*config)
+
+type config struct {
+	interval time.Duration
+	tenant   func(context.Context) (string, bool)
+	onError  func(err error)
+}
+
+// WithFlushInterval sets how often Run writes buffered counts. Defaults to 10s.
+func WithFlushInterval(d time.Duration) Option {
+	return func(c *config) { c.interval = d }
+}
+
+// WithTenantFunc sets how the tenant is read from an operation's context.
+// Defaults to multitenancy.TenantFromContext. Operations without a tenant are
+// not metered.
+func WithTenantFunc(fn func(context.Context) (string, bool)) Option {
+	return func(c *config) { c.tenant = fn }
+}
+
+// WithErrorHandler registers a callback for flush errors in Run.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) { c.onError = fn }
+}
+
+type usageKey struct {
+	tenant, collection string
+	day                time.Time
+}
+
+// Meter buffers usage counts and flushes them to a usage collection.
+// It is safe for concurrent use.
+type Meter struct {
+	store *mongo.Collection
+	cfg   config
+	now   func() time.Time
+
+	mu      sync.Mutex
+	pending map[usageKey]*Usage
+}
+
+// NewMeter creates a Meter that stores usage in store.
+func NewMeter(store *mongo.Collection, opts ...Option) *Meter {
+	cfg := config{interval: 10 * time.Second, tenant: multitenancy.TenantFromContext}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = 10 * time.Second
+	}
+	return &Meter{
+		store:   store,
+		cfg:     cfg,
+		now:     func() time.Time { return time.Now().UTC() },
+		pending: make(map[usageKey]*Usage),
+	}
+}
+
+// EnsureIndexes creates the index used by Usage queries.
+func (m *Meter) EnsureIndexes(ctx context.Context) error {
+	_, err := m.store.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "day", Value: 1}},
+	})
+	return err
+}
+
+// record adds u to the buffer of the tenant in ctx, if any.
+func (m *Meter) record(ctx context.Context, collection string, u Usage) {
+	tenant, ok := m.cfg.tenant(ctx)
+	if !ok {
+		return
+	}
+	now := m.now()
+	key := usageKey{tenant, collection, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pending[key]
+	if !ok {
+		p = &Usage{Tenant: key.tenant, Collection: key.collection, Day: key.day}
+		m.pending[key] = p
+	}
+	p.add(u)
+}
+
+// Pending returns the buffered counts not flushed yet, one per tenant,
+// collection, and day, in no particular order.
+func (m *Meter) Pending() []Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Usage, 0, len(m.pending))
+	for _, u := range m.pending {
+		out = append(out, *u)
+	}
+	return out
+}
+
+// Run flushes buffered counts every flush interval until ctx is cancelled,
+// then flushes once more with a fresh context and returns ctx.Err().
+func (m *Meter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+			m.reportError(m.Flush(flushCtx))
+			return ctx.Err()
+		case <-ticker.C:
+			m.reportError(m.Flush(ctx))
+		}
+	}
+}
+
+func (m *Meter) reportError(err error) {
+	if err != nil && m.cfg.onError != nil {
+		m.cfg.onError(err)
+	}
+}
+
+// Flush writes buffered counts to the usage collection. Counts that fail to
+// be written are kept for the next flush.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	batch := m.pending
+	m.pending = make(map[usageKey]*Usage)
+	m.mu.Unlock()
+
+	var errs []error
+	for key, u := range batch {
+		if err := m.write(ctx, u); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(batch, key)
+	}
+	if len(batch) > 0 {
+		m.mu.Lock()
+		for key, u := range batch {
+			if p, ok := m.pending[key]; ok {
+				p.add(*u)
+			} else {
+				m.pending[key] = u
+			}
+		}
+		m.mu.Unlock()
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("metering: flush: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (m *Meter) write(ctx context.Context, u *Usage) error {
+	id := u.Tenant + "/" + u.Collection + "/" + u.Day.Format(time.DateOnly)
+	_, err := m.store.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$setOnInsert": bson.M{"tenant": u.Tenant, "collection": u.Collection, "day": u.Day},
+			"$inc": bson.M{
+				"ops":           u.Ops,
+				"docs_read":     u.DocsRead,
+				"docs_written":  u.DocsWritten,
+				"docs_deleted":  u.DocsDeleted,
+				"bytes_read":    u.BytesRead,
+				"bytes_written": u.BytesWritten,
+			},
+		},
+		mopt.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Usage returns the tenant's total usage over all collections for the UTC days
+// from from through to, including counts not flushed yet. The result has no
+// Collection or Day.
+func (m *Meter) Usage(ctx context.Context, tenant string, from, to time.Time) (Usage, error) {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC()
+
+	total := Usage{Tenant: tenant}
+	cur, err := m.store.Find(ctx, bson.M{"tenant": tenant, "day": bson.M{"$gte": from, "$lte": to}})
+	if err != nil {
+		return total, err
+	}
+	var days []Usage
+	if err := cur.All(ctx, &days); err != nil {
+		return total, err
+	}
+	for _, d := range days {
+		total.add(d)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, u := range m.pending {
+		if key.tenant == tenant && !key.day.Before(from) && !key.day.After(to) {
+			total.add(*u)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,87 @@
+//go:build integration
+
+package metering_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/metering"
+	"github.com/dElCIoGio/mongox/multitenancy"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestMeter_FlushAndUsage(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	meter := metering.NewMeter(db.Collection("usage"))
+	if err := meter.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes: %v", err)
+	}
+	notes := metering.Wrap[Note](mongorepo.New[Note](db.Collection("notes")), meter, "notes")
+
+	acme := multitenancy.WithTenant(ctx, "acme")
+	for i := 0; i < 2; i++ {
+		if _, err := notes.InsertMany(acme, []*Note{{Text: "a"}, {Text: "b"}}); err != nil {
+			t.Fatalf("InsertMany: %v", err)
+		}
+		if err := meter.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	if _, err := notes.Find(acme, nil); err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+
+	// Two flushes accumulate into one document per tenant, collection, and day.
+	if n, _ := db.Collection("usage").CountDocuments(ctx, bson.M{"tenant": "acme"}); n != 1 {
+		t.Fatalf("expected one usage document, got %d", n)
+	}
+
+	// Usage includes the unflushed Find.
+	u, err := meter.Usage(ctx, "acme", time.Now().Add(-24*time.Hour), time.Now())
+	if err != nil {
+		t.Fatalf("Usage: %v", err)
+	}
+	if u.Ops != 3 || u.DocsWritten != 4 || u.DocsRead != 4 || u.BytesWritten == 0 {
+		t.Fatalf("unexpected usage: %+v", u)
+	}
+
+	if u, err := meter.Usage(ctx, "globex", time.Now().Add(-24*time.Hour), time.Now()); err != nil || u.Ops != 0 {
+		t.Fatalf("expected no usage for another tenant, got %+v (%v)", u, err)
+	}
+}
@@ -0,0 +1,108 @@
+package metering_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	"github.com/dElCIoGio/mongox/metering"
+	"github.com/dElCIoGio/mongox/multitenancy"
+	embeddedrepo "github.com/dElCIoGio/mongox/repository/embedded"
+	"github.com/dElCIoGio/mongox/spec"
+)
+
+type Note struct {
+	document.Base `bson:",inline"`
+
+	Text string `bson:"text"`
+}
+
+func newNotes(t *testing.T, meter *metering.Meter) *metering.Repository[Note] {
+	t.Helper()
+	coll, err := embeddedrepo.Memory().Collection("notes")
+	if err != nil {
+		t.Fatalf("Collection: %v", err)
+	}
+	return metering.Wrap[Note](embeddedrepo.New[Note](coll), meter, "notes")
+}
+
+func pendingFor(m *metering.Meter, tenant string) metering.Usage {
+	for _, u := range m.Pending() {
+		if u.Tenant == tenant {
+			return u
+		}
+	}
+	return metering.Usage{}
+}
+
+func TestRepository_CountsPerTenant(t *testing.T) {
+	meter := metering.NewMeter(testutil.LazyCollection(t, "usage"))
+	notes := newNotes(t, meter)
+
+	acme := multitenancy.WithTenant(context.Background(), "acme")
+	globex := multitenancy.WithTenant(context.Background(), "globex")
+
+	if _, err := notes.InsertMany(acme, []*Note{{Text: "a"}, {Text: "b"}, {Text: "c"}}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+	if _, err := notes.Find(acme, nil); err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if _, _, err := notes.UpdateMany(acme, spec.Eq("text", "a"), spec.Set("text", "z")); err != nil {
+		t.Fatalf("UpdateMany: %v", err)
+	}
+	if _, err := notes.DeleteOne(acme, spec.Eq("text", "b")); err != nil {
+		t.Fatalf("DeleteOne: %v", err)
+	}
+	if _, err := notes.Count(globex, nil); err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+
+	// Operations without a tenant are not metered.
+	if err := notes.InsertOne(context.Background(), &Note{Text: "untenanted"}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	got := pendingFor(meter, "acme")
+	if got.Collection != "notes" || got.Ops != 4 || got.DocsWritten != 4 || got.DocsRead != 3 || got.DocsDeleted != 1 {
+		t.Fatalf("unexpected acme usage: %+v", got)
+	}
+	if got.BytesRead == 0 || got.BytesWritten == 0 {
+		t.Fatalf("expected byte counts, got %+v", got)
+	}
+	if got := pendingFor(meter, "globex"); got.Ops != 1 || got.DocsRead != 0 {
+		t.Fatalf("unexpected globex usage: %+v", got)
+	}
+	if len(meter.Pending()) != 2 {
+		t.Fatalf("expected usage for two tenants, got %+v", meter.Pending())
+	}
+}
+
+func TestRepository_FailedOperationCountsOnlyTheOp(t *testing.T) {
+	meter := metering.NewMeter(testutil.LazyCollection(t, "usage"))
+	notes := newNotes(t, meter)
+	ctx := multitenancy.WithTenant(context.Background(), "acme")
+
+	if _, err := notes.FindOne(ctx, spec.Eq("text", "missing")); !errors.Is(err, embeddedrepo.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if got := pendingFor(meter, "acme"); got.Ops != 1 || got.DocsRead != 0 || got.BytesRead != 0 {
+		t.Fatalf("unexpected usage: %+v", got)
+	}
+}
+
+func TestWithTenantFunc(t *testing.T) {
+	meter := metering.NewMeter(testutil.LazyCollection(t, "usage"), metering.WithTenantFunc(func(context.Context) (string, bool) {
+		return "fixed", true
+	}))
+	notes := newNotes(t, meter)
+
+	if err := notes.InsertOne(context.Background(), &Note{Text: "x"}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	if got := pendingFor(meter, "fixed"); got.DocsWritten != 1 {
+		t.Fatalf("expected usage for the fixed tenant, got %+v", meter.Pending())
+	}
+}
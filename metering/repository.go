@@ -0,0 +1,159 @@
+package metering
+
+import (
+	"context"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Repository is a repository.Repository that meters every call made with a
+// tenant in its context and delegates to an inner repository.
+//
+// Byte counts are the encoded BSON size of the documents passed in and
+// returned, and of the update documents; they measure payload, not wire
+// traffic. Failed operations count as an operation only.
+type Repository[T any] struct {
+	inner      repository.Repository[T]
+	meter      *Meter
+	collection string
+}
+
+var _ repository.Repository[struct{}] = (*Repository[struct{}])(nil)
+
+// Wrap returns a metering decorator for repo. The collection name is stored
+// with the usage counts.
+func Wrap[T any](repo repository.Repository[T], meter *Meter, collection string) *Repository[T] {
+	return &Repository[T]{inner: repo, meter: meter, collection: collection}
+}
+
+// Unwrap returns the decorated repository.
+func (r *Repository[T]) Unwrap() repository.Repository[T] { return r.inner }
+
+func (r *Repository[T]) record(ctx context.Context, u Usage) {
+	u.Ops = 1
+	r.meter.record(ctx, r.collection, u)
+}
+
+func (r *Repository[T]) InsertOne(ctx context.Context, doc *T) error {
+	err := r.inner.InsertOne(ctx, doc)
+	if err != nil {
+		r.record(ctx, Usage{})
+		return err
+	}
+	r.record(ctx, Usage{DocsWritten: 1, BytesWritten: size(doc)})
+	return nil
+}
+
+func (r *Repository[T]) FindOne(ctx context.Context, filter any, opts ...repository.FindOption) (*T, error) {
+	doc, err := r.inner.FindOne(ctx, filter, opts...)
+	if err != nil {
+		r.record(ctx, Usage{})
+		return nil, err
+	}
+	r.record(ctx, Usage{DocsRead: 1, BytesRead: size(doc)})
+	return doc, nil
+}
+
+func (r *Repository[T]) Find(ctx context.Context, filter any, opts ...repository.FindOption) ([]T, error) {
+	docs, err := r.inner.Find(ctx, filter, opts...)
+	r.record(ctx, readUsage(docs))
+	return docs, err
+}
+
+func (r *Repository[T]) UpdateOne(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (int64, int64, error) {
+	matched, modified, err := r.inner.UpdateOne(ctx, filter, update, opts...)
+	r.record(ctx, updateUsage(modified, update))
+	return matched, modified, err
+}
+
+func (r *Repository[T]) ReplaceOne(ctx context.Context, filter any, doc *T) (int64, int64, error) {
+	matched, modified, err := r.inner.ReplaceOne(ctx, filter, doc)
+	u := Usage{DocsWritten: modified}
+	if modified > 0 {
+		u.BytesWritten = size(doc)
+	}
+	r.record(ctx, u)
+	return matched, modified, err
+}
+
+func (r *Repository[T]) DeleteOne(ctx context.Context, filter any) (int64, error) {
+	deleted, err := r.inner.DeleteOne(ctx, filter)
+	r.record(ctx, Usage{DocsDeleted: deleted})
+	return deleted, err
+}
+
+func (r *Repository[T]) InsertMany(ctx context.Context, docs []*T) ([]primitive.ObjectID, error) {
+	ids, err := r.inner.InsertMany(ctx, docs)
+	u := Usage{DocsWritten: int64(len(ids))}
+	for i := range ids {
+		if i < len(docs) {
+			u.BytesWritten += size(docs[i])
+		}
+	}
+	r.record(ctx, u)
+	return ids, err
+}
+
+func (r *Repository[T]) UpdateMany(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (int64, int64, error) {
+	matched, modified, err := r.inner.UpdateMany(ctx, filter, update, opts...)
+	r.record(ctx, updateUsage(modified, update))
+	return matched, modified, err
+}
+
+func (r *Repository[T]) DeleteMany(ctx context.Context, filter any) (int64, error) {
+	deleted, err := r.inner.DeleteMany(ctx, filter)
+	r.record(ctx, Usage{DocsDeleted: deleted})
+	return deleted, err
+}
+
+func (r *Repository[T]) Aggregate(ctx context.Context, pipeline any) ([]T, error) {
+	docs, err := r.inner.Aggregate(ctx, pipeline)
+	r.record(ctx, readUsage(docs))
+	return docs, err
+}
+
+func (r *Repository[T]) AggregateRaw(ctx context.Context, pipeline any) ([]bson.M, error) {
+	docs, err := r.inner.AggregateRaw(ctx, pipeline)
+	r.record(ctx, readUsage(docs))
+	return docs, err
+}
+
+func (r *Repository[T]) Count(ctx context.Context, filter any, opts ...repository.CountOption) (int64, error) {
+	n, err := r.inner.Count(ctx, filter, opts...)
+	r.record(ctx, Usage{})
+	return n, err
+}
+
+// readUsage counts the documents returned by a read.
+func readUsage[D any](docs []D) Usage {
+	u := Usage{DocsRead: int64(len(docs))}
+	for i := range docs {
+		u.BytesRead += size(&docs[i])
+	}
+	return u
+}
+
+// updateUsage counts an update that modified n documents; each is charged the
+// size of the update document.
+func updateUsage(n int64, update any) Usage {
+	u := Usage{DocsWritten: n}
+	if n > 0 {
+		u.BytesWritten = n * size(update)
+	}
+	return u
+}
+
+// size returns the encoded BSON size of v, or 0 if it cannot be encoded.
+func size(v any) int64 {
+	if c, ok := v.(interface{ ToBsonUpdate() bson.M }); ok {
+		v = c.ToBsonUpdate()
+	}
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return int64(len(b))
+}
@@ -0,0 +1,305 @@
+// Package migrate runs versioned schema migrations, such as index changes and
+// field renames, against a MongoDB database.
+//
+// Each Migration has a unique version and an Up function, and optionally a
+// Down function that reverts it. A Migrator records applied versions in a
+// collection and runs the pending ones in version order. It holds a
+// distributed lock (see package lock) while it works, so every instance of a
+// deployment can call Run at startup: one applies the migrations while the
+// others wait and then find nothing left to do.
+//
+// MongoDB cannot roll back index or collection changes, and a migration is only
+// recorded after its Up function returns, so Up and Down should be idempotent:
+// a migration interrupted half way is run again in full.
+//
+// Example:
+//
+//	m, err := migrate.New(db, []migrate.Migration{
+//	    {
+//	        Version:     1,
+//	        Description: "index users by email",
+//	        Up: func(ctx context.Context, db *mongo.Database) error {
+//	            _, err := db.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+//	                Keys:    bson.D{{Key: "email", Value: 1}},
+//	                Options: options.Index().SetName("email_1").SetUnique(true),
+//	            })
+//	            return err
+//	        },
+//	        Down: func(ctx context.Context, db *mongo.Database) error {
+//	            _, err := db.Collection("users").Indexes().DropOne(ctx, "email_1")
+//	            return err
+//	        },
+//	    },
+//	    {
+//	        Version:     2,
+//	        Description: "rename fullname to name",
+//	        Up: func(ctx context.Context, db *mongo.Database) error {
+//	            return datafix.Run(ctx, db.Collection("users"), []datafix.Operation{datafix.RenameField("fullname", "name")})
+//	        },
+//	    },
+//	})
+//	if err != nil {
+//	    return err
+//	}
+//	applied, err := m.Run(ctx)
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/dElCIoGio/mongox/lock"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrInvalidMigration is returned by New for migrations without an Up
+	// function or with a version that is not positive or not unique.
+	ErrInvalidMigration = errors.New("migrate: invalid migration")
+
+	// ErrIrreversible is returned by Rollback for a migration without a Down
+	// function. Nothing is rolled back past it.
+	ErrIrreversible = errors.New("migrate: migration has no Down function")
+
+	// ErrUnknownVersion is returned by Rollback when the most recent applied
+	// version is not among the Migrator's migrations, e.g. after deploying an
+	// older release.
+	ErrUnknownVersion = errors.New("migrate: applied version is not a known migration")
+)
+
+// Func changes the database. Use db.Client() for transactions or other
+// databases.
+type Func func(ctx context.Context, db *mongo.Database) error
+
+// Migration is one versioned change to the database.
+type Migration struct {
+	// Version orders the migrations and identifies them once applied. Use
+	// increasing numbers or timestamps such as 20240611120000.
+	Version int64
+
+	// Description is recorded with the applied version.
+	Description string
+
+	// Up applies the migration. Required.
+	Up Func
+
+	// Down reverts Up. Migrations without Down cannot be rolled back.
+	Down Func
+}
+
+// Record is an applied migration, as stored in the migrations collection.
+type Record struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// Option configures a Migrator.
+type Option func(*config)
+
+type config struct {
+	collection string
+	locker     *lock.Locker
+}
+
+// WithCollection sets the collection that records applied migrations.
+// Defaults to "migrations".
+func WithCollection(name string) Option {
+	return func(c *config) { c.collection = name }
+}
+
+// WithLocker sets the locker that serializes migrators across instances.
+// Defaults to a locker on the database's "locks" collection. The lock is named
+// after the migrations collection.
+func WithLocker(l *lock.Locker) Option {
+	return func(c *config) { c.locker = l }
+}
+
+// Migrator applies and rolls back a fixed set of migrations.
+// It is safe for concurrent use.
+type Migrator struct {
+	db         *mongo.Database
+	coll       *mongo.Collection
+	locker     *lock.Locker
+	lockName   string
+	migrations []Migration
+}
+
+// New creates a Migrator for migrations on db. The migrations may be given in
+// any order.
+func New(db *mongo.Database, migrations []Migration, opts ...Option) (*Migrator, error) {
+	cfg := config{collection: "migrations"}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.locker == nil {
+		cfg.locker = lock.New(db.Collection("locks"))
+	}
+
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	for i, m := range sorted {
+		switch {
+		case m.Version <= 0:
+			return nil, fmt.Errorf("%w: version %d is not positive", ErrInvalidMigration, m.Version)
+		case m.Up == nil:
+			return nil, fmt.Errorf("%w: version %d has no Up function", ErrInvalidMigration, m.Version)
+		case i > 0 && sorted[i-1].Version == m.Version:
+			return nil, fmt.Errorf("%w: duplicate version %d", ErrInvalidMigration, m.Version)
+		}
+	}
+
+	return &Migrator{
+		db:         db,
+		coll:       db.Collection(cfg.collection),
+		locker:     cfg.locker,
+		lockName:   "migrate:" + cfg.collection,
+		migrations: sorted,
+	}, nil
+}
+
+// Applied returns the applied migrations in version order.
+func (m *Migrator) Applied(ctx context.Context) ([]Record, error) {
+	cur, err := m.coll.Find(ctx, bson.M{}, mopt.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("migrate: list applied: %w", err)
+	}
+	var records []Record
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("migrate: list applied: %w", err)
+	}
+	return records, nil
+}
+
+// Pending returns the migrations that have not been applied, in version order.
+// Migrations older than the latest applied one are pending too, e.g. when
+// branches were merged; Run applies them.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	records, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.pending(records), nil
+}
+
+func (m *Migrator) pending(records []Record) []Migration {
+	applied := make(map[int64]bool, len(records))
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+	var out []Migration
+	for _, mg := range m.migrations {
+		if !applied[mg.Version] {
+			out = append(out, mg)
+		}
+	}
+	return out
+}
+
+// Run applies the pending migrations in version order and returns the versions
+// it applied. It stops at the first migration that fails; the migrations before
+// it stay applied.
+func (m *Migrator) Run(ctx context.Context) ([]int64, error) {
+	var done []int64
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		records, err := m.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.pending(records) {
+			if err := mg.Up(ctx, m.db); err != nil {
+				return fmt.Errorf("migrate: up %d (%s): %w", mg.Version, mg.Description, err)
+			}
+			rec := Record{Version: mg.Version, Description: mg.Description, AppliedAt: time.Now().UTC()}
+			if _, err := m.coll.InsertOne(ctx, rec); err != nil {
+				return fmt.Errorf("migrate: record %d: %w", mg.Version, err)
+			}
+			done = append(done, mg.Version)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Rollback reverts the most recently applied steps migrations, newest first,
+// and returns the versions it reverted. It stops at the first migration that
+// fails or cannot be reverted.
+func (m *Migrator) Rollback(ctx context.Context, steps int) ([]int64, error) {
+	var done []int64
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		records, err := m.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(records) - 1; i >= 0 && len(done) < steps; i-- {
+			v := records[i].Version
+			idx, found := slices.BinarySearchFunc(m.migrations, v, func(mg Migration, v int64) int {
+				return cmp.Compare(mg.Version, v)
+			})
+			if !found {
+				return fmt.Errorf("%w: %d", ErrUnknownVersion, v)
+			}
+			mg := m.migrations[idx]
+			if mg.Down == nil {
+				return fmt.Errorf("%w: %d (%s)", ErrIrreversible, v, mg.Description)
+			}
+			if err := mg.Down(ctx, m.db); err != nil {
+				return fmt.Errorf("migrate: down %d (%s): %w", v, mg.Description, err)
+			}
+			if _, err := m.coll.DeleteOne(ctx, bson.M{"_id": v}); err != nil {
+				return fmt.Errorf("migrate: unrecord %d: %w", v, err)
+			}
+			done = append(done, v)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// withLock runs fn while holding the migration lock, refreshing the lease in
+// the background. If the lease is lost, fn's context is cancelled and the error
+// matches lock.ErrLost.
+func (m *Migrator) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	lk, err := m.locker.Acquire(ctx, m.lockName)
+	if err != nil {
+		return fmt.Errorf("migrate: acquire lock: %w", err)
+	}
+	defer func() { _ = lk.Release(context.WithoutCancel(ctx)) }()
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(m.locker.TTL() / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-ticker.C:
+				if err := lk.Refresh(runCtx); errors.Is(err, lock.ErrLost) {
+					cancel(err)
+					return
+				}
+			}
+		}
+	}()
+
+	err = fn(runCtx)
+	lost := context.Cause(runCtx)
+	cancel(nil)
+	<-stopped
+	if err != nil && errors.Is(lost, lock.ErrLost) {
+		return fmt.Errorf("migrate: %w", lost)
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+//go:build integration
+
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/migrate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestMigrator_RunAndRollback(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	users := db.Collection("users")
+	if _, err := users.InsertOne(ctx, bson.M{"fullname": "Ada", "email": "ada@example.com"}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	var ups atomic.Int32
+	migrations := []migrate.Migration{
+		{
+			Version:     2,
+			Description: "rename fullname",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				ups.Add(1)
+				_, err := db.Collection("users").UpdateMany(ctx, bson.M{}, bson.M{"$rename": bson.M{"fullname": "name"}})
+				return err
+			},
+			Down: func(ctx context.Context, db *mongo.Database) error {
+				_, err := db.Collection("users").UpdateMany(ctx, bson.M{}, bson.M{"$rename": bson.M{"name": "fullname"}})
+				return err
+			},
+		},
+		{
+			Version:     1,
+			Description: "index email",
+			Up: func(ctx context.Context, db *mongo.Database) error {
+				ups.Add(1)
+				_, err := db.Collection("users").Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "email", Value: 1}},
+					Options: mopt.Index().SetName("email_1"),
+				})
+				return err
+			},
+		},
+	}
+
+	// Two instances starting at once apply each migration exactly once.
+	var wg sync.WaitGroup
+	results := make([][]int64, 2)
+	for i := range results {
+		m, err := migrate.New(db, migrations)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied, err := m.Run(ctx)
+			if err != nil {
+				t.Errorf("Run: %v", err)
+			}
+			results[i] = applied
+		}()
+	}
+	wg.Wait()
+	if ups.Load() != 2 || len(results[0])+len(results[1]) != 2 {
+		t.Fatalf("ups = %d, applied = %v", ups.Load(), results)
+	}
+
+	m, _ := migrate.New(db, migrations)
+	records, err := m.Applied(ctx)
+	if err != nil || len(records) != 2 || records[0].Version != 1 || records[1].Description != "rename fullname" {
+		t.Fatalf("Applied: %+v, %v", records, err)
+	}
+	if n, _ := users.CountDocuments(ctx, bson.M{"name": "Ada"}); n != 1 {
+		t.Fatal("rename not applied")
+	}
+
+	// Version 1 has no Down, so rolling back everything stops after version 2.
+	reverted, err := m.Rollback(ctx, 5)
+	if !errors.Is(err, migrate.ErrIrreversible) || !reflect.DeepEqual(reverted, []int64{2}) {
+		t.Fatalf("Rollback: %v, %v", reverted, err)
+	}
+	if n, _ := users.CountDocuments(ctx, bson.M{"fullname": "Ada"}); n != 1 {
+		t.Fatal("rename not reverted")
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil || len(pending) != 1 || pending[0].Version != 2 {
+		t.Fatalf("Pending: %+v, %v", pending, err)
+	}
+}
@@ -0,0 +1,69 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/migrate"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lazyDatabase returns a database on a client that never connects; it is only
+// used where no server round trip happens.
+func lazyDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client.Database("testdb")
+}
+
+func noop(context.Context, *mongo.Database) error { return nil }
+
+func TestNew_Validates(t *testing.T) {
+	db := lazyDatabase(t)
+
+	tests := []struct {
+		name       string
+		migrations []migrate.Migration
+	}{
+		{"zero version", []migrate.Migration{{Version: 0, Up: noop}}},
+		{"negative version", []migrate.Migration{{Version: -3, Up: noop}}},
+		{"missing Up", []migrate.Migration{{Version: 1, Down: noop}}},
+		{"duplicate version", []migrate.Migration{{Version: 2, Up: noop}, {Version: 1, Up: noop}, {Version: 2, Up: noop}}},
+	}
+	for _, tt := range tests {
+		if _, err := migrate.New(db, tt.migrations); !errors.Is(err, migrate.ErrInvalidMigration) {
+			t.Errorf("%s: err = %v, want ErrInvalidMigration", tt.name, err)
+		}
+	}
+
+	if _, err := migrate.New(db, []migrate.Migration{{Version: 2, Up: noop}, {Version: 1, Up: noop}}); err != nil {
+		t.Fatalf("unordered migrations: %v", err)
+	}
+}
+
+func TestRun_CanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ran := false
+	m, err := migrate.New(lazyDatabase(t), []migrate.Migration{{
+		Version: 1,
+		Up:      func(context.Context, *mongo.Database) error { ran = true; return nil },
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run err = %v, want context.Canceled", err)
+	}
+	if ran {
+		t.Fatal("migration ran without the lock")
+	}
+}
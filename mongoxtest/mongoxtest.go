@@ -0,0 +1,244 @@
+// Package mongoxtest provides test helpers for verifying that repositories and
+// pipelines return equivalent data.
+//
+// The helpers are intended for refactoring safety nets: run the same query against
+// two repositories (or two pipelines against one), or snapshot results into a
+// golden file and compare subsequent runs against it.
+//
+// Example:
+//
+//	func TestPipelineRewrite(t *testing.T) {
+//	    mongoxtest.AssertSameAggregate(t, repo, oldPipeline, repo, newPipeline)
+//	}
+package mongoxtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to a non-empty value,
+// makes AssertGolden rewrite golden files instead of comparing against them.
+//
+// Example:
+//
+//	MONGOX_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "MONGOX_UPDATE_GOLDEN"
+
+// AssertSameResults runs Find with the same filter and options against both
+// repositories and fails the test if the result sets differ.
+// Results are compared as unordered sets of documents; use AssertSameOrderedResults
+// when ordering matters.
+func AssertSameResults[T any](t testing.TB, a, b repository.Repository[T], filter any, opts ...repository.FindOption) {
+	t.Helper()
+	assertSameFind(t, a, b, filter, opts, false)
+}
+
+// AssertSameOrderedResults is like AssertSameResults but also requires both
+// repositories to return documents in the same order.
+func AssertSameOrderedResults[T any](t testing.TB, a, b repository.Repository[T], filter any, opts ...repository.FindOption) {
+	t.Helper()
+	assertSameFind(t, a, b, filter, opts, true)
+}
+
+func assertSameFind[T any](t testing.TB, a, b repository.Repository[T], filter any, opts []repository.FindOption, ordered bool) {
+	t.Helper()
+	ctx := context.Background()
+
+	got, err := a.Find(ctx, filter, opts...)
+	if err != nil {
+		t.Fatalf("mongoxtest: find on first repository: %v", err)
+	}
+	want, err := b.Find(ctx, filter, opts...)
+	if err != nil {
+		t.Fatalf("mongoxtest: find on second repository: %v", err)
+	}
+
+	assertSame(t, got, want, ordered)
+}
+
+// AssertSameAggregate runs pipelineA against a and pipelineB against b and fails
+// the test if the raw results differ. Results are compared in order, since
+// pipelines usually end in an explicit $sort.
+//
+// Example:
+//
+//	// Verify that a rewritten pipeline returns the same data
+//	mongoxtest.AssertSameAggregate(t, repo, legacyPipeline, repo, optimizedPipeline)
+func AssertSameAggregate[T any](t testing.TB, a repository.Repository[T], pipelineA any, b repository.Repository[T], pipelineB any) {
+	t.Helper()
+	ctx := context.Background()
+
+	got, err := a.AggregateRaw(ctx, pipelineA)
+	if err != nil {
+		t.Fatalf("mongoxtest: aggregate on first repository: %v", err)
+	}
+	want, err := b.AggregateRaw(ctx, pipelineB)
+	if err != nil {
+		t.Fatalf("mongoxtest: aggregate on second repository: %v", err)
+	}
+
+	assertSame(t, got, want, true)
+}
+
+func assertSame[T any](t testing.TB, got, want []T, ordered bool) {
+	t.Helper()
+
+	diff, err := Diff(got, want, ordered)
+	if err != nil {
+		t.Fatalf("mongoxtest: %v", err)
+	}
+	if diff != "" {
+		t.Fatalf("mongoxtest: results differ (-first +second):\n%s", diff)
+	}
+}
+
+// Diff compares two result slices and returns a line-based description of the
+// documents present in only one of them. An empty string means the results are
+// equivalent. Documents are compared by their canonical Extended JSON encoding
+// with map keys sorted, so bson.M ordering does not produce false differences.
+func Diff[T any](got, want []T, ordered bool) (string, error) {
+	gotLines, err := canonicalLines(got)
+	if err != nil {
+		return "", err
+	}
+	wantLines, err := canonicalLines(want)
+	if err != nil {
+		return "", err
+	}
+
+	if !ordered {
+		sort.Strings(gotLines)
+		sort.Strings(wantLines)
+	}
+
+	var sb strings.Builder
+	if ordered {
+		n := max(len(gotLines), len(wantLines))
+		for i := 0; i < n; i++ {
+			switch {
+			case i >= len(gotLines):
+				fmt.Fprintf(&sb, "+ [%d] %s\n", i, wantLines[i])
+			case i >= len(wantLines):
+				fmt.Fprintf(&sb, "- [%d] %s\n", i, gotLines[i])
+			case gotLines[i] != wantLines[i]:
+				fmt.Fprintf(&sb, "- [%d] %s\n+ [%d] %s\n", i, gotLines[i], i, wantLines[i])
+			}
+		}
+		return sb.String(), nil
+	}
+
+	counts := make(map[string]int, len(gotLines))
+	for _, l := range gotLines {
+		counts[l]++
+	}
+	for _, l := range wantLines {
+		counts[l]--
+	}
+	for _, l := range gotLines {
+		if counts[l] > 0 {
+			fmt.Fprintf(&sb, "- %s\n", l)
+			counts[l]--
+		}
+	}
+	for _, l := range wantLines {
+		if counts[l] < 0 {
+			fmt.Fprintf(&sb, "+ %s\n", l)
+			counts[l]++
+		}
+	}
+	return sb.String(), nil
+}
+
+// AssertGolden compares results against the golden file testdata/<name>.golden,
+// relative to the test's working directory. When the UpdateGoldenEnv environment
+// variable is set, the golden file is (re)written instead.
+//
+// Results are stored as one canonical Extended JSON document per line with map
+// keys sorted, which keeps golden files stable and diff-friendly.
+//
+// Example:
+//
+//	users, _ := repo.Find(ctx, spec.Eq("status", "active"), repository.WithSort(bson.D{{"_id", 1}}))
+//	mongoxtest.AssertGolden(t, "active_users", users)
+func AssertGolden[T any](t testing.TB, name string, results []T) {
+	t.Helper()
+
+	lines, err := canonicalLines(results)
+	if err != nil {
+		t.Fatalf("mongoxtest: %v", err)
+	}
+	content := []byte(strings.Join(lines, "\n") + "\n")
+	path := filepath.Join("testdata", name+".golden")
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mongoxtest: create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatalf("mongoxtest: write golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("mongoxtest: read golden file (set %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(expected, content) {
+		t.Fatalf("mongoxtest: results do not match golden file %s\n got:\n%s\nwant:\n%s", path, content, expected)
+	}
+}
+
+// canonicalLines encodes each document as canonical Extended JSON with sorted keys.
+func canonicalLines[T any](docs []T) ([]string, error) {
+	lines := make([]string, len(docs))
+	for i, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("marshal document %d: %w", i, err)
+		}
+		var d bson.D
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return nil, fmt.Errorf("unmarshal document %d: %w", i, err)
+		}
+		out, err := bson.MarshalExtJSON(sortKeys(d), true, false)
+		if err != nil {
+			return nil, fmt.Errorf("encode document %d: %w", i, err)
+		}
+		lines[i] = string(out)
+	}
+	return lines, nil
+}
+
+// sortKeys recursively orders document keys so encodings are deterministic.
+func sortKeys(v any) any {
+	switch val := v.(type) {
+	case bson.D:
+		sorted := make(bson.D, len(val))
+		for i, e := range val {
+			sorted[i] = bson.E{Key: e.Key, Value: sortKeys(e.Value)}
+		}
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+		return sorted
+	case primitive.A:
+		out := make(primitive.A, len(val))
+		for i, item := range val {
+			out[i] = sortKeys(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
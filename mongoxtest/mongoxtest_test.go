@@ -0,0 +1,71 @@
+package mongoxtest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/mongoxtest"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDiff_Unordered(t *testing.T) {
+	got := []bson.M{{"a": 1, "b": 2}, {"a": 2}}
+	want := []bson.M{{"a": 2}, {"b": 2, "a": 1}}
+
+	diff, err := mongoxtest.Diff(got, want, false)
+	if err != nil {
+		t.Fatalf("Diff returned error: %v", err)
+	}
+	if diff != "" {
+		t.Fatalf("expected no diff, got:\n%s", diff)
+	}
+}
+
+func TestDiff_Ordered(t *testing.T) {
+	got := []bson.M{{"a": 1}, {"a": 2}}
+	want := []bson.M{{"a": 2}, {"a": 1}}
+
+	diff, err := mongoxtest.Diff(got, want, true)
+	if err != nil {
+		t.Fatalf("Diff returned error: %v", err)
+	}
+	if diff == "" {
+		t.Fatal("expected ordered diff to report differences")
+	}
+}
+
+func TestDiff_MissingDocument(t *testing.T) {
+	got := []bson.M{{"a": 1}}
+	want := []bson.M{{"a": 1}, {"a": 3}}
+
+	diff, err := mongoxtest.Diff(got, want, false)
+	if err != nil {
+		t.Fatalf("Diff returned error: %v", err)
+	}
+	if !strings.HasPrefix(diff, "+ ") || strings.Count(diff, "\n") != 1 {
+		t.Fatalf("expected a single added document, got:\n%s", diff)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	t.Chdir(t.TempDir())
+	results := []bson.M{{"name": "alice", "age": 30}}
+
+	t.Setenv(mongoxtest.UpdateGoldenEnv, "1")
+	mongoxtest.AssertGolden(t, "users", results)
+
+	content, err := os.ReadFile(filepath.Join("testdata", "users.golden"))
+	if err != nil {
+		t.Fatalf("expected golden file to be written: %v", err)
+	}
+	want := `{"age":{"$numberInt":"30"},"name":"alice"}` + "\n"
+	if string(content) != want {
+		t.Fatalf("golden content mismatch.\n got: %q\nwant: %q", content, want)
+	}
+
+	t.Setenv(mongoxtest.UpdateGoldenEnv, "")
+	mongoxtest.AssertGolden(t, "users", results)
+}
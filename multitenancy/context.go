@@ -0,0 +1,21 @@
+package multitenancy
+
+import "context"
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying tenantID, for tenant-aware components
+// such as the metering decorator.
+//
+// Example:
+//
+//	ctx = multitenancy.WithTenant(r.Context(), claims.TenantID)
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant ID stored by WithTenant.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
@@ -0,0 +1,57 @@
+package multitenancy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Exporter saves the documents of one tenant collection before it is deleted.
+// It must consume cur; DeprovisionTenant closes it afterwards.
+type Exporter func(ctx context.Context, tenantID, collection string, cur *mongo.Cursor) error
+
+// NoExport is an Exporter that discards the data, for deleting tenants whose
+// data must not be kept.
+func NoExport(context.Context, string, string, *mongo.Cursor) error { return nil }
+
+// ExportTo returns an Exporter writing each collection to
+// "<dir>/<tenant>/<collection>.bson" as concatenated BSON documents, the format
+// read by mongorestore. Files are written to a temporary name and renamed once
+// complete, so a partial export never looks finished.
+func ExportTo(dir string) Exporter {
+	return func(ctx context.Context, tenantID, collection string, cur *mongo.Cursor) (err error) {
+		target := filepath.Join(dir, tenantID)
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return err
+		}
+
+		f, err := os.CreateTemp(target, collection+".*.tmp")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				_ = f.Close()
+				_ = os.Remove(f.Name())
+			}
+		}()
+
+		for cur.Next(ctx) {
+			if _, err := f.Write(cur.Current); err != nil {
+				return err
+			}
+		}
+		if err := cur.Err(); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return os.Rename(f.Name(), filepath.Join(target, collection+".bson"))
+	}
+}
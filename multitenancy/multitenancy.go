@@ -0,0 +1,326 @@
+// Package multitenancy provisions and removes the MongoDB storage of tenants in
+// multi-tenant applications.
+//
+// Document types are registered once with Register. ProvisionTenant then
+// creates every registered collection for a tenant, with its server-side
+// validator and the indexes declared by the type's document.Indexed
+// implementation. Provisioning is idempotent: running it again for an existing
+// tenant updates validators and creates missing indexes, so it can also roll
+// out schema changes to every tenant. DeprovisionTenant exports the tenant's
+// data before deleting it.
+//
+// Tenants either get a database each (LayoutDatabase, the default) or share
+// one database with collections named "<tenant>.<collection>" (LayoutCollection).
+//
+// Example:
+//
+//	p := multitenancy.New(mongoClient, multitenancy.WithDatabasePrefix("app_"))
+//	multitenancy.Register[User](p, "users")
+//	multitenancy.Register[Order](p, "orders", multitenancy.WithValidator(orderSchema))
+//
+//	if err := p.ProvisionTenant(ctx, "acme"); err != nil {
+//	    return err
+//	}
+//	coll, _ := p.Collection("acme", "orders")
+//	orders := mongorepo.New[Order](coll)
+//	...
+//	err := p.DeprovisionTenant(ctx, "acme", multitenancy.ExportTo("/var/backups/tenants"))
+package multitenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrInvalidTenantID is returned for tenant IDs that are empty, longer than
+	// 32 characters, or contain characters other than letters, digits, '-' and '_'.
+	ErrInvalidTenantID = errors.New("multitenancy: invalid tenant id")
+
+	// ErrUnknownCollection is returned for collection names that were not registered.
+	ErrUnknownCollection = errors.New("multitenancy: unknown collection")
+
+	// ErrNoExporter is returned by DeprovisionTenant when no Exporter is given.
+	// Pass NoExport to delete a tenant without exporting it.
+	ErrNoExporter = errors.New("multitenancy: no exporter given")
+)
+
+// Layout selects how tenants are separated.
+type Layout int
+
+const (
+	// LayoutDatabase gives every tenant its own database, named by the
+	// database prefix followed by the tenant ID.
+	LayoutDatabase Layout = iota
+
+	// LayoutCollection keeps every tenant in one shared database, with
+	// collections named "<tenant>.<collection>".
+	LayoutCollection
+)
+
+// Option configures a Provisioner.
+type Option func(*config)
+
+type config struct {
+	layout   Layout
+	prefix   string
+	database string
+}
+
+// WithDatabasePrefix sets the prefix of per-tenant database names in
+// LayoutDatabase. Defaults to "tenant_".
+func WithDatabasePrefix(prefix string) Option {
+	return func(c *config) { c.prefix = prefix }
+}
+
+// WithSharedDatabase selects LayoutCollection, storing every tenant in the
+// named database.
+func WithSharedDatabase(name string) Option {
+	return func(c *config) {
+		c.layout = LayoutCollection
+		c.database = name
+	}
+}
+
+// CollectionOption configures a registered collection.
+type CollectionOption func(*collectionSpec)
+
+// WithValidator sets a server-side validator, typically a {"$jsonSchema": ...}
+// document, applied with the "strict" level and the "error" action.
+func WithValidator(validator bson.M) CollectionOption {
+	return func(s *collectionSpec) { s.validator = validator }
+}
+
+// WithValidationAction sets the action for documents failing the validator:
+// "error" (the default) rejects them, "warn" only logs them on the server.
+func WithValidationAction(action string) CollectionOption {
+	return func(s *collectionSpec) { s.action = action }
+}
+
+type collectionSpec struct {
+	name      string
+	validator bson.M
+	action    string
+	indexes   func(ctx context.Context, coll *mongo.Collection) error
+}
+
+// Provisioner creates and removes the collections of tenants.
+type Provisioner struct {
+	client *mongo.Client
+	cfg    config
+
+	mu          sync.RWMutex
+	collections map[string]collectionSpec
+}
+
+// New creates a Provisioner on client.
+func New(client *mongo.Client, opts ...Option) *Provisioner {
+	cfg := config{prefix: "tenant_"}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	return &Provisioner{client: client, cfg: cfg, collections: make(map[string]collectionSpec)}
+}
+
+// Register adds a collection storing documents of type T to every tenant. If T
+// implements document.Indexed, its indexes are created on provisioning.
+// Registering a name twice replaces the earlier registration.
+func Register[T any](p *Provisioner, name string, opts ...CollectionOption) {
+	s := collectionSpec{
+		name:   name,
+		action: "error",
+		indexes: func(ctx context.Context, coll *mongo.Collection) error {
+			return mongorepo.New[T](coll).EnsureIndexes(ctx)
+		},
+	}
+	for _, o := range opts {
+		if o != nil {
+			o(&s)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.collections[name] = s
+}
+
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// ValidateTenantID reports whether id can be used as a tenant ID.
+func ValidateTenantID(id string) error {
+	if !tenantIDPattern.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidTenantID, id)
+	}
+	return nil
+}
+
+// Database returns the database holding the tenant's collections.
+func (p *Provisioner) Database(tenantID string) (*mongo.Database, error) {
+	if err := ValidateTenantID(tenantID); err != nil {
+		return nil, err
+	}
+	if p.cfg.layout == LayoutCollection {
+		return p.client.Database(p.cfg.database), nil
+	}
+	return p.client.Database(p.cfg.prefix + tenantID), nil
+}
+
+// Collection returns the tenant's copy of a registered collection.
+func (p *Provisioner) Collection(tenantID, name string) (*mongo.Collection, error) {
+	p.mu.RLock()
+	_, ok := p.collections[name]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCollection, name)
+	}
+	db, err := p.Database(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return db.Collection(p.collectionName(tenantID, name)), nil
+}
+
+func (p *Provisioner) collectionName(tenantID, name string) string {
+	if p.cfg.layout == LayoutCollection {
+		return tenantID + "." + name
+	}
+	return name
+}
+
+// ProvisionTenant creates the tenant's registered collections with their
+// validators and indexes. It is safe to call for tenants that already exist:
+// validators are updated and missing indexes created.
+func (p *Provisioner) ProvisionTenant(ctx context.Context, tenantID string) error {
+	db, err := p.Database(tenantID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range p.specs() {
+		name := p.collectionName(tenantID, s.name)
+		if err := ensureCollection(ctx, db, name, s); err != nil {
+			return fmt.Errorf("multitenancy: provision %s for tenant %q: %w", s.name, tenantID, err)
+		}
+		if err := s.indexes(ctx, db.Collection(name)); err != nil {
+			return fmt.Errorf("multitenancy: create indexes on %s for tenant %q: %w", s.name, tenantID, err)
+		}
+	}
+	return nil
+}
+
+// specs returns the registered collections in name order.
+func (p *Provisioner) specs() []collectionSpec {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]collectionSpec, 0, len(p.collections))
+	for _, s := range p.collections {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// codeNamespaceExists is the server error code for creating an existing collection.
+const codeNamespaceExists = 48
+
+// ensureCollection creates the collection, or updates the validator of an
+// existing one.
+func ensureCollection(ctx context.Context, db *mongo.Database, name string, s collectionSpec) error {
+	opts := mopt.CreateCollection()
+	if s.validator != nil {
+		opts.SetValidator(s.validator).SetValidationLevel("strict").SetValidationAction(s.action)
+	}
+	err := db.CreateCollection(ctx, name, opts)
+
+	var cmdErr mongo.CommandError
+	switch {
+	case err == nil:
+		return nil
+	case !errors.As(err, &cmdErr) || cmdErr.Code != codeNamespaceExists:
+		return err
+	case s.validator == nil:
+		return nil
+	}
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: name},
+		{Key: "validator", Value: s.validator},
+		{Key: "validationLevel", Value: "strict"},
+		{Key: "validationAction", Value: s.action},
+	}).Err()
+}
+
+// DeprovisionTenant exports every collection of the tenant with export and
+// then deletes them; in LayoutDatabase the tenant's database is dropped. All
+// collections are exported before anything is deleted, so a failed export
+// leaves the tenant intact. Collections of the tenant that were never
+// registered are exported and deleted too.
+func (p *Provisioner) DeprovisionTenant(ctx context.Context, tenantID string, export Exporter) error {
+	if export == nil {
+		return ErrNoExporter
+	}
+	db, err := p.Database(tenantID)
+	if err != nil {
+		return err
+	}
+
+	names, err := p.tenantCollections(ctx, db, tenantID)
+	if err != nil {
+		return fmt.Errorf("multitenancy: list collections of tenant %q: %w", tenantID, err)
+	}
+
+	for _, name := range names {
+		if err := exportCollection(ctx, db.Collection(name), tenantID, strings.TrimPrefix(name, p.collectionName(tenantID, "")), export); err != nil {
+			return fmt.Errorf("multitenancy: export %s of tenant %q: %w", name, tenantID, err)
+		}
+	}
+
+	if p.cfg.layout == LayoutDatabase {
+		if err := db.Drop(ctx); err != nil {
+			return fmt.Errorf("multitenancy: drop database of tenant %q: %w", tenantID, err)
+		}
+		return nil
+	}
+	for _, name := range names {
+		if err := db.Collection(name).Drop(ctx); err != nil {
+			return fmt.Errorf("multitenancy: drop %s of tenant %q: %w", name, tenantID, err)
+		}
+	}
+	return nil
+}
+
+// tenantCollections lists the names of the tenant's collections, excluding
+// system collections.
+func (p *Provisioner) tenantCollections(ctx context.Context, db *mongo.Database, tenantID string) ([]string, error) {
+	filter := bson.M{"name": bson.M{"$not": bson.M{"$regex": `^system\.`}}}
+	if p.cfg.layout == LayoutCollection {
+		filter = bson.M{"name": bson.M{"$regex": "^" + regexp.QuoteMeta(tenantID+".")}}
+	}
+	names, err := db.ListCollectionNames(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func exportCollection(ctx context.Context, coll *mongo.Collection, tenantID, name string, export Exporter) error {
+	cur, err := coll.Find(ctx, bson.M{}, mopt.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	return export(ctx, tenantID, name, cur)
+}
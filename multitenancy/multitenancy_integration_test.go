@@ -0,0 +1,115 @@
+//go:build integration
+
+package multitenancy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/multitenancy"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+var orderSchema = bson.M{"$jsonSchema": bson.M{
+	"bsonType": "object",
+	"required": bson.A{"number"},
+	"properties": bson.M{
+		"number": bson.M{"bsonType": "string"},
+	},
+}}
+
+func TestProvisionAndDeprovisionTenant(t *testing.T) {
+	client := setupMongo(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		opts []multitenancy.Option
+	}{
+		{"database per tenant", nil},
+		{"shared database", []multitenancy.Option{multitenancy.WithSharedDatabase("shared")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := multitenancy.New(client, tc.opts...)
+			multitenancy.Register[Order](p, "orders", multitenancy.WithValidator(orderSchema))
+
+			// Provisioning twice is a no-op the second time.
+			for i := 0; i < 2; i++ {
+				if err := p.ProvisionTenant(ctx, "acme"); err != nil {
+					t.Fatalf("ProvisionTenant #%d: %v", i+1, err)
+				}
+			}
+			if err := p.ProvisionTenant(ctx, "globex"); err != nil {
+				t.Fatalf("ProvisionTenant globex: %v", err)
+			}
+
+			coll, err := p.Collection("acme", "orders")
+			if err != nil {
+				t.Fatalf("Collection: %v", err)
+			}
+			if _, err := coll.InsertOne(ctx, bson.M{"number": "A-1"}); err != nil {
+				t.Fatalf("insert valid order: %v", err)
+			}
+			if _, err := coll.InsertOne(ctx, bson.M{"total": 10}); err == nil {
+				t.Fatal("expected the validator to reject an order without a number")
+			}
+			if _, err := coll.InsertOne(ctx, bson.M{"number": "A-1"}); !mongo.IsDuplicateKeyError(err) {
+				t.Fatalf("expected the unique index to reject a duplicate, got %v", err)
+			}
+
+			dir := t.TempDir()
+			if err := p.DeprovisionTenant(ctx, "acme", multitenancy.ExportTo(dir)); err != nil {
+				t.Fatalf("DeprovisionTenant: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "acme", "orders.bson"))
+			if err != nil {
+				t.Fatalf("read export: %v", err)
+			}
+			if got := bson.Raw(data).Lookup("number").StringValue(); got != "A-1" {
+				t.Fatalf("expected the exported order, got %q", got)
+			}
+
+			if n, _ := coll.CountDocuments(ctx, bson.M{}); n != 0 {
+				t.Fatalf("expected the tenant's data to be deleted, found %d documents", n)
+			}
+			other, _ := p.Collection("globex", "orders")
+			names, err := other.Database().ListCollectionNames(ctx, bson.M{"name": other.Name()})
+			if err != nil || len(names) != 1 {
+				t.Fatalf("expected other tenants to be untouched, got %v (%v)", names, err)
+			}
+		})
+	}
+}
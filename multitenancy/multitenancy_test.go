@@ -0,0 +1,134 @@
+package multitenancy_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/multitenancy"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type Order struct {
+	document.Base `bson:",inline"`
+
+	Number string `bson:"number"`
+	Total  int    `bson:"total"`
+}
+
+func (Order) Indexes() []document.Index {
+	return []document.Index{{Keys: bson.D{{Key: "number", Value: 1}}, Unique: true, Name: "unique_number"}}
+}
+
+// lazyClient returns a client that never connects; it is only used where no
+// server round trip happens.
+func lazyClient(t *testing.T) *mongo.Client {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client
+}
+
+func TestValidateTenantID(t *testing.T) {
+	for _, id := range []string{"acme", "Acme-EU_2", "a"} {
+		if err := multitenancy.ValidateTenantID(id); err != nil {
+			t.Errorf("ValidateTenantID(%q) = %v, want nil", id, err)
+		}
+	}
+	for _, id := range []string{"", "acme.eu", "acme/eu", "a b", "0123456789012345678901234567890123"} {
+		if err := multitenancy.ValidateTenantID(id); !errors.Is(err, multitenancy.ErrInvalidTenantID) {
+			t.Errorf("ValidateTenantID(%q) = %v, want ErrInvalidTenantID", id, err)
+		}
+	}
+}
+
+func TestCollection_Layouts(t *testing.T) {
+	client := lazyClient(t)
+
+	perDB := multitenancy.New(client, multitenancy.WithDatabasePrefix("app_"))
+	multitenancy.Register[Order](perDB, "orders")
+	coll, err := perDB.Collection("acme", "orders")
+	if err != nil {
+		t.Fatalf("Collection: %v", err)
+	}
+	if coll.Database().Name() != "app_acme" || coll.Name() != "orders" {
+		t.Fatalf("unexpected namespace %s.%s", coll.Database().Name(), coll.Name())
+	}
+
+	shared := multitenancy.New(client, multitenancy.WithSharedDatabase("app"))
+	multitenancy.Register[Order](shared, "orders")
+	coll, err = shared.Collection("acme", "orders")
+	if err != nil {
+		t.Fatalf("Collection: %v", err)
+	}
+	if coll.Database().Name() != "app" || coll.Name() != "acme.orders" {
+		t.Fatalf("unexpected namespace %s.%s", coll.Database().Name(), coll.Name())
+	}
+
+	if _, err := shared.Collection("acme", "invoices"); !errors.Is(err, multitenancy.ErrUnknownCollection) {
+		t.Fatalf("expected ErrUnknownCollection, got %v", err)
+	}
+	if _, err := shared.Collection("acme.eu", "orders"); !errors.Is(err, multitenancy.ErrInvalidTenantID) {
+		t.Fatalf("expected ErrInvalidTenantID, got %v", err)
+	}
+}
+
+func TestDeprovisionTenant_RequiresExporter(t *testing.T) {
+	p := multitenancy.New(lazyClient(t))
+	if err := p.DeprovisionTenant(context.Background(), "acme", nil); !errors.Is(err, multitenancy.ErrNoExporter) {
+		t.Fatalf("expected ErrNoExporter, got %v", err)
+	}
+}
+
+func TestExportTo_WritesConcatenatedBSON(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	docs := []any{bson.D{{Key: "_id", Value: 1}}, bson.D{{Key: "_id", Value: 2}}}
+	cur, err := mongo.NewCursorFromDocuments(docs, nil, nil)
+	if err != nil {
+		t.Fatalf("NewCursorFromDocuments: %v", err)
+	}
+
+	if err := multitenancy.ExportTo(dir)(ctx, "acme", "orders", cur); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "acme", "orders.bson"))
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	var want bytes.Buffer
+	for _, d := range docs {
+		b, _ := bson.Marshal(d)
+		want.Write(b)
+	}
+	if !bytes.Equal(data, want.Bytes()) {
+		t.Fatalf("unexpected export contents: %x", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(dir, "acme"))
+	if len(entries) != 1 {
+		t.Fatalf("expected only the final file, got %d entries", len(entries))
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := multitenancy.TenantFromContext(ctx); ok {
+		t.Fatal("expected no tenant in a plain context")
+	}
+	if id, ok := multitenancy.TenantFromContext(multitenancy.WithTenant(ctx, "acme")); !ok || id != "acme" {
+		t.Fatalf("expected tenant acme, got %q", id)
+	}
+}
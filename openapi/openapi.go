@@ -0,0 +1,353 @@
+// Package openapi generates OpenAPI 3.0 component schemas from document
+// types, so API specifications are derived from the stored models instead of
+// being maintained by hand.
+//
+// Field names are the names the type has in JSON: the json tag, or the Go
+// field name, with embedded structs flattened, as encoding/json writes them.
+// Use WithBSONNames for APIs that write documents with document.ToJSON.
+// Formats describe how values are written:
+//   - primitive.ObjectID: string, format "objectid" (24 hex characters)
+//   - time.Time: string, format "date-time"
+//   - primitive.Decimal128: string, format "decimal"
+//   - []byte: string, format "byte"
+//
+// Descriptions come from the doc struct tag, and required fields, enums,
+// bounds, and patterns from the schema tag (see document.JSONSchema).
+// Named struct types become components of their own, referenced with $ref.
+//
+// Example:
+//
+//	g := openapi.New()
+//	if err := openapi.Register[User](g); err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := openapi.RegisterPage[User](g); err != nil { // UserPage
+//	    log.Fatal(err)
+//	}
+//	_ = g.WriteJSON(os.Stdout) // {"components": {"schemas": {...}}}
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schema is an OpenAPI 3.0 schema object.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              any                `json:"minimum,omitempty"`
+	Maximum              any                `json:"maximum,omitempty"`
+	MinLength            *int64             `json:"minLength,omitempty"`
+	MaxLength            *int64             `json:"maxLength,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+}
+
+// Option configures a Generator.
+type Option func(*Generator)
+
+// WithBSONNames names fields as document.ToJSON does: by their bson names,
+// with _id written as id, and with inline structs flattened.
+func WithBSONNames() Option {
+	return func(g *Generator) { g.bsonNames = true }
+}
+
+// Generator collects the component schemas of registered types. It is safe
+// for concurrent use.
+type Generator struct {
+	bsonNames bool
+
+	mu      sync.Mutex
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+// New returns an empty Generator.
+func New(opts ...Option) *Generator {
+	g := &Generator{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+	for _, o := range opts {
+		if o != nil {
+			o(g)
+		}
+	}
+	return g
+}
+
+// Register adds the schema of the struct type T, named after the type, and of
+// the named struct types it uses. Registering a type again has no effect.
+func Register[T any](g *Generator) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, err := g.component(reflect.TypeFor[T](), "")
+	return err
+}
+
+// RegisterPage adds the schema of repository.Page[T], the envelope returned
+// by FindPaginated, named after T with a "Page" suffix, e.g. "UserPage". T is
+// registered too.
+func RegisterPage[T any](g *Generator) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	t := reflect.TypeFor[T]()
+	_, err := g.component(reflect.TypeFor[repository.Page[T]](), t.Name()+"Page")
+	return err
+}
+
+// Schemas returns a copy of the registered component schemas by name.
+func (g *Generator) Schemas() map[string]*Schema {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return maps.Clone(g.schemas)
+}
+
+// WriteJSON writes the schemas as an OpenAPI components object,
+// {"components": {"schemas": {...}}}, to merge into a specification.
+func (g *Generator) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{"components": map[string]any{"schemas": g.Schemas()}})
+}
+
+// component registers the struct type t under name (t's name if empty) and
+// returns a reference to it.
+func (g *Generator) component(t reflect.Type, name string) (*Schema, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("openapi: %s is not a struct type", t)
+	}
+	if name == "" {
+		name = t.Name()
+	}
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if existing, ok := g.names[t]; ok && existing == name {
+		return ref, nil
+	}
+	for other, n := range g.names {
+		if n == name && other != t {
+			return nil, fmt.Errorf("openapi: %s and %s are both named %s", other, t, name)
+		}
+	}
+	g.names[t] = name // before the fields, for recursive types
+
+	s, err := g.object(t)
+	if err != nil {
+		delete(g.names, t)
+		return nil, err
+	}
+	g.schemas[name] = s
+	return ref, nil
+}
+
+// object returns the schema of the fields of struct type t.
+func (g *Generator) object(t reflect.Type) (*Schema, error) {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	if err := g.addFields(s, t); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// addFields adds the fields of struct type t, including those of embedded or
+// inline structs, to s.
+func (g *Generator) addFields(s *Schema, t reflect.Type) error {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, flatten, skip := g.fieldName(sf)
+		if skip {
+			continue
+		}
+		if flatten {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if err := g.addFields(s, ft); err != nil {
+				return err
+			}
+			continue
+		}
+
+		prop, err := g.value(sf.Type)
+		if err != nil {
+			return fmt.Errorf("openapi: %s.%s: %w", t, sf.Name, err)
+		}
+		rules, err := document.ParseSchemaTag(sf)
+		if err != nil {
+			return err
+		}
+		s.Properties[name] = constrain(prop, sf.Tag.Get("doc"), rules)
+		if rules.Required {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return nil
+}
+
+// fieldName returns the name of a field in JSON, or reports that the field is
+// an embedded struct whose fields are flattened, or that it is not written.
+func (g *Generator) fieldName(sf reflect.StructField) (name string, flatten, skip bool) {
+	ft := sf.Type
+	if ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	isStruct := ft.Kind() == reflect.Struct
+
+	if g.bsonNames {
+		tag := sf.Tag.Get("bson")
+		name, opts, _ := strings.Cut(tag, ",")
+		switch {
+		case tag == "-":
+			return "", false, true
+		case strings.Contains(","+opts+",", ",inline,") && isStruct:
+			return "", true, false
+		case !sf.IsExported():
+			return "", false, true
+		case name == "":
+			name = strings.ToLower(sf.Name)
+		}
+		if name == "_id" {
+			name = "id"
+		}
+		return name, false, false
+	}
+
+	tag := sf.Tag.Get("json")
+	name, _, _ = strings.Cut(tag, ",")
+	switch {
+	case tag == "-":
+		return "", false, true
+	case sf.Anonymous && name == "" && isStruct:
+		return "", true, false
+	case !sf.IsExported():
+		return "", false, true
+	case name == "":
+		name = sf.Name
+	}
+	return name, false, false
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	dateTimeType      = reflect.TypeOf(primitive.DateTime(0))
+	objectIDType      = reflect.TypeOf(primitive.ObjectID{})
+	decimalType       = reflect.TypeOf(primitive.Decimal128{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// value returns the schema of a value of type t.
+func (g *Generator) value(t reflect.Type) (*Schema, error) {
+	switch t {
+	case timeType, dateTimeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case objectIDType:
+		return &Schema{Type: "string", Format: "objectid", Pattern: "^[0-9a-f]{24}$"}, nil
+	case decimalType:
+		return &Schema{Type: "string", Format: "decimal"}, nil
+	}
+	if t.Kind() != reflect.Pointer {
+		if implements(t, jsonMarshalerType) {
+			return &Schema{}, nil
+		}
+		if implements(t, textMarshalerType) {
+			return &Schema{Type: "string"}, nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s, err := g.value(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return nullable(s), nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}, nil
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: true}, nil
+		}
+		items, err := g.value(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items, Nullable: t.Kind() == reflect.Slice}, nil
+	case reflect.Map:
+		elem, err := g.value(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: elem, Nullable: true}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.component(t, "")
+	default:
+		return &Schema{}, nil
+	}
+}
+
+// constrain adds a description and the rules of a schema tag to s.
+func constrain(s *Schema, desc string, rules document.SchemaRules) *Schema {
+	if desc == "" && rules.Enum == nil && rules.Minimum == nil && rules.Maximum == nil &&
+		rules.MinLength == nil && rules.MaxLength == nil && rules.Pattern == "" {
+		return s
+	}
+	if s.Ref != "" {
+		s = &Schema{AllOf: []*Schema{s}}
+	}
+	s.Description = desc
+	s.Enum = rules.Enum
+	s.Minimum, s.Maximum = rules.Minimum, rules.Maximum
+	s.MinLength, s.MaxLength = rules.MinLength, rules.MaxLength
+	if rules.Pattern != "" {
+		s.Pattern = rules.Pattern
+	}
+	return s
+}
+
+// implements reports whether t or *t implements iface.
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+// nullable marks s as allowing null. A reference is wrapped in allOf, since
+// OpenAPI 3.0 ignores the siblings of $ref.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{AllOf: []*Schema{s}, Nullable: true}
+	}
+	s.Nullable = true
+	return s
+}
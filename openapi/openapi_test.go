@@ -0,0 +1,155 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/openapi"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Address struct {
+	City string `json:"city" bson:"city"`
+}
+
+type User struct {
+	document.Base `bson:",inline"`
+	Email         string              `json:"email" bson:"email" schema:"required,pattern=^[^@]+@[^@]+$" doc:"Login address"`
+	Age           int32               `json:"age,omitempty" bson:"age" schema:"min=0,max=150"`
+	Status        string              `json:"status" bson:"status" schema:"enum=active|banned"`
+	Score         float64             `json:"score" bson:"score"`
+	ManagerID     *primitive.ObjectID `json:"manager_id" bson:"manager_id"`
+	Address       *Address            `json:"address" bson:"address"`
+	Tags          []string            `json:"tags" bson:"tags"`
+	Meta          map[string]int      `json:"meta" bson:"meta"`
+	DeletedAt     *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at"`
+	Secret        string              `json:"-" bson:"secret"`
+	Manager       *User               `json:"manager,omitempty" bson:"-"`
+}
+
+func TestRegister_JSONNames(t *testing.T) {
+	g := openapi.New()
+	if err := openapi.Register[User](g); err != nil {
+		t.Fatal(err)
+	}
+	schemas := g.Schemas()
+	if len(schemas) != 2 || schemas["User"] == nil || schemas["Address"] == nil {
+		t.Fatalf("expected User and Address components, got %v", keys(schemas))
+	}
+
+	u := schemas["User"]
+	got := map[string]openapi.Schema{}
+	for name, s := range u.Properties {
+		got[name] = *s
+	}
+	check := func(name string, want openapi.Schema) {
+		t.Helper()
+		if !reflect.DeepEqual(got[name], want) {
+			t.Errorf("%s: got %+v, want %+v", name, got[name], want)
+		}
+	}
+	check("id", openapi.Schema{Type: "string", Format: "objectid", Pattern: "^[0-9a-f]{24}$"})
+	check("created_at", openapi.Schema{Type: "string", Format: "date-time"})
+	check("email", openapi.Schema{Type: "string", Description: "Login address", Pattern: "^[^@]+@[^@]+$"})
+	check("age", openapi.Schema{Type: "integer", Format: "int32", Minimum: int64(0), Maximum: int64(150)})
+	check("status", openapi.Schema{Type: "string", Enum: []any{"active", "banned"}})
+	check("score", openapi.Schema{Type: "number", Format: "double"})
+	check("manager_id", openapi.Schema{Type: "string", Format: "objectid", Pattern: "^[0-9a-f]{24}$", Nullable: true})
+	check("address", openapi.Schema{AllOf: []*openapi.Schema{{Ref: "#/components/schemas/Address"}}, Nullable: true})
+	check("tags", openapi.Schema{Type: "array", Items: &openapi.Schema{Type: "string"}, Nullable: true})
+	check("meta", openapi.Schema{Type: "object", AdditionalProperties: &openapi.Schema{Type: "integer", Format: "int64"}, Nullable: true})
+	check("manager", openapi.Schema{AllOf: []*openapi.Schema{{Ref: "#/components/schemas/User"}}, Nullable: true})
+	if _, ok := got["Secret"]; ok {
+		t.Error("expected json:\"-\" field to be skipped")
+	}
+	if len(got) != 13 {
+		t.Errorf("expected 13 properties, got %v", keys(u.Properties))
+	}
+	if !reflect.DeepEqual(u.Required, []string{"email"}) {
+		t.Errorf("expected email required, got %v", u.Required)
+	}
+}
+
+func TestRegister_BSONNames(t *testing.T) {
+	g := openapi.New(openapi.WithBSONNames())
+	if err := openapi.Register[User](g); err != nil {
+		t.Fatal(err)
+	}
+	props := g.Schemas()["User"].Properties
+	for _, name := range []string{"id", "created_at", "secret"} {
+		if props[name] == nil {
+			t.Errorf("expected property %s, got %v", name, keys(props))
+		}
+	}
+	if props["manager"] != nil {
+		t.Error("expected bson:\"-\" field to be skipped")
+	}
+}
+
+func TestRegisterPage(t *testing.T) {
+	g := openapi.New()
+	if err := openapi.RegisterPage[User](g); err != nil {
+		t.Fatal(err)
+	}
+	page := g.Schemas()["UserPage"]
+	if page == nil {
+		t.Fatalf("expected UserPage, got %v", keys(g.Schemas()))
+	}
+	items := page.Properties["Items"]
+	if items == nil || items.Type != "array" || items.Items.Ref != "#/components/schemas/User" {
+		t.Fatalf("unexpected Items schema: %+v", items)
+	}
+	if s := page.Properties["Total"]; s == nil || s.Type != "integer" {
+		t.Fatalf("unexpected Total schema: %+v", s)
+	}
+	if g.Schemas()["User"] == nil {
+		t.Fatal("expected User registered with its page")
+	}
+}
+
+func TestRegister_Errors(t *testing.T) {
+	if err := openapi.Register[string](openapi.New()); err == nil {
+		t.Fatal("expected an error for a non-struct type")
+	}
+	type bad struct {
+		N int `schema:"min=x"`
+	}
+	if err := openapi.Register[bad](openapi.New()); err == nil {
+		t.Fatal("expected an error for an invalid schema tag")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	g := openapi.New()
+	if err := openapi.Register[Address](g); err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	if err := g.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal([]byte(buf.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Components.Schemas["Address"]["type"] != "object" {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
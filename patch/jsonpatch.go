@@ -0,0 +1,197 @@
+package patch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Operation is a single RFC 6902 JSON Patch operation.
+type Operation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Result is the translation of a JSON Patch document.
+type Result struct {
+	// Update applies the add, remove, replace, and move operations.
+	// It is nil if the patch contains only test operations.
+	Update spec.Update
+
+	// Filter holds the preconditions from test and replace operations.
+	// Combine it with the target filter so the update only applies when they hold.
+	// It is nil if there are no preconditions.
+	Filter spec.Filter
+}
+
+// JSONPatch translates an RFC 6902 JSON Patch document into an update and a
+// precondition filter.
+//
+// Operation mapping:
+//   - add: $set, or $push when the path ends in "-" or an array index
+//   - remove: $unset (removing array elements by index is not supported)
+//   - replace: $set, plus an existence precondition on the path
+//   - move: $rename (array element paths are not supported)
+//   - test: an equality precondition on the path
+//   - copy: not supported
+//
+// Example:
+//
+//	res, err := patch.JSONPatch(body, patch.WithAllowedFields("name", "tags"))
+//	if err != nil {
+//	    return err
+//	}
+//	_, _, err = repo.UpdateOneStrict(ctx, spec.And(spec.Eq("_id", id), res.Filter), res.Update)
+func JSONPatch(patch []byte, opts ...Option) (*Result, error) {
+	var ops []Operation
+	if err := decodeJSON(patch, &ops); err != nil {
+		return nil, err
+	}
+	return JSONPatchOps(ops, opts...)
+}
+
+// JSONPatchOps is like JSONPatch but takes already decoded operations.
+func JSONPatchOps(ops []Operation, opts ...Option) (*Result, error) {
+	c := applyOptions(opts)
+
+	var updates []spec.Update
+	var filters []spec.Filter
+	for i, op := range ops {
+		segs, err := pointerSegments(op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+		last := segs[len(segs)-1]
+		parent := segs[:len(segs)-1]
+
+		switch op.Op {
+		case "add":
+			switch {
+			case last == "-" && len(parent) > 0:
+				path, err := c.fieldPath(parent)
+				if err != nil {
+					return nil, fmt.Errorf("operation %d: %w", i, err)
+				}
+				updates = append(updates, spec.Push(path, normalizeValue(op.Value)))
+			case isIndex(last) && len(parent) > 0:
+				path, err := c.fieldPath(parent)
+				if err != nil {
+					return nil, fmt.Errorf("operation %d: %w", i, err)
+				}
+				pos, _ := strconv.Atoi(last)
+				updates = append(updates, pushAt(path, normalizeValue(op.Value), pos))
+			default:
+				path, err := c.fieldPath(segs)
+				if err != nil {
+					return nil, fmt.Errorf("operation %d: %w", i, err)
+				}
+				updates = append(updates, spec.Set(path, normalizeValue(op.Value)))
+			}
+
+		case "remove":
+			if isIndex(last) {
+				return nil, fmt.Errorf("operation %d: %w: remove of array element %q", i, ErrUnsupportedOp, op.Path)
+			}
+			path, err := c.fieldPath(segs)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			updates = append(updates, spec.Unset(path))
+
+		case "replace":
+			path, err := c.fieldPath(segs)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			updates = append(updates, spec.Set(path, normalizeValue(op.Value)))
+			filters = append(filters, spec.Exists(path, true))
+
+		case "move":
+			fromSegs, err := pointerSegments(op.From)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			if hasIndex(fromSegs) || hasIndex(segs) {
+				return nil, fmt.Errorf("operation %d: %w: move involving array elements", i, ErrUnsupportedOp)
+			}
+			from, err := c.fieldPath(fromSegs)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			to, err := c.fieldPath(segs)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			updates = append(updates, spec.Rename(from, to))
+
+		case "test":
+			path, err := pathOf(segs)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+			filters = append(filters, spec.Eq(path, normalizeValue(op.Value)))
+
+		case "copy":
+			return nil, fmt.Errorf("operation %d: %w: copy", i, ErrUnsupportedOp)
+
+		default:
+			return nil, fmt.Errorf("operation %d: %w: unknown op %q", i, ErrInvalidPatch, op.Op)
+		}
+	}
+
+	return &Result{
+		Update: spec.Combine(updates...),
+		Filter: spec.And(filters...),
+	}, nil
+}
+
+// fieldPath converts pointer segments to a dotted path and checks it is writable.
+func (c config) fieldPath(segs []string) (string, error) {
+	path, err := pathOf(segs)
+	if err != nil {
+		return "", err
+	}
+	if err := c.checkField(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func pathOf(segs []string) (string, error) {
+	for _, seg := range segs {
+		if err := checkSegment(seg); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(segs, "."), nil
+}
+
+func hasIndex(segs []string) bool {
+	for _, seg := range segs {
+		if isIndex(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// pushAtUpdate inserts a value at a given array position.
+type pushAtUpdate struct {
+	field    string
+	value    any
+	position int
+}
+
+func (u pushAtUpdate) ToBsonUpdate() bson.M {
+	return bson.M{"$push": bson.M{u.field: bson.M{"$each": []any{u.value}, "$position": u.position}}}
+}
+
+func pushAt(field string, value any, position int) spec.Update {
+	return pushAtUpdate{field: field, value: value, position: position}
+}
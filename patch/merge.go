@@ -0,0 +1,65 @@
+package patch
+
+import (
+	"strings"
+
+	"github.com/dElCIoGio/mongox/spec"
+)
+
+// MergePatch translates an RFC 7386 JSON Merge Patch document into an update.
+//
+// Behavior:
+//   - null values remove the field ($unset)
+//   - Nested objects are merged field by field using dotted paths
+//   - Any other value, including arrays, replaces the field ($set)
+//   - An empty nested object leaves the field unchanged
+//   - Returns nil if the patch changes nothing
+//
+// Example:
+//
+//	MergePatch([]byte(`{"name": "Jane", "profile": {"bio": null, "age": 31}}`))
+//	// {"$set": {"name": "Jane", "profile.age": 31}, "$unset": {"profile.bio": ""}}
+func MergePatch(patch []byte, opts ...Option) (spec.Update, error) {
+	var doc map[string]any
+	if err := decodeJSON(patch, &doc); err != nil {
+		return nil, err
+	}
+	return MergePatchMap(doc, opts...)
+}
+
+// MergePatchMap is like MergePatch but takes an already decoded patch document.
+func MergePatchMap(patch map[string]any, opts ...Option) (spec.Update, error) {
+	c := applyOptions(opts)
+	var updates []spec.Update
+	if err := mergeInto(&updates, c, nil, patch); err != nil {
+		return nil, err
+	}
+	return spec.Combine(updates...), nil
+}
+
+func mergeInto(updates *[]spec.Update, c config, prefix []string, patch map[string]any) error {
+	for key, value := range patch {
+		if err := checkSegment(key); err != nil {
+			return err
+		}
+		segs := append(prefix[:len(prefix):len(prefix)], key)
+		path := strings.Join(segs, ".")
+
+		if nested, ok := value.(map[string]any); ok {
+			if err := mergeInto(updates, c, segs, nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := c.checkField(path); err != nil {
+			return err
+		}
+		if value == nil {
+			*updates = append(*updates, spec.Unset(path))
+		} else {
+			*updates = append(*updates, spec.Set(path, normalizeValue(value)))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Package patch translates HTTP PATCH documents into MongoDB updates.
+//
+// Two formats are supported:
+//   - JSON Merge Patch (RFC 7386) via MergePatch
+//   - JSON Patch (RFC 6902) via JSONPatch
+//
+// Both produce a spec.Update that can be passed straight to a repository.
+// Use WithAllowedFields to restrict which fields a client may modify.
+//
+// Example:
+//
+//	func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
+//	    body, _ := io.ReadAll(r.Body)
+//	    update, err := patch.MergePatch(body, patch.WithAllowedFields("name", "profile"))
+//	    if err != nil {
+//	        http.Error(w, err.Error(), http.StatusBadRequest)
+//	        return
+//	    }
+//	    _, _, err = h.repo.UpdateOneStrict(r.Context(), spec.Eq("_id", id), update)
+//	    ...
+//	}
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Errors returned when a patch cannot be translated.
+var (
+	// ErrInvalidPatch is returned when the patch document is malformed.
+	ErrInvalidPatch = errors.New("patch: invalid patch")
+
+	// ErrFieldNotAllowed is returned when the patch touches a field outside the allow-list,
+	// or the immutable _id field.
+	ErrFieldNotAllowed = errors.New("patch: field not allowed")
+
+	// ErrUnsupportedOp is returned for JSON Patch operations that have no MongoDB update equivalent.
+	ErrUnsupportedOp = errors.New("patch: unsupported operation")
+)
+
+// Option configures patch translation.
+type Option func(*config)
+
+type config struct {
+	allowed []string
+}
+
+// WithAllowedFields restricts the patch to the given fields (dotted paths).
+// A field also allows everything nested under it: allowing "profile" permits
+// "profile.bio", but allowing "profile.bio" does not permit replacing "profile".
+//
+// Without this option every field except _id may be modified.
+//
+// Example:
+//
+//	patch.WithAllowedFields("name", "email", "profile.bio")
+func WithAllowedFields(fields ...string) Option {
+	return func(c *config) { c.allowed = append(c.allowed, fields...) }
+}
+
+func applyOptions(opts []Option) config {
+	var c config
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&c)
+		}
+	}
+	return c
+}
+
+// checkField reports an error if the dotted path may not be modified.
+func (c config) checkField(path string) error {
+	if path == "_id" || strings.HasPrefix(path, "_id.") {
+		return fmt.Errorf("%w: %q", ErrFieldNotAllowed, path)
+	}
+	if c.allowed == nil {
+		return nil
+	}
+	for _, a := range c.allowed {
+		if path == a || strings.HasPrefix(path, a+".") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrFieldNotAllowed, path)
+}
+
+// checkSegment rejects keys that cannot be addressed with a dotted path or
+// would be interpreted as operators.
+func checkSegment(seg string) error {
+	if seg == "" || strings.HasPrefix(seg, "$") || strings.Contains(seg, ".") {
+		return fmt.Errorf("%w: invalid field name %q", ErrInvalidPatch, seg)
+	}
+	return nil
+}
+
+// pointerSegments splits an RFC 6901 JSON Pointer into unescaped segments.
+func pointerSegments(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") || pointer == "/" {
+		return nil, fmt.Errorf("%w: invalid path %q", ErrInvalidPatch, pointer)
+	}
+	segs := strings.Split(pointer[1:], "/")
+	for i, seg := range segs {
+		seg = strings.ReplaceAll(seg, "~1", "/")
+		seg = strings.ReplaceAll(seg, "~0", "~")
+		segs[i] = seg
+	}
+	return segs, nil
+}
+
+// isIndex reports whether a pointer segment addresses an array element.
+func isIndex(seg string) bool {
+	if seg == "" || (len(seg) > 1 && seg[0] == '0') {
+		return false
+	}
+	_, err := strconv.Atoi(seg)
+	return err == nil
+}
+
+// decodeJSON decodes data preserving integers as int64 rather than float64,
+// so numeric fields keep their BSON type.
+func decodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return nil
+}
+
+// normalizeValue converts json.Number values (recursively) to int64 or float64.
+func normalizeValue(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]any:
+		for k, item := range val {
+			val[k] = normalizeValue(item)
+		}
+		return val
+	case []any:
+		for i, item := range val {
+			val[i] = normalizeValue(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,144 @@
+package patch_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/patch"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMergePatch(t *testing.T) {
+	update, err := patch.MergePatch([]byte(`{"name": "Jane", "age": 31, "score": 1.5, "profile": {"bio": null, "tags": ["a"]}}`))
+	if err != nil {
+		t.Fatalf("MergePatch failed: %v", err)
+	}
+
+	got := update.ToBsonUpdate()
+	want := bson.M{
+		"$set": bson.M{
+			"name":         "Jane",
+			"age":          int64(31),
+			"score":        1.5,
+			"profile.tags": []any{"a"},
+		},
+		"$unset": bson.M{"profile.bio": ""},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("MergePatch mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestMergePatch_Empty(t *testing.T) {
+	update, err := patch.MergePatch([]byte(`{"profile": {}}`))
+	if err != nil {
+		t.Fatalf("MergePatch failed: %v", err)
+	}
+	if update != nil {
+		t.Fatalf("expected nil update, got %#v", update.ToBsonUpdate())
+	}
+}
+
+func TestMergePatch_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		opts  []patch.Option
+		want  error
+	}{
+		{"malformed", `{"name":`, nil, patch.ErrInvalidPatch},
+		{"operator key", `{"$where": "1"}`, nil, patch.ErrInvalidPatch},
+		{"dotted key", `{"a.b": 1}`, nil, patch.ErrInvalidPatch},
+		{"id", `{"_id": "x"}`, nil, patch.ErrFieldNotAllowed},
+		{"not allowed", `{"role": "admin"}`, []patch.Option{patch.WithAllowedFields("name")}, patch.ErrFieldNotAllowed},
+		{"parent of allowed", `{"profile": null}`, []patch.Option{patch.WithAllowedFields("profile.bio")}, patch.ErrFieldNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := patch.MergePatch([]byte(tt.patch), tt.opts...)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMergePatch_AllowedNested(t *testing.T) {
+	update, err := patch.MergePatch([]byte(`{"profile": {"bio": "hi"}}`), patch.WithAllowedFields("profile"))
+	if err != nil {
+		t.Fatalf("MergePatch failed: %v", err)
+	}
+	want := bson.M{"$set": bson.M{"profile.bio": "hi"}}
+	if got := update.ToBsonUpdate(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	res, err := patch.JSONPatch([]byte(`[
+		{"op": "test", "path": "/version", "value": 3},
+		{"op": "replace", "path": "/name", "value": "Jane"},
+		{"op": "add", "path": "/tags/-", "value": "new"},
+		{"op": "add", "path": "/history/0", "value": "first"},
+		{"op": "add", "path": "/a~1b", "value": true},
+		{"op": "remove", "path": "/legacy"},
+		{"op": "move", "from": "/old", "path": "/new"}
+	]`))
+	if err != nil {
+		t.Fatalf("JSONPatch failed: %v", err)
+	}
+
+	if _, err := patch.JSONPatch([]byte(`[{"op": "add", "path": "/a.b", "value": 1}]`)); !errors.Is(err, patch.ErrInvalidPatch) {
+		t.Fatalf("expected ErrInvalidPatch for dotted segment, got %v", err)
+	}
+
+	wantUpdate := bson.M{
+		"$set":    bson.M{"name": "Jane", "a/b": true},
+		"$push":   bson.M{"tags": "new", "history": bson.M{"$each": []any{"first"}, "$position": 0}},
+		"$unset":  bson.M{"legacy": ""},
+		"$rename": bson.M{"old": "new"},
+	}
+	if got := res.Update.ToBsonUpdate(); !reflect.DeepEqual(got, wantUpdate) {
+		t.Fatalf("update mismatch.\n got: %#v\nwant: %#v", got, wantUpdate)
+	}
+
+	wantFilter := bson.M{"$and": []bson.M{
+		{"version": int64(3)},
+		{"name": bson.M{"$exists": true}},
+	}}
+	if got := res.Filter.ToMongo(); !reflect.DeepEqual(got, wantFilter) {
+		t.Fatalf("filter mismatch.\n got: %#v\nwant: %#v", got, wantFilter)
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		opts  []patch.Option
+		want  error
+	}{
+		{"malformed", `[{"op":`, nil, patch.ErrInvalidPatch},
+		{"unknown op", `[{"op": "frob", "path": "/a"}]`, nil, patch.ErrInvalidPatch},
+		{"bad pointer", `[{"op": "remove", "path": "a"}]`, nil, patch.ErrInvalidPatch},
+		{"copy", `[{"op": "copy", "from": "/a", "path": "/b"}]`, nil, patch.ErrUnsupportedOp},
+		{"remove index", `[{"op": "remove", "path": "/tags/0"}]`, nil, patch.ErrUnsupportedOp},
+		{"move index", `[{"op": "move", "from": "/tags/0", "path": "/first"}]`, nil, patch.ErrUnsupportedOp},
+		{"not allowed", `[{"op": "add", "path": "/role", "value": "admin"}]`, []patch.Option{patch.WithAllowedFields("name")}, patch.ErrFieldNotAllowed},
+		{"move from not allowed", `[{"op": "move", "from": "/secret", "path": "/name"}]`, []patch.Option{patch.WithAllowedFields("name")}, patch.ErrFieldNotAllowed},
+		{"id", `[{"op": "replace", "path": "/_id", "value": 1}]`, nil, patch.ErrFieldNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := patch.JSONPatch([]byte(tt.patch), tt.opts...)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
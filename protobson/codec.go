@@ -0,0 +1,259 @@
+// Package protobson stores protobuf-generated messages in MongoDB and maps
+// them to document types.
+//
+// It does not import the protobuf runtime. Messages are recognized by the
+// ProtoMessage method that protoc-gen-go generates, and timestamps by the
+// shape of google.protobuf.Timestamp: a pointer to a struct with Seconds
+// int64 and Nanos int32 fields and an AsTime method.
+//
+// Two approaches are supported:
+//   - Store messages directly with the registry from NewRegistry
+//   - Keep document types for storage and convert with a Mapper
+//
+// Example:
+//
+//	coll := db.Collection("users", options.Collection().SetRegistry(protobson.NewRegistry()))
+//	_, err := coll.InsertOne(ctx, &pb.User{Id: primitive.NewObjectID().Hex(), DisplayName: "Ada"})
+//
+//	var u pb.User
+//	err = coll.FindOne(ctx, bson.M{"display_name": "Ada"}).Decode(&u)
+package protobson
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// message is implemented by every protoc-gen-go message.
+type message interface {
+	ProtoMessage()
+}
+
+var (
+	messageType = reflect.TypeOf((*message)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+// NewRegistry returns the default BSON registry with the codecs of Register.
+func NewRegistry() *bsoncodec.Registry {
+	reg := bson.NewRegistry()
+	Register(reg)
+	return reg
+}
+
+// Register adds codecs for protobuf messages to reg:
+//   - Fields are named by the proto field name from the protobuf tag, e.g.
+//     "display_name"; a bson tag takes precedence
+//   - The "id" field is stored as _id, as an ObjectID when it holds a valid
+//     hex ObjectID, and is omitted when empty so the server generates one
+//   - google.protobuf.Timestamp fields are stored as BSON dates, with
+//     millisecond precision
+//
+// Oneof fields are not stored.
+func Register(reg *bsoncodec.Registry) {
+	sc, err := bsoncodec.NewStructCodec(bsoncodec.StructTagParserFunc(parseTags))
+	if err != nil {
+		panic(err) // only fails for a nil parser
+	}
+	c := &messageCodec{structs: sc}
+	reg.RegisterInterfaceEncoder(messageType, c)
+	reg.RegisterInterfaceDecoder(messageType, c)
+}
+
+// parseTags names a message field after its proto field name.
+func parseTags(sf reflect.StructField) (bsoncodec.StructTags, error) {
+	if _, ok := sf.Tag.Lookup("bson"); ok {
+		return bsoncodec.DefaultStructTagParser(sf)
+	}
+	if _, ok := sf.Tag.Lookup("protobuf_oneof"); ok {
+		return bsoncodec.StructTags{Skip: true}, nil
+	}
+	tags, err := bsoncodec.DefaultStructTagParser(sf)
+	if err != nil {
+		return tags, err
+	}
+	if name := protoName(sf); name != "" {
+		tags.Name = name
+	}
+	if tags.Name == "id" {
+		tags.Name = "_id"
+		tags.OmitEmpty = true
+	}
+	return tags, nil
+}
+
+// protoName returns the name= part of a field's protobuf tag.
+func protoName(sf reflect.StructField) string {
+	for _, part := range strings.Split(sf.Tag.Get("protobuf"), ",") {
+		if name, ok := strings.CutPrefix(part, "name="); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// isTimestamp reports whether t has the shape of *timestamppb.Timestamp.
+func isTimestamp(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	s, ok := t.Elem().FieldByName("Seconds")
+	if !ok || s.Type.Kind() != reflect.Int64 {
+		return false
+	}
+	n, ok := t.Elem().FieldByName("Nanos")
+	if !ok || n.Type.Kind() != reflect.Int32 {
+		return false
+	}
+	m, ok := t.MethodByName("AsTime")
+	return ok && m.Type.NumIn() == 1 && m.Type.NumOut() == 1 && m.Type.Out(0) == timeType
+}
+
+// timestampTime returns the time of a non-nil timestamp.
+func timestampTime(v reflect.Value) time.Time {
+	return v.MethodByName("AsTime").Call(nil)[0].Interface().(time.Time)
+}
+
+// newTimestamp returns a timestamp of type t (a pointer type) set to tm.
+func newTimestamp(t reflect.Type, tm time.Time) reflect.Value {
+	v := reflect.New(t.Elem())
+	v.Elem().FieldByName("Seconds").SetInt(tm.Unix())
+	v.Elem().FieldByName("Nanos").SetInt(int64(tm.Nanosecond()))
+	return v
+}
+
+// messageCodec encodes and decodes protobuf messages.
+type messageCodec struct {
+	structs *bsoncodec.StructCodec
+}
+
+func (c *messageCodec) EncodeValue(ec bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if val.Kind() == reflect.Struct && val.CanAddr() {
+		val = val.Addr()
+	}
+	if val.Kind() != reflect.Pointer {
+		return bsoncodec.ValueEncoderError{Name: "protobson.EncodeValue", Kinds: []reflect.Kind{reflect.Pointer}, Received: val}
+	}
+	if val.IsNil() {
+		return vw.WriteNull()
+	}
+	if isTimestamp(val.Type()) {
+		return vw.WriteDateTime(timestampTime(val).UnixMilli())
+	}
+
+	var buf bytes.Buffer
+	w, err := bsonrw.NewBSONValueWriter(&buf)
+	if err != nil {
+		return err
+	}
+	if err := c.structs.EncodeValue(ec, w, val.Elem()); err != nil {
+		return err
+	}
+	doc, err := mapID(buf.Bytes(), func(v bson.RawValue) (bsoncore.Value, bool) {
+		s, ok := v.StringValueOK()
+		if !ok {
+			return bsoncore.Value{}, false
+		}
+		oid, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return bsoncore.Value{}, false
+		}
+		return bsoncore.Value{Type: bsontype.ObjectID, Data: oid[:]}, true
+	})
+	if err != nil {
+		return err
+	}
+	return bsonrw.Copier{}.CopyDocumentFromBytes(vw, doc)
+}
+
+func (c *messageCodec) DecodeValue(dc bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if val.Kind() == reflect.Struct && val.CanAddr() {
+		// A message value, e.g. the target of Unmarshal, decoded in place.
+		raw, err := bsonrw.Copier{}.CopyDocumentToBytes(vr)
+		if err != nil {
+			return err
+		}
+		return c.decodeMessage(dc, raw, val)
+	}
+	if !val.CanSet() || val.Kind() != reflect.Pointer {
+		return bsoncodec.ValueDecoderError{Name: "protobson.DecodeValue", Kinds: []reflect.Kind{reflect.Pointer}, Received: val}
+	}
+	switch vr.Type() {
+	case bsontype.Null:
+		val.Set(reflect.Zero(val.Type()))
+		return vr.ReadNull()
+	case bsontype.Undefined:
+		val.Set(reflect.Zero(val.Type()))
+		return vr.ReadUndefined()
+	}
+	if isTimestamp(val.Type()) {
+		if vr.Type() != bsontype.DateTime {
+			return fmt.Errorf("protobson: cannot decode %v into %s", vr.Type(), val.Type())
+		}
+		ms, err := vr.ReadDateTime()
+		if err != nil {
+			return err
+		}
+		val.Set(newTimestamp(val.Type(), time.UnixMilli(ms).UTC()))
+		return nil
+	}
+
+	raw, err := bsonrw.Copier{}.CopyDocumentToBytes(vr)
+	if err != nil {
+		return err
+	}
+	if val.IsNil() {
+		val.Set(reflect.New(val.Type().Elem()))
+	}
+	return c.decodeMessage(dc, raw, val.Elem())
+}
+
+// decodeMessage decodes the document raw into the message struct val.
+func (c *messageCodec) decodeMessage(dc bsoncodec.DecodeContext, raw []byte, val reflect.Value) error {
+	doc, err := mapID(raw, func(v bson.RawValue) (bsoncore.Value, bool) {
+		oid, ok := v.ObjectIDOK()
+		if !ok {
+			return bsoncore.Value{}, false
+		}
+		return bsoncore.Value{Type: bsontype.String, Data: bsoncore.AppendString(nil, oid.Hex())}, true
+	})
+	if err != nil {
+		return err
+	}
+	return c.structs.DecodeValue(dc, bsonrw.NewBSONDocumentReader(doc), val)
+}
+
+// mapID returns doc with its _id replaced by fn's result when fn reports one.
+func mapID(doc []byte, fn func(bson.RawValue) (bsoncore.Value, bool)) ([]byte, error) {
+	id, err := bson.Raw(doc).LookupErr("_id")
+	if err != nil {
+		return doc, nil
+	}
+	repl, ok := fn(id)
+	if !ok {
+		return doc, nil
+	}
+	elems, err := bson.Raw(doc).Elements()
+	if err != nil {
+		return nil, err
+	}
+	idx, out := bsoncore.AppendDocumentStart(nil)
+	for _, e := range elems {
+		if e.Key() == "_id" {
+			out = bsoncore.AppendValueElement(out, "_id", repl)
+			continue
+		}
+		out = append(out, e...)
+	}
+	return bsoncore.AppendDocumentEnd(out, idx)
+}
@@ -0,0 +1,86 @@
+package protobson_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/protobson"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRegistry_EncodesMessages(t *testing.T) {
+	reg := protobson.NewRegistry()
+	oid := primitive.NewObjectID()
+	created := time.Date(2026, 1, 2, 15, 4, 5, 123e6, time.UTC)
+	in := &pbUser{
+		Id:          oid.Hex(),
+		DisplayName: "Ada",
+		Age:         36,
+		CreatedAt:   &timestamp{Seconds: created.Unix(), Nanos: int32(created.Nanosecond())},
+		ManagerId:   "m-1",
+		Address:     &pbAddress{City: "London"},
+	}
+
+	data, err := bson.MarshalWithRegistry(reg, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := bson.Raw(data)
+	if got, ok := raw.Lookup("_id").ObjectIDOK(); !ok || got != oid {
+		t.Fatalf("expected _id stored as ObjectID %s, got %v", oid.Hex(), raw.Lookup("_id"))
+	}
+	if got, ok := raw.Lookup("created_at").TimeOK(); !ok || !got.Equal(created) {
+		t.Fatalf("expected created_at stored as a date, got %v", raw.Lookup("created_at"))
+	}
+	if got := raw.Lookup("display_name").StringValue(); got != "Ada" {
+		t.Fatalf("expected display_name, got %q", got)
+	}
+	if got := raw.Lookup("address", "city").StringValue(); got != "London" {
+		t.Fatalf("expected nested address.city, got %q", got)
+	}
+	if _, err := raw.LookupErr("contact"); err == nil {
+		t.Fatal("expected oneof field to be skipped")
+	}
+
+	var out pbUser
+	if err := bson.UnmarshalWithRegistry(reg, data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&out, in) {
+		t.Fatalf("round trip mismatch.\n got: %+v\nwant: %+v", out, *in)
+	}
+}
+
+func TestRegistry_EmptyAndNonObjectIDs(t *testing.T) {
+	reg := protobson.NewRegistry()
+
+	data, err := bson.MarshalWithRegistry(reg, &pbUser{DisplayName: "Ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := bson.Raw(data)
+	if _, err := raw.LookupErr("_id"); err == nil {
+		t.Fatal("expected empty id to be omitted")
+	}
+	if v := raw.Lookup("created_at"); v.Type != bson.TypeNull {
+		t.Fatalf("expected nil timestamp stored as null, got %v", v)
+	}
+
+	data, err = bson.MarshalWithRegistry(reg, &pbUser{Id: "user-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bson.Raw(data).Lookup("_id").StringValue(); got != "user-1" {
+		t.Fatalf("expected non-hex id kept as a string, got %q", got)
+	}
+	var out pbUser
+	if err := bson.UnmarshalWithRegistry(reg, data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Id != "user-1" || out.CreatedAt != nil {
+		t.Fatalf("unexpected decode: %+v", out)
+	}
+}
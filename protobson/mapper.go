@@ -0,0 +1,287 @@
+package protobson
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Mapper converts between a protobuf message type P and a document type D,
+// so APIs can speak protobuf while storage keeps its own types. Build one per
+// pair of types with NewMapper and reuse it; it is safe for concurrent use.
+//
+// Fields are matched by name, ignoring case and underscores, so Id matches ID
+// and UserId matches UserID; fields of embedded structs such as document.Base
+// are matched as if they were declared directly. Matched fields convert:
+//   - between identical or assignable types
+//   - between numeric types, e.g. int32 and int
+//   - between hex strings and primitive.ObjectID
+//   - between google.protobuf.Timestamp and time.Time or *time.Time
+//   - between nested messages and structs, and slices of any of these
+//
+// Fields without a counterpart, or whose types do not convert, are left zero.
+//
+// Example:
+//
+//	var users = protobson.NewMapper[pb.User, User]()
+//
+//	func (s *Server) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+//	    u, err := s.repo.FindByID(ctx, req.Id)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return users.ToProto(u)
+//	}
+type Mapper[P, D any] struct {
+	toDoc   *structPlan
+	toProto *structPlan
+}
+
+// NewMapper returns a Mapper between P and D, which must be struct types.
+// It panics otherwise.
+func NewMapper[P, D any]() *Mapper[P, D] {
+	p, d := reflect.TypeFor[P](), reflect.TypeFor[D]()
+	if p.Kind() != reflect.Struct || d.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("protobson: NewMapper: %s and %s must be struct types", p, d))
+	}
+	b := planner{}
+	return &Mapper[P, D]{toDoc: b.structPlan(p, d), toProto: b.structPlan(d, p)}
+}
+
+// ToDocument converts a message to a document. A nil message gives nil.
+func (m *Mapper[P, D]) ToDocument(p *P) (*D, error) {
+	if p == nil {
+		return nil, nil
+	}
+	var d D
+	if err := m.toDoc.copy(reflect.ValueOf(p).Elem(), reflect.ValueOf(&d).Elem()); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ToProto converts a document to a message. A nil document gives nil.
+func (m *Mapper[P, D]) ToProto(d *D) (*P, error) {
+	if d == nil {
+		return nil, nil
+	}
+	p := new(P)
+	if err := m.toProto.copy(reflect.ValueOf(d).Elem(), reflect.ValueOf(p).Elem()); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ToProtos converts documents to messages, e.g. for a list response.
+func (m *Mapper[P, D]) ToProtos(docs []D) ([]*P, error) {
+	out := make([]*P, len(docs))
+	for i := range docs {
+		p, err := m.ToProto(&docs[i])
+		if err != nil {
+			return nil, err
+		}
+		out[i] = p
+	}
+	return out, nil
+}
+
+// convertFunc converts src into the settable dst.
+type convertFunc func(src, dst reflect.Value) error
+
+// fieldPlan copies one field.
+type fieldPlan struct {
+	name     string
+	src, dst []int
+	convert  convertFunc
+}
+
+// structPlan copies the matching fields of one struct type into another.
+type structPlan struct {
+	fields []fieldPlan
+}
+
+func (sp *structPlan) copy(src, dst reflect.Value) error {
+	for _, f := range sp.fields {
+		if err := f.convert(src.FieldByIndex(f.src), dst.FieldByIndex(f.dst)); err != nil {
+			return fmt.Errorf("protobson: field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// planner builds conversion plans, sharing the plan of each pair of struct
+// types so recursive messages terminate.
+type planner map[[2]reflect.Type]*structPlan
+
+func (b planner) structPlan(src, dst reflect.Type) *structPlan {
+	key := [2]reflect.Type{src, dst}
+	if sp, ok := b[key]; ok {
+		return sp
+	}
+	sp := &structPlan{}
+	b[key] = sp
+
+	dstFields := fieldsOf(dst)
+	for name, si := range fieldsOf(src) {
+		di, ok := dstFields[name]
+		if !ok {
+			continue
+		}
+		st, dt := src.FieldByIndex(si).Type, dst.FieldByIndex(di).Type
+		if conv := b.converter(st, dt); conv != nil {
+			sp.fields = append(sp.fields, fieldPlan{name: dst.FieldByIndex(di).Name, src: si, dst: di, convert: conv})
+		}
+	}
+	return sp
+}
+
+var objectIDType = reflect.TypeOf(primitive.ObjectID{})
+
+// converter returns how to convert a value of type src to type dst, or nil.
+func (b planner) converter(src, dst reflect.Type) convertFunc {
+	switch {
+	case src.AssignableTo(dst):
+		return func(s, d reflect.Value) error { d.Set(s); return nil }
+
+	case src.Kind() == reflect.String && dst == objectIDType:
+		return func(s, d reflect.Value) error {
+			if s.Len() == 0 {
+				return nil
+			}
+			oid, err := primitive.ObjectIDFromHex(s.String())
+			if err != nil {
+				return err
+			}
+			d.Set(reflect.ValueOf(oid))
+			return nil
+		}
+	case src == objectIDType && dst.Kind() == reflect.String:
+		return func(s, d reflect.Value) error {
+			if oid := s.Interface().(primitive.ObjectID); !oid.IsZero() {
+				d.SetString(oid.Hex())
+			}
+			return nil
+		}
+
+	case isTimestamp(src) && (dst == timeType || dst == reflect.PointerTo(timeType)):
+		return func(s, d reflect.Value) error {
+			if s.IsNil() {
+				return nil
+			}
+			t := timestampTime(s)
+			if dst == timeType {
+				d.Set(reflect.ValueOf(t))
+			} else {
+				d.Set(reflect.ValueOf(&t))
+			}
+			return nil
+		}
+	case (src == timeType || src == reflect.PointerTo(timeType)) && isTimestamp(dst):
+		return func(s, d reflect.Value) error {
+			if src != timeType {
+				if s.IsNil() {
+					return nil
+				}
+				s = s.Elem()
+			}
+			if t := s.Interface().(time.Time); !t.IsZero() {
+				d.Set(newTimestamp(dst, t))
+			}
+			return nil
+		}
+
+	case isNumeric(src.Kind()) && isNumeric(dst.Kind()):
+		return func(s, d reflect.Value) error { d.Set(s.Convert(dst)); return nil }
+
+	case structType(src) != nil && structType(dst) != nil:
+		sp := b.structPlan(structType(src), structType(dst))
+		return func(s, d reflect.Value) error {
+			if s.Kind() == reflect.Pointer {
+				if s.IsNil() {
+					return nil
+				}
+				s = s.Elem()
+			}
+			if d.Kind() == reflect.Pointer {
+				d.Set(reflect.New(dst.Elem()))
+				d = d.Elem()
+			}
+			return sp.copy(s, d)
+		}
+
+	case src.Kind() == reflect.Slice && dst.Kind() == reflect.Slice:
+		elem := b.converter(src.Elem(), dst.Elem())
+		if elem == nil {
+			return nil
+		}
+		return func(s, d reflect.Value) error {
+			if s.IsNil() {
+				return nil
+			}
+			out := reflect.MakeSlice(dst, s.Len(), s.Len())
+			for i := range s.Len() {
+				if err := elem(s.Index(i), out.Index(i)); err != nil {
+					return fmt.Errorf("index %d: %w", i, err)
+				}
+			}
+			d.Set(out)
+			return nil
+		}
+	}
+	return nil
+}
+
+// structType returns t, or the type t points to, if that is a struct type
+// other than time.Time, and nil otherwise.
+func structType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil
+	}
+	return t
+}
+
+func isNumeric(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// fieldsOf returns the index paths of the exported fields of t, including
+// those of embedded structs (not struct pointers), keyed by their normalized
+// names. Fields declared directly win over embedded ones.
+func fieldsOf(t reflect.Type) map[string][]int {
+	out := map[string][]int{}
+	var walk func(t reflect.Type, prefix []int, depth int)
+	depths := map[string]int{}
+	walk = func(t reflect.Type, prefix []int, depth int) {
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			index := append(append([]int(nil), prefix...), i)
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				walk(sf.Type, index, depth+1)
+				continue
+			}
+			if !sf.IsExported() {
+				continue
+			}
+			key := strings.ToLower(strings.ReplaceAll(sf.Name, "_", ""))
+			if d, ok := depths[key]; ok && d <= depth {
+				continue
+			}
+			depths[key] = depth
+			out[key] = index
+		}
+	}
+	walk(t, nil, 0)
+	return out
+}
@@ -0,0 +1,73 @@
+package protobson_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/protobson"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMapper_RoundTrip(t *testing.T) {
+	m := protobson.NewMapper[pbUser, user]()
+	oid := primitive.NewObjectID()
+	created := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	in := &pbUser{
+		Id:          oid.Hex(),
+		DisplayName: "Ada",
+		Age:         36,
+		CreatedAt:   &timestamp{Seconds: created.Unix()},
+		ManagerId:   "m-1",
+		Address:     &pbAddress{City: "London"},
+		Previous:    []*pbAddress{{City: "Paris"}},
+	}
+
+	d, err := m.ToDocument(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != oid || !d.CreatedAt.Equal(created) || d.DisplayName != "Ada" || d.Age != 36 ||
+		d.ManagerID != "m-1" || d.Address.City != "London" || len(d.Previous) != 1 || d.Previous[0].City != "Paris" {
+		t.Fatalf("unexpected document: %+v", d)
+	}
+	if !d.UpdatedAt.IsZero() {
+		t.Fatalf("expected unmatched field left zero, got %v", d.UpdatedAt)
+	}
+
+	p, err := m.ToProto(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Id != oid.Hex() || p.CreatedAt.AsTime() != created || p.DisplayName != "Ada" || p.Age != 36 ||
+		p.Address.City != "London" || p.Previous[0].City != "Paris" {
+		t.Fatalf("unexpected message: %+v", p)
+	}
+
+	list, err := m.ToProtos([]user{*d, {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[1].Id != "" || list[1].CreatedAt != nil {
+		t.Fatalf("expected zero IDs and times left unset, got %+v", list[1])
+	}
+}
+
+func TestMapper_InvalidObjectID(t *testing.T) {
+	m := protobson.NewMapper[pbUser, user]()
+	if _, err := m.ToDocument(&pbUser{Id: "nope"}); err == nil {
+		t.Fatal("expected an error for an invalid id")
+	}
+	if d, err := m.ToDocument(nil); d != nil || err != nil {
+		t.Fatalf("expected nil for a nil message, got %v, %v", d, err)
+	}
+}
+
+func TestNewMapper_PanicsOnNonStruct(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	protobson.NewMapper[pbUser, string]()
+}
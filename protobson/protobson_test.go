@@ -0,0 +1,63 @@
+package protobson_test
+
+import (
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+)
+
+// timestamp has the shape of the generated google.protobuf.Timestamp.
+type timestamp struct {
+	state         struct{}
+	sizeCache     int32
+	unknownFields []byte
+
+	Seconds int64 `protobuf:"varint,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	Nanos   int32 `protobuf:"varint,2,opt,name=nanos,proto3" json:"nanos,omitempty"`
+}
+
+func (*timestamp) ProtoMessage() {}
+
+func (x *timestamp) AsTime() time.Time {
+	return time.Unix(x.Seconds, int64(x.Nanos)).UTC()
+}
+
+// pbAddress and pbUser have the shape of protoc-gen-go output.
+type pbAddress struct {
+	state struct{}
+
+	City string `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+}
+
+func (*pbAddress) ProtoMessage() {}
+
+type pbUser struct {
+	state     struct{}
+	sizeCache int32
+
+	Id          string       `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	DisplayName string       `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Age         int32        `protobuf:"varint,3,opt,name=age,proto3" json:"age,omitempty"`
+	CreatedAt   *timestamp   `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ManagerId   string       `protobuf:"bytes,5,opt,name=manager_id,json=managerId,proto3" json:"manager_id,omitempty"`
+	Address     *pbAddress   `protobuf:"bytes,6,opt,name=address,proto3" json:"address,omitempty"`
+	Previous    []*pbAddress `protobuf:"bytes,7,rep,name=previous,proto3" json:"previous,omitempty"`
+	Contact     isContact    `protobuf_oneof:"contact"`
+}
+
+func (*pbUser) ProtoMessage() {}
+
+type isContact interface{ isContact() }
+
+type address struct {
+	City string `bson:"city"`
+}
+
+type user struct {
+	document.Base `bson:",inline"`
+	DisplayName   string     `bson:"display_name"`
+	Age           int        `bson:"age"`
+	ManagerID     string     `bson:"manager_id"`
+	Address       address    `bson:"address"`
+	Previous      []*address `bson:"previous"`
+}
@@ -0,0 +1,446 @@
+// Package quota enforces document-count and storage limits per collection or
+// per tenant.
+//
+// An Enforcer holds a Policy for each registered collection and a cached usage
+// snapshot for each collection or tenant. Wrap decorates a repository so that
+// InsertOne and InsertMany check the snapshot, plus the inserts made since it
+// was taken, and fail with an error matching ErrQuotaExceeded instead of
+// writing past the limit. Run refreshes the snapshots periodically, so checks
+// cost no round trip.
+//
+// Limits are enforced per process and snapshots lag deletes until the next
+// refresh, so treat them as soft limits suited to plan quotas, not as hard
+// storage caps.
+//
+// Example:
+//
+//	q := quota.New()
+//	q.Register("notes", db.Collection("notes"), quota.Policy{MaxDocuments: 1000},
+//	    quota.PerTenant("tenant_id"),
+//	    quota.WithTenantPolicy("acme", quota.Policy{MaxDocuments: 100000}),
+//	)
+//	go q.Run(ctx)
+//
+//	notes := quota.Wrap[Note](mongorepo.New[Note](db.Collection("notes")), q, "notes")
+//	err := notes.InsertOne(multitenancy.WithTenant(ctx, "globex"), note)
+//	if errors.Is(err, quota.ErrQuotaExceeded) {
+//	    // ask the tenant to upgrade
+//	}
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dElCIoGio/mongox/multitenancy"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrQuotaExceeded is returned when an insert would exceed a quota.
+	ErrQuotaExceeded = errors.New("quota: quota exceeded")
+
+	// ErrUnknownCollection is returned for names that were not registered.
+	ErrUnknownCollection = errors.New("quota: unknown collection")
+
+	// ErrNoTenant is returned for inserts into a per-tenant collection without
+	// a tenant in the context.
+	ErrNoTenant = errors.New("quota: no tenant in context")
+)
+
+// Policy limits the size of a collection or of one tenant's share of it.
+// Zero fields are unlimited.
+type Policy struct {
+	// MaxDocuments is the maximum number of documents.
+	MaxDocuments int64
+
+	// MaxBytes is the maximum total BSON size of the documents.
+	MaxBytes int64
+}
+
+// Usage is the size of a collection or of one tenant's share of it.
+type Usage struct {
+	Documents   int64
+	Bytes       int64
+	RefreshedAt time.Time
+}
+
+// ExceededError describes the limit an insert would exceed. It matches
+// ErrQuotaExceeded with errors.Is.
+type ExceededError struct {
+	Collection string
+	Tenant     string // empty for collection-wide quotas
+	Limit      string // "documents" or "bytes"
+	Max        int64
+	Requested  int64 // usage after the insert
+}
+
+func (e *ExceededError) Error() string {
+	scope := e.Collection
+	if e.Tenant != "" {
+		scope += " for tenant " + e.Tenant
+	}
+	return fmt.Sprintf("quota: %s quota of %s exceeded: %d > %d", e.Limit, scope, e.Requested, e.Max)
+}
+
+func (e *ExceededError) Is(target error) bool { return target == ErrQuotaExceeded }
+
+// Option configures an Enforcer.
+type Option func(*config)
+
+type config struct {
+	interval time.Duration
+	tenant   func(context.Context) (string, bool)
+	onError  func(err error)
+}
+
+// WithRefreshInterval sets how often Run refreshes usage snapshots. Defaults to one minute.
+func WithRefreshInterval(d time.Duration) Option {
+	return func(c *config) { c.interval = d }
+}
+
+// WithTenantFunc sets how the tenant is read from an insert's context.
+// Defaults to multitenancy.TenantFromContext.
+func WithTenantFunc(fn func(context.Context) (string, bool)) Option {
+	return func(c *config) { c.tenant = fn }
+}
+
+// WithErrorHandler registers a callback for refresh errors in Run.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) { c.onError = fn }
+}
+
+// CollectionOption configures a registered collection.
+type CollectionOption func(*target)
+
+// PerTenant applies the policy to each tenant's documents separately. Tenants
+// are identified by field, which must hold the tenant ID in every document.
+func PerTenant(field string) CollectionOption {
+	return func(t *target) { t.tenantField = field }
+}
+
+// WithTenantPolicy overrides the policy of one tenant, e.g. for a larger plan.
+func WithTenantPolicy(tenant string, p Policy) CollectionOption {
+	return func(t *target) { t.overrides[tenant] = p }
+}
+
+type target struct {
+	name        string
+	coll        *mongo.Collection
+	policy      Policy
+	tenantField string
+	overrides   map[string]Policy
+}
+
+func (t *target) policyFor(tenant string) Policy {
+	if p, ok := t.overrides[tenant]; ok {
+		return p
+	}
+	return t.policy
+}
+
+type usageKey struct{ name, tenant string }
+
+type usageEntry struct {
+	snapshot Usage
+	loaded   bool
+	// pending counts inserts admitted since the snapshot was taken.
+	pendingDocs, pendingBytes int64
+}
+
+// Enforcer checks inserts against quotas using cached usage snapshots.
+// It is safe for concurrent use.
+type Enforcer struct {
+	cfg config
+	now func() time.Time
+
+	mu      sync.Mutex
+	targets map[string]*target
+	usage   map[usageKey]*usageEntry
+}
+
+// New creates an Enforcer with no registered collections.
+func New(opts ...Option) *Enforcer {
+	cfg := config{interval: time.Minute, tenant: multitenancy.TenantFromContext}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = time.Minute
+	}
+	return &Enforcer{
+		cfg:     cfg,
+		now:     func() time.Time { return time.Now().UTC() },
+		targets: make(map[string]*target),
+		usage:   make(map[usageKey]*usageEntry),
+	}
+}
+
+// Register sets the policy of a collection under name. Registering a name
+// twice replaces the policy and discards its cached usage.
+func (e *Enforcer) Register(name string, coll *mongo.Collection, p Policy, opts ...CollectionOption) {
+	t := &target{name: name, coll: coll, policy: p, overrides: make(map[string]Policy)}
+	for _, o := range opts {
+		if o != nil {
+			o(t)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.targets[name] = t
+	for k := range e.usage {
+		if k.name == name {
+			delete(e.usage, k)
+		}
+	}
+}
+
+// Reserve admits an insert of docs documents totalling bytes into the named
+// collection, or returns an *ExceededError. Usage not loaded yet is computed
+// first. Admitted inserts count against the quota until the next refresh;
+// call Release if the insert then fails.
+func (e *Enforcer) Reserve(ctx context.Context, name string, docs, bytes int64) error {
+	t, tenant, err := e.resolve(ctx, name)
+	if err != nil {
+		return err
+	}
+	key := usageKey{name, tenant}
+	if err := e.ensureLoaded(ctx, t, key); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	u := e.entry(key)
+	p := t.policyFor(tenant)
+	if n := u.snapshot.Documents + u.pendingDocs + docs; p.MaxDocuments > 0 && n > p.MaxDocuments {
+		return &ExceededError{Collection: name, Tenant: tenant, Limit: "documents", Max: p.MaxDocuments, Requested: n}
+	}
+	if n := u.snapshot.Bytes + u.pendingBytes + bytes; p.MaxBytes > 0 && n > p.MaxBytes {
+		return &ExceededError{Collection: name, Tenant: tenant, Limit: "bytes", Max: p.MaxBytes, Requested: n}
+	}
+	u.pendingDocs += docs
+	u.pendingBytes += bytes
+	return nil
+}
+
+// Release returns a reservation made by Reserve for an insert that failed.
+func (e *Enforcer) Release(ctx context.Context, name string, docs, bytes int64) {
+	_, tenant, err := e.resolve(ctx, name)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	u := e.entry(usageKey{name, tenant})
+	u.pendingDocs = max(0, u.pendingDocs-docs)
+	u.pendingBytes = max(0, u.pendingBytes-bytes)
+}
+
+// Usage returns the cached usage of a collection, or of one tenant's share of
+// a per-tenant collection, including admitted inserts since the last refresh.
+// It reports false when no snapshot has been loaded yet.
+func (e *Enforcer) Usage(name, tenant string) (Usage, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	u, ok := e.usage[usageKey{name, tenant}]
+	if !ok || !u.loaded {
+		return Usage{}, false
+	}
+	out := u.snapshot
+	out.Documents += u.pendingDocs
+	out.Bytes += u.pendingBytes
+	return out, true
+}
+
+func (e *Enforcer) resolve(ctx context.Context, name string) (*target, string, error) {
+	e.mu.Lock()
+	t, ok := e.targets[name]
+	e.mu.Unlock()
+	if !ok {
+		return nil, "", fmt.Errorf("%w: %q", ErrUnknownCollection, name)
+	}
+	if t.tenantField == "" {
+		return t, "", nil
+	}
+	tenant, ok := e.cfg.tenant(ctx)
+	if !ok {
+		return nil, "", ErrNoTenant
+	}
+	return t, tenant, nil
+}
+
+// entry returns the usage entry for key, creating it. The caller holds e.mu.
+func (e *Enforcer) entry(key usageKey) *usageEntry {
+	u, ok := e.usage[key]
+	if !ok {
+		u = &usageEntry{}
+		e.usage[key] = u
+	}
+	return u
+}
+
+func (e *Enforcer) ensureLoaded(ctx context.Context, t *target, key usageKey) error {
+	e.mu.Lock()
+	loaded := e.entry(key).loaded
+	e.mu.Unlock()
+	if loaded {
+		return nil
+	}
+
+	var snap Usage
+	var err error
+	if t.tenantField == "" {
+		snap, err = collectionUsage(ctx, t.coll)
+	} else {
+		var all map[string]Usage
+		all, err = tenantUsage(ctx, t.coll, t.tenantField, bson.M{t.tenantField: key.tenant})
+		snap = all[key.tenant]
+	}
+	if err != nil {
+		return fmt.Errorf("quota: load usage of %s: %w", t.name, err)
+	}
+	snap.RefreshedAt = e.now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if u := e.entry(key); !u.loaded {
+		u.snapshot, u.loaded = snap, true
+	}
+	return nil
+}
+
+// Run refreshes usage snapshots every refresh interval until ctx is cancelled,
+// then returns ctx.Err().
+func (e *Enforcer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := e.Refresh(ctx); err != nil && ctx.Err() == nil && e.cfg.onError != nil {
+				e.cfg.onError(err)
+			}
+		}
+	}
+}
+
+// Refresh recomputes the usage of every registered collection and of every
+// tenant found in per-tenant collections, replacing the cached snapshots and
+// the counts of admitted inserts.
+func (e *Enforcer) Refresh(ctx context.Context) error {
+	e.mu.Lock()
+	targets := make([]*target, 0, len(e.targets))
+	for _, t := range e.targets {
+		targets = append(targets, t)
+	}
+	e.mu.Unlock()
+
+	var errs []error
+	for _, t := range targets {
+		fresh := map[string]Usage{}
+		var err error
+		if t.tenantField == "" {
+			fresh[""], err = collectionUsage(ctx, t.coll)
+		} else {
+			fresh, err = tenantUsage(ctx, t.coll, t.tenantField, nil)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("quota: refresh %s: %w", t.name, err))
+			continue
+		}
+
+		now := e.now()
+		e.mu.Lock()
+		for k, u := range e.usage {
+			if k.name == t.name {
+				// Tenants without documents have no group; they are empty now.
+				u.snapshot = Usage{RefreshedAt: now}
+				u.pendingDocs, u.pendingBytes = 0, 0
+			}
+		}
+		for tenant, snap := range fresh {
+			snap.RefreshedAt = now
+			u := e.entry(usageKey{t.name, tenant})
+			u.snapshot, u.loaded = snap, true
+			u.pendingDocs, u.pendingBytes = 0, 0
+		}
+		e.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// codeNamespaceNotFound is the server error code for a missing collection.
+const codeNamespaceNotFound = 26
+
+// collectionUsage reads the document count and data size of a collection from
+// its storage statistics. A missing collection is empty.
+func collectionUsage(ctx context.Context, coll *mongo.Collection) (Usage, error) {
+	cur, err := coll.Aggregate(ctx, []bson.M{{"$collStats": bson.M{"storageStats": bson.M{}}}})
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == codeNamespaceNotFound {
+			return Usage{}, nil
+		}
+		return Usage{}, err
+	}
+	var stats []struct {
+		StorageStats struct {
+			Count int64 `bson:"count"`
+			Size  int64 `bson:"size"`
+		} `bson:"storageStats"`
+	}
+	if err := cur.All(ctx, &stats); err != nil {
+		return Usage{}, err
+	}
+	var u Usage
+	for _, s := range stats {
+		u.Documents += s.StorageStats.Count
+		u.Bytes += s.StorageStats.Size
+	}
+	return u, nil
+}
+
+// tenantUsage counts documents and their BSON size per tenant, optionally
+// restricted by match.
+func tenantUsage(ctx context.Context, coll *mongo.Collection, field string, match bson.M) (map[string]Usage, error) {
+	var pipeline []bson.M
+	if match != nil {
+		pipeline = append(pipeline, bson.M{"$match": match})
+	}
+	pipeline = append(pipeline, bson.M{"$group": bson.M{
+		"_id":   "$" + field,
+		"docs":  bson.M{"$sum": 1},
+		"bytes": bson.M{"$sum": bson.M{"$bsonSize": "$$ROOT"}},
+	}})
+
+	cur, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		Tenant any   `bson:"_id"`
+		Docs   int64 `bson:"docs"`
+		Bytes  int64 `bson:"bytes"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	out := make(map[string]Usage, len(groups))
+	for _, g := range groups {
+		if id, ok := g.Tenant.(string); ok {
+			out[id] = Usage{Documents: g.Docs, Bytes: g.Bytes}
+		}
+	}
+	return out, nil
+}
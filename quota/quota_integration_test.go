@@ -0,0 +1,132 @@
+//go:build integration
+
+package quota_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/multitenancy"
+	"github.com/dElCIoGio/mongox/quota"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+type Note struct {
+	document.Base `bson:",inline"`
+
+	TenantID string `bson:"tenant_id"`
+	Text     string `bson:"text"`
+}
+
+func TestWrap_EnforcesPerTenantQuota(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("notes")
+	e := quota.New()
+	e.Register("notes", coll, quota.Policy{MaxDocuments: 3},
+		quota.PerTenant("tenant_id"),
+		quota.WithTenantPolicy("acme", quota.Policy{MaxDocuments: 5}),
+	)
+	notes := quota.Wrap[Note](mongorepo.New[Note](coll), e, "notes")
+
+	globex := multitenancy.WithTenant(ctx, "globex")
+	for i := 0; i < 3; i++ {
+		if err := notes.InsertOne(globex, &Note{TenantID: "globex"}); err != nil {
+			t.Fatalf("InsertOne #%d: %v", i+1, err)
+		}
+	}
+	err := notes.InsertOne(globex, &Note{TenantID: "globex"})
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Tenant != "globex" || exceeded.Limit != "documents" {
+		t.Fatalf("expected the globex quota to be exceeded, got %v", err)
+	}
+
+	// acme has a larger plan; a batch that does not fit is rejected whole.
+	acme := multitenancy.WithTenant(ctx, "acme")
+	batch := []*Note{{TenantID: "acme"}, {TenantID: "acme"}, {TenantID: "acme"}, {TenantID: "acme"}}
+	if _, err := notes.InsertMany(acme, batch); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+	if _, err := notes.InsertMany(acme, []*Note{{TenantID: "acme"}, {TenantID: "acme"}}); !errors.Is(err, quota.ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if n, _ := coll.CountDocuments(ctx, bson.M{"tenant_id": "acme"}); n != 4 {
+		t.Fatalf("expected 4 acme notes, got %d", n)
+	}
+
+	// Deletes free quota once the snapshots are refreshed.
+	if _, err := coll.DeleteMany(ctx, bson.M{"tenant_id": "globex"}); err != nil {
+		t.Fatalf("DeleteMany: %v", err)
+	}
+	if err := e.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if u, ok := e.Usage("notes", "globex"); !ok || u.Documents != 0 {
+		t.Fatalf("expected globex usage to be empty after refresh, got %+v", u)
+	}
+	if u, ok := e.Usage("notes", "acme"); !ok || u.Documents != 4 || u.Bytes == 0 {
+		t.Fatalf("unexpected acme usage: %+v", u)
+	}
+	if err := notes.InsertOne(globex, &Note{TenantID: "globex"}); err != nil {
+		t.Fatalf("expected an insert after the refresh to succeed, got %v", err)
+	}
+}
+
+func TestWrap_EnforcesCollectionQuota(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("audit")
+	e := quota.New()
+	e.Register("audit", coll, quota.Policy{MaxBytes: 1 << 10})
+	audit := quota.Wrap[Note](mongorepo.New[Note](coll), e, "audit")
+
+	big := make([]byte, 400)
+	for i := range big {
+		big[i] = 'x'
+	}
+	for i := 0; i < 2; i++ {
+		if err := audit.InsertOne(ctx, &Note{Text: string(big)}); err != nil {
+			t.Fatalf("InsertOne #%d: %v", i+1, err)
+		}
+	}
+	err := audit.InsertOne(ctx, &Note{Text: string(big)})
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) || exceeded.Limit != "bytes" || exceeded.Tenant != "" {
+		t.Fatalf("expected the bytes quota to be exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package quota_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	"github.com/dElCIoGio/mongox/quota"
+)
+
+func TestExceededError(t *testing.T) {
+	err := error(&quota.ExceededError{Collection: "notes", Tenant: "acme", Limit: "documents", Max: 10, Requested: 11})
+	if !errors.Is(err, quota.ErrQuotaExceeded) {
+		t.Fatal("expected ExceededError to match ErrQuotaExceeded")
+	}
+	if got, want := err.Error(), "quota: documents quota of notes for tenant acme exceeded: 11 > 10"; got != want {
+		t.Fatalf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestReserve_ResolvesBeforeLoading(t *testing.T) {
+	ctx := context.Background()
+	e := quota.New()
+	e.Register("notes", testutil.LazyCollection(t, "notes"), quota.Policy{MaxDocuments: 10}, quota.PerTenant("tenant_id"))
+
+	if err := e.Reserve(ctx, "invoices", 1, 100); !errors.Is(err, quota.ErrUnknownCollection) {
+		t.Fatalf("expected ErrUnknownCollection, got %v", err)
+	}
+	if err := e.Reserve(ctx, "notes", 1, 100); !errors.Is(err, quota.ErrNoTenant) {
+		t.Fatalf("expected ErrNoTenant, got %v", err)
+	}
+	if _, ok := e.Usage("notes", "acme"); ok {
+		t.Fatal("expected no usage before a snapshot is loaded")
+	}
+}
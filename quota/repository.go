@@ -0,0 +1,68 @@
+package quota
+
+import (
+	"context"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Repository is a repository.Repository that checks quotas before inserts and
+// delegates every call to an inner repository.
+type Repository[T any] struct {
+	repository.Repository[T]
+
+	enforcer *Enforcer
+	name     string
+}
+
+// Wrap returns a decorator for repo that checks the quota registered under name
+// before InsertOne and InsertMany.
+func Wrap[T any](repo repository.Repository[T], e *Enforcer, name string) *Repository[T] {
+	return &Repository[T]{Repository: repo, enforcer: e, name: name}
+}
+
+// Unwrap returns the decorated repository.
+func (r *Repository[T]) Unwrap() repository.Repository[T] { return r.Repository }
+
+// InsertOne inserts doc if it fits the quota, and returns an error matching
+// ErrQuotaExceeded otherwise.
+func (r *Repository[T]) InsertOne(ctx context.Context, doc *T) error {
+	n := size(doc)
+	if err := r.enforcer.Reserve(ctx, r.name, 1, n); err != nil {
+		return err
+	}
+	if err := r.Repository.InsertOne(ctx, doc); err != nil {
+		r.enforcer.Release(ctx, r.name, 1, n)
+		return err
+	}
+	return nil
+}
+
+// InsertMany inserts docs if all of them fit the quota, and returns an error
+// matching ErrQuotaExceeded otherwise; nothing is inserted then.
+func (r *Repository[T]) InsertMany(ctx context.Context, docs []*T) ([]primitive.ObjectID, error) {
+	var n int64
+	for _, d := range docs {
+		n += size(d)
+	}
+	if err := r.enforcer.Reserve(ctx, r.name, int64(len(docs)), n); err != nil {
+		return nil, err
+	}
+	ids, err := r.Repository.InsertMany(ctx, docs)
+	if err != nil {
+		r.enforcer.Release(ctx, r.name, int64(len(docs)), n)
+	}
+	return ids, err
+}
+
+// size returns the encoded BSON size of v, or 0 if it cannot be encoded.
+func size(v any) int64 {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return int64(len(b))
+}
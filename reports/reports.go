@@ -0,0 +1,430 @@
+// Package reports runs named aggregation pipelines on a schedule and stores
+// each run's results as a snapshot, so dashboards and exports can read recent
+// analytics without re-running expensive pipelines.
+//
+// A Scheduler owns a snapshots collection. Register adds reports, Run executes
+// them whenever their interval elapses, and Latest, Nth, and History read the
+// stored snapshots back. Scheduled runs are keyed by report and time slot, so
+// several processes can run the same Scheduler and each slot still runs once.
+//
+// Example:
+//
+//	s := reports.New(db.Collection("report_snapshots"))
+//	_ = s.Register(reports.Report{
+//	    Name:       "revenue-by-country",
+//	    Collection: db.Collection("orders"),
+//	    Pipeline: spec.NewPipeline().
+//	        Match(spec.Eq("paid", true)).
+//	        GroupBy("$country", bson.M{"revenue": spec.Sum("$total")}),
+//	    Every: time.Hour,
+//	    Keep:  48,
+//	})
+//	go s.Run(ctx)
+//
+//	snap, err := s.Latest(ctx, "revenue-by-country")
+package reports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrUnknownReport is returned for report names that were not registered.
+	ErrUnknownReport = errors.New("reports: unknown report")
+
+	// ErrNotFound is returned when no snapshot matches a query.
+	ErrNotFound = errors.New("reports: snapshot not found")
+)
+
+// Snapshot statuses.
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// Report is a named pipeline run on a schedule.
+type Report struct {
+	// Name identifies the report and its snapshots.
+	Name string
+
+	// Collection is the collection the pipeline runs against.
+	Collection *mongo.Collection
+
+	// Pipeline is the aggregation to run: a *spec.Pipeline, a []bson.M, or
+	// nil. Register rejects a spec.Pipeline a builder method found invalid.
+	Pipeline any
+
+	// Every is the interval between scheduled runs. Runs are aligned to
+	// multiples of Every since the Unix epoch. Zero disables scheduling; the
+	// report then only runs through RunNow.
+	Every time.Duration
+
+	// Keep is the number of successful snapshots to retain. Older snapshots
+	// are deleted after each successful run. Zero keeps all snapshots.
+	Keep int
+
+	// MaxResults caps the number of result documents stored per snapshot, to
+	// stay below MongoDB's 16MB document limit. Defaults to 10000.
+	MaxResults int
+
+	stages []bson.M // Pipeline, converted by Register
+}
+
+// pipelineConverter is implemented by pipeline builders such as spec.Pipeline.
+type pipelineConverter interface {
+	ToPipeline() ([]bson.M, error)
+}
+
+// Snapshot is the stored outcome of one report run.
+type Snapshot struct {
+	// ID is "<report>/<slot>" for scheduled runs and "<report>/manual/<id>" for RunNow.
+	ID         string     `bson:"_id"`
+	Report     string     `bson:"report"`
+	Status     string     `bson:"status"`
+	StartedAt  time.Time  `bson:"started_at"`
+	FinishedAt time.Time  `bson:"finished_at,omitempty"`
+	DurationMS int64      `bson:"duration_ms"`
+	Count      int        `bson:"count"`
+	Truncated  bool       `bson:"truncated,omitempty"`
+	Error      string     `bson:"error,omitempty"`
+	Results    []bson.Raw `bson:"results,omitempty"`
+}
+
+// Decode unmarshals the snapshot's results into out, which must be a pointer to a slice.
+func (s *Snapshot) Decode(out any) error {
+	arr := make(bson.A, len(s.Results))
+	for i, r := range s.Results {
+		arr[i] = r
+	}
+	b, err := bson.Marshal(bson.M{"r": arr})
+	if err != nil {
+		return err
+	}
+	raw, err := bson.Raw(b).LookupErr("r")
+	if err != nil {
+		return err
+	}
+	return raw.Unmarshal(out)
+}
+
+// Option configures a Scheduler.
+type Option func(*config)
+
+type config struct {
+	poll    time.Duration
+	onError func(report string, err error)
+}
+
+// WithPollInterval sets how often Run checks for due reports. Defaults to 10s.
+func WithPollInterval(d time.Duration) Option {
+	return func(c *config) { c.poll = d }
+}
+
+// WithErrorHandler registers a callback for errors of scheduled runs, such as
+// a failing pipeline or a failure to write to the snapshots collection.
+// Failing pipelines are also stored as failed snapshots.
+func WithErrorHandler(fn func(report string, err error)) Option {
+	return func(c *config) { c.onError = fn }
+}
+
+// Scheduler runs registered reports and stores their snapshots.
+type Scheduler struct {
+	store *mongo.Collection
+	cfg   config
+	now   func() time.Time
+
+	mu       sync.Mutex
+	reports  map[string]Report
+	lastSlot map[string]int64
+}
+
+// New creates a Scheduler that stores snapshots in store.
+func New(store *mongo.Collection, opts ...Option) *Scheduler {
+	cfg := config{poll: 10 * time.Second}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.poll <= 0 {
+		cfg.poll = 10 * time.Second
+	}
+	return &Scheduler{
+		store:    store,
+		cfg:      cfg,
+		now:      func() time.Time { return time.Now().UTC() },
+		reports:  make(map[string]Report),
+		lastSlot: make(map[string]int64),
+	}
+}
+
+// Register adds a report. Registering a name twice replaces the report.
+func (s *Scheduler) Register(r Report) error {
+	if r.Name == "" {
+		return errors.New("reports: report has no name")
+	}
+	if r.Collection == nil {
+		return fmt.Errorf("reports: report %q has no collection", r.Name)
+	}
+	if r.Every < 0 {
+		return fmt.Errorf("reports: report %q has a negative interval", r.Name)
+	}
+	if r.MaxResults <= 0 {
+		r.MaxResults = 10000
+	}
+	switch p := r.Pipeline.(type) {
+	case nil:
+		r.stages = []bson.M{}
+	case []bson.M:
+		r.stages = p
+	case pipelineConverter:
+		stages, err := p.ToPipeline()
+		if err != nil {
+			return fmt.Errorf("reports: report %q: %w", r.Name, err)
+		}
+		r.stages = stages
+	default:
+		return fmt.Errorf("reports: report %q has an unsupported pipeline %T", r.Name, r.Pipeline)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reports[r.Name] = r
+	return nil
+}
+
+func (s *Scheduler) report(name string) (Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.reports[name]
+	if !ok {
+		return Report{}, fmt.Errorf("%w: %q", ErrUnknownReport, name)
+	}
+	return r, nil
+}
+
+// EnsureIndexes creates the index used by snapshot queries.
+func (s *Scheduler) EnsureIndexes(ctx context.Context) error {
+	_, err := s.store.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "report", Value: 1}, {Key: "status", Value: 1}, {Key: "started_at", Value: -1}},
+	})
+	return err
+}
+
+// Run executes due reports until ctx is cancelled, then returns ctx.Err().
+// Reports run one at a time.
+func (s *Scheduler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.poll)
+	defer ticker.Stop()
+	for {
+		s.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs every scheduled report whose current slot has not run yet and
+// returns the number of runs that succeeded. Run calls it on every poll; call it
+// directly to drive the scheduler from an existing cron or job runner.
+func (s *Scheduler) RunDue(ctx context.Context) int {
+	s.mu.Lock()
+	due := make([]Report, 0, len(s.reports))
+	for _, r := range s.reports {
+		if r.Every > 0 {
+			due = append(due, r)
+		}
+	}
+	s.mu.Unlock()
+
+	ran := 0
+	for _, r := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		slot := s.now().Truncate(r.Every).Unix()
+
+		s.mu.Lock()
+		seen := s.lastSlot[r.Name] == slot
+		s.lastSlot[r.Name] = slot
+		s.mu.Unlock()
+		if seen {
+			continue
+		}
+
+		snap, err := s.run(ctx, r, r.Name+"/"+strconv.FormatInt(slot, 10))
+		switch {
+		case errors.Is(err, errClaimed):
+		case err != nil:
+			if snap == nil {
+				// Nothing was recorded for the slot; retry on the next poll.
+				s.mu.Lock()
+				delete(s.lastSlot, r.Name)
+				s.mu.Unlock()
+			}
+			s.reportError(r.Name, err)
+		default:
+			ran++
+		}
+	}
+	return ran
+}
+
+func (s *Scheduler) reportError(name string, err error) {
+	if s.cfg.onError != nil {
+		s.cfg.onError(name, err)
+	}
+}
+
+// RunNow runs the named report immediately, outside its schedule, and returns
+// the stored snapshot. A failing pipeline is stored as a failed snapshot and
+// returned together with the error.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (*Snapshot, error) {
+	r, err := s.report(name)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, r, r.Name+"/manual/"+primitive.NewObjectID().Hex())
+}
+
+// errClaimed means another scheduler already started the slot.
+var errClaimed = errors.New("reports: slot already claimed")
+
+func (s *Scheduler) run(ctx context.Context, r Report, id string) (*Snapshot, error) {
+	snap := &Snapshot{ID: id, Report: r.Name, Status: StatusRunning, StartedAt: s.now()}
+	if _, err := s.store.InsertOne(ctx, snap); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, errClaimed
+		}
+		return nil, fmt.Errorf("reports: record run: %w", err)
+	}
+
+	results, truncated, runErr := aggregate(ctx, r)
+	snap.FinishedAt = s.now()
+	snap.DurationMS = snap.FinishedAt.Sub(snap.StartedAt).Milliseconds()
+	if runErr != nil {
+		snap.Status = StatusFailed
+		snap.Error = runErr.Error()
+	} else {
+		snap.Status = StatusDone
+		snap.Results = results
+		snap.Count = len(results)
+		snap.Truncated = truncated
+	}
+
+	if _, err := s.store.ReplaceOne(ctx, bson.M{"_id": id}, snap); err != nil {
+		return nil, errors.Join(runErr, fmt.Errorf("reports: store snapshot: %w", err))
+	}
+	if runErr != nil {
+		return snap, fmt.Errorf("reports: run %q: %w", r.Name, runErr)
+	}
+	if r.Keep > 0 {
+		if err := s.prune(ctx, r); err != nil {
+			return snap, err
+		}
+	}
+	return snap, nil
+}
+
+func aggregate(ctx context.Context, r Report) ([]bson.Raw, bool, error) {
+	cur, err := r.Collection.Aggregate(ctx, r.stages)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cur.Close(ctx)
+
+	results := []bson.Raw{}
+	for cur.Next(ctx) {
+		if len(results) == r.MaxResults {
+			return results, true, nil
+		}
+		results = append(results, append(bson.Raw(nil), cur.Current...))
+	}
+	return results, false, cur.Err()
+}
+
+// prune deletes finished snapshots of r beyond the newest r.Keep successful ones.
+func (s *Scheduler) prune(ctx context.Context, r Report) error {
+	var oldest Snapshot
+	err := s.store.FindOne(ctx,
+		bson.M{"report": r.Name, "status": StatusDone},
+		mopt.FindOne().
+			SetSort(bson.D{{Key: "started_at", Value: -1}}).
+			SetSkip(int64(r.Keep-1)).
+			SetProjection(bson.M{"started_at": 1}),
+	).Decode(&oldest)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reports: prune: %w", err)
+	}
+
+	_, err = s.store.DeleteMany(ctx, bson.M{
+		"report":     r.Name,
+		"status":     bson.M{"$ne": StatusRunning},
+		"started_at": bson.M{"$lt": oldest.StartedAt},
+	})
+	if err != nil {
+		return fmt.Errorf("reports: prune: %w", err)
+	}
+	return nil
+}
+
+// Latest returns the most recent successful snapshot of the named report.
+func (s *Scheduler) Latest(ctx context.Context, name string) (*Snapshot, error) {
+	return s.Nth(ctx, name, 0)
+}
+
+// Nth returns the nth most recent successful snapshot, where 0 is the latest.
+// Returns ErrNotFound if there are not enough snapshots.
+func (s *Scheduler) Nth(ctx context.Context, name string, n int) (*Snapshot, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("reports: negative snapshot index %d", n)
+	}
+	var snap Snapshot
+	err := s.store.FindOne(ctx,
+		bson.M{"report": name, "status": StatusDone},
+		mopt.FindOne().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetSkip(int64(n)),
+	).Decode(&snap)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// History returns up to limit of the most recent snapshots of the named report,
+// newest first and including failed and running ones, without their results.
+func (s *Scheduler) History(ctx context.Context, name string, limit int) ([]Snapshot, error) {
+	opts := mopt.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetProjection(bson.M{"results": 0})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cur, err := s.store.Find(ctx, bson.M{"report": name}, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := []Snapshot{}
+	if err := cur.All(ctx, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,176 @@
+//go:build integration
+
+package reports_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/reports"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestScheduler_SnapshotsAndRetention(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	orders := db.Collection("report_orders")
+	if _, err := orders.InsertMany(ctx, []any{
+		bson.M{"country": "PT", "total": 10},
+		bson.M{"country": "PT", "total": 5},
+		bson.M{"country": "BR", "total": 7},
+	}); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	s := reports.New(db.Collection("report_snapshots"))
+	if err := s.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes: %v", err)
+	}
+	if err := s.Register(reports.Report{
+		Name:       "revenue",
+		Collection: orders,
+		Pipeline: []bson.M{
+			{"$group": bson.M{"_id": "$country", "revenue": bson.M{"$sum": "$total"}}},
+			{"$sort": bson.D{{Key: "_id", Value: 1}}},
+		},
+		Keep: 2,
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	first, err := s.RunNow(ctx, "revenue")
+	if err != nil {
+		t.Fatalf("RunNow: %v", err)
+	}
+	if first.Status != reports.StatusDone || first.Count != 2 {
+		t.Fatalf("unexpected snapshot: %+v", first)
+	}
+
+	if _, err := orders.InsertOne(ctx, bson.M{"country": "BR", "total": 3}); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+		if _, err := s.RunNow(ctx, "revenue"); err != nil {
+			t.Fatalf("RunNow: %v", err)
+		}
+	}
+
+	latest, err := s.Latest(ctx, "revenue")
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	var rows []struct {
+		Country string `bson:"_id"`
+		Revenue int    `bson:"revenue"`
+	}
+	if err := latest.Decode(&rows); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Country != "BR" || rows[0].Revenue != 10 {
+		t.Fatalf("unexpected latest rows: %+v", rows)
+	}
+
+	if _, err := s.Nth(ctx, "revenue", 1); err != nil {
+		t.Fatalf("Nth(1): %v", err)
+	}
+	if _, err := s.Nth(ctx, "revenue", 2); !errors.Is(err, reports.ErrNotFound) {
+		t.Fatalf("expected the oldest snapshot to be pruned, got %v", err)
+	}
+
+	history, err := s.History(ctx, "revenue", 0)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 2 || history[0].Results != nil {
+		t.Fatalf("expected 2 snapshots without results, got %+v", history)
+	}
+}
+
+func TestScheduler_RunDueOncePerSlotAcrossSchedulers(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	store := db.Collection("report_snapshots_due")
+
+	var failures []error
+	newScheduler := func() *reports.Scheduler {
+		s := reports.New(store, reports.WithErrorHandler(func(_ string, err error) {
+			failures = append(failures, err)
+		}))
+		if err := s.Register(reports.Report{
+			Name:       "count",
+			Collection: db.Collection("report_events"),
+			Pipeline:   []bson.M{{"$count": "n"}},
+			Every:      time.Hour,
+		}); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		if err := s.Register(reports.Report{
+			Name:       "broken",
+			Collection: db.Collection("report_events"),
+			Pipeline:   []bson.M{{"$nope": 1}},
+			Every:      time.Hour,
+		}); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		return s
+	}
+
+	a, b := newScheduler(), newScheduler()
+	if n := a.RunDue(ctx); n != 1 {
+		t.Fatalf("expected 1 successful run, got %d", n)
+	}
+	if n := a.RunDue(ctx); n != 0 {
+		t.Fatalf("expected nothing due on the second poll, got %d", n)
+	}
+	if n := b.RunDue(ctx); n != 0 {
+		t.Fatalf("expected the other scheduler to skip the claimed slot, got %d", n)
+	}
+
+	if len(failures) != 1 {
+		t.Fatalf("expected one reported failure, got %v", failures)
+	}
+	history, err := a.History(ctx, "broken", 10)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 1 || history[0].Status != reports.StatusFailed || history[0].Error == "" {
+		t.Fatalf("expected a failed snapshot, got %+v", history)
+	}
+}
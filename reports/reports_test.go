@@ -0,0 +1,71 @@
+package reports_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	"github.com/dElCIoGio/mongox/reports"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRegister_Validates(t *testing.T) {
+	coll := testutil.LazyCollection(t, "snapshots")
+	s := reports.New(coll)
+
+	tests := []struct {
+		name   string
+		report reports.Report
+	}{
+		{"missing name", reports.Report{Collection: coll}},
+		{"missing collection", reports.Report{Name: "r"}},
+		{"negative interval", reports.Report{Name: "r", Collection: coll, Every: -1}},
+		{"invalid pipeline", reports.Report{Name: "r", Collection: coll, Pipeline: spec.NewPipeline().Out("copy").Limit(1)}},
+		{"unsupported pipeline", reports.Report{Name: "r", Collection: coll, Pipeline: "$match"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Register(tt.report); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+
+	if err := s.Register(reports.Report{Name: "ok", Collection: coll}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := s.Register(reports.Report{Name: "built", Collection: coll, Pipeline: spec.NewPipeline().Count("n")}); err != nil {
+		t.Fatalf("Register with a spec.Pipeline: %v", err)
+	}
+	bad := reports.Report{Name: "bad", Collection: coll, Pipeline: spec.NewPipeline().Merge("t", nil, "upsert", "")}
+	if err := s.Register(bad); !errors.Is(err, spec.ErrInvalidPipeline) {
+		t.Fatalf("expected ErrInvalidPipeline, got %v", err)
+	}
+}
+
+func TestRunNow_UnknownReport(t *testing.T) {
+	s := reports.New(testutil.LazyCollection(t, "snapshots"))
+	if _, err := s.RunNow(context.Background(), "missing"); !errors.Is(err, reports.ErrUnknownReport) {
+		t.Fatalf("expected ErrUnknownReport, got %v", err)
+	}
+}
+
+func TestSnapshot_Decode(t *testing.T) {
+	a, _ := bson.Marshal(bson.M{"_id": "PT", "revenue": 10})
+	b, _ := bson.Marshal(bson.M{"_id": "BR", "revenue": 7})
+	snap := reports.Snapshot{Results: []bson.Raw{a, b}}
+
+	var rows []struct {
+		Country string `bson:"_id"`
+		Revenue int    `bson:"revenue"`
+	}
+	if err := snap.Decode(&rows); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Country != "PT" || rows[1].Revenue != 7 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+}
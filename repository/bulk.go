@@ -12,12 +12,19 @@ const (
 
 // BulkOp represents a single operation in a bulk write.
 type BulkOp struct {
-	Type    BulkOpType
-	Filter  any // For update, replace, delete operations
-	Doc     any // For insert and replace operations
-	Update  any // For update operations
-	Upsert  bool
+	Type      BulkOpType
+	Filter    any // For update, replace, delete operations
+	Doc       any // For insert and replace operations
+	Update    any // For update operations
+	Upsert    bool
 	Collation *Collation
+
+	// ArrayFilters select the array elements "$[identifier]" positions in an
+	// update refer to. Update operations only.
+	ArrayFilters []any
+
+	// Hint names the index to use, as an index name string or key document.
+	Hint any
 }
 
 // Collation specifies collation options for string comparison.
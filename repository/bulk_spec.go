@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// BulkOpOption configures a bulk operation built by the typed constructors.
+type BulkOpOption func(*BulkOp)
+
+// WithBulkUpsert inserts a document when an update or replace matches nothing.
+func WithBulkUpsert() BulkOpOption {
+	return func(op *BulkOp) { op.Upsert = true }
+}
+
+// WithArrayFilters sets the filters for "$[identifier]" positions in the update.
+//
+// Example:
+//
+//	repository.WithArrayFilters(bson.M{"item.sku": "A-1"})
+func WithArrayFilters(filters ...any) BulkOpOption {
+	return func(op *BulkOp) { op.ArrayFilters = append(op.ArrayFilters, filters...) }
+}
+
+// WithHint forces the index to use, as an index name or key document.
+func WithHint(hint any) BulkOpOption {
+	return func(op *BulkOp) { op.Hint = hint }
+}
+
+// WithBulkCollation sets the collation used to match the filter.
+func WithBulkCollation(c *Collation) BulkOpOption {
+	return func(op *BulkOp) { op.Collation = c }
+}
+
+// UpdateOpSpec creates a bulk update operation from typed specifications,
+// validating it immediately instead of when BulkWrite runs.
+// Errors match ErrInvalidBulkOp.
+//
+// Validation:
+//   - filter and update must be non-nil; use UpdateOp with bson.M{} to update any document
+//   - update must produce at least one operator, and only operators
+//   - every "$[identifier]" in the update needs an array filter on that identifier, and vice versa
+//   - hint must be an index name or key document
+//
+// Example:
+//
+//	op, err := repository.UpdateOpSpec(
+//	    spec.Eq("_id", orderID),
+//	    spec.Set("items.$[item].status", "shipped"),
+//	    repository.WithArrayFilters(bson.M{"item.sku": sku}),
+//	    repository.WithHint("_id_"),
+//	)
+func UpdateOpSpec(filter spec.Filter, update spec.Update, opts ...BulkOpOption) (BulkOp, error) {
+	if filter == nil {
+		return BulkOp{}, fmt.Errorf("%w: nil filter", ErrInvalidBulkOp)
+	}
+	if update == nil {
+		return BulkOp{}, fmt.Errorf("%w: nil update", ErrInvalidBulkOp)
+	}
+	u := update.ToBsonUpdate()
+	if len(u) == 0 {
+		return BulkOp{}, fmt.Errorf("%w: empty update", ErrInvalidBulkOp)
+	}
+	for k := range u {
+		if !strings.HasPrefix(k, "$") {
+			return BulkOp{}, fmt.Errorf("%w: update key %q is not an operator", ErrInvalidBulkOp, k)
+		}
+	}
+
+	op := BulkOp{Type: BulkOpUpdate, Filter: filter, Update: u}
+	for _, o := range opts {
+		if o != nil {
+			o(&op)
+		}
+	}
+	if err := validateArrayFilters(u, op.ArrayFilters); err != nil {
+		return BulkOp{}, err
+	}
+	if err := validateHint(op.Hint); err != nil {
+		return BulkOp{}, err
+	}
+	return op, nil
+}
+
+// ReplaceOpSpec creates a bulk replace operation with a typed filter.
+// Array filters are rejected; they only apply to updates.
+func ReplaceOpSpec(filter spec.Filter, doc any, opts ...BulkOpOption) (BulkOp, error) {
+	if filter == nil {
+		return BulkOp{}, fmt.Errorf("%w: nil filter", ErrInvalidBulkOp)
+	}
+	if doc == nil {
+		return BulkOp{}, fmt.Errorf("%w: nil replacement", ErrInvalidBulkOp)
+	}
+	return buildOp(BulkOp{Type: BulkOpReplace, Filter: filter, Doc: doc}, opts)
+}
+
+// DeleteOpSpec creates a bulk delete operation with a typed filter.
+// Upserts and array filters are rejected.
+func DeleteOpSpec(filter spec.Filter, opts ...BulkOpOption) (BulkOp, error) {
+	if filter == nil {
+		return BulkOp{}, fmt.Errorf("%w: nil filter", ErrInvalidBulkOp)
+	}
+	op, err := buildOp(BulkOp{Type: BulkOpDelete, Filter: filter}, opts)
+	if err == nil && op.Upsert {
+		return BulkOp{}, fmt.Errorf("%w: delete cannot upsert", ErrInvalidBulkOp)
+	}
+	return op, err
+}
+
+// buildOp applies opts to a non-update operation and validates the result.
+func buildOp(op BulkOp, opts []BulkOpOption) (BulkOp, error) {
+	for _, o := range opts {
+		if o != nil {
+			o(&op)
+		}
+	}
+	if len(op.ArrayFilters) > 0 {
+		return BulkOp{}, fmt.Errorf("%w: array filters only apply to updates", ErrInvalidBulkOp)
+	}
+	if err := validateHint(op.Hint); err != nil {
+		return BulkOp{}, err
+	}
+	return op, nil
+}
+
+func validateHint(hint any) error {
+	switch h := hint.(type) {
+	case nil:
+		return nil
+	case string:
+		if h == "" {
+			return fmt.Errorf("%w: empty hint", ErrInvalidBulkOp)
+		}
+		return nil
+	case bson.D:
+		if len(h) == 0 {
+			return fmt.Errorf("%w: empty hint", ErrInvalidBulkOp)
+		}
+		return nil
+	case bson.M:
+		if len(h) != 1 {
+			return fmt.Errorf("%w: hint documents with several keys must be a bson.D", ErrInvalidBulkOp)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: hint must be an index name or key document, got %T", ErrInvalidBulkOp, hint)
+	}
+}
+
+var arrayFilterIdent = regexp.MustCompile(`\$\[([a-z][A-Za-z0-9]*)\]`)
+
+// validateArrayFilters checks that the identifiers used in the update and
+// declared by the array filters match one to one.
+func validateArrayFilters(update bson.M, filters []any) error {
+	used := map[string]bool{}
+	for _, arg := range update {
+		for _, path := range updatePaths(arg) {
+			for _, m := range arrayFilterIdent.FindAllStringSubmatch(path, -1) {
+				used[m[1]] = true
+			}
+		}
+	}
+
+	declared := map[string]bool{}
+	for i, f := range filters {
+		var keys []string
+		switch d := f.(type) {
+		case bson.M:
+			for k := range d {
+				keys = append(keys, k)
+			}
+		case map[string]any:
+			for k := range d {
+				keys = append(keys, k)
+			}
+		case bson.D:
+			for _, e := range d {
+				keys = append(keys, e.Key)
+			}
+		default:
+			return fmt.Errorf("%w: array filter %d must be a document, got %T", ErrInvalidBulkOp, i, f)
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("%w: array filter %d is empty", ErrInvalidBulkOp, i)
+		}
+		for _, k := range keys {
+			ident, _, _ := strings.Cut(k, ".")
+			if !used[ident] {
+				return fmt.Errorf("%w: array filter identifier %q is not used in the update", ErrInvalidBulkOp, ident)
+			}
+			declared[ident] = true
+		}
+	}
+
+	for ident := range used {
+		if !declared[ident] {
+			return fmt.Errorf("%w: no array filter for identifier %q", ErrInvalidBulkOp, ident)
+		}
+	}
+	return nil
+}
+
+// updatePaths returns the field paths of one update operator's argument.
+func updatePaths(arg any) []string {
+	var paths []string
+	switch d := arg.(type) {
+	case bson.M:
+		for k := range d {
+			paths = append(paths, k)
+		}
+	case map[string]any:
+		for k := range d {
+			paths = append(paths, k)
+		}
+	case bson.D:
+		for _, e := range d {
+			paths = append(paths, e.Key)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/repository"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUpdateOpSpec(t *testing.T) {
+	op, err := repository.UpdateOpSpec(
+		spec.Eq("_id", 1),
+		spec.Set("items.$[item].status", "shipped"),
+		repository.WithArrayFilters(bson.M{"item.sku": "A-1"}),
+		repository.WithHint("_id_"),
+		repository.WithBulkUpsert(),
+	)
+	if err != nil {
+		t.Fatalf("UpdateOpSpec: %v", err)
+	}
+	if op.Type != repository.BulkOpUpdate || !op.Upsert || op.Hint != "_id_" || len(op.ArrayFilters) != 1 {
+		t.Fatalf("unexpected op: %+v", op)
+	}
+	if _, ok := op.Update.(bson.M)["$set"]; !ok {
+		t.Fatalf("expected the update to be converted, got %#v", op.Update)
+	}
+}
+
+func TestUpdateOpSpec_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter spec.Filter
+		update spec.Update
+		opts   []repository.BulkOpOption
+	}{
+		{"nil filter", nil, spec.Set("a", 1), nil},
+		{"nil update", spec.Eq("_id", 1), nil, nil},
+		{"empty update", spec.Eq("_id", 1), spec.Combine(), nil},
+		{"missing array filter", spec.Eq("_id", 1), spec.Set("items.$[item].qty", 0), nil},
+		{"unused array filter", spec.Eq("_id", 1), spec.Set("qty", 0),
+			[]repository.BulkOpOption{repository.WithArrayFilters(bson.M{"item.sku": "A"})}},
+		{"array filter not a document", spec.Eq("_id", 1), spec.Set("items.$[item].qty", 0),
+			[]repository.BulkOpOption{repository.WithArrayFilters("item.sku")}},
+		{"bad hint", spec.Eq("_id", 1), spec.Set("a", 1),
+			[]repository.BulkOpOption{repository.WithHint(42)}},
+		{"ambiguous hint", spec.Eq("_id", 1), spec.Set("a", 1),
+			[]repository.BulkOpOption{repository.WithHint(bson.M{"a": 1, "b": 1})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repository.UpdateOpSpec(tt.filter, tt.update, tt.opts...)
+			if !errors.Is(err, repository.ErrInvalidBulkOp) {
+				t.Fatalf("expected ErrInvalidBulkOp, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReplaceAndDeleteOpSpec(t *testing.T) {
+	if _, err := repository.ReplaceOpSpec(spec.Eq("_id", 1), bson.M{"a": 1}, repository.WithBulkUpsert()); err != nil {
+		t.Fatalf("ReplaceOpSpec: %v", err)
+	}
+	if _, err := repository.ReplaceOpSpec(spec.Eq("_id", 1), bson.M{"a": 1},
+		repository.WithArrayFilters(bson.M{"x.a": 1})); !errors.Is(err, repository.ErrInvalidBulkOp) {
+		t.Fatalf("expected array filters on replace to be rejected, got %v", err)
+	}
+	if _, err := repository.DeleteOpSpec(spec.Eq("_id", 1), repository.WithHint(bson.D{{Key: "_id", Value: 1}})); err != nil {
+		t.Fatalf("DeleteOpSpec: %v", err)
+	}
+	if _, err := repository.DeleteOpSpec(spec.Eq("_id", 1), repository.WithBulkUpsert()); !errors.Is(err, repository.ErrInvalidBulkOp) {
+		t.Fatalf("expected upsert on delete to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package repository
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// DuplicateGroup is a set of documents that share the same values for a set of key fields.
+type DuplicateGroup[T any] struct {
+	// Key holds the shared key field values.
+	Key bson.M
+
+	// Docs contains the duplicate documents, in natural order.
+	Docs []T
+}
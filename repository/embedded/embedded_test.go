@@ -0,0 +1,408 @@
+package embeddedrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+	embeddedrepo "github.com/dElCIoGio/mongox/repository/embedded"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Task struct {
+	document.Base `bson:",inline"`
+
+	Title    string   `bson:"title"`
+	Owner    string   `bson:"owner"`
+	Priority int      `bson:"priority"`
+	Tags     []string `bson:"tags,omitempty"`
+
+	loaded bool
+}
+
+func (t *Task) Validate() error {
+	if t.Title == "" {
+		return repository.ValidationError{Field: "title", Message: "required"}
+	}
+	return nil
+}
+
+func (t *Task) AfterLoad(context.Context) error {
+	t.loaded = true
+	return nil
+}
+
+func newRepo(t *testing.T) *embeddedrepo.Repository[Task] {
+	t.Helper()
+	coll, err := embeddedrepo.Memory().Collection("tasks")
+	if err != nil {
+		t.Fatalf("Collection: %v", err)
+	}
+	return embeddedrepo.New[Task](coll)
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	task := &Task{Title: "write docs", Owner: "ada", Priority: 2}
+	if err := repo.InsertOne(ctx, task); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	if task.ID.IsZero() || task.CreatedAt.IsZero() {
+		t.Fatal("expected InsertOne to auto-touch the document")
+	}
+
+	got, err := repo.FindOne(ctx, spec.Eq("_id", task.ID))
+	if err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	if got.Title != "write docs" || !got.loaded {
+		t.Fatalf("unexpected document: %+v", got)
+	}
+
+	matched, modified, err := repo.UpdateOne(ctx, spec.Eq("_id", task.ID), spec.Set("priority", 5))
+	if err != nil || matched != 1 || modified != 1 {
+		t.Fatalf("UpdateOne: matched=%d modified=%d err=%v", matched, modified, err)
+	}
+	got, _ = repo.FindOne(ctx, spec.Eq("_id", task.ID))
+	if got.Priority != 5 || !got.UpdatedAt.After(task.CreatedAt.Add(-time.Millisecond)) {
+		t.Fatalf("expected priority 5 and updated_at set, got %+v", got)
+	}
+
+	got.Title = "write better docs"
+	if _, _, err := repo.ReplaceOne(ctx, spec.Eq("_id", task.ID), got); err != nil {
+		t.Fatalf("ReplaceOne: %v", err)
+	}
+
+	deleted, err := repo.DeleteOne(ctx, spec.Eq("title", "write better docs"))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteOne: deleted=%d err=%v", deleted, err)
+	}
+	if _, err := repo.FindOne(ctx, spec.Eq("_id", task.ID)); !errors.Is(err, embeddedrepo.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRepository_ValidationAndDuplicates(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	if err := repo.InsertOne(ctx, &Task{}); !errors.Is(err, repository.ErrValidation) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	task := &Task{Title: "a"}
+	if err := repo.InsertOne(ctx, task); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	dup := &Task{Title: "b"}
+	dup.ID = task.ID
+	if err := repo.InsertOne(ctx, dup); !errors.Is(err, embeddedrepo.ErrDuplicateKey) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+}
+
+func TestRepository_Upsert(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	res, err := repo.UpsertOne(ctx, spec.Eq("title", "triage"), spec.Set("priority", 1))
+	if err != nil {
+		t.Fatalf("UpsertOne: %v", err)
+	}
+	if res.UpsertedCount != 1 || res.UpsertedID.IsZero() || res.MatchedCount != 0 {
+		t.Fatalf("expected an insert, got %+v", res)
+	}
+
+	res, err = repo.UpsertOne(ctx, spec.Eq("title", "triage"), spec.Set("priority", 2))
+	if err != nil || res.UpsertedCount != 0 || res.MatchedCount != 1 || !res.UpsertedID.IsZero() {
+		t.Fatalf("expected the second upsert to match, got %+v err=%v", res, err)
+	}
+
+	if _, _, err := repo.UpdateOne(ctx, spec.Eq("title", "review"), spec.Set("priority", 3), repository.WithUpsert()); err != nil {
+		t.Fatalf("UpdateOne with upsert: %v", err)
+	}
+	if n, _ := repo.Count(ctx, nil); n != 2 {
+		t.Fatalf("expected 2 tasks, got %d", n)
+	}
+	if matched, _, _ := repo.UpdateMany(ctx, spec.Eq("title", "missing"), spec.Set("priority", 4)); matched != 0 {
+		t.Fatalf("expected no match without upsert, got %d", matched)
+	}
+	if n, _ := repo.Count(ctx, nil); n != 2 {
+		t.Fatalf("expected UpdateMany without upsert not to insert, got %d tasks", n)
+	}
+	if n, _ := repo.Count(ctx, nil, repository.WithCountLimit(1)); n != 1 {
+		t.Fatalf("expected the count limit to cap the count at 1, got %d", n)
+	}
+}
+
+func TestRepository_FindOptionsAndFilters(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	_, err := repo.InsertMany(ctx, []*Task{
+		{Title: "a", Owner: "ada", Priority: 3, Tags: []string{"docs"}},
+		{Title: "b", Owner: "bob", Priority: 1},
+		{Title: "c", Owner: "ada", Priority: 2, Tags: []string{"bug", "docs"}},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	tasks, err := repo.Find(ctx, spec.Eq("owner", "ada"), repository.WithSort(bson.D{{Key: "priority", Value: 1}}))
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Title != "c" || tasks[1].Title != "a" {
+		t.Fatalf("unexpected order: %+v", tasks)
+	}
+
+	n, err := repo.Count(ctx, spec.And(spec.In("tags", []string{"docs"}), spec.Gte("priority", 3)))
+	if err != nil || n != 1 {
+		t.Fatalf("Count: n=%d err=%v", n, err)
+	}
+
+	since := time.Now().Add(-time.Hour)
+	n, err = repo.Count(ctx, spec.Gt("created_at", since))
+	if err != nil || n != 3 {
+		t.Fatalf("expected time filters to match stored dates, n=%d err=%v", n, err)
+	}
+
+	matched, _, err := repo.UpdateMany(ctx, spec.Eq("owner", "ada"), spec.Inc("priority", 10))
+	if err != nil || matched != 2 {
+		t.Fatalf("UpdateMany: matched=%d err=%v", matched, err)
+	}
+}
+
+func TestRepository_Aggregate(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+	_, _ = repo.InsertMany(ctx, []*Task{
+		{Title: "a", Owner: "ada", Priority: 3},
+		{Title: "b", Owner: "bob", Priority: 1},
+		{Title: "c", Owner: "ada", Priority: 2},
+	})
+
+	out, err := repo.AggregateRaw(ctx, spec.NewPipeline().
+		GroupBy("$owner", bson.M{"total": spec.Sum("$priority")}).
+		SortBy("total", -1))
+	if err != nil {
+		t.Fatalf("AggregateRaw: %v", err)
+	}
+	if len(out) != 2 || out[0]["_id"] != "ada" || out[0]["total"] != int32(5) {
+		t.Fatalf("unexpected result: %v", out)
+	}
+
+	_, err = repo.AggregateRaw(ctx, spec.NewPipeline().Lookup("users", "owner", "name", "user"))
+	if !errors.Is(err, embeddedrepo.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+
+	_, err = repo.AggregateRaw(ctx, spec.NewPipeline().Out("archive").Limit(1))
+	if !errors.Is(err, spec.ErrInvalidPipeline) {
+		t.Fatalf("expected ErrInvalidPipeline, got %v", err)
+	}
+}
+
+func TestRepository_FindWithProjection(t *testing.T) {
+	ctx := context.Background()
+	repo := newRepo(t)
+
+	task := &Task{Title: "ship", Owner: "ada", Priority: 3, Tags: []string{"a", "b"}}
+	if err := repo.InsertOne(ctx, task); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+
+	got, err := repo.FindOne(ctx, spec.Eq("_id", task.ID), repository.WithFields("title"))
+	if err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	if got.ID != task.ID || got.Title != "ship" || got.Owner != "" || got.Priority != 0 {
+		t.Fatalf("unexpected included fields: %+v", got)
+	}
+
+	all, err := repo.Find(ctx, nil, repository.WithProjection(spec.Exclude("tags", "owner")))
+	if err != nil || len(all) != 1 {
+		t.Fatalf("Find: %v, %v", all, err)
+	}
+	if all[0].Tags != nil || all[0].Owner != "" || all[0].Priority != 3 {
+		t.Fatalf("unexpected excluded fields: %+v", all[0])
+	}
+}
+
+func TestStore_PersistsAcrossOpen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	store, err := embeddedrepo.Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	coll, _ := store.Collection("tasks")
+	repo := embeddedrepo.New[Task](coll)
+	task := &Task{Title: "persist me", Priority: 1}
+	if err := repo.InsertOne(ctx, task); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	if _, _, err := repo.UpdateOne(ctx, spec.Eq("_id", task.ID), spec.Set("priority", 9)); err != nil {
+		t.Fatalf("UpdateOne: %v", err)
+	}
+
+	reopened, err := embeddedrepo.Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	coll, err = reopened.Collection("tasks")
+	if err != nil {
+		t.Fatalf("Collection: %v", err)
+	}
+	got, err := embeddedrepo.New[Task](coll).FindOne(ctx, spec.Eq("_id", task.ID))
+	if err != nil {
+		t.Fatalf("FindOne after reopen: %v", err)
+	}
+	if got.Title != "persist me" || got.Priority != 9 {
+		t.Fatalf("unexpected document after reopen: %+v", got)
+	}
+}
+
+func TestStore_RejectsInvalidNames(t *testing.T) {
+	store := embeddedrepo.Memory()
+	for _, name := range []string{"", "../x", "a/b", ".hidden"} {
+		if _, err := store.Collection(name); err == nil {
+			t.Errorf("expected error for collection name %q", name)
+		}
+	}
+}
+
+// auditedTask records its update and delete hooks in auditLog and stamps
+// every update with an audited flag.
+type auditedTask struct {
+	document.Base `bson:",inline"`
+
+	Title   string `bson:"title"`
+	Audited bool   `bson:"audited"`
+}
+
+var auditLog []string
+
+func (*auditedTask) BeforeUpdate(_ context.Context, e *document.UpdateEvent) error {
+	e.Update = spec.Combine(e.Update.(spec.Update), spec.Set("audited", true))
+	return nil
+}
+
+func (*auditedTask) AfterUpdate(_ context.Context, e *document.UpdateEvent) error {
+	auditLog = append(auditLog, e.Op)
+	return nil
+}
+
+func (*auditedTask) BeforeDelete(_ context.Context, e *document.DeleteEvent) error {
+	if e.Filter == nil {
+		return errors.New("refusing to delete without a filter")
+	}
+	return nil
+}
+
+func (*auditedTask) AfterDelete(_ context.Context, e *document.DeleteEvent) error {
+	auditLog = append(auditLog, e.Op)
+	return nil
+}
+
+func TestRepository_UpdateAndDeleteHooks(t *testing.T) {
+	ctx := context.Background()
+	coll, err := embeddedrepo.Memory().Collection("audited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := embeddedrepo.New[auditedTask](coll)
+	auditLog = nil
+
+	task := &auditedTask{Title: "a"}
+	if err := repo.InsertOne(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := repo.UpdateOne(ctx, spec.Eq("_id", task.ID), spec.Set("title", "b")); err != nil {
+		t.Fatalf("UpdateOne: %v", err)
+	}
+	got, err := repo.FindOne(ctx, spec.Eq("_id", task.ID))
+	if err != nil || got.Title != "b" || !got.Audited {
+		t.Fatalf("expected BeforeUpdate to add audited, got %+v (%v)", got, err)
+	}
+
+	if _, err := repo.DeleteMany(ctx, nil); err == nil {
+		t.Fatal("expected BeforeDelete to refuse a nil filter")
+	}
+	if n, err := repo.DeleteOne(ctx, spec.Eq("_id", task.ID)); err != nil || n != 1 {
+		t.Fatalf("DeleteOne: deleted=%d err=%v", n, err)
+	}
+
+	want := []string{repository.OpUpdateOne, repository.OpDeleteOne}
+	if len(auditLog) != len(want) || auditLog[0] != want[0] || auditLog[1] != want[1] {
+		t.Fatalf("audit log = %v, want %v", auditLog, want)
+	}
+}
+
+type Article struct {
+	document.Base      `bson:",inline"`
+	document.Versioned `bson:",inline"`
+
+	Title string `bson:"title"`
+}
+
+func TestRepository_OptimisticLocking(t *testing.T) {
+	ctx := context.Background()
+	coll, err := embeddedrepo.Memory().Collection("articles")
+	if err != nil {
+		t.Fatalf("Collection: %v", err)
+	}
+	repo := embeddedrepo.New[Article](coll)
+
+	a := &Article{Title: "draft"}
+	if err := repo.InsertOne(ctx, a); err != nil {
+		t.Fatalf("InsertOne: %v", err)
+	}
+	if a.Version != 1 {
+		t.Fatalf("expected version 1 after insert, got %d", a.Version)
+	}
+
+	first, _ := repo.FindOne(ctx, spec.Eq("_id", a.ID))
+	stale, _ := repo.FindOne(ctx, spec.Eq("_id", a.ID))
+
+	first.Title = "edited"
+	if _, _, err := repo.ReplaceOne(ctx, spec.Eq("_id", a.ID), first); err != nil {
+		t.Fatalf("ReplaceOne: %v", err)
+	}
+	if first.Version != 2 {
+		t.Fatalf("expected version 2 after replace, got %d", first.Version)
+	}
+
+	stale.Title = "overwritten"
+	if _, _, err := repo.ReplaceOne(ctx, spec.Eq("_id", a.ID), stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if stale.Version != 1 {
+		t.Fatalf("expected a failed replace to keep version 1, got %d", stale.Version)
+	}
+
+	if _, _, err := repo.UpdateOne(ctx, spec.Eq("_id", a.ID), spec.Set("title", "x"), repository.WithExpectedVersion(1)); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	matched, _, err := repo.UpdateOne(ctx, spec.Eq("_id", a.ID), spec.Set("title", "final"), repository.WithExpectedVersion(2))
+	if err != nil || matched != 1 {
+		t.Fatalf("UpdateOne: matched=%d err=%v", matched, err)
+	}
+	got, _ := repo.FindOne(ctx, spec.Eq("_id", a.ID))
+	if got.Title != "final" || got.Version != 3 {
+		t.Fatalf("expected final at version 3, got %+v", got)
+	}
+
+	matched, _, err = repo.UpdateOne(ctx, spec.Eq("title", "missing"), spec.Set("title", "y"), repository.WithExpectedVersion(3))
+	if err != nil || matched != 0 {
+		t.Fatalf("expected no match and no conflict for a missing document, got matched=%d err=%v", matched, err)
+	}
+}
@@ -0,0 +1,576 @@
+package embeddedrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/internal/docstore"
+	"github.com/dElCIoGio/mongox/repository"
+	mongospec "github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Re-export common errors for convenience.
+var (
+	ErrNotFound     = repository.ErrNotFound
+	ErrDuplicateKey = repository.ErrDuplicateKey
+)
+
+// Repository implements repository.Repository[T] on an embedded Collection.
+// Lifecycle hooks, auto-touch, and updated_at injection behave as in MongoRepository.
+type Repository[T any] struct {
+	coll *Collection
+}
+
+var _ repository.Repository[struct{}] = (*Repository[struct{}])(nil)
+
+// New creates a Repository for the given collection.
+func New[T any](coll *Collection) *Repository[T] {
+	return &Repository[T]{coll: coll}
+}
+
+// Collection returns the underlying embedded collection.
+func (r *Repository[T]) Collection() *Collection {
+	return r.coll
+}
+
+// ---- auto-touch helpers ----
+
+type insertToucher interface{ TouchForInsert(time.Time) }
+type updateToucher interface{ TouchForUpdate(time.Time) }
+
+func nowUTC() time.Time { return time.Now().UTC() }
+
+// prepare auto-touches, validates, and runs the BeforeSave hook on a document
+// about to be written, then encodes it.
+func prepare[T any](ctx context.Context, doc *T, now time.Time, inserting bool) (bson.M, error) {
+	if doc == nil {
+		return nil, repository.ErrNilDocument
+	}
+
+	if inserting {
+		if t, ok := any(doc).(insertToucher); ok {
+			t.TouchForInsert(now)
+		}
+		if v, ok := any(doc).(document.VersionedDoc); ok && v.CurrentVersion() == 0 {
+			v.SetVersion(1)
+		}
+	} else if t, ok := any(doc).(updateToucher); ok {
+		t.TouchForUpdate(now)
+	}
+
+	if v, ok := any(doc).(document.Validatable); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if h, ok := any(doc).(document.BeforeSave); ok {
+		if err := h.BeforeSave(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return docstore.CanonicalDoc(doc)
+}
+
+// ---- CRUD ----
+
+func (r *Repository[T]) InsertOne(ctx context.Context, doc *T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	d, err := prepare(ctx, doc, nowUTC(), true)
+	if err != nil {
+		return err
+	}
+	return mapError(r.coll.write(func(c *docstore.Collection) error {
+		_, err := c.Insert(d)
+		return err
+	}))
+}
+
+func (r *Repository[T]) FindOne(ctx context.Context, filter any, opts ...repository.FindOption) (*T, error) {
+	fo := applyFindOptions(opts)
+	fo.Limit = 1
+	results, err := r.find(ctx, filter, fo)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	return &results[0], nil
+}
+
+func (r *Repository[T]) Find(ctx context.Context, filter any, opts ...repository.FindOption) ([]T, error) {
+	return r.find(ctx, filter, applyFindOptions(opts))
+}
+
+func (r *Repository[T]) find(ctx context.Context, filter any, fo repository.FindOptions) ([]T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := r.coll.docs.Find(f, docstore.FindOptions{Sort: fo.Sort, Skip: fo.Skip, Limit: fo.Limit, Projection: fo.Projection})
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[T](ctx, docs, fo.CapacityHint)
+}
+
+func (r *Repository[T]) UpdateOne(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	res, err := r.update(ctx, filter, update, false, applyUpdateOptions(opts))
+	return res.Matched, res.Modified, err
+}
+
+func (r *Repository[T]) UpdateMany(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	uo := applyUpdateOptions(opts)
+	uo.ExpectedVersion = nil
+	res, err := r.update(ctx, filter, update, true, uo)
+	return res.Matched, res.Modified, err
+}
+
+// UpsertOne updates the first matching document or, if none matches, inserts
+// one built from the filter's equality conditions and the update.
+func (r *Repository[T]) UpsertOne(ctx context.Context, filter any, update any) (repository.UpsertResult, error) {
+	res, err := r.update(ctx, filter, update, false, repository.UpdateOptions{Upsert: true})
+	if err != nil {
+		return repository.UpsertResult{}, err
+	}
+	out := repository.UpsertResult{MatchedCount: res.Matched, ModifiedCount: res.Modified}
+	if res.UpsertedID != nil {
+		out.UpsertedCount = 1
+		out.UpsertedID, _ = res.UpsertedID.(primitive.ObjectID)
+	}
+	return out, nil
+}
+
+func (r *Repository[T]) update(ctx context.Context, filter, update any, many bool, uo repository.UpdateOptions) (docstore.UpdateResult, error) {
+	if err := ctx.Err(); err != nil {
+		return docstore.UpdateResult{}, err
+	}
+	e := &document.UpdateEvent{Op: repository.OpUpdateOne, Filter: filter, Update: update}
+	if many {
+		e.Op = repository.OpUpdateMany
+	}
+	if h, ok := any(new(T)).(document.BeforeUpdate); ok {
+		if err := h.BeforeUpdate(ctx, e); err != nil {
+			return docstore.UpdateResult{}, err
+		}
+	}
+	filter, update = e.Filter, e.Update
+
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return docstore.UpdateResult{}, err
+	}
+	if update == nil {
+		return docstore.UpdateResult{}, repository.ErrNilUpdate
+	}
+	if uo.ExpectedVersion != nil && uo.Upsert {
+		return docstore.UpdateResult{}, errors.New("embeddedrepo: WithExpectedVersion cannot be combined with an upsert")
+	}
+	u, err := normalizeUpdate(update, nowUTC())
+	if err != nil {
+		return docstore.UpdateResult{}, err
+	}
+	if _, ok := any(new(T)).(document.VersionedDoc); ok {
+		u = injectVersionInc(u)
+	}
+
+	var res docstore.UpdateResult
+	err = r.coll.write(func(c *docstore.Collection) error {
+		if uo.ExpectedVersion == nil {
+			res, err = c.Update(f, u, many, uo.Upsert)
+			return err
+		}
+		res, err = c.Update(withVersion(f, *uo.ExpectedVersion), u, many, false)
+		if err == nil && res.Matched == 0 {
+			err = versionConflict(c, f)
+		}
+		return err
+	})
+	if err != nil {
+		return docstore.UpdateResult{}, mapError(err)
+	}
+	if h, ok := any(new(T)).(document.AfterUpdate); ok {
+		e.Matched, e.Modified = res.Matched, res.Modified
+		if err := h.AfterUpdate(ctx, e); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// ReplaceOne replaces the first matching document, running auto-touch,
+// validation, and BeforeSave as MongoRepository does.
+func (r *Repository[T]) ReplaceOne(ctx context.Context, filter any, doc *T) (matched int64, modified int64, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	if doc == nil {
+		return 0, 0, repository.ErrNilDocument
+	}
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return 0, 0, err
+	}
+	d, err := prepare(ctx, doc, nowUTC(), false)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	v, versioned := any(doc).(document.VersionedDoc)
+	if versioned {
+		// Optimistic locking: only replace the version doc was loaded with.
+		d[document.VersionField] = v.CurrentVersion() + 1
+	}
+
+	var res docstore.UpdateResult
+	err = r.coll.write(func(c *docstore.Collection) error {
+		if !versioned {
+			res, err = c.Replace(f, d, false)
+			return err
+		}
+		res, err = c.Replace(withVersion(f, v.CurrentVersion()), d, false)
+		if err == nil && res.Matched == 0 {
+			err = versionConflict(c, f)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, 0, mapError(err)
+	}
+	if versioned && res.Matched > 0 {
+		v.SetVersion(v.CurrentVersion() + 1)
+	}
+	return res.Matched, res.Modified, nil
+}
+
+func (r *Repository[T]) DeleteOne(ctx context.Context, filter any) (int64, error) {
+	return r.delete(ctx, filter, false)
+}
+
+func (r *Repository[T]) DeleteMany(ctx context.Context, filter any) (int64, error) {
+	return r.delete(ctx, filter, true)
+}
+
+func (r *Repository[T]) delete(ctx context.Context, filter any, many bool) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	e := &document.DeleteEvent{Op: repository.OpDeleteOne, Filter: filter}
+	if many {
+		e.Op = repository.OpDeleteMany
+	}
+	if h, ok := any(new(T)).(document.BeforeDelete); ok {
+		if err := h.BeforeDelete(ctx, e); err != nil {
+			return 0, err
+		}
+	}
+
+	f, err := normalizeFilter(e.Filter)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = r.coll.write(func(c *docstore.Collection) error {
+		n, err = c.Delete(f, many)
+		return err
+	})
+	if err != nil {
+		return n, err
+	}
+	if h, ok := any(new(T)).(document.AfterDelete); ok {
+		e.Deleted = n
+		if err := h.AfterDelete(ctx, e); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// InsertMany inserts docs in order and returns their ObjectIDs. As with an
+// ordered MongoDB insert, documents before a duplicate are kept and the rest
+// are not inserted.
+func (r *Repository[T]) InsertMany(ctx context.Context, docs []*T) ([]primitive.ObjectID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return []primitive.ObjectID{}, nil
+	}
+
+	now := nowUTC()
+	encoded := make([]bson.M, len(docs))
+	for i, doc := range docs {
+		d, err := prepare(ctx, doc, now, true)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = d
+	}
+
+	var ids []any
+	err := r.coll.write(func(c *docstore.Collection) error {
+		var err error
+		ids, err = c.Insert(encoded...)
+		return err
+	})
+	if err != nil {
+		return nil, mapError(err)
+	}
+
+	out := make([]primitive.ObjectID, len(ids))
+	for i, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok {
+			out[i] = oid
+		}
+	}
+	return out, nil
+}
+
+// Count returns the number of documents matching the filter. Of the count
+// options only the limit applies; hints and time limits are ignored.
+func (r *Repository[T]) Count(ctx context.Context, filter any, opts ...repository.CountOption) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+	n, err := r.coll.docs.Count(f)
+	if err != nil {
+		return 0, err
+	}
+	var co repository.CountOptions
+	for _, o := range opts {
+		if o != nil {
+			o(&co)
+		}
+	}
+	if co.Limit > 0 && n > co.Limit {
+		n = co.Limit
+	}
+	return n, nil
+}
+
+// ---- Aggregation ----
+
+// Aggregate executes an aggregation pipeline and returns the results decoded as type T.
+// See AggregateRaw for the supported stages.
+func (r *Repository[T]) Aggregate(ctx context.Context, pipeline any) ([]T, error) {
+	docs, err := r.AggregateRaw(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[T](ctx, docs, 0)
+}
+
+// AggregateRaw executes an aggregation pipeline and returns raw bson.M results.
+//
+// Supported stages: $match, $sort, $skip, $limit, $count, $unwind, $project,
+// $addFields, $set, $unset, $replaceRoot, and $group with the $sum, $avg,
+// $min, $max, $first, $last, $push, and $addToSet accumulators. Expressions
+// are limited to literals and "$field" paths.
+func (r *Repository[T]) AggregateRaw(ctx context.Context, pipeline any) ([]bson.M, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p, err := normalizePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return r.coll.docs.Aggregate(p)
+}
+
+// ---- helpers ----
+
+// decodeAll decodes stored documents into T and runs the AfterLoad hook.
+func decodeAll[T any](ctx context.Context, docs []bson.M, capacity int) ([]T, error) {
+	if capacity < len(docs) {
+		capacity = len(docs)
+	}
+	out := make([]T, len(docs), capacity)
+	for i, d := range docs {
+		b, err := bson.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		if err := bson.Unmarshal(b, &out[i]); err != nil {
+			return nil, err
+		}
+		if h, ok := any(&out[i]).(document.AfterLoad); ok {
+			if err := h.AfterLoad(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+func mapError(err error) error {
+	if errors.Is(err, docstore.ErrDuplicateKey) {
+		return ErrDuplicateKey
+	}
+	return err
+}
+
+// normalizeFilter converts spec filters to documents and canonicalizes the
+// values so they compare like stored ones (time.Time becomes a DateTime, ...).
+func normalizeFilter(filter any) (any, error) {
+	if filter == nil {
+		return bson.M{}, nil
+	}
+	if c, ok := filter.(*mongospec.CompiledFilter); ok {
+		filter = c.Raw()
+	} else if f, ok := filter.(mongospec.Filter); ok {
+		filter = f.ToMongo()
+	}
+	f, err := docstore.Canonical(filter)
+	if err != nil {
+		return nil, repository.ErrInvalidFilter
+	}
+	return f, nil
+}
+
+// updateConverter is implemented by types that can be converted to a MongoDB update.
+type updateConverter interface {
+	ToBsonUpdate() bson.M
+}
+
+// normalizeUpdate converts and canonicalizes update and, as MongoRepository
+// does, adds updated_at to its $set document.
+func normalizeUpdate(update any, now time.Time) (any, error) {
+	if u, ok := update.(updateConverter); ok {
+		update = u.ToBsonUpdate()
+	}
+	u, err := docstore.Canonical(update)
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := u.(bson.M); ok {
+		if set, ok := m["$set"].(bson.M); ok {
+			set["updated_at"] = primitive.NewDateTimeFromTime(now)
+		}
+	}
+	return u, nil
+}
+
+// withVersion restricts f to documents stored with version v, as
+// MongoRepository does.
+func withVersion(f any, v int64) bson.M {
+	var cond any = v
+	if v == 0 {
+		cond = bson.M{"$in": bson.A{int64(0), nil}}
+	}
+	return bson.M{"$and": bson.A{f, bson.M{document.VersionField: cond}}}
+}
+
+// versionConflict returns ErrVersionConflict if a document matches f, for a
+// version-checked write that matched nothing.
+func versionConflict(c *docstore.Collection, f any) error {
+	n, err := c.Count(f)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return repository.ErrVersionConflict
+	}
+	return nil
+}
+
+// injectVersionInc adds {$inc: {version: 1}} to a canonical update document.
+func injectVersionInc(u any) any {
+	m, ok := u.(bson.M)
+	if !ok {
+		return u
+	}
+	inc, ok := m["$inc"].(bson.M)
+	if !ok {
+		inc = bson.M{}
+		m["$inc"] = inc
+	}
+	inc[document.VersionField] = int64(1)
+	return m
+}
+
+// pipelineConverter is implemented by types that can be converted to a MongoDB
+// pipeline, such as spec.Pipeline.
+type pipelineConverter interface {
+	ToPipeline() ([]bson.M, error)
+}
+
+// normalizePipeline converts pipeline to canonical stages. $sort arguments keep
+// their key order.
+func normalizePipeline(pipeline any) ([]bson.M, error) {
+	var stages []bson.M
+	switch p := pipeline.(type) {
+	case nil:
+		return []bson.M{}, nil
+	case []bson.M:
+		stages = p
+	case []bson.D:
+		stages = make([]bson.M, len(p))
+		for i, stage := range p {
+			stages[i] = bson.M{}
+			for _, elem := range stage {
+				stages[i][elem.Key] = elem.Value
+			}
+		}
+	case pipelineConverter:
+		var err error
+		if stages, err = p.ToPipeline(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, repository.ErrInvalidFilter
+	}
+
+	out := make([]bson.M, len(stages))
+	for i, stage := range stages {
+		out[i] = make(bson.M, len(stage))
+		for name, arg := range stage {
+			if name == "$sort" {
+				out[i][name] = arg
+				continue
+			}
+			v, err := docstore.Canonical(arg)
+			if err != nil {
+				return nil, err
+			}
+			out[i][name] = v
+		}
+	}
+	return out, nil
+}
+
+func applyUpdateOptions(opts []repository.UpdateOption) repository.UpdateOptions {
+	var uo repository.UpdateOptions
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&uo)
+		}
+	}
+	return uo
+}
+
+func applyFindOptions(opts []repository.FindOption) repository.FindOptions {
+	var fo repository.FindOptions
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&fo)
+		}
+	}
+	return fo
+}
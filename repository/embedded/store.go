@@ -0,0 +1,190 @@
+// Package embeddedrepo implements repository.Repository on an embedded,
+// file-backed document store, so CLI tools, demos, and local development can
+// run mongox code without a MongoDB server.
+//
+// Filters, updates, sorts, and a subset of aggregation follow MongoDB
+// semantics: anything built with the spec package works the same way against
+// both repositories. Operators outside that subset fail with an error matching
+// ErrUnsupported instead of silently matching the wrong documents.
+//
+// Each collection is kept in memory and written through to <dir>/<name>.bson
+// after every write, as concatenated BSON documents. The files can be loaded
+// into a real server with mongorestore. The store is meant for a single process
+// and modest data sets; it has no indexes other than the unique _id.
+//
+// Example:
+//
+//	store, err := embeddedrepo.Open(".data")
+//	if err != nil {
+//	    return err
+//	}
+//	users, err := store.Collection("users")
+//	if err != nil {
+//	    return err
+//	}
+//
+//	var repo repository.Repository[User] = embeddedrepo.New[User](users)
+//	err = repo.InsertOne(ctx, &User{Name: "Ada"})
+package embeddedrepo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/dElCIoGio/mongox/internal/docstore"
+	"github.com/dElCIoGio/mongox/internal/match"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrUnsupported matches errors returned for query, update, or aggregation
+// operators the embedded store does not implement.
+var ErrUnsupported = match.ErrUnsupported
+
+// fileExt is the extension of collection files, matching mongodump output.
+const fileExt = ".bson"
+
+// Store is a set of named collections, optionally persisted to a directory.
+// It is safe for concurrent use.
+type Store struct {
+	dir   string
+	mu    sync.Mutex
+	colls map[string]*Collection
+}
+
+// Open returns a store persisted under dir, creating the directory if needed.
+// Collections are loaded from their files on first use.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("embeddedrepo: %w", err)
+	}
+	return &Store{dir: dir, colls: make(map[string]*Collection)}, nil
+}
+
+// Memory returns a store that is never written to disk, for tests and demos.
+func Memory() *Store {
+	return &Store{colls: make(map[string]*Collection)}
+}
+
+// Collection returns the named collection, loading it from disk the first
+// time it is requested. Names may not contain path separators.
+func (s *Store) Collection(name string) (*Collection, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("embeddedrepo: invalid collection name %q", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.colls[name]; ok {
+		return c, nil
+	}
+	c := &Collection{name: name, docs: docstore.New()}
+	if s.dir != "" {
+		c.path = filepath.Join(s.dir, name+fileExt)
+		docs, err := readFile(c.path)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.docs.Load(docs); err != nil {
+			return nil, fmt.Errorf("embeddedrepo: load %s: %w", c.path, err)
+		}
+	}
+	s.colls[name] = c
+	return c, nil
+}
+
+// Collection is one named set of documents.
+type Collection struct {
+	name string
+	path string // empty for in-memory stores
+
+	// mu serializes writes so the file always reflects the latest state.
+	mu   sync.Mutex
+	docs *docstore.Collection
+}
+
+// Name returns the collection name.
+func (c *Collection) Name() string {
+	return c.name
+}
+
+// write runs fn and persists the collection afterwards, even when fn fails
+// part way through, so the file matches what readers observe.
+func (c *Collection) write(fn func(*docstore.Collection) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err := fn(c.docs)
+	if c.path == "" {
+		return err
+	}
+	if ferr := writeFile(c.path, c.docs.All()); ferr != nil {
+		return errors.Join(err, ferr)
+	}
+	return err
+}
+
+// writeFile atomically replaces path with docs encoded as concatenated BSON.
+func writeFile(path string, docs []bson.M) error {
+	var buf bytes.Buffer
+	for _, d := range docs {
+		b, err := bson.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("embeddedrepo: encode: %w", err)
+		}
+		buf.Write(b)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("embeddedrepo: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("embeddedrepo: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("embeddedrepo: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("embeddedrepo: %w", err)
+	}
+	return nil
+}
+
+// readFile decodes a collection file. A missing file is an empty collection.
+func readFile(path string) ([]bson.M, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("embeddedrepo: %w", err)
+	}
+
+	var docs []bson.M
+	for len(b) > 0 {
+		if len(b) < 5 {
+			return nil, fmt.Errorf("embeddedrepo: %s: truncated document", path)
+		}
+		n := int(binary.LittleEndian.Uint32(b))
+		if n < 5 || n > len(b) {
+			return nil, fmt.Errorf("embeddedrepo: %s: truncated document", path)
+		}
+		var d bson.M
+		if err := bson.Unmarshal(b[:n], &d); err != nil {
+			return nil, fmt.Errorf("embeddedrepo: %s: %w", path, err)
+		}
+		docs = append(docs, d)
+		b = b[n:]
+	}
+	return docs, nil
+}
@@ -24,6 +24,19 @@ var (
 
 	// ErrNilUpdate is returned when a nil update is passed to an update operation.
 	ErrNilUpdate = errors.New("repository: nil update")
+
+	// ErrReferenced is returned when a delete is restricted because other documents still reference the target.
+	ErrReferenced = errors.New("repository: document is still referenced")
+
+	// ErrInvalidID is returned when an ID is neither an ObjectID nor its hex string form.
+	ErrInvalidID = errors.New("repository: invalid document ID")
+
+	// ErrInvalidBulkOp is returned when a typed bulk operation constructor rejects its arguments.
+	ErrInvalidBulkOp = errors.New("repository: invalid bulk operation")
+
+	// ErrVersionConflict is returned when a write to a versioned document expects a version
+	// that is no longer current because another writer changed the document first.
+	ErrVersionConflict = errors.New("repository: version conflict")
 )
 
 // ValidationError represents a validation error for a specific field.
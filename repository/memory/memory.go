@@ -0,0 +1,39 @@
+// Package memoryrepo provides an in-memory repository.Repository for unit tests.
+//
+// Filters, updates, sorts, and aggregations built with the spec package are
+// evaluated in process with the same semantics as against MongoDB, so services
+// written against repository.Repository can be tested without a server or
+// testcontainers. It shares its engine with embeddedrepo, including the set of
+// supported operators; see that package for details.
+//
+// Example:
+//
+//	repo := memoryrepo.New[User]()
+//	svc := NewUserService(repo) // accepts repository.Repository[User]
+//
+//	_ = repo.InsertOne(ctx, &User{Name: "Ada", Age: 36})
+//	n, _ := repo.Count(ctx, spec.Gt("age", 30))
+package memoryrepo
+
+import (
+	"github.com/dElCIoGio/mongox/repository"
+	embeddedrepo "github.com/dElCIoGio/mongox/repository/embedded"
+)
+
+// Re-export common errors for convenience.
+var (
+	ErrNotFound     = repository.ErrNotFound
+	ErrDuplicateKey = repository.ErrDuplicateKey
+	ErrUnsupported  = embeddedrepo.ErrUnsupported
+)
+
+// Repository is an in-memory repository.Repository[T].
+type Repository[T any] = embeddedrepo.Repository[T]
+
+// New returns an empty in-memory repository. Each call returns an independent
+// repository.
+func New[T any]() *Repository[T] {
+	// The name is valid, so Collection cannot fail on a memory store.
+	coll, _ := embeddedrepo.Memory().Collection("documents")
+	return embeddedrepo.New[T](coll)
+}
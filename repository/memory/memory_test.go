@@ -0,0 +1,67 @@
+package memoryrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+	memoryrepo "github.com/dElCIoGio/mongox/repository/memory"
+	"github.com/dElCIoGio/mongox/spec"
+)
+
+type Account struct {
+	document.Base `bson:",inline"`
+
+	Owner   string   `bson:"owner"`
+	Balance int      `bson:"balance"`
+	Tags    []string `bson:"tags,omitempty"`
+}
+
+func TestNew_EvaluatesSpecFiltersAndUpdates(t *testing.T) {
+	ctx := context.Background()
+	var repo repository.Repository[Account] = memoryrepo.New[Account]()
+
+	for _, a := range []*Account{
+		{Owner: "ada", Balance: 10},
+		{Owner: "bob", Balance: 50},
+		{Owner: "cy", Balance: 90},
+	} {
+		if err := repo.InsertOne(ctx, a); err != nil {
+			t.Fatalf("InsertOne: %v", err)
+		}
+	}
+
+	n, err := repo.Count(ctx, spec.Or(
+		spec.And(spec.Gt("balance", 20), spec.In("owner", []string{"bob", "cy"})),
+		spec.Eq("owner", "ada"),
+	))
+	if err != nil || n != 3 {
+		t.Fatalf("Count = %d, %v; want 3", n, err)
+	}
+
+	_, modified, err := repo.UpdateMany(ctx, spec.Gte("balance", 50), spec.Combine(
+		spec.Inc("balance", 5),
+		spec.Push("tags", "vip"),
+	))
+	if err != nil || modified != 2 {
+		t.Fatalf("UpdateMany: modified=%d err=%v", modified, err)
+	}
+	got, err := repo.FindOne(ctx, spec.Eq("owner", "bob"))
+	if err != nil {
+		t.Fatalf("FindOne: %v", err)
+	}
+	if got.Balance != 55 || len(got.Tags) != 1 || got.Tags[0] != "vip" {
+		t.Fatalf("unexpected document after update: %+v", got)
+	}
+
+	if _, err := repo.FindOne(ctx, spec.Eq("owner", "zed")); !errors.Is(err, memoryrepo.ErrNotFound) {
+		t.Fatalf("FindOne missing err = %v, want ErrNotFound", err)
+	}
+
+	// Repositories do not share state.
+	if n, _ := memoryrepo.New[Account]().Count(ctx, spec.And()); n != 0 {
+		t.Fatalf("new repository has %d documents, want 0", n)
+	}
+}
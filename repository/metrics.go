@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Operation names reported to an OperationObserver.
+const (
+	OpInsertOne  = "insert_one"
+	OpInsertMany = "insert_many"
+	OpFindOne    = "find_one"
+	OpFind       = "find"
+	OpCount      = "count"
+	OpUpdateOne  = "update_one"
+	OpUpdateMany = "update_many"
+	OpReplaceOne = "replace_one"
+	OpDeleteOne  = "delete_one"
+	OpDeleteMany = "delete_many"
+	OpAggregate  = "aggregate"
+	OpBulkWrite  = "bulk_write"
+
+	OpFindOneAndUpdate  = "find_one_and_update"
+	OpFindOneAndReplace = "find_one_and_replace"
+	OpFindOneAndDelete  = "find_one_and_delete"
+)
+
+// ErrTimeout is returned when an operation exceeds its time limit, either the
+// context deadline or the server-side maxTimeMS.
+var ErrTimeout = errors.New("repository: operation timed out")
+
+// OperationObserver receives the outcome of every repository operation.
+// Implementations must be safe for concurrent use.
+type OperationObserver interface {
+	// ObserveOperation is called after an operation completes with its name
+	// (one of the Op* constants), duration, and resulting error (nil on success).
+	ObserveOperation(op string, d time.Duration, err error)
+}
+
+// IsTimeout reports whether err is a timeout: ErrTimeout or a context deadline.
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// DefaultLatencyBuckets are the histogram bucket upper bounds used by NewHistogram
+// when no bounds are given.
+var DefaultLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// Histogram is an OperationObserver that records per-operation latency
+// distributions along with error and timeout counts.
+// It is safe for concurrent use.
+//
+// Example:
+//
+//	hist := repository.NewHistogram()
+//	repo := mongorepo.New[User](coll, mongorepo.WithObserver(hist))
+//	...
+//	snap := hist.Snapshot()[repository.OpFind]
+//	log.Printf("find p99=%v timeouts=%d", snap.Quantile(0.99), snap.Timeouts)
+type Histogram struct {
+	bounds []time.Duration
+
+	mu  sync.Mutex
+	ops map[string]*HistogramSnapshot
+}
+
+// HistogramSnapshot is a point-in-time copy of the statistics for one operation.
+type HistogramSnapshot struct {
+	// Bounds are the bucket upper bounds (inclusive).
+	Bounds []time.Duration
+
+	// Counts holds the number of observations per bucket. It has one more entry
+	// than Bounds; the last entry counts observations above the largest bound.
+	Counts []uint64
+
+	// Count is the total number of observations.
+	Count uint64
+
+	// Sum is the total duration of all observations.
+	Sum time.Duration
+
+	// Errors is the number of operations that returned an error (including timeouts).
+	Errors uint64
+
+	// Timeouts is the number of operations that timed out.
+	Timeouts uint64
+}
+
+// NewHistogram creates a Histogram with the given bucket upper bounds.
+// If no bounds are given, DefaultLatencyBuckets is used.
+func NewHistogram(bounds ...time.Duration) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultLatencyBuckets
+	}
+	sorted := append([]time.Duration(nil), bounds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return &Histogram{bounds: sorted, ops: make(map[string]*HistogramSnapshot)}
+}
+
+// ObserveOperation records an operation outcome.
+func (h *Histogram) ObserveOperation(op string, d time.Duration, err error) {
+	idx := sort.Search(len(h.bounds), func(i int) bool { return d <= h.bounds[i] })
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.ops[op]
+	if !ok {
+		s = &HistogramSnapshot{Bounds: h.bounds, Counts: make([]uint64, len(h.bounds)+1)}
+		h.ops[op] = s
+	}
+	s.Counts[idx]++
+	s.Count++
+	s.Sum += d
+	if err != nil {
+		s.Errors++
+		if IsTimeout(err) {
+			s.Timeouts++
+		}
+	}
+}
+
+// Snapshot returns a copy of the statistics for every observed operation.
+func (h *Histogram) Snapshot() map[string]HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	out := make(map[string]HistogramSnapshot, len(h.ops))
+	for op, s := range h.ops {
+		cp := *s
+		cp.Counts = append([]uint64(nil), s.Counts...)
+		out[op] = cp
+	}
+	return out
+}
+
+// Reset discards all recorded observations.
+func (h *Histogram) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ops = make(map[string]*HistogramSnapshot)
+}
+
+// Mean returns the average observed duration, or 0 if there are no observations.
+func (s HistogramSnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile returns an upper-bound estimate of the q-th quantile (0 < q <= 1):
+// the upper bound of the bucket containing it. Observations above the largest
+// bound report that bound. Returns 0 if there are no observations.
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 || len(s.Bounds) == 0 {
+		return 0
+	}
+	// Nearest-rank: the smallest rank covering at least q of the observations.
+	target := uint64(math.Ceil(q * float64(s.Count)))
+	if target == 0 {
+		target = 1
+	}
+	var cumulative uint64
+	for i, c := range s.Counts {
+		cumulative += c
+		if cumulative >= target {
+			if i >= len(s.Bounds) {
+				return s.Bounds[len(s.Bounds)-1]
+			}
+			return s.Bounds[i]
+		}
+	}
+	return s.Bounds[len(s.Bounds)-1]
+}
@@ -0,0 +1,108 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+)
+
+func TestHistogram_ObserveOperation(t *testing.T) {
+	h := repository.NewHistogram(10*time.Millisecond, 100*time.Millisecond)
+
+	h.ObserveOperation(repository.OpFind, 5*time.Millisecond, nil)
+	h.ObserveOperation(repository.OpFind, 50*time.Millisecond, nil)
+	h.ObserveOperation(repository.OpFind, time.Second, context.DeadlineExceeded)
+	h.ObserveOperation(repository.OpCount, time.Millisecond, errors.New("boom"))
+
+	snap := h.Snapshot()
+
+	find := snap[repository.OpFind]
+	if find.Count != 3 {
+		t.Fatalf("Count = %d, want 3", find.Count)
+	}
+	if want := []uint64{1, 1, 1}; fmt.Sprint(find.Counts) != fmt.Sprint(want) {
+		t.Fatalf("Counts = %v, want %v", find.Counts, want)
+	}
+	if find.Errors != 1 || find.Timeouts != 1 {
+		t.Fatalf("Errors = %d, Timeouts = %d, want 1 and 1", find.Errors, find.Timeouts)
+	}
+
+	count := snap[repository.OpCount]
+	if count.Errors != 1 || count.Timeouts != 0 {
+		t.Fatalf("count Errors = %d, Timeouts = %d, want 1 and 0", count.Errors, count.Timeouts)
+	}
+}
+
+func TestHistogramSnapshot_Quantile(t *testing.T) {
+	h := repository.NewHistogram(10*time.Millisecond, 100*time.Millisecond)
+	for i := 0; i < 9; i++ {
+		h.ObserveOperation(repository.OpFind, time.Millisecond, nil)
+	}
+	h.ObserveOperation(repository.OpFind, 80*time.Millisecond, nil)
+
+	snap := h.Snapshot()[repository.OpFind]
+
+	if got := snap.Quantile(0.5); got != 10*time.Millisecond {
+		t.Errorf("Quantile(0.5) = %v, want 10ms", got)
+	}
+	if got := snap.Quantile(1); got != 100*time.Millisecond {
+		t.Errorf("Quantile(1) = %v, want 100ms", got)
+	}
+	if got := snap.Mean(); got != 8900*time.Microsecond {
+		t.Errorf("Mean() = %v, want 8.9ms", got)
+	}
+}
+
+func TestHistogramSnapshot_QuantileNearestRank(t *testing.T) {
+	h := repository.NewHistogram(10*time.Millisecond, 100*time.Millisecond)
+	for i := 0; i < 49; i++ {
+		h.ObserveOperation(repository.OpFind, time.Millisecond, nil)
+	}
+	h.ObserveOperation(repository.OpFind, 80*time.Millisecond, nil)
+
+	snap := h.Snapshot()[repository.OpFind]
+
+	// P99 over 50 samples is rank 50, the slow observation.
+	if got := snap.Quantile(0.99); got != 100*time.Millisecond {
+		t.Errorf("Quantile(0.99) = %v, want 100ms", got)
+	}
+	if got := snap.Quantile(0.98); got != 10*time.Millisecond {
+		t.Errorf("Quantile(0.98) = %v, want 10ms", got)
+	}
+}
+
+func TestHistogram_Reset(t *testing.T) {
+	h := repository.NewHistogram()
+	h.ObserveOperation(repository.OpFind, time.Millisecond, nil)
+	h.Reset()
+
+	if n := len(h.Snapshot()); n != 0 {
+		t.Fatalf("expected empty snapshot after Reset, got %d operations", n)
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"ErrTimeout", repository.ErrTimeout, true},
+		{"wrapped ErrTimeout", fmt.Errorf("find: %w", repository.ErrTimeout), true},
+		{"deadline", context.DeadlineExceeded, true},
+		{"other", repository.ErrNotFound, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := repository.IsTimeout(tt.err); got != tt.want {
+				t.Fatalf("IsTimeout(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
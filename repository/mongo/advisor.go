@@ -0,0 +1,404 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrAdvisorDisabled is returned by SuggestIndexes when the repository was not
+// created with WithIndexAdvisor.
+var ErrAdvisorDisabled = errors.New("mongorepo: index advisor is not enabled")
+
+// maxShapes bounds the number of distinct filter shapes an advisor keeps, so
+// repositories queried with generated field names cannot grow it without limit.
+const maxShapes = 1000
+
+// IndexSuggestion is a candidate index proposed by SuggestIndexes.
+type IndexSuggestion struct {
+	// Keys is the index key pattern, ready for mongo.IndexModel.
+	Keys bson.D
+
+	// Queries is the number of observed operations the index would serve.
+	Queries int64
+
+	// Shape describes the query shape that produced the suggestion, e.g.
+	// "eq(status,tenant_id) sort(created_at:-1) range(total)".
+	Shape string
+}
+
+// WithIndexAdvisor records the shape of every filter and sort the repository
+// runs, so SuggestIndexes can propose indexes from real access patterns.
+// It is meant for development and staging: recording costs an extra encode of
+// each filter, so leave it off in production.
+//
+// Behavior:
+//   - Find, FindOne, FindInto, Count, and the update, replace, and delete
+//     methods are recorded; aggregations and bulk writes are not
+//   - Only field names and operator kinds are kept, never values
+//   - Branches of $or, $nor, and $expr are ignored
+//
+// Example:
+//
+//	repo := mongorepo.New[Order](coll, mongorepo.WithIndexAdvisor())
+//	// ... exercise the application ...
+//	suggestions, err := repo.SuggestIndexes(ctx)
+func WithIndexAdvisor() Option {
+	return func(s *settings) { s.advisor = &indexAdvisor{shapes: map[string]*queryShape{}} }
+}
+
+// indexAdvisor counts the distinct filter shapes seen by a repository.
+type indexAdvisor struct {
+	mu     sync.Mutex
+	shapes map[string]*queryShape
+}
+
+// queryShape is a filter reduced to the fields it constrains, grouped the way
+// the equality, sort, range rule orders index keys.
+type queryShape struct {
+	equality []string // sorted
+	sort     bson.D   // in sort order, values are 1 or -1
+	rng      []string // sorted
+	count    int64
+}
+
+// record adds one observation of filter and sort. It is a no-op on a nil advisor.
+func (a *indexAdvisor) record(filter, sortSpec any) {
+	if a == nil {
+		return
+	}
+	shape, ok := shapeOf(filter, sortSpec)
+	if !ok {
+		return
+	}
+	key := shape.String()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if existing, ok := a.shapes[key]; ok {
+		existing.count++
+		return
+	}
+	if len(a.shapes) >= maxShapes {
+		return
+	}
+	shape.count = 1
+	a.shapes[key] = shape
+}
+
+// snapshot returns a copy of the recorded shapes.
+func (a *indexAdvisor) snapshot() []queryShape {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]queryShape, 0, len(a.shapes))
+	for _, s := range a.shapes {
+		out = append(out, *s)
+	}
+	return out
+}
+
+// SuggestIndexes proposes indexes for the filter shapes recorded since the
+// repository was created. Keys follow the equality, sort, range rule; shapes
+// already served by an existing index prefix, and shapes that only match on
+// _id, are left out. Suggestions are ordered by the number of queries they serve.
+//
+// Suggestions are a starting point: check field selectivity and the write cost
+// of each new index before creating it.
+//
+// Example:
+//
+//	suggestions, err := repo.SuggestIndexes(ctx)
+//	for _, s := range suggestions {
+//	    log.Printf("%d queries: %v (%s)", s.Queries, s.Keys, s.Shape)
+//	}
+func (r *MongoRepository[T]) SuggestIndexes(ctx context.Context) ([]IndexSuggestion, error) {
+	if r.settings.advisor == nil {
+		return nil, ErrAdvisorDisabled
+	}
+
+	cur, err := r.coll.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var specs []struct {
+		Key bson.D `bson:"key"`
+	}
+	if err := cur.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+	existing := make([]bson.D, len(specs))
+	for i, s := range specs {
+		existing[i] = s.Key
+	}
+
+	return suggestIndexes(r.settings.advisor.snapshot(), existing), nil
+}
+
+// suggestIndexes turns recorded shapes into suggestions, dropping shapes served
+// by an existing index and folding shapes served by a longer candidate into it.
+func suggestIndexes(shapes []queryShape, existing []bson.D) []IndexSuggestion {
+	// Longest candidates first, so shorter ones can be folded into them.
+	sort.Slice(shapes, func(i, j int) bool {
+		li, lj := shapes[i].width(), shapes[j].width()
+		if li != lj {
+			return li > lj
+		}
+		return shapes[i].String() < shapes[j].String()
+	})
+
+	var kept []queryShape
+next:
+	for _, s := range shapes {
+		if s.onlyID() {
+			continue
+		}
+		for _, idx := range existing {
+			if s.servedBy(idx) {
+				continue next
+			}
+		}
+		for i := range kept {
+			if s.servedBy(kept[i].keys()) {
+				kept[i].count += s.count
+				continue next
+			}
+		}
+		kept = append(kept, s)
+	}
+
+	out := make([]IndexSuggestion, len(kept))
+	for i, s := range kept {
+		out[i] = IndexSuggestion{Keys: s.keys(), Queries: s.count, Shape: s.String()}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Queries > out[j].Queries })
+	return out
+}
+
+// keys returns the index key pattern for the shape.
+func (s queryShape) keys() bson.D {
+	eq, srt, rng := s.segments()
+	keys := make(bson.D, 0, len(eq)+len(srt)+len(rng))
+	keys = append(keys, eq...)
+	keys = append(keys, srt...)
+	return append(keys, rng...)
+}
+
+// segments splits the index keys into the equality, sort, and range groups,
+// listing each field once in the first group that uses it.
+func (s queryShape) segments() (eq, srt, rng bson.D) {
+	seen := map[string]bool{}
+	for _, f := range s.equality {
+		eq = append(eq, bson.E{Key: f, Value: 1})
+		seen[f] = true
+	}
+	for _, e := range s.sort {
+		if !seen[e.Key] {
+			srt = append(srt, e)
+			seen[e.Key] = true
+		}
+	}
+	for _, f := range s.rng {
+		if !seen[f] {
+			rng = append(rng, bson.E{Key: f, Value: 1})
+		}
+	}
+	return eq, srt, rng
+}
+
+func (s queryShape) width() int { return len(s.keys()) }
+
+func (s queryShape) onlyID() bool {
+	return s.width() == 0 || len(s.equality) == 1 && s.equality[0] == "_id" && len(s.sort) == 0 && len(s.rng) == 0
+}
+
+// servedBy reports whether an index with the given key pattern starts with the
+// shape's keys: equality fields in any order, then the sort fields in order
+// (or all reversed), then the range fields in any order.
+func (s queryShape) servedBy(index bson.D) bool {
+	eq, srt, rng := s.segments()
+	if len(index) < len(eq)+len(srt)+len(rng) {
+		return false
+	}
+	if !sameFields(index[:len(eq)], eq) {
+		return false
+	}
+
+	index = index[len(eq):]
+	forward, backward := true, true
+	for i, e := range srt {
+		if index[i].Key != e.Key {
+			return false
+		}
+		d := direction(index[i].Value)
+		forward = forward && d == direction(e.Value)
+		backward = backward && d == -direction(e.Value)
+	}
+	if !forward && !backward {
+		return false
+	}
+
+	return sameFields(index[len(srt):len(srt)+len(rng)], rng)
+}
+
+// sameFields reports whether a and b hold the same field names, in any order.
+func sameFields(a, b bson.D) bool {
+	names := make(map[string]bool, len(b))
+	for _, e := range b {
+		names[e.Key] = true
+	}
+	for _, e := range a {
+		if !names[e.Key] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+// String renders the shape without values, e.g. "eq(a,b) sort(c:-1) range(d)".
+func (s queryShape) String() string {
+	var parts []string
+	if len(s.equality) > 0 {
+		parts = append(parts, "eq("+strings.Join(s.equality, ",")+")")
+	}
+	if len(s.sort) > 0 {
+		keys := make([]string, len(s.sort))
+		for i, e := range s.sort {
+			keys[i] = fmt.Sprintf("%s:%d", e.Key, direction(e.Value))
+		}
+		parts = append(parts, "sort("+strings.Join(keys, ",")+")")
+	}
+	if len(s.rng) > 0 {
+		parts = append(parts, "range("+strings.Join(s.rng, ",")+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+// shapeOf reduces a normalized filter and sort to a query shape. It reports
+// false when the filter cannot be encoded.
+func shapeOf(filter, sortSpec any) (*queryShape, bool) {
+	if filter == nil {
+		filter = bson.D{}
+	}
+	raw, err := bson.Marshal(filter)
+	if err != nil {
+		return nil, false
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, false
+	}
+
+	eq, rng := map[string]bool{}, map[string]bool{}
+	collectFields(doc, eq, rng)
+	for f := range eq {
+		delete(rng, f)
+	}
+
+	s := &queryShape{equality: sortedKeys(eq), rng: sortedKeys(rng), sort: sortKeys(sortSpec)}
+	return s, true
+}
+
+// collectFields classifies the fields of a filter document as equality or
+// range predicates, descending into $and.
+func collectFields(doc bson.D, eq, rng map[string]bool) {
+	for _, e := range doc {
+		if strings.HasPrefix(e.Key, "$") {
+			if e.Key == "$and" {
+				if clauses, ok := e.Value.(bson.A); ok {
+					for _, c := range clauses {
+						if d, ok := c.(bson.D); ok {
+							collectFields(d, eq, rng)
+						}
+					}
+				}
+			}
+			continue
+		}
+
+		ops, ok := e.Value.(bson.D)
+		if !ok || len(ops) == 0 || !strings.HasPrefix(ops[0].Key, "$") {
+			eq[e.Key] = true
+			continue
+		}
+		for _, op := range ops {
+			switch op.Key {
+			case "$eq", "$in":
+				eq[e.Key] = true
+			case "$options", "$comment":
+			default:
+				rng[e.Key] = true
+			}
+		}
+	}
+}
+
+// sortKeys converts a sort specification into ordered keys with 1 or -1 values.
+func sortKeys(sortSpec any) bson.D {
+	if sortSpec == nil {
+		return nil
+	}
+	var d bson.D
+	switch v := sortSpec.(type) {
+	case bson.D:
+		d = v
+	default:
+		raw, err := bson.Marshal(sortSpec)
+		if err != nil {
+			return nil
+		}
+		if err := bson.Unmarshal(raw, &d); err != nil {
+			return nil
+		}
+	}
+	out := make(bson.D, 0, len(d))
+	for _, e := range d {
+		dir := direction(e.Value)
+		if dir == 0 {
+			// Text score and other meta sorts cannot use a regular index.
+			continue
+		}
+		out = append(out, bson.E{Key: e.Key, Value: dir})
+	}
+	return out
+}
+
+// direction returns 1 or -1 for a numeric sort or index direction, and 0 otherwise.
+func direction(v any) int {
+	var f float64
+	switch n := v.(type) {
+	case int:
+		f = float64(n)
+	case int32:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	case float64:
+		f = n
+	default:
+		return 0
+	}
+	switch {
+	case f > 0:
+		return 1
+	case f < 0:
+		return -1
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]bool) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,28 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSuggestIndexes_RequiresAdvisor(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; SuggestIndexes must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	repo := mongorepo.New[invoiceRow](client.Database("testdb").Collection("invoices"))
+	if _, err := repo.SuggestIndexes(ctx); !errors.Is(err, mongorepo.ErrAdvisorDisabled) {
+		t.Fatalf("expected ErrAdvisorDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package mongorepo
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// pipelineSource is implemented by repositories that can run aggregation
+// pipelines with their own scoping (e.g. soft delete) and settings.
+type pipelineSource interface {
+	aggregateCursor(ctx context.Context, pipeline any) (*mongo.Cursor, error)
+	repoSettings() settings
+}
+
+func (r *MongoRepository[T]) aggregateCursor(ctx context.Context, pipeline any) (*mongo.Cursor, error) {
+	p, err := normalizePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return r.aggregate(ctx, p)
+}
+
+func (r *SoftDeleteRepository[T]) aggregateCursor(ctx context.Context, pipeline any) (*mongo.Cursor, error) {
+	p, err := r.scopePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return r.MongoRepository.aggregateCursor(ctx, p)
+}
+
+// AggregateAs runs the pipeline on repo's collection and decodes each result
+// into R, for pipelines whose output is not shaped like the repository's
+// documents, such as $group results. R's AfterLoad hook runs on each result if
+// it has one. repo is a *MongoRepository or *SoftDeleteRepository; soft-delete
+// repositories only aggregate non-deleted documents.
+//
+// The pipeline can be []bson.M, []bson.D, or a Pipeline builder.
+//
+// Example:
+//
+//	type CategoryTotal struct {
+//	    Category string  `bson:"_id"`
+//	    Total    float64 `bson:"total"`
+//	    Count    int     `bson:"count"`
+//	}
+//
+//	totals, err := mongorepo.AggregateAs[CategoryTotal](ctx, orders, spec.NewPipeline().
+//	    Match(spec.Eq("status", "paid")).
+//	    GroupBy("$category", bson.M{"total": spec.Sum("$amount"), "count": spec.Sum(1)}))
+func AggregateAs[R any](ctx context.Context, repo pipelineSource, pipeline any) (_ []R, err error) {
+	s := repo.repoSettings()
+	defer func(start time.Time) {
+		err = wrapTimeout(err)
+		s.observe(repository.OpAggregate, start, err)
+	}(time.Now())
+	if err = s.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	cur, err := repo.aggregateCursor(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var results []R
+	if err := decodeAll[R](ctx, s, cur, &results); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		if err := s.afterLoad(ctx, &results[i]); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// AggregatePaginated runs the pipeline and returns one page of its results
+// together with the total number of results, in a single round trip: the
+// pipeline is followed by a $facet stage with a $skip/$limit branch and a
+// $count branch. page and perPage are normalized as in FindPaginated.
+//
+// The pipeline must not end with $out or $merge. Sort it for stable pages.
+//
+// Example:
+//
+//	page, err := repo.AggregatePaginated(ctx, spec.NewPipeline().
+//	    Match(spec.Eq("status", "paid")).
+//	    GroupBy("$customer_id", bson.M{"total": spec.Sum("$amount")}).
+//	    SortBy("total", -1), 2, 20)
+func (r *MongoRepository[T]) AggregatePaginated(ctx context.Context, pipeline any, page, perPage int) (*repository.Page[bson.M], error) {
+	return AggregatePaginatedAs[bson.M](ctx, r, pipeline, page, perPage)
+}
+
+// AggregatePaginated pages the pipeline's results over non-deleted documents only.
+func (r *SoftDeleteRepository[T]) AggregatePaginated(ctx context.Context, pipeline any, page, perPage int) (*repository.Page[bson.M], error) {
+	return AggregatePaginatedAs[bson.M](ctx, r, pipeline, page, perPage)
+}
+
+// AggregatePaginatedAs is AggregatePaginated with the items decoded into R, as
+// AggregateAs does.
+//
+// Example:
+//
+//	page, err := mongorepo.AggregatePaginatedAs[CustomerTotal](ctx, repo, pipeline, 1, 20)
+func AggregatePaginatedAs[R any](ctx context.Context, repo pipelineSource, pipeline any, page, perPage int) (_ *repository.Page[R], err error) {
+	s := repo.repoSettings()
+	defer func(start time.Time) {
+		err = wrapTimeout(err)
+		s.observe(repository.OpAggregate, start, err)
+	}(time.Now())
+	if err = s.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	p, err := normalizePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	pagOpts := s.pagination(page, perPage)
+	pagOpts.Normalize()
+	p = append(slices.Clip(p), bson.M{"$facet": bson.M{
+		"items": []bson.M{{"$skip": pagOpts.Skip()}, {"$limit": pagOpts.Limit()}},
+		"total": []bson.M{{"$count": "n"}},
+	}})
+
+	cur, err := repo.aggregateCursor(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var out struct {
+		Items []bson.Raw `bson:"items"`
+		Total []struct {
+			N int64 `bson:"n"`
+		} `bson:"total"`
+	}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&out); err != nil {
+			return nil, err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+	items := make([]R, len(out.Items))
+	for i := range out.Items {
+		if err := open[R](ctx, s, out.Items[i], &items[i]); err != nil {
+			return nil, err
+		}
+		if err := s.afterLoad(ctx, &items[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var total int64
+	if len(out.Total) > 0 {
+		total = out.Total[0].N
+	}
+	totalPages := repository.CalculateTotalPages(total, pagOpts.PerPage)
+	return &repository.Page[R]{
+		Items:      items,
+		Total:      total,
+		Page:       pagOpts.Page,
+		PerPage:    pagOpts.PerPage,
+		TotalPages: totalPages,
+		HasNext:    pagOpts.Page < totalPages,
+		HasPrev:    pagOpts.Page > 1,
+	}, nil
+}
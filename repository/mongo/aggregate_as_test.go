@@ -0,0 +1,59 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestAggregateAs_RejectsInvalidPipeline(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; an invalid pipeline must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	coll := client.Database("testdb").Collection("invoices")
+	if _, err := mongorepo.AggregateAs[struct{}](ctx, mongorepo.New[invoiceRow](coll), "not a pipeline"); !errors.Is(err, repository.ErrInvalidFilter) {
+		t.Fatalf("expected ErrInvalidFilter, got %v", err)
+	}
+	if _, err := mongorepo.AggregateAs[struct{}](ctx, mongorepo.NewSoftDelete[invoiceRow](coll), 42); !errors.Is(err, repository.ErrInvalidFilter) {
+		t.Fatalf("soft delete: expected ErrInvalidFilter, got %v", err)
+	}
+
+	bad := spec.NewPipeline().Out("archive").Limit(1)
+	if _, err := mongorepo.New[invoiceRow](coll).Aggregate(ctx, bad); !errors.Is(err, spec.ErrInvalidPipeline) {
+		t.Fatalf("expected ErrInvalidPipeline, got %v", err)
+	}
+	if _, err := mongorepo.AggregateAs[struct{}](ctx, mongorepo.New[invoiceRow](coll), bad); !errors.Is(err, spec.ErrInvalidPipeline) {
+		t.Fatalf("AggregateAs: expected ErrInvalidPipeline, got %v", err)
+	}
+}
+
+func TestAggregatePaginated_RejectsInvalidPipeline(t *testing.T) {
+	ctx := context.Background()
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	coll := client.Database("testdb").Collection("invoices")
+	if _, err := mongorepo.New[invoiceRow](coll).AggregatePaginated(ctx, "not a pipeline", 1, 10); !errors.Is(err, repository.ErrInvalidFilter) {
+		t.Fatalf("expected ErrInvalidFilter, got %v", err)
+	}
+	if _, err := mongorepo.AggregatePaginatedAs[struct{}](ctx, mongorepo.NewSoftDelete[invoiceRow](coll), 42, 1, 10); !errors.Is(err, repository.ErrInvalidFilter) {
+		t.Fatalf("soft delete: expected ErrInvalidFilter, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package mongorepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ObjectID converts id to an ObjectID. It accepts a primitive.ObjectID, a
+// non-nil *primitive.ObjectID, or the 24-character hex string form, so IDs
+// taken from URLs and JSON payloads can be used directly. Anything else,
+// including the zero ObjectID, returns an error matching ErrInvalidID.
+//
+// Example:
+//
+//	oid, err := mongorepo.ObjectID(r.PathValue("id"))
+func ObjectID(id any) (primitive.ObjectID, error) {
+	var oid primitive.ObjectID
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		oid = v
+	case *primitive.ObjectID:
+		if v != nil {
+			oid = *v
+		}
+	case string:
+		parsed, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return primitive.NilObjectID, fmt.Errorf("%w: %q", ErrInvalidID, v)
+		}
+		oid = parsed
+	default:
+		return primitive.NilObjectID, fmt.Errorf("%w: unsupported type %T", ErrInvalidID, id)
+	}
+	if oid.IsZero() {
+		return primitive.NilObjectID, fmt.Errorf("%w: zero ObjectID", ErrInvalidID)
+	}
+	return oid, nil
+}
+
+func idFilter(id any) (bson.M, error) {
+	oid, err := ObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"_id": oid}, nil
+}
+
+// FindByID finds the document with the given ID, an ObjectID or its hex
+// string. It returns ErrNotFound if there is none.
+//
+// Example:
+//
+//	user, err := repo.FindByID(ctx, r.PathValue("id"))
+func (r *MongoRepository[T]) FindByID(ctx context.Context, id any, opts ...repository.FindOption) (*T, error) {
+	oid, err := ObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.findCached(ctx, cacheKey{id: oid}, bson.M{"_id": oid}, opts)
+}
+
+// UpdateByID applies update to the document with the given ID, as UpdateOne does.
+//
+// Example:
+//
+//	_, modified, err := repo.UpdateByID(ctx, id, spec.Set("status", "active"))
+func (r *MongoRepository[T]) UpdateByID(ctx context.Context, id any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	f, err := idFilter(id)
+	if err != nil {
+		return 0, 0, err
+	}
+	return r.UpdateOne(ctx, f, update, opts...)
+}
+
+// DeleteByID deletes the document with the given ID and returns the number
+// deleted (0 or 1). References are enforced as in DeleteOne.
+func (r *MongoRepository[T]) DeleteByID(ctx context.Context, id any) (int64, error) {
+	f, err := idFilter(id)
+	if err != nil {
+		return 0, err
+	}
+	return r.DeleteOne(ctx, f)
+}
+
+// ExistsByID reports whether a document with the given ID exists.
+func (r *MongoRepository[T]) ExistsByID(ctx context.Context, id any) (bool, error) {
+	f, err := idFilter(id)
+	if err != nil {
+		return false, err
+	}
+	n, err := r.Count(ctx, f)
+	return n > 0, err
+}
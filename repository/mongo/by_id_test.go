@@ -0,0 +1,28 @@
+package mongorepo_test
+
+import (
+	"errors"
+	"testing"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestObjectID(t *testing.T) {
+	oid := primitive.NewObjectID()
+
+	for _, in := range []any{oid, &oid, oid.Hex()} {
+		got, err := mongorepo.ObjectID(in)
+		if err != nil || got != oid {
+			t.Fatalf("ObjectID(%T) = %v, %v; want %v", in, got, err, oid)
+		}
+	}
+
+	var nilPtr *primitive.ObjectID
+	for _, in := range []any{"not-an-id", "", primitive.NilObjectID, nilPtr, 42, nil} {
+		if _, err := mongorepo.ObjectID(in); !errors.Is(err, mongorepo.ErrInvalidID) {
+			t.Fatalf("ObjectID(%#v) err = %v, want ErrInvalidID", in, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrCacheDisabled is returned by Warm when the repository was not created
+// with WithCache.
+var ErrCacheDisabled = errors.New("mongorepo: cache is not enabled")
+
+// WithCache caches the documents returned by FindByID for ttl, so hot lookups
+// such as the current user or tenant skip the database.
+//
+// Behavior:
+//   - Only FindByID without find options is cached; misses are not cached
+//   - Any write through the repository clears the whole cache
+//   - Writes by other processes become visible once the entry expires
+//   - Cached documents are shallow copies; don't mutate their slices or maps
+//   - A non-positive ttl disables the cache
+//   - Warm fills the cache ahead of time; see WithWarmup
+//
+// Example:
+//
+//	tenants := mongorepo.New[Tenant](coll, mongorepo.WithCache(30*time.Second))
+func WithCache(ttl time.Duration) Option {
+	return func(s *settings) {
+		if ttl <= 0 {
+			s.cache = nil
+			return
+		}
+		s.cache = &idCache{ttl: ttl, entries: make(map[cacheKey]cacheEntry), now: time.Now}
+	}
+}
+
+// cacheKey identifies a cached lookup. active separates lookups that exclude
+// soft-deleted documents from those that don't.
+type cacheKey struct {
+	id     primitive.ObjectID
+	active bool
+}
+
+type cacheEntry struct {
+	doc     any
+	expires time.Time
+}
+
+// idCache is a TTL cache of documents by _id. A nil *idCache is a disabled
+// cache.
+type idCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[cacheKey]cacheEntry
+}
+
+func (c *idCache) get(k cacheKey) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, k)
+		return nil, false
+	}
+	return e.doc, true
+}
+
+func (c *idCache) put(k cacheKey, doc any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[k] = cacheEntry{doc: doc, expires: c.now().Add(c.ttl)}
+}
+
+func (c *idCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// isWriteOp reports whether op can change stored documents, and so must clear
+// the cache.
+func isWriteOp(op string) bool {
+	switch op {
+	case repository.OpUpdateOne, repository.OpUpdateMany, repository.OpReplaceOne,
+		repository.OpDeleteOne, repository.OpDeleteMany, repository.OpBulkWrite,
+		repository.OpFindOneAndUpdate, repository.OpFindOneAndReplace, repository.OpFindOneAndDelete:
+		return true
+	}
+	return false
+}
+
+// findCached runs FindOne with filter, the lookup of id, through the cache.
+func (r *MongoRepository[T]) findCached(ctx context.Context, k cacheKey, filter any, opts []repository.FindOption) (*T, error) {
+	c := r.settings.cache
+	if c == nil || len(opts) > 0 {
+		return r.FindOne(ctx, filter, opts...)
+	}
+	if v, ok := c.get(k); ok {
+		doc := v.(T)
+		return &doc, nil
+	}
+	doc, err := r.FindOne(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	c.put(k, *doc)
+	return doc, nil
+}
+
+// WithWarmup declares the filters Warm runs to fill the WithCache cache, so
+// the documents a service looks up right after starting are already cached.
+// Filters accept the same values as Find.
+//
+// Example:
+//
+//	tenants := mongorepo.New[Tenant](coll,
+//	    mongorepo.WithCache(5*time.Minute),
+//	    mongorepo.WithWarmup(spec.Eq("plan", "enterprise"), spec.Eq("featured", true)),
+//	)
+//	go func() {
+//	    if _, err := tenants.Warm(ctx); err != nil {
+//	        log.Printf("cache warmup: %v", err)
+//	    }
+//	}()
+func WithWarmup(filters ...any) Option {
+	return func(s *settings) { s.warmup = append(s.warmup, filters...) }
+}
+
+// Warm runs the WithWarmup filters and caches every document they match, as
+// FindByID would, and returns the number of documents cached. Documents whose
+// _id is not an ObjectID are skipped. It returns ErrCacheDisabled without
+// WithCache.
+//
+// Warmed entries expire and are cleared by writes like any other, so call
+// Warm at startup, before serving traffic or in the background.
+func (r *MongoRepository[T]) Warm(ctx context.Context) (int, error) {
+	return r.warm(ctx, r.FindEach, false)
+}
+
+// Warm caches the non-deleted documents matched by the WithWarmup filters,
+// for both FindByID and the embedded MongoRepository's FindByID.
+func (r *SoftDeleteRepository[T]) Warm(ctx context.Context) (int, error) {
+	return r.warm(ctx, r.FindEach, true)
+}
+
+// warm caches the documents each yields for the warmup filters. active also
+// caches them for lookups that exclude soft-deleted documents.
+func (r *MongoRepository[T]) warm(ctx context.Context, each func(context.Context, any, func(*T) error, ...repository.FindOption) error, active bool) (int, error) {
+	c := r.settings.cache
+	if c == nil {
+		return 0, ErrCacheDisabled
+	}
+	n := 0
+	for _, filter := range r.settings.warmup {
+		err := each(ctx, filter, func(doc *T) error {
+			raw, err := bson.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			oid, ok := bson.Raw(raw).Lookup("_id").ObjectIDOK()
+			if !ok {
+				return nil
+			}
+			c.put(cacheKey{id: oid}, *doc)
+			if active {
+				c.put(cacheKey{id: oid, active: true}, *doc)
+			}
+			n++
+			return nil
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
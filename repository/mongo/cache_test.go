@@ -0,0 +1,31 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestWarm_RequiresCache(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; Warm must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	coll := client.Database("testdb").Collection("invoices")
+	if _, err := mongorepo.New[invoiceRow](coll, mongorepo.WithWarmup(nil)).Warm(ctx); !errors.Is(err, mongorepo.ErrCacheDisabled) {
+		t.Fatalf("expected ErrCacheDisabled, got %v", err)
+	}
+	if _, err := mongorepo.NewSoftDelete[invoiceRow](coll).Warm(ctx); !errors.Is(err, mongorepo.ErrCacheDisabled) {
+		t.Fatalf("soft delete: expected ErrCacheDisabled, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrNotCapped is returned when a collection that must be capped exists and is
+// not. Converting it would rewrite its data, so it is left to the caller.
+var ErrNotCapped = errors.New("mongorepo: collection exists and is not capped")
+
+// cappedSize is the size limit of a capped collection.
+type cappedSize struct {
+	bytes   int64
+	maxDocs int64
+}
+
+// CreateCapped creates the capped collection name in db, which keeps at most
+// sizeBytes bytes and, if maxDocs is positive, at most maxDocs documents,
+// removing the oldest documents to make room for new ones. It succeeds if the
+// collection already exists and is capped, whatever its limits, and returns
+// ErrNotCapped if it exists and is not.
+//
+// Example:
+//
+//	err := mongorepo.CreateCapped(ctx, db, "audit_log", 64<<20, 100_000)
+func CreateCapped(ctx context.Context, db *mongo.Database, name string, sizeBytes, maxDocs int64) error {
+	if sizeBytes <= 0 {
+		return fmt.Errorf("mongorepo: capped collection %s: size must be positive", name)
+	}
+	opts := mopt.CreateCollection().SetCapped(true).SetSizeInBytes(sizeBytes)
+	if maxDocs > 0 {
+		opts.SetMaxDocuments(maxDocs)
+	}
+	err := db.CreateCollection(ctx, name, opts)
+	var cmdErr mongo.CommandError
+	if err == nil || !errors.As(err, &cmdErr) || cmdErr.Code != codeNamespaceExists {
+		return wrapTimeout(err)
+	}
+
+	specs, err := db.ListCollectionSpecifications(ctx, bson.M{"name": name})
+	if err != nil {
+		return wrapTimeout(err)
+	}
+	if len(specs) == 1 {
+		var o struct {
+			Capped bool `bson:"capped"`
+		}
+		if err := bson.Unmarshal(specs[0].Options, &o); err == nil && o.Capped {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotCapped, namespace(db.Collection(name)))
+}
+
+// WithCapped makes EnsureIndexes create the repository's collection as a
+// capped collection before building indexes; see CreateCapped. Use it for
+// logs and queues read with Tail.
+//
+// Example:
+//
+//	events := mongorepo.New[Event](db.Collection("events"), mongorepo.WithCapped(64<<20, 0))
+//	if err := events.EnsureIndexes(ctx); err != nil {
+//	    return err
+//	}
+func WithCapped(sizeBytes, maxDocs int64) Option {
+	return func(s *settings) { s.capped = &cappedSize{bytes: sizeBytes, maxDocs: maxDocs} }
+}
+
+// TailOption configures Tail.
+type TailOption func(*tailConfig)
+
+type tailConfig struct {
+	retry time.Duration
+}
+
+// WithTailRetryInterval sets the pause before reopening the cursor when it
+// has nothing to follow, such as on an empty collection, or after an error.
+// Defaults to 1s.
+func WithTailRetryInterval(d time.Duration) TailOption {
+	return func(c *tailConfig) { c.retry = d }
+}
+
+// Tail calls fn for each document matching the filter in insertion order,
+// then waits for new documents and calls fn for them as they are inserted,
+// like tail -f. It runs until ctx is cancelled, when it returns ctx's error,
+// or until fn returns an error, which Tail returns. AfterLoad runs on each
+// document before fn sees it.
+//
+// Behavior:
+//   - The collection must be capped; see WithCapped and CreateCapped
+//   - When the cursor dies, e.g. because the collection was empty or fn fell
+//     so far behind that the documents were overwritten, it is reopened after
+//     the last document seen, by _id, so _ids must increase with insertion
+//     order as ObjectIDs do
+//
+// Example:
+//
+//	err := repo.Tail(ctx, spec.Eq("level", "error"), func(e *LogEntry) error {
+//	    return alert(ctx, e)
+//	})
+func (r *MongoRepository[T]) Tail(ctx context.Context, filter any, fn func(doc *T) error, opts ...TailOption) error {
+	cfg := tailConfig{retry: time.Second}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return err
+	}
+	findOpts := mopt.Find().
+		SetCursorType(mopt.TailableAwait).
+		SetSort(bson.D{{Key: "$natural", Value: 1}})
+
+	var last *bson.RawValue
+	for {
+		query := f
+		if last != nil {
+			query = bson.M{"$and": bson.A{f, bson.M{"_id": bson.M{"$gt": *last}}}}
+		}
+		cur, err := r.coll.Find(ctx, query, findOpts)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return wrapTimeout(err)
+		}
+
+		for cur.Next(ctx) {
+			var doc T
+			if err := decodeCurrent[T](ctx, r.settings, cur, &doc); err != nil {
+				cur.Close(ctx)
+				return err
+			}
+			if err := r.settings.afterLoad(ctx, &doc); err != nil {
+				cur.Close(ctx)
+				return err
+			}
+			id := cur.Current.Lookup("_id")
+			id.Value = append([]byte(nil), id.Value...) // outlives the batch
+			last = &id
+			if err := fn(&doc); err != nil {
+				cur.Close(ctx)
+				return err
+			}
+		}
+		cur.Close(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(cfg.retry):
+		}
+	}
+}
+
+// Tail follows the non-deleted documents matching the filter; see
+// MongoRepository.Tail.
+func (r *SoftDeleteRepository[T]) Tail(ctx context.Context, filter any, fn func(doc *T) error, opts ...TailOption) error {
+	return r.MongoRepository.Tail(ctx, r.combineWithNotDeleted(filter), fn, opts...)
+}
+
+// ensureCapped creates the collection as configured by WithCapped.
+func (r *MongoRepository[T]) ensureCapped(ctx context.Context) error {
+	if r.settings.capped == nil {
+		return nil
+	}
+	return CreateCapped(ctx, r.coll.Database(), r.coll.Name(), r.settings.capped.bytes, r.settings.capped.maxDocs)
+}
@@ -0,0 +1,26 @@
+package mongorepo_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestCreateCapped_RequiresSize(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; CreateCapped must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	if err := mongorepo.CreateCapped(ctx, client.Database("testdb"), "log", 0, 10); err == nil {
+		t.Fatal("expected an error for a zero size")
+	}
+}
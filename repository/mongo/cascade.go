@@ -0,0 +1,247 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dElCIoGio/mongox/internal/match"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Cascade declares a child collection whose documents follow the soft-delete
+// state of their parent. Children are matched by ForeignKey against the
+// parent's LocalKey.
+//
+// Example:
+//
+//	// Soft-deleting a user soft-deletes their sessions and orders,
+//	// and each order's line items.
+//	users := mongorepo.NewSoftDelete[User](db.Collection("users"),
+//	    mongorepo.WithCascade(
+//	        mongorepo.Cascade{Collection: db.Collection("sessions"), ForeignKey: "user_id"},
+//	        mongorepo.Cascade{
+//	            Collection: db.Collection("orders"),
+//	            ForeignKey: "user_id",
+//	            Children: []mongorepo.Cascade{
+//	                {Collection: db.Collection("order_items"), ForeignKey: "order_id"},
+//	            },
+//	        },
+//	    ),
+//	)
+type Cascade struct {
+	// Collection holds the child documents.
+	Collection *mongo.Collection
+
+	// ForeignKey is the child field referencing the parent.
+	ForeignKey string
+
+	// LocalKey is the parent field referenced by ForeignKey. Defaults to "_id".
+	LocalKey string
+
+	// Children are cascades applied to this collection's documents in turn.
+	Children []Cascade
+}
+
+func (c Cascade) localKey() string {
+	if c.LocalKey == "" {
+		return "_id"
+	}
+	return c.LocalKey
+}
+
+// WithCascade declares child collections that are soft-deleted and restored
+// together with a SoftDeleteRepository's documents.
+//
+// Behavior:
+//   - SoftDelete, SoftDeleteMany, Restore, and RestoreMany run in a transaction
+//     together with the cascaded writes (a replica set or sharded cluster is required)
+//   - If the context already carries a session, its transaction is reused
+//   - Children are stamped with the parent's deleted_at; restoring a parent only
+//     restores children deleted at that same instant, so children deleted on their
+//     own beforehand stay deleted
+//   - The option has no effect on a plain MongoRepository
+func WithCascade(cascades ...Cascade) Option {
+	return func(s *settings) { s.cascades = append(s.cascades, cascades...) }
+}
+
+// inTransaction runs fn in a transaction on coll's client, joining the
+// transaction or session already carried by ctx.
+func inTransaction(ctx context.Context, coll *mongo.Collection, fn func(ctx context.Context) error) error {
+	return RunInTransaction(ctx, coll.Database().Client(), fn)
+}
+
+// cascadeKeys returns the fields needed to follow cascades from a document.
+func cascadeKeys(field string, cascades []Cascade) []string {
+	keys := []string{field}
+	for _, c := range cascades {
+		keys = append(keys, c.localKey())
+	}
+	return keys
+}
+
+// findKeyDocs loads the documents matching filter with only _id and the given key fields.
+func findKeyDocs(ctx context.Context, coll *mongo.Collection, filter any, keys []string, limit int64) ([]bson.M, error) {
+	proj := bson.M{"_id": 1}
+	for _, k := range keys {
+		proj[k] = 1
+	}
+	opts := mopt.Find().SetProjection(proj)
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	cur, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.M
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// keyValues collects the values of field from docs, flattening arrays.
+func keyValues(docs []bson.M, field string) []any {
+	values := make([]any, 0, len(docs))
+	for _, doc := range docs {
+		v, ok := match.Lookup(doc, field)
+		if !ok || v == nil {
+			continue
+		}
+		if arr, ok := match.ToSlice(v); ok {
+			values = append(values, arr...)
+			continue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// softDeleteCascade soft-deletes the children of parents, recursively.
+func softDeleteCascade(ctx context.Context, cascades []Cascade, parents []bson.M, ts time.Time) error {
+	for _, c := range cascades {
+		keys := keyValues(parents, c.localKey())
+		if len(keys) == 0 {
+			continue
+		}
+		filter := bson.M{c.ForeignKey: bson.M{"$in": keys}, "deleted_at": bson.M{"$exists": false}}
+		if err := stampCascade(ctx, c, filter, bson.M{"$set": bson.M{"deleted_at": ts}}, func(children []bson.M) error {
+			return softDeleteCascade(ctx, c.Children, children, ts)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreCascade restores the children of parents that were deleted at ts, recursively.
+func restoreCascade(ctx context.Context, cascades []Cascade, parents []bson.M, ts time.Time) error {
+	for _, c := range cascades {
+		keys := keyValues(parents, c.localKey())
+		if len(keys) == 0 {
+			continue
+		}
+		filter := bson.M{c.ForeignKey: bson.M{"$in": keys}, "deleted_at": ts}
+		if err := stampCascade(ctx, c, filter, bson.M{"$unset": bson.M{"deleted_at": ""}}, func(children []bson.M) error {
+			return restoreCascade(ctx, c.Children, children, ts)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stampCascade applies update to the children of c matching filter. When c has
+// children of its own, the affected documents are loaded first and passed to next.
+func stampCascade(ctx context.Context, c Cascade, filter bson.M, update bson.M, next func([]bson.M) error) error {
+	if len(c.Children) == 0 {
+		_, err := c.Collection.UpdateMany(ctx, filter, update)
+		return err
+	}
+
+	children, err := findKeyDocs(ctx, c.Collection, filter, cascadeKeys("deleted_at", c.Children), 0)
+	if err != nil || len(children) == 0 {
+		return err
+	}
+	ids := keyValues(children, "_id")
+	if _, err := c.Collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, update); err != nil {
+		return err
+	}
+	return next(children)
+}
+
+// softDeleteWithCascade soft-deletes up to limit (0 for all) matching documents
+// and their cascades, stamping them with ts.
+func (r *SoftDeleteRepository[T]) softDeleteWithCascade(ctx context.Context, filter any, limit int64, ts time.Time) (int64, error) {
+	f, err := normalizeFilter(r.combineWithNotDeleted(filter))
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	err = inTransaction(ctx, r.coll, func(ctx context.Context) error {
+		deleted = 0
+		parents, err := findKeyDocs(ctx, r.coll, f, cascadeKeys(r.settings.deletedField(), r.settings.cascades), limit)
+		if err != nil || len(parents) == 0 {
+			return err
+		}
+
+		res, err := r.coll.UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$in": keyValues(parents, "_id")}},
+			bson.M{"$set": bson.M{r.settings.deletedField(): ts}},
+		)
+		if err != nil {
+			return err
+		}
+		deleted = res.ModifiedCount
+
+		return softDeleteCascade(ctx, r.settings.cascades, parents, ts)
+	})
+	return deleted, err
+}
+
+// restoreWithCascade restores up to limit (0 for all) matching deleted documents and their cascades.
+func (r *SoftDeleteRepository[T]) restoreWithCascade(ctx context.Context, filter any, limit int64) (int64, error) {
+	f, err := normalizeFilter(r.combineWithDeleted(filter))
+	if err != nil {
+		return 0, err
+	}
+
+	var restored int64
+	err = inTransaction(ctx, r.coll, func(ctx context.Context) error {
+		restored = 0
+		parents, err := findKeyDocs(ctx, r.coll, f, cascadeKeys(r.settings.deletedField(), r.settings.cascades), limit)
+		if err != nil || len(parents) == 0 {
+			return err
+		}
+
+		res, err := r.coll.UpdateMany(ctx,
+			bson.M{"_id": bson.M{"$in": keyValues(parents, "_id")}},
+			bson.M{"$unset": bson.M{r.settings.deletedField(): ""}},
+		)
+		if err != nil {
+			return err
+		}
+		restored = res.ModifiedCount
+
+		// Children were stamped with their parent's deletion time; restore per timestamp.
+		byTime := make(map[time.Time][]bson.M)
+		for _, p := range parents {
+			if dt, ok := p[r.settings.deletedField()].(primitive.DateTime); ok {
+				ts := dt.Time().UTC()
+				byTime[ts] = append(byTime[ts], p)
+			}
+		}
+		for ts, group := range byTime {
+			if err := restoreCascade(ctx, r.settings.cascades, group, ts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return restored, err
+}
@@ -0,0 +1,194 @@
+package mongorepo
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidCausalToken is returned by ParseCausalToken for malformed tokens.
+var ErrInvalidCausalToken = errors.New("mongox: invalid causal token")
+
+// CausalSession is a causally consistent session. Reads made through it observe
+// every write made through it earlier, even when they are routed to a
+// different replica set member.
+//
+// The guarantee requires majority read and write concern on the collections
+// involved when reads may go to secondaries. A CausalSession is not safe for
+// concurrent use; start one per logical request.
+//
+// Example:
+//
+//	sess, err := mongorepo.StartCausalSession(client)
+//	if err != nil {
+//	    return err
+//	}
+//	defer sess.End(ctx)
+//
+//	users := mongorepo.Causal[User](userRepo, sess)
+//	_ = users.InsertOne(ctx, user)
+//	u, _ := users.FindOne(ctx, spec.Eq("_id", user.ID)) // sees the insert
+type CausalSession struct {
+	sess mongo.Session
+}
+
+// StartCausalSession starts a causally consistent session on client.
+// Pass tokens from earlier sessions to continue their causal chain, e.g. a token
+// returned to a browser by the previous HTTP request.
+func StartCausalSession(client *mongo.Client, tokens ...CausalToken) (*CausalSession, error) {
+	sess, err := client.StartSession(options.Session().SetCausalConsistency(true))
+	if err != nil {
+		return nil, err
+	}
+	s := &CausalSession{sess: sess}
+	for _, t := range tokens {
+		if err := s.Advance(t); err != nil {
+			sess.EndSession(context.Background())
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Context returns ctx bound to the session. Driver and repository calls made
+// with the returned context take part in the session.
+func (s *CausalSession) Context(ctx context.Context) context.Context {
+	return mongo.NewSessionContext(ctx, s.sess)
+}
+
+// Token captures the session's causal position so a later session, possibly in
+// another process, can continue from it.
+func (s *CausalSession) Token() CausalToken {
+	t := CausalToken{ClusterTime: s.sess.ClusterTime()}
+	if ts := s.sess.OperationTime(); ts != nil {
+		t.OperationTime = *ts
+	}
+	return t
+}
+
+// Advance moves the session forward to at least the position in t, so
+// subsequent reads observe everything that happened before t was taken.
+func (s *CausalSession) Advance(t CausalToken) error {
+	if len(t.ClusterTime) > 0 {
+		if err := s.sess.AdvanceClusterTime(t.ClusterTime); err != nil {
+			return err
+		}
+	}
+	if !t.OperationTime.IsZero() {
+		if err := s.sess.AdvanceOperationTime(&t.OperationTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// End ends the session. Always call End when the session is no longer needed.
+func (s *CausalSession) End(ctx context.Context) {
+	s.sess.EndSession(ctx)
+}
+
+// CausalToken is a serializable causal position: the cluster time and
+// operation time last seen by a session.
+type CausalToken struct {
+	ClusterTime   bson.Raw            `bson:"cluster_time,omitempty"`
+	OperationTime primitive.Timestamp `bson:"operation_time"`
+}
+
+// String encodes the token as URL-safe base64, suitable for a header or cookie.
+// The zero token encodes as the empty string.
+func (t CausalToken) String() string {
+	if len(t.ClusterTime) == 0 && t.OperationTime.IsZero() {
+		return ""
+	}
+	b, err := bson.Marshal(t)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCausalToken decodes a token produced by CausalToken.String.
+// The empty string decodes to the zero token.
+func ParseCausalToken(s string) (CausalToken, error) {
+	var t CausalToken
+	if s == "" {
+		return t, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, fmt.Errorf("%w: %v", ErrInvalidCausalToken, err)
+	}
+	if err := bson.Unmarshal(b, &t); err != nil {
+		return t, fmt.Errorf("%w: %v", ErrInvalidCausalToken, err)
+	}
+	return t, nil
+}
+
+// Causal binds repo to sess: every call runs in the session, whatever context
+// the caller passes. Deadlines and values of the caller's context are kept.
+func Causal[T any](repo repository.Repository[T], sess *CausalSession) repository.Repository[T] {
+	return &causalRepository[T]{repo: repo, sess: sess}
+}
+
+type causalRepository[T any] struct {
+	repo repository.Repository[T]
+	sess *CausalSession
+}
+
+var _ repository.Repository[struct{}] = (*causalRepository[struct{}])(nil)
+
+func (c *causalRepository[T]) InsertOne(ctx context.Context, doc *T) error {
+	return c.repo.InsertOne(c.sess.Context(ctx), doc)
+}
+
+func (c *causalRepository[T]) FindOne(ctx context.Context, filter any, opts ...repository.FindOption) (*T, error) {
+	return c.repo.FindOne(c.sess.Context(ctx), filter, opts...)
+}
+
+func (c *causalRepository[T]) Find(ctx context.Context, filter any, opts ...repository.FindOption) ([]T, error) {
+	return c.repo.Find(c.sess.Context(ctx), filter, opts...)
+}
+
+func (c *causalRepository[T]) UpdateOne(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (int64, int64, error) {
+	return c.repo.UpdateOne(c.sess.Context(ctx), filter, update, opts...)
+}
+
+func (c *causalRepository[T]) ReplaceOne(ctx context.Context, filter any, doc *T) (int64, int64, error) {
+	return c.repo.ReplaceOne(c.sess.Context(ctx), filter, doc)
+}
+
+func (c *causalRepository[T]) DeleteOne(ctx context.Context, filter any) (int64, error) {
+	return c.repo.DeleteOne(c.sess.Context(ctx), filter)
+}
+
+func (c *causalRepository[T]) InsertMany(ctx context.Context, docs []*T) ([]primitive.ObjectID, error) {
+	return c.repo.InsertMany(c.sess.Context(ctx), docs)
+}
+
+func (c *causalRepository[T]) UpdateMany(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (int64, int64, error) {
+	return c.repo.UpdateMany(c.sess.Context(ctx), filter, update, opts...)
+}
+
+func (c *causalRepository[T]) DeleteMany(ctx context.Context, filter any) (int64, error) {
+	return c.repo.DeleteMany(c.sess.Context(ctx), filter)
+}
+
+func (c *causalRepository[T]) Aggregate(ctx context.Context, pipeline any) ([]T, error) {
+	return c.repo.Aggregate(c.sess.Context(ctx), pipeline)
+}
+
+func (c *causalRepository[T]) AggregateRaw(ctx context.Context, pipeline any) ([]bson.M, error) {
+	return c.repo.AggregateRaw(c.sess.Context(ctx), pipeline)
+}
+
+func (c *causalRepository[T]) Count(ctx context.Context, filter any, opts ...repository.CountOption) (int64, error) {
+	return c.repo.Count(c.sess.Context(ctx), filter, opts...)
+}
@@ -0,0 +1,49 @@
+package mongorepo_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCausalToken_RoundTrip(t *testing.T) {
+	clusterTime, err := bson.Marshal(bson.M{"$clusterTime": bson.M{"clusterTime": primitive.Timestamp{T: 100, I: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := mongorepo.CausalToken{ClusterTime: clusterTime, OperationTime: primitive.Timestamp{T: 100, I: 1}}
+
+	got, err := mongorepo.ParseCausalToken(tok.String())
+	if err != nil {
+		t.Fatalf("ParseCausalToken: %v", err)
+	}
+	if got.OperationTime != tok.OperationTime {
+		t.Fatalf("operation time: got %v, want %v", got.OperationTime, tok.OperationTime)
+	}
+	if !bytes.Equal(got.ClusterTime, tok.ClusterTime) {
+		t.Fatalf("cluster time: got %v, want %v", got.ClusterTime, tok.ClusterTime)
+	}
+}
+
+func TestCausalToken_Empty(t *testing.T) {
+	if s := (mongorepo.CausalToken{}).String(); s != "" {
+		t.Fatalf("expected zero token to encode as empty string, got %q", s)
+	}
+	tok, err := mongorepo.ParseCausalToken("")
+	if err != nil || !tok.OperationTime.IsZero() || len(tok.ClusterTime) != 0 {
+		t.Fatalf("expected zero token, got %+v, %v", tok, err)
+	}
+}
+
+func TestParseCausalToken_Invalid(t *testing.T) {
+	for _, s := range []string{"not base64!", "AAAA"} {
+		if _, err := mongorepo.ParseCausalToken(s); !errors.Is(err, mongorepo.ErrInvalidCausalToken) {
+			t.Fatalf("ParseCausalToken(%q): expected ErrInvalidCausalToken, got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxIDsPerDelete bounds the size of the $in list sent in one delete.
+const maxIDsPerDelete = 1000
+
+// DeleteByIDs deletes the documents with the given ids and returns the number
+// deleted. Large id lists are split into several deletes so the filter stays
+// small. References declared with WithReferences are enforced as in DeleteMany.
+//
+// Example:
+//
+//	deleted, err := repo.DeleteByIDs(ctx, selectedIDs)
+func (r *MongoRepository[T]) DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	var total int64
+	for start := 0; start < len(ids); start += maxIDsPerDelete {
+		end := min(start+maxIDsPerDelete, len(ids))
+		n, err := r.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids[start:end]}})
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// ChunkOption configures ChunkedDeleteMany and ChunkedUpdateMany.
+type ChunkOption func(*chunkConfig)
+
+type chunkConfig struct {
+	pause    time.Duration
+	progress func(deleted int64)
+}
+
+// WithChunkPause sets the pause between batches. Defaults to 100ms; zero disables it.
+func WithChunkPause(d time.Duration) ChunkOption {
+	return func(c *chunkConfig) { c.pause = d }
+}
+
+// WithChunkProgress registers a callback invoked after every ChunkedDeleteMany
+// batch with the total number of documents deleted so far.
+func WithChunkProgress(fn func(deleted int64)) ChunkOption {
+	return func(c *chunkConfig) { c.progress = fn }
+}
+
+// ChunkedDeleteMany deletes the documents matching the filter in batches of at
+// most batchSize, pausing between batches. Huge purges then hold locks briefly,
+// keep replication lag and cache churn bounded, and can be stopped part way.
+//
+// Behavior:
+//   - Each batch selects up to batchSize _ids, then deletes those that still match
+//   - References declared with WithReferences are enforced per batch
+//   - Cancelling ctx stops before the next batch; the count deleted so far is returned
+//   - A batchSize of 0 or less defaults to 1000
+//
+// Example:
+//
+//	deleted, err := repo.ChunkedDeleteMany(ctx, spec.Lt("created_at", cutoff), 5000,
+//	    mongorepo.WithChunkPause(250*time.Millisecond),
+//	    mongorepo.WithChunkProgress(func(n int64) { log.Printf("purged %d", n) }),
+//	)
+func (r *MongoRepository[T]) ChunkedDeleteMany(ctx context.Context, filter any, batchSize int, opts ...ChunkOption) (int64, error) {
+	cfg := chunkConfig{pause: 100 * time.Millisecond}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+	findOpts := mopt.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetLimit(int64(batchSize))
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		cur, err := r.coll.Find(ctx, f, findOpts)
+		if err != nil {
+			return total, err
+		}
+		var docs []struct {
+			ID any `bson:"_id"`
+		}
+		if err := cur.All(ctx, &docs); err != nil {
+			return total, err
+		}
+		if len(docs) == 0 {
+			return total, nil
+		}
+
+		ids := make([]any, len(docs))
+		for i, d := range docs {
+			ids[i] = d.ID
+		}
+		n, err := r.DeleteMany(ctx, bson.M{"$and": bson.A{f, bson.M{"_id": bson.M{"$in": ids}}}})
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if cfg.progress != nil {
+			cfg.progress(total)
+		}
+		if len(docs) < batchSize {
+			return total, nil
+		}
+
+		if err := sleepCtx(ctx, cfg.pause); err != nil {
+			return total, err
+		}
+	}
+}
+
+// ChunkedUpdateMany applies update to the documents matching the filter one
+// _id range at a time, pausing between ranges, so massive updates do not hold
+// locks for long or flood the oplog in one burst. onProgress, if not nil, is
+// called after every range with the running matched and modified totals.
+//
+// Behavior:
+//   - Documents are visited in ascending _id order; each range spans up to batchSize
+//     matching documents and is updated with one UpdateMany
+//   - Updates that change the filtered fields are safe: ranges never revisit earlier _ids
+//   - updated_at is added to $set updates, as in UpdateMany
+//   - Cancelling ctx stops before the next range; the totals so far are returned
+//   - A batchSize of 0 or less defaults to 1000
+//
+// Example:
+//
+//	matched, modified, err := repo.ChunkedUpdateMany(ctx,
+//	    spec.Eq("plan", "legacy"), spec.Set("plan", "basic"), 2000,
+//	    func(matched, modified int64) { log.Printf("migrated %d", modified) },
+//	)
+func (r *MongoRepository[T]) ChunkedUpdateMany(ctx context.Context, filter any, update any, batchSize int, onProgress func(matched, modified int64), opts ...ChunkOption) (matched int64, modified int64, err error) {
+	cfg := chunkConfig{pause: 100 * time.Millisecond}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	if update == nil {
+		return 0, 0, repository.ErrNilUpdate
+	}
+
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return 0, 0, err
+	}
+	findOpts := mopt.Find().
+		SetProjection(bson.M{"_id": 1}).
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(batchSize))
+
+	var lastID any
+	for {
+		if err := ctx.Err(); err != nil {
+			return matched, modified, err
+		}
+
+		page := f
+		if lastID != nil {
+			page = bson.M{"$and": bson.A{f, bson.M{"_id": bson.M{"$gt": lastID}}}}
+		}
+		cur, err := r.coll.Find(ctx, page, findOpts)
+		if err != nil {
+			return matched, modified, err
+		}
+		var docs []struct {
+			ID any `bson:"_id"`
+		}
+		if err := cur.All(ctx, &docs); err != nil {
+			return matched, modified, err
+		}
+		if len(docs) == 0 {
+			return matched, modified, nil
+		}
+
+		first, last := docs[0].ID, docs[len(docs)-1].ID
+		m, n, err := r.UpdateMany(ctx, bson.M{"$and": bson.A{f, bson.M{"_id": bson.M{"$gte": first, "$lte": last}}}}, update)
+		matched += m
+		modified += n
+		if err != nil {
+			return matched, modified, err
+		}
+		if onProgress != nil {
+			onProgress(matched, modified)
+		}
+		if len(docs) < batchSize {
+			return matched, modified, nil
+		}
+		lastID = last
+
+		if err := sleepCtx(ctx, cfg.pause); err != nil {
+			return matched, modified, err
+		}
+	}
+}
+
+// sleepCtx pauses for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// ChunkedDeleteMany permanently removes the non-deleted documents matching
+// the filter in batches, as MongoRepository.ChunkedDeleteMany does.
+func (r *SoftDeleteRepository[T]) ChunkedDeleteMany(ctx context.Context, filter any, batchSize int, opts ...ChunkOption) (int64, error) {
+	return r.MongoRepository.ChunkedDeleteMany(ctx, r.combineWithNotDeleted(filter), batchSize, opts...)
+}
+
+// ChunkedDeleteManyWithDeleted removes the documents matching the filter in
+// batches, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) ChunkedDeleteManyWithDeleted(ctx context.Context, filter any, batchSize int, opts ...ChunkOption) (int64, error) {
+	return r.MongoRepository.ChunkedDeleteMany(ctx, filter, batchSize, opts...)
+}
+
+// ChunkedUpdateMany updates the non-deleted documents matching the filter one
+// _id range at a time, as MongoRepository.ChunkedUpdateMany does.
+func (r *SoftDeleteRepository[T]) ChunkedUpdateMany(ctx context.Context, filter any, update any, batchSize int, onProgress func(matched, modified int64), opts ...ChunkOption) (matched int64, modified int64, err error) {
+	return r.MongoRepository.ChunkedUpdateMany(ctx, r.combineWithNotDeleted(filter), update, batchSize, onProgress, opts...)
+}
+
+// ChunkedUpdateManyWithDeleted updates the documents matching the filter one
+// _id range at a time, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) ChunkedUpdateManyWithDeleted(ctx context.Context, filter any, update any, batchSize int, onProgress func(matched, modified int64), opts ...ChunkOption) (matched int64, modified int64, err error) {
+	return r.MongoRepository.ChunkedUpdateMany(ctx, filter, update, batchSize, onProgress, opts...)
+}
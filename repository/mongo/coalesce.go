@@ -0,0 +1,64 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dElCIoGio/mongox/internal/singleflight"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithCoalescing makes concurrent identical FindOne and Count calls share one
+// round trip: while a call is in flight, callers with the same filter wait for
+// it and receive its result instead of querying again. It smooths thundering
+// herds on hot documents, such as a popular product page after a cache expiry.
+//
+// Behavior:
+//   - Only calls without find or count options are coalesced, and only while
+//     one is in flight; nothing is cached afterwards
+//   - Calls in a session or transaction, or with different operation
+//     comments, are never coalesced
+//   - Each caller gets its own copy of the document, but copies are shallow;
+//     don't mutate their slices or maps
+//   - A waiting caller whose context ends returns its context's error; if the
+//     shared call fails because its caller's context ended, waiters query again
+//
+// Example:
+//
+//	products := mongorepo.New[Product](coll, mongorepo.WithCoalescing())
+func WithCoalescing() Option {
+	return func(s *settings) { s.flight = &singleflight.Group{} }
+}
+
+// flightKey returns the key identifying a coalescible call of op with the
+// normalized filter f, or "" if the call must not be coalesced.
+func (s settings) flightKey(ctx context.Context, op string, f any) string {
+	if s.flight == nil || mongo.SessionFromContext(ctx) != nil {
+		return ""
+	}
+	raw, err := bson.Marshal(f)
+	if err != nil {
+		return ""
+	}
+	return op + "\x00" + s.comment(ctx, "") + "\x00" + string(raw)
+}
+
+// coalesce runs fn, sharing its result with concurrent calls of the same key.
+// An empty key runs fn alone.
+func coalesce[V any](ctx context.Context, g *singleflight.Group, key string, fn func() (V, error)) (V, error) {
+	if key == "" {
+		return fn()
+	}
+	v, err, shared := g.Do(ctx, key, func() (any, error) { return fn() })
+	if shared && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		// The leader's context ended, not ours.
+		return fn()
+	}
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return v.(V), nil
+}
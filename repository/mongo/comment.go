@@ -0,0 +1,57 @@
+package mongorepo
+
+import (
+	"context"
+)
+
+type commentKey struct{}
+
+// ContextWithComment returns ctx carrying comment, which is attached to every
+// find, count, aggregate, update, replace, and delete the repository runs with
+// it. The server records comments in its logs, the profiler, and currentOp, so
+// slow operations can be traced back to the request that issued them.
+//
+// A comment passed to a single call with repository.WithComment or
+// repository.WithUpdateComment takes precedence.
+//
+// Example:
+//
+//	ctx = mongorepo.ContextWithComment(r.Context(), "GET /orders")
+func ContextWithComment(ctx context.Context, comment string) context.Context {
+	return context.WithValue(ctx, commentKey{}, comment)
+}
+
+// WithCommentFromContext derives part of every operation's comment from its
+// context, typically a trace or request ID set by middleware. The derived
+// text is appended to the explicit comment, if any, so operations stay
+// correlated with their request. An empty result adds nothing.
+//
+// Example:
+//
+//	repo := mongorepo.New[Order](coll, mongorepo.WithCommentFromContext(func(ctx context.Context) string {
+//	    if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+//	        return "request_id=" + id
+//	    }
+//	    return ""
+//	}))
+func WithCommentFromContext(fn func(ctx context.Context) string) Option {
+	return func(s *settings) { s.commenter = fn }
+}
+
+// comment returns the comment for an operation run with ctx: explicit, or
+// else the context's comment, followed by the derived comment.
+func (s settings) comment(ctx context.Context, explicit string) string {
+	c := explicit
+	if c == "" {
+		c, _ = ctx.Value(commentKey{}).(string)
+	}
+	if s.commenter != nil {
+		if derived := s.commenter(ctx); derived != "" {
+			if c != "" {
+				return c + " " + derived
+			}
+			return derived
+		}
+	}
+	return c
+}
@@ -0,0 +1,107 @@
+package mongorepo
+
+import (
+	"fmt"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// WithReadPreference sets the read preference of the repository's reads,
+// overriding the one inherited from the client and database. Use
+// repository.WithReadPreference to override it for a single Find or FindOne.
+//
+// Example:
+//
+//	reports := mongorepo.New[Order](coll, mongorepo.WithReadPreference(readpref.SecondaryPreferred()))
+func WithReadPreference(rp *readpref.ReadPref) Option {
+	return func(s *settings) { s.readPref = rp }
+}
+
+// WithReadConcern sets the read concern of the repository's reads, overriding
+// the one inherited from the client and database. Use
+// repository.WithReadConcern to override it for a single Find or FindOne.
+//
+// Example:
+//
+//	repo := mongorepo.New[Account](coll, mongorepo.WithReadConcern(readconcern.Majority()))
+func WithReadConcern(rc *readconcern.ReadConcern) Option {
+	return func(s *settings) { s.readConcern = rc }
+}
+
+// WithWriteConcern sets the write concern of the repository's writes,
+// overriding the one inherited from the client and database.
+//
+// Example:
+//
+//	repo := mongorepo.New[Payment](coll, mongorepo.WithWriteConcern(writeconcern.Majority()))
+func WithWriteConcern(wc *writeconcern.WriteConcern) Option {
+	return func(s *settings) { s.writeConcern = wc }
+}
+
+// configure returns coll with the repository's read preference and concerns
+// applied, or coll itself if none are set.
+func (s settings) configure(coll *mongo.Collection) *mongo.Collection {
+	if coll == nil || (s.readPref == nil && s.readConcern == nil && s.writeConcern == nil) {
+		return coll
+	}
+	o := mopt.Collection()
+	if s.readPref != nil {
+		o.SetReadPreference(s.readPref)
+	}
+	if s.readConcern != nil {
+		o.SetReadConcern(s.readConcern)
+	}
+	if s.writeConcern != nil {
+		o.SetWriteConcern(s.writeConcern)
+	}
+	c, err := coll.Clone(o)
+	if err != nil {
+		// Clone only copies settings; keep the collection as given if it fails.
+		return coll
+	}
+	return c
+}
+
+// readCollection returns coll with the per-call read preference and read
+// concern in fo applied.
+func readCollection(coll *mongo.Collection, fo repository.FindOptions) (*mongo.Collection, error) {
+	if fo.ReadPreference == "" && fo.ReadConcern == "" {
+		return coll, nil
+	}
+	o := mopt.Collection()
+	if fo.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(fo.ReadPreference)
+		if err != nil {
+			return nil, fmt.Errorf("mongorepo: %w", err)
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, fmt.Errorf("mongorepo: %w", err)
+		}
+		o.SetReadPreference(rp)
+	}
+	if fo.ReadConcern != "" {
+		rc, err := readConcernLevel(fo.ReadConcern)
+		if err != nil {
+			return nil, err
+		}
+		o.SetReadConcern(rc)
+	}
+	return coll.Clone(o)
+}
+
+// readConcernLevel returns the read concern for level, rejecting unknown
+// levels rather than falling back to local like transaction options do.
+func readConcernLevel(level string) (*readconcern.ReadConcern, error) {
+	switch level {
+	case "local", "available", "majority", "linearizable", "snapshot":
+		return parseReadConcern(level), nil
+	}
+	return nil, fmt.Errorf("mongorepo: unknown read concern %q", level)
+}
@@ -0,0 +1,61 @@
+package mongorepo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+func TestNew_ReadAndWriteConcerns(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; the options only configure the collection.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	coll := client.Database("testdb").Collection("orders")
+	repo := mongorepo.New[invoiceRow](coll,
+		mongorepo.WithReadPreference(readpref.SecondaryPreferred()),
+		mongorepo.WithReadConcern(readconcern.Majority()),
+		mongorepo.WithWriteConcern(writeconcern.Majority()),
+	)
+
+	// The driver has no accessors for collection settings; the repository
+	// must work on a configured copy rather than the collection given.
+	if got := repo.Collection(); got == coll || got.Name() != coll.Name() {
+		t.Fatal("expected a configured copy of the collection")
+	}
+	if plain := mongorepo.New[invoiceRow](coll); plain.Collection() != coll {
+		t.Fatal("expected New without options to use the collection as given")
+	}
+}
+
+func TestFind_RejectsUnknownReadOverrides(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; invalid overrides must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	repo := mongorepo.New[invoiceRow](client.Database("testdb").Collection("orders"))
+	if _, err := repo.Find(ctx, nil, repository.WithReadPreference("fastest")); err == nil {
+		t.Fatal("Find: expected an error for an unknown read preference")
+	}
+	if _, err := repo.FindOne(ctx, nil, repository.WithReadConcern("eventual")); err == nil {
+		t.Fatal("FindOne: expected an error for an unknown read concern")
+	}
+}
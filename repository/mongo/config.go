@@ -0,0 +1,200 @@
+package mongorepo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig is returned by ParseRepoConfig, LoadRepoConfig, and
+// FromConfig when a RepoConfig is malformed or has invalid values.
+var ErrInvalidConfig = errors.New("mongorepo: invalid repository config")
+
+// RepoConfig describes a repository in a deployment config file, so
+// data-access behavior can be tuned without code changes. Durations are
+// strings such as "30s" or "2m".
+//
+// Example (YAML):
+//
+//	collection: orders
+//	read_preference: secondaryPreferred
+//	write_concern: majority
+//	soft_delete: true
+//	cache_ttl: 30s
+//	default_page_size: 50
+//	max_query_time: 2s
+type RepoConfig struct {
+	// Collection is the collection name. Required.
+	Collection string `yaml:"collection"`
+
+	// ReadPreference is primary (the default), primaryPreferred, secondary,
+	// secondaryPreferred, or nearest.
+	ReadPreference string `yaml:"read_preference"`
+
+	// WriteConcern is "majority" or a number of nodes. Empty keeps the
+	// database's write concern.
+	WriteConcern string `yaml:"write_concern"`
+
+	// Journal requests acknowledgment that writes reached the journal.
+	Journal bool `yaml:"journal"`
+
+	// SoftDelete makes FromConfig return a SoftDeleteRepository.
+	SoftDelete bool `yaml:"soft_delete"`
+
+	// CacheTTL enables the FindByID cache; see WithCache.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+
+	// DefaultPageSize is the FindPaginated page size; see WithDefaultPageSize.
+	DefaultPageSize int `yaml:"default_page_size"`
+
+	// MaxQueryTime is the server-side query time limit; see WithMaxQueryTime.
+	MaxQueryTime time.Duration `yaml:"max_query_time"`
+}
+
+// ParseRepoConfig parses a RepoConfig from YAML or JSON and validates it.
+// Unknown keys are rejected so that typos don't go unnoticed.
+func ParseRepoConfig(data []byte) (RepoConfig, error) {
+	var cfg RepoConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return RepoConfig{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return RepoConfig{}, err
+	}
+	return cfg, nil
+}
+
+// LoadRepoConfig reads and parses a YAML or JSON config file.
+func LoadRepoConfig(path string) (RepoConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RepoConfig{}, err
+	}
+	return ParseRepoConfig(data)
+}
+
+// Validate reports whether cfg can be used by FromConfig. Errors match
+// ErrInvalidConfig.
+func (cfg RepoConfig) Validate() error {
+	if cfg.Collection == "" {
+		return fmt.Errorf("%w: collection is required", ErrInvalidConfig)
+	}
+	if _, err := cfg.readPref(); err != nil {
+		return err
+	}
+	if _, err := cfg.writeConcern(); err != nil {
+		return err
+	}
+	if cfg.CacheTTL < 0 || cfg.MaxQueryTime < 0 {
+		return fmt.Errorf("%w: durations must not be negative", ErrInvalidConfig)
+	}
+	if cfg.DefaultPageSize < 0 {
+		return fmt.Errorf("%w: default_page_size must not be negative", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// Options returns the repository options cfg configures.
+func (cfg RepoConfig) Options() []Option {
+	var opts []Option
+	if cfg.CacheTTL > 0 {
+		opts = append(opts, WithCache(cfg.CacheTTL))
+	}
+	if cfg.DefaultPageSize > 0 {
+		opts = append(opts, WithDefaultPageSize(cfg.DefaultPageSize))
+	}
+	if cfg.MaxQueryTime > 0 {
+		opts = append(opts, WithMaxQueryTime(cfg.MaxQueryTime))
+	}
+	return opts
+}
+
+func (cfg RepoConfig) readPref() (*readpref.ReadPref, error) {
+	if cfg.ReadPreference == "" {
+		return nil, nil
+	}
+	mode, err := readpref.ModeFromString(cfg.ReadPreference)
+	if err != nil {
+		return nil, fmt.Errorf("%w: read_preference %q", ErrInvalidConfig, cfg.ReadPreference)
+	}
+	rp, err := readpref.New(mode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: read_preference %q: %w", ErrInvalidConfig, cfg.ReadPreference, err)
+	}
+	return rp, nil
+}
+
+func (cfg RepoConfig) writeConcern() (*writeconcern.WriteConcern, error) {
+	if cfg.WriteConcern == "" && !cfg.Journal {
+		return nil, nil
+	}
+	wc := &writeconcern.WriteConcern{}
+	switch cfg.WriteConcern {
+	case "":
+	case "majority":
+		wc.W = "majority"
+	default:
+		n, err := strconv.Atoi(cfg.WriteConcern)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: write_concern %q", ErrInvalidConfig, cfg.WriteConcern)
+		}
+		wc.W = n
+	}
+	if cfg.Journal {
+		journal := true
+		wc.Journal = &journal
+	}
+	return wc, nil
+}
+
+// FromConfig creates a repository for cfg.Collection in db with the read
+// preference, write concern, and options cfg describes. opts are applied after
+// the options from cfg, so code can still override them.
+//
+// The result is a *SoftDeleteRepository[T] when cfg.SoftDelete is set and a
+// *MongoRepository[T] otherwise; type-assert it to use methods outside
+// repository.Repository.
+//
+// Example:
+//
+//	cfg, err := mongorepo.LoadRepoConfig("config/orders.yaml")
+//	if err != nil {
+//	    return err
+//	}
+//	orders, err := mongorepo.FromConfig[Order](db, cfg, mongorepo.WithObserver(hist))
+func FromConfig[T any](db *mongo.Database, cfg RepoConfig, opts ...Option) (repository.Repository[T], error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	rp, _ := cfg.readPref()
+	wc, _ := cfg.writeConcern()
+
+	collOpts := mopt.Collection()
+	if rp != nil {
+		collOpts.SetReadPreference(rp)
+	}
+	if wc != nil {
+		collOpts.SetWriteConcern(wc)
+	}
+	coll := db.Collection(cfg.Collection, collOpts)
+
+	all := append(cfg.Options(), opts...)
+	if cfg.SoftDelete {
+		return NewSoftDelete[T](coll, all...), nil
+	}
+	return New[T](coll, all...), nil
+}
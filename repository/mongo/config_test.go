@@ -0,0 +1,97 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestParseRepoConfig_YAMLAndJSON(t *testing.T) {
+	want := mongorepo.RepoConfig{
+		Collection:      "orders",
+		ReadPreference:  "secondaryPreferred",
+		WriteConcern:    "majority",
+		SoftDelete:      true,
+		CacheTTL:        30 * time.Second,
+		DefaultPageSize: 50,
+	}
+
+	yamlCfg, err := mongorepo.ParseRepoConfig([]byte(`
+collection: orders
+read_preference: secondaryPreferred
+write_concern: majority
+soft_delete: true
+cache_ttl: 30s
+default_page_size: 50
+`))
+	if err != nil || yamlCfg != want {
+		t.Fatalf("YAML: got %+v, %v; want %+v", yamlCfg, err, want)
+	}
+
+	jsonCfg, err := mongorepo.ParseRepoConfig([]byte(`{
+		"collection": "orders", "read_preference": "secondaryPreferred",
+		"write_concern": "majority", "soft_delete": true,
+		"cache_ttl": "30s", "default_page_size": 50
+	}`))
+	if err != nil || jsonCfg != want {
+		t.Fatalf("JSON: got %+v, %v; want %+v", jsonCfg, err, want)
+	}
+}
+
+func TestParseRepoConfig_Invalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"missing collection": `read_preference: primary`,
+		"unknown key":        "collection: orders\ncache_tll: 30s",
+		"bad read pref":      "collection: orders\nread_preference: closest",
+		"bad write concern":  "collection: orders\nwrite_concern: all",
+		"negative page size": "collection: orders\ndefault_page_size: -1",
+		"bad duration":       "collection: orders\ncache_ttl: soon",
+	} {
+		if _, err := mongorepo.ParseRepoConfig([]byte(data)); !errors.Is(err, mongorepo.ErrInvalidConfig) {
+			t.Errorf("%s: expected ErrInvalidConfig, got %v", name, err)
+		}
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; FromConfig does not reach the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+	db := client.Database("testdb")
+
+	repo, err := mongorepo.FromConfig[invoiceRow](db, mongorepo.RepoConfig{
+		Collection:     "invoices",
+		ReadPreference: "nearest",
+		SoftDelete:     true,
+	})
+	if err != nil {
+		t.Fatalf("FromConfig: %v", err)
+	}
+	soft, ok := repo.(*mongorepo.SoftDeleteRepository[invoiceRow])
+	if !ok {
+		t.Fatalf("expected *SoftDeleteRepository, got %T", repo)
+	}
+	if name := soft.Collection().Name(); name != "invoices" {
+		t.Fatalf("collection = %q, want invoices", name)
+	}
+
+	repo, err = mongorepo.FromConfig[invoiceRow](db, mongorepo.RepoConfig{Collection: "invoices"})
+	if _, ok := repo.(*mongorepo.MongoRepository[invoiceRow]); err != nil || !ok {
+		t.Fatalf("expected *MongoRepository, got %T (%v)", repo, err)
+	}
+
+	if _, err := mongorepo.FromConfig[invoiceRow](db, mongorepo.RepoConfig{}); !errors.Is(err, mongorepo.ErrInvalidConfig) {
+		t.Fatalf("expected ErrInvalidConfig, got %v", err)
+	}
+}
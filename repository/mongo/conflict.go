@@ -0,0 +1,266 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/internal/match"
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type conflictAction int
+
+const (
+	conflictIgnore conflictAction = iota
+	conflictReplace
+	conflictMerge
+)
+
+// OnConflict decides what happens when an inserted document violates a unique
+// index. The conflicting document is identified by the key the server reports
+// in the duplicate key error, so any unique index works without declaring it.
+type OnConflict struct {
+	action conflictAction
+	fields []string
+}
+
+var (
+	// OnConflictIgnore keeps the existing document and drops the new one.
+	OnConflictIgnore = OnConflict{action: conflictIgnore}
+
+	// OnConflictReplace replaces the existing document with the new one,
+	// keeping the existing _id. Versioned documents keep their stored version
+	// plus one.
+	OnConflictReplace = OnConflict{action: conflictReplace}
+)
+
+// OnConflictMerge copies the given fields of the new document onto the existing
+// one and leaves its other fields unchanged. Dotted paths are supported.
+// Versioned documents have their stored version incremented.
+func OnConflictMerge(fields ...string) OnConflict {
+	return OnConflict{action: conflictMerge, fields: fields}
+}
+
+// ConflictResult reports the outcome of InsertManyOnConflict.
+type ConflictResult struct {
+	// InsertedCount is the number of documents inserted. It is 0 when the
+	// insert fails with an error other than write errors, such as a network
+	// failure, since the number written is then unknown.
+	InsertedCount int64
+
+	// Conflicts holds the indexes, into the input slice, of the documents that
+	// violated a unique index.
+	Conflicts []int
+
+	// ResolvedCount is the number of existing documents replaced or merged into.
+	// It is always 0 with OnConflictIgnore.
+	ResolvedCount int64
+}
+
+// InsertOneOnConflict inserts doc and resolves a unique index violation with
+// policy instead of failing. It reports whether doc conflicted with an existing
+// document.
+//
+// Example:
+//
+//	conflicted, err := repo.InsertOneOnConflict(ctx, user, mongorepo.OnConflictMerge("name", "updated_at"))
+func (r *MongoRepository[T]) InsertOneOnConflict(ctx context.Context, doc *T, policy OnConflict) (conflicted bool, err error) {
+	defer r.track(repository.OpInsertOne, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return false, err
+	}
+
+	res, err := r.insertOnConflict(ctx, []*T{doc}, policy)
+	if err != nil {
+		return false, err
+	}
+	return len(res.Conflicts) > 0, nil
+}
+
+// InsertManyOnConflict inserts docs and resolves unique index violations with
+// policy, so ingestion pipelines can express their dedupe policy declaratively.
+//
+// Behavior:
+//   - Documents are inserted with one unordered InsertMany; conflicts don't stop other inserts
+//   - Conflicting documents are then resolved with one unordered BulkWrite
+//   - Errors other than duplicate keys are returned together with the partial result
+//   - The ID fields of conflicting documents are not updated to the existing _id
+//   - A document deleted between the insert and the resolution is not recreated
+//
+// Example:
+//
+//	res, err := repo.InsertManyOnConflict(ctx, batch, mongorepo.OnConflictIgnore)
+//	log.Printf("inserted %d, skipped %d", res.InsertedCount, len(res.Conflicts))
+func (r *MongoRepository[T]) InsertManyOnConflict(ctx context.Context, docs []*T, policy OnConflict) (_ *ConflictResult, err error) {
+	defer r.track(repository.OpInsertMany, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+	return r.insertOnConflict(ctx, docs, policy)
+}
+
+func (r *MongoRepository[T]) insertOnConflict(ctx context.Context, docs []*T, policy OnConflict) (*ConflictResult, error) {
+	res := &ConflictResult{}
+	if len(docs) == 0 {
+		return res, nil
+	}
+
+	now := nowUTC()
+	insertDocs := make([]any, len(docs))
+	for i, doc := range docs {
+		if err := prepareInsert(ctx, doc, now); err != nil {
+			return nil, err
+		}
+		insertDocs[i] = doc
+	}
+	if err := r.settings.sealAll(ctx, insertDocs); err != nil {
+		return nil, err
+	}
+
+	_, conflicts, err := insertUnordered(ctx, r.coll, insertDocs)
+	res.InsertedCount = insertedCount(len(docs), err)
+	for _, c := range conflicts {
+		res.Conflicts = append(res.Conflicts, c.Index)
+	}
+	if err != nil || policy.action == conflictIgnore || len(conflicts) == 0 {
+		return res, err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(conflicts))
+	for _, c := range conflicts {
+		model, err := resolveModel(policy, c, docs[c.Index], now)
+		if err != nil {
+			return res, err
+		}
+		models = append(models, model)
+	}
+	bw, err := r.coll.BulkWrite(ctx, models, mopt.BulkWrite().SetOrdered(false))
+	if bw != nil {
+		res.ResolvedCount = bw.MatchedCount
+	}
+	return res, err
+}
+
+// insertUnordered inserts docs without stopping at the first error. It returns
+// the _id of every document (inserted or not), and the duplicate key errors
+// separately from any other failure.
+func insertUnordered(ctx context.Context, coll *mongo.Collection, docs []any) ([]any, []mongo.WriteError, error) {
+	res, err := coll.InsertMany(ctx, docs, mopt.InsertMany().SetOrdered(false))
+	var ids []any
+	if res != nil {
+		ids = res.InsertedIDs
+	}
+	if err == nil {
+		return ids, nil, nil
+	}
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) {
+		return ids, nil, err
+	}
+
+	var dups []mongo.WriteError
+	others := 0
+	for _, we := range bwe.WriteErrors {
+		if we.Code == 11000 {
+			dups = append(dups, we.WriteError)
+		} else {
+			others++
+		}
+	}
+	if others > 0 || bwe.WriteConcernError != nil {
+		return ids, dups, err
+	}
+	return ids, dups, nil
+}
+
+// insertedCount returns how many of n documents an unordered insert that
+// returned err wrote. Every write error, duplicate key or not, marks one
+// document as not inserted. Any other error leaves the count unknown, so 0 is
+// reported.
+func insertedCount(n int, err error) int64 {
+	if err == nil {
+		return int64(n)
+	}
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) {
+		return 0
+	}
+	failed := make(map[int]bool, len(bwe.WriteErrors))
+	for _, we := range bwe.WriteErrors {
+		failed[we.Index] = true
+	}
+	return int64(n - len(failed))
+}
+
+// resolveModel builds the write that applies policy to the existing document
+// identified by a duplicate key error.
+func resolveModel[T any](policy OnConflict, we mongo.WriteError, doc *T, now time.Time) (mongo.WriteModel, error) {
+	filter, err := conflictKey(we)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := toBsonM(doc)
+	if err != nil {
+		return nil, err
+	}
+	delete(fields, "_id")
+
+	if policy.action == conflictReplace {
+		if !isVersioned[T]() {
+			return mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(fields), nil
+		}
+		// A replacement cannot read the stored version, so replace through an
+		// update pipeline that keeps _id and increments the version.
+		replacement := bson.M{"$mergeObjects": bson.A{
+			bson.M{"$literal": fields},
+			bson.M{"_id": "$_id", document.VersionField: versionIncExpr()[document.VersionField]},
+		}}
+		pipeline := mongo.Pipeline{{{Key: "$replaceWith", Value: replacement}}}
+		return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(pipeline), nil
+	}
+
+	set := bson.M{}
+	for _, f := range policy.fields {
+		if v, ok := match.Lookup(fields, f); ok {
+			set[f] = v
+		}
+	}
+	update := injectUpdatedAt(bson.M{"$set": set}, now)
+	if isVersioned[T]() {
+		update = injectVersionInc(update)
+	}
+	return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update), nil
+}
+
+// conflictKey returns the key of the existing document from a duplicate key
+// error, as reported by the server in keyValue.
+func conflictKey(we mongo.WriteError) (bson.M, error) {
+	raw, err := we.Raw.LookupErr("keyValue")
+	if err != nil {
+		return nil, fmt.Errorf("%w: server did not report the conflicting key", repository.ErrDuplicateKey)
+	}
+	var key bson.M
+	if err := raw.Unmarshal(&key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func toBsonM(v any) (bson.M, error) {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m bson.M
+	if err := bson.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,66 @@
+package mongorepo
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestInsertedCount(t *testing.T) {
+	mixed := mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{
+		{WriteError: mongo.WriteError{Index: 1, Code: 11000}},
+		{WriteError: mongo.WriteError{Index: 3, Code: 121}},
+	}}
+	concern := mongo.BulkWriteException{WriteConcernError: &mongo.WriteConcernError{Code: 64}}
+
+	tests := []struct {
+		name string
+		err  error
+		want int64
+	}{
+		{"no error", nil, 5},
+		{"duplicate and validation errors", mixed, 3},
+		{"write concern error only", concern, 5},
+		{"network error", errors.New("connection reset"), 0},
+	}
+	for _, tt := range tests {
+		if got := insertedCount(5, tt.err); got != tt.want {
+			t.Errorf("%s: insertedCount = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+type versionedNote struct {
+	document.Base      `bson:",inline"`
+	document.Versioned `bson:",inline"`
+	Body               string `bson:"body"`
+}
+
+func TestResolveModel_IncrementsVersion(t *testing.T) {
+	raw, _ := bson.Marshal(bson.M{"keyValue": bson.M{"body": "x"}})
+	we := mongo.WriteError{Code: 11000, Raw: raw}
+	doc := &versionedNote{Body: "x"}
+
+	model, err := resolveModel(OnConflictMerge("body"), we, doc, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := model.(*mongo.UpdateOneModel).Update.(bson.M)
+	if inc, _ := update["$inc"].(bson.M); inc[document.VersionField] != int64(1) {
+		t.Fatalf("merge: expected version $inc, got %#v", update)
+	}
+
+	model, err = resolveModel(OnConflictReplace, we, doc, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline, ok := model.(*mongo.UpdateOneModel).Update.(mongo.Pipeline)
+	if !ok || len(pipeline) != 1 || pipeline[0][0].Key != "$replaceWith" {
+		t.Fatalf("replace: expected $replaceWith pipeline, got %#v", model)
+	}
+}
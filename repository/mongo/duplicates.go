@@ -0,0 +1,189 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FindDuplicates returns groups of documents sharing the same values for keyFields.
+// Groups are ordered by size, largest first. Documents missing a key field are
+// grouped under a null value for it.
+//
+// MongoDB equivalent:
+//
+//	db.collection.aggregate([
+//	    {$group: {_id: {email: "$email"}, docs: {$push: "$$ROOT"}, count: {$sum: 1}}},
+//	    {$match: {count: {$gt: 1}}},
+//	    {$sort: {count: -1}},
+//	], {allowDiskUse: true})
+//
+// Example:
+//
+//	groups, err := repo.FindDuplicates(ctx, "email")
+//	for _, g := range groups {
+//	    log.Printf("%v appears %d times", g.Key["email"], len(g.Docs))
+//	}
+func (r *MongoRepository[T]) FindDuplicates(ctx context.Context, keyFields ...string) ([]repository.DuplicateGroup[T], error) {
+	return r.findDuplicates(ctx, nil, keyFields)
+}
+
+func (r *MongoRepository[T]) findDuplicates(ctx context.Context, match bson.M, keyFields []string) (_ []repository.DuplicateGroup[T], err error) {
+	defer r.track(repository.OpAggregate, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	if len(keyFields) == 0 {
+		return nil, errors.New("mongorepo: FindDuplicates requires at least one key field")
+	}
+
+	key := bson.D{}
+	for _, f := range keyFields {
+		key = append(key, bson.E{Key: f, Value: "$" + f})
+	}
+
+	pipeline := []bson.M{}
+	if match != nil {
+		pipeline = append(pipeline, bson.M{"$match": match})
+	}
+	pipeline = append(pipeline,
+		bson.M{"$group": bson.M{"_id": key, "docs": bson.M{"$push": "$$ROOT"}, "count": bson.M{"$sum": 1}}},
+		bson.M{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+		bson.M{"$sort": bson.M{"count": -1}},
+	)
+
+	aggOpts := r.aggregateOptions(ctx).SetAllowDiskUse(true)
+	cur, err := r.coll.Aggregate(ctx, pipeline, aggOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var raw []struct {
+		Key  bson.M     `bson:"_id"`
+		Docs []bson.Raw `bson:"docs"`
+	}
+	if err := cur.All(ctx, &raw); err != nil {
+		return nil, err
+	}
+
+	groups := make([]repository.DuplicateGroup[T], len(raw))
+	for i, g := range raw {
+		docs := make([]T, len(g.Docs))
+		for j := range g.Docs {
+			if err := open[T](ctx, r.settings, g.Docs[j], &docs[j]); err != nil {
+				return nil, err
+			}
+			if err := r.settings.afterLoad(ctx, &docs[j]); err != nil {
+				return nil, err
+			}
+		}
+		groups[i] = repository.DuplicateGroup[T]{Key: g.Key, Docs: docs}
+	}
+	return groups, nil
+}
+
+// MergeDuplicates collapses duplicates into a survivor: references declared with
+// WithReferences are re-pointed from the duplicates to the survivor, then the
+// duplicates are deleted, all in one transaction.
+// Returns the number of duplicates removed.
+//
+// Behavior:
+//   - Only scalar foreign keys are re-pointed; array foreign keys are left unchanged
+//   - Declared OnDelete actions are not applied to the duplicates, since their
+//     references have been moved to the survivor
+//   - Returns ErrNotFound if the survivor does not exist
+//   - Requires a replica set or sharded cluster; an existing session transaction is reused
+//
+// Example:
+//
+//	for _, g := range groups {
+//	    survivor := g.Docs[0]
+//	    ids := make([]any, 0, len(g.Docs)-1)
+//	    for _, d := range g.Docs[1:] {
+//	        ids = append(ids, d.ID)
+//	    }
+//	    if _, err := repo.MergeDuplicates(ctx, survivor.ID, ids); err != nil {
+//	        return err
+//	    }
+//	}
+func (r *MongoRepository[T]) MergeDuplicates(ctx context.Context, survivorID any, duplicateIDs []any) (int64, error) {
+	return r.mergeDuplicates(ctx, survivorID, duplicateIDs, func(ctx context.Context, filter bson.M) (int64, error) {
+		res, err := r.coll.DeleteMany(ctx, filter)
+		if err != nil {
+			return 0, err
+		}
+		return res.DeletedCount, nil
+	})
+}
+
+// mergeDuplicates re-points references and calls remove for the duplicates.
+func (r *MongoRepository[T]) mergeDuplicates(ctx context.Context, survivorID any, duplicateIDs []any, remove func(context.Context, bson.M) (int64, error)) (int64, error) {
+	if len(duplicateIDs) == 0 {
+		return 0, nil
+	}
+	defer r.settings.cache.clear()
+
+	keys := make([]string, 0, len(r.settings.references))
+	for _, ref := range r.settings.references {
+		keys = append(keys, ref.localKey())
+	}
+
+	var removed int64
+	err := inTransaction(ctx, r.coll, func(ctx context.Context) error {
+		removed = 0
+		survivors, err := findKeyDocs(ctx, r.coll, bson.M{"_id": survivorID}, keys, 1)
+		if err != nil {
+			return err
+		}
+		if len(survivors) == 0 {
+			return ErrNotFound
+		}
+		dups, err := findKeyDocs(ctx, r.coll, bson.M{"_id": bson.M{"$in": duplicateIDs}}, keys, 0)
+		if err != nil || len(dups) == 0 {
+			return err
+		}
+
+		for _, ref := range r.settings.references {
+			target := keyValues(survivors, ref.localKey())
+			old := keyValues(dups, ref.localKey())
+			if len(target) != 1 || len(old) == 0 {
+				continue
+			}
+			_, err := ref.Collection.UpdateMany(ctx,
+				bson.M{ref.ForeignKey: bson.M{"$in": old, "$not": bson.M{"$type": "array"}}},
+				bson.M{"$set": bson.M{ref.ForeignKey: target[0]}},
+			)
+			if err != nil {
+				return fmt.Errorf("re-point %s.%s: %w", ref.Collection.Name(), ref.ForeignKey, err)
+			}
+		}
+
+		removed, err = remove(ctx, bson.M{"_id": bson.M{"$in": keyValues(dups, "_id")}})
+		return err
+	})
+	return removed, err
+}
+
+// FindDuplicates returns groups of non-deleted documents sharing the same values for keyFields.
+func (r *SoftDeleteRepository[T]) FindDuplicates(ctx context.Context, keyFields ...string) ([]repository.DuplicateGroup[T], error) {
+	return r.findDuplicates(ctx, r.notDeletedFilter(), keyFields)
+}
+
+// MergeDuplicates re-points references from the duplicates to the survivor and
+// soft-deletes the duplicates, in one transaction.
+func (r *SoftDeleteRepository[T]) MergeDuplicates(ctx context.Context, survivorID any, duplicateIDs []any) (int64, error) {
+	return r.mergeDuplicates(ctx, survivorID, duplicateIDs, func(ctx context.Context, filter bson.M) (int64, error) {
+		res, err := r.coll.UpdateMany(ctx, r.combineWithNotDeleted(filter), bson.M{"$set": bson.M{r.settings.deletedField(): nowUTC()}})
+		if err != nil {
+			return 0, err
+		}
+		return res.ModifiedCount, nil
+	})
+}
@@ -0,0 +1,339 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/encryption"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrEncryptorRequired is returned when a document with fields marked
+// mongox:"encrypt" is written by a repository without WithEncryption, so
+// they are never stored in plaintext by mistake.
+var ErrEncryptorRequired = errors.New("mongorepo: document has encrypted fields but no encryptor is configured")
+
+// ErrEncryptedUpdate is returned for an update that writes a field marked
+// mongox:"encrypt" in a way the repository cannot encrypt: with an operator
+// other than $set and $setOnInsert, by setting the subdocument holding it, or
+// from an update pipeline.
+var ErrEncryptedUpdate = errors.New("mongorepo: update writes an encrypted field it cannot encrypt")
+
+// WithEncryption encrypts the fields of documents marked with the
+// mongox:"encrypt" tag with enc when they are written, after BeforeSave, and
+// decrypts them when they are read, before AfterLoad. Values that $set and
+// $setOnInsert updates write to marked fields are encrypted too; other updates
+// of marked fields fail with ErrEncryptedUpdate. Raw results such as
+// StreamJSON and AggregateRaw keep the stored ciphertext. See package
+// encryption.
+//
+// Example:
+//
+//	enc := encryption.NewAESGCM(encryption.StaticKeys("k1", map[string][]byte{"k1": key}))
+//	repo := mongorepo.New[Patient](coll, mongorepo.WithEncryption(enc))
+func WithEncryption(enc encryption.Encryptor) Option {
+	return func(s *settings) { s.encryptor = enc }
+}
+
+// seal returns doc as it is written: encoded with its fields marked for
+// encryption encrypted, or doc itself if it has none. Call it once doc is
+// final, e.g. after its version is bumped, since later changes are not
+// written.
+func (s settings) seal(ctx context.Context, doc any) (any, error) {
+	if doc == nil {
+		return doc, nil
+	}
+	paths := encryption.Paths(reflect.TypeOf(doc))
+	if len(paths) == 0 {
+		return doc, nil
+	}
+	if s.encryptor == nil {
+		return nil, ErrEncryptorRequired
+	}
+	d, err := asDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := encryption.EncryptDocument(ctx, s.encryptor, d, paths); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// sealAll replaces each of docs with the result of seal.
+func (s settings) sealAll(ctx context.Context, docs []any) error {
+	for i, doc := range docs {
+		sealed, err := s.seal(ctx, doc)
+		if err != nil {
+			return err
+		}
+		docs[i] = sealed
+	}
+	return nil
+}
+
+// sealUpdate returns update with the values it writes to fields of T marked
+// for encryption encrypted. update itself is not modified.
+func sealUpdate[T any](ctx context.Context, s settings, update any) (any, error) {
+	paths := encryption.Paths(reflect.TypeFor[T]())
+	if len(paths) == 0 || update == nil {
+		return update, nil
+	}
+
+	switch u := update.(type) {
+	case bson.M:
+		out := make(bson.M, len(u))
+		for op, fields := range u {
+			sealed, err := sealOperator(ctx, s, paths, op, fields)
+			if err != nil {
+				return nil, err
+			}
+			out[op] = sealed
+		}
+		return out, nil
+	case bson.D:
+		out := make(bson.D, len(u))
+		for i, e := range u {
+			sealed, err := sealOperator(ctx, s, paths, e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = bson.E{Key: e.Key, Value: sealed}
+		}
+		return out, nil
+	}
+
+	if _, raw := update.(bson.Raw); !raw && reflect.ValueOf(update).Kind() == reflect.Slice {
+		v := reflect.ValueOf(update)
+		// Pipeline stages compute values on the server, where they cannot be
+		// encrypted, so only stages that leave marked fields alone are allowed.
+		for i := range v.Len() {
+			stage, err := asDoc(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range stage {
+				if err := checkStage(paths, e.Key, e.Value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return update, nil
+	}
+	doc, err := asDoc(update)
+	if err != nil {
+		return nil, err
+	}
+	return sealUpdate[T](ctx, s, doc)
+}
+
+// sealOperator encrypts the values op writes to the marked paths, or fails
+// with ErrEncryptedUpdate if it writes them in another way.
+func sealOperator(ctx context.Context, s settings, paths []string, op string, fields any) (any, error) {
+	doc, err := asDoc(fields)
+	if err != nil {
+		return nil, err
+	}
+	out := make(bson.D, 0, len(doc))
+	for _, e := range doc {
+		exact, overlaps := touches(paths, e.Key)
+		if target, ok := e.Value.(string); op == "$rename" && ok && !overlaps {
+			exact, overlaps = touches(paths, target)
+		}
+		switch {
+		case !overlaps || op == "$unset":
+		case exact && (op == "$set" || op == "$setOnInsert"):
+			if s.encryptor == nil {
+				return nil, ErrEncryptorRequired
+			}
+			sealed, err := encryption.EncryptValue(ctx, s.encryptor, e.Key, e.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s %s: %w", ErrEncryptedUpdate, op, e.Key, err)
+			}
+			e.Value = sealed
+		default:
+			return nil, fmt.Errorf("%w: %s %s", ErrEncryptedUpdate, op, e.Key)
+		}
+		out = append(out, e)
+	}
+	if _, ok := fields.(bson.M); ok {
+		return out.Map(), nil
+	}
+	return out, nil
+}
+
+// checkStage fails with ErrEncryptedUpdate if an update pipeline stage writes
+// one of the marked paths.
+func checkStage(paths []string, name string, spec any) error {
+	switch name {
+	case "$set", "$addFields", "$project":
+		fields, err := asDoc(spec)
+		if err != nil {
+			return err
+		}
+		for _, e := range fields {
+			if _, overlaps := touches(paths, e.Key); overlaps {
+				if name == "$project" && isExclusion(e.Value) {
+					continue
+				}
+				return fmt.Errorf("%w: pipeline %s %s", ErrEncryptedUpdate, name, e.Key)
+			}
+		}
+	case "$replaceWith", "$replaceRoot":
+		return fmt.Errorf("%w: pipeline %s", ErrEncryptedUpdate, name)
+	}
+	return nil
+}
+
+// isExclusion reports whether a $project value removes the field.
+func isExclusion(v any) bool {
+	switch v := v.(type) {
+	case bool:
+		return !v
+	case int:
+		return v == 0
+	case int32:
+		return v == 0
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	}
+	return false
+}
+
+// touches reports whether the update path key is one of paths (exact), or
+// overlaps one by being its parent or child.
+func touches(paths []string, key string) (exact, overlaps bool) {
+	for _, p := range paths {
+		switch {
+		case key == p:
+			return true, true
+		case strings.HasPrefix(p, key+"."), strings.HasPrefix(key, p+"."):
+			overlaps = true
+		}
+	}
+	return false, overlaps
+}
+
+// asDoc returns v as an ordered document.
+func asDoc(v any) (bson.D, error) {
+	switch v := v.(type) {
+	case bson.D:
+		return v, nil
+	case bson.M:
+		d := make(bson.D, 0, len(v))
+		for k, val := range v {
+			d = append(d, bson.E{Key: k, Value: val})
+		}
+		return d, nil
+	}
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var d bson.D
+	if err := bson.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// keepID returns projection without an exclusion of _id, so that values
+// bound to their document's _id can be decrypted.
+func keepID(projection any) (any, error) {
+	if projection == nil {
+		return nil, nil
+	}
+	d, err := asDoc(projection)
+	if err != nil {
+		return nil, err
+	}
+	out := make(bson.D, 0, len(d))
+	for _, e := range d {
+		if e.Key == "_id" && isExclusion(e.Value) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// decrypts reports whether documents decoded as T have fields to decrypt.
+func decrypts[T any](s settings) bool {
+	return s.encryptor != nil && encryption.HasEncryptedFields(reflect.TypeFor[T]())
+}
+
+// open decodes raw, a stored document, into out, decrypting the fields T
+// marks for encryption first. out need not carry the marks itself, as the
+// projections of FindAs don't.
+func open[T any](ctx context.Context, s settings, raw bson.Raw, out any) error {
+	if !decrypts[T](s) {
+		return bson.Unmarshal(raw, out)
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	if err := encryption.DecryptDocument(ctx, s.encryptor, doc, encryption.Paths(reflect.TypeFor[T]())); err != nil {
+		return err
+	}
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(b, out)
+}
+
+// decodeCurrent decodes the current document of cur into out as open does.
+func decodeCurrent[T any](ctx context.Context, s settings, cur *mongo.Cursor, out any) error {
+	if !decrypts[T](s) {
+		return cur.Decode(out)
+	}
+	return open[T](ctx, s, cur.Current, out)
+}
+
+// decodeAll decodes the remaining documents of cur into out as open does,
+// and closes cur.
+func decodeAll[T, P any](ctx context.Context, s settings, cur *mongo.Cursor, out *[]P) error {
+	if !decrypts[T](s) {
+		return cur.All(ctx, out)
+	}
+	defer cur.Close(ctx)
+	items := (*out)[:0]
+	for cur.Next(ctx) {
+		var p P
+		if err := open[T](ctx, s, cur.Current, &p); err != nil {
+			return err
+		}
+		items = append(items, p)
+	}
+	*out = items
+	return cur.Err()
+}
+
+// decodeResult decodes the document of res into out as open does.
+func decodeResult[T any](ctx context.Context, s settings, res *mongo.SingleResult, out any) error {
+	if !decrypts[T](s) {
+		return res.Decode(out)
+	}
+	raw, err := res.Raw()
+	if err != nil {
+		return err
+	}
+	return open[T](ctx, s, raw, out)
+}
+
+// afterLoad runs the AfterLoad hook of doc, if it has one.
+func (s settings) afterLoad(ctx context.Context, doc any) error {
+	if h, ok := doc.(document.AfterLoad); ok {
+		return h.AfterLoad(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/encryption"
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type secretDoc struct {
+	document.Base `bson:",inline"`
+	Name          string `bson:"name"`
+	SSN           string `bson:"ssn" mongox:"encrypt"`
+}
+
+func TestEncryption_RequiresEncryptor(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; writes must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+	repo := mongorepo.New[secretDoc](client.Database("testdb").Collection("secrets"))
+
+	doc := &secretDoc{Name: "Ada", SSN: "123-45-6789"}
+	if err := repo.InsertOne(ctx, doc); !errors.Is(err, mongorepo.ErrEncryptorRequired) {
+		t.Fatalf("InsertOne: expected ErrEncryptorRequired, got %v", err)
+	}
+	if _, err := repo.InsertMany(ctx, []*secretDoc{doc}); !errors.Is(err, mongorepo.ErrEncryptorRequired) {
+		t.Fatalf("InsertMany: expected ErrEncryptorRequired, got %v", err)
+	}
+	ops := []repository.BulkOp{{Type: repository.BulkOpInsert, Doc: *doc}}
+	if _, err := repo.BulkWrite(ctx, ops); !errors.Is(err, mongorepo.ErrEncryptorRequired) {
+		t.Fatalf("BulkWrite: expected ErrEncryptorRequired, got %v", err)
+	}
+	if doc.SSN != "123-45-6789" {
+		t.Fatalf("expected the document to be unchanged, got %q", doc.SSN)
+	}
+}
+
+func TestEncryption_Updates(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; rejected updates must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+	coll := client.Database("testdb").Collection("secrets")
+	enc := encryption.NewAESGCM(encryption.StaticKeys("k1", map[string][]byte{"k1": make([]byte, 32)}))
+
+	plain := mongorepo.New[secretDoc](coll)
+	if _, _, err := plain.UpdateOne(ctx, spec.Eq("name", "Ada"), spec.Set("ssn", "123-45-6789")); !errors.Is(err, mongorepo.ErrEncryptorRequired) {
+		t.Fatalf("UpdateOne: expected ErrEncryptorRequired, got %v", err)
+	}
+
+	repo := mongorepo.New[secretDoc](coll, mongorepo.WithEncryption(enc))
+	rejected := []struct {
+		name   string
+		update any
+	}{
+		{"$push", bson.M{"$push": bson.M{"ssn": "x"}}},
+		{"$rename", bson.M{"$rename": bson.M{"name": "ssn"}}},
+		{"non-string value", spec.Set("ssn", 42)},
+		{"ciphertext", spec.Set("ssn", primitive.Binary{Subtype: encryption.Subtype, Data: []byte{2, 0}})},
+		{"pipeline", mongo.Pipeline{{{Key: "$set", Value: bson.M{"ssn": "$name"}}}}},
+	}
+	for _, tt := range rejected {
+		if _, _, err := repo.UpdateMany(ctx, spec.Eq("name", "Ada"), tt.update); !errors.Is(err, mongorepo.ErrEncryptedUpdate) {
+			t.Errorf("%s: expected ErrEncryptedUpdate, got %v", tt.name, err)
+		}
+	}
+	if _, err := repo.FindOneAndUpdate(ctx, spec.Eq("name", "Ada"), bson.M{"$inc": bson.M{"ssn": 1}}); !errors.Is(err, mongorepo.ErrEncryptedUpdate) {
+		t.Fatalf("FindOneAndUpdate: expected ErrEncryptedUpdate, got %v", err)
+	}
+	ops := []repository.BulkOp{repository.UpdateOp(spec.Eq("name", "Ada"), bson.M{"$addToSet": bson.M{"ssn": "x"}})}
+	if _, err := repo.BulkWrite(ctx, ops); !errors.Is(err, mongorepo.ErrEncryptedUpdate) {
+		t.Fatalf("BulkWrite: expected ErrEncryptedUpdate, got %v", err)
+	}
+
+	// Unmarked fields and $unset are left alone, so the update reaches the
+	// driver and fails with ctx's error.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	update := bson.M{"$set": bson.M{"name": "Grace"}, "$unset": bson.M{"ssn": ""}}
+	if _, _, err := repo.UpdateOne(canceled, spec.Eq("name", "Ada"), update); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the update to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package mongorepo
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// ColumnSpec maps a document field to a CSV column.
+type ColumnSpec struct {
+	// Header is the column title. Defaults to Field.
+	Header string
+
+	// Field is the BSON field to export; dotted paths reach into subdocuments.
+	Field string
+
+	// Format converts the field value to a cell. Missing fields are passed as
+	// a zero RawValue. Defaults to the built-in formatting described on ExportCSV.
+	Format func(v bson.RawValue) string
+}
+
+var columnsCache sync.Map // reflect.Type -> []ColumnSpec
+
+// ColumnsOf derives CSV columns from the struct tags of T, in field order.
+// The header comes from the `csv` tag and defaults to the BSON field name;
+// fields tagged `csv:"-"` are skipped. The result is cached per type and must
+// be treated as read-only.
+//
+// Example:
+//
+//	type Order struct {
+//	    document.Base `bson:",inline"`
+//	    Number string  `bson:"number" csv:"Order #"`
+//	    Total  float64 `bson:"total" csv:"Total"`
+//	    Notes  string  `bson:"notes" csv:"-"`
+//	}
+//
+//	ColumnsOf[Order]()  // _id, created_at, updated_at, Order #, Total
+func ColumnsOf[T any]() []ColumnSpec {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if cached, ok := columnsCache.Load(t); ok {
+		return cached.([]ColumnSpec)
+	}
+
+	var cols []ColumnSpec
+	walkStructFields(t, func(name string, field reflect.StructField) {
+		header, ok := field.Tag.Lookup("csv")
+		if header == "-" {
+			return
+		}
+		if !ok || header == "" {
+			header = name
+		}
+		cols = append(cols, ColumnSpec{Header: header, Field: name})
+	})
+
+	columnsCache.Store(t, cols)
+	return cols
+}
+
+// ExportCSV writes the documents matching the filter to w as CSV, streaming
+// them from a cursor so exports of any size use constant memory. Only the
+// exported fields are fetched.
+//
+// If columns is empty, they are derived from T with ColumnsOf. opts apply as
+// in Find, except that the projection is always derived from the columns. A
+// FindPolicy only supplies the default sort; its MaxLimit does not apply.
+// Documents are decoded into T when it has encrypted fields or an AfterLoad
+// hook, so cells show the decrypted and loaded values; fields T does not
+// declare are exported as stored.
+//
+// Default cell formatting:
+//   - Strings as-is; numbers in plain decimal notation; booleans as true/false
+//   - Dates in RFC 3339 (UTC); ObjectIDs in hex
+//   - Arrays as their formatted elements joined with ";"
+//   - Subdocuments as extended JSON; missing and null fields as empty cells
+//
+// Text cells starting with =, +, -, @, tab, or carriage return are prefixed with
+// a single quote so spreadsheet applications such as Excel do not evaluate them
+// as formulas. Numeric cells are never altered.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "text/csv")
+//	w.Header().Set("Content-Disposition", `attachment; filename="orders.csv"`)
+//	err := repo.ExportCSV(ctx, w, spec.Eq("status", "paid"), []mongorepo.ColumnSpec{
+//	    {Header: "Order", Field: "number"},
+//	    {Header: "Customer", Field: "customer.name"},
+//	    {Header: "Total", Field: "total"},
+//	}, repository.WithSort(bson.D{{Key: "number", Value: 1}}))
+func (r *MongoRepository[T]) ExportCSV(ctx context.Context, w io.Writer, filter any, columns []ColumnSpec, opts ...repository.FindOption) (err error) {
+	defer r.track(repository.OpFind, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return err
+	}
+
+	if len(columns) == 0 {
+		columns = ColumnsOf[T]()
+	}
+	fo := applyFindOptions(opts)
+	f, err := r.prepareScan(ctx, filter, &fo)
+	if err != nil {
+		return err
+	}
+	fo.Projection = exportProjection(columns)
+	if decrypts[T](r.settings) {
+		if fo.Projection, err = keepID(fo.Projection); err != nil {
+			return err
+		}
+	}
+
+	cur, err := r.findCursor(ctx, f, fo)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	cw := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, c := range columns {
+		record[i] = c.Header
+		if record[i] == "" {
+			record[i] = c.Field
+		}
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	_, hooked := any(new(T)).(document.AfterLoad)
+	decode := hooked || decrypts[T](r.settings)
+	for cur.Next(ctx) {
+		var loaded bson.Raw
+		if decode {
+			if loaded, err = r.loadRaw(ctx, cur.Current); err != nil {
+				return err
+			}
+		}
+		for i, c := range columns {
+			var v bson.RawValue
+			if c.Field != "" {
+				path := strings.Split(c.Field, ".")
+				v, _ = cur.Current.LookupErr(path...)
+				if v.Type != 0 && loaded != nil {
+					if lv, err := loaded.LookupErr(path...); err == nil {
+						v = lv
+					}
+				}
+			}
+			if c.Format != nil {
+				record[i] = escapeFormula(c.Format(v))
+				continue
+			}
+			cell, numeric := formatCell(v)
+			if !numeric {
+				cell = escapeFormula(cell)
+			}
+			record[i] = cell
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportCSV writes the non-deleted documents matching the filter to w as CSV.
+func (r *SoftDeleteRepository[T]) ExportCSV(ctx context.Context, w io.Writer, filter any, columns []ColumnSpec, opts ...repository.FindOption) error {
+	return r.MongoRepository.ExportCSV(ctx, w, r.combineWithNotDeleted(filter), columns, opts...)
+}
+
+// ExportCSVWithDeleted writes the documents matching the filter to w as CSV,
+// including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) ExportCSVWithDeleted(ctx context.Context, w io.Writer, filter any, columns []ColumnSpec, opts ...repository.FindOption) error {
+	return r.MongoRepository.ExportCSV(ctx, w, filter, columns, opts...)
+}
+
+// loadRaw decodes raw into T as Find does, decrypting it and running its
+// AfterLoad hook, and returns the result encoded again.
+func (r *MongoRepository[T]) loadRaw(ctx context.Context, raw bson.Raw) (bson.Raw, error) {
+	var doc T
+	if err := open[T](ctx, r.settings, raw, &doc); err != nil {
+		return nil, err
+	}
+	if err := r.settings.afterLoad(ctx, &doc); err != nil {
+		return nil, err
+	}
+	return bson.Marshal(&doc)
+}
+
+// exportProjection includes every column field once. Paths inside another
+// included path are dropped, since MongoDB rejects overlapping projections.
+func exportProjection(columns []ColumnSpec) bson.D {
+	var paths []string
+	for _, c := range columns {
+		if c.Field != "" {
+			paths = append(paths, c.Field)
+		}
+	}
+	sort.Strings(paths)
+
+	projection := bson.D{}
+	hasID := false
+next:
+	for _, p := range paths {
+		for _, kept := range projection {
+			if p == kept.Key || strings.HasPrefix(p, kept.Key+".") {
+				continue next
+			}
+		}
+		if p == "_id" || strings.HasPrefix(p, "_id.") {
+			hasID = true
+		}
+		projection = append(projection, bson.E{Key: p, Value: 1})
+	}
+	if !hasID {
+		projection = append(projection, bson.E{Key: "_id", Value: 0})
+	}
+	return projection
+}
+
+// formatCell renders a BSON value as CSV text and reports whether it is a number.
+func formatCell(v bson.RawValue) (string, bool) {
+	switch v.Type {
+	case 0, bsontype.Null, bsontype.Undefined:
+		return "", false
+	case bsontype.String:
+		return v.StringValue(), false
+	case bsontype.Int32:
+		return strconv.FormatInt(int64(v.Int32()), 10), true
+	case bsontype.Int64:
+		return strconv.FormatInt(v.Int64(), 10), true
+	case bsontype.Double:
+		return strconv.FormatFloat(v.Double(), 'f', -1, 64), true
+	case bsontype.Decimal128:
+		return v.Decimal128().String(), true
+	case bsontype.Boolean:
+		return strconv.FormatBool(v.Boolean()), false
+	case bsontype.DateTime:
+		return v.Time().UTC().Format(time.RFC3339Nano), false
+	case bsontype.ObjectID:
+		return v.ObjectID().Hex(), false
+	case bsontype.Array:
+		values, err := v.Array().Values()
+		if err != nil {
+			return v.String(), false
+		}
+		parts := make([]string, len(values))
+		for i, e := range values {
+			parts[i], _ = formatCell(e)
+		}
+		return strings.Join(parts, ";"), false
+	default:
+		return v.String(), false
+	}
+}
+
+// escapeFormula neutralizes cells that spreadsheet applications would evaluate.
+func escapeFormula(s string) string {
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,52 @@
+package mongorepo_test
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type invoiceRow struct {
+	document.Base `bson:",inline"`
+	Number        string  `bson:"number" csv:"Invoice #"`
+	Total         float64 `bson:"total"`
+	Notes         string  `bson:"notes" csv:"-"`
+	Internal      string  `bson:"-" csv:"Internal"`
+}
+
+func TestColumnsOf(t *testing.T) {
+	got := mongorepo.ColumnsOf[invoiceRow]()
+	want := []mongorepo.ColumnSpec{
+		{Header: "_id", Field: "_id"},
+		{Header: "created_at", Field: "created_at"},
+		{Header: "updated_at", Field: "updated_at"},
+		{Header: "Invoice #", Field: "number"},
+		{Header: "total", Field: "total"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ColumnsOf mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestStreamJSON_RejectsUnknownFormat(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; StreamJSON must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	repo := mongorepo.New[invoiceRow](client.Database("testdb").Collection("invoices"))
+	if err := repo.StreamJSON(ctx, io.Discard, nil, mongorepo.JSONFormat(7)); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,325 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ModifyOption configures FindOneAndUpdate, FindOneAndReplace, and FindOneAndDelete.
+type ModifyOption func(*modifyConfig)
+
+type modifyConfig struct {
+	after      bool
+	upsert     bool
+	sort       any
+	projection any
+}
+
+func applyModifyOptions(opts []ModifyOption) modifyConfig {
+	var c modifyConfig
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&c)
+		}
+	}
+	return c
+}
+
+// ReturnBefore returns the document as it was before the update or replace.
+// This is the default.
+func ReturnBefore() ModifyOption {
+	return func(c *modifyConfig) { c.after = false }
+}
+
+// ReturnAfter returns the document as it is after the update or replace.
+// It has no effect on FindOneAndDelete.
+func ReturnAfter() ModifyOption {
+	return func(c *modifyConfig) { c.after = true }
+}
+
+// WithModifyUpsert inserts a document when none matches the filter.
+// It has no effect on FindOneAndDelete.
+func WithModifyUpsert() ModifyOption {
+	return func(c *modifyConfig) { c.upsert = true }
+}
+
+// WithModifySort picks which document is modified when several match, e.g.
+// the oldest pending job with bson.D{{"created_at", 1}}.
+func WithModifySort(sort any) ModifyOption {
+	return func(c *modifyConfig) { c.sort = sort }
+}
+
+// WithModifyProjection limits the fields of the returned document. It is
+// ignored by FindOneAndDelete on repositories with references, which need the
+// whole document.
+func WithModifyProjection(projection any) ModifyOption {
+	return func(c *modifyConfig) { c.projection = projection }
+}
+
+func (c modifyConfig) returnDocument() mopt.ReturnDocument {
+	if c.after {
+		return mopt.After
+	}
+	return mopt.Before
+}
+
+// FindOneAndUpdate atomically updates the first document matching the filter
+// and returns it, so counters and claim-work patterns need no separate read.
+// Returns ErrNotFound if no document matches.
+//
+// Behavior:
+//   - The document is returned as it was before the update unless ReturnAfter is given
+//   - updated_at is added to $set updates, as in UpdateOne
+//   - With WithModifyUpsert and no match a document is inserted; ReturnBefore then reports ErrNotFound
+//   - The AfterLoad hook is called on the returned document
+//
+// MongoDB equivalent: db.collection.findOneAndUpdate(filter, update, options)
+//
+// Example:
+//
+//	// Claim the oldest pending job.
+//	job, err := jobs.FindOneAndUpdate(ctx,
+//	    spec.Eq("status", "pending"),
+//	    spec.Set("status", "running"),
+//	    mongorepo.WithModifySort(bson.D{{"created_at", 1}}),
+//	    mongorepo.ReturnAfter(),
+//	)
+//	if errors.Is(err, repository.ErrNotFound) {
+//	    return nil // nothing to do
+//	}
+func (r *MongoRepository[T]) FindOneAndUpdate(ctx context.Context, filter any, update any, opts ...ModifyOption) (_ *T, err error) {
+	defer r.track(repository.OpFindOneAndUpdate, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	c := applyModifyOptions(opts)
+	r.settings.advisor.record(f, c.sort)
+	if err := r.checkQuery(ctx, repository.OpFindOneAndUpdate, f, c.sort); err != nil {
+		return nil, err
+	}
+	defer r.observeShape(repository.OpFindOneAndUpdate, f, c.sort, time.Now(), &err)
+	if update == nil {
+		return nil, repository.ErrNilUpdate
+	}
+
+	u, err := sealUpdate[T](ctx, r.settings, normalizeUpdate(update))
+	if err != nil {
+		return nil, err
+	}
+	u = injectUpdatedAt(u, nowUTC())
+	if isVersioned[T]() {
+		u = injectVersionInc(u)
+	}
+
+	mongoOpts := mopt.FindOneAndUpdate().
+		SetReturnDocument(c.returnDocument()).
+		SetUpsert(c.upsert)
+	if c.sort != nil {
+		mongoOpts.SetSort(c.sort)
+	}
+	if c.projection != nil {
+		mongoOpts.SetProjection(c.projection)
+	}
+
+	return decodeModified[T](ctx, r.settings, r.coll.FindOneAndUpdate(ctx, f, u, mongoOpts))
+}
+
+// FindOneAndReplace atomically replaces the first document matching the filter
+// with doc and returns the replaced document, or doc as stored with ReturnAfter.
+// Returns ErrNotFound if no document matches.
+//
+// Behavior:
+//   - doc is touched, validated, and passed to BeforeSave, as in ReplaceOne
+//   - Versioned documents are only replaced at the version doc was loaded with,
+//     as in ReplaceOne; a stale doc fails with ErrVersionConflict
+//   - With WithModifyUpsert and no match doc is inserted; ReturnBefore then reports ErrNotFound
+//   - The AfterLoad hook is called on the returned document
+//
+// MongoDB equivalent: db.collection.findOneAndReplace(filter, doc, options)
+//
+// Example:
+//
+//	previous, err := repo.FindOneAndReplace(ctx, spec.Eq("_id", cfg.ID), cfg)
+//	if err != nil {
+//	    return err
+//	}
+//	audit.Record(previous, cfg)
+func (r *MongoRepository[T]) FindOneAndReplace(ctx context.Context, filter any, doc *T, opts ...ModifyOption) (_ *T, err error) {
+	defer r.track(repository.OpFindOneAndReplace, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	if doc == nil {
+		return nil, repository.ErrNilDocument
+	}
+
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	c := applyModifyOptions(opts)
+	r.settings.advisor.record(f, c.sort)
+	if err := r.checkQuery(ctx, repository.OpFindOneAndReplace, f, c.sort); err != nil {
+		return nil, err
+	}
+	defer r.observeShape(repository.OpFindOneAndReplace, f, c.sort, time.Now(), &err)
+
+	if t, ok := any(doc).(updateToucher); ok {
+		t.TouchForUpdate(nowUTC())
+	}
+	if v, ok := any(doc).(document.Validatable); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if h, ok := any(doc).(document.BeforeSave); ok {
+		if err := h.BeforeSave(ctx); err != nil {
+			return nil, err
+		}
+	}
+	mongoOpts := mopt.FindOneAndReplace().
+		SetReturnDocument(c.returnDocument()).
+		SetUpsert(c.upsert)
+	if c.sort != nil {
+		mongoOpts.SetSort(c.sort)
+	}
+	if c.projection != nil {
+		mongoOpts.SetProjection(c.projection)
+	}
+
+	v, versioned := any(doc).(document.VersionedDoc)
+	if !versioned {
+		sealed, err := r.settings.seal(ctx, doc)
+		if err != nil {
+			return nil, err
+		}
+		return decodeModified[T](ctx, r.settings, r.coll.FindOneAndReplace(ctx, f, sealed, mongoOpts))
+	}
+
+	// Optimistic locking: only replace the version doc was loaded with.
+	expected := v.CurrentVersion()
+	v.SetVersion(expected + 1)
+	sealed, err := r.settings.seal(ctx, doc)
+	if err != nil {
+		v.SetVersion(expected)
+		return nil, err
+	}
+	out, err := decodeModified[T](ctx, r.settings, r.coll.FindOneAndReplace(ctx, withVersion(f, expected), sealed, mongoOpts))
+	if errors.Is(err, ErrNotFound) {
+		if cerr := r.versionConflict(ctx, f); cerr != nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		v.SetVersion(expected)
+		return nil, err
+	}
+	return out, nil
+}
+
+// FindOneAndDelete atomically deletes the first document matching the filter
+// and returns it, e.g. to pop an item from a queue.
+// Returns ErrNotFound if no document matches.
+//
+// Behavior:
+//   - References declared with WithReferences are enforced as in DeleteOne
+//   - The AfterLoad hook is called on the returned document
+//
+// MongoDB equivalent: db.collection.findOneAndDelete(filter, options)
+//
+// Example:
+//
+//	next, err := queue.FindOneAndDelete(ctx, spec.Eq("topic", "emails"),
+//	    mongorepo.WithModifySort(bson.D{{"_id", 1}}),
+//	)
+func (r *MongoRepository[T]) FindOneAndDelete(ctx context.Context, filter any, opts ...ModifyOption) (_ *T, err error) {
+	defer r.track(repository.OpFindOneAndDelete, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	c := applyModifyOptions(opts)
+	r.settings.advisor.record(f, c.sort)
+	if err := r.checkQuery(ctx, repository.OpFindOneAndDelete, f, c.sort); err != nil {
+		return nil, err
+	}
+	defer r.observeShape(repository.OpFindOneAndDelete, f, c.sort, time.Now(), &err)
+
+	mongoOpts := mopt.FindOneAndDelete()
+	if c.sort != nil {
+		mongoOpts.SetSort(c.sort)
+	}
+
+	if len(r.settings.references) == 0 {
+		if c.projection != nil {
+			mongoOpts.SetProjection(c.projection)
+		}
+		return decodeModified[T](ctx, r.settings, r.coll.FindOneAndDelete(ctx, f, mongoOpts))
+	}
+
+	// The reference keys are read from the deleted document, so the
+	// projection is not applied here.
+	var out *T
+	err = inTransaction(ctx, r.coll, func(ctx context.Context) error {
+		raw, err := r.coll.FindOneAndDelete(ctx, f, mongoOpts).Raw()
+		if err != nil {
+			return err
+		}
+		var target bson.M
+		if err := bson.Unmarshal(raw, &target); err != nil {
+			return err
+		}
+		if err := r.applyReferences(ctx, []bson.M{target}); err != nil {
+			return err
+		}
+		out = new(T)
+		return open[T](ctx, r.settings, raw, out)
+	})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := r.settings.afterLoad(ctx, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeModified decodes the result of a find-and-modify command, mapping a
+// missing document to ErrNotFound and calling the AfterLoad hook.
+func decodeModified[T any](ctx context.Context, s settings, res *mongo.SingleResult) (*T, error) {
+	var out T
+	if err := decodeResult[T](ctx, s, res, &out); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// AfterLoad hook.
+	if err := s.afterLoad(ctx, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
@@ -0,0 +1,101 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// hedging configures hedged reads; see WithHedgedReads.
+type hedging struct {
+	delay time.Duration
+	pref  *readpref.ReadPref
+	coll  *mongo.Collection // the repository's collection with pref, set by bind
+}
+
+// bind sets the collection hedged reads are sent to from the repository's
+// collection.
+func (h *hedging) bind(coll *mongo.Collection) {
+	if h == nil || coll == nil {
+		return
+	}
+	if c, err := coll.Clone(mopt.Collection().SetReadPreference(h.pref)); err == nil {
+		h.coll = c
+	}
+}
+
+// WithHedgedReads sends a second, identical FindOne or Count when the first
+// has not answered after delay, and returns whichever answers first. The
+// second read uses pref, nearest if nil, so on a replica set it can be served
+// by another member than the slow one. Use it on latency-critical read paths
+// to cut tail latency at the cost of extra load from slow reads.
+//
+// Behavior:
+//   - The first answer wins, even if it is an error such as ErrNotFound;
+//     the slower read is cancelled
+//   - Reads in a session or transaction, and reads with a per-call read
+//     preference or read concern, are not hedged
+//   - Hedged reads may be served by a secondary, so they can be stale
+//   - A non-positive delay disables hedging
+//
+// Example:
+//
+//	sessions := mongorepo.New[Session](coll,
+//	    mongorepo.WithHedgedReads(20*time.Millisecond, readpref.SecondaryPreferred()))
+func WithHedgedReads(delay time.Duration, pref *readpref.ReadPref) Option {
+	return func(s *settings) {
+		if delay <= 0 {
+			s.hedge = nil
+			return
+		}
+		if pref == nil {
+			pref = readpref.Nearest()
+		}
+		s.hedge = &hedging{delay: delay, pref: pref}
+	}
+}
+
+// hedgedCollection returns the collection for the second read of a hedged
+// read, or nil if the read must not be hedged.
+func (s settings) hedgedCollection(ctx context.Context) *mongo.Collection {
+	if s.hedge == nil || mongo.SessionFromContext(ctx) != nil {
+		return nil
+	}
+	return s.hedge.coll
+}
+
+// hedged runs read on primary and, if it has not returned after delay, on
+// backup as well, and returns the first result. A nil backup runs read on
+// primary only.
+func hedged[V any](ctx context.Context, h *hedging, primary, backup *mongo.Collection, read func(context.Context, *mongo.Collection) (V, error)) (V, error) {
+	if h == nil || backup == nil {
+		return read(ctx, primary)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		v   V
+		err error
+	}
+	results := make(chan result, 2)
+	run := func(coll *mongo.Collection) {
+		v, err := read(ctx, coll)
+		results <- result{v, err}
+	}
+	go run(primary)
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			go run(backup)
+		case res := <-results:
+			return res.v, res.err
+		}
+	}
+}
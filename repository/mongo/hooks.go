@@ -0,0 +1,121 @@
+package mongorepo
+
+import (
+	"context"
+
+	"github.com/dElCIoGio/mongox/document"
+)
+
+// UpdateHook is a repository-level hook called around filter-based updates.
+type UpdateHook func(ctx context.Context, e *document.UpdateEvent) error
+
+// DeleteHook is a repository-level hook called around filter-based deletes.
+type DeleteHook func(ctx context.Context, e *document.DeleteEvent) error
+
+// WithBeforeUpdate registers a hook that runs before UpdateOne, UpsertOne,
+// and UpdateMany (and the helpers built on them, such as UpdateByID). Use it
+// for cross-cutting concerns such as audit fields, so they are not bypassed
+// when callers update with a spec.Update instead of ReplaceOne.
+//
+// Behavior:
+//   - Runs after the document type's BeforeUpdate hook, in registration order
+//   - The hook may replace e.Filter or e.Update
+//   - An error aborts the operation and is returned to the caller
+//
+// Example:
+//
+//	repo := mongorepo.New[Order](coll,
+//	    mongorepo.WithBeforeUpdate(func(ctx context.Context, e *document.UpdateEvent) error {
+//	        log.Printf("%s %v", e.Op, e.Filter)
+//	        return nil
+//	    }),
+//	)
+func WithBeforeUpdate(h UpdateHook) Option {
+	return func(s *settings) { s.beforeUpdate = append(s.beforeUpdate, h) }
+}
+
+// WithAfterUpdate registers a hook that runs after a successful UpdateOne,
+// UpsertOne, or UpdateMany, with e.Matched and e.Modified set. An error is
+// returned to the caller but does not undo the update.
+func WithAfterUpdate(h UpdateHook) Option {
+	return func(s *settings) { s.afterUpdate = append(s.afterUpdate, h) }
+}
+
+// WithBeforeDelete registers a hook that runs before DeleteOne and DeleteMany
+// (and the helpers built on them, such as DeleteByID). It runs after the
+// document type's BeforeDelete hook, may replace e.Filter, and aborts the
+// delete by returning an error.
+func WithBeforeDelete(h DeleteHook) Option {
+	return func(s *settings) { s.beforeDelete = append(s.beforeDelete, h) }
+}
+
+// WithAfterDelete registers a hook that runs after a successful DeleteOne or
+// DeleteMany, with e.Deleted set. An error is returned to the caller but does
+// not undo the delete.
+func WithAfterDelete(h DeleteHook) Option {
+	return func(s *settings) { s.afterDelete = append(s.afterDelete, h) }
+}
+
+// runBeforeUpdate calls the BeforeUpdate hook of T, then the repository-level
+// hooks.
+func (r *MongoRepository[T]) runBeforeUpdate(ctx context.Context, e *document.UpdateEvent) error {
+	if h, ok := any(new(T)).(document.BeforeUpdate); ok {
+		if err := h.BeforeUpdate(ctx, e); err != nil {
+			return err
+		}
+	}
+	for _, h := range r.settings.beforeUpdate {
+		if err := h(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterUpdate calls the AfterUpdate hook of T, then the repository-level
+// hooks.
+func (r *MongoRepository[T]) runAfterUpdate(ctx context.Context, e *document.UpdateEvent) error {
+	if h, ok := any(new(T)).(document.AfterUpdate); ok {
+		if err := h.AfterUpdate(ctx, e); err != nil {
+			return err
+		}
+	}
+	for _, h := range r.settings.afterUpdate {
+		if err := h(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runBeforeDelete calls the BeforeDelete hook of T, then the repository-level
+// hooks.
+func (r *MongoRepository[T]) runBeforeDelete(ctx context.Context, e *document.DeleteEvent) error {
+	if h, ok := any(new(T)).(document.BeforeDelete); ok {
+		if err := h.BeforeDelete(ctx, e); err != nil {
+			return err
+		}
+	}
+	for _, h := range r.settings.beforeDelete {
+		if err := h(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterDelete calls the AfterDelete hook of T, then the repository-level
+// hooks.
+func (r *MongoRepository[T]) runAfterDelete(ctx context.Context, e *document.DeleteEvent) error {
+	if h, ok := any(new(T)).(document.AfterDelete); ok {
+		if err := h.AfterDelete(ctx, e); err != nil {
+			return err
+		}
+	}
+	for _, h := range r.settings.afterDelete {
+		if err := h(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
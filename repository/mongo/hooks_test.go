@@ -0,0 +1,70 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errReadOnly = errors.New("read-only")
+
+// lockedDoc refuses every filter-based update and delete.
+type lockedDoc struct {
+	document.Base `bson:",inline"`
+}
+
+func (*lockedDoc) BeforeUpdate(context.Context, *document.UpdateEvent) error { return errReadOnly }
+func (*lockedDoc) BeforeDelete(context.Context, *document.DeleteEvent) error { return errReadOnly }
+
+func TestHooks_AbortBeforeServer(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; hooks that fail must abort before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+	coll := client.Database("testdb").Collection("hooks")
+
+	locked := mongorepo.New[lockedDoc](coll)
+	if _, _, err := locked.UpdateOne(ctx, spec.Eq("a", 1), spec.Set("b", 2)); !errors.Is(err, errReadOnly) {
+		t.Fatalf("UpdateOne: expected document hook error, got %v", err)
+	}
+	if _, err := locked.UpsertOne(ctx, spec.Eq("a", 1), spec.Set("b", 2)); !errors.Is(err, errReadOnly) {
+		t.Fatalf("UpsertOne: expected document hook error, got %v", err)
+	}
+	if _, err := locked.DeleteMany(ctx, spec.Eq("a", 1)); !errors.Is(err, errReadOnly) {
+		t.Fatalf("DeleteMany: expected document hook error, got %v", err)
+	}
+
+	var ops []string
+	errDenied := errors.New("denied")
+	repo := mongorepo.New[invoiceRow](coll,
+		mongorepo.WithBeforeUpdate(func(_ context.Context, e *document.UpdateEvent) error {
+			ops = append(ops, e.Op)
+			return errDenied
+		}),
+		mongorepo.WithBeforeDelete(func(_ context.Context, e *document.DeleteEvent) error {
+			ops = append(ops, e.Op)
+			return errDenied
+		}),
+	)
+	if _, _, err := repo.UpdateMany(ctx, spec.Eq("a", 1), spec.Set("b", 2)); !errors.Is(err, errDenied) {
+		t.Fatalf("UpdateMany: expected repository hook error, got %v", err)
+	}
+	if _, err := repo.DeleteOne(ctx, spec.Eq("a", 1)); !errors.Is(err, errDenied) {
+		t.Fatalf("DeleteOne: expected repository hook error, got %v", err)
+	}
+	if want := []string{repository.OpUpdateMany, repository.OpDeleteOne}; len(ops) != 2 || ops[0] != want[0] || ops[1] != want[1] {
+		t.Fatalf("ops = %v, want %v", ops, want)
+	}
+}
@@ -0,0 +1,295 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Server error codes for an index that conflicts with an existing one.
+const (
+	codeIndexOptionsConflict  = 85 // same keys, different name or options
+	codeIndexKeySpecsConflict = 86 // same name, different keys
+)
+
+// IndexError reports a failure to create one of the indexes declared by a
+// document type. EnsureIndexes returns one per failed index, joined.
+type IndexError struct {
+	// Index is the declared index that could not be created.
+	Index document.Index
+
+	// Err is the server's error.
+	Err error
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("mongorepo: create index %v: %v", e.Index.Keys, e.Err)
+}
+
+func (e *IndexError) Unwrap() error { return e.Err }
+
+// WithIndexBuildTimeout bounds each index build started by EnsureIndexes.
+// Builds that take longer fail with an error matching repository.ErrTimeout;
+// the server may still finish them in the background.
+//
+// Example:
+//
+//	repo := mongorepo.New[User](coll, mongorepo.WithIndexBuildTimeout(time.Minute))
+func WithIndexBuildTimeout(d time.Duration) Option {
+	return func(s *settings) { s.indexTimeout = d }
+}
+
+// WithIndexRecreate makes EnsureIndexes drop and recreate an existing index
+// that conflicts with a declared one, e.g. because its options or name changed.
+// Without it, such conflicts are returned as errors.
+//
+// Dropping an index briefly leaves queries that use it without one, so
+// prefer running this during a deployment rather than at every startup.
+func WithIndexRecreate() Option {
+	return func(s *settings) { s.indexRecreate = true }
+}
+
+// EnsureIndexes creates indexes defined by the document type's Indexes() method.
+// This is automatically called by NewWithIndexes, but can also be called manually.
+// If the type T does not implement document.Indexed, this method does nothing.
+//
+// Indexes are built concurrently. A failed index does not stop the others;
+// the failures are returned joined, as *IndexError values. See
+// WithIndexBuildTimeout and WithIndexRecreate. With WithCapped, the
+// collection is created as a capped collection first.
+func (r *MongoRepository[T]) EnsureIndexes(ctx context.Context) error {
+	if err := r.ensureCapped(ctx); err != nil {
+		return err
+	}
+
+	var zero T
+	indexed, ok := any(zero).(document.Indexed)
+	if !ok {
+		return nil
+	}
+
+	indexes := indexed.Indexes()
+	errs := make([]error, len(indexes))
+	var wg sync.WaitGroup
+	for i, idx := range indexes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.ensureIndex(ctx, idx); err != nil {
+				errs[i] = &IndexError{Index: idx, Err: err}
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ensureIndex creates one index, recreating a conflicting index if configured.
+func (r *MongoRepository[T]) ensureIndex(ctx context.Context, idx document.Index) error {
+	if r.settings.indexTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.settings.indexTimeout)
+		defer cancel()
+	}
+
+	model := indexModel(idx)
+	_, err := r.coll.Indexes().CreateOne(ctx, model)
+	if err != nil && r.settings.indexRecreate && isIndexConflict(err) {
+		if err = dropConflicting(ctx, r.coll, idx); err == nil {
+			_, err = r.coll.Indexes().CreateOne(ctx, model)
+		}
+	}
+	return wrapTimeout(err)
+}
+
+// indexModel converts a declared index to a driver index model.
+func indexModel(idx document.Index) mongo.IndexModel {
+	opts := mopt.Index()
+	if idx.Unique {
+		opts.SetUnique(true)
+	}
+	if idx.Sparse {
+		opts.SetSparse(true)
+	}
+	if idx.Name != "" {
+		opts.SetName(idx.Name)
+	}
+	if idx.TTL != nil {
+		opts.SetExpireAfterSeconds(int32(idx.TTL.Seconds()))
+	}
+	if idx.Background {
+		opts.SetBackground(true)
+	}
+	if idx.PartialFilterExpression != nil {
+		opts.SetPartialFilterExpression(idx.PartialFilterExpression)
+	}
+	return mongo.IndexModel{Keys: idx.Keys, Options: opts}
+}
+
+// isIndexConflict reports whether err says an existing index conflicts with
+// the one being created.
+func isIndexConflict(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) &&
+		(cmdErr.Code == codeIndexOptionsConflict || cmdErr.Code == codeIndexKeySpecsConflict)
+}
+
+// dropConflicting drops the existing index with idx's name or keys.
+func dropConflicting(ctx context.Context, coll *mongo.Collection, idx document.Index) error {
+	cur, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	var specs []struct {
+		Name string `bson:"name"`
+		Key  bson.D `bson:"key"`
+	}
+	if err := cur.All(ctx, &specs); err != nil {
+		return err
+	}
+	for _, s := range specs {
+		if s.Name == "_id_" {
+			continue
+		}
+		if (idx.Name != "" && s.Name == idx.Name) || sameKeys(s.Key, idx.Keys) {
+			_, err := coll.Indexes().DropOne(ctx, s.Name)
+			return err
+		}
+	}
+	return fmt.Errorf("mongorepo: no existing index conflicts with %v", idx.Keys)
+}
+
+// sameKeys reports whether an existing index key pattern equals a declared one.
+func sameKeys(existing, declared bson.D) bool {
+	if len(existing) != len(declared) {
+		return false
+	}
+	for i, e := range existing {
+		if e.Key != declared[i].Key {
+			return false
+		}
+		if dir := direction(e.Value); dir != 0 {
+			if dir != direction(declared[i].Value) {
+				return false
+			}
+		} else if fmt.Sprint(e.Value) != fmt.Sprint(declared[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// IndexBuild describes an index build in progress on the server.
+type IndexBuild struct {
+	// Namespace is the "database.collection" name the index is built on.
+	Namespace string
+
+	// Indexes are the names of the indexes being built.
+	Indexes []string
+
+	// Phase is the server's description of the current phase, e.g.
+	// "Index Build: scanning collection". It is empty before the build starts.
+	Phase string
+
+	// Done and Total count the units of work of the current phase, usually
+	// documents or keys. Total is 0 when the server reports no progress.
+	Done, Total int64
+
+	// Running is how long the build has been running.
+	Running time.Duration
+}
+
+// Percent returns the completion of the current phase, from 0 to 100.
+func (b IndexBuild) Percent() float64 {
+	if b.Total <= 0 {
+		return 0
+	}
+	return float64(b.Done) * 100 / float64(b.Total)
+}
+
+// IndexBuildProgress reports the index builds running on the server, such as
+// those started by EnsureIndexes, using $currentOp. It requires the inprog
+// privilege (e.g. the clusterMonitor role).
+//
+// Example:
+//
+//	builds, err := mongorepo.IndexBuildProgress(ctx, client)
+//	for _, b := range builds {
+//	    log.Printf("%s %v: %s %.0f%%", b.Namespace, b.Indexes, b.Phase, b.Percent())
+//	}
+func IndexBuildProgress(ctx context.Context, client *mongo.Client) ([]IndexBuild, error) {
+	cur, err := client.Database("admin").Aggregate(ctx, []bson.M{
+		{"$currentOp": bson.M{"allUsers": true, "idleConnections": false}},
+		{"$match": bson.M{"command.createIndexes": bson.M{"$exists": true}}},
+	})
+	if err != nil {
+		return nil, wrapTimeout(err)
+	}
+	var ops []struct {
+		NS      string `bson:"ns"`
+		Msg     string `bson:"msg"`
+		Micros  int64  `bson:"microsecs_running"`
+		Command struct {
+			Indexes []struct {
+				Name string `bson:"name"`
+			} `bson:"indexes"`
+		} `bson:"command"`
+		Progress struct {
+			Done  int64 `bson:"done"`
+			Total int64 `bson:"total"`
+		} `bson:"progress"`
+	}
+	if err := cur.All(ctx, &ops); err != nil {
+		return nil, wrapTimeout(err)
+	}
+
+	// A build appears both as the client's createIndexes command and as the
+	// server thread doing the work; keep one entry, preferring the one with
+	// progress.
+	var builds []IndexBuild
+	seen := map[string]int{}
+	for _, op := range ops {
+		b := IndexBuild{
+			Namespace: op.NS,
+			Phase:     strings.TrimSpace(phase(op.Msg)),
+			Done:      op.Progress.Done,
+			Total:     op.Progress.Total,
+			Running:   time.Duration(op.Micros) * time.Microsecond,
+		}
+		for _, idx := range op.Command.Indexes {
+			b.Indexes = append(b.Indexes, idx.Name)
+		}
+		key := b.Namespace + " " + strings.Join(b.Indexes, ",")
+		if i, ok := seen[key]; ok {
+			if builds[i].Total == 0 && b.Total > 0 {
+				builds[i] = b
+			}
+			continue
+		}
+		seen[key] = len(builds)
+		builds = append(builds, b)
+	}
+	return builds, nil
+}
+
+// phase strips the progress counter from a currentOp message such as
+// "Index Build: scanning collection Index Build: scanning collection: 500/1000 50%".
+func phase(msg string) string {
+	if i := strings.LastIndex(msg, ": "); i >= 0 && strings.HasSuffix(msg, "%") {
+		msg = msg[:i]
+	}
+	const prefix = "Index Build: "
+	if i := strings.LastIndex(msg, prefix); i > 0 {
+		msg = msg[i:]
+	}
+	return msg
+}
@@ -0,0 +1,45 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestEnsureIndexes_ReportsEachFailedIndex(t *testing.T) {
+	ctx := context.Background()
+
+	// Nothing listens on this port; every build times out in server selection.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	repo := mongorepo.New[ticket](client.Database("testdb").Collection("tickets"),
+		mongorepo.WithIndexBuildTimeout(50*time.Millisecond))
+	start := time.Now()
+	err = repo.EnsureIndexes(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected concurrent builds bounded by the timeout, took %v", elapsed)
+	}
+	if !errors.Is(err, repository.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != len(ticket{}.Indexes()) {
+		t.Fatalf("expected one error per declared index, got %v", err)
+	}
+	var ie *mongorepo.IndexError
+	if !errors.As(err, &ie) || len(ie.Index.Keys) == 0 {
+		t.Fatalf("expected an *IndexError naming the index, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package mongorepo
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// JSONFormat selects how StreamJSON lays out documents.
+type JSONFormat int
+
+const (
+	// JSONArray writes a single JSON array: [doc,doc,...].
+	JSONArray JSONFormat = iota
+
+	// NDJSON writes one document per line (newline-delimited JSON), which
+	// clients can parse incrementally.
+	NDJSON
+)
+
+// StreamJSON writes the documents matching the filter to w as JSON, straight
+// from the cursor, so downloads of any size use constant memory. Documents
+// are written in relaxed extended JSON: ObjectIDs as {"$oid": ...}, dates as
+// {"$date": ...}, and other BSON types as their natural JSON values.
+//
+// Documents are not decoded into T, so AfterLoad hooks do not run; use
+// repository.WithProjection or repository.WithFields to limit the output.
+// A FindPolicy only supplies the default sort; its MaxLimit does not apply.
+// If an error occurs after writing started, w holds incomplete JSON.
+//
+// Example:
+//
+//	w.Header().Set("Content-Type", "application/x-ndjson")
+//	err := repo.StreamJSON(ctx, w, spec.Eq("status", "paid"), mongorepo.NDJSON,
+//	    repository.WithFields("number", "total"), repository.WithBatchSize(1000))
+func (r *MongoRepository[T]) StreamJSON(ctx context.Context, w io.Writer, filter any, format JSONFormat, opts ...repository.FindOption) (err error) {
+	defer r.track(repository.OpFind, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return err
+	}
+	if format != JSONArray && format != NDJSON {
+		return fmt.Errorf("mongorepo: unknown JSON format %d", format)
+	}
+
+	fo := applyFindOptions(opts)
+	f, err := r.prepareScan(ctx, filter, &fo)
+	if err != nil {
+		return err
+	}
+	cur, err := r.findCursor(ctx, f, fo)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	bw := bufio.NewWriter(w)
+	if format == JSONArray {
+		bw.WriteByte('[')
+	}
+	var buf []byte
+	for n := 0; cur.Next(ctx); n++ {
+		if n > 0 && format == JSONArray {
+			bw.WriteByte(',')
+		}
+		buf, err = bson.MarshalExtJSONAppend(buf[:0], cur.Current, false, false)
+		if err != nil {
+			return err
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return err
+		}
+		if format == NDJSON {
+			bw.WriteByte('\n')
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	if format == JSONArray {
+		bw.WriteByte(']')
+	}
+	return bw.Flush()
+}
+
+// StreamJSON writes the non-deleted documents matching the filter to w as JSON.
+func (r *SoftDeleteRepository[T]) StreamJSON(ctx context.Context, w io.Writer, filter any, format JSONFormat, opts ...repository.FindOption) error {
+	return r.MongoRepository.StreamJSON(ctx, w, r.combineWithNotDeleted(filter), format, opts...)
+}
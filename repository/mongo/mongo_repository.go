@@ -3,8 +3,11 @@ package mongorepo
 import (
 	"context"
 	"errors"
+	"slices"
+	"sort"
 	"time"
 
+	"github.com/dElCIoGio/mongox/compat"
 	"github.com/dElCIoGio/mongox/document"
 	"github.com/dElCIoGio/mongox/repository"
 	mongospec "github.com/dElCIoGio/mongox/spec"
@@ -19,6 +22,8 @@ import (
 var (
 	ErrNotFound     = repository.ErrNotFound
 	ErrDuplicateKey = repository.ErrDuplicateKey
+	ErrReferenced   = repository.ErrReferenced
+	ErrInvalidID    = repository.ErrInvalidID
 )
 
 // isDuplicateKeyError checks if the error is a MongoDB duplicate key error.
@@ -32,15 +37,48 @@ func isDuplicateKeyError(err error) bool {
 			}
 		}
 	}
+	// InsertMany and BulkWrite report write errors in a BulkWriteException.
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, we := range bulkErr.WriteErrors {
+			if we.Code == 11000 {
+				return true
+			}
+		}
+	}
 	return false
 }
 
 type MongoRepository[T any] struct {
-	coll *mongo.Collection
+	coll     *mongo.Collection
+	settings settings
 }
 
-func New[T any](coll *mongo.Collection) *MongoRepository[T] {
-	return &MongoRepository[T]{coll: coll}
+// New creates a new MongoRepository for the given collection.
+// Options configure repository-wide behavior such as query time limits and observers.
+func New[T any](coll *mongo.Collection, opts ...Option) *MongoRepository[T] {
+	s := applyOptions(opts)
+	if s.strict != nil {
+		s.strict.declareIndexes(new(T))
+	}
+	r := &MongoRepository[T]{coll: s.configure(coll), settings: s}
+	s.hedge.bind(r.coll)
+	if s.registry != nil {
+		s.registry.Register(r)
+	}
+	return r
+}
+
+// NewInDatabase creates a MongoRepository on T's collection in db, named by
+// document.CollectionName: pluralized snake_case of the type name unless T
+// implements document.CollectionNamer or a name was registered for it.
+//
+// Example:
+//
+//	users := mongorepo.NewInDatabase[User](db)        // "users"
+//	items := mongorepo.NewInDatabase[OrderItem](db)   // "order_items"
+func NewInDatabase[T any](db *mongo.Database, opts ...Option) *MongoRepository[T] {
+	return New[T](db.Collection(document.CollectionName[T]()), opts...)
 }
 
 // NewWithIndexes creates a new MongoRepository and ensures indexes are created.
@@ -61,61 +99,14 @@ func New[T any](coll *mongo.Collection) *MongoRepository[T] {
 //	}
 //
 //	repo, err := mongorepo.NewWithIndexes[User](ctx, coll)
-func NewWithIndexes[T document.Indexed](ctx context.Context, coll *mongo.Collection) (*MongoRepository[T], error) {
-	repo := &MongoRepository[T]{coll: coll}
+func NewWithIndexes[T document.Indexed](ctx context.Context, coll *mongo.Collection, opts ...Option) (*MongoRepository[T], error) {
+	repo := New[T](coll, opts...)
 	if err := repo.EnsureIndexes(ctx); err != nil {
 		return nil, err
 	}
 	return repo, nil
 }
 
-// EnsureIndexes creates indexes defined by the document type's Indexes() method.
-// This is automatically called by NewWithIndexes, but can also be called manually.
-// If the type T does not implement document.Indexed, this method does nothing.
-func (r *MongoRepository[T]) EnsureIndexes(ctx context.Context) error {
-	var zero T
-	indexed, ok := any(zero).(document.Indexed)
-	if !ok {
-		return nil
-	}
-
-	indexes := indexed.Indexes()
-	if len(indexes) == 0 {
-		return nil
-	}
-
-	models := make([]mongo.IndexModel, len(indexes))
-	for i, idx := range indexes {
-		opts := mopt.Index()
-		if idx.Unique {
-			opts.SetUnique(true)
-		}
-		if idx.Sparse {
-			opts.SetSparse(true)
-		}
-		if idx.Name != "" {
-			opts.SetName(idx.Name)
-		}
-		if idx.TTL != nil {
-			opts.SetExpireAfterSeconds(int32(idx.TTL.Seconds()))
-		}
-		if idx.Background {
-			opts.SetBackground(true)
-		}
-		if idx.PartialFilterExpression != nil {
-			opts.SetPartialFilterExpression(idx.PartialFilterExpression)
-		}
-
-		models[i] = mongo.IndexModel{
-			Keys:    idx.Keys,
-			Options: opts,
-		}
-	}
-
-	_, err := r.coll.Indexes().CreateMany(ctx, models)
-	return err
-}
-
 // Collection returns the underlying mongo.Collection.
 // Use this for advanced operations not covered by the repository interface.
 func (r *MongoRepository[T]) Collection() *mongo.Collection {
@@ -179,16 +170,31 @@ func injectUpdatedAt(update any, ts time.Time) any {
 	}
 }
 
+// track wraps timeout errors with repository.ErrTimeout and reports the
+// operation to the configured observer. Call it deferred with a named error result.
+func (r *MongoRepository[T]) track(op string, start time.Time, errp *error) {
+	*errp = wrapTimeout(*errp)
+	if isWriteOp(op) {
+		r.settings.cache.clear()
+	}
+	r.settings.observe(op, start, *errp)
+}
+
 // ---- CRUD ----
 
-func (r *MongoRepository[T]) InsertOne(ctx context.Context, doc *T) error {
+// prepareInsert auto-touches, validates, and runs the BeforeSave hook on a
+// document about to be inserted.
+func prepareInsert[T any](ctx context.Context, doc *T, now time.Time) error {
 	if doc == nil {
 		return repository.ErrNilDocument
 	}
 
 	// Auto-touch if embedded Base exists (promoted methods).
 	if t, ok := any(doc).(insertToucher); ok {
-		t.TouchForInsert(nowUTC())
+		t.TouchForInsert(now)
+	}
+	if v, ok := any(doc).(document.VersionedDoc); ok && v.CurrentVersion() == 0 {
+		v.SetVersion(1)
 	}
 
 	// Validate if the document implements Validatable.
@@ -204,8 +210,24 @@ func (r *MongoRepository[T]) InsertOne(ctx context.Context, doc *T) error {
 			return err
 		}
 	}
+	return nil
+}
+
+func (r *MongoRepository[T]) InsertOne(ctx context.Context, doc *T) (err error) {
+	defer r.track(repository.OpInsertOne, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return err
+	}
 
-	_, err := r.coll.InsertOne(ctx, doc)
+	if err := prepareInsert(ctx, doc, nowUTC()); err != nil {
+		return err
+	}
+	sealed, err := r.settings.seal(ctx, doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.coll.InsertOne(ctx, sealed)
 	if err != nil {
 		if isDuplicateKeyError(err) {
 			return repository.ErrDuplicateKey
@@ -215,20 +237,56 @@ func (r *MongoRepository[T]) InsertOne(ctx context.Context, doc *T) error {
 	return nil
 }
 
-func (r *MongoRepository[T]) FindOne(ctx context.Context, filter any, opts ...repository.FindOption) (*T, error) {
+func (r *MongoRepository[T]) FindOne(ctx context.Context, filter any, opts ...repository.FindOption) (_ *T, err error) {
+	defer r.track(repository.OpFindOne, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	f, err := normalizeFilter(filter)
 	if err != nil {
 		return nil, err
 	}
 
 	fo := applyFindOptions(opts)
+	r.settings.advisor.record(f, fo.Sort)
+	if err := r.checkQuery(ctx, repository.OpFindOne, f, fo.Sort); err != nil {
+		return nil, err
+	}
+	defer r.observeShape(repository.OpFindOne, f, fo.Sort, time.Now(), &err)
 	mongoOpts := mopt.FindOne()
+	if c := r.settings.comment(ctx, fo.Comment); c != "" {
+		mongoOpts.SetComment(c)
+	}
 	if fo.Sort != nil {
 		mongoOpts.SetSort(fo.Sort)
 	}
+	if fo.Projection != nil {
+		mongoOpts.SetProjection(fo.Projection)
+	}
+	if d := r.settings.maxTime(ctx); d > 0 {
+		mongoOpts.SetMaxTime(d)
+	}
 
-	var out T
-	err = r.coll.FindOne(ctx, f, mongoOpts).Decode(&out)
+	coll, err := readCollection(r.coll, fo)
+	if err != nil {
+		return nil, err
+	}
+	var key string
+	if len(opts) == 0 {
+		key = r.settings.flightKey(ctx, repository.OpFindOne, f)
+	}
+	var backup *mongo.Collection
+	if fo.ReadPreference == "" && fo.ReadConcern == "" {
+		backup = r.settings.hedgedCollection(ctx)
+	}
+	out, err := coalesce(ctx, r.settings.flight, key, func() (T, error) {
+		return hedged(ctx, r.settings.hedge, coll, backup, func(ctx context.Context, coll *mongo.Collection) (T, error) {
+			var out T
+			err := decodeResult[T](ctx, r.settings, coll.FindOne(ctx, f, mongoOpts), &out)
+			return out, err
+		})
+	})
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
 			return nil, ErrNotFound
@@ -237,23 +295,131 @@ func (r *MongoRepository[T]) FindOne(ctx context.Context, filter any, opts ...re
 	}
 
 	// AfterLoad hook.
-	if h, ok := any(&out).(document.AfterLoad); ok {
-		if err := h.AfterLoad(ctx); err != nil {
-			return nil, err
-		}
+	if err := r.settings.afterLoad(ctx, &out); err != nil {
+		return nil, err
 	}
 
 	return &out, nil
 }
 
-func (r *MongoRepository[T]) Find(ctx context.Context, filter any, opts ...repository.FindOption) ([]T, error) {
+func (r *MongoRepository[T]) Find(ctx context.Context, filter any, opts ...repository.FindOption) (_ []T, err error) {
+	defer r.track(repository.OpFind, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	fo := applyFindOptions(opts)
+
+	var results []T
+	if fo.CapacityHint > 0 {
+		results = make([]T, 0, fo.CapacityHint)
+	}
+	if err := r.findInto(ctx, filter, fo, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// FindInto finds documents matching the filter and decodes them into *out,
+// reusing the slice's existing capacity. The slice is truncated first, so callers
+// can pass the same buffer across calls in tight loops to avoid allocations.
+//
+// Example:
+//
+//	var batch []Event
+//	for cursor := range cursors {
+//	    if err := repo.FindInto(ctx, spec.Gt("_id", cursor), &batch, repository.WithLimit(500)); err != nil {
+//	        return err
+//	    }
+//	    process(batch)
+//	}
+func (r *MongoRepository[T]) FindInto(ctx context.Context, filter any, out *[]T, opts ...repository.FindOption) (err error) {
+	defer r.track(repository.OpFind, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return repository.ErrNilDocument
+	}
+	fo := applyFindOptions(opts)
+
+	// Zero reused elements so fields absent from the new documents do not keep stale values.
+	buf := (*out)[:cap(*out)]
+	clear(buf)
+	*out = buf[:0]
+	if fo.CapacityHint > cap(*out) {
+		*out = make([]T, 0, fo.CapacityHint)
+	}
+
+	return r.findInto(ctx, filter, fo, out)
+}
+
+func (r *MongoRepository[T]) findInto(ctx context.Context, filter any, fo repository.FindOptions, results *[]T) (err error) {
+	f, err := r.prepareFind(ctx, filter, &fo)
+	if err != nil {
+		return err
+	}
+	defer r.observeShape(repository.OpFind, f, fo.Sort, time.Now(), &err)
+
+	cur, err := r.findCursor(ctx, f, fo)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	if err := decodeAll[T](ctx, r.settings, cur, results); err != nil {
+		return err
+	}
+
+	// AfterLoad hook for each document (best-effort).
+	for i := range *results {
+		if err := r.settings.afterLoad(ctx, &(*results)[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prepareFind normalizes filter, applies the find policy to fo, and runs the
+// index checks for a find.
+func (r *MongoRepository[T]) prepareFind(ctx context.Context, filter any, fo *repository.FindOptions) (any, error) {
+	return r.prepareRead(ctx, filter, fo, true)
+}
+
+// prepareScan is like prepareFind for reads that walk every matching document,
+// such as exports: only the policy's default sort applies, not its limit.
+func (r *MongoRepository[T]) prepareScan(ctx context.Context, filter any, fo *repository.FindOptions) (any, error) {
+	return r.prepareRead(ctx, filter, fo, false)
+}
+
+func (r *MongoRepository[T]) prepareRead(ctx context.Context, filter any, fo *repository.FindOptions, capLimit bool) (any, error) {
 	f, err := normalizeFilter(filter)
 	if err != nil {
 		return nil, err
 	}
+	policy := r.settings.findPolicyFor(ctx)
+	if capLimit {
+		if err := policy.apply(fo); err != nil {
+			return nil, err
+		}
+	} else {
+		policy.applySort(fo)
+	}
+	r.settings.advisor.record(f, fo.Sort)
+	if err := r.checkQuery(ctx, repository.OpFind, f, fo.Sort); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
 
-	fo := applyFindOptions(opts)
+// findCursor opens a cursor over the documents matching the normalized filter f.
+func (r *MongoRepository[T]) findCursor(ctx context.Context, f any, fo repository.FindOptions) (*mongo.Cursor, error) {
 	mongoOpts := mopt.Find()
+	if c := r.settings.comment(ctx, fo.Comment); c != "" {
+		mongoOpts.SetComment(c)
+	}
 	if fo.Limit > 0 {
 		mongoOpts.SetLimit(fo.Limit)
 	}
@@ -263,37 +429,31 @@ func (r *MongoRepository[T]) Find(ctx context.Context, filter any, opts ...repos
 	if fo.Sort != nil {
 		mongoOpts.SetSort(fo.Sort)
 	}
-
-	cur, err := r.coll.Find(ctx, f, mongoOpts)
-	if err != nil {
-		return nil, err
+	if fo.Projection != nil {
+		mongoOpts.SetProjection(fo.Projection)
 	}
-	defer cur.Close(ctx)
-
-	var results []T
-	if err := cur.All(ctx, &results); err != nil {
-		return nil, err
+	if fo.BatchSize > 0 {
+		mongoOpts.SetBatchSize(fo.BatchSize)
 	}
-
-	// AfterLoad hook for each document (best-effort).
-	for i := range results {
-		if h, ok := any(&results[i]).(document.AfterLoad); ok {
-			if err := h.AfterLoad(ctx); err != nil {
-				return nil, err
-			}
-		}
+	if d := r.settings.maxTime(ctx); d > 0 {
+		mongoOpts.SetMaxTime(d)
 	}
 
-	return results, nil
+	coll, err := readCollection(r.coll, fo)
+	if err != nil {
+		return nil, err
+	}
+	return coll.Find(ctx, f, mongoOpts)
 }
 
 // FindPaginated finds documents matching the filter with pagination.
 // Returns a Page containing the documents and pagination metadata.
 func (r *MongoRepository[T]) FindPaginated(ctx context.Context, filter any, page, perPage int, opts ...repository.FindOption) (*repository.Page[T], error) {
 	// Normalize pagination options
-	pagOpts := repository.PaginationOptions{
-		Page:    page,
-		PerPage: perPage,
+	pagOpts := r.settings.pagination(page, perPage)
+	// Keep pages within the find policy so TotalPages matches what Find returns.
+	if p := r.settings.findPolicyFor(ctx); p != nil && p.MaxLimit > 0 && p.MaxLimit < int64(pagOpts.MaxPerPage) {
+		pagOpts.MaxPerPage = int(p.MaxLimit)
 	}
 	pagOpts.Normalize()
 
@@ -331,35 +491,163 @@ func (r *MongoRepository[T]) FindPaginated(ctx context.Context, filter any, page
 	}, nil
 }
 
-func (r *MongoRepository[T]) UpdateOne(ctx context.Context, filter any, update any) (matched int64, modified int64, err error) {
-	f, err := normalizeFilter(filter)
+func (r *MongoRepository[T]) UpdateOne(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	res, err := r.updateOne(ctx, filter, update, applyUpdateOptions(opts))
 	if err != nil {
 		return 0, 0, err
 	}
+	return res.MatchedCount, res.ModifiedCount, nil
+}
+
+// UpsertOne updates the first document matching the filter or, if none
+// matches, inserts one built from the filter's equality conditions and the
+// update, and reports the _id of the inserted document. Use it for idempotent
+// writes keyed by a natural key.
+//
+// Behavior:
+//   - updated_at is added to $set updates, as in UpdateOne
+//   - created_at is not set on insert; add it with $setOnInsert if needed
+//
+// MongoDB equivalent: db.collection.updateOne(filter, update, {upsert: true})
+//
+// Example:
+//
+//	res, err := repo.UpsertOne(ctx,
+//	    spec.Eq("email", "jane@example.com"),
+//	    spec.Set("last_login", time.Now()),
+//	)
+//	if err == nil && res.UpsertedCount == 1 {
+//	    log.Printf("created user %s", res.UpsertedID.Hex())
+//	}
+func (r *MongoRepository[T]) UpsertOne(ctx context.Context, filter any, update any) (repository.UpsertResult, error) {
+	res, err := r.updateOne(ctx, filter, update, repository.UpdateOptions{Upsert: true})
+	if err != nil {
+		return repository.UpsertResult{}, err
+	}
+	out := repository.UpsertResult{
+		MatchedCount:  res.MatchedCount,
+		ModifiedCount: res.ModifiedCount,
+		UpsertedCount: res.UpsertedCount,
+	}
+	out.UpsertedID, _ = res.UpsertedID.(primitive.ObjectID)
+	return out, nil
+}
+
+func (r *MongoRepository[T]) updateOne(ctx context.Context, filter any, update any, uo repository.UpdateOptions) (_ *mongo.UpdateResult, err error) {
+	defer r.track(repository.OpUpdateOne, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	e := &document.UpdateEvent{Op: repository.OpUpdateOne, Filter: filter, Update: update}
+	if err = r.runBeforeUpdate(ctx, e); err != nil {
+		return nil, err
+	}
+	filter, update = e.Filter, e.Update
+
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	r.settings.advisor.record(f, nil)
+	if err := r.checkQuery(ctx, repository.OpUpdateOne, f, nil); err != nil {
+		return nil, err
+	}
+	defer r.observeShape(repository.OpUpdateOne, f, nil, time.Now(), &err)
 	if update == nil {
-		return 0, 0, repository.ErrNilUpdate
+		return nil, repository.ErrNilUpdate
+	}
+	if uo.ExpectedVersion != nil && uo.Upsert {
+		return nil, errors.New("mongorepo: WithExpectedVersion cannot be combined with an upsert")
 	}
 
 	// Normalize update if it implements the Update interface
-	u := normalizeUpdate(update)
+	u, err := sealUpdate[T](ctx, r.settings, normalizeUpdate(update))
+	if err != nil {
+		return nil, err
+	}
 
 	// Best-effort: add updated_at to $set updates.
 	u = injectUpdatedAt(u, nowUTC())
+	if isVersioned[T]() {
+		u = injectVersionInc(u)
+	}
 
-	res, err := r.coll.UpdateOne(ctx, f, u)
+	target := f
+	if uo.ExpectedVersion != nil {
+		target = withVersion(f, *uo.ExpectedVersion)
+	}
+	updateOpts := mopt.Update().SetUpsert(uo.Upsert)
+	if c := r.settings.comment(ctx, uo.Comment); c != "" {
+		updateOpts.SetComment(c)
+	}
+	res, err := r.coll.UpdateOne(ctx, target, u, updateOpts)
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
-	return res.MatchedCount, res.ModifiedCount, nil
+	if uo.ExpectedVersion != nil && res.MatchedCount == 0 {
+		if err := r.versionConflict(ctx, f); err != nil {
+			return nil, err
+		}
+	}
+	e.Matched, e.Modified = res.MatchedCount, res.ModifiedCount
+	return res, r.runAfterUpdate(ctx, e)
+}
+
+// UpdateAndFetch updates the first document matching the filter and returns it
+// as it is after the update, in a single round trip.
+// Returns ErrNotFound if no document matches.
+//
+// Behavior:
+//   - updated_at is added to $set updates, as in UpdateOne
+//   - The AfterLoad hook is called on the returned document
+//   - Use FindOneAndUpdate for upserts, sorting, or the document before the update
+//
+// MongoDB equivalent: db.collection.findOneAndUpdate(filter, update, {returnDocument: "after"})
+//
+// Example:
+//
+//	user, err := repo.UpdateAndFetch(ctx, spec.Eq("_id", id), spec.Set("name", "Jane"))
+//	if err != nil {
+//	    return err
+//	}
+//	return json.NewEncoder(w).Encode(user)
+func (r *MongoRepository[T]) UpdateAndFetch(ctx context.Context, filter any, update any) (*T, error) {
+	return r.FindOneAndUpdate(ctx, filter, update, ReturnAfter())
 }
 
 func (r *MongoRepository[T]) DeleteOne(ctx context.Context, filter any) (deleted int64, err error) {
-	f, err := normalizeFilter(filter)
+	defer r.track(repository.OpDeleteOne, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return 0, err
+	}
+
+	e := &document.DeleteEvent{Op: repository.OpDeleteOne, Filter: filter}
+	if err = r.runBeforeDelete(ctx, e); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err == nil {
+			e.Deleted = deleted
+			err = r.runAfterDelete(ctx, e)
+		}
+	}()
+
+	f, err := normalizeFilter(e.Filter)
 	if err != nil {
 		return 0, err
 	}
+	r.settings.advisor.record(f, nil)
+	if err := r.checkQuery(ctx, repository.OpDeleteOne, f, nil); err != nil {
+		return 0, err
+	}
 
-	res, err := r.coll.DeleteOne(ctx, f)
+	if len(r.settings.references) > 0 {
+		return r.deleteWithReferences(ctx, f, 1)
+	}
+	defer r.observeShape(repository.OpDeleteOne, f, nil, time.Now(), &err)
+
+	res, err := r.coll.DeleteOne(ctx, f, r.deleteOptions(ctx))
 	if err != nil {
 		return 0, err
 	}
@@ -369,6 +657,11 @@ func (r *MongoRepository[T]) DeleteOne(ctx context.Context, filter any) (deleted
 // ReplaceOne is useful when you want auto-touch + BeforeSave for updates.
 // (Mongo UpdateOne can't mutate a doc instance, so ReplaceOne is the "document-aware" update.)
 func (r *MongoRepository[T]) ReplaceOne(ctx context.Context, filter any, doc *T) (matched int64, modified int64, err error) {
+	defer r.track(repository.OpReplaceOne, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return 0, 0, err
+	}
+
 	if doc == nil {
 		return 0, 0, repository.ErrNilDocument
 	}
@@ -377,6 +670,11 @@ func (r *MongoRepository[T]) ReplaceOne(ctx context.Context, filter any, doc *T)
 	if err != nil {
 		return 0, 0, err
 	}
+	r.settings.advisor.record(f, nil)
+	if err := r.checkQuery(ctx, repository.OpReplaceOne, f, nil); err != nil {
+		return 0, 0, err
+	}
+	defer r.observeShape(repository.OpReplaceOne, f, nil, time.Now(), &err)
 
 	// Auto-touch on replace (UpdatedAt).
 	if t, ok := any(doc).(updateToucher); ok {
@@ -397,10 +695,37 @@ func (r *MongoRepository[T]) ReplaceOne(ctx context.Context, filter any, doc *T)
 		}
 	}
 
-	res, err := r.coll.ReplaceOne(ctx, f, doc)
+	v, versioned := any(doc).(document.VersionedDoc)
+	if !versioned {
+		sealed, err := r.settings.seal(ctx, doc)
+		if err != nil {
+			return 0, 0, err
+		}
+		res, err := r.coll.ReplaceOne(ctx, f, sealed, r.replaceOptions(ctx))
+		if err != nil {
+			return 0, 0, err
+		}
+		return res.MatchedCount, res.ModifiedCount, nil
+	}
+
+	// Optimistic locking: only replace the version doc was loaded with.
+	expected := v.CurrentVersion()
+	v.SetVersion(expected + 1)
+	sealed, err := r.settings.seal(ctx, doc)
 	if err != nil {
+		v.SetVersion(expected)
 		return 0, 0, err
 	}
+	res, err := r.coll.ReplaceOne(ctx, withVersion(f, expected), sealed, r.replaceOptions(ctx))
+	if err == nil && res.MatchedCount == 0 {
+		err = r.versionConflict(ctx, f)
+	}
+	if err != nil || res.MatchedCount == 0 {
+		v.SetVersion(expected)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
 	return res.MatchedCount, res.ModifiedCount, nil
 }
 
@@ -408,7 +733,12 @@ func (r *MongoRepository[T]) ReplaceOne(ctx context.Context, filter any, doc *T)
 
 // InsertMany inserts multiple documents into the collection.
 // Returns the ObjectIDs of the inserted documents.
-func (r *MongoRepository[T]) InsertMany(ctx context.Context, docs []*T) ([]primitive.ObjectID, error) {
+func (r *MongoRepository[T]) InsertMany(ctx context.Context, docs []*T) (_ []primitive.ObjectID, err error) {
+	defer r.track(repository.OpInsertMany, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	if len(docs) == 0 {
 		return []primitive.ObjectID{}, nil
 	}
@@ -417,31 +747,14 @@ func (r *MongoRepository[T]) InsertMany(ctx context.Context, docs []*T) ([]primi
 	now := nowUTC()
 	insertDocs := make([]any, len(docs))
 	for i, doc := range docs {
-		if doc == nil {
-			return nil, repository.ErrNilDocument
-		}
-
-		// Auto-touch if embedded Base exists
-		if t, ok := any(doc).(insertToucher); ok {
-			t.TouchForInsert(now)
-		}
-
-		// Validate if the document implements Validatable
-		if v, ok := any(doc).(document.Validatable); ok {
-			if err := v.Validate(); err != nil {
-				return nil, err
-			}
-		}
-
-		// BeforeSave hook
-		if h, ok := any(doc).(document.BeforeSave); ok {
-			if err := h.BeforeSave(ctx); err != nil {
-				return nil, err
-			}
+		if err := prepareInsert(ctx, doc, now); err != nil {
+			return nil, err
 		}
-
 		insertDocs[i] = doc
 	}
+	if err := r.settings.sealAll(ctx, insertDocs); err != nil {
+		return nil, err
+	}
 
 	res, err := r.coll.InsertMany(ctx, insertDocs)
 	if err != nil {
@@ -461,39 +774,156 @@ func (r *MongoRepository[T]) InsertMany(ctx context.Context, docs []*T) ([]primi
 	return ids, nil
 }
 
+// InsertManyUnordered inserts docs in unordered mode, so a duplicate does not
+// stop the remaining inserts, and reports which documents were inserted and
+// which were duplicates. Importers can use it to continue past documents that
+// already exist.
+//
+// Behavior:
+//   - Duplicate key violations are reported in the result, not as an error
+//   - Other write errors are returned together with the partial result
+//   - Re-running the same import inserts nothing new and reports every document as a duplicate
+//
+// Example:
+//
+//	res, err := repo.InsertManyUnordered(ctx, batch)
+//	if err != nil {
+//	    return err
+//	}
+//	log.Printf("inserted %d, skipped %d duplicates", len(res.InsertedIDs), len(res.Duplicates))
+func (r *MongoRepository[T]) InsertManyUnordered(ctx context.Context, docs []*T) (_ *repository.InsertManyResult, err error) {
+	defer r.track(repository.OpInsertMany, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	res := &repository.InsertManyResult{InsertedIDs: make(map[int]primitive.ObjectID)}
+	if len(docs) == 0 {
+		return res, nil
+	}
+
+	now := nowUTC()
+	insertDocs := make([]any, len(docs))
+	for i, doc := range docs {
+		if err := prepareInsert(ctx, doc, now); err != nil {
+			return nil, err
+		}
+		insertDocs[i] = doc
+	}
+	if err := r.settings.sealAll(ctx, insertDocs); err != nil {
+		return nil, err
+	}
+
+	ids, dups, err := insertUnordered(ctx, r.coll, insertDocs)
+	failed := make(map[int]bool, len(dups))
+	for _, we := range dups {
+		failed[we.Index] = true
+		res.Duplicates = append(res.Duplicates, we.Index)
+	}
+	sort.Ints(res.Duplicates)
+
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) {
+		for _, we := range bulkErr.WriteErrors {
+			failed[we.Index] = true
+		}
+	} else if err != nil {
+		return res, err
+	}
+	for i, id := range ids {
+		if oid, ok := id.(primitive.ObjectID); ok && !failed[i] {
+			res.InsertedIDs[i] = oid
+		}
+	}
+	return res, err
+}
+
 // UpdateMany updates all documents matching the filter.
 // Returns the number of documents matched and modified.
-func (r *MongoRepository[T]) UpdateMany(ctx context.Context, filter any, update any) (matched int64, modified int64, err error) {
+func (r *MongoRepository[T]) UpdateMany(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	defer r.track(repository.OpUpdateMany, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	e := &document.UpdateEvent{Op: repository.OpUpdateMany, Filter: filter, Update: update}
+	if err = r.runBeforeUpdate(ctx, e); err != nil {
+		return 0, 0, err
+	}
+	filter, update = e.Filter, e.Update
+
 	f, err := normalizeFilter(filter)
 	if err != nil {
 		return 0, 0, err
 	}
+	r.settings.advisor.record(f, nil)
+	if err := r.checkQuery(ctx, repository.OpUpdateMany, f, nil); err != nil {
+		return 0, 0, err
+	}
+	defer r.observeShape(repository.OpUpdateMany, f, nil, time.Now(), &err)
 	if update == nil {
 		return 0, 0, repository.ErrNilUpdate
 	}
 
 	// Normalize update if it implements the Update interface
-	u := normalizeUpdate(update)
+	u, err := sealUpdate[T](ctx, r.settings, normalizeUpdate(update))
+	if err != nil {
+		return 0, 0, err
+	}
 
 	// Best-effort: add updated_at to $set updates
 	u = injectUpdatedAt(u, nowUTC())
+	if isVersioned[T]() {
+		u = injectVersionInc(u)
+	}
 
-	res, err := r.coll.UpdateMany(ctx, f, u)
+	uo := applyUpdateOptions(opts)
+	updateOpts := mopt.Update().SetUpsert(uo.Upsert)
+	if c := r.settings.comment(ctx, uo.Comment); c != "" {
+		updateOpts.SetComment(c)
+	}
+	res, err := r.coll.UpdateMany(ctx, f, u, updateOpts)
 	if err != nil {
 		return 0, 0, err
 	}
-	return res.MatchedCount, res.ModifiedCount, nil
+	e.Matched, e.Modified = res.MatchedCount, res.ModifiedCount
+	return res.MatchedCount, res.ModifiedCount, r.runAfterUpdate(ctx, e)
 }
 
 // DeleteMany deletes all documents matching the filter.
 // Returns the number of documents deleted.
 func (r *MongoRepository[T]) DeleteMany(ctx context.Context, filter any) (deleted int64, err error) {
-	f, err := normalizeFilter(filter)
+	defer r.track(repository.OpDeleteMany, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return 0, err
+	}
+
+	e := &document.DeleteEvent{Op: repository.OpDeleteMany, Filter: filter}
+	if err = r.runBeforeDelete(ctx, e); err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err == nil {
+			e.Deleted = deleted
+			err = r.runAfterDelete(ctx, e)
+		}
+	}()
+
+	f, err := normalizeFilter(e.Filter)
 	if err != nil {
 		return 0, err
 	}
+	r.settings.advisor.record(f, nil)
+	if err := r.checkQuery(ctx, repository.OpDeleteMany, f, nil); err != nil {
+		return 0, err
+	}
+
+	if len(r.settings.references) > 0 {
+		return r.deleteWithReferences(ctx, f, 0)
+	}
+	defer r.observeShape(repository.OpDeleteMany, f, nil, time.Now(), &err)
 
-	res, err := r.coll.DeleteMany(ctx, f)
+	res, err := r.coll.DeleteMany(ctx, f, r.deleteOptions(ctx))
 	if err != nil {
 		return 0, err
 	}
@@ -501,36 +931,133 @@ func (r *MongoRepository[T]) DeleteMany(ctx context.Context, filter any) (delete
 }
 
 // Count returns the number of documents matching the filter.
-func (r *MongoRepository[T]) Count(ctx context.Context, filter any) (int64, error) {
+func (r *MongoRepository[T]) Count(ctx context.Context, filter any, opts ...repository.CountOption) (_ int64, err error) {
+	defer r.track(repository.OpCount, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return 0, err
+	}
+
 	f, err := normalizeFilter(filter)
 	if err != nil {
 		return 0, err
 	}
+	r.settings.advisor.record(f, nil)
+	if err := r.checkQuery(ctx, repository.OpCount, f, nil); err != nil {
+		return 0, err
+	}
+	defer r.observeShape(repository.OpCount, f, nil, time.Now(), &err)
+
+	co := applyCountOptions(opts)
+	countOpts := mopt.Count()
+	if c := r.settings.comment(ctx, ""); c != "" {
+		countOpts.SetComment(c)
+	}
+	if co.Hint != nil {
+		countOpts.SetHint(co.Hint)
+	}
+	if co.Limit > 0 {
+		countOpts.SetLimit(co.Limit)
+	}
+	if d := r.settings.withMaxQueryTime(co.MaxTime).maxTime(ctx); d > 0 {
+		countOpts.SetMaxTime(d)
+	}
+	var key string
+	if len(opts) == 0 {
+		key = r.settings.flightKey(ctx, repository.OpCount, f)
+	}
+	backup := r.settings.hedgedCollection(ctx)
+	return coalesce(ctx, r.settings.flight, key, func() (int64, error) {
+		return hedged(ctx, r.settings.hedge, r.coll, backup, func(ctx context.Context, coll *mongo.Collection) (int64, error) {
+			return coll.CountDocuments(ctx, f, countOpts)
+		})
+	})
+}
+
+// EstimatedCount returns the number of documents in the collection from its
+// metadata, without scanning. It is much faster than Count on large
+// collections but takes no filter, includes soft-deleted documents, and can be
+// inaccurate after an unclean shutdown or with orphaned documents in sharded
+// clusters.
+//
+// Example:
+//
+//	total, err := repo.EstimatedCount(ctx)
+func (r *MongoRepository[T]) EstimatedCount(ctx context.Context) (_ int64, err error) {
+	defer r.track(repository.OpCount, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return 0, err
+	}
 
-	return r.coll.CountDocuments(ctx, f)
+	countOpts := mopt.EstimatedDocumentCount()
+	if c := r.settings.comment(ctx, ""); c != "" {
+		countOpts.SetComment(c)
+	}
+	if d := r.settings.maxTime(ctx); d > 0 {
+		countOpts.SetMaxTime(d)
+	}
+	return r.coll.EstimatedDocumentCount(ctx, countOpts)
 }
 
 // BulkWrite executes multiple write operations in a single batch.
 // Returns a BulkWriteResult with counts of affected documents.
-func (r *MongoRepository[T]) BulkWrite(ctx context.Context, ops []repository.BulkOp) (*repository.BulkWriteResult, error) {
+//
+// For versioned documents, updates increment the stored version, and a
+// replacement only matches the version its document was loaded with. A stale
+// replacement matches nothing, which shows as a MatchedCount lower than the
+// number of operations.
+func (r *MongoRepository[T]) BulkWrite(ctx context.Context, ops []repository.BulkOp) (_ *repository.BulkWriteResult, err error) {
+	defer r.track(repository.OpBulkWrite, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	if len(ops) == 0 {
 		return &repository.BulkWriteResult{}, nil
 	}
 
 	models := make([]mongo.WriteModel, 0, len(ops))
 
+	// Versioned replacements are bumped before the write and rolled back if it fails.
+	var bumped []document.VersionedDoc
+	defer func() {
+		if err != nil {
+			for _, v := range bumped {
+				v.SetVersion(v.CurrentVersion() - 1)
+			}
+		}
+	}()
+
 	for _, op := range ops {
 		switch op.Type {
 		case repository.BulkOpInsert:
-			models = append(models, mongo.NewInsertOneModel().SetDocument(op.Doc))
+			doc, err := r.settings.seal(ctx, op.Doc)
+			if err != nil {
+				return nil, err
+			}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(doc))
 
 		case repository.BulkOpUpdate:
 			f, err := normalizeFilter(op.Filter)
 			if err != nil {
 				return nil, err
 			}
-			u := normalizeUpdate(op.Update)
+			u, err := sealUpdate[T](ctx, r.settings, normalizeUpdate(op.Update))
+			if err != nil {
+				return nil, err
+			}
+			if isVersioned[T]() {
+				u = injectVersionInc(u)
+			}
 			model := mongo.NewUpdateOneModel().SetFilter(f).SetUpdate(u).SetUpsert(op.Upsert)
+			if len(op.ArrayFilters) > 0 {
+				model.SetArrayFilters(mopt.ArrayFilters{Filters: op.ArrayFilters})
+			}
+			if op.Hint != nil {
+				model.SetHint(op.Hint)
+			}
+			if op.Collation != nil {
+				model.SetCollation(toDriverCollation(op.Collation))
+			}
 			models = append(models, model)
 
 		case repository.BulkOpReplace:
@@ -538,7 +1065,24 @@ func (r *MongoRepository[T]) BulkWrite(ctx context.Context, ops []repository.Bul
 			if err != nil {
 				return nil, err
 			}
-			model := mongo.NewReplaceOneModel().SetFilter(f).SetReplacement(op.Doc).SetUpsert(op.Upsert)
+			doc := versionable(op.Doc)
+			if v, ok := doc.(document.VersionedDoc); ok {
+				expected := v.CurrentVersion()
+				v.SetVersion(expected + 1)
+				bumped = append(bumped, v)
+				f = withVersion(f, expected)
+			}
+			sealed, err := r.settings.seal(ctx, doc)
+			if err != nil {
+				return nil, err
+			}
+			model := mongo.NewReplaceOneModel().SetFilter(f).SetReplacement(sealed).SetUpsert(op.Upsert)
+			if op.Hint != nil {
+				model.SetHint(op.Hint)
+			}
+			if op.Collation != nil {
+				model.SetCollation(toDriverCollation(op.Collation))
+			}
 			models = append(models, model)
 
 		case repository.BulkOpDelete:
@@ -546,7 +1090,14 @@ func (r *MongoRepository[T]) BulkWrite(ctx context.Context, ops []repository.Bul
 			if err != nil {
 				return nil, err
 			}
-			models = append(models, mongo.NewDeleteOneModel().SetFilter(f))
+			model := mongo.NewDeleteOneModel().SetFilter(f)
+			if op.Hint != nil {
+				model.SetHint(op.Hint)
+			}
+			if op.Collation != nil {
+				model.SetCollation(toDriverCollation(op.Collation))
+			}
+			models = append(models, model)
 		}
 	}
 
@@ -575,11 +1126,26 @@ func (r *MongoRepository[T]) BulkWrite(ctx context.Context, ops []repository.Bul
 	}, nil
 }
 
+// toDriverCollation converts a repository collation to the driver's type.
+func toDriverCollation(c *repository.Collation) *mopt.Collation {
+	return &mopt.Collation{
+		Locale:          c.Locale,
+		CaseLevel:       c.CaseLevel,
+		CaseFirst:       c.CaseFirst,
+		Strength:        c.Strength,
+		NumericOrdering: c.NumericOrdering,
+		Alternate:       c.Alternate,
+		MaxVariable:     c.MaxVariable,
+		Backwards:       c.Backwards,
+	}
+}
+
 // ---- Aggregation ----
 
-// pipelineConverter is implemented by types that can be converted to a MongoDB pipeline.
+// pipelineConverter is implemented by types that can be converted to a MongoDB
+// pipeline, such as spec.Pipeline.
 type pipelineConverter interface {
-	ToPipeline() []bson.M
+	ToPipeline() ([]bson.M, error)
 }
 
 func normalizePipeline(pipeline any) ([]bson.M, error) {
@@ -600,7 +1166,7 @@ func normalizePipeline(pipeline any) ([]bson.M, error) {
 		}
 		return result, nil
 	case pipelineConverter:
-		return p.ToPipeline(), nil
+		return p.ToPipeline()
 	default:
 		return nil, repository.ErrInvalidFilter
 	}
@@ -608,29 +1174,33 @@ func normalizePipeline(pipeline any) ([]bson.M, error) {
 
 // Aggregate executes an aggregation pipeline and returns the results decoded as type T.
 // The pipeline can be []bson.M, []bson.D, or a Pipeline builder.
-func (r *MongoRepository[T]) Aggregate(ctx context.Context, pipeline any) ([]T, error) {
+func (r *MongoRepository[T]) Aggregate(ctx context.Context, pipeline any) (_ []T, err error) {
+	defer r.track(repository.OpAggregate, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	p, err := normalizePipeline(pipeline)
 	if err != nil {
 		return nil, err
 	}
+	defer r.observeShape(repository.OpAggregate, p, nil, time.Now(), &err)
 
-	cur, err := r.coll.Aggregate(ctx, p)
+	cur, err := r.aggregate(ctx, p)
 	if err != nil {
 		return nil, err
 	}
 	defer cur.Close(ctx)
 
 	var results []T
-	if err := cur.All(ctx, &results); err != nil {
+	if err := decodeAll[T](ctx, r.settings, cur, &results); err != nil {
 		return nil, err
 	}
 
 	// AfterLoad hook for each document.
 	for i := range results {
-		if h, ok := any(&results[i]).(document.AfterLoad); ok {
-			if err := h.AfterLoad(ctx); err != nil {
-				return nil, err
-			}
+		if err := r.settings.afterLoad(ctx, &results[i]); err != nil {
+			return nil, err
 		}
 	}
 
@@ -639,13 +1209,19 @@ func (r *MongoRepository[T]) Aggregate(ctx context.Context, pipeline any) ([]T,
 
 // AggregateRaw executes an aggregation pipeline and returns raw bson.M results.
 // Use this when the aggregation output doesn't match type T.
-func (r *MongoRepository[T]) AggregateRaw(ctx context.Context, pipeline any) ([]bson.M, error) {
+func (r *MongoRepository[T]) AggregateRaw(ctx context.Context, pipeline any) (_ []bson.M, err error) {
+	defer r.track(repository.OpAggregate, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	p, err := normalizePipeline(pipeline)
 	if err != nil {
 		return nil, err
 	}
+	defer r.observeShape(repository.OpAggregate, p, nil, time.Now(), &err)
 
-	cur, err := r.coll.Aggregate(ctx, p)
+	cur, err := r.aggregate(ctx, p)
 	if err != nil {
 		return nil, err
 	}
@@ -659,13 +1235,99 @@ func (r *MongoRepository[T]) AggregateRaw(ctx context.Context, pipeline any) ([]
 	return results, nil
 }
 
+// CountPipeline runs the pipeline with a $count stage appended and returns the
+// number of documents it produces, or 0 if it produces none. The pipeline can be
+// []bson.M, []bson.D, or a Pipeline builder; it is not modified.
+//
+// Example:
+//
+//	n, err := repo.CountPipeline(ctx, spec.NewPipeline().
+//	    Match(spec.Eq("status", "paid")).
+//	    Unwind("$items"))
+func (r *MongoRepository[T]) CountPipeline(ctx context.Context, pipeline any) (_ int64, err error) {
+	defer r.track(repository.OpAggregate, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return 0, err
+	}
+
+	p, err := normalizePipeline(pipeline)
+	if err != nil {
+		return 0, err
+	}
+	p = append(slices.Clip(p), bson.M{"$count": "n"})
+	defer r.observeShape(repository.OpAggregate, p, nil, time.Now(), &err)
+
+	cur, err := r.aggregate(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var out struct {
+		N int64 `bson:"n"`
+	}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&out); err != nil {
+			return 0, err
+		}
+	}
+	return out.N, cur.Err()
+}
+
+// aggregate runs a normalized pipeline, adapted to the compatibility profile if
+// one is configured.
+func (r *MongoRepository[T]) aggregate(ctx context.Context, p []bson.M) (*mongo.Cursor, error) {
+	if r.settings.compat != nil {
+		return compat.Aggregate(ctx, r.coll, *r.settings.compat, p, r.aggregateOptions(ctx))
+	}
+	return r.coll.Aggregate(ctx, p, r.aggregateOptions(ctx))
+}
+
+// aggregateOptions returns the driver options shared by Aggregate and AggregateRaw.
+func (r *MongoRepository[T]) aggregateOptions(ctx context.Context) *mopt.AggregateOptions {
+	aggOpts := mopt.Aggregate()
+	if c := r.settings.comment(ctx, ""); c != "" {
+		aggOpts.SetComment(c)
+	}
+	if d := r.settings.maxTime(ctx); d > 0 {
+		aggOpts.SetMaxTime(d)
+	}
+	return aggOpts
+}
+
+// replaceOptions returns the driver options for ReplaceOne.
+func (r *MongoRepository[T]) replaceOptions(ctx context.Context) *mopt.ReplaceOptions {
+	o := mopt.Replace()
+	if c := r.settings.comment(ctx, ""); c != "" {
+		o.SetComment(c)
+	}
+	return o
+}
+
+// deleteOptions returns the driver options for DeleteOne and DeleteMany.
+func (r *MongoRepository[T]) deleteOptions(ctx context.Context) *mopt.DeleteOptions {
+	o := mopt.Delete()
+	if c := r.settings.comment(ctx, ""); c != "" {
+		o.SetComment(c)
+	}
+	return o
+}
+
 // ---- helpers ----
 
 func normalizeFilter(filter any) (any, error) {
 	if filter == nil {
 		return bson.M{}, nil
 	}
+	if c, ok := filter.(*mongospec.CompiledFilter); ok {
+		return c.Raw(), nil
+	}
 	if f, ok := filter.(mongospec.Filter); ok {
+		// Simple filters encode straight to BSON, skipping the intermediate bson.M
+		// tree and the driver's reflection-based marshaling.
+		if _, ok := f.(mongospec.RawAppender); ok {
+			return mongospec.AppendFilter(nil, f)
+		}
 		return f.ToMongo(), nil
 	}
 	return filter, nil
@@ -695,3 +1357,23 @@ func applyFindOptions(opts []repository.FindOption) repository.FindOptions {
 	}
 	return fo
 }
+
+func applyCountOptions(opts []repository.CountOption) repository.CountOptions {
+	var co repository.CountOptions
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&co)
+		}
+	}
+	return co
+}
+
+func applyUpdateOptions(opts []repository.UpdateOption) repository.UpdateOptions {
+	var uo repository.UpdateOptions
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&uo)
+		}
+	}
+	return uo
+}
@@ -3,17 +3,31 @@
 package mongorepo_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/encryption"
+	"github.com/dElCIoGio/mongox/repository"
 	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
 	mongospec "github.com/dElCIoGio/mongox/spec"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	mopt "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 
 	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
 )
@@ -72,6 +86,35 @@ func setupMongo(t *testing.T) (*mongo.Client, func()) {
 	return client, cleanup
 }
 
+// setupMongoReplicaSet starts a single-node replica set, required for transactions.
+func setupMongoReplicaSet(t *testing.T) (*mongo.Client, func()) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7", mongodb.WithReplicaSet("rs0"))
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri).SetDirect(true))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+
+	cleanup := func() {
+		_ = client.Disconnect(ctx)
+		_ = container.Terminate(ctx)
+	}
+
+	return client, cleanup
+}
+
 func TestInsertOne_AutoTouchAndBeforeSaveHook(t *testing.T) {
 	client, cleanup := setupMongo(t)
 	defer cleanup()
@@ -238,7 +281,7 @@ func TestReplaceOne_TouchesUpdatedAtAndCallsBeforeSave(t *testing.T) {
 		Total:    999,
 	}
 
-	matched, modified, err := repo.ReplaceOne(ctx, mongospec.Eq("_id", doc.ID), replacement)
+	matched, _, err := repo.ReplaceOne(ctx, mongospec.Eq("_id", doc.ID), replacement)
 	if err != nil {
 		t.Fatalf("ReplaceOne failed: %v", err)
 	}
@@ -262,3 +305,2598 @@ func TestReplaceOne_TouchesUpdatedAtAndCallsBeforeSave(t *testing.T) {
 		t.Fatalf("expected updated_at to increase, old=%v new=%v", oldUpdatedAt, got.UpdatedAt)
 	}
 }
+
+func TestFindInto_ReusesBuffer(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_find_into")
+
+	repo := mongorepo.New[Order](coll)
+
+	for i := 0; i < 5; i++ {
+		if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: i}); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	buf := make([]Order, 0, 16)
+	if err := repo.FindInto(ctx, mongospec.Eq("tenant_id", "t1"), &buf); err != nil {
+		t.Fatalf("FindInto failed: %v", err)
+	}
+	if len(buf) != 5 {
+		t.Fatalf("expected 5 docs, got %d", len(buf))
+	}
+	if cap(buf) != 16 {
+		t.Fatalf("expected buffer capacity to be reused, got cap=%d", cap(buf))
+	}
+
+	if err := repo.FindInto(ctx, mongospec.Gte("total", 3), &buf); err != nil {
+		t.Fatalf("FindInto failed: %v", err)
+	}
+	if len(buf) != 2 {
+		t.Fatalf("expected 2 docs on second call, got %d", len(buf))
+	}
+}
+
+func TestFindAs_ProjectsIntoLightweightStruct(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_find_as")
+
+	repo := mongorepo.New[Order](coll)
+
+	if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Paid: true, Total: 42}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	type orderTotal struct {
+		Total int `bson:"total"`
+	}
+
+	got, err := mongorepo.FindAs[Order, orderTotal](ctx, repo, mongospec.Eq("tenant_id", "t1"), nil)
+	if err != nil {
+		t.Fatalf("FindAs failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Total != 42 {
+		t.Fatalf("unexpected FindAs result: %#v", got)
+	}
+}
+
+func TestWithMaxQueryTime_ReturnsErrTimeout(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_max_time")
+
+	hist := repository.NewHistogram()
+	repo := mongorepo.New[Order](coll,
+		mongorepo.WithMaxQueryTime(50*time.Millisecond),
+		mongorepo.WithObserver(hist),
+	)
+
+	if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: 1}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	_, err := repo.Find(ctx, bson.M{"$where": "sleep(500) || true"})
+	if !errors.Is(err, repository.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+
+	snap := hist.Snapshot()
+	if snap[repository.OpInsertOne].Count != 1 {
+		t.Fatalf("expected 1 insert observation, got %d", snap[repository.OpInsertOne].Count)
+	}
+	if find := snap[repository.OpFind]; find.Count != 1 || find.Timeouts != 1 {
+		t.Fatalf("expected 1 timed-out find, got count=%d timeouts=%d", find.Count, find.Timeouts)
+	}
+}
+
+func TestWithRateLimit_ThrottlesOperations(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_rate_limit")
+
+	repo := mongorepo.New[Order](coll, mongorepo.WithRateLimit(10, 1))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := repo.Count(ctx, bson.M{}); err != nil {
+			t.Fatalf("Count failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Fatalf("expected 3 calls at 10/s with burst 1 to take >= 200ms, took %v", elapsed)
+	}
+
+	tight, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := repo.Count(tight, bson.M{}); !errors.Is(err, repository.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout when the deadline is shorter than the wait, got %v", err)
+	}
+}
+
+func TestStrictVariants_ReturnErrNotFound(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_strict")
+
+	repo := mongorepo.New[Order](coll)
+
+	doc := &Order{TenantID: "t1", Total: 10}
+	if err := repo.InsertOne(ctx, doc); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	missing := mongospec.Eq("tenant_id", "missing")
+
+	if _, _, err := repo.UpdateOneStrict(ctx, missing, mongospec.Set("paid", true)); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("UpdateOneStrict: expected ErrNotFound, got %v", err)
+	}
+	if _, _, err := repo.ReplaceOneStrict(ctx, missing, &Order{TenantID: "t2"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("ReplaceOneStrict: expected ErrNotFound, got %v", err)
+	}
+	if _, err := repo.DeleteOneStrict(ctx, missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("DeleteOneStrict: expected ErrNotFound, got %v", err)
+	}
+
+	matched, _, err := repo.UpdateOneStrict(ctx, mongospec.Eq("_id", doc.ID), mongospec.Set("paid", true))
+	if err != nil || matched != 1 {
+		t.Fatalf("UpdateOneStrict on existing doc: matched=%d err=%v", matched, err)
+	}
+	if deleted, err := repo.DeleteOneStrict(ctx, mongospec.Eq("_id", doc.ID)); err != nil || deleted != 1 {
+		t.Fatalf("DeleteOneStrict on existing doc: deleted=%d err=%v", deleted, err)
+	}
+}
+
+func TestUpsertOne_InsertsThenUpdates(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_upsert")
+
+	repo := mongorepo.New[Order](coll)
+
+	res, err := repo.UpsertOne(ctx, mongospec.Eq("tenant_id", "t1"), mongospec.Inc("total", 5))
+	if err != nil {
+		t.Fatalf("UpsertOne failed: %v", err)
+	}
+	if res.UpsertedCount != 1 || res.UpsertedID.IsZero() {
+		t.Fatalf("expected an inserted document, got %+v", res)
+	}
+
+	res, err = repo.UpsertOne(ctx, mongospec.Eq("tenant_id", "t1"), mongospec.Inc("total", 5))
+	if err != nil || res.MatchedCount != 1 || res.UpsertedCount != 0 {
+		t.Fatalf("expected the second upsert to match, got %+v err=%v", res, err)
+	}
+
+	got, err := repo.FindOne(ctx, mongospec.Eq("tenant_id", "t1"))
+	if err != nil || got.Total != 10 {
+		t.Fatalf("expected total=10, got %+v err=%v", got, err)
+	}
+
+	if _, _, err := repo.UpdateOne(ctx, mongospec.Eq("tenant_id", "t2"), mongospec.Set("paid", true), repository.WithUpsert()); err != nil {
+		t.Fatalf("UpdateOne with upsert failed: %v", err)
+	}
+	if n, _ := repo.Count(ctx, nil); n != 2 {
+		t.Fatalf("expected 2 orders, got %d", n)
+	}
+}
+
+func TestUpdateAndFetch_ReturnsUpdatedDocument(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_update_fetch")
+
+	repo := mongorepo.New[Order](coll)
+
+	doc := &Order{TenantID: "t1", Total: 10}
+	if err := repo.InsertOne(ctx, doc); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	got, err := repo.UpdateAndFetch(ctx, mongospec.Eq("_id", doc.ID), mongospec.Inc("total", 5))
+	if err != nil {
+		t.Fatalf("UpdateAndFetch failed: %v", err)
+	}
+	if got.Total != 15 {
+		t.Fatalf("expected total=15, got %d", got.Total)
+	}
+	if !got.AfterLoadCalled {
+		t.Fatal("expected AfterLoad to be called")
+	}
+
+	if _, err := repo.UpdateAndFetch(ctx, mongospec.Eq("tenant_id", "missing"), mongospec.Inc("total", 1)); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestFindOneAndModify_ReturnsBeforeOrAfter(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_find_and_modify")
+
+	repo := mongorepo.New[Order](coll)
+
+	for _, total := range []int{10, 20} {
+		if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: total}); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	// Claim the smallest unpaid order; the default returns it before the update.
+	before, err := repo.FindOneAndUpdate(ctx,
+		mongospec.Eq("paid", false),
+		mongospec.Set("paid", true),
+		mongorepo.WithModifySort(bson.D{{Key: "total", Value: 1}}),
+	)
+	if err != nil {
+		t.Fatalf("FindOneAndUpdate failed: %v", err)
+	}
+	if before.Total != 10 || before.Paid {
+		t.Fatalf("expected unpaid order with total=10, got total=%d paid=%v", before.Total, before.Paid)
+	}
+
+	after, err := repo.FindOneAndUpdate(ctx,
+		mongospec.Eq("tenant_id", "counter"),
+		mongospec.Inc("total", 1),
+		mongorepo.WithModifyUpsert(),
+		mongorepo.ReturnAfter(),
+	)
+	if err != nil {
+		t.Fatalf("FindOneAndUpdate upsert failed: %v", err)
+	}
+	if after.Total != 1 || !after.AfterLoadCalled {
+		t.Fatalf("expected upserted counter=1 with AfterLoad, got total=%d afterLoad=%v", after.Total, after.AfterLoadCalled)
+	}
+
+	replacement := &Order{TenantID: "t2", Total: 99}
+	replaced, err := repo.FindOneAndReplace(ctx, mongospec.Eq("_id", before.ID), replacement)
+	if err != nil {
+		t.Fatalf("FindOneAndReplace failed: %v", err)
+	}
+	if !replaced.Paid || replaced.TenantID != "t1" {
+		t.Fatalf("expected the paid t1 order before replace, got %+v", replaced)
+	}
+	if !replacement.BeforeSaveCalled {
+		t.Fatal("expected BeforeSave to be called on the replacement")
+	}
+
+	deleted, err := repo.FindOneAndDelete(ctx, mongospec.Eq("tenant_id", "t2"))
+	if err != nil {
+		t.Fatalf("FindOneAndDelete failed: %v", err)
+	}
+	if deleted.Total != 99 {
+		t.Fatalf("expected deleted total=99, got %d", deleted.Total)
+	}
+	if _, err := repo.FindOneAndDelete(ctx, mongospec.Eq("tenant_id", "t2")); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+type Customer struct {
+	document.Base `bson:",inline"`
+	DeletedAt     *time.Time `bson:"deleted_at,omitempty"`
+
+	Name string `bson:"name"`
+}
+
+func TestSoftDelete_CascadesAndRestores(t *testing.T) {
+	client, cleanup := setupMongoReplicaSet(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	orders := db.Collection("cascade_orders")
+	items := db.Collection("cascade_items")
+
+	customers := mongorepo.NewSoftDelete[Customer](db.Collection("cascade_customers"),
+		mongorepo.WithCascade(mongorepo.Cascade{
+			Collection: orders,
+			ForeignKey: "customer_id",
+			Children:   []mongorepo.Cascade{{Collection: items, ForeignKey: "order_id"}},
+		}),
+	)
+
+	cust := &Customer{Name: "Ada"}
+	if err := customers.InsertOne(ctx, cust); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	orderRes, err := orders.InsertMany(ctx, []any{
+		bson.M{"customer_id": cust.ID},
+		bson.M{"customer_id": cust.ID, "deleted_at": time.Now().Add(-time.Hour).UTC()},
+	})
+	if err != nil {
+		t.Fatalf("insert orders: %v", err)
+	}
+	if _, err := items.InsertOne(ctx, bson.M{"order_id": orderRes.InsertedIDs[0]}); err != nil {
+		t.Fatalf("insert item: %v", err)
+	}
+
+	countDeleted := func(coll *mongo.Collection) int64 {
+		t.Helper()
+		n, err := coll.CountDocuments(ctx, bson.M{"deleted_at": bson.M{"$exists": true}})
+		if err != nil {
+			t.Fatalf("CountDocuments: %v", err)
+		}
+		return n
+	}
+
+	if n, err := customers.SoftDelete(ctx, mongospec.Eq("_id", cust.ID)); err != nil || n != 1 {
+		t.Fatalf("SoftDelete: n=%d err=%v", n, err)
+	}
+	if got := countDeleted(orders); got != 2 {
+		t.Fatalf("expected 2 deleted orders, got %d", got)
+	}
+	if got := countDeleted(items); got != 1 {
+		t.Fatalf("expected 1 deleted item, got %d", got)
+	}
+
+	if n, err := customers.Restore(ctx, mongospec.Eq("_id", cust.ID)); err != nil || n != 1 {
+		t.Fatalf("Restore: n=%d err=%v", n, err)
+	}
+	// The order deleted before the customer stays deleted.
+	if got := countDeleted(orders); got != 1 {
+		t.Fatalf("expected 1 deleted order after restore, got %d", got)
+	}
+	if got := countDeleted(items); got != 0 {
+		t.Fatalf("expected 0 deleted items after restore, got %d", got)
+	}
+}
+
+func TestDeleteOne_EnforcesReferences(t *testing.T) {
+	client, cleanup := setupMongoReplicaSet(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	invoices := db.Collection("ref_invoices")
+	sessions := db.Collection("ref_sessions")
+	notes := db.Collection("ref_notes")
+
+	customers := mongorepo.New[Customer](db.Collection("ref_customers"),
+		mongorepo.WithReferences(
+			mongorepo.Reference{Collection: invoices, ForeignKey: "customer_id", OnDelete: mongorepo.OnDeleteRestrict},
+			mongorepo.Reference{Collection: sessions, ForeignKey: "customer_id", OnDelete: mongorepo.OnDeleteCascade},
+			mongorepo.Reference{Collection: notes, ForeignKey: "customer_id", OnDelete: mongorepo.OnDeleteSetNull},
+		),
+	)
+
+	cust := &Customer{Name: "Ada"}
+	if err := customers.InsertOne(ctx, cust); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	invoice, err := invoices.InsertOne(ctx, bson.M{"customer_id": cust.ID})
+	if err != nil {
+		t.Fatalf("insert invoice: %v", err)
+	}
+	if _, err := sessions.InsertOne(ctx, bson.M{"customer_id": cust.ID}); err != nil {
+		t.Fatalf("insert session: %v", err)
+	}
+	if _, err := notes.InsertOne(ctx, bson.M{"customer_id": cust.ID}); err != nil {
+		t.Fatalf("insert note: %v", err)
+	}
+
+	if _, err := customers.DeleteOne(ctx, mongospec.Eq("_id", cust.ID)); !errors.Is(err, repository.ErrReferenced) {
+		t.Fatalf("expected ErrReferenced, got %v", err)
+	}
+	if n, _ := sessions.CountDocuments(ctx, bson.M{}); n != 1 {
+		t.Fatalf("restricted delete must not cascade, got %d sessions", n)
+	}
+
+	if _, err := invoices.DeleteOne(ctx, bson.M{"_id": invoice.InsertedID}); err != nil {
+		t.Fatalf("delete invoice: %v", err)
+	}
+
+	deleted, err := customers.DeleteOne(ctx, mongospec.Eq("_id", cust.ID))
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteOne: deleted=%d err=%v", deleted, err)
+	}
+	if n, _ := sessions.CountDocuments(ctx, bson.M{}); n != 0 {
+		t.Fatalf("expected sessions to be cascaded, got %d", n)
+	}
+	if n, _ := notes.CountDocuments(ctx, bson.M{"customer_id": nil}); n != 1 {
+		t.Fatalf("expected note customer_id to be nulled, got %d", n)
+	}
+}
+
+func TestFindDuplicates_AndMerge(t *testing.T) {
+	client, cleanup := setupMongoReplicaSet(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	invoices := db.Collection("dup_invoices")
+
+	customers := mongorepo.New[Customer](db.Collection("dup_customers"),
+		mongorepo.WithReferences(mongorepo.Reference{Collection: invoices, ForeignKey: "customer_id"}),
+	)
+
+	docs := []*Customer{{Name: "Ada"}, {Name: "Ada"}, {Name: "Ada"}, {Name: "Grace"}}
+	if _, err := customers.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	if _, err := invoices.InsertOne(ctx, bson.M{"customer_id": docs[2].ID}); err != nil {
+		t.Fatalf("insert invoice: %v", err)
+	}
+
+	groups, err := customers.FindDuplicates(ctx, "name")
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Docs) != 3 || groups[0].Key["name"] != "Ada" {
+		t.Fatalf("unexpected groups: %#v", groups)
+	}
+
+	removed, err := customers.MergeDuplicates(ctx, docs[0].ID, []any{docs[1].ID, docs[2].ID})
+	if err != nil || removed != 2 {
+		t.Fatalf("MergeDuplicates: removed=%d err=%v", removed, err)
+	}
+	if n, _ := invoices.CountDocuments(ctx, bson.M{"customer_id": docs[0].ID}); n != 1 {
+		t.Fatalf("expected invoice re-pointed to survivor, got %d", n)
+	}
+	if n, _ := customers.Count(ctx, mongospec.Eq("name", "Ada")); n != 1 {
+		t.Fatalf("expected 1 Ada left, got %d", n)
+	}
+}
+
+func TestCausalSession_ReadYourWrites(t *testing.T) {
+	client, cleanup := setupMongoReplicaSet(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("causal_customers")
+
+	sess, err := mongorepo.StartCausalSession(client)
+	if err != nil {
+		t.Fatalf("StartCausalSession: %v", err)
+	}
+	defer sess.End(ctx)
+
+	customers := mongorepo.Causal[Customer](mongorepo.New[Customer](coll), sess)
+	c := &Customer{Name: "Ada"}
+	if err := customers.InsertOne(ctx, c); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	got, err := customers.FindOne(ctx, mongospec.Eq("_id", c.ID))
+	if err != nil || got.Name != "Ada" {
+		t.Fatalf("expected to read own write, got %+v, %v", got, err)
+	}
+
+	tok, err := mongorepo.ParseCausalToken(sess.Token().String())
+	if err != nil {
+		t.Fatalf("ParseCausalToken: %v", err)
+	}
+	if tok.OperationTime.IsZero() {
+		t.Fatal("expected token to carry the operation time of the insert")
+	}
+
+	next, err := mongorepo.StartCausalSession(client, tok)
+	if err != nil {
+		t.Fatalf("StartCausalSession with token: %v", err)
+	}
+	defer next.End(ctx)
+	if n, err := mongorepo.New[Customer](coll).Count(next.Context(ctx), mongospec.Eq("_id", c.ID)); err != nil || n != 1 {
+		t.Fatalf("expected continued session to see the insert, got %d, %v", n, err)
+	}
+}
+
+func TestRunInTransaction_JoinsTransactionFromContext(t *testing.T) {
+	client, cleanup := setupMongoReplicaSet(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	customers := mongorepo.New[Customer](client.Database("testdb").Collection("txn_join_customers"))
+	if err := customers.InsertOne(ctx, &Customer{Name: "seed"}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err := mongorepo.RunInTransaction(ctx, client, func(txCtx context.Context) error {
+		info, ok := mongorepo.TxInfoFromContext(txCtx)
+		if !ok || info.Attempt != 1 || !mongorepo.InTransaction(txCtx) {
+			t.Fatalf("expected transaction metadata in context, got %+v, %v", info, ok)
+		}
+		inner := mongorepo.RunInTransaction(txCtx, client, func(innerCtx context.Context) error {
+			if mongorepo.SessionFromContext(innerCtx) != mongorepo.SessionFromContext(txCtx) {
+				t.Fatal("expected nested call to reuse the outer session")
+			}
+			return customers.InsertOne(innerCtx, &Customer{Name: "inner"})
+		})
+		if inner != nil {
+			return inner
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected errAbort, got %v", err)
+	}
+
+	if n, _ := customers.Count(ctx, mongospec.Eq("name", "inner")); n != 0 {
+		t.Fatalf("expected nested insert to roll back with the outer transaction, found %d", n)
+	}
+}
+
+type Subscriber struct {
+	document.Base `bson:",inline"`
+
+	Email string `bson:"email"`
+	Name  string `bson:"name"`
+	Plan  string `bson:"plan"`
+}
+
+func TestInsertManyOnConflict_Policies(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("subscribers_conflict")
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: mopt.Index().SetUnique(true),
+	}); err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+
+	repo := mongorepo.New[Subscriber](coll)
+	if err := repo.InsertOne(ctx, &Subscriber{Email: "ada@example.com", Name: "Ada", Plan: "free"}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	res, err := repo.InsertManyOnConflict(ctx, []*Subscriber{
+		{Email: "grace@example.com", Name: "Grace", Plan: "free"},
+		{Email: "ada@example.com", Name: "Ada L.", Plan: "pro"},
+	}, mongorepo.OnConflictIgnore)
+	if err != nil {
+		t.Fatalf("InsertManyOnConflict(ignore) failed: %v", err)
+	}
+	if res.InsertedCount != 1 || len(res.Conflicts) != 1 || res.Conflicts[0] != 1 || res.ResolvedCount != 0 {
+		t.Fatalf("unexpected ignore result: %+v", res)
+	}
+
+	conflicted, err := repo.InsertOneOnConflict(ctx, &Subscriber{Email: "ada@example.com", Name: "Ada L.", Plan: "pro"}, mongorepo.OnConflictMerge("plan"))
+	if err != nil || !conflicted {
+		t.Fatalf("InsertOneOnConflict(merge): conflicted=%v err=%v", conflicted, err)
+	}
+	ada, err := repo.FindOne(ctx, mongospec.Eq("email", "ada@example.com"))
+	if err != nil || ada.Name != "Ada" || ada.Plan != "pro" {
+		t.Fatalf("expected only plan to be merged, got %+v, %v", ada, err)
+	}
+
+	res, err = repo.InsertManyOnConflict(ctx, []*Subscriber{{Email: "grace@example.com", Name: "Grace H.", Plan: "team"}}, mongorepo.OnConflictReplace)
+	if err != nil || res.ResolvedCount != 1 {
+		t.Fatalf("InsertManyOnConflict(replace): %+v, %v", res, err)
+	}
+	grace, err := repo.FindOne(ctx, mongospec.Eq("email", "grace@example.com"))
+	if err != nil || grace.Name != "Grace H." || grace.Plan != "team" {
+		t.Fatalf("expected grace to be replaced, got %+v, %v", grace, err)
+	}
+	if n, _ := repo.Count(ctx, bson.M{}); n != 2 {
+		t.Fatalf("expected 2 subscribers, got %d", n)
+	}
+}
+
+func TestInsertManyUnordered_ReportsDuplicates(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("subscribers_unordered")
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: mopt.Index().SetUnique(true),
+	}); err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+
+	repo := mongorepo.New[Subscriber](coll)
+	if err := repo.InsertOne(ctx, &Subscriber{Email: "ada@example.com"}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	if _, err := repo.InsertMany(ctx, []*Subscriber{{Email: "ada@example.com"}}); !errors.Is(err, repository.ErrDuplicateKey) {
+		t.Fatalf("expected ordered InsertMany to return ErrDuplicateKey, got %v", err)
+	}
+
+	res, err := repo.InsertManyUnordered(ctx, []*Subscriber{
+		{Email: "ada@example.com"},
+		{Email: "grace@example.com"},
+		{Email: "grace@example.com"},
+		{Email: "linus@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("InsertManyUnordered failed: %v", err)
+	}
+	if len(res.Duplicates) != 2 || res.Duplicates[0] != 0 || res.Duplicates[1] != 2 {
+		t.Fatalf("expected duplicates at indexes 0 and 2, got %v", res.Duplicates)
+	}
+	if _, ok := res.InsertedIDs[1]; !ok || len(res.InsertedIDs) != 2 {
+		t.Fatalf("expected documents 1 and 3 inserted, got %v", res.InsertedIDs)
+	}
+	if n, _ := repo.Count(ctx, bson.M{}); n != 3 {
+		t.Fatalf("expected 3 subscribers, got %d", n)
+	}
+}
+
+func TestExportCSV_StreamsColumns(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("export_orders")
+	_, err := coll.InsertMany(ctx, []any{
+		bson.M{"_id": 1, "number": "A-1", "total": 12.5, "qty": 2, "customer": bson.M{"name": "Ada"}, "tags": bson.A{"gift", "rush"}},
+		bson.M{"_id": 2, "number": "A-2", "total": -3, "customer": bson.M{"name": "=HYPERLINK(\"x\")"}},
+		bson.M{"_id": 3, "number": "B-1", "total": 7, "paid": true},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+
+	repo := mongorepo.New[bson.M](coll)
+	var buf strings.Builder
+	err = repo.ExportCSV(ctx, &buf, mongospec.Lt("_id", 3), []mongorepo.ColumnSpec{
+		{Header: "Order", Field: "number"},
+		{Header: "Customer", Field: "customer.name"},
+		{Field: "total"},
+		{Header: "Qty", Field: "qty"},
+		{Header: "Tags", Field: "tags"},
+		{Header: "Has customer", Field: "customer", Format: func(v bson.RawValue) string {
+			return strconv.FormatBool(v.Type != 0)
+		}},
+	}, repository.WithSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+
+	want := "Order,Customer,total,Qty,Tags,Has customer\n" +
+		"A-1,Ada,12.5,2,gift;rush,true\n" +
+		"A-2,\"'=HYPERLINK(\"\"x\"\")\",-3,,,true\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected CSV.\n got: %q\nwant: %q", buf.String(), want)
+	}
+}
+
+func TestExportCSV_DecryptsAndSkipsDeleted(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("export_secrets")
+	enc := encryption.NewAESGCM(encryption.StaticKeys("k1", map[string][]byte{"k1": make([]byte, 32)}))
+	repo := mongorepo.NewSoftDelete[secretDoc](coll, mongorepo.WithEncryption(enc))
+
+	for _, d := range []*secretDoc{{Name: "Ada", SSN: "123-45-6789"}, {Name: "Bob", SSN: "987-65-4321"}} {
+		if err := repo.InsertOne(ctx, d); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+	if _, err := repo.SoftDelete(ctx, mongospec.Eq("name", "Bob")); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+
+	var buf strings.Builder
+	columns := []mongorepo.ColumnSpec{{Header: "Name", Field: "name"}, {Header: "SSN", Field: "ssn"}}
+	if err := repo.ExportCSV(ctx, &buf, nil, columns, repository.WithComment("export")); err != nil {
+		t.Fatalf("ExportCSV failed: %v", err)
+	}
+	if want := "Name,SSN\nAda,123-45-6789\n"; buf.String() != want {
+		t.Fatalf("unexpected CSV.\n got: %q\nwant: %q", buf.String(), want)
+	}
+}
+
+func TestStreamJSON_ArrayAndNDJSON(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("export_json")
+	_, err := coll.InsertMany(ctx, []any{
+		bson.M{"_id": 1, "number": "A-1", "total": 12.5},
+		bson.M{"_id": 2, "number": "A-2", "total": 3},
+		bson.M{"_id": 3, "number": "B-1", "total": 7},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+
+	repo := mongorepo.New[bson.M](coll)
+	var buf strings.Builder
+	err = repo.StreamJSON(ctx, &buf, mongospec.Lt("_id", 3), mongorepo.JSONArray,
+		repository.WithSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		t.Fatalf("StreamJSON array failed: %v", err)
+	}
+	var docs []map[string]any
+	if err := json.Unmarshal([]byte(buf.String()), &docs); err != nil {
+		t.Fatalf("array output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if len(docs) != 2 || docs[0]["number"] != "A-1" || docs[1]["total"] != float64(3) {
+		t.Fatalf("unexpected array output: %s", buf.String())
+	}
+
+	buf.Reset()
+	if err := repo.StreamJSON(ctx, &buf, mongospec.Eq("_id", 99), mongorepo.JSONArray); err != nil {
+		t.Fatalf("StreamJSON empty failed: %v", err)
+	}
+	if buf.String() != "[]" {
+		t.Fatalf("expected [] for no matches, got %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := repo.StreamJSON(ctx, &buf, nil, mongorepo.NDJSON); err != nil {
+		t.Fatalf("StreamJSON NDJSON failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %q", buf.String())
+	}
+	for _, line := range lines {
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(line), &doc); err != nil {
+			t.Fatalf("line is not valid JSON: %v\n%s", err, line)
+		}
+	}
+}
+
+type logEntry struct {
+	document.Base `bson:",inline"`
+	Level         string `bson:"level"`
+	Message       string `bson:"message"`
+}
+
+func TestCapped_CreateAndTail(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	if _, err := db.Collection("plain").InsertOne(ctx, bson.M{"x": 1}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if err := mongorepo.CreateCapped(ctx, db, "plain", 1<<20, 0); !errors.Is(err, mongorepo.ErrNotCapped) {
+		t.Fatalf("expected ErrNotCapped, got %v", err)
+	}
+
+	repo := mongorepo.New[logEntry](db.Collection("log"), mongorepo.WithCapped(1<<20, 100))
+	if err := repo.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes failed: %v", err)
+	}
+	if err := repo.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes on an existing capped collection failed: %v", err)
+	}
+
+	if err := repo.InsertOne(ctx, &logEntry{Level: "error", Message: "first"}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	got := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.Tail(tctx, mongospec.Eq("level", "error"), func(e *logEntry) error {
+			got <- e.Message
+			return nil
+		}, mongorepo.WithTailRetryInterval(50*time.Millisecond))
+	}()
+
+	if m := <-got; m != "first" {
+		t.Fatalf("expected the existing entry first, got %q", m)
+	}
+	_ = repo.InsertOne(ctx, &logEntry{Level: "info", Message: "skipped"})
+	_ = repo.InsertOne(ctx, &logEntry{Level: "error", Message: "second"})
+	select {
+	case m := <-got:
+		if m != "second" {
+			t.Fatalf("expected the new error entry, got %q", m)
+		}
+	case <-tctx.Done():
+		t.Fatal("timed out waiting for the tailed entry")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestEncryption_StoresCiphertext(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("secrets")
+	enc := encryption.NewAESGCM(encryption.StaticKeys("k1", map[string][]byte{"k1": make([]byte, 32)}))
+	repo := mongorepo.New[secretDoc](coll, mongorepo.WithEncryption(enc))
+
+	doc := &secretDoc{Name: "Ada", SSN: "123-45-6789"}
+	if err := repo.InsertOne(ctx, doc); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if doc.SSN != "123-45-6789" {
+		t.Fatalf("expected the caller's document to keep its plaintext, got %q", doc.SSN)
+	}
+
+	var raw bson.M
+	if err := coll.FindOne(ctx, bson.M{"_id": doc.ID}).Decode(&raw); err != nil {
+		t.Fatalf("FindOne failed: %v", err)
+	}
+	if ssn, _ := raw["ssn"].(primitive.Binary); ssn.Subtype != encryption.Subtype || raw["name"] != "Ada" {
+		t.Fatalf("expected only ssn to be stored encrypted, got %v", raw)
+	}
+
+	got, err := repo.FindByID(ctx, doc.ID)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if got.SSN != "123-45-6789" {
+		t.Fatalf("expected the decrypted value, got %q", got.SSN)
+	}
+
+	// Values written before the field was marked are read as they are.
+	if _, err := coll.InsertOne(ctx, bson.M{"name": "Legacy", "ssn": "000-00-0000"}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	legacy, err := repo.FindOne(ctx, mongospec.Eq("name", "Legacy"))
+	if err != nil {
+		t.Fatalf("FindOne failed: %v", err)
+	}
+	if legacy.SSN != "000-00-0000" {
+		t.Fatalf("expected the plaintext value, got %q", legacy.SSN)
+	}
+}
+
+func TestEncryption_EncryptsEveryValue(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("secrets_prefixed")
+	enc := encryption.NewAESGCM(encryption.StaticKeys("k1", map[string][]byte{"k1": make([]byte, 32)}))
+	repo := mongorepo.New[secretDoc](coll, mongorepo.WithEncryption(enc))
+
+	// A value that looks like ciphertext of some other format is still encrypted.
+	doc := &secretDoc{Name: "Ada", SSN: "enc:123-45-6789"}
+	if err := repo.InsertOne(ctx, doc); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	var raw bson.M
+	if err := coll.FindOne(ctx, bson.M{"_id": doc.ID}).Decode(&raw); err != nil {
+		t.Fatalf("FindOne failed: %v", err)
+	}
+	if ssn, _ := raw["ssn"].(primitive.Binary); ssn.Subtype != encryption.Subtype {
+		t.Fatalf("expected ssn to be stored encrypted, got %v", raw["ssn"])
+	}
+	got, err := repo.FindByID(ctx, doc.ID)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if got.SSN != "enc:123-45-6789" {
+		t.Fatalf("expected the original value, got %q", got.SSN)
+	}
+
+	// Ciphertext is bound to its document, so a copy does not decrypt.
+	other := &secretDoc{Name: "Bob", SSN: "987-65-4321"}
+	if err := repo.InsertOne(ctx, other); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if _, err := coll.UpdateOne(ctx, bson.M{"_id": other.ID}, bson.M{"$set": bson.M{"ssn": raw["ssn"]}}); err != nil {
+		t.Fatalf("UpdateOne failed: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, other.ID); !errors.Is(err, encryption.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for copied ciphertext, got %v", err)
+	}
+}
+
+func TestEncryption_UpdatesStoreCiphertext(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("secrets_updates")
+	enc := encryption.NewAESGCM(encryption.StaticKeys("k1", map[string][]byte{"k1": make([]byte, 32)}))
+	repo := mongorepo.New[secretDoc](coll, mongorepo.WithEncryption(enc))
+
+	doc := &secretDoc{Name: "Ada", SSN: "123-45-6789"}
+	if err := repo.InsertOne(ctx, doc); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	storedSSN := func() primitive.Binary {
+		t.Helper()
+		var raw bson.M
+		if err := coll.FindOne(ctx, bson.M{"_id": doc.ID}).Decode(&raw); err != nil {
+			t.Fatalf("FindOne failed: %v", err)
+		}
+		ssn, _ := raw["ssn"].(primitive.Binary)
+		return ssn
+	}
+
+	filter := mongospec.Eq("_id", doc.ID)
+	if _, _, err := repo.UpdateOne(ctx, filter, mongospec.Set("ssn", "987-65-4321")); err != nil {
+		t.Fatalf("UpdateOne failed: %v", err)
+	}
+	if ssn := storedSSN(); ssn.Subtype != encryption.Subtype || bytes.Contains(ssn.Data, []byte("987-65-4321")) {
+		t.Fatalf("expected UpdateOne to store ciphertext, got %v", ssn)
+	}
+	got, err := repo.UpdateAndFetch(ctx, filter, mongospec.Set("ssn", "111-22-3333"))
+	if err != nil {
+		t.Fatalf("UpdateAndFetch failed: %v", err)
+	}
+	if got.SSN != "111-22-3333" || storedSSN().Subtype != encryption.Subtype {
+		t.Fatalf("expected the decrypted value and stored ciphertext, got %q / %v", got.SSN, storedSSN())
+	}
+
+	if _, _, err := repo.UpdateOne(ctx, filter, bson.M{"$push": bson.M{"ssn": "x"}}); !errors.Is(err, mongorepo.ErrEncryptedUpdate) {
+		t.Fatalf("expected ErrEncryptedUpdate, got %v", err)
+	}
+}
+
+func TestEncryption_FindAsDecryptsProjection(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("secrets_projection")
+	enc := encryption.NewAESGCM(encryption.StaticKeys("k1", map[string][]byte{"k1": make([]byte, 32)}))
+	repo := mongorepo.New[secretDoc](coll, mongorepo.WithEncryption(enc))
+
+	if err := repo.InsertOne(ctx, &secretDoc{Name: "Ada", SSN: "123-45-6789"}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	// The projection type does not mark ssn; it is decrypted by its path in secretDoc.
+	type ssnOnly struct {
+		SSN string `bson:"ssn"`
+	}
+	got, err := mongorepo.FindAs[secretDoc, ssnOnly](ctx, repo, nil, nil)
+	if err != nil {
+		t.Fatalf("FindAs failed: %v", err)
+	}
+	if len(got) != 1 || got[0].SSN != "123-45-6789" {
+		t.Fatalf("expected the decrypted value, got %+v", got)
+	}
+}
+
+func TestBulkWrite_TypedOpsWithArrayFiltersAndHint(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("bulk_typed")
+	if _, err := coll.InsertOne(ctx, bson.M{"_id": 1, "items": bson.A{
+		bson.M{"sku": "A", "status": "new"},
+		bson.M{"sku": "B", "status": "new"},
+	}}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	update, err := repository.UpdateOpSpec(
+		mongospec.Eq("_id", 1),
+		mongospec.Set("items.$[item].status", "shipped"),
+		repository.WithArrayFilters(bson.M{"item.sku": "B"}),
+		repository.WithHint("_id_"),
+	)
+	if err != nil {
+		t.Fatalf("UpdateOpSpec failed: %v", err)
+	}
+
+	repo := mongorepo.New[bson.M](coll)
+	res, err := repo.BulkWrite(ctx, []repository.BulkOp{update})
+	if err != nil {
+		t.Fatalf("BulkWrite failed: %v", err)
+	}
+	if res.ModifiedCount != 1 {
+		t.Fatalf("expected 1 modified, got %+v", res)
+	}
+
+	var doc struct {
+		Items []struct {
+			SKU    string `bson:"sku"`
+			Status string `bson:"status"`
+		} `bson:"items"`
+	}
+	if err := coll.FindOne(ctx, bson.M{"_id": 1}).Decode(&doc); err != nil {
+		t.Fatalf("FindOne failed: %v", err)
+	}
+	if doc.Items[0].Status != "new" || doc.Items[1].Status != "shipped" {
+		t.Fatalf("expected only item B to be shipped, got %+v", doc.Items)
+	}
+}
+
+func TestDeleteByIDs_AndChunkedDeleteMany(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("chunked_deletes")
+	repo := mongorepo.New[Order](coll)
+
+	orders := make([]*Order, 0, 25)
+	for i := 0; i < 25; i++ {
+		orders = append(orders, &Order{TenantID: "t1", Total: i})
+	}
+	ids, err := repo.InsertMany(ctx, orders)
+	if err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+
+	deleted, err := repo.DeleteByIDs(ctx, ids[:3])
+	if err != nil || deleted != 3 {
+		t.Fatalf("DeleteByIDs: deleted=%d err=%v", deleted, err)
+	}
+
+	var batches []int64
+	deleted, err = repo.ChunkedDeleteMany(ctx, mongospec.Gte("total", 10), 4,
+		mongorepo.WithChunkPause(time.Millisecond),
+		mongorepo.WithChunkProgress(func(n int64) { batches = append(batches, n) }),
+	)
+	if err != nil {
+		t.Fatalf("ChunkedDeleteMany failed: %v", err)
+	}
+	if deleted != 15 {
+		t.Fatalf("expected 15 deleted, got %d", deleted)
+	}
+	if len(batches) != 4 || batches[3] != 15 {
+		t.Fatalf("expected 4 batches ending at 15, got %v", batches)
+	}
+
+	remaining, err := repo.Count(ctx, nil)
+	if err != nil || remaining != 7 {
+		t.Fatalf("expected 7 remaining, got %d (%v)", remaining, err)
+	}
+}
+
+func TestChunkedUpdateMany_VisitsEachRangeOnce(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("chunked_updates")
+	repo := mongorepo.New[Order](coll)
+
+	orders := make([]*Order, 0, 10)
+	for i := 0; i < 10; i++ {
+		orders = append(orders, &Order{TenantID: "t1", Total: i})
+	}
+	if _, err := repo.InsertMany(ctx, orders); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+
+	var progress [][2]int64
+	matched, modified, err := repo.ChunkedUpdateMany(ctx,
+		mongospec.Lt("total", 100), mongospec.Inc("total", 100), 3,
+		func(m, n int64) { progress = append(progress, [2]int64{m, n}) },
+		mongorepo.WithChunkPause(0),
+	)
+	if err != nil {
+		t.Fatalf("ChunkedUpdateMany failed: %v", err)
+	}
+	if matched != 10 || modified != 10 {
+		t.Fatalf("expected 10 matched/modified, got %d/%d", matched, modified)
+	}
+	if len(progress) != 4 || progress[3] != [2]int64{10, 10} {
+		t.Fatalf("unexpected progress: %v", progress)
+	}
+
+	n, err := repo.Count(ctx, mongospec.Gte("total", 100))
+	if err != nil || n != 10 {
+		t.Fatalf("expected every order incremented exactly once, got %d (%v)", n, err)
+	}
+}
+
+func TestSuggestIndexes_FromObservedFilters(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("index_advisor")
+	repo := mongorepo.New[Order](coll, mongorepo.WithIndexAdvisor())
+
+	if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: 10}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	// Two shapes: tenant + paid sorted by total with a range on created_at, and tenant alone.
+	byTenant := mongospec.And(mongospec.Eq("tenant_id", "t1"), mongospec.Eq("paid", false), mongospec.Gte("created_at", time.Time{}))
+	for i := 0; i < 3; i++ {
+		if _, err := repo.Find(ctx, byTenant, repository.WithSort(bson.D{{Key: "total", Value: -1}})); err != nil {
+			t.Fatalf("Find failed: %v", err)
+		}
+	}
+	if _, err := repo.Count(ctx, mongospec.Eq("tenant_id", "t1")); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	// Lookups by _id never need a suggestion.
+	if _, err := repo.FindOne(ctx, mongospec.Eq("_id", primitive.NewObjectID())); err != nil && !errors.Is(err, mongorepo.ErrNotFound) {
+		t.Fatalf("FindOne failed: %v", err)
+	}
+
+	got, err := repo.SuggestIndexes(ctx)
+	if err != nil {
+		t.Fatalf("SuggestIndexes failed: %v", err)
+	}
+	want := bson.D{{Key: "paid", Value: 1}, {Key: "tenant_id", Value: 1}, {Key: "total", Value: -1}, {Key: "created_at", Value: 1}}
+	if len(got) != 2 || !reflect.DeepEqual(got[0].Keys, want) || got[0].Queries != 3 {
+		t.Fatalf("unexpected suggestions: %+v", got)
+	}
+
+	// Once the tenant index exists, only the compound suggestion remains.
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "tenant_id", Value: 1}}}); err != nil {
+		t.Fatalf("create index: %v", err)
+	}
+	got, err = repo.SuggestIndexes(ctx)
+	if err != nil {
+		t.Fatalf("SuggestIndexes failed: %v", err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Keys, want) {
+		t.Fatalf("expected only the compound suggestion, got %+v", got)
+	}
+}
+
+func TestWatch_TypedEventsAndResume(t *testing.T) {
+	client, cleanup := setupMongoReplicaSet(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	repo := mongorepo.New[Order](db.Collection("orders_watch"))
+	tokens := mongorepo.NewResumeTokenStore(db.Collection("resume_tokens"))
+
+	next := func(events <-chan mongorepo.ChangeEvent[Order]) mongorepo.ChangeEvent[Order] {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a change event")
+			return mongorepo.ChangeEvent[Order]{}
+		}
+	}
+
+	watchCtx, stop := context.WithCancel(ctx)
+	events, err := repo.Watch(watchCtx,
+		mongorepo.WithWatchFilter(mongospec.Eq("tenant_id", "t1")),
+		mongorepo.WithResumeTokenStore(tokens, "orders"),
+	)
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	order := &Order{TenantID: "t1", Total: 10}
+	if err := repo.InsertOne(ctx, order); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if err := repo.InsertOne(ctx, &Order{TenantID: "t2", Total: 20}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if _, _, err := repo.UpdateOne(ctx, mongospec.Eq("_id", order.ID), mongospec.Set("paid", true)); err != nil {
+		t.Fatalf("UpdateOne failed: %v", err)
+	}
+
+	ev := next(events)
+	if ev.Operation != mongorepo.OpTypeInsert || ev.Document == nil || ev.Document.Total != 10 {
+		t.Fatalf("expected the t1 insert, got %+v", ev)
+	}
+	// Updates are matched against the looked-up document, so the delete waits
+	// until the update was delivered.
+	ev = next(events)
+	if ev.Operation != mongorepo.OpTypeUpdate || ev.UpdatedFields["paid"] != true {
+		t.Fatalf("expected the t1 update, got %+v", ev)
+	}
+
+	if _, err := repo.DeleteOne(ctx, mongospec.Eq("_id", order.ID)); err != nil {
+		t.Fatalf("DeleteOne failed: %v", err)
+	}
+	ev = next(events)
+	if ev.Operation != mongorepo.OpTypeDelete || ev.ID != order.ID || ev.Document != nil {
+		t.Fatalf("expected the t1 delete, got %+v", ev)
+	}
+
+	stop()
+	for range events {
+	}
+
+	// The update's token was saved when the delete was received, so a new
+	// watcher resumes with the delete and then sees later changes.
+	if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: 30}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	watchCtx, stop = context.WithCancel(ctx)
+	defer stop()
+	events, err = repo.Watch(watchCtx,
+		mongorepo.WithWatchFilter(mongospec.Eq("tenant_id", "t1")),
+		mongorepo.WithResumeTokenStore(tokens, "orders"),
+	)
+	if err != nil {
+		t.Fatalf("Watch after restart failed: %v", err)
+	}
+	if ev := next(events); ev.Operation != mongorepo.OpTypeDelete {
+		t.Fatalf("expected to resume with the delete, got %+v", ev)
+	}
+	if ev := next(events); ev.Operation != mongorepo.OpTypeInsert || ev.Document.Total != 30 {
+		t.Fatalf("expected the insert made while stopped, got %+v", ev)
+	}
+}
+
+func TestAggregate_CurrencyTotals(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("payments")
+	dec := func(s string) primitive.Decimal128 {
+		d, err := primitive.ParseDecimal128(s)
+		if err != nil {
+			t.Fatalf("ParseDecimal128(%q): %v", s, err)
+		}
+		return d
+	}
+	if _, err := coll.InsertMany(ctx, []any{
+		bson.M{"amount": dec("10.10"), "currency": "EUR"},
+		bson.M{"amount": dec("0.20"), "currency": "EUR"},
+		bson.M{"amount": dec("5.00"), "currency": "USD"},
+	}); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	repo := mongorepo.New[mongospec.CurrencyTotal](coll)
+
+	totals, err := repo.Aggregate(ctx, mongospec.NewPipeline().SumByCurrency("amount", "currency"))
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(totals) != 2 || totals[0].Currency != "EUR" || !decimalEquals(totals[0].Total, 10.3) || totals[0].Count != 2 {
+		t.Fatalf("unexpected per-currency totals: %+v", totals)
+	}
+
+	provider := mongospec.RateProviderFunc(func(context.Context, string) (mongospec.Rates, error) {
+		return mongospec.Rates{"EUR": dec("2")}, nil
+	})
+	rates, err := mongospec.RatesFor(ctx, provider, "USD")
+	if err != nil {
+		t.Fatalf("RatesFor failed: %v", err)
+	}
+	totals, err = repo.Aggregate(ctx, mongospec.NewPipeline().SumInCurrency("amount", "currency", "USD", rates))
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(totals) != 1 || totals[0].Currency != "USD" || !decimalEquals(totals[0].Total, 25.6) || totals[0].Count != 3 {
+		t.Fatalf("unexpected converted total: %+v", totals)
+	}
+
+	// A currency without a rate fails the aggregation instead of being skipped.
+	if _, err := repo.Aggregate(ctx, mongospec.NewPipeline().SumInCurrency("amount", "currency", "GBP", mongospec.Rates{"EUR": dec("1")})); err == nil {
+		t.Fatal("expected an error for a currency without a rate")
+	}
+}
+
+// decimalEquals compares a Decimal128 to a float, ignoring its scale.
+func decimalEquals(d primitive.Decimal128, want float64) bool {
+	got, err := strconv.ParseFloat(d.String(), 64)
+	return err == nil && got == want
+}
+
+func TestByID_AcceptsObjectIDAndHex(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.New[Order](client.Database("testdb").Collection("orders_by_id"))
+
+	order := &Order{TenantID: "t1", Total: 10}
+	if err := repo.InsertOne(ctx, order); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	got, err := repo.FindByID(ctx, order.ID.Hex())
+	if err != nil || got.ID != order.ID {
+		t.Fatalf("FindByID(hex) = %+v, %v", got, err)
+	}
+	if _, modified, err := repo.UpdateByID(ctx, order.ID, mongospec.Set("paid", true)); err != nil || modified != 1 {
+		t.Fatalf("UpdateByID: modified=%d err=%v", modified, err)
+	}
+	if ok, err := repo.ExistsByID(ctx, order.ID.Hex()); err != nil || !ok {
+		t.Fatalf("ExistsByID = %v, %v; want true", ok, err)
+	}
+	if _, err := repo.FindByID(ctx, "nope"); !errors.Is(err, mongorepo.ErrInvalidID) {
+		t.Fatalf("FindByID(invalid) err = %v, want ErrInvalidID", err)
+	}
+
+	if n, err := repo.DeleteByID(ctx, order.ID.Hex()); err != nil || n != 1 {
+		t.Fatalf("DeleteByID = %d, %v; want 1", n, err)
+	}
+	if ok, _ := repo.ExistsByID(ctx, order.ID); ok {
+		t.Fatal("ExistsByID after delete = true")
+	}
+	if _, err := repo.FindByID(ctx, order.ID); !errors.Is(err, mongorepo.ErrNotFound) {
+		t.Fatalf("FindByID after delete err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFind_WithProjection(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.New[Order](client.Database("testdb").Collection("orders_projection"))
+
+	order := &Order{TenantID: "t1", Paid: true, Total: 42}
+	if err := repo.InsertOne(ctx, order); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	got, err := repo.FindOne(ctx, mongospec.Eq("_id", order.ID), repository.WithFields("total"))
+	if err != nil {
+		t.Fatalf("FindOne failed: %v", err)
+	}
+	if got.ID != order.ID || got.Total != 42 || got.TenantID != "" || got.Paid {
+		t.Fatalf("unexpected included fields: %+v", got)
+	}
+
+	all, err := repo.Find(ctx, nil, repository.WithProjection(mongospec.Exclude("tenant_id").Exclude("_id")))
+	if err != nil || len(all) != 1 {
+		t.Fatalf("Find failed: %v, %v", all, err)
+	}
+	if !all[0].ID.IsZero() || all[0].TenantID != "" || all[0].Total != 42 {
+		t.Fatalf("unexpected excluded fields: %+v", all[0])
+	}
+}
+
+type article struct {
+	document.Base `bson:",inline"`
+	Title         string  `bson:"title"`
+	Body          string  `bson:"body"`
+	Score         float64 `bson:"score,omitempty"`
+}
+
+func (article) Indexes() []document.Index {
+	return []document.Index{{Keys: document.TextIndex("title", "body")}}
+}
+
+func TestFind_TextSearchSortedByScore(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo, err := mongorepo.NewWithIndexes[article](ctx, client.Database("testdb").Collection("articles_text"))
+	if err != nil {
+		t.Fatalf("NewWithIndexes failed: %v", err)
+	}
+	for _, a := range []*article{
+		{Title: "Brewing coffee", Body: "Coffee, coffee, and more coffee."},
+		{Title: "Tea time", Body: "A little coffee on the side."},
+		{Title: "Gardening", Body: "Nothing to drink here."},
+	} {
+		if err := repo.InsertOne(ctx, a); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	got, err := repo.Find(ctx, mongospec.Text("coffee"),
+		repository.WithTextScoreSort(),
+		repository.WithProjection(mongospec.IncludeTextScore("score")),
+	)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Title != "Brewing coffee" || got[1].Title != "Tea time" {
+		t.Fatalf("unexpected results: %+v", got)
+	}
+	if got[0].Score <= got[1].Score || got[1].Score <= 0 {
+		t.Fatalf("scores not descending: %v, %v", got[0].Score, got[1].Score)
+	}
+}
+
+type place struct {
+	document.Base `bson:",inline"`
+	Name          string          `bson:"name"`
+	Location      mongospec.Point `bson:"location"`
+}
+
+func (place) Indexes() []document.Index {
+	return []document.Index{{Keys: document.GeoIndex("location")}}
+}
+
+func TestFind_Geospatial(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo, err := mongorepo.NewWithIndexes[place](ctx, client.Database("testdb").Collection("places_geo"))
+	if err != nil {
+		t.Fatalf("NewWithIndexes failed: %v", err)
+	}
+	for _, p := range []*place{
+		{Name: "near", Location: mongospec.NewPoint(-9.1400, 38.7230)},
+		{Name: "far", Location: mongospec.NewPoint(-8.6110, 41.1496)},
+	} {
+		if err := repo.InsertOne(ctx, p); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+	center := mongospec.NewPoint(-9.1393, 38.7223)
+
+	near, err := repo.Find(ctx, mongospec.Near("location", center, mongospec.MaxDistance(1000)))
+	if err != nil || len(near) != 1 || near[0].Name != "near" {
+		t.Fatalf("Near: %+v, %v", near, err)
+	}
+	within, err := repo.Count(ctx, mongospec.GeoWithin("location", mongospec.Circle{Center: center, Radius: 400_000}))
+	if err != nil || within != 2 {
+		t.Fatalf("GeoWithin circle: %d, %v", within, err)
+	}
+	boxed, err := repo.Find(ctx, mongospec.GeoWithin("location",
+		mongospec.NewBox(mongospec.NewPoint(-9.2, 38.7), mongospec.NewPoint(-9.1, 38.8))))
+	if err != nil || len(boxed) != 1 || boxed[0].Name != "near" {
+		t.Fatalf("GeoWithin box: %+v, %v", boxed, err)
+	}
+
+	rows, err := repo.AggregateRaw(ctx, mongospec.NewPipeline().GeoNear(mongospec.GeoNearOptions{
+		Near:               center,
+		DistanceField:      "km",
+		DistanceMultiplier: 0.001,
+	}))
+	if err != nil || len(rows) != 2 || rows[0]["name"] != "near" || rows[1]["km"].(float64) < 250 {
+		t.Fatalf("GeoNear: %v, %v", rows, err)
+	}
+}
+
+func TestAggregate_RedactByLabels(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("reports_redact")
+	_, err := coll.InsertMany(ctx, []any{
+		bson.M{"title": "Q3", "acl": bson.A{"staff"}, "financials": bson.M{"acl": bson.A{"finance"}, "revenue": 10}},
+		bson.M{"title": "Board minutes", "acl": bson.A{"board"}},
+		bson.M{"title": "Handbook"},
+	})
+	if err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+	repo := mongorepo.New[Order](coll)
+
+	rows, err := repo.AggregateRaw(ctx, mongospec.NewPipeline().
+		Redact(mongospec.RedactByLabels("acl", "staff")).
+		SortBy("title", 1))
+	if err != nil {
+		t.Fatalf("AggregateRaw failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0]["title"] != "Handbook" || rows[1]["title"] != "Q3" {
+		t.Fatalf("unexpected documents: %v", rows)
+	}
+	if _, ok := rows[1]["financials"]; ok {
+		t.Fatalf("financials not pruned: %v", rows[1])
+	}
+}
+
+type member struct {
+	document.Base `bson:",inline"`
+	Email         string `bson:"email" schema:"required,minLength=3"`
+	Role          string `bson:"role" schema:"enum=admin|member"`
+}
+
+func TestEnsureValidator_RejectsInvalidDocuments(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("members_validated")
+	repo := mongorepo.New[member](coll)
+
+	// The second call updates the existing collection's validator.
+	for i := 0; i < 2; i++ {
+		if err := repo.EnsureValidator(ctx); err != nil {
+			t.Fatalf("EnsureValidator #%d failed: %v", i+1, err)
+		}
+	}
+
+	if err := repo.InsertOne(ctx, &member{Email: "ada@example.com", Role: "admin"}); err != nil {
+		t.Fatalf("valid insert failed: %v", err)
+	}
+	err := repo.InsertOne(ctx, &member{Email: "ada@example.com", Role: "owner"})
+	var we mongo.WriteException
+	if !errors.As(err, &we) || len(we.WriteErrors) == 0 || we.WriteErrors[0].Code != 121 {
+		t.Fatalf("expected document validation failure, got %v", err)
+	}
+	if _, err := coll.InsertOne(ctx, bson.M{"role": "member"}); err == nil {
+		t.Fatal("expected insert without email to fail")
+	}
+}
+
+func TestHooks_UpdateAndDelete(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	var audit []string
+	repo := mongorepo.New[Order](client.Database("testdb").Collection("orders_hooks"),
+		mongorepo.WithBeforeUpdate(func(_ context.Context, e *document.UpdateEvent) error {
+			e.Update = mongospec.Combine(e.Update.(mongospec.Update), mongospec.Set("paid", true))
+			return nil
+		}),
+		mongorepo.WithAfterUpdate(func(_ context.Context, e *document.UpdateEvent) error {
+			audit = append(audit, fmt.Sprintf("%s:%d", e.Op, e.Modified))
+			return nil
+		}),
+		mongorepo.WithAfterDelete(func(_ context.Context, e *document.DeleteEvent) error {
+			audit = append(audit, fmt.Sprintf("%s:%d", e.Op, e.Deleted))
+			return nil
+		}),
+	)
+
+	order := &Order{TenantID: "t1", Total: 10}
+	if err := repo.InsertOne(ctx, order); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if _, _, err := repo.UpdateByID(ctx, order.ID, mongospec.Set("total", 20)); err != nil {
+		t.Fatalf("UpdateByID failed: %v", err)
+	}
+	got, err := repo.FindByID(ctx, order.ID)
+	if err != nil || got.Total != 20 || !got.Paid {
+		t.Fatalf("expected hook to set paid, got %+v (%v)", got, err)
+	}
+	if _, err := repo.DeleteMany(ctx, mongospec.Eq("tenant_id", "t1")); err != nil {
+		t.Fatalf("DeleteMany failed: %v", err)
+	}
+
+	want := []string{"update_one:1", "delete_many:1"}
+	if !reflect.DeepEqual(audit, want) {
+		t.Fatalf("audit = %v, want %v", audit, want)
+	}
+}
+
+func TestFromConfig_CacheAndPageSize(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	cfg, err := mongorepo.ParseRepoConfig([]byte("collection: orders_config\ncache_ttl: 1m\ndefault_page_size: 3\n"))
+	if err != nil {
+		t.Fatalf("ParseRepoConfig failed: %v", err)
+	}
+	r, err := mongorepo.FromConfig[Order](db, cfg)
+	if err != nil {
+		t.Fatalf("FromConfig failed: %v", err)
+	}
+	repo := r.(*mongorepo.MongoRepository[Order])
+
+	orders := make([]*Order, 0, 5)
+	for i := 0; i < 5; i++ {
+		orders = append(orders, &Order{TenantID: "t1", Total: i})
+	}
+	if _, err := repo.InsertMany(ctx, orders); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+
+	page, err := repo.FindPaginated(ctx, nil, 1, 0)
+	if err != nil || len(page.Items) != 3 || page.TotalPages != 2 {
+		t.Fatalf("expected 3 items on page 1 of 2, got %+v (%v)", page, err)
+	}
+
+	id := orders[0].ID
+	if _, err := repo.FindByID(ctx, id); err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	// A write by another client is not seen until the entry expires...
+	if _, err := db.Collection("orders_config").UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"total": 100}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := repo.FindByID(ctx, id); got.Total != 0 {
+		t.Fatalf("expected cached total 0, got %d", got.Total)
+	}
+	// ...but a write through the repository clears the cache.
+	if _, _, err := repo.UpdateByID(ctx, id, mongospec.Inc("total", 1)); err != nil {
+		t.Fatalf("UpdateByID failed: %v", err)
+	}
+	if got, _ := repo.FindByID(ctx, id); got.Total != 101 {
+		t.Fatalf("expected total 101 after cache clear, got %d", got.Total)
+	}
+}
+
+func TestFindPolicy_CapsLimitAndSorts(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.New[Order](client.Database("testdb").Collection("orders_policy"),
+		mongorepo.WithFindPolicy(mongorepo.FindPolicy{
+			MaxLimit:    3,
+			DefaultSort: bson.D{{Key: "total", Value: -1}},
+		}),
+	)
+	for _, total := range []int{5, 1, 4, 2, 3} {
+		if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: total}); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	got, err := repo.Find(ctx, nil)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(got) != 3 || got[0].Total != 5 || got[2].Total != 3 {
+		t.Fatalf("expected the 3 largest totals in order, got %+v", got)
+	}
+
+	got, err = repo.Find(ctx, nil, repository.WithLimit(10), repository.WithSort(bson.D{{Key: "total", Value: 1}}))
+	if err != nil || len(got) != 3 || got[0].Total != 1 {
+		t.Fatalf("expected explicit sort kept and limit capped, got %+v (%v)", got, err)
+	}
+
+	page, err := repo.FindPaginated(ctx, nil, 1, 20)
+	if err != nil || page.PerPage != 3 || page.TotalPages != 2 {
+		t.Fatalf("expected pages of 3, got %+v (%v)", page, err)
+	}
+
+	var buf strings.Builder
+	if err := repo.StreamJSON(ctx, &buf, nil, mongorepo.NDJSON, repository.WithFields("total")); err != nil {
+		t.Fatalf("StreamJSON failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], `"total":5`) {
+		t.Fatalf("expected all 5 documents by default sort, got %q", buf.String())
+	}
+}
+
+func TestSoftDelete_ExcludesDeletedEverywhere(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.NewSoftDelete[Order](client.Database("testdb").Collection("orders_soft_scope"))
+
+	active := &Order{TenantID: "t1", Total: 10}
+	deleted := &Order{TenantID: "t1", Total: 20}
+	for _, o := range []*Order{active, deleted} {
+		if err := repo.InsertOne(ctx, o); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+	if n, err := repo.SoftDelete(ctx, mongospec.Eq("_id", deleted.ID)); err != nil || n != 1 {
+		t.Fatalf("SoftDelete: n=%d err=%v", n, err)
+	}
+
+	tenant := mongospec.Eq("tenant_id", "t1")
+	if n, err := repo.Count(ctx, tenant); err != nil || n != 1 {
+		t.Fatalf("Count: n=%d err=%v, want 1", n, err)
+	}
+	if n, err := repo.CountWithDeleted(ctx, tenant); err != nil || n != 2 {
+		t.Fatalf("CountWithDeleted: n=%d err=%v, want 2", n, err)
+	}
+
+	if matched, _, err := repo.UpdateMany(ctx, tenant, mongospec.Set("paid", true)); err != nil || matched != 1 {
+		t.Fatalf("UpdateMany: matched=%d err=%v, want 1", matched, err)
+	}
+	if matched, _, err := repo.UpdateByID(ctx, deleted.ID, mongospec.Set("paid", true)); err != nil || matched != 0 {
+		t.Fatalf("UpdateByID on deleted: matched=%d err=%v, want 0", matched, err)
+	}
+
+	streamed := 0
+	if err := repo.FindEach(ctx, tenant, func(*Order) error { streamed++; return nil }); err != nil || streamed != 1 {
+		t.Fatalf("FindEach: %d documents (%v), want 1", streamed, err)
+	}
+
+	page, err := repo.FindPaginated(ctx, tenant, 1, 10)
+	if err != nil || page.Total != 1 || len(page.Items) != 1 {
+		t.Fatalf("FindPaginated: %+v (%v), want 1 item", page, err)
+	}
+
+	rows, err := repo.AggregateRaw(ctx, mongospec.NewPipeline().Match(tenant))
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("AggregateRaw: %d rows (%v), want 1", len(rows), err)
+	}
+	rows, err = repo.AggregateRawWithDeleted(ctx, mongospec.NewPipeline().Match(tenant))
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("AggregateRawWithDeleted: %d rows (%v), want 2", len(rows), err)
+	}
+	if n, err := repo.CountPipeline(ctx, mongospec.NewPipeline().Match(tenant)); err != nil || n != 1 {
+		t.Fatalf("CountPipeline: n=%d err=%v, want 1", n, err)
+	}
+
+	res, err := repo.BulkWrite(ctx, []repository.BulkOp{
+		repository.UpdateOp(mongospec.Eq("_id", deleted.ID), mongospec.Set("total", 0)),
+	})
+	if err != nil || res.MatchedCount != 0 {
+		t.Fatalf("BulkWrite on deleted: %+v (%v), want no match", res, err)
+	}
+}
+
+func TestSoftDelete_WritesSkipDeleted(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_soft_writes")
+	repo := mongorepo.NewSoftDelete[Order](coll)
+
+	deleted := &Order{TenantID: "t1", Total: 20}
+	if err := repo.InsertOne(ctx, deleted); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if _, err := repo.SoftDelete(ctx, mongospec.Eq("_id", deleted.ID)); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	byID := mongospec.Eq("_id", deleted.ID)
+
+	replacement := &Order{TenantID: "t1", Total: 99}
+	if matched, _, err := repo.ReplaceOne(ctx, byID, replacement); err != nil || matched != 0 {
+		t.Fatalf("ReplaceOne: matched=%d err=%v, want 0", matched, err)
+	}
+	if _, _, err := repo.ReplaceOneStrict(ctx, byID, replacement); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("ReplaceOneStrict: expected ErrNotFound, got %v", err)
+	}
+	if _, _, err := repo.UpdateOneStrict(ctx, byID, mongospec.Set("total", 99)); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("UpdateOneStrict: expected ErrNotFound, got %v", err)
+	}
+	noPause := mongorepo.WithChunkPause(0)
+	if matched, _, err := repo.ChunkedUpdateMany(ctx, byID, mongospec.Set("total", 99), 10, nil, noPause); err != nil || matched != 0 {
+		t.Fatalf("ChunkedUpdateMany: matched=%d err=%v, want 0", matched, err)
+	}
+	if n, err := repo.ChunkedDeleteMany(ctx, byID, 10, noPause); err != nil || n != 0 {
+		t.Fatalf("ChunkedDeleteMany: n=%d err=%v, want 0", n, err)
+	}
+	if n, err := repo.DeleteByID(ctx, deleted.ID); err != nil || n != 0 {
+		t.Fatalf("DeleteByID: n=%d err=%v, want 0", n, err)
+	}
+	if n, err := repo.DeleteByIDs(ctx, []primitive.ObjectID{deleted.ID}); err != nil || n != 0 {
+		t.Fatalf("DeleteByIDs: n=%d err=%v, want 0", n, err)
+	}
+
+	var buf strings.Builder
+	if err := repo.ExportCSV(ctx, &buf, nil, []mongorepo.ColumnSpec{{Field: "total"}}); err != nil || buf.String() != "total\n" {
+		t.Fatalf("ExportCSV: %q (%v), want only the header", buf.String(), err)
+	}
+
+	var raw bson.M
+	if err := coll.FindOne(ctx, bson.M{"_id": deleted.ID}).Decode(&raw); err != nil {
+		t.Fatalf("FindOne failed: %v", err)
+	}
+	if raw["total"] != int32(20) || raw["deleted_at"] == nil {
+		t.Fatalf("expected the deleted document untouched, got %v", raw)
+	}
+
+	// The escape hatches reach deleted documents.
+	if matched, _, err := repo.ChunkedUpdateManyWithDeleted(ctx, byID, mongospec.Set("total", 30), 10, nil, noPause); err != nil || matched != 1 {
+		t.Fatalf("ChunkedUpdateManyWithDeleted: matched=%d err=%v, want 1", matched, err)
+	}
+	buf.Reset()
+	if err := repo.ExportCSVWithDeleted(ctx, &buf, byID, []mongorepo.ColumnSpec{{Field: "total"}}); err != nil || buf.String() != "total\n30\n" {
+		t.Fatalf("ExportCSVWithDeleted: %q (%v)", buf.String(), err)
+	}
+	if n, err := repo.ChunkedDeleteManyWithDeleted(ctx, byID, 10, noPause); err != nil || n != 1 {
+		t.Fatalf("ChunkedDeleteManyWithDeleted: n=%d err=%v, want 1", n, err)
+	}
+}
+
+type archivedNote struct {
+	document.Base `bson:",inline"`
+
+	Text       string     `bson:"text"`
+	ArchivedAt *time.Time `bson:"archived_at,omitempty"`
+}
+
+func (n *archivedNote) IsDeleted() bool           { return n.ArchivedAt != nil }
+func (n *archivedNote) MarkDeleted(now time.Time) { n.ArchivedAt = &now }
+func (n *archivedNote) Restore()                  { n.ArchivedAt = nil }
+
+func TestSoftDeleteDoc_MutatesDocumentWithCustomField(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.NewSoftDelete[archivedNote](client.Database("testdb").Collection("notes_archived"),
+		mongorepo.WithSoftDeleteField("archived_at"),
+	)
+
+	note := &archivedNote{Text: "hello"}
+	if err := repo.InsertOne(ctx, note); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if err := repo.SoftDeleteDoc(ctx, note); err != nil {
+		t.Fatalf("SoftDeleteDoc failed: %v", err)
+	}
+	if !note.IsDeleted() {
+		t.Fatal("expected SoftDeleteDoc to mark the struct deleted")
+	}
+	if err := repo.SoftDeleteDoc(ctx, note); !errors.Is(err, mongorepo.ErrNotFound) {
+		t.Fatalf("second SoftDeleteDoc: expected ErrNotFound, got %v", err)
+	}
+
+	if _, err := repo.FindByID(ctx, note.ID); !errors.Is(err, mongorepo.ErrNotFound) {
+		t.Fatalf("expected deleted note to be hidden, got %v", err)
+	}
+	stored, err := repo.FindOneWithDeleted(ctx, mongospec.Eq("_id", note.ID))
+	if err != nil || stored.ArchivedAt == nil || !stored.ArchivedAt.Equal(*note.ArchivedAt) {
+		t.Fatalf("expected archived_at %v stored, got %+v (%v)", note.ArchivedAt, stored, err)
+	}
+
+	if err := repo.RestoreDoc(ctx, note); err != nil {
+		t.Fatalf("RestoreDoc failed: %v", err)
+	}
+	if note.IsDeleted() {
+		t.Fatal("expected RestoreDoc to clear the deletion time")
+	}
+	if n, err := repo.Count(ctx, nil); err != nil || n != 1 {
+		t.Fatalf("Count after restore: n=%d err=%v, want 1", n, err)
+	}
+}
+
+type revision struct {
+	document.Base      `bson:",inline"`
+	document.Versioned `bson:",inline"`
+	Body               string `bson:"body"`
+}
+
+func TestOptimisticLocking_RejectsStaleWrites(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("revisions")
+	repo := mongorepo.New[revision](coll)
+
+	r := &revision{Body: "draft"}
+	if err := repo.InsertOne(ctx, r); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if r.Version != 1 {
+		t.Fatalf("expected version 1 after insert, got %d", r.Version)
+	}
+
+	first, _ := repo.FindOne(ctx, mongospec.Eq("_id", r.ID))
+	stale, _ := repo.FindOne(ctx, mongospec.Eq("_id", r.ID))
+
+	first.Body = "edited"
+	if _, _, err := repo.ReplaceOne(ctx, mongospec.Eq("_id", r.ID), first); err != nil {
+		t.Fatalf("ReplaceOne failed: %v", err)
+	}
+	stale.Body = "overwritten"
+	if _, _, err := repo.ReplaceOne(ctx, mongospec.Eq("_id", r.ID), stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+
+	if _, _, err := repo.UpdateOne(ctx, mongospec.Eq("_id", r.ID), mongospec.Set("body", "x"), repository.WithExpectedVersion(1)); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if _, _, err := repo.UpdateOne(ctx, mongospec.Eq("_id", r.ID), mongospec.Set("body", "final"), repository.WithExpectedVersion(2)); err != nil {
+		t.Fatalf("UpdateOne failed: %v", err)
+	}
+	got, _ := repo.FindOne(ctx, mongospec.Eq("_id", r.ID))
+	if got.Body != "final" || got.Version != 3 {
+		t.Fatalf("expected final at version 3, got %+v", got)
+	}
+
+	// Documents written before versioning was added have no version field.
+	legacy := primitive.NewObjectID()
+	if _, err := coll.InsertOne(ctx, bson.M{"_id": legacy, "body": "old"}); err != nil {
+		t.Fatalf("raw insert failed: %v", err)
+	}
+	old, _ := repo.FindOne(ctx, mongospec.Eq("_id", legacy))
+	old.Body = "migrated"
+	if _, _, err := repo.ReplaceOne(ctx, mongospec.Eq("_id", legacy), old); err != nil || old.Version != 1 {
+		t.Fatalf("expected legacy replace to set version 1, got version=%d err=%v", old.Version, err)
+	}
+}
+
+func TestOptimisticLocking_FindOneAndReplaceBulkAndConflicts(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("revisions_paths")
+	repo := mongorepo.New[revision](coll)
+	byID := func(id primitive.ObjectID) *revision {
+		t.Helper()
+		got, err := repo.FindOne(ctx, mongospec.Eq("_id", id))
+		if err != nil {
+			t.Fatalf("FindOne failed: %v", err)
+		}
+		return got
+	}
+
+	r := &revision{Body: "draft"}
+	if err := repo.InsertOne(ctx, r); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	filter := mongospec.Eq("_id", r.ID)
+
+	// FindOneAndReplace checks and bumps the version.
+	first, stale := byID(r.ID), byID(r.ID)
+	first.Body = "edited"
+	if _, err := repo.FindOneAndReplace(ctx, filter, first); err != nil {
+		t.Fatalf("FindOneAndReplace failed: %v", err)
+	}
+	stale.Body = "overwritten"
+	if _, err := repo.FindOneAndReplace(ctx, filter, stale); !errors.Is(err, repository.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if stale.Version != 1 {
+		t.Fatalf("expected stale version to be restored to 1, got %d", stale.Version)
+	}
+	if got := byID(r.ID); got.Body != "edited" || got.Version != 2 {
+		t.Fatalf("expected edited at version 2, got %+v", got)
+	}
+
+	// Bulk updates increment the version; stale bulk replaces match nothing.
+	if _, err := repo.BulkWrite(ctx, []repository.BulkOp{repository.UpdateOp(filter, mongospec.Set("body", "bulk"))}); err != nil {
+		t.Fatalf("BulkWrite update failed: %v", err)
+	}
+	fresh := byID(r.ID)
+	if fresh.Version != 3 {
+		t.Fatalf("expected version 3 after bulk update, got %d", fresh.Version)
+	}
+	res, err := repo.BulkWrite(ctx, []repository.BulkOp{repository.ReplaceOp(filter, stale)})
+	if err != nil || res.MatchedCount != 0 {
+		t.Fatalf("expected stale bulk replace to match nothing, got %+v, %v", res, err)
+	}
+	fresh.Body = "replaced"
+	res, err = repo.BulkWrite(ctx, []repository.BulkOp{repository.ReplaceOp(filter, *fresh)})
+	if err != nil || res.MatchedCount != 1 {
+		t.Fatalf("expected bulk replace to match, got %+v, %v", res, err)
+	}
+	if got := byID(r.ID); got.Body != "replaced" || got.Version != 4 {
+		t.Fatalf("expected replaced at version 4, got %+v", got)
+	}
+
+	// Conflict resolution bumps the stored version too.
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "body", Value: 1}},
+		Options: mopt.Index().SetUnique(true),
+	}); err != nil {
+		t.Fatalf("create index failed: %v", err)
+	}
+	if _, err := repo.InsertManyOnConflict(ctx, []*revision{{Body: "replaced"}}, mongorepo.OnConflictMerge("body")); err != nil {
+		t.Fatalf("InsertManyOnConflict merge failed: %v", err)
+	}
+	if got := byID(r.ID); got.Version != 5 {
+		t.Fatalf("expected version 5 after merge, got %d", got.Version)
+	}
+	if _, err := repo.InsertManyOnConflict(ctx, []*revision{{Body: "replaced"}}, mongorepo.OnConflictReplace); err != nil {
+		t.Fatalf("InsertManyOnConflict replace failed: %v", err)
+	}
+	if got := byID(r.ID); got.ID != r.ID || got.Version != 6 {
+		t.Fatalf("expected _id kept at version 6 after replace, got %+v", got)
+	}
+}
+
+func TestConcerns_MajorityWritesAndReadOverrides(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.New[Order](client.Database("testdb").Collection("orders_concerns"),
+		mongorepo.WithWriteConcern(writeconcern.Majority()),
+		mongorepo.WithReadConcern(readconcern.Majority()),
+		mongorepo.WithReadPreference(readpref.PrimaryPreferred()),
+	)
+
+	if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: 10}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	orders, err := repo.Find(ctx, mongospec.Eq("tenant_id", "t1"),
+		repository.WithReadPreference("nearest"),
+		repository.WithReadConcern("local"),
+	)
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("Find with overrides: orders=%d err=%v", len(orders), err)
+	}
+}
+
+func TestFindEachAndFindIter_StreamInBatches(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.New[Order](client.Database("testdb").Collection("orders_stream"))
+	for i := 0; i < 25; i++ {
+		if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: i}); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	var totals []int
+	err := repo.FindEach(ctx, mongospec.Eq("tenant_id", "t1"), func(o *Order) error {
+		if !o.AfterLoadCalled {
+			t.Fatal("expected AfterLoad to run before the callback")
+		}
+		totals = append(totals, o.Total)
+		return nil
+	}, repository.WithBatchSize(10), repository.WithSort(bson.D{{Key: "total", Value: 1}}))
+	if err != nil || len(totals) != 25 || totals[24] != 24 {
+		t.Fatalf("FindEach: %v (%v), want 25 totals in order", totals, err)
+	}
+
+	stop := errors.New("stop")
+	seen := 0
+	err = repo.FindEach(ctx, nil, func(*Order) error {
+		seen++
+		if seen == 3 {
+			return stop
+		}
+		return nil
+	}, repository.WithBatchSize(2))
+	if !errors.Is(err, stop) || seen != 3 {
+		t.Fatalf("expected FindEach to stop at the callback error, got seen=%d err=%v", seen, err)
+	}
+
+	it, err := repo.FindIter(ctx, mongospec.Gte("total", 20), repository.WithBatchSize(2))
+	if err != nil {
+		t.Fatalf("FindIter failed: %v", err)
+	}
+	defer it.Close(ctx)
+	n := 0
+	for it.Next(ctx) {
+		var o Order
+		if err := it.Decode(&o); err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		if o.Total < 20 || !o.AfterLoadCalled {
+			t.Fatalf("unexpected document %+v", o)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil || n != 5 {
+		t.Fatalf("FindIter: %d documents (%v), want 5", n, err)
+	}
+}
+
+func TestCountPipeline(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.New[Order](client.Database("testdb").Collection("orders_count_pipeline"))
+	for i, tenant := range []string{"t1", "t1", "t2"} {
+		if err := repo.InsertOne(ctx, &Order{TenantID: tenant, Total: i}); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	p := mongospec.NewPipeline().Match(mongospec.Eq("tenant_id", "t1"))
+	if n, err := repo.CountPipeline(ctx, p); err != nil || n != 2 {
+		t.Fatalf("CountPipeline: n=%d err=%v, want 2", n, err)
+	}
+	if stages, err := p.ToPipeline(); err != nil || len(stages) != 1 {
+		t.Fatalf("expected CountPipeline to leave the pipeline unchanged, got %v (%v)", stages, err)
+	}
+
+	groups := mongospec.NewPipeline().GroupBy("$tenant_id", bson.M{"n": mongospec.Sum(1)})
+	if n, err := repo.CountPipeline(ctx, groups); err != nil || n != 2 {
+		t.Fatalf("CountPipeline over groups: n=%d err=%v, want 2", n, err)
+	}
+
+	none := mongospec.NewPipeline().Match(mongospec.Eq("tenant_id", "t3"))
+	if n, err := repo.CountPipeline(ctx, none); err != nil || n != 0 {
+		t.Fatalf("CountPipeline with no results: n=%d err=%v, want 0", n, err)
+	}
+}
+
+func TestCount_OptionsAndEstimatedCount(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_counts")
+	repo := mongorepo.New[Order](coll)
+	for i := 0; i < 5; i++ {
+		if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: i}); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}},
+		Options: mopt.Index().SetName("tenant_id_1"),
+	}); err != nil {
+		t.Fatalf("CreateOne failed: %v", err)
+	}
+
+	tenant := mongospec.Eq("tenant_id", "t1")
+	if n, err := repo.Count(ctx, tenant, repository.WithCountHint("tenant_id_1"), repository.WithCountMaxTime(5*time.Second)); err != nil || n != 5 {
+		t.Fatalf("Count with hint: n=%d err=%v, want 5", n, err)
+	}
+	if n, err := repo.Count(ctx, tenant, repository.WithCountLimit(3)); err != nil || n != 3 {
+		t.Fatalf("Count with limit: n=%d err=%v, want 3", n, err)
+	}
+	if _, err := repo.Count(ctx, tenant, repository.WithCountHint("missing_1")); err == nil {
+		t.Fatal("expected Count with an unknown hint to fail")
+	}
+	if n, err := repo.EstimatedCount(ctx); err != nil || n != 5 {
+		t.Fatalf("EstimatedCount: n=%d err=%v, want 5", n, err)
+	}
+}
+
+type requestIDKey struct{}
+
+func TestComments_RecordedInProfiler(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb_comments")
+	if err := db.RunCommand(ctx, bson.D{{Key: "profile", Value: 2}}).Err(); err != nil {
+		t.Fatalf("enable profiler: %v", err)
+	}
+	repo := mongorepo.New[Order](db.Collection("orders"),
+		mongorepo.WithCommentFromContext(func(ctx context.Context) string {
+			id, _ := ctx.Value(requestIDKey{}).(string)
+			if id == "" {
+				return ""
+			}
+			return "request_id=" + id
+		}),
+	)
+	reqCtx := context.WithValue(ctx, requestIDKey{}, "r42")
+
+	if err := repo.InsertOne(ctx, &Order{TenantID: "t1"}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	if _, err := repo.Find(reqCtx, mongospec.Eq("tenant_id", "t1"), repository.WithComment("orders page")); err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if _, _, err := repo.UpdateMany(ctx, mongospec.Eq("tenant_id", "t1"), mongospec.Set("paid", true), repository.WithUpdateComment("mark paid")); err != nil {
+		t.Fatalf("UpdateMany failed: %v", err)
+	}
+	if _, err := repo.AggregateRaw(mongorepo.ContextWithComment(reqCtx, "report"), mongospec.NewPipeline().Match(mongospec.Eq("paid", true))); err != nil {
+		t.Fatalf("AggregateRaw failed: %v", err)
+	}
+
+	// Profiled updates record the statement rather than the command, so only
+	// reads are checked here.
+	for _, want := range []string{"orders page request_id=r42", "report request_id=r42"} {
+		n, err := db.Collection("system.profile").CountDocuments(ctx, bson.M{"command.comment": want})
+		if err != nil || n == 0 {
+			t.Errorf("expected a profiled operation with comment %q, got n=%d err=%v", want, n, err)
+		}
+	}
+}
+
+type tenantTotal struct {
+	TenantID string `bson:"_id"`
+	Total    int    `bson:"total"`
+	Count    int    `bson:"count"`
+}
+
+func TestAggregateAs_DecodesIntoResultType(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.NewSoftDelete[Order](client.Database("testdb").Collection("orders_aggregate_as"))
+	for _, o := range []*Order{{TenantID: "t1", Total: 10}, {TenantID: "t1", Total: 5}, {TenantID: "t2", Total: 7}, {TenantID: "t2", Total: 100}} {
+		if err := repo.InsertOne(ctx, o); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+	if _, err := repo.SoftDelete(ctx, mongospec.Eq("total", 100)); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+
+	pipeline := mongospec.NewPipeline().
+		GroupBy("$tenant_id", bson.M{"total": mongospec.Sum("$total"), "count": mongospec.Sum(1)}).
+		SortBy("_id", 1)
+	got, err := mongorepo.AggregateAs[tenantTotal](ctx, repo, pipeline)
+	if err != nil {
+		t.Fatalf("AggregateAs failed: %v", err)
+	}
+	want := []tenantTotal{{TenantID: "t1", Total: 15, Count: 2}, {TenantID: "t2", Total: 7, Count: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("AggregateAs mismatch.\n got: %+v\nwant: %+v", got, want)
+	}
+
+	all, err := mongorepo.AggregateAs[tenantTotal](ctx, repo.MongoRepository, pipeline)
+	if err != nil || len(all) != 2 || all[1].Total != 107 {
+		t.Fatalf("AggregateAs on the underlying repository: %+v (%v), want deleted documents included", all, err)
+	}
+}
+
+func TestAggregatePaginated_ReturnsPageAndTotal(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	repo := mongorepo.NewSoftDelete[Order](client.Database("testdb").Collection("orders_aggregate_paginated"))
+	for i := 1; i <= 5; i++ {
+		if err := repo.InsertOne(ctx, &Order{TenantID: fmt.Sprintf("t%d", i), Total: i}); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+	if _, err := repo.SoftDelete(ctx, mongospec.Eq("tenant_id", "t5")); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+
+	pipeline := mongospec.NewPipeline().
+		GroupBy("$tenant_id", bson.M{"total": mongospec.Sum("$total"), "count": mongospec.Sum(1)}).
+		SortBy("_id", 1)
+	page, err := mongorepo.AggregatePaginatedAs[tenantTotal](ctx, repo, pipeline, 2, 3)
+	if err != nil {
+		t.Fatalf("AggregatePaginatedAs failed: %v", err)
+	}
+	want := []tenantTotal{{TenantID: "t4", Total: 4, Count: 1}}
+	if !reflect.DeepEqual(page.Items, want) || page.Total != 4 || page.TotalPages != 2 || page.HasNext || !page.HasPrev {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+
+	raw, err := repo.AggregatePaginated(ctx, pipeline, 5, 3)
+	if err != nil {
+		t.Fatalf("AggregatePaginated failed: %v", err)
+	}
+	if len(raw.Items) != 0 || raw.Total != 4 {
+		t.Fatalf("expected an empty page past the end with total 4, got %+v", raw)
+	}
+
+	empty, err := repo.AggregatePaginated(ctx, mongospec.NewPipeline().Match(mongospec.Eq("tenant_id", "none")), 1, 3)
+	if err != nil || empty.Total != 0 || len(empty.Items) != 0 || empty.Items == nil {
+		t.Fatalf("expected an empty first page, got %+v (%v)", empty, err)
+	}
+}
+
+func TestRegistry_AdministersAllRepositories(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	reg := mongorepo.NewRegistry()
+	articles := mongorepo.New[article](db.Collection("registry_articles"), mongorepo.WithRegistry(reg))
+	orders := mongorepo.NewSoftDelete[Order](db.Collection("registry_orders"), mongorepo.WithRegistry(reg))
+	mongorepo.New[Order](db.Collection("registry_orders"), mongorepo.WithRegistry(reg)) // shares a collection
+	_ = db.Collection("registry_articles").Drop(ctx)
+	_ = db.Collection("registry_orders").Drop(ctx)
+
+	if err := reg.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes failed: %v", err)
+	}
+	if err := articles.InsertOne(ctx, &article{Title: "a", Body: "b"}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+	for _, o := range []*Order{{TenantID: "t1", Total: 1}, {TenantID: "t1", Total: 2}} {
+		if err := orders.InsertOne(ctx, o); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	health := reg.Health(ctx)
+	if len(health) != 2 || health[0].Namespace != "testdb.registry_articles" || health[0].Err != nil || health[1].Err != nil {
+		t.Fatalf("unexpected health: %+v", health)
+	}
+
+	stats, err := reg.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if len(stats) != 2 || stats[0].Documents != 1 || stats[0].Indexes != 2 || stats[1].Documents != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	n, err := reg.Purge(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("Purge: deleted %d (%v), want 3", n, err)
+	}
+	if c, _ := orders.MongoRepository.Count(ctx, bson.M{}); c != 0 {
+		t.Fatalf("expected an empty collection after Purge, got %d documents", c)
+	}
+}
+
+type sku struct {
+	document.Base `bson:",inline"`
+	Code          string `bson:"code"`
+}
+
+func (sku) Indexes() []document.Index {
+	return []document.Index{
+		{Keys: bson.D{{Key: "code", Value: 1}}, Name: "code_unique", Unique: true},
+		{Keys: bson.D{{Key: "created_at", Value: -1}}},
+	}
+}
+
+func TestEnsureIndexes_RecreatesConflictingIndex(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("skus_indexes")
+	_ = coll.Drop(ctx)
+	if _, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "code", Value: 1}}}); err != nil {
+		t.Fatalf("CreateOne failed: %v", err)
+	}
+
+	err := mongorepo.New[sku](coll).EnsureIndexes(ctx)
+	var ie *mongorepo.IndexError
+	if !errors.As(err, &ie) || ie.Index.Name != "code_unique" {
+		t.Fatalf("expected an IndexError for the conflicting index, got %v", err)
+	}
+
+	if err := mongorepo.New[sku](coll, mongorepo.WithIndexRecreate()).EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes with recreate failed: %v", err)
+	}
+	cur, err := coll.Indexes().List(ctx)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	var specs []struct {
+		Name   string `bson:"name"`
+		Unique bool   `bson:"unique"`
+	}
+	if err := cur.All(ctx, &specs); err != nil {
+		t.Fatalf("decode indexes: %v", err)
+	}
+	names := map[string]bool{}
+	for _, s := range specs {
+		names[s.Name] = s.Unique || s.Name != "code_unique"
+	}
+	if len(specs) != 3 || !names["code_unique"] || !names["created_at_-1"] || names["code_1"] {
+		t.Fatalf("unexpected indexes after recreate: %+v", specs)
+	}
+}
+
+func TestIndexBuildProgress_ReportsRunningBuilds(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("index_progress")
+	_ = coll.Drop(ctx)
+	docs := make([]any, 50000)
+	for i := range docs {
+		docs[i] = bson.M{"n": i, "s": fmt.Sprintf("value-%d", i)}
+	}
+	if _, err := coll.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("InsertMany failed: %v", err)
+	}
+
+	if _, err := mongorepo.IndexBuildProgress(ctx, client); err != nil {
+		t.Fatalf("IndexBuildProgress failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "s", Value: 1}, {Key: "n", Value: -1}}})
+		done <- err
+	}()
+
+	// The build may finish before it is observed; only check what is reported.
+	for {
+		builds, err := mongorepo.IndexBuildProgress(ctx, client)
+		if err != nil {
+			t.Fatalf("IndexBuildProgress failed: %v", err)
+		}
+		for _, b := range builds {
+			if b.Namespace != "testdb.index_progress" {
+				continue // other tests may be building indexes
+			}
+			if len(b.Indexes) != 1 || b.Indexes[0] != "s_1_n_-1" {
+				t.Fatalf("unexpected build: %+v", b)
+			}
+			if p := b.Percent(); p < 0 || p > 100 {
+				t.Fatalf("unexpected progress %v%% in %+v", p, b)
+			}
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("CreateOne failed: %v", err)
+			}
+			return
+		default:
+		}
+	}
+}
+
+type employee struct {
+	document.Base `bson:",inline"`
+	Name          string `bson:"name"`
+	ReportsTo     string `bson:"reports_to,omitempty"`
+}
+
+func TestAggregate_GraphLookupWalksHierarchy(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("employees_graph")
+	_ = coll.Drop(ctx)
+	repo := mongorepo.New[employee](coll)
+	for _, e := range []*employee{{Name: "ceo"}, {Name: "cto", ReportsTo: "ceo"}, {Name: "lead", ReportsTo: "cto"}, {Name: "dev", ReportsTo: "lead"}} {
+		if err := repo.InsertOne(ctx, e); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	pipeline := mongospec.NewPipeline().
+		Match(mongospec.Eq("name", "dev")).
+		GraphLookup("employees_graph", "$reports_to", "reports_to", "name", "managers",
+			mongospec.DepthField("level"), mongospec.MaxDepth(1)).
+		Unwind("$managers").
+		SortBy("managers.level", 1).
+		Project(bson.M{"_id": 0, "name": "$managers.name", "level": "$managers.level"})
+	got, err := repo.AggregateRaw(ctx, pipeline)
+	if err != nil {
+		t.Fatalf("AggregateRaw failed: %v", err)
+	}
+	if len(got) != 2 || got[0]["name"] != "lead" || got[1]["name"] != "cto" || got[1]["level"] != int64(1) {
+		t.Fatalf("unexpected managers: %v", got)
+	}
+}
+
+func TestWarm_PrimesFindByIDCache(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("orders_warm")
+	_ = coll.Drop(ctx)
+	repo := mongorepo.NewSoftDelete[Order](coll,
+		mongorepo.WithCache(time.Minute),
+		mongorepo.WithWarmup(mongospec.Eq("tenant_id", "t1")),
+	)
+	orders := []*Order{{TenantID: "t1", Total: 1}, {TenantID: "t1", Total: 2}, {TenantID: "t2", Total: 3}, {TenantID: "t1", Total: 4}}
+	for _, o := range orders {
+		if err := repo.InsertOne(ctx, o); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+	if _, err := repo.SoftDelete(ctx, mongospec.Eq("total", 4)); err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+
+	n, err := repo.Warm(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("Warm cached %d documents (%v), want 2", n, err)
+	}
+
+	// Change the documents behind the repository's back: cached lookups still
+	// see the warmed values, uncached ones the new values.
+	if _, err := coll.UpdateMany(ctx, bson.M{}, bson.M{"$inc": bson.M{"total": 100}}); err != nil {
+		t.Fatalf("UpdateMany failed: %v", err)
+	}
+	for _, o := range orders[:2] {
+		got, err := repo.FindByID(ctx, o.ID)
+		if err != nil || got.Total != o.Total {
+			t.Fatalf("FindByID(%v) = %+v (%v), want the warmed document", o.ID, got, err)
+		}
+		got, err = repo.MongoRepository.FindByID(ctx, o.ID)
+		if err != nil || got.Total != o.Total {
+			t.Fatalf("MongoRepository.FindByID(%v) = %+v (%v), want the warmed document", o.ID, got, err)
+		}
+	}
+	if got, err := repo.FindByID(ctx, orders[2].ID); err != nil || got.Total != 103 {
+		t.Fatalf("FindByID of an unwarmed document = %+v (%v), want it read from the database", got, err)
+	}
+}
+
+func TestAggregate_UnionWithArchive(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	db := client.Database("testdb")
+	live := mongorepo.New[Order](db.Collection("orders_union_live"))
+	archive := mongorepo.New[Order](db.Collection("orders_union_archive"))
+	_ = live.Collection().Drop(ctx)
+	_ = archive.Collection().Drop(ctx)
+	for _, o := range []*Order{{TenantID: "t1", Total: 1}, {TenantID: "t2", Total: 2}} {
+		if err := live.InsertOne(ctx, o); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+	for _, o := range []*Order{{TenantID: "t1", Total: 3}, {TenantID: "t2", Total: 4}} {
+		if err := archive.InsertOne(ctx, o); err != nil {
+			t.Fatalf("InsertOne failed: %v", err)
+		}
+	}
+
+	pipeline := mongospec.NewPipeline().
+		Match(mongospec.Eq("tenant_id", "t1")).
+		UnionWith("orders_union_archive", mongospec.Eq("tenant_id", "t1")).
+		SortBy("total", 1)
+	got, err := live.Aggregate(ctx, pipeline)
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Total != 1 || got[1].Total != 3 {
+		t.Fatalf("unexpected union: %+v", got)
+	}
+}
+
+func TestCoalescing_SharesConcurrentReads(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	ctx := mongorepo.ContextWithComment(context.Background(), "coalesced")
+	db := client.Database("testdb_coalescing")
+	if err := db.RunCommand(ctx, bson.D{{Key: "profile", Value: 2}}).Err(); err != nil {
+		t.Fatalf("enable profiler: %v", err)
+	}
+	repo := mongorepo.New[Order](db.Collection("orders"), mongorepo.WithCoalescing())
+	if err := repo.InsertOne(ctx, &Order{TenantID: "t1", Total: 5}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	// $where with a sleep keeps each query in flight long enough to overlap.
+	slow := bson.M{"tenant_id": "t1", "$where": "sleep(200) || true"}
+	const callers = 10
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if o, err := repo.FindOne(ctx, slow); err != nil || o.Total != 5 {
+				t.Errorf("FindOne = %+v, %v", o, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if n, err := repo.Count(ctx, slow); err != nil || n != 1 {
+				t.Errorf("Count = %d, %v", n, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, op := range []string{"find", "aggregate"} { // CountDocuments runs an aggregation
+		n, err := db.Collection("system.profile").CountDocuments(ctx, bson.M{"command.comment": "coalesced", "command." + op: "orders"})
+		if err != nil || n == 0 || n >= callers {
+			t.Errorf("%s: %d round trips for %d concurrent callers (%v), want them shared", op, n, callers, err)
+		}
+	}
+}
+
+func TestHedgedReads_SendSecondReadAfterDelay(t *testing.T) {
+	client, cleanup := setupMongo(t)
+	defer cleanup()
+
+	db := client.Database("testdb_hedged")
+	if err := db.RunCommand(context.Background(), bson.D{{Key: "profile", Value: 2}}).Err(); err != nil {
+		t.Fatalf("enable profiler: %v", err)
+	}
+	repo := mongorepo.New[Order](db.Collection("orders"), mongorepo.WithHedgedReads(20*time.Millisecond, nil))
+	if err := repo.InsertOne(context.Background(), &Order{TenantID: "t1", Total: 5}); err != nil {
+		t.Fatalf("InsertOne failed: %v", err)
+	}
+
+	roundTrips := func(comment string) int64 {
+		// The cancelled read is profiled when the server stops it.
+		time.Sleep(500 * time.Millisecond)
+		n, err := db.Collection("system.profile").CountDocuments(context.Background(),
+			bson.M{"command.comment": comment, "command.find": "orders"})
+		if err != nil {
+			t.Fatalf("read profiler: %v", err)
+		}
+		return n
+	}
+
+	fast := mongorepo.ContextWithComment(context.Background(), "fast")
+	if o, err := repo.FindOne(fast, mongospec.Eq("tenant_id", "t1")); err != nil || o.Total != 5 {
+		t.Fatalf("FindOne = %+v, %v", o, err)
+	}
+	if n := roundTrips("fast"); n != 1 {
+		t.Errorf("fast read: %d round trips, want 1", n)
+	}
+
+	slow := mongorepo.ContextWithComment(context.Background(), "slow")
+	if o, err := repo.FindOne(slow, bson.M{"tenant_id": "t1", "$where": "sleep(200) || true"}); err != nil || o.Total != 5 {
+		t.Fatalf("FindOne = %+v, %v", o, err)
+	}
+	if n := roundTrips("slow"); n != 2 {
+		t.Errorf("slow read: %d round trips, want a hedged second read", n)
+	}
+}
@@ -0,0 +1,220 @@
+package mongorepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dElCIoGio/mongox/compat"
+	"github.com/dElCIoGio/mongox/encryption"
+	"github.com/dElCIoGio/mongox/internal/ratelimit"
+	"github.com/dElCIoGio/mongox/internal/singleflight"
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// Option configures a MongoRepository at construction time.
+//
+// Example:
+//
+//	repo := mongorepo.New[User](coll,
+//	    mongorepo.WithMaxQueryTime(2*time.Second),
+//	    mongorepo.WithObserver(hist),
+//	)
+type Option func(*settings)
+
+// settings holds repository-level configuration applied by Option functions.
+type settings struct {
+	maxQueryTime time.Duration
+	observer     repository.OperationObserver
+	cascades     []Cascade
+	references   []Reference
+	limiter      *ratelimit.Bucket
+	compat       *compat.Profile
+	advisor      *indexAdvisor
+	shapes       repository.ShapeObserver
+	cache        *idCache
+	pageSize     int
+	findPolicy   *FindPolicy
+	strict       *strictQueries
+	registry     *Registry
+	warmup       []any
+	flight       *singleflight.Group
+	hedge        *hedging
+	capped       *cappedSize
+	encryptor    encryption.Encryptor
+
+	indexTimeout  time.Duration
+	indexRecreate bool
+
+	readPref     *readpref.ReadPref
+	readConcern  *readconcern.ReadConcern
+	writeConcern *writeconcern.WriteConcern
+	commenter    func(ctx context.Context) string
+
+	softDeleteField string
+
+	beforeUpdate []UpdateHook
+	afterUpdate  []UpdateHook
+	beforeDelete []DeleteHook
+	afterDelete  []DeleteHook
+}
+
+func applyOptions(opts []Option) settings {
+	var s settings
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&s)
+		}
+	}
+	return s
+}
+
+// WithMaxQueryTime sets a default server-side time limit (maxTimeMS) for
+// find, count, and aggregate operations, so runaway queries are killed by the
+// server rather than left running after the client gives up.
+//
+// Behavior:
+//   - A value of 0 (the default) disables the limit
+//   - If the context deadline is sooner, the remaining time is used instead
+//   - Queries that exceed the limit return an error matching repository.ErrTimeout
+//
+// Example:
+//
+//	repo := mongorepo.New[User](coll, mongorepo.WithMaxQueryTime(2*time.Second))
+//
+//	_, err := repo.Find(ctx, filter)
+//	if errors.Is(err, repository.ErrTimeout) {
+//	    // query took longer than 2s
+//	}
+func WithMaxQueryTime(d time.Duration) Option {
+	return func(s *settings) { s.maxQueryTime = d }
+}
+
+// WithObserver registers an observer that receives the name, duration, and
+// error of every repository operation. Use repository.NewHistogram for
+// per-operation latency and timeout statistics.
+//
+// Example:
+//
+//	hist := repository.NewHistogram()
+//	repo := mongorepo.New[User](coll, mongorepo.WithObserver(hist))
+func WithObserver(obs repository.OperationObserver) Option {
+	return func(s *settings) { s.observer = obs }
+}
+
+// WithRateLimit throttles the repository to opsPerSecond operations, allowing
+// bursts of up to burst operations. Use it on repositories that serve background
+// jobs so bulk backfills don't starve production traffic on a shared cluster.
+//
+// Behavior:
+//   - Each CRUD, count, and aggregate call takes one token; a bulk call
+//     (InsertMany, UpdateMany, BulkWrite) counts as one
+//   - Callers block until a token is available or ctx is done
+//   - If ctx's deadline would pass before a token is available, the call fails
+//     immediately with an error matching repository.ErrTimeout
+//   - The limit is per repository value; repositories sharing a collection are limited separately
+//   - A non-positive opsPerSecond disables the limit
+//
+// Example:
+//
+//	backfill := mongorepo.New[User](coll, mongorepo.WithRateLimit(200, 20))
+func WithRateLimit(opsPerSecond float64, burst int) Option {
+	return func(s *settings) {
+		if opsPerSecond <= 0 {
+			s.limiter = nil
+			return
+		}
+		s.limiter = ratelimit.New(opsPerSecond, burst)
+	}
+}
+
+// WithCompatibility targets a MongoDB-compatible service such as Amazon
+// DocumentDB or Azure Cosmos DB. Pipelines passed to Aggregate and AggregateRaw
+// are rewritten and validated against the profile before they are sent; see
+// package compat for what is rewritten and emulated.
+//
+// Behavior:
+//   - Unsupported stages with a supported equivalent are rewritten
+//   - A trailing $facet is emulated when the profile lacks it
+//   - Anything else unsupported fails with an error matching compat.ErrUnsupported
+//
+// Example:
+//
+//	repo := mongorepo.New[Order](coll, mongorepo.WithCompatibility(compat.DocumentDB))
+func WithCompatibility(p compat.Profile) Option {
+	return func(s *settings) { s.compat = &p }
+}
+
+// WithDefaultPageSize sets the page size FindPaginated uses when perPage is
+// zero or negative. The maximum page size is raised to n if n exceeds it.
+//
+// Example:
+//
+//	repo := mongorepo.New[Product](coll, mongorepo.WithDefaultPageSize(50))
+//	page, err := repo.FindPaginated(ctx, filter, 1, 0) // 50 per page
+func WithDefaultPageSize(n int) Option {
+	return func(s *settings) { s.pageSize = n }
+}
+
+// wait blocks until the rate limiter, if any, admits one operation.
+func (s settings) wait(ctx context.Context) error {
+	if s.limiter == nil {
+		return nil
+	}
+	return s.limiter.Wait(ctx)
+}
+
+// pagination returns the pagination options for a page request, using the
+// repository's default page size.
+func (s settings) pagination(page, perPage int) repository.PaginationOptions {
+	return repository.PaginationOptions{
+		Page:           page,
+		PerPage:        perPage,
+		DefaultPerPage: s.pageSize,
+		MaxPerPage:     max(s.pageSize, 100),
+	}
+}
+
+// maxTime returns the maxTimeMS to send with a query, or 0 for none.
+func (s settings) maxTime(ctx context.Context) time.Duration {
+	limit := s.maxQueryTime
+	if limit <= 0 {
+		return 0
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining > 0 && remaining < limit {
+			limit = remaining
+		}
+	}
+	return limit
+}
+
+// withMaxQueryTime returns s with the query time limit replaced by d, or s
+// itself if d is not positive.
+func (s settings) withMaxQueryTime(d time.Duration) settings {
+	if d > 0 {
+		s.maxQueryTime = d
+	}
+	return s
+}
+
+// observe reports an operation outcome to the configured observer, if any.
+func (s settings) observe(op string, start time.Time, err error) {
+	if s.observer != nil {
+		s.observer.ObserveOperation(op, time.Since(start), err)
+	}
+}
+
+// wrapTimeout marks driver timeout errors (context deadline, maxTimeMS expired)
+// with repository.ErrTimeout while keeping the original error in the chain.
+func wrapTimeout(err error) error {
+	if err == nil || !mongo.IsTimeout(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", repository.ErrTimeout, err)
+}
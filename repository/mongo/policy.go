@@ -0,0 +1,97 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dElCIoGio/mongox/repository"
+)
+
+// ErrLimitExceeded is returned by Find calls that request more documents than
+// a strict FindPolicy allows.
+var ErrLimitExceeded = errors.New("mongorepo: requested limit exceeds the find policy")
+
+// FindPolicy bounds the results of Find calls, protecting APIs from unbounded
+// queries and unstable ordering.
+type FindPolicy struct {
+	// MaxLimit caps the number of documents a Find returns. A Find without a
+	// limit, or with a larger one, is limited to MaxLimit. 0 means no cap.
+	MaxLimit int64
+
+	// DefaultSort is used when a Find has no sort, e.g. bson.D{{Key: "_id",
+	// Value: 1}} for stable ordering across pages.
+	DefaultSort any
+
+	// Strict makes a Find with an explicit limit above MaxLimit fail with
+	// ErrLimitExceeded instead of being capped. Finds without a limit are
+	// still capped.
+	Strict bool
+}
+
+// WithFindPolicy applies p to every Find, FindInto, FindPaginated, and FindAs
+// call of the repository. ExportCSV, StreamJSON, FindEach, and FindIter only
+// use its DefaultSort, so exports and streams are never cut short; FindOne is
+// not affected.
+//
+// A policy set on the context with ContextWithFindPolicy takes precedence, so
+// a request can tighten or relax the repository default.
+//
+// Example:
+//
+//	repo := mongorepo.New[Order](coll, mongorepo.WithFindPolicy(mongorepo.FindPolicy{
+//	    MaxLimit:    1000,
+//	    DefaultSort: bson.D{{Key: "_id", Value: 1}},
+//	}))
+func WithFindPolicy(p FindPolicy) Option {
+	return func(s *settings) { s.findPolicy = &p }
+}
+
+type findPolicyKey struct{}
+
+// ContextWithFindPolicy returns ctx carrying p, which replaces the repository's
+// find policy for calls made with it, e.g. a stricter limit for public API
+// requests set by middleware.
+//
+// Example:
+//
+//	ctx = mongorepo.ContextWithFindPolicy(r.Context(), mongorepo.FindPolicy{MaxLimit: 100, Strict: true})
+func ContextWithFindPolicy(ctx context.Context, p FindPolicy) context.Context {
+	return context.WithValue(ctx, findPolicyKey{}, p)
+}
+
+// findPolicyFor returns the policy for a call made with ctx, or nil for none.
+func (s settings) findPolicyFor(ctx context.Context) *FindPolicy {
+	if p, ok := ctx.Value(findPolicyKey{}).(FindPolicy); ok {
+		return &p
+	}
+	return s.findPolicy
+}
+
+// apply caps fo's limit and fills in the default sort. A nil policy leaves fo
+// unchanged.
+func (p *FindPolicy) apply(fo *repository.FindOptions) error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxLimit > 0 {
+		switch {
+		case fo.Limit <= 0:
+			fo.Limit = p.MaxLimit
+		case fo.Limit > p.MaxLimit && p.Strict:
+			return fmt.Errorf("%w: %d > %d", ErrLimitExceeded, fo.Limit, p.MaxLimit)
+		case fo.Limit > p.MaxLimit:
+			fo.Limit = p.MaxLimit
+		}
+	}
+	p.applySort(fo)
+	return nil
+}
+
+// applySort fills in the default sort when fo has none. A nil policy leaves
+// fo unchanged.
+func (p *FindPolicy) applySort(fo *repository.FindOptions) {
+	if p != nil && fo.Sort == nil && p.DefaultSort != nil {
+		fo.Sort = p.DefaultSort
+	}
+}
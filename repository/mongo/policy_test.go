@@ -0,0 +1,58 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestFindPolicy_StrictLimit(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; a rejected limit must fail before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	repo := mongorepo.New[invoiceRow](client.Database("testdb").Collection("invoices"),
+		mongorepo.WithFindPolicy(mongorepo.FindPolicy{MaxLimit: 100, Strict: true}),
+	)
+	if _, err := repo.Find(ctx, nil, repository.WithLimit(500)); !errors.Is(err, mongorepo.ErrLimitExceeded) {
+		t.Fatalf("Find: expected ErrLimitExceeded, got %v", err)
+	}
+	if _, err := mongorepo.FindAs[invoiceRow, invoiceRow](ctx, repo, nil, nil, repository.WithLimit(500)); !errors.Is(err, mongorepo.ErrLimitExceeded) {
+		t.Fatalf("FindAs: expected ErrLimitExceeded, got %v", err)
+	}
+
+	// Exports and streams read every matching document: the limit policy does
+	// not apply, so the calls only fail on the canceled context.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := repo.ExportCSV(canceled, io.Discard, nil, nil, repository.WithLimit(500)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExportCSV: expected context.Canceled, got %v", err)
+	}
+	if err := repo.StreamJSON(canceled, io.Discard, nil, mongorepo.NDJSON, repository.WithLimit(500)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("StreamJSON: expected context.Canceled, got %v", err)
+	}
+	if _, err := repo.FindIter(canceled, nil, repository.WithLimit(500)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FindIter: expected context.Canceled, got %v", err)
+	}
+	if err := repo.FindEach(canceled, nil, func(*invoiceRow) error { return nil }, repository.WithLimit(500)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("FindEach: expected context.Canceled, got %v", err)
+	}
+
+	// A request-scoped policy replaces the repository's.
+	strict := mongorepo.ContextWithFindPolicy(ctx, mongorepo.FindPolicy{MaxLimit: 10, Strict: true})
+	if _, err := repo.Find(strict, nil, repository.WithLimit(50)); !errors.Is(err, mongorepo.ErrLimitExceeded) {
+		t.Fatalf("Find with context policy: expected ErrLimitExceeded, got %v", err)
+	}
+}
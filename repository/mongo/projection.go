@@ -0,0 +1,213 @@
+package mongorepo
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scopedSource is implemented by repositories that can run raw queries against
+// their collection while applying their own filter scoping (e.g. soft delete).
+type scopedSource interface {
+	Collection() *mongo.Collection
+	scopeFilter(filter any) any
+	repoSettings() settings
+}
+
+// repositoryOf constrains generic helpers to the concrete repositories of T.
+type repositoryOf[T any] interface {
+	*MongoRepository[T] | *SoftDeleteRepository[T]
+	scopedSource
+}
+
+func (r *MongoRepository[T]) scopeFilter(filter any) any {
+	return filter
+}
+
+func (r *MongoRepository[T]) repoSettings() settings {
+	return r.settings
+}
+
+func (r *SoftDeleteRepository[T]) scopeFilter(filter any) any {
+	return r.combineWithNotDeleted(filter)
+}
+
+// FindAs finds documents matching the filter in repo's collection, fetching only the
+// projected fields and decoding each result into the lightweight type P.
+// This cuts bandwidth and decode time for list endpoints that don't need full documents.
+//
+// If projection is nil, it is taken from a WithProjection option or else derived
+// from P's bson struct tags (see ProjectionOf).
+// Soft-delete repositories only return non-deleted documents. Fields of T
+// marked for encryption are decrypted, whether or not P marks them, and P's
+// AfterLoad hook, if any, is called.
+//
+// Example:
+//
+//	type UserSummary struct {
+//	    ID   primitive.ObjectID `bson:"_id"`
+//	    Name string             `bson:"name"`
+//	}
+//
+//	summaries, err := mongorepo.FindAs[User, UserSummary](ctx, repo,
+//	    spec.Eq("status", "active"), nil,
+//	    repository.WithLimit(50),
+//	)
+func FindAs[T, P any, R repositoryOf[T]](ctx context.Context, repo R, filter any, projection any, opts ...repository.FindOption) (_ []P, err error) {
+	s := repo.repoSettings()
+	defer func(start time.Time) {
+		err = wrapTimeout(err)
+		s.observe(repository.OpFind, start, err)
+	}(time.Now())
+	if err = s.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	f, err := normalizeFilter(repo.scopeFilter(filter))
+	if err != nil {
+		return nil, err
+	}
+
+	fo := applyFindOptions(opts)
+	if err := s.findPolicyFor(ctx).apply(&fo); err != nil {
+		return nil, err
+	}
+	if projection == nil {
+		projection = fo.Projection
+	}
+	if projection == nil {
+		projection = ProjectionOf[P]()
+	}
+	if decrypts[T](s) {
+		if projection, err = keepID(projection); err != nil {
+			return nil, err
+		}
+	}
+
+	mongoOpts := mopt.Find().SetProjection(projection)
+	if c := s.comment(ctx, fo.Comment); c != "" {
+		mongoOpts.SetComment(c)
+	}
+	if fo.Limit > 0 {
+		mongoOpts.SetLimit(fo.Limit)
+	}
+	if fo.Skip > 0 {
+		mongoOpts.SetSkip(fo.Skip)
+	}
+	if fo.Sort != nil {
+		mongoOpts.SetSort(fo.Sort)
+	}
+	if d := s.maxTime(ctx); d > 0 {
+		mongoOpts.SetMaxTime(d)
+	}
+
+	coll, err := readCollection(repo.Collection(), fo)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := coll.Find(ctx, f, mongoOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	results := make([]P, 0, fo.CapacityHint)
+	// P need not mark the fields T encrypts, so they are decrypted by T's marks.
+	if err := decodeAll[T](ctx, s, cur, &results); err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if err := s.afterLoad(ctx, &results[i]); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+var projectionCache sync.Map // reflect.Type -> bson.D
+
+// ProjectionOf derives an inclusion projection from the bson struct tags of P.
+// Fields tagged `bson:"-"` are skipped and `bson:",inline"` structs are flattened.
+// If P has no _id field, _id is explicitly excluded.
+// The result is cached per type and must be treated as read-only.
+//
+// Example:
+//
+//	type UserSummary struct {
+//	    ID   primitive.ObjectID `bson:"_id"`
+//	    Name string             `bson:"name"`
+//	}
+//
+//	ProjectionOf[UserSummary]()  // bson.D{{"_id", 1}, {"name", 1}}
+func ProjectionOf[P any]() bson.D {
+	t := reflect.TypeOf((*P)(nil)).Elem()
+	if cached, ok := projectionCache.Load(t); ok {
+		return cached.(bson.D)
+	}
+
+	var proj bson.D
+	hasID := false
+	for _, name := range structFieldNames(t) {
+		if name == "_id" {
+			hasID = true
+		}
+		proj = append(proj, bson.E{Key: name, Value: 1})
+	}
+	if !hasID && len(proj) > 0 {
+		proj = append(proj, bson.E{Key: "_id", Value: 0})
+	}
+
+	projectionCache.Store(t, proj)
+	return proj
+}
+
+// structFieldNames returns the BSON field names of a struct type, following the
+// driver's default naming rules (tag name, or the lowercased Go field name).
+func structFieldNames(t reflect.Type) []string {
+	var names []string
+	walkStructFields(t, func(name string, _ reflect.StructField) {
+		names = append(names, name)
+	})
+	return names
+}
+
+// walkStructFields calls fn with the BSON name of every exported field of a
+// struct type, skipping `bson:"-"` fields and flattening `bson:",inline"` structs.
+func walkStructFields(t reflect.Type, fn func(name string, field reflect.StructField)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("bson")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(","+opts+",", ",inline,") {
+			walkStructFields(field.Type, fn)
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fn(name, field)
+	}
+}
@@ -0,0 +1,62 @@
+package mongorepo_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type userSummary struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Name     string             `bson:"name"`
+	Email    string             `bson:"email,omitempty"`
+	Internal string             `bson:"-"`
+	Age      int
+	secret   string
+}
+
+type auditedSummary struct {
+	document.Base `bson:",inline"`
+	Title         string `bson:"title"`
+}
+
+type nameOnly struct {
+	Name string `bson:"name"`
+}
+
+func TestProjectionOf(t *testing.T) {
+	tests := []struct {
+		name string
+		got  bson.D
+		want bson.D
+	}{
+		{
+			name: "tags, defaults and skipped fields",
+			got:  mongorepo.ProjectionOf[userSummary](),
+			want: bson.D{{Key: "_id", Value: 1}, {Key: "name", Value: 1}, {Key: "email", Value: 1}, {Key: "age", Value: 1}},
+		},
+		{
+			name: "inline struct",
+			got:  mongorepo.ProjectionOf[auditedSummary](),
+			want: bson.D{{Key: "_id", Value: 1}, {Key: "created_at", Value: 1}, {Key: "updated_at", Value: 1}, {Key: "title", Value: 1}},
+		},
+		{
+			name: "excludes _id when absent",
+			got:  mongorepo.ProjectionOf[nameOnly](),
+			want: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Fatalf("ProjectionOf mismatch.\n got: %#v\nwant: %#v", tt.got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package mongorepo
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OnDeleteAction determines what happens to referencing documents when a
+// referenced document is deleted.
+type OnDeleteAction int
+
+const (
+	// OnDeleteRestrict rejects the delete with ErrReferenced while any referencing document exists.
+	OnDeleteRestrict OnDeleteAction = iota
+
+	// OnDeleteCascade deletes the referencing documents.
+	OnDeleteCascade
+
+	// OnDeleteSetNull sets the referencing field to null.
+	OnDeleteSetNull
+)
+
+// String returns "restrict", "cascade", or "set null".
+func (a OnDeleteAction) String() string {
+	switch a {
+	case OnDeleteRestrict:
+		return "restrict"
+	case OnDeleteCascade:
+		return "cascade"
+	case OnDeleteSetNull:
+		return "set null"
+	default:
+		return fmt.Sprintf("OnDeleteAction(%d)", int(a))
+	}
+}
+
+// Reference declares that documents in Collection refer to this repository's
+// documents through ForeignKey, approximating a foreign key constraint.
+//
+// Example:
+//
+//	users := mongorepo.New[User](db.Collection("users"),
+//	    mongorepo.WithReferences(
+//	        mongorepo.Reference{Collection: db.Collection("orders"), ForeignKey: "user_id", OnDelete: mongorepo.OnDeleteRestrict},
+//	        mongorepo.Reference{Collection: db.Collection("sessions"), ForeignKey: "user_id", OnDelete: mongorepo.OnDeleteCascade},
+//	        mongorepo.Reference{Collection: db.Collection("posts"), ForeignKey: "author_id", OnDelete: mongorepo.OnDeleteSetNull},
+//	    ),
+//	)
+//
+//	_, err := users.DeleteOne(ctx, spec.Eq("_id", id))
+//	if errors.Is(err, repository.ErrReferenced) {
+//	    // the user still has orders
+//	}
+type Reference struct {
+	// Collection holds the referencing documents.
+	Collection *mongo.Collection
+
+	// ForeignKey is the referencing field.
+	ForeignKey string
+
+	// LocalKey is the referenced field in this repository's documents. Defaults to "_id".
+	LocalKey string
+
+	// OnDelete is the action taken when a referenced document is deleted.
+	OnDelete OnDeleteAction
+}
+
+func (ref Reference) localKey() string {
+	if ref.LocalKey == "" {
+		return "_id"
+	}
+	return ref.LocalKey
+}
+
+// WithReferences declares collections that reference this repository's documents.
+// DeleteOne and DeleteMany then enforce each reference's OnDelete action.
+//
+// Behavior:
+//   - Restrict checks run before anything is deleted
+//   - The delete and all follow-up writes run in one transaction
+//     (a replica set or sharded cluster is required)
+//   - If the context already carries a session, its transaction is reused
+//   - Cascaded deletes do not follow references declared on other repositories
+func WithReferences(refs ...Reference) Option {
+	return func(s *settings) { s.references = append(s.references, refs...) }
+}
+
+// deleteWithReferences deletes up to limit (0 for all) documents matching filter,
+// enforcing the declared references.
+func (r *MongoRepository[T]) deleteWithReferences(ctx context.Context, filter any, limit int64) (int64, error) {
+	f, err := normalizeFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	keys := make([]string, 0, len(r.settings.references))
+	for _, ref := range r.settings.references {
+		keys = append(keys, ref.localKey())
+	}
+
+	var deleted int64
+	err = inTransaction(ctx, r.coll, func(ctx context.Context) error {
+		deleted = 0
+		targets, err := findKeyDocs(ctx, r.coll, f, keys, limit)
+		if err != nil || len(targets) == 0 {
+			return err
+		}
+
+		if err := r.applyReferences(ctx, targets); err != nil {
+			return err
+		}
+
+		res, err := r.coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": keyValues(targets, "_id")}})
+		if err != nil {
+			return err
+		}
+		deleted = res.DeletedCount
+		return nil
+	})
+	return deleted, err
+}
+
+// applyReferences enforces the declared references for the documents in
+// targets, which hold _id and every reference key: it fails with ErrReferenced
+// if a restriction applies and otherwise cascades or nulls the referencing
+// documents. It must run in the transaction that deletes targets.
+func (r *MongoRepository[T]) applyReferences(ctx context.Context, targets []bson.M) error {
+	// Check every restriction before writing anything.
+	for _, ref := range r.settings.references {
+		if ref.OnDelete != OnDeleteRestrict {
+			continue
+		}
+		values := keyValues(targets, ref.localKey())
+		if len(values) == 0 {
+			continue
+		}
+		n, err := ref.Collection.CountDocuments(ctx,
+			bson.M{ref.ForeignKey: bson.M{"$in": values}},
+			mopt.Count().SetLimit(1),
+		)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return fmt.Errorf("%w by %s.%s", ErrReferenced, ref.Collection.Name(), ref.ForeignKey)
+		}
+	}
+
+	for _, ref := range r.settings.references {
+		values := keyValues(targets, ref.localKey())
+		if len(values) == 0 {
+			continue
+		}
+		refFilter := bson.M{ref.ForeignKey: bson.M{"$in": values}}
+
+		switch ref.OnDelete {
+		case OnDeleteCascade:
+			if _, err := ref.Collection.DeleteMany(ctx, refFilter); err != nil {
+				return err
+			}
+		case OnDeleteSetNull:
+			if _, err := ref.Collection.UpdateMany(ctx, refFilter, bson.M{"$set": bson.M{ref.ForeignKey: nil}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Managed is the part of a repository that administrative operations need.
+// MongoRepository and SoftDeleteRepository implement it.
+type Managed interface {
+	Collection() *mongo.Collection
+	EnsureIndexes(ctx context.Context) error
+}
+
+// Registry keeps track of an application's repositories so administrative
+// operations such as index creation, health checks, and statistics can run
+// over all of them. It is safe for concurrent use.
+//
+// Example:
+//
+//	reg := mongorepo.NewRegistry()
+//	users := mongorepo.New[User](db.Collection("users"), mongorepo.WithRegistry(reg))
+//	orders := mongorepo.NewSoftDelete[Order](db.Collection("orders"), mongorepo.WithRegistry(reg))
+//
+//	if err := reg.EnsureIndexes(ctx); err != nil {
+//	    log.Fatal(err)
+//	}
+type Registry struct {
+	mu    sync.Mutex
+	repos []Managed
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// WithRegistry registers the repository with reg when it is created.
+//
+// Example:
+//
+//	repo := mongorepo.New[User](coll, mongorepo.WithRegistry(reg))
+func WithRegistry(reg *Registry) Option {
+	return func(s *settings) { s.registry = reg }
+}
+
+// Register adds repo to the registry. Registering the same repository twice
+// has no effect.
+func (g *Registry) Register(repo Managed) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, r := range g.repos {
+		if r == repo {
+			return
+		}
+	}
+	g.repos = append(g.repos, repo)
+}
+
+// Repositories returns the registered repositories in registration order.
+func (g *Registry) Repositories() []Managed {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Managed(nil), g.repos...)
+}
+
+// collections returns the distinct collections of the registered repositories,
+// in registration order. Repositories sharing a collection share an entry.
+func (g *Registry) collections() []*mongo.Collection {
+	seen := map[string]bool{}
+	var out []*mongo.Collection
+	for _, r := range g.Repositories() {
+		coll := r.Collection()
+		ns := namespace(coll)
+		if seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		out = append(out, coll)
+	}
+	return out
+}
+
+// namespace returns the "database.collection" name of coll.
+func namespace(coll *mongo.Collection) string {
+	return coll.Database().Name() + "." + coll.Name()
+}
+
+// EnsureIndexes calls EnsureIndexes on every registered repository. It
+// continues past failures and returns them joined, each prefixed with the
+// collection's namespace.
+func (g *Registry) EnsureIndexes(ctx context.Context) error {
+	var errs []error
+	for _, r := range g.Repositories() {
+		if err := r.EnsureIndexes(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", namespace(r.Collection()), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CollectionHealth is the result of a health check of one collection.
+type CollectionHealth struct {
+	// Namespace is the collection's "database.collection" name.
+	Namespace string
+
+	// Latency is the round-trip time of the check.
+	Latency time.Duration
+
+	// Err is nil if the collection could be read.
+	Err error
+}
+
+// Health checks that every registered collection can be read, by asking the
+// server for its estimated document count. Use ctx to bound the checks.
+//
+// Example:
+//
+//	for _, h := range reg.Health(ctx) {
+//	    if h.Err != nil {
+//	        log.Printf("%s unhealthy: %v", h.Namespace, h.Err)
+//	    }
+//	}
+func (g *Registry) Health(ctx context.Context) []CollectionHealth {
+	colls := g.collections()
+	out := make([]CollectionHealth, len(colls))
+	for i, coll := range colls {
+		start := time.Now()
+		_, err := coll.EstimatedDocumentCount(ctx)
+		out[i] = CollectionHealth{Namespace: namespace(coll), Latency: time.Since(start), Err: wrapTimeout(err)}
+	}
+	return out
+}
+
+// CollectionStats holds the storage statistics of one collection, as reported
+// by $collStats.
+type CollectionStats struct {
+	Namespace      string
+	Documents      int64 // number of documents
+	Size           int64 // uncompressed size of the documents, in bytes
+	StorageSize    int64 // space allocated for the documents, in bytes
+	Indexes        int   // number of indexes
+	TotalIndexSize int64 // space allocated for the indexes, in bytes
+}
+
+// Stats returns the storage statistics of every registered collection.
+// Collections that do not exist yet are reported with zero values.
+func (g *Registry) Stats(ctx context.Context) ([]CollectionStats, error) {
+	colls := g.collections()
+	out := make([]CollectionStats, 0, len(colls))
+	for _, coll := range colls {
+		st, err := collectionStats(ctx, coll)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", namespace(coll), wrapTimeout(err))
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// collectionStats reads the storage statistics of coll with $collStats.
+func collectionStats(ctx context.Context, coll *mongo.Collection) (CollectionStats, error) {
+	st := CollectionStats{Namespace: namespace(coll)}
+	cur, err := coll.Aggregate(ctx, []bson.M{{"$collStats": bson.M{"storageStats": bson.M{}}}})
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == 26 { // NamespaceNotFound
+			return st, nil
+		}
+		return st, err
+	}
+	defer cur.Close(ctx)
+
+	var res struct {
+		StorageStats struct {
+			Count          int64 `bson:"count"`
+			Size           int64 `bson:"size"`
+			StorageSize    int64 `bson:"storageSize"`
+			NIndexes       int   `bson:"nindexes"`
+			TotalIndexSize int64 `bson:"totalIndexSize"`
+		} `bson:"storageStats"`
+	}
+	if cur.Next(ctx) {
+		if err := cur.Decode(&res); err != nil {
+			return st, err
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return st, err
+	}
+	st.Documents = res.StorageStats.Count
+	st.Size = res.StorageStats.Size
+	st.StorageSize = res.StorageStats.StorageSize
+	st.Indexes = res.StorageStats.NIndexes
+	st.TotalIndexSize = res.StorageStats.TotalIndexSize
+	return st, nil
+}
+
+// Purge permanently deletes every document in every registered collection,
+// bypassing hooks, soft delete, and cascades, and returns the number deleted.
+// Indexes and validators are kept. It is meant for resetting test and
+// development databases.
+//
+// Example:
+//
+//	t.Cleanup(func() { _, _ = reg.Purge(context.Background()) })
+func (g *Registry) Purge(ctx context.Context) (int64, error) {
+	var total int64
+	for _, coll := range g.collections() {
+		res, err := coll.DeleteMany(ctx, bson.M{})
+		if err != nil {
+			return total, fmt.Errorf("%s: %w", namespace(coll), wrapTimeout(err))
+		}
+		total += res.DeletedCount
+	}
+	return total, nil
+}
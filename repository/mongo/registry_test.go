@@ -0,0 +1,40 @@
+package mongorepo_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestRegistry_RegistersRepositories(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; registration never reaches the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	db := client.Database("testdb")
+	reg := mongorepo.NewRegistry()
+	invoices := mongorepo.New[invoiceRow](db.Collection("invoices"), mongorepo.WithRegistry(reg))
+	archived := mongorepo.NewSoftDelete[invoiceRow](db.Collection("archived_invoices"), mongorepo.WithRegistry(reg))
+	mongorepo.New[invoiceRow](db.Collection("unregistered"))
+	reg.Register(invoices)
+
+	got := reg.Repositories()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 registered repositories, got %d", len(got))
+	}
+	if got[0] != mongorepo.Managed(invoices) || got[1].Collection().Name() != "archived_invoices" {
+		t.Fatalf("unexpected registration order: %v, %v", got[0].Collection().Name(), got[1].Collection().Name())
+	}
+	if got[1] != mongorepo.Managed(archived.MongoRepository) {
+		t.Fatal("expected the soft delete repository's MongoRepository to be registered")
+	}
+}
@@ -0,0 +1,54 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ContextWithSession returns ctx bound to sess. Repository and driver calls made
+// with the returned context run in the session, and in its transaction if one
+// is active.
+func ContextWithSession(ctx context.Context, sess mongo.Session) context.Context {
+	return mongo.NewSessionContext(ctx, sess)
+}
+
+// SessionFromContext returns the session bound to ctx, or nil if there is none.
+func SessionFromContext(ctx context.Context) mongo.Session {
+	return mongo.SessionFromContext(ctx)
+}
+
+// TxInfo describes the transaction a context belongs to.
+type TxInfo struct {
+	// Attempt is 1 for the first run of the transaction function and increases
+	// on every retry after a transient error.
+	Attempt int
+
+	// StartedAt is when the current attempt started.
+	StartedAt time.Time
+}
+
+type txInfoKey struct{}
+
+// TxInfoFromContext returns the metadata of the transaction started by a
+// TransactionManager (or RunInTransaction) that ctx belongs to.
+func TxInfoFromContext(ctx context.Context) (TxInfo, bool) {
+	info, ok := ctx.Value(txInfoKey{}).(TxInfo)
+	return info, ok
+}
+
+// InTransaction reports whether ctx carries a session with an active
+// transaction, whether it was started by this package or directly through the
+// driver.
+func InTransaction(ctx context.Context) bool {
+	if _, ok := TxInfoFromContext(ctx); ok {
+		return true
+	}
+	sess := mongo.SessionFromContext(ctx)
+	if sess == nil {
+		return false
+	}
+	x, ok := sess.(mongo.XSession)
+	return ok && x.ClientSession().TransactionRunning()
+}
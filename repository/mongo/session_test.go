@@ -0,0 +1,45 @@
+package mongorepo_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestSessionFromContext(t *testing.T) {
+	ctx := context.Background()
+	if mongorepo.SessionFromContext(ctx) != nil {
+		t.Fatal("expected no session in a plain context")
+	}
+	if mongorepo.InTransaction(ctx) {
+		t.Fatal("expected a plain context not to be in a transaction")
+	}
+	if _, ok := mongorepo.TxInfoFromContext(ctx); ok {
+		t.Fatal("expected no transaction info in a plain context")
+	}
+
+	// Connect is lazy, so no server is needed to start a session.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	sess, err := client.StartSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sess.EndSession(ctx)
+
+	sctx := mongorepo.ContextWithSession(ctx, sess)
+	if got := mongorepo.SessionFromContext(sctx); got != sess {
+		t.Fatalf("expected the bound session back, got %v", got)
+	}
+	if mongorepo.InTransaction(sctx) {
+		t.Fatal("expected a session without a transaction not to be in a transaction")
+	}
+}
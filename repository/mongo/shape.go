@@ -0,0 +1,158 @@
+package mongorepo
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// WithShapeObserver reports every find, count, update, replace, delete, and
+// aggregate operation to obs together with its normalized query shape. Use
+// repository.NewShapeRegistry to collect P50/P95 latency per shape and alert on
+// slow ones; one registry can be shared by all repositories of an application.
+//
+// Shapes keep field names and operators but replace every value with ?, e.g.
+//
+//	{"status":?,"total":{"$gte":?}} sort {"created_at":-1}
+//
+// Example:
+//
+//	shapes := repository.NewShapeRegistry()
+//	repo := mongorepo.New[Order](coll, mongorepo.WithShapeObserver(shapes))
+//	http.Handle("/debug/query-shapes", shapes)
+func WithShapeObserver(obs repository.ShapeObserver) Option {
+	return func(s *settings) { s.shapes = obs }
+}
+
+// observeShape reports an operation with its query shape to the configured
+// shape observer, if any. Call it deferred with a named error result, after the
+// filter is normalized; the filter is only encoded when an observer is set.
+func (r *MongoRepository[T]) observeShape(op string, query, sortSpec any, start time.Time, errp *error) {
+	if r.settings.shapes == nil {
+		return
+	}
+	r.settings.shapes.ObserveShape(r.coll.Name(), op, queryShapeString(query, sortSpec), time.Since(start), wrapTimeout(*errp))
+}
+
+// queryShapeString renders a filter or pipeline, and an optional sort, with
+// literal values replaced by ?. Document keys are sorted so that maps with the
+// same fields yield the same shape; sort keys keep their order.
+func queryShapeString(query, sortSpec any) string {
+	var b strings.Builder
+	if query == nil {
+		query = bson.D{}
+	}
+	writeShapeValue(&b, query)
+	if sortSpec != nil {
+		b.WriteString(" sort ")
+		b.WriteString(sortShapeString(sortSpec))
+	}
+	return b.String()
+}
+
+// writeShapeValue encodes v and writes its shape. Values that cannot be encoded
+// are written as ?.
+func writeShapeValue(b *strings.Builder, v any) {
+	raw, err := bson.Marshal(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		b.WriteString("?")
+		return
+	}
+	writeShape(b, bson.Raw(raw).Lookup("v"), rootKey)
+}
+
+// rootKey is the parent key passed for the top-level value and array elements;
+// it cannot collide with a field name.
+const rootKey = "\x00"
+
+func writeShape(b *strings.Builder, v bson.RawValue, parent string) {
+	switch v.Type {
+	case bson.TypeEmbeddedDocument:
+		elems, err := v.Document().Elements()
+		if err != nil {
+			b.WriteString("?")
+			return
+		}
+		sort.SliceStable(elems, func(i, j int) bool { return elems[i].Key() < elems[j].Key() })
+		b.WriteByte('{')
+		for i, e := range elems {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(strconv.Quote(e.Key()))
+			b.WriteByte(':')
+			writeShape(b, e.Value(), e.Key())
+		}
+		b.WriteByte('}')
+	case bson.TypeArray:
+		// Pipelines and logical operators keep their structure; other arrays
+		// ($in lists, literal arrays) vary in length, so they collapse to one ?.
+		if !structuralArray(parent) {
+			b.WriteString("?")
+			return
+		}
+		values, err := v.Array().Values()
+		if err != nil {
+			b.WriteString("?")
+			return
+		}
+		b.WriteByte('[')
+		for i, e := range values {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeShape(b, e, rootKey)
+		}
+		b.WriteByte(']')
+	default:
+		b.WriteString("?")
+	}
+}
+
+// structuralArray reports whether an array under the given key describes query
+// structure rather than data.
+func structuralArray(key string) bool {
+	switch key {
+	case rootKey, "$and", "$or", "$nor":
+		return true
+	}
+	return false
+}
+
+// sortShapeString renders a sort specification with its directions, keeping
+// key order.
+func sortShapeString(sortSpec any) string {
+	raw, err := bson.Marshal(bson.D{{Key: "v", Value: sortSpec}})
+	if err != nil {
+		return "?"
+	}
+	doc, ok := bson.Raw(raw).Lookup("v").DocumentOK()
+	if !ok {
+		return "?"
+	}
+	elems, err := doc.Elements()
+	if err != nil {
+		return "?"
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, e := range elems {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Quote(e.Key()))
+		b.WriteByte(':')
+		if d, ok := e.Value().AsInt64OK(); ok {
+			b.WriteString(strconv.FormatInt(d, 10))
+		} else {
+			b.WriteString(e.Value().String())
+		}
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package mongorepo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+	mongospec "github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestWithShapeObserver_NormalizesValues(t *testing.T) {
+	ctx := context.Background()
+
+	// Nothing listens on this port: every operation fails fast, but is still observed.
+	client, err := mongo.Connect(ctx, mopt.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	shapes := repository.NewShapeRegistry()
+	repo := mongorepo.New[invoiceRow](client.Database("testdb").Collection("invoices"), mongorepo.WithShapeObserver(shapes))
+
+	_, _ = repo.Find(ctx, bson.M{"number": "A-1", "total": bson.M{"$gt": 10}}, repository.WithSort(bson.D{{Key: "total", Value: -1}}))
+	_, _ = repo.Find(ctx, bson.M{"total": bson.M{"$gt": 99}, "number": "B-7"}, repository.WithSort(bson.D{{Key: "total", Value: -1}}))
+	_, _ = repo.Count(ctx, mongospec.In("number", []string{"A-1", "A-2", "A-3"}))
+	_, _ = repo.AggregateRaw(ctx, []bson.M{{"$match": bson.M{"number": "A-1"}}, {"$limit": 5}})
+
+	got := map[string]repository.ShapeStats{}
+	for _, s := range shapes.Snapshot() {
+		got[s.Op+" "+s.Shape] = s
+	}
+
+	want := map[string]uint64{
+		`find {"number":?,"total":{"$gt":?}} sort {"total":-1}`: 2,
+		`count {"number":{"$in":?}}`:                            1,
+		`aggregate [{"$match":{"number":?}},{"$limit":?}]`:      1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d shapes, got %v", len(want), got)
+	}
+	for key, count := range want {
+		s, ok := got[key]
+		if !ok {
+			t.Fatalf("missing shape %q in %v", key, got)
+		}
+		if s.Count != count || s.Errors != count || s.Collection != "invoices" {
+			t.Fatalf("unexpected stats for %q: %+v", key, s)
+		}
+	}
+}
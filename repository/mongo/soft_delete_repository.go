@@ -2,50 +2,92 @@ package mongorepo
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/dElCIoGio/mongox/document"
 	"github.com/dElCIoGio/mongox/repository"
 	mongospec "github.com/dElCIoGio/mongox/spec"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // SoftDeleteRepository extends MongoRepository with soft delete functionality.
 // Documents are marked as deleted instead of being removed from the database.
-// Find operations automatically exclude deleted documents unless FindWithDeleted is used.
+//
+// Reads (Find*, Count, FindPaginated, Aggregate, ExportCSV), updates
+// (UpdateOne, UpdateMany, UpsertOne, ReplaceOne, ChunkedUpdateMany, BulkWrite),
+// and the by-ID and chunked deletes automatically exclude deleted documents.
+// Use the *WithDeleted variants, or the embedded MongoRepository, to include
+// them. DeleteOne and DeleteMany remove documents permanently, as HardDelete does.
 type SoftDeleteRepository[T any] struct {
 	*MongoRepository[T]
 }
 
 // NewSoftDelete creates a new SoftDeleteRepository wrapping the given collection.
-func NewSoftDelete[T any](coll *mongo.Collection) *SoftDeleteRepository[T] {
+// Options are passed through to the underlying MongoRepository.
+func NewSoftDelete[T any](coll *mongo.Collection, opts ...Option) *SoftDeleteRepository[T] {
 	return &SoftDeleteRepository[T]{
-		MongoRepository: New[T](coll),
+		MongoRepository: New[T](coll, opts...),
 	}
 }
 
+// WithSoftDeleteField sets the field a SoftDeleteRepository stores the
+// deletion time in. The default is "deleted_at", the field of
+// document.SoftDeletable; set it for documents that use another name.
+// Collections cascaded to with WithCascade keep using deleted_at.
+//
+// Example:
+//
+//	repo := mongorepo.NewSoftDelete[Account](coll, mongorepo.WithSoftDeleteField("removed_at"))
+func WithSoftDeleteField(name string) Option {
+	return func(s *settings) { s.softDeleteField = name }
+}
+
+// deletedField returns the field holding the deletion time.
+func (s settings) deletedField() string {
+	if s.softDeleteField == "" {
+		return "deleted_at"
+	}
+	return s.softDeleteField
+}
+
 // notDeletedFilter returns a filter that excludes soft-deleted documents.
-func notDeletedFilter() bson.M {
-	return bson.M{"deleted_at": bson.M{"$exists": false}}
+func (r *SoftDeleteRepository[T]) notDeletedFilter() bson.M {
+	return bson.M{r.settings.deletedField(): bson.M{"$exists": false}}
+}
+
+// deletedFilter returns a filter that matches only soft-deleted documents.
+func (r *SoftDeleteRepository[T]) deletedFilter() bson.M {
+	return bson.M{r.settings.deletedField(): bson.M{"$exists": true}}
 }
 
 // combineWithNotDeleted combines the given filter with the not-deleted filter.
-func combineWithNotDeleted(filter any) any {
-	notDeleted := notDeletedFilter()
+func (r *SoftDeleteRepository[T]) combineWithNotDeleted(filter any) any {
+	return combineWith(filter, r.notDeletedFilter())
+}
+
+// combineWithDeleted combines the given filter with the deleted filter.
+func (r *SoftDeleteRepository[T]) combineWithDeleted(filter any) any {
+	return combineWith(filter, r.deletedFilter())
+}
 
+// combineWith combines the given filter with a soft-delete state filter.
+func combineWith(filter any, state bson.M) any {
 	if filter == nil {
-		return notDeleted
+		return state
 	}
 
 	// Handle Filter interface
 	if f, ok := filter.(mongospec.Filter); ok {
-		return bson.M{"$and": []bson.M{f.ToMongo(), notDeleted}}
+		return bson.M{"$and": []bson.M{f.ToMongo(), state}}
 	}
 
 	// Handle bson.M
 	if m, ok := filter.(bson.M); ok {
-		return bson.M{"$and": []bson.M{m, notDeleted}}
+		return bson.M{"$and": []bson.M{m, state}}
 	}
 
 	// Handle bson.D
@@ -54,21 +96,70 @@ func combineWithNotDeleted(filter any) any {
 		for _, e := range d {
 			filterM[e.Key] = e.Value
 		}
-		return bson.M{"$and": []bson.M{filterM, notDeleted}}
+		return bson.M{"$and": []bson.M{filterM, state}}
 	}
 
 	// Fallback: wrap in $and
-	return bson.M{"$and": []any{filter, notDeleted}}
+	return bson.M{"$and": []any{filter, state}}
 }
 
 // FindOne finds a single non-deleted document matching the filter.
 func (r *SoftDeleteRepository[T]) FindOne(ctx context.Context, filter any, opts ...repository.FindOption) (*T, error) {
-	return r.MongoRepository.FindOne(ctx, combineWithNotDeleted(filter), opts...)
+	return r.MongoRepository.FindOne(ctx, r.combineWithNotDeleted(filter), opts...)
 }
 
 // Find finds all non-deleted documents matching the filter.
 func (r *SoftDeleteRepository[T]) Find(ctx context.Context, filter any, opts ...repository.FindOption) ([]T, error) {
-	return r.MongoRepository.Find(ctx, combineWithNotDeleted(filter), opts...)
+	return r.MongoRepository.Find(ctx, r.combineWithNotDeleted(filter), opts...)
+}
+
+// FindInto finds all non-deleted documents matching the filter into *out, reusing its capacity.
+func (r *SoftDeleteRepository[T]) FindInto(ctx context.Context, filter any, out *[]T, opts ...repository.FindOption) error {
+	return r.MongoRepository.FindInto(ctx, r.combineWithNotDeleted(filter), out, opts...)
+}
+
+// FindEach calls fn for each non-deleted document matching the filter, streaming them.
+func (r *SoftDeleteRepository[T]) FindEach(ctx context.Context, filter any, fn func(doc *T) error, opts ...repository.FindOption) error {
+	return r.MongoRepository.FindEach(ctx, r.combineWithNotDeleted(filter), fn, opts...)
+}
+
+// FindIter returns an iterator over the non-deleted documents matching the filter.
+func (r *SoftDeleteRepository[T]) FindIter(ctx context.Context, filter any, opts ...repository.FindOption) (*Iter[T], error) {
+	return r.MongoRepository.FindIter(ctx, r.combineWithNotDeleted(filter), opts...)
+}
+
+// UpdateAndFetch updates the first non-deleted document matching the filter and returns it.
+func (r *SoftDeleteRepository[T]) UpdateAndFetch(ctx context.Context, filter any, update any) (*T, error) {
+	return r.MongoRepository.UpdateAndFetch(ctx, r.combineWithNotDeleted(filter), update)
+}
+
+// FindOneAndUpdate atomically updates the first non-deleted document matching the filter and returns it.
+func (r *SoftDeleteRepository[T]) FindOneAndUpdate(ctx context.Context, filter any, update any, opts ...ModifyOption) (*T, error) {
+	return r.MongoRepository.FindOneAndUpdate(ctx, r.combineWithNotDeleted(filter), update, opts...)
+}
+
+// FindOneAndReplace atomically replaces the first non-deleted document matching the filter and returns it.
+func (r *SoftDeleteRepository[T]) FindOneAndReplace(ctx context.Context, filter any, doc *T, opts ...ModifyOption) (*T, error) {
+	return r.MongoRepository.FindOneAndReplace(ctx, r.combineWithNotDeleted(filter), doc, opts...)
+}
+
+// FindByID finds the non-deleted document with the given ID.
+func (r *SoftDeleteRepository[T]) FindByID(ctx context.Context, id any, opts ...repository.FindOption) (*T, error) {
+	oid, err := ObjectID(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.findCached(ctx, cacheKey{id: oid, active: true}, r.combineWithNotDeleted(bson.M{"_id": oid}), opts)
+}
+
+// ExistsByID reports whether a non-deleted document with the given ID exists.
+func (r *SoftDeleteRepository[T]) ExistsByID(ctx context.Context, id any) (bool, error) {
+	f, err := idFilter(id)
+	if err != nil {
+		return false, err
+	}
+	n, err := r.Count(ctx, f)
+	return n > 0, err
 }
 
 // FindWithDeleted finds documents including soft-deleted ones.
@@ -84,32 +175,21 @@ func (r *SoftDeleteRepository[T]) FindOneWithDeleted(ctx context.Context, filter
 
 // FindDeleted finds only soft-deleted documents matching the filter.
 func (r *SoftDeleteRepository[T]) FindDeleted(ctx context.Context, filter any, opts ...repository.FindOption) ([]T, error) {
-	deletedFilter := bson.M{"deleted_at": bson.M{"$exists": true}}
-
-	if filter == nil {
-		return r.MongoRepository.Find(ctx, deletedFilter, opts...)
-	}
-
-	// Handle Filter interface
-	if f, ok := filter.(mongospec.Filter); ok {
-		return r.MongoRepository.Find(ctx, bson.M{"$and": []bson.M{f.ToMongo(), deletedFilter}}, opts...)
-	}
-
-	// Handle bson.M
-	if m, ok := filter.(bson.M); ok {
-		return r.MongoRepository.Find(ctx, bson.M{"$and": []bson.M{m, deletedFilter}}, opts...)
-	}
-
-	return r.MongoRepository.Find(ctx, bson.M{"$and": []any{filter, deletedFilter}}, opts...)
+	return r.MongoRepository.Find(ctx, r.combineWithDeleted(filter), opts...)
 }
 
-// SoftDelete marks documents matching the filter as deleted by setting deleted_at.
+// SoftDelete marks documents matching the filter as deleted by setting the deletion time.
 // Returns the number of documents that were soft deleted.
 func (r *SoftDeleteRepository[T]) SoftDelete(ctx context.Context, filter any) (int64, error) {
+	defer r.settings.cache.clear()
+	if len(r.settings.cascades) > 0 {
+		return r.softDeleteWithCascade(ctx, filter, 1, deletionTime())
+	}
+
 	// Only soft-delete non-deleted documents
-	f := combineWithNotDeleted(filter)
+	f := r.combineWithNotDeleted(filter)
 
-	update := bson.M{"$set": bson.M{"deleted_at": time.Now().UTC()}}
+	update := bson.M{"$set": bson.M{r.settings.deletedField(): time.Now().UTC()}}
 	matched, _, err := r.MongoRepository.UpdateOne(ctx, f, update)
 	return matched, err
 }
@@ -117,10 +197,15 @@ func (r *SoftDeleteRepository[T]) SoftDelete(ctx context.Context, filter any) (i
 // SoftDeleteMany marks all documents matching the filter as deleted.
 // Returns the number of documents that were soft deleted.
 func (r *SoftDeleteRepository[T]) SoftDeleteMany(ctx context.Context, filter any) (int64, error) {
+	defer r.settings.cache.clear()
+	if len(r.settings.cascades) > 0 {
+		return r.softDeleteWithCascade(ctx, filter, 0, deletionTime())
+	}
+
 	// Only soft-delete non-deleted documents
-	f := combineWithNotDeleted(filter)
+	f := r.combineWithNotDeleted(filter)
 
-	update := bson.M{"$set": bson.M{"deleted_at": time.Now().UTC()}}
+	update := bson.M{"$set": bson.M{r.settings.deletedField(): time.Now().UTC()}}
 
 	res, err := r.coll.UpdateMany(ctx, f, update)
 	if err != nil {
@@ -129,46 +214,115 @@ func (r *SoftDeleteRepository[T]) SoftDeleteMany(ctx context.Context, filter any
 	return res.ModifiedCount, nil
 }
 
-// Restore removes the deleted_at timestamp from documents matching the filter.
+// Restore removes the deletion time from documents matching the filter.
 // This restores soft-deleted documents.
 // Returns the number of documents that were restored.
 func (r *SoftDeleteRepository[T]) Restore(ctx context.Context, filter any) (int64, error) {
+	defer r.settings.cache.clear()
+	if len(r.settings.cascades) > 0 {
+		return r.restoreWithCascade(ctx, filter, 1)
+	}
+
 	// Only restore deleted documents
-	deletedFilter := bson.M{"deleted_at": bson.M{"$exists": true}}
-	var f any
+	f := r.combineWithDeleted(filter)
 
-	if filter == nil {
-		f = deletedFilter
-	} else if flt, ok := filter.(mongospec.Filter); ok {
-		f = bson.M{"$and": []bson.M{flt.ToMongo(), deletedFilter}}
-	} else if m, ok := filter.(bson.M); ok {
-		f = bson.M{"$and": []bson.M{m, deletedFilter}}
+	update := bson.M{"$unset": bson.M{r.settings.deletedField(): ""}}
+	matched, _, err := r.MongoRepository.UpdateOne(ctx, f, update)
+	return matched, err
+}
+
+// SoftDeleteDoc soft-deletes doc by its _id and, if it implements
+// document.SoftDeletableDoc (e.g. by embedding document.SoftDeletable), marks
+// the in-memory struct deleted with the stored time. Returns ErrNotFound if doc
+// is not stored or already deleted. Cascades apply as in SoftDelete.
+//
+// Example:
+//
+//	if err := repo.SoftDeleteDoc(ctx, post); err != nil {
+//	    return err
+//	}
+//	post.IsDeleted() // true
+func (r *SoftDeleteRepository[T]) SoftDeleteDoc(ctx context.Context, doc *T) error {
+	f, err := documentIDFilter(doc)
+	if err != nil {
+		return err
+	}
+	defer r.settings.cache.clear()
+
+	ts := deletionTime()
+	var n int64
+	if len(r.settings.cascades) > 0 {
+		n, err = r.softDeleteWithCascade(ctx, f, 1, ts)
 	} else {
-		f = bson.M{"$and": []any{filter, deletedFilter}}
+		update := bson.M{"$set": bson.M{r.settings.deletedField(): ts}}
+		n, _, err = r.MongoRepository.UpdateOne(ctx, r.combineWithNotDeleted(f), update)
+	}
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrNotFound
 	}
+	if d, ok := any(doc).(document.SoftDeletableDoc); ok {
+		d.MarkDeleted(ts)
+	}
+	return nil
+}
 
-	update := bson.M{"$unset": bson.M{"deleted_at": ""}}
-	matched, _, err := r.MongoRepository.UpdateOne(ctx, f, update)
-	return matched, err
+// RestoreDoc restores the soft-deleted doc by its _id and, if it implements
+// document.SoftDeletableDoc, clears its deletion time in memory. Returns
+// ErrNotFound if doc is not stored or not deleted.
+func (r *SoftDeleteRepository[T]) RestoreDoc(ctx context.Context, doc *T) error {
+	f, err := documentIDFilter(doc)
+	if err != nil {
+		return err
+	}
+	n, err := r.Restore(ctx, f)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repository.ErrNotFound
+	}
+	if d, ok := any(doc).(document.SoftDeletableDoc); ok {
+		d.Restore()
+	}
+	return nil
+}
+
+// deletionTime returns the current time at the millisecond precision BSON
+// stores, so in-memory documents match what was written.
+func deletionTime() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// documentIDFilter returns a filter matching doc by its encoded _id.
+func documentIDFilter[T any](doc *T) (bson.M, error) {
+	if doc == nil {
+		return nil, repository.ErrNilDocument
+	}
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	id, err := bson.Raw(raw).LookupErr("_id")
+	if err != nil {
+		return nil, fmt.Errorf("%w: document has no _id", ErrInvalidID)
+	}
+	return bson.M{"_id": id}, nil
 }
 
 // RestoreMany restores all soft-deleted documents matching the filter.
 // Returns the number of documents that were restored.
 func (r *SoftDeleteRepository[T]) RestoreMany(ctx context.Context, filter any) (int64, error) {
-	deletedFilter := bson.M{"deleted_at": bson.M{"$exists": true}}
-	var f any
-
-	if filter == nil {
-		f = deletedFilter
-	} else if flt, ok := filter.(mongospec.Filter); ok {
-		f = bson.M{"$and": []bson.M{flt.ToMongo(), deletedFilter}}
-	} else if m, ok := filter.(bson.M); ok {
-		f = bson.M{"$and": []bson.M{m, deletedFilter}}
-	} else {
-		f = bson.M{"$and": []any{filter, deletedFilter}}
+	defer r.settings.cache.clear()
+	if len(r.settings.cascades) > 0 {
+		return r.restoreWithCascade(ctx, filter, 0)
 	}
 
-	update := bson.M{"$unset": bson.M{"deleted_at": ""}}
+	f := r.combineWithDeleted(filter)
+
+	update := bson.M{"$unset": bson.M{r.settings.deletedField(): ""}}
 
 	res, err := r.coll.UpdateMany(ctx, f, update)
 	if err != nil {
@@ -186,6 +340,7 @@ func (r *SoftDeleteRepository[T]) HardDelete(ctx context.Context, filter any) (i
 // HardDeleteMany permanently removes all documents matching the filter.
 // Use with caution - this cannot be undone.
 func (r *SoftDeleteRepository[T]) HardDeleteMany(ctx context.Context, filter any) (int64, error) {
+	defer r.settings.cache.clear()
 	f, err := normalizeFilter(filter)
 	if err != nil {
 		return 0, err
@@ -201,18 +356,8 @@ func (r *SoftDeleteRepository[T]) HardDeleteMany(ctx context.Context, filter any
 // Purge permanently removes all soft-deleted documents matching the filter.
 // This is useful for cleaning up old deleted data.
 func (r *SoftDeleteRepository[T]) Purge(ctx context.Context, filter any) (int64, error) {
-	deletedFilter := bson.M{"deleted_at": bson.M{"$exists": true}}
-	var f any
-
-	if filter == nil {
-		f = deletedFilter
-	} else if flt, ok := filter.(mongospec.Filter); ok {
-		f = bson.M{"$and": []bson.M{flt.ToMongo(), deletedFilter}}
-	} else if m, ok := filter.(bson.M); ok {
-		f = bson.M{"$and": []bson.M{m, deletedFilter}}
-	} else {
-		f = bson.M{"$and": []any{filter, deletedFilter}}
-	}
+	defer r.settings.cache.clear()
+	f := r.combineWithDeleted(filter)
 
 	res, err := r.coll.DeleteMany(ctx, f)
 	if err != nil {
@@ -222,31 +367,196 @@ func (r *SoftDeleteRepository[T]) Purge(ctx context.Context, filter any) (int64,
 }
 
 // CountActive returns the count of non-deleted documents matching the filter.
+// It is equivalent to Count.
 func (r *SoftDeleteRepository[T]) CountActive(ctx context.Context, filter any) (int64, error) {
-	f := combineWithNotDeleted(filter)
+	return r.Count(ctx, filter)
+}
+
+// CountDeleted returns the count of soft-deleted documents matching the filter.
+func (r *SoftDeleteRepository[T]) CountDeleted(ctx context.Context, filter any) (int64, error) {
+	f := r.combineWithDeleted(filter)
+
+	return r.coll.CountDocuments(ctx, f)
+}
 
-	fNorm, err := normalizeFilter(f)
+// UpdateOne updates the first non-deleted document matching the filter.
+func (r *SoftDeleteRepository[T]) UpdateOne(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	return r.MongoRepository.UpdateOne(ctx, r.combineWithNotDeleted(filter), update, opts...)
+}
+
+// UpdateMany updates all non-deleted documents matching the filter.
+func (r *SoftDeleteRepository[T]) UpdateMany(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	return r.MongoRepository.UpdateMany(ctx, r.combineWithNotDeleted(filter), update, opts...)
+}
+
+// UpsertOne updates the first non-deleted document matching the filter or,
+// if none matches, inserts one. A matching deleted document is not restored;
+// a new document is inserted instead, which fails if a unique index covers the filter.
+func (r *SoftDeleteRepository[T]) UpsertOne(ctx context.Context, filter any, update any) (repository.UpsertResult, error) {
+	return r.MongoRepository.UpsertOne(ctx, r.combineWithNotDeleted(filter), update)
+}
+
+// UpdateByID applies update to the document with the given ID unless it is deleted.
+func (r *SoftDeleteRepository[T]) UpdateByID(ctx context.Context, id any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	f, err := idFilter(id)
+	if err != nil {
+		return 0, 0, err
+	}
+	return r.UpdateOne(ctx, f, update, opts...)
+}
+
+// UpdateOneStrict is like UpdateOne but returns ErrNotFound when no non-deleted document matches.
+func (r *SoftDeleteRepository[T]) UpdateOneStrict(ctx context.Context, filter any, update any) (matched int64, modified int64, err error) {
+	return r.MongoRepository.UpdateOneStrict(ctx, r.combineWithNotDeleted(filter), update)
+}
+
+// ReplaceOne replaces the first non-deleted document matching the filter.
+// A deleted document is never replaced, so it cannot be restored by accident.
+func (r *SoftDeleteRepository[T]) ReplaceOne(ctx context.Context, filter any, doc *T) (matched int64, modified int64, err error) {
+	return r.MongoRepository.ReplaceOne(ctx, r.combineWithNotDeleted(filter), doc)
+}
+
+// ReplaceOneStrict is like ReplaceOne but returns ErrNotFound when no non-deleted document matches.
+func (r *SoftDeleteRepository[T]) ReplaceOneStrict(ctx context.Context, filter any, doc *T) (matched int64, modified int64, err error) {
+	return r.MongoRepository.ReplaceOneStrict(ctx, r.combineWithNotDeleted(filter), doc)
+}
+
+// ReplaceOneWithDeleted replaces the first document matching the filter, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) ReplaceOneWithDeleted(ctx context.Context, filter any, doc *T) (matched int64, modified int64, err error) {
+	return r.MongoRepository.ReplaceOne(ctx, filter, doc)
+}
+
+// DeleteByID permanently removes the document with the given ID unless it is
+// soft-deleted. Use HardDelete to remove it either way.
+func (r *SoftDeleteRepository[T]) DeleteByID(ctx context.Context, id any) (int64, error) {
+	f, err := idFilter(id)
 	if err != nil {
 		return 0, err
 	}
+	return r.MongoRepository.DeleteOne(ctx, r.combineWithNotDeleted(f))
+}
 
-	return r.coll.CountDocuments(ctx, fNorm)
+// DeleteByIDs permanently removes the non-deleted documents with the given
+// ids. Use HardDeleteMany to remove them either way.
+func (r *SoftDeleteRepository[T]) DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	var total int64
+	for start := 0; start < len(ids); start += maxIDsPerDelete {
+		end := min(start+maxIDsPerDelete, len(ids))
+		n, err := r.MongoRepository.DeleteMany(ctx, r.combineWithNotDeleted(bson.M{"_id": bson.M{"$in": ids[start:end]}}))
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
 }
 
-// CountDeleted returns the count of soft-deleted documents matching the filter.
-func (r *SoftDeleteRepository[T]) CountDeleted(ctx context.Context, filter any) (int64, error) {
-	deletedFilter := bson.M{"deleted_at": bson.M{"$exists": true}}
-	var f any
+// UpdateOneWithDeleted updates the first document matching the filter, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) UpdateOneWithDeleted(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	return r.MongoRepository.UpdateOne(ctx, filter, update, opts...)
+}
 
-	if filter == nil {
-		f = deletedFilter
-	} else if flt, ok := filter.(mongospec.Filter); ok {
-		f = bson.M{"$and": []bson.M{flt.ToMongo(), deletedFilter}}
-	} else if m, ok := filter.(bson.M); ok {
-		f = bson.M{"$and": []bson.M{m, deletedFilter}}
-	} else {
-		f = bson.M{"$and": []any{filter, deletedFilter}}
+// UpdateManyWithDeleted updates all documents matching the filter, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) UpdateManyWithDeleted(ctx context.Context, filter any, update any, opts ...repository.UpdateOption) (matched int64, modified int64, err error) {
+	return r.MongoRepository.UpdateMany(ctx, filter, update, opts...)
+}
+
+// Count returns the number of non-deleted documents matching the filter.
+func (r *SoftDeleteRepository[T]) Count(ctx context.Context, filter any, opts ...repository.CountOption) (int64, error) {
+	return r.MongoRepository.Count(ctx, r.combineWithNotDeleted(filter), opts...)
+}
+
+// CountWithDeleted returns the number of documents matching the filter, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) CountWithDeleted(ctx context.Context, filter any, opts ...repository.CountOption) (int64, error) {
+	return r.MongoRepository.Count(ctx, filter, opts...)
+}
+
+// FindPaginated finds a page of non-deleted documents matching the filter.
+func (r *SoftDeleteRepository[T]) FindPaginated(ctx context.Context, filter any, page, perPage int, opts ...repository.FindOption) (*repository.Page[T], error) {
+	return r.MongoRepository.FindPaginated(ctx, r.combineWithNotDeleted(filter), page, perPage, opts...)
+}
+
+// FindPaginatedWithDeleted finds a page of documents matching the filter, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) FindPaginatedWithDeleted(ctx context.Context, filter any, page, perPage int, opts ...repository.FindOption) (*repository.Page[T], error) {
+	return r.MongoRepository.FindPaginated(ctx, filter, page, perPage, opts...)
+}
+
+// Aggregate runs the pipeline over non-deleted documents only, by adding a
+// $match stage at its start (after a leading $geoNear, $search, or
+// $vectorSearch stage, which must come first).
+func (r *SoftDeleteRepository[T]) Aggregate(ctx context.Context, pipeline any) ([]T, error) {
+	p, err := r.scopePipeline(pipeline)
+	if err != nil {
+		return nil, err
 	}
+	return r.MongoRepository.Aggregate(ctx, p)
+}
 
-	return r.coll.CountDocuments(ctx, f)
+// AggregateRaw runs the pipeline over non-deleted documents only, as Aggregate does.
+func (r *SoftDeleteRepository[T]) AggregateRaw(ctx context.Context, pipeline any) ([]bson.M, error) {
+	p, err := r.scopePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	return r.MongoRepository.AggregateRaw(ctx, p)
+}
+
+// CountPipeline counts the results of the pipeline over non-deleted documents only.
+func (r *SoftDeleteRepository[T]) CountPipeline(ctx context.Context, pipeline any) (int64, error) {
+	p, err := r.scopePipeline(pipeline)
+	if err != nil {
+		return 0, err
+	}
+	return r.MongoRepository.CountPipeline(ctx, p)
+}
+
+// AggregateWithDeleted runs the pipeline over all documents, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) AggregateWithDeleted(ctx context.Context, pipeline any) ([]T, error) {
+	return r.MongoRepository.Aggregate(ctx, pipeline)
+}
+
+// AggregateRawWithDeleted runs the pipeline over all documents, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) AggregateRawWithDeleted(ctx context.Context, pipeline any) ([]bson.M, error) {
+	return r.MongoRepository.AggregateRaw(ctx, pipeline)
+}
+
+// BulkWrite executes the operations, restricting update and replace
+// operations to non-deleted documents. Inserts are unchanged and delete
+// operations remove documents permanently.
+func (r *SoftDeleteRepository[T]) BulkWrite(ctx context.Context, ops []repository.BulkOp) (*repository.BulkWriteResult, error) {
+	scoped := make([]repository.BulkOp, len(ops))
+	for i, op := range ops {
+		if op.Type == repository.BulkOpUpdate || op.Type == repository.BulkOpReplace {
+			op.Filter = r.combineWithNotDeleted(op.Filter)
+		}
+		scoped[i] = op
+	}
+	return r.MongoRepository.BulkWrite(ctx, scoped)
+}
+
+// BulkWriteWithDeleted executes the operations on all documents, including soft-deleted ones.
+func (r *SoftDeleteRepository[T]) BulkWriteWithDeleted(ctx context.Context, ops []repository.BulkOp) (*repository.BulkWriteResult, error) {
+	return r.MongoRepository.BulkWrite(ctx, ops)
+}
+
+// scopePipeline adds a $match excluding soft-deleted documents to the start of
+// pipeline. Stages that must be first in a pipeline keep their place.
+func (r *SoftDeleteRepository[T]) scopePipeline(pipeline any) ([]bson.M, error) {
+	p, err := normalizePipeline(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	at := 0
+	if len(p) > 0 {
+		for _, first := range []string{"$geoNear", "$search", "$searchMeta", "$vectorSearch"} {
+			if _, ok := p[0][first]; ok {
+				at = 1
+				break
+			}
+		}
+	}
+	out := make([]bson.M, 0, len(p)+1)
+	out = append(out, p[:at]...)
+	out = append(out, bson.M{"$match": r.notDeletedFilter()})
+	return append(out, p[at:]...), nil
 }
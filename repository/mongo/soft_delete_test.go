@@ -0,0 +1,43 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type post struct {
+	document.Base          `bson:",inline"`
+	document.SoftDeletable `bson:",inline"`
+
+	Title string `bson:"title"`
+}
+
+func TestSoftDeleteDoc_RequiresID(t *testing.T) {
+	ctx := context.Background()
+
+	// Connect is lazy; invalid documents must be rejected before reaching the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	repo := mongorepo.NewSoftDelete[post](client.Database("testdb").Collection("posts"))
+	if err := repo.SoftDeleteDoc(ctx, nil); !errors.Is(err, repository.ErrNilDocument) {
+		t.Fatalf("expected ErrNilDocument, got %v", err)
+	}
+	if err := repo.SoftDeleteDoc(ctx, &post{Title: "unsaved"}); !errors.Is(err, mongorepo.ErrInvalidID) {
+		t.Fatalf("expected ErrInvalidID, got %v", err)
+	}
+	if err := repo.RestoreDoc(ctx, &post{Title: "unsaved"}); !errors.Is(err, mongorepo.ErrInvalidID) {
+		t.Fatalf("expected ErrInvalidID, got %v", err)
+	}
+}
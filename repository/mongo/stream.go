@@ -0,0 +1,139 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FindEach calls fn for each document matching the filter, streaming them from
+// the server instead of loading the whole result into memory. AfterLoad runs on
+// each document before fn sees it. Use repository.WithBatchSize to control how
+// many documents are fetched per round trip. A FindPolicy only supplies the
+// default sort; its MaxLimit does not apply.
+//
+// Iteration stops at the first error from fn, which FindEach returns.
+//
+// Example:
+//
+//	err := repo.FindEach(ctx, spec.Eq("status", "active"), func(u *User) error {
+//	    return mailer.Send(ctx, u.Email, digest)
+//	}, repository.WithBatchSize(500))
+func (r *MongoRepository[T]) FindEach(ctx context.Context, filter any, fn func(doc *T) error, opts ...repository.FindOption) (err error) {
+	defer r.track(repository.OpFind, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return err
+	}
+
+	it, err := r.openIter(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer it.Close(ctx)
+
+	for it.Next(ctx) {
+		var doc T
+		if err := it.Decode(&doc); err != nil {
+			return err
+		}
+		if err := fn(&doc); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// FindIter returns an iterator over the documents matching the filter,
+// streaming them from the server. Callers must Close it. As with FindEach,
+// the FindPolicy's MaxLimit does not apply.
+//
+// Example:
+//
+//	it, err := repo.FindIter(ctx, spec.Eq("status", "active"), repository.WithBatchSize(500))
+//	if err != nil {
+//	    return err
+//	}
+//	defer it.Close(ctx)
+//
+//	for it.Next(ctx) {
+//	    var u User
+//	    if err := it.Decode(&u); err != nil {
+//	        return err
+//	    }
+//	    process(u)
+//	}
+//	return it.Err()
+func (r *MongoRepository[T]) FindIter(ctx context.Context, filter any, opts ...repository.FindOption) (_ *Iter[T], err error) {
+	defer r.track(repository.OpFind, time.Now(), &err)
+	if err = r.settings.wait(ctx); err != nil {
+		return nil, err
+	}
+	return r.openIter(ctx, filter, opts)
+}
+
+func (r *MongoRepository[T]) openIter(ctx context.Context, filter any, opts []repository.FindOption) (_ *Iter[T], err error) {
+	fo := applyFindOptions(opts)
+	f, err := r.prepareScan(ctx, filter, &fo)
+	if err != nil {
+		return nil, err
+	}
+	defer r.observeShape(repository.OpFind, f, fo.Sort, time.Now(), &err)
+
+	cur, err := r.findCursor(ctx, f, fo)
+	if err != nil {
+		return nil, err
+	}
+	return &Iter[T]{ctx: ctx, cur: cur, s: r.settings}, nil
+}
+
+// Iter streams documents of type T from a cursor. AfterLoad hooks run with the
+// context the iterator was opened with. It is not safe for concurrent use.
+type Iter[T any] struct {
+	ctx context.Context
+	cur *mongo.Cursor
+	s   settings
+	err error
+}
+
+// Next advances to the next document, fetching another batch from the server
+// when needed. It returns false when the results are exhausted or an error
+// occurs; check Err afterwards.
+func (it *Iter[T]) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	return it.cur.Next(ctx)
+}
+
+// Decode decodes the current document into doc and runs its AfterLoad hook.
+// An error also stops the iteration and is returned by Err.
+func (it *Iter[T]) Decode(doc *T) error {
+	if doc == nil {
+		return repository.ErrNilDocument
+	}
+	if err := decodeCurrent[T](it.ctx, it.s, it.cur, doc); err != nil {
+		it.err = err
+		return err
+	}
+	if err := it.s.afterLoad(it.ctx, doc); err != nil {
+		it.err = err
+		return err
+	}
+	return nil
+}
+
+// Err returns the first error encountered while iterating, if any.
+func (it *Iter[T]) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return wrapTimeout(it.cur.Err())
+}
+
+// Close releases the cursor on the server. It is safe to call more than once.
+func (it *Iter[T]) Close(ctx context.Context) error {
+	return it.cur.Close(ctx)
+}
@@ -0,0 +1,53 @@
+package mongorepo
+
+import (
+	"context"
+)
+
+// ---- Strict variants ----
+//
+// The strict variants behave like their counterparts but return ErrNotFound when
+// no document matches the filter, giving handlers update-or-404 semantics without
+// checking the counts manually.
+
+// UpdateOneStrict is like UpdateOne but returns ErrNotFound when no document matches.
+// A matched document whose fields already hold the new values is not an error.
+//
+// Example:
+//
+//	_, _, err := repo.UpdateOneStrict(ctx, spec.Eq("_id", id), spec.Set("status", "archived"))
+//	if errors.Is(err, repository.ErrNotFound) {
+//	    http.Error(w, "not found", http.StatusNotFound)
+//	    return
+//	}
+func (r *MongoRepository[T]) UpdateOneStrict(ctx context.Context, filter any, update any) (matched int64, modified int64, err error) {
+	matched, modified, err = r.UpdateOne(ctx, filter, update)
+	if err == nil && matched == 0 {
+		return 0, 0, ErrNotFound
+	}
+	return matched, modified, err
+}
+
+// ReplaceOneStrict is like ReplaceOne but returns ErrNotFound when no document matches.
+func (r *MongoRepository[T]) ReplaceOneStrict(ctx context.Context, filter any, doc *T) (matched int64, modified int64, err error) {
+	matched, modified, err = r.ReplaceOne(ctx, filter, doc)
+	if err == nil && matched == 0 {
+		return 0, 0, ErrNotFound
+	}
+	return matched, modified, err
+}
+
+// DeleteOneStrict is like DeleteOne but returns ErrNotFound when no document matches.
+//
+// Example:
+//
+//	if _, err := repo.DeleteOneStrict(ctx, spec.Eq("_id", id)); err != nil {
+//	    return err // ErrNotFound if the document was already gone
+//	}
+func (r *MongoRepository[T]) DeleteOneStrict(ctx context.Context, filter any) (deleted int64, err error) {
+	deleted, err = r.DeleteOne(ctx, filter)
+	if err == nil && deleted == 0 {
+		return 0, ErrNotFound
+	}
+	return deleted, err
+}
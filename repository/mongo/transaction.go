@@ -3,6 +3,7 @@ package mongorepo
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/dElCIoGio/mongox/repository"
 
@@ -30,13 +31,25 @@ func NewTransactionManager(client *mongo.Client, opts *repository.TransactionOpt
 // The function receives a context that should be used for all database operations.
 // If the function returns an error, the transaction is aborted.
 // If the function returns nil, the transaction is committed.
+//
+// If ctx is already in a transaction, fn joins it and commit or abort is left to
+// the outer caller. If ctx carries a session without a transaction, the
+// transaction is started on that session instead of a new one.
 func (tm *MongoTransactionManager) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	// Start a session
-	session, err := tm.client.StartSession()
-	if err != nil {
-		return err
+	if InTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	// Reuse the session from the context, or start one
+	session := SessionFromContext(ctx)
+	if session == nil {
+		s, err := tm.client.StartSession()
+		if err != nil {
+			return err
+		}
+		defer s.EndSession(ctx)
+		session = s
 	}
-	defer session.EndSession(ctx)
 
 	// Build transaction options
 	txnOpts := options.Transaction()
@@ -64,7 +77,8 @@ func (tm *MongoTransactionManager) WithTransaction(ctx context.Context, fn func(
 			}
 
 			// Execute the user function
-			if err := fn(sessCtx); err != nil {
+			txCtx := context.WithValue(sessCtx, txInfoKey{}, TxInfo{Attempt: attempt + 1, StartedAt: time.Now()})
+			if err := fn(txCtx); err != nil {
 				// Abort on error
 				_ = session.AbortTransaction(sessCtx)
 				return err
@@ -0,0 +1,206 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dElCIoGio/mongox/document"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrUnindexedQuery is returned by repositories created with WithStrictQueries
+// for queries that no declared index can serve.
+var ErrUnindexedQuery = errors.New("mongorepo: query cannot use any declared index")
+
+// UnindexedQuery describes a query that no declared index can serve.
+type UnindexedQuery struct {
+	Collection string
+
+	// Op is the operation name (one of the repository.Op* constants).
+	Op string
+
+	// Shape describes the filter and sort without values, in the format of
+	// IndexSuggestion.Shape, e.g. "eq(status) range(total)".
+	Shape string
+}
+
+// UnindexedQueryHandler decides what happens to an unindexed query. Returning
+// an error aborts the query with that error; returning nil lets it run.
+type UnindexedQueryHandler func(ctx context.Context, q UnindexedQuery) error
+
+// WithStrictQueries checks every filter against the indexes declared by the
+// document type's Indexes method and catches queries that would scan the
+// whole collection. It is meant for development and tests, where an accidental
+// COLLSCAN should fail loudly before it reaches production data volumes.
+//
+// Behavior:
+//   - A query is indexed if a constrained field, or its first sort field, is
+//     the first key of a declared index or _id; each $or branch must be indexed
+//   - A $text filter is indexed if a text index is declared
+//   - Queries with an empty filter and no sort are allowed, as are filters
+//     that only exclude soft-deleted documents
+//   - Find, FindOne, FindInto, Count, and the update, replace, delete, and
+//     find-and-modify methods are checked; aggregations and bulk writes are not
+//   - With a nil handler, unindexed queries fail with ErrUnindexedQuery; pass a
+//     handler that logs and returns nil to only warn
+//
+// Example:
+//
+//	repo := mongorepo.New[Order](coll, mongorepo.WithStrictQueries(nil))
+//
+//	// Warn instead of failing:
+//	repo = mongorepo.New[Order](coll, mongorepo.WithStrictQueries(
+//	    func(ctx context.Context, q mongorepo.UnindexedQuery) error {
+//	        log.Printf("unindexed %s on %s: %s", q.Op, q.Collection, q.Shape)
+//	        return nil
+//	    },
+//	))
+func WithStrictQueries(onUnindexed UnindexedQueryHandler) Option {
+	return func(s *settings) { s.strict = &strictQueries{handler: onUnindexed} }
+}
+
+// strictQueries holds the first key of every declared index.
+type strictQueries struct {
+	handler  UnindexedQueryHandler
+	prefixes map[string]bool
+	text     bool // a text index is declared
+}
+
+// declareIndexes records the first key of each index declared by T, plus _id,
+// and whether T declares a text index.
+func (sq *strictQueries) declareIndexes(ptr any) {
+	sq.prefixes = map[string]bool{"_id": true}
+	ix, ok := ptr.(document.Indexed)
+	if !ok {
+		return
+	}
+	for _, idx := range ix.Indexes() {
+		for i, k := range idx.Keys {
+			switch {
+			case k.Value == "text":
+				// Text indexes only serve $text, not queries on their fields.
+				sq.text = true
+			case i == 0:
+				sq.prefixes[k.Key] = true
+			}
+		}
+	}
+}
+
+// checkQuery reports an unindexed query to the strict query handler. It is a
+// no-op unless the repository was created with WithStrictQueries.
+func (r *MongoRepository[T]) checkQuery(ctx context.Context, op string, filter, sortSpec any) error {
+	sq := r.settings.strict
+	if sq == nil {
+		return nil
+	}
+	doc, ok := encodeFilter(filter)
+	if !ok {
+		return nil
+	}
+	srt := sortKeys(sortSpec)
+	if len(srt) > 0 && sq.prefixes[srt[0].Key] {
+		return nil
+	}
+	if sq.indexed(doc) || len(srt) == 0 && unconstrained(doc, r.settings.deletedField()) {
+		return nil
+	}
+
+	q := UnindexedQuery{Collection: r.coll.Name(), Op: op}
+	if shape, ok := shapeOf(filter, sortSpec); ok {
+		q.Shape = shape.String()
+	}
+	if sq.handler == nil {
+		return fmt.Errorf("%w: %s on %s with %s", ErrUnindexedQuery, op, q.Collection, q.Shape)
+	}
+	return sq.handler(ctx, q)
+}
+
+// indexed reports whether a declared index can serve the filter.
+func (sq *strictQueries) indexed(doc bson.D) bool {
+	for _, e := range doc {
+		switch e.Key {
+		case "$and":
+			for _, c := range clausesOf(e.Value) {
+				if sq.indexed(c) {
+					return true
+				}
+			}
+		case "$text":
+			if sq.text {
+				return true
+			}
+		case "$or":
+			clauses := clausesOf(e.Value)
+			all := len(clauses) > 0
+			for _, c := range clauses {
+				all = all && sq.indexed(c)
+			}
+			if all {
+				return true
+			}
+		default:
+			if !strings.HasPrefix(e.Key, "$") && sq.prefixes[e.Key] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unconstrained reports whether the filter matches every document, apart from
+// excluding soft-deleted ones.
+func unconstrained(doc bson.D, deletedField string) bool {
+	for _, e := range doc {
+		switch {
+		case e.Key == "$and":
+			for _, c := range clausesOf(e.Value) {
+				if !unconstrained(c, deletedField) {
+					return false
+				}
+			}
+		case e.Key == deletedField:
+			ops, ok := e.Value.(bson.D)
+			if !ok || len(ops) != 1 || ops[0].Key != "$exists" || ops[0].Value != false {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// encodeFilter converts a normalized filter to a bson.D.
+func encodeFilter(filter any) (bson.D, bool) {
+	if filter == nil {
+		return bson.D{}, true
+	}
+	raw, err := bson.Marshal(filter)
+	if err != nil {
+		return nil, false
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, false
+	}
+	return doc, true
+}
+
+// clausesOf returns the document clauses of a $and or $or array.
+func clausesOf(v any) []bson.D {
+	arr, ok := v.(bson.A)
+	if !ok {
+		return nil
+	}
+	out := make([]bson.D, 0, len(arr))
+	for _, c := range arr {
+		if d, ok := c.(bson.D); ok {
+			out = append(out, d)
+		}
+	}
+	return out
+}
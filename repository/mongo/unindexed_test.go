@@ -0,0 +1,105 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ticket struct {
+	document.Base `bson:",inline"`
+
+	TenantID string `bson:"tenant_id"`
+	Status   string `bson:"status"`
+	Subject  string `bson:"subject"`
+}
+
+func (ticket) Indexes() []document.Index {
+	return []document.Index{
+		{Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+		{Keys: document.TextIndex("subject")},
+	}
+}
+
+func TestStrictQueries(t *testing.T) {
+	// Connect is lazy and ctx is canceled: unindexed queries fail with
+	// ErrUnindexedQuery before reaching the server, indexed ones with ctx's error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client, err := mongo.Connect(context.Background(), mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	coll := client.Database("testdb").Collection("tickets")
+
+	repo := mongorepo.New[ticket](coll, mongorepo.WithStrictQueries(nil))
+	soft := mongorepo.NewSoftDelete[ticket](coll, mongorepo.WithStrictQueries(nil))
+	refs := mongorepo.New[ticket](coll, mongorepo.WithStrictQueries(nil), mongorepo.WithReferences(
+		mongorepo.Reference{Collection: client.Database("testdb").Collection("replies"), ForeignKey: "ticket_id"},
+	))
+
+	tests := []struct {
+		name      string
+		run       func() error
+		unindexed bool
+	}{
+		{"index prefix", func() error { _, err := repo.Find(ctx, spec.Eq("tenant_id", "t1")); return err }, false},
+		{"_id", func() error { _, err := repo.DeleteOne(ctx, spec.Eq("_id", "x")); return err }, false},
+		{"empty filter", func() error { _, err := repo.Count(ctx, nil); return err }, false},
+		{"soft delete only", func() error { _, err := soft.Find(ctx, nil); return err }, false},
+		{"sort on index", func() error {
+			_, err := repo.Find(ctx, nil, repository.WithSort(bson.D{{Key: "status", Value: 1}}))
+			return err
+		}, false},
+		{"indexed $or", func() error {
+			_, err := repo.Find(ctx, spec.Or(spec.Eq("status", "open"), spec.Eq("tenant_id", "t1")))
+			return err
+		}, false},
+		{"text search", func() error {
+			_, err := repo.Find(ctx, spec.Text("refund"), repository.WithTextScoreSort())
+			return err
+		}, false},
+		{"non-prefix field", func() error { _, err := repo.Find(ctx, spec.Eq("created_at", 1)); return err }, true},
+		{"unindexed update", func() error {
+			_, _, err := repo.UpdateMany(ctx, spec.Eq("subject", "hi"), spec.Set("status", "closed"))
+			return err
+		}, true},
+		{"partly indexed $or", func() error {
+			_, err := repo.Count(ctx, spec.Or(spec.Eq("status", "open"), spec.Eq("subject", "hi")))
+			return err
+		}, true},
+		{"unindexed delete with references", func() error {
+			_, err := refs.DeleteMany(ctx, spec.Eq("subject", "hi"))
+			return err
+		}, true},
+		{"indexed delete with references", func() error { _, err := refs.DeleteOne(ctx, spec.Eq("_id", "x")); return err }, false},
+		{"soft delete unindexed", func() error { _, err := soft.Find(ctx, spec.Eq("subject", "hi")); return err }, true},
+	}
+	for _, tt := range tests {
+		err := tt.run()
+		if got := errors.Is(err, mongorepo.ErrUnindexedQuery); got != tt.unindexed {
+			t.Errorf("%s: err = %v, want unindexed=%v", tt.name, err, tt.unindexed)
+		}
+	}
+
+	var seen []mongorepo.UnindexedQuery
+	warn := mongorepo.New[ticket](coll, mongorepo.WithStrictQueries(func(_ context.Context, q mongorepo.UnindexedQuery) error {
+		seen = append(seen, q)
+		return nil
+	}))
+	_, _ = warn.Find(ctx, spec.Eq("subject", "hi"))
+	if len(seen) != 1 || seen[0].Op != repository.OpFind || seen[0].Collection != "tickets" || seen[0].Shape != "eq(subject)" {
+		t.Fatalf("unexpected warnings: %+v", seen)
+	}
+}
@@ -0,0 +1,80 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dElCIoGio/mongox/document"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ValidatorOption configures EnsureValidator.
+type ValidatorOption func(*validatorConfig)
+
+type validatorConfig struct {
+	level  string
+	action string
+}
+
+// ValidationModerate only validates inserts and updates of documents that are
+// already valid, so existing invalid documents can still be updated while
+// they are fixed. The default validates every insert and update.
+func ValidationModerate() ValidatorOption {
+	return func(c *validatorConfig) { c.level = "moderate" }
+}
+
+// ValidationWarn makes the server log invalid writes instead of rejecting
+// them, for rolling out a validator on a collection with unknown data.
+func ValidationWarn() ValidatorOption {
+	return func(c *validatorConfig) { c.action = "warn" }
+}
+
+// codeNamespaceExists is the server error code for creating an existing collection.
+const codeNamespaceExists = 48
+
+// EnsureValidator installs the $jsonSchema validator derived from T by
+// document.JSONSchema as the collection's validator, creating the collection
+// if it does not exist. Calling it again replaces the validator, so it can run
+// at every startup.
+//
+// Invalid writes fail with a WriteException whose error code is 121
+// (DocumentValidationFailure).
+//
+// Example:
+//
+//	repo := mongorepo.New[User](db.Collection("users"))
+//	if err := repo.EnsureValidator(ctx); err != nil {
+//	    return err
+//	}
+func (r *MongoRepository[T]) EnsureValidator(ctx context.Context, opts ...ValidatorOption) error {
+	validator, err := document.JSONSchema[T]()
+	if err != nil {
+		return err
+	}
+	cfg := validatorConfig{level: "strict", action: "error"}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+
+	db := r.coll.Database()
+	err = db.CreateCollection(ctx, r.coll.Name(), mopt.CreateCollection().
+		SetValidator(validator).
+		SetValidationLevel(cfg.level).
+		SetValidationAction(cfg.action))
+
+	var cmdErr mongo.CommandError
+	if err == nil || !errors.As(err, &cmdErr) || cmdErr.Code != codeNamespaceExists {
+		return err
+	}
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: r.coll.Name()},
+		{Key: "validator", Value: validator},
+		{Key: "validationLevel", Value: cfg.level},
+		{Key: "validationAction", Value: cfg.action},
+	}).Err()
+}
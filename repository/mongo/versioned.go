@@ -0,0 +1,118 @@
+package mongorepo
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/repository"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrVersionConflict is re-exported for convenience.
+var ErrVersionConflict = repository.ErrVersionConflict
+
+// ---- optimistic locking helpers ----
+//
+// Documents embedding document.Versioned carry a version that starts at 1 on
+// insert and is incremented by every update and replace. ReplaceOne, and
+// UpdateOne with repository.WithExpectedVersion, only write if the stored
+// version still matches, and report ErrVersionConflict otherwise.
+// FindOneAndReplace and BulkWrite replacements check the version the same way.
+
+// isVersioned reports whether T supports optimistic locking.
+func isVersioned[T any]() bool {
+	_, ok := any(new(T)).(document.VersionedDoc)
+	return ok
+}
+
+// withVersion restricts f to documents stored with version v. Version 0 also
+// matches documents written before the type was versioned.
+func withVersion(f any, v int64) bson.M {
+	var cond any = v
+	if v == 0 {
+		cond = bson.M{"$in": bson.A{int64(0), nil}}
+	}
+	return bson.M{"$and": bson.A{f, bson.M{document.VersionField: cond}}}
+}
+
+// versionable returns doc, or a pointer to a copy of it if it is a struct
+// value of a versioned type, whose version cannot be bumped in place.
+func versionable(doc any) any {
+	if _, ok := doc.(document.VersionedDoc); ok {
+		return doc
+	}
+	v := reflect.ValueOf(doc)
+	if v.Kind() != reflect.Struct {
+		return doc
+	}
+	p := reflect.New(v.Type())
+	if _, ok := p.Interface().(document.VersionedDoc); !ok {
+		return doc
+	}
+	p.Elem().Set(v)
+	return p.Interface()
+}
+
+// versionConflict is called when a version-checked write matched nothing. It
+// returns ErrVersionConflict if a document matches f without the version
+// check, and nil if there is no such document at all.
+func (r *MongoRepository[T]) versionConflict(ctx context.Context, f any) error {
+	n, err := r.coll.CountDocuments(ctx, f, mopt.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// injectVersionInc adds {$inc: {version: 1}} to an update document, or a stage
+// that increments the version to an update pipeline.
+func injectVersionInc(update any) any {
+	switch u := update.(type) {
+	case bson.M:
+		switch inc := u["$inc"].(type) {
+		case nil:
+			u["$inc"] = bson.M{document.VersionField: int64(1)}
+		case bson.M:
+			inc[document.VersionField] = int64(1)
+		case bson.D:
+			u["$inc"] = append(inc, bson.E{Key: document.VersionField, Value: int64(1)})
+		}
+		return u
+	case bson.D:
+		for i := range u {
+			if u[i].Key != "$inc" {
+				continue
+			}
+			switch inc := u[i].Value.(type) {
+			case bson.M:
+				inc[document.VersionField] = int64(1)
+			case bson.D:
+				u[i].Value = append(inc, bson.E{Key: document.VersionField, Value: int64(1)})
+			}
+			return u
+		}
+		return append(u, bson.E{Key: "$inc", Value: bson.M{document.VersionField: int64(1)}})
+	case mongo.Pipeline:
+		return append(u, bson.D{{Key: "$set", Value: versionIncExpr()}})
+	case []bson.M:
+		return append(u, bson.M{"$set": versionIncExpr()})
+	case []bson.D:
+		return append(u, bson.D{{Key: "$set", Value: versionIncExpr()}})
+	default:
+		return update
+	}
+}
+
+func versionIncExpr() bson.M {
+	return bson.M{document.VersionField: bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$" + document.VersionField, int64(0)}},
+		int64(1),
+	}}}
+}
@@ -0,0 +1,462 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Change event operation types delivered by Watch.
+const (
+	OpTypeInsert  = "insert"
+	OpTypeUpdate  = "update"
+	OpTypeReplace = "replace"
+	OpTypeDelete  = "delete"
+)
+
+// ErrResumeTokenLost is reported to the watch error handler when a change
+// stream could not resume because its resume token fell off the oplog. The
+// stream restarts from the current time, so events in between were missed.
+var ErrResumeTokenLost = errors.New("mongorepo: change stream resume token lost")
+
+// Server error codes for change streams that cannot be resumed.
+const (
+	codeChangeStreamHistoryLost = 286
+	codeChangeStreamFatalError  = 280
+)
+
+// ChangeEvent is a typed change stream event.
+type ChangeEvent[T any] struct {
+	// Operation is one of OpTypeInsert, OpTypeUpdate, OpTypeReplace, or OpTypeDelete.
+	Operation string
+
+	// Database and Collection identify the changed document's namespace.
+	Database   string
+	Collection string
+
+	// ID is the _id of the changed document.
+	ID any
+
+	// Document is the document after the change. It is nil for deletes and for
+	// updates of documents deleted before the lookup, or when update lookup is
+	// disabled with WithFullDocument.
+	Document *T
+
+	// UpdatedFields and RemovedFields describe update events.
+	UpdatedFields bson.M
+	RemovedFields []string
+
+	// ClusterTime is the time of the change's oplog entry.
+	ClusterTime primitive.Timestamp
+
+	// ResumeToken resumes a stream right after this event.
+	ResumeToken bson.Raw
+}
+
+// rawChangeEvent is the stored form of a change stream event.
+type rawChangeEvent struct {
+	ID            bson.Raw `bson:"_id"`
+	OperationType string   `bson:"operationType"`
+	NS            struct {
+		DB   string `bson:"db"`
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID any `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument      bson.Raw `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+	ClusterTime primitive.Timestamp `bson:"clusterTime"`
+}
+
+// ResumeTokenStore persists the resume token of a named change stream, so a
+// restarted process continues where it left off. Implementations must be safe
+// for concurrent use.
+type ResumeTokenStore interface {
+	// LoadResumeToken returns the token saved for stream, or nil if there is none.
+	LoadResumeToken(ctx context.Context, stream string) (bson.Raw, error)
+
+	// SaveResumeToken stores the token for stream, replacing any previous one.
+	SaveResumeToken(ctx context.Context, stream string, token bson.Raw) error
+}
+
+// WatchOption configures Watch and WatchDatabase.
+type WatchOption func(*watchConfig)
+
+type watchConfig struct {
+	filter     any
+	operations []string
+	fullDoc    mopt.FullDocument
+	store      ResumeTokenStore
+	stream     string
+	retry      time.Duration
+	onError    func(err error)
+}
+
+// WithWatchFilter delivers only events whose document matches filter, which
+// is written against the document's fields like a Find filter. Delete events
+// carry no document and are always delivered unless excluded with
+// WithOperations. Update events are matched against the looked-up document.
+func WithWatchFilter(filter any) WatchOption {
+	return func(c *watchConfig) { c.filter = filter }
+}
+
+// WithOperations delivers only events of the given operation types.
+func WithOperations(ops ...string) WatchOption {
+	return func(c *watchConfig) { c.operations = append(c.operations, ops...) }
+}
+
+// WithFullDocument sets how update events carry the document. Defaults to
+// mopt.UpdateLookup, which reads the current document for every update; use
+// mopt.Default to skip the read and rely on UpdatedFields.
+func WithFullDocument(mode mopt.FullDocument) WatchOption {
+	return func(c *watchConfig) { c.fullDoc = mode }
+}
+
+// WithResumeTokenStore resumes the stream from the token saved under stream
+// and saves the token of each event once the next event is received from the
+// channel, i.e. once the consumer has finished with it.
+func WithResumeTokenStore(store ResumeTokenStore, stream string) WatchOption {
+	return func(c *watchConfig) {
+		c.store = store
+		c.stream = stream
+	}
+}
+
+// WithWatchRetryInterval sets the pause before reopening a stream after an
+// error. Defaults to 1s.
+func WithWatchRetryInterval(d time.Duration) WatchOption {
+	return func(c *watchConfig) { c.retry = d }
+}
+
+// WithWatchErrorHandler registers a callback for errors after which the stream
+// is reopened, including ErrResumeTokenLost.
+func WithWatchErrorHandler(fn func(err error)) WatchOption {
+	return func(c *watchConfig) { c.onError = fn }
+}
+
+// Watch opens a change stream on the repository's collection and delivers
+// typed insert, update, replace, and delete events on the returned channel
+// until ctx is cancelled, when the channel is closed.
+//
+// Behavior:
+//   - The stream is reopened after errors, resuming after the last delivered event
+//   - With WithResumeTokenStore, a restarted process resumes after the last saved event
+//   - Drops and renames of the collection end the stream's events; it then waits for new ones
+//   - Change streams need a replica set or sharded cluster; opening one on a
+//     standalone server returns an error
+//
+// Example:
+//
+//	events, err := repo.Watch(ctx,
+//	    mongorepo.WithWatchFilter(spec.Eq("tenant_id", tenant)),
+//	    mongorepo.WithResumeTokenStore(mongorepo.NewResumeTokenStore(db.Collection("resume_tokens")), "user-cache"),
+//	)
+//	if err != nil {
+//	    return err
+//	}
+//	for ev := range events {
+//	    cache.Invalidate(ev.ID)
+//	}
+func (r *MongoRepository[T]) Watch(ctx context.Context, opts ...WatchOption) (<-chan ChangeEvent[T], error) {
+	return watch[T](ctx, r.settings, r.coll, opts)
+}
+
+// WatchDatabase is Watch for every collection of db, with untyped documents.
+//
+// Example:
+//
+//	events, err := mongorepo.WatchDatabase(ctx, db, mongorepo.WithOperations(mongorepo.OpTypeDelete))
+func WatchDatabase(ctx context.Context, db *mongo.Database, opts ...WatchOption) (<-chan ChangeEvent[bson.M], error) {
+	return watch[bson.M](ctx, settings{}, db, opts)
+}
+
+// watchable is implemented by *mongo.Collection and *mongo.Database.
+type watchable interface {
+	Watch(ctx context.Context, pipeline any, opts ...*mopt.ChangeStreamOptions) (*mongo.ChangeStream, error)
+}
+
+func watch[T any](ctx context.Context, s settings, target watchable, opts []WatchOption) (<-chan ChangeEvent[T], error) {
+	cfg := watchConfig{fullDoc: mopt.UpdateLookup, retry: time.Second}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.retry <= 0 {
+		cfg.retry = time.Second
+	}
+
+	pipeline, err := watchPipeline(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher[T]{settings: s, target: target, cfg: cfg, pipeline: pipeline}
+	if cfg.store != nil {
+		if w.resume, err = cfg.store.LoadResumeToken(ctx, cfg.stream); err != nil {
+			return nil, fmt.Errorf("mongorepo: load resume token: %w", err)
+		}
+	}
+
+	// Open the first stream synchronously so setup errors reach the caller.
+	cs, err := w.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan ChangeEvent[T])
+	go w.run(ctx, cs, out)
+	return out, nil
+}
+
+type watcher[T any] struct {
+	settings settings // decrypts the documents of events
+	target   watchable
+	cfg      watchConfig
+	pipeline mongo.Pipeline
+
+	resume  bson.Raw // token of the last delivered event
+	pending bson.Raw // token to save once the next event is received
+}
+
+func (w *watcher[T]) open(ctx context.Context) (*mongo.ChangeStream, error) {
+	opts := mopt.ChangeStream().SetFullDocument(w.cfg.fullDoc)
+	if w.resume != nil {
+		// StartAfter, unlike ResumeAfter, also resumes after an invalidate event.
+		opts.SetStartAfter(w.resume)
+	}
+	cs, err := w.target.Watch(ctx, w.pipeline, opts)
+	if err != nil && w.resume != nil && resumeTokenLost(err) {
+		w.reportError(fmt.Errorf("%w: %v", ErrResumeTokenLost, err))
+		w.resume = nil
+		return w.target.Watch(ctx, w.pipeline, mopt.ChangeStream().SetFullDocument(w.cfg.fullDoc))
+	}
+	return cs, err
+}
+
+func (w *watcher[T]) run(ctx context.Context, cs *mongo.ChangeStream, out chan<- ChangeEvent[T]) {
+	defer close(out)
+	for {
+		err := w.stream(ctx, cs, out)
+		_ = cs.Close(context.WithoutCancel(ctx))
+		if ctx.Err() != nil {
+			return
+		}
+		w.reportError(err)
+
+		for {
+			if sleepCtx(ctx, w.cfg.retry) != nil {
+				return
+			}
+			if cs, err = w.open(ctx); err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			w.reportError(err)
+		}
+	}
+}
+
+// stream delivers the events of cs until it fails or ctx is cancelled.
+func (w *watcher[T]) stream(ctx context.Context, cs *mongo.ChangeStream, out chan<- ChangeEvent[T]) error {
+	for cs.Next(ctx) {
+		var raw rawChangeEvent
+		if err := cs.Decode(&raw); err != nil {
+			return err
+		}
+		if !isDocumentOperation(raw.OperationType) {
+			// An invalidation closes the stream; the next open starts after it.
+			w.resume = raw.ID
+			return nil
+		}
+
+		ev, err := decodeChangeEvent[T](ctx, w.settings, raw)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- ev:
+		}
+
+		// The consumer came back for this event, so it is done with the
+		// previous one.
+		w.save(ctx, w.pending)
+		w.pending = raw.ID
+		w.resume = raw.ID
+	}
+	if err := cs.Err(); err != nil {
+		return err
+	}
+	return errors.New("mongorepo: change stream closed")
+}
+
+func (w *watcher[T]) save(ctx context.Context, token bson.Raw) {
+	if w.cfg.store == nil || token == nil {
+		return
+	}
+	if err := w.cfg.store.SaveResumeToken(ctx, w.cfg.stream, token); err != nil && ctx.Err() == nil {
+		w.reportError(fmt.Errorf("mongorepo: save resume token: %w", err))
+	}
+}
+
+func (w *watcher[T]) reportError(err error) {
+	if err != nil && w.cfg.onError != nil {
+		w.cfg.onError(err)
+	}
+}
+
+func decodeChangeEvent[T any](ctx context.Context, s settings, raw rawChangeEvent) (ChangeEvent[T], error) {
+	ev := ChangeEvent[T]{
+		Operation:     raw.OperationType,
+		Database:      raw.NS.DB,
+		Collection:    raw.NS.Coll,
+		ID:            raw.DocumentKey.ID,
+		UpdatedFields: raw.UpdateDescription.UpdatedFields,
+		RemovedFields: raw.UpdateDescription.RemovedFields,
+		ClusterTime:   raw.ClusterTime,
+		ResumeToken:   raw.ID,
+	}
+	if len(raw.FullDocument) > 0 {
+		var doc T
+		if err := open[T](ctx, s, raw.FullDocument, &doc); err != nil {
+			return ev, fmt.Errorf("mongorepo: decode change event document: %w", err)
+		}
+		ev.Document = &doc
+	}
+	return ev, nil
+}
+
+func isDocumentOperation(op string) bool {
+	switch op {
+	case OpTypeInsert, OpTypeUpdate, OpTypeReplace, OpTypeDelete:
+		return true
+	}
+	return false
+}
+
+// watchPipeline builds the $match stage for the configured filter and operations.
+func watchPipeline(cfg watchConfig) (mongo.Pipeline, error) {
+	ops := cfg.operations
+	if len(ops) == 0 {
+		ops = []string{OpTypeInsert, OpTypeUpdate, OpTypeReplace, OpTypeDelete}
+	}
+	// Invalidations are always let through so the stream can restart after them.
+	in := bson.A{"invalidate"}
+	for _, op := range ops {
+		in = append(in, op)
+	}
+	match := bson.D{{Key: "operationType", Value: bson.M{"$in": in}}}
+
+	if cfg.filter != nil {
+		f, err := normalizeFilter(cfg.filter)
+		if err != nil {
+			return nil, err
+		}
+		docFilter, err := prefixFilter(f, "fullDocument.")
+		if err != nil {
+			return nil, err
+		}
+		match = append(match, bson.E{Key: "$or", Value: bson.A{
+			docFilter,
+			bson.M{"operationType": bson.M{"$in": bson.A{OpTypeDelete, "invalidate"}}},
+		}})
+	}
+	return mongo.Pipeline{{{Key: "$match", Value: match}}}, nil
+}
+
+// prefixFilter rewrites the field paths of a query filter to start with prefix,
+// descending into $and, $or, and $nor.
+func prefixFilter(filter any, prefix string) (bson.D, error) {
+	b, err := bson.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	var d bson.D
+	if err := bson.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	return prefixFields(d, prefix), nil
+}
+
+func prefixFields(d bson.D, prefix string) bson.D {
+	out := make(bson.D, 0, len(d))
+	for _, e := range d {
+		switch {
+		case e.Key == "$and" || e.Key == "$or" || e.Key == "$nor":
+			if arr, ok := e.Value.(bson.A); ok {
+				clauses := make(bson.A, len(arr))
+				for i, c := range arr {
+					if cd, ok := c.(bson.D); ok {
+						clauses[i] = prefixFields(cd, prefix)
+					} else {
+						clauses[i] = c
+					}
+				}
+				e.Value = clauses
+			}
+		case !strings.HasPrefix(e.Key, "$"):
+			e.Key = prefix + e.Key
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func resumeTokenLost(err error) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	return cmdErr.Code == codeChangeStreamHistoryLost || cmdErr.Code == codeChangeStreamFatalError
+}
+
+// MongoResumeTokenStore stores resume tokens in a collection, one document per
+// stream keyed by stream name.
+type MongoResumeTokenStore struct {
+	coll *mongo.Collection
+}
+
+// NewResumeTokenStore creates a ResumeTokenStore backed by coll.
+func NewResumeTokenStore(coll *mongo.Collection) *MongoResumeTokenStore {
+	return &MongoResumeTokenStore{coll: coll}
+}
+
+// LoadResumeToken returns the token saved for stream, or nil if there is none.
+func (s *MongoResumeTokenStore) LoadResumeToken(ctx context.Context, stream string) (bson.Raw, error) {
+	var doc struct {
+		Token bson.Raw `bson:"token"`
+	}
+	err := s.coll.FindOne(ctx, bson.M{"_id": stream}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.Token, nil
+}
+
+// SaveResumeToken stores the token for stream.
+func (s *MongoResumeTokenStore) SaveResumeToken(ctx context.Context, stream string, token bson.Raw) error {
+	_, err := s.coll.UpdateOne(ctx,
+		bson.M{"_id": stream},
+		bson.M{"$set": bson.M{"token": token, "updated_at": nowUTC()}},
+		mopt.Update().SetUpsert(true),
+	)
+	return err
+}
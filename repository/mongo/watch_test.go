@@ -0,0 +1,47 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type failingTokenStore struct{ err error }
+
+func (s failingTokenStore) LoadResumeToken(context.Context, string) (bson.Raw, error) {
+	return nil, s.err
+}
+
+func (s failingTokenStore) SaveResumeToken(context.Context, string, bson.Raw) error {
+	return s.err
+}
+
+func TestWatch_ReportsSetupErrors(t *testing.T) {
+	ctx := context.Background()
+
+	// Nothing listens on this port, so opening a stream fails fast.
+	client, err := mongo.Connect(ctx, mopt.Client().
+		ApplyURI("mongodb://127.0.0.1:1").
+		SetServerSelectionTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	repo := mongorepo.New[invoiceRow](client.Database("testdb").Collection("invoices"))
+
+	errStore := errors.New("store unavailable")
+	if _, err := repo.Watch(ctx, mongorepo.WithResumeTokenStore(failingTokenStore{errStore}, "invoices")); !errors.Is(err, errStore) {
+		t.Fatalf("expected the resume token load error, got %v", err)
+	}
+	if events, err := repo.Watch(ctx); err == nil || events != nil {
+		t.Fatalf("expected an error opening a stream without a server, got %v", err)
+	}
+}
@@ -1,5 +1,11 @@
 package repository
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
 // FindOption is a functional option for configuring Find and FindOne operations.
 // Use the With* functions to create options.
 //
@@ -26,6 +32,32 @@ type FindOptions struct {
 	// Sort specifies the order in which to return documents.
 	// Typically bson.D for ordered sorting, e.g., bson.D{{"created_at", -1}}.
 	Sort any
+
+	// CapacityHint preallocates the result slice for the expected number of documents.
+	// A value of 0 lets the slice grow on demand.
+	CapacityHint int
+
+	// Projection limits the fields returned, as a bson.M or bson.D projection
+	// document. A nil Projection returns whole documents.
+	Projection any
+
+	// Comment is recorded with the operation in the server's logs and profiler.
+	Comment string
+
+	// BatchSize sets how many documents each round trip to the server returns.
+	// A value of 0 uses the server default. It matters most when streaming
+	// results with FindEach or FindIter.
+	BatchSize int32
+
+	// ReadPreference overrides the repository's read preference for this call:
+	// "primary", "primaryPreferred", "secondary", "secondaryPreferred", or
+	// "nearest". Empty keeps the repository's setting.
+	ReadPreference string
+
+	// ReadConcern overrides the repository's read concern level for this call:
+	// "local", "available", "majority", "linearizable", or "snapshot". Empty
+	// keeps the repository's setting.
+	ReadConcern string
 }
 
 // WithLimit creates an option that limits the number of documents returned.
@@ -68,6 +100,167 @@ func WithSort(sort any) FindOption {
 	return func(o *FindOptions) { o.Sort = sort }
 }
 
+// WithCapacityHint creates an option that preallocates room for n results.
+// Use it when the approximate result size is known to avoid repeated slice growth.
+// It does not limit the number of documents returned; combine it with WithLimit for that.
+//
+// Example:
+//
+//	// Batches are usually 500 documents
+//	WithCapacityHint(500)
+func WithCapacityHint(n int) FindOption {
+	return func(o *FindOptions) { o.CapacityHint = n }
+}
+
+// WithComment creates an option that attaches comment to the find, so it can
+// be identified in the server's logs, profiler output, and currentOp.
+//
+// Example:
+//
+//	WithComment("orders page: customer dashboard")
+func WithComment(comment string) FindOption {
+	return func(o *FindOptions) { o.Comment = comment }
+}
+
+// WithBatchSize creates an option that fetches n documents per round trip to
+// the server. Use it with FindEach or FindIter to bound memory use while
+// streaming large result sets.
+//
+// Example:
+//
+//	WithBatchSize(500)
+func WithBatchSize(n int32) FindOption {
+	return func(o *FindOptions) { o.BatchSize = n }
+}
+
+// WithReadPreference creates an option that reads from servers matching mode
+// for this call only, e.g. to send a heavy report to a secondary. Implementations
+// without replicas ignore it.
+//
+// Example:
+//
+//	WithReadPreference("secondaryPreferred")
+func WithReadPreference(mode string) FindOption {
+	return func(o *FindOptions) { o.ReadPreference = mode }
+}
+
+// WithReadConcern creates an option that reads with the given read concern
+// level for this call only. Implementations without replicas ignore it.
+//
+// Example:
+//
+//	WithReadConcern("majority") // only data acknowledged by a majority
+func WithReadConcern(level string) FindOption {
+	return func(o *FindOptions) { o.ReadConcern = level }
+}
+
+// projectionConverter is implemented by projection builders such as spec.Projection.
+type projectionConverter interface {
+	ToProjection() bson.D
+}
+
+// WithProjection creates an option that returns only some fields of each
+// document. The projection is a bson.M or bson.D projection document, or a
+// builder such as spec.Include or spec.Exclude. Fields left out are zero in
+// the decoded documents, so avoid writing projected documents back with
+// ReplaceOne.
+//
+// Example:
+//
+//	WithProjection(bson.M{"name": 1, "email": 1})
+//	WithProjection(spec.Exclude("history", "attachments"))
+func WithProjection(projection any) FindOption {
+	if p, ok := projection.(projectionConverter); ok {
+		projection = p.ToProjection()
+	}
+	return func(o *FindOptions) { o.Projection = projection }
+}
+
+// WithFields creates an option that returns only the given fields (and _id)
+// of each document. It is shorthand for WithProjection(spec.Include(fields...)).
+//
+// Example:
+//
+//	WithFields("name", "email")
+func WithFields(fields ...string) FindOption {
+	proj := make(bson.D, 0, len(fields))
+	for _, f := range fields {
+		proj = append(proj, bson.E{Key: f, Value: 1})
+	}
+	return func(o *FindOptions) { o.Projection = proj }
+}
+
+// WithTextScoreSort creates an option that sorts the results of a spec.Text
+// filter by relevance, best matches first. It replaces any sort set by
+// WithSort. Combine it with spec.IncludeTextScore to also return the score.
+//
+// Example:
+//
+//	repo.Find(ctx, spec.Text("coffee shop"), WithTextScoreSort(), WithLimit(20))
+func WithTextScoreSort() FindOption {
+	return func(o *FindOptions) {
+		o.Sort = bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}
+	}
+}
+
+// UpdateOption is a functional option for configuring UpdateOne and UpdateMany.
+//
+// Example:
+//
+//	_, _, err := repo.UpdateOne(ctx, filter, update, WithUpsert())
+type UpdateOption func(*UpdateOptions)
+
+// UpdateOptions contains the configuration for update operations.
+// This struct is populated by applying UpdateOption functions.
+type UpdateOptions struct {
+	// Upsert inserts a document built from the filter's equality conditions
+	// and the update when no document matches.
+	Upsert bool
+
+	// ExpectedVersion, when set, makes UpdateOne apply only if the document's
+	// version (see document.Versioned) still equals it.
+	ExpectedVersion *int64
+
+	// Comment is recorded with the operation in the server's logs and profiler.
+	Comment string
+}
+
+// WithUpsert creates an option that inserts a document when none matches the
+// filter. The inserted document is not counted as matched or modified; use
+// UpsertOne to learn its _id.
+//
+// Example:
+//
+//	repo.UpdateOne(ctx, spec.Eq("email", email), spec.Set("last_seen", now), WithUpsert())
+func WithUpsert() UpdateOption {
+	return func(o *UpdateOptions) { o.Upsert = true }
+}
+
+// WithUpdateComment creates an option that attaches comment to the update, so
+// it can be identified in the server's logs, profiler output, and currentOp.
+//
+// Example:
+//
+//	WithUpdateComment("nightly plan migration")
+func WithUpdateComment(comment string) UpdateOption {
+	return func(o *UpdateOptions) { o.Comment = comment }
+}
+
+// WithExpectedVersion creates an option that makes UpdateOne apply only if the
+// matched document still has the given version (see document.Versioned), and
+// return ErrVersionConflict if it was changed by someone else. Use the version
+// the client read, e.g. from an If-Match header.
+//
+// Example:
+//
+//	_, _, err := repo.UpdateOne(ctx, spec.Eq("_id", id), spec.Set("title", title), WithExpectedVersion(req.Version))
+//	if errors.Is(err, ErrVersionConflict) {
+//	    http.Error(w, "edited by someone else", http.StatusConflict)
+//	}
+func WithExpectedVersion(version int64) UpdateOption {
+	return func(o *UpdateOptions) { o.ExpectedVersion = &version }
+}
+
 // applyFindOptions applies all provided options to create a FindOptions struct.
 func applyFindOptions(opts []FindOption) FindOptions {
 	var o FindOptions
@@ -78,3 +271,57 @@ func applyFindOptions(opts []FindOption) FindOptions {
 	}
 	return o
 }
+
+// CountOption is a functional option for configuring Count operations.
+//
+// Example:
+//
+//	n, err := repo.Count(ctx, filter, WithCountLimit(1000), WithCountHint("status_1"))
+type CountOption func(*CountOptions)
+
+// CountOptions contains the configuration for Count operations.
+// This struct is populated by applying CountOption functions.
+type CountOptions struct {
+	// Hint is the index to count with, as an index name or key document.
+	Hint any
+
+	// Limit stops counting after this many documents. A value of 0 means no
+	// limit.
+	Limit int64
+
+	// MaxTime is the server-side time limit for this count, replacing the
+	// repository's default. A value of 0 keeps the default.
+	MaxTime time.Duration
+}
+
+// WithCountHint creates an option that makes Count use the given index, as an
+// index name or key document, when the query planner would pick a slower one.
+//
+// Example:
+//
+//	WithCountHint("tenant_id_1_status_1")
+//	WithCountHint(bson.D{{"tenant_id", 1}, {"status", 1}})
+func WithCountHint(hint any) CountOption {
+	return func(o *CountOptions) { o.Hint = hint }
+}
+
+// WithCountLimit creates an option that stops counting after n documents.
+// Use it when only a bound matters, e.g. to show "1000+ results".
+//
+// Example:
+//
+//	n, _ := repo.Count(ctx, filter, WithCountLimit(1001))
+//	if n > 1000 { ... }
+func WithCountLimit(n int64) CountOption {
+	return func(o *CountOptions) { o.Limit = n }
+}
+
+// WithCountMaxTime creates an option that limits how long the server spends
+// counting, replacing the repository's default for this call.
+//
+// Example:
+//
+//	WithCountMaxTime(500 * time.Millisecond)
+func WithCountMaxTime(d time.Duration) CountOption {
+	return func(o *CountOptions) { o.MaxTime = d }
+}
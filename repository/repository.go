@@ -13,13 +13,13 @@ type Repository[T any] interface {
 	InsertOne(ctx context.Context, doc *T) error
 	FindOne(ctx context.Context, filter any, opts ...FindOption) (*T, error)
 	Find(ctx context.Context, filter any, opts ...FindOption) ([]T, error)
-	UpdateOne(ctx context.Context, filter any, update any) (matched int64, modified int64, err error)
+	UpdateOne(ctx context.Context, filter any, update any, opts ...UpdateOption) (matched int64, modified int64, err error)
 	ReplaceOne(ctx context.Context, filter any, doc *T) (matched int64, modified int64, err error)
 	DeleteOne(ctx context.Context, filter any) (deleted int64, err error)
 
 	// Bulk operations
 	InsertMany(ctx context.Context, docs []*T) ([]primitive.ObjectID, error)
-	UpdateMany(ctx context.Context, filter any, update any) (matched int64, modified int64, err error)
+	UpdateMany(ctx context.Context, filter any, update any, opts ...UpdateOption) (matched int64, modified int64, err error)
 	DeleteMany(ctx context.Context, filter any) (deleted int64, err error)
 
 	// Aggregate executes an aggregation pipeline and returns the results.
@@ -31,7 +31,7 @@ type Repository[T any] interface {
 	AggregateRaw(ctx context.Context, pipeline any) ([]bson.M, error)
 
 	// Count returns the number of documents matching the filter.
-	Count(ctx context.Context, filter any) (int64, error)
+	Count(ctx context.Context, filter any, opts ...CountOption) (int64, error)
 }
 
 // BulkWriteResult contains the results of a bulk write operation.
@@ -43,3 +43,25 @@ type BulkWriteResult struct {
 	UpsertedCount int64
 	UpsertedIDs   map[int64]primitive.ObjectID
 }
+
+// UpsertResult reports the outcome of an upsert.
+type UpsertResult struct {
+	MatchedCount  int64
+	ModifiedCount int64
+	UpsertedCount int64
+
+	// UpsertedID is the _id of the inserted document. It is the zero ObjectID
+	// when an existing document matched or the _id is not an ObjectID.
+	UpsertedID primitive.ObjectID
+}
+
+// InsertManyResult reports which documents an unordered InsertMany inserted and
+// which were rejected as duplicates.
+type InsertManyResult struct {
+	// InsertedIDs maps the index of each inserted document to its ObjectID.
+	InsertedIDs map[int]primitive.ObjectID
+
+	// Duplicates lists, in ascending order, the indexes of the documents that
+	// violated a unique index and were not inserted.
+	Duplicates []int
+}
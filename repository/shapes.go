@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ShapeObserver receives the outcome of every repository operation together
+// with its normalized query shape: the filter, sort, or pipeline with every
+// literal value replaced by "?", so operations that differ only in their
+// arguments are grouped. Implementations must be safe for concurrent use.
+type ShapeObserver interface {
+	// ObserveShape is called after an operation completes with the collection
+	// name, operation name (one of the Op* constants), shape, duration, and
+	// resulting error (nil on success).
+	ObserveShape(collection, op, shape string, d time.Duration, err error)
+}
+
+// DefaultMaxShapes is the number of distinct shapes a ShapeRegistry tracks
+// unless WithMaxShapes is given. Operations with further shapes are counted
+// in ShapeRegistry.Dropped.
+const DefaultMaxShapes = 500
+
+// ShapeStats is a point-in-time copy of the statistics for one query shape.
+type ShapeStats struct {
+	Collection string
+	Op         string
+	Shape      string
+
+	HistogramSnapshot
+}
+
+// P50 returns the estimated median latency of the shape.
+func (s ShapeStats) P50() time.Duration { return s.Quantile(0.5) }
+
+// P95 returns the estimated 95th percentile latency of the shape.
+func (s ShapeStats) P95() time.Duration { return s.Quantile(0.95) }
+
+// ShapeRegistryOption configures a ShapeRegistry.
+type ShapeRegistryOption func(*ShapeRegistry)
+
+// WithShapeBuckets sets the latency bucket upper bounds used for every shape.
+// Defaults to DefaultLatencyBuckets.
+func WithShapeBuckets(bounds ...time.Duration) ShapeRegistryOption {
+	return func(r *ShapeRegistry) {
+		if len(bounds) > 0 {
+			r.bounds = bounds
+		}
+	}
+}
+
+// WithMaxShapes bounds the number of distinct shapes tracked. Defaults to
+// DefaultMaxShapes.
+func WithMaxShapes(n int) ShapeRegistryOption {
+	return func(r *ShapeRegistry) {
+		if n > 0 {
+			r.maxShapes = n
+		}
+	}
+}
+
+// WithSlowShapeAlert calls fn once per shape, the first time its P95 latency
+// exceeds threshold after at least minCount observations. fn runs on the
+// goroutine that completed the operation, outside the registry's lock; keep it
+// short or hand off to another goroutine.
+//
+// Example:
+//
+//	shapes := repository.NewShapeRegistry(
+//	    repository.WithSlowShapeAlert(200*time.Millisecond, 50, func(s repository.ShapeStats) {
+//	        log.Printf("slow query on %s: %s %s p95=%v", s.Collection, s.Op, s.Shape, s.P95())
+//	    }),
+//	)
+func WithSlowShapeAlert(threshold time.Duration, minCount uint64, fn func(ShapeStats)) ShapeRegistryOption {
+	return func(r *ShapeRegistry) {
+		r.alertAfter = threshold
+		r.alertMin = minCount
+		r.alert = fn
+	}
+}
+
+type shapeKey struct {
+	collection, op, shape string
+}
+
+type shapeEntry struct {
+	stats   ShapeStats
+	alerted bool
+}
+
+// ShapeRegistry is a ShapeObserver that aggregates latency per query shape, so
+// the slowest shapes of an application surface without manual instrumentation.
+// It is safe for concurrent use and serves its statistics as JSON over HTTP.
+//
+// Example:
+//
+//	shapes := repository.NewShapeRegistry()
+//	users := mongorepo.New[User](db.Collection("users"), mongorepo.WithShapeObserver(shapes))
+//	orders := mongorepo.New[Order](db.Collection("orders"), mongorepo.WithShapeObserver(shapes))
+//
+//	http.Handle("/debug/query-shapes", shapes)
+//	...
+//	for _, s := range shapes.Slowest(10) {
+//	    log.Printf("%s %s %s p50=%v p95=%v n=%d", s.Collection, s.Op, s.Shape, s.P50(), s.P95(), s.Count)
+//	}
+type ShapeRegistry struct {
+	bounds     []time.Duration
+	maxShapes  int
+	alertAfter time.Duration
+	alertMin   uint64
+	alert      func(ShapeStats)
+
+	mu      sync.Mutex
+	shapes  map[shapeKey]*shapeEntry
+	dropped uint64
+}
+
+// NewShapeRegistry creates an empty ShapeRegistry.
+func NewShapeRegistry(opts ...ShapeRegistryOption) *ShapeRegistry {
+	r := &ShapeRegistry{
+		bounds:    DefaultLatencyBuckets,
+		maxShapes: DefaultMaxShapes,
+		shapes:    make(map[shapeKey]*shapeEntry),
+	}
+	for _, fn := range opts {
+		if fn != nil {
+			fn(r)
+		}
+	}
+	r.bounds = append([]time.Duration(nil), r.bounds...)
+	sort.Slice(r.bounds, func(i, j int) bool { return r.bounds[i] < r.bounds[j] })
+	return r
+}
+
+// ObserveShape records an operation outcome.
+func (r *ShapeRegistry) ObserveShape(collection, op, shape string, d time.Duration, err error) {
+	idx := sort.Search(len(r.bounds), func(i int) bool { return d <= r.bounds[i] })
+	key := shapeKey{collection, op, shape}
+
+	r.mu.Lock()
+	e, ok := r.shapes[key]
+	if !ok {
+		if len(r.shapes) >= r.maxShapes {
+			r.dropped++
+			r.mu.Unlock()
+			return
+		}
+		e = &shapeEntry{stats: ShapeStats{
+			Collection: collection,
+			Op:         op,
+			Shape:      shape,
+			HistogramSnapshot: HistogramSnapshot{
+				Bounds: r.bounds,
+				Counts: make([]uint64, len(r.bounds)+1),
+			},
+		}}
+		r.shapes[key] = e
+	}
+	s := &e.stats
+	s.Counts[idx]++
+	s.Count++
+	s.Sum += d
+	if err != nil {
+		s.Errors++
+		if IsTimeout(err) {
+			s.Timeouts++
+		}
+	}
+
+	var fire *ShapeStats
+	if r.alert != nil && !e.alerted && s.Count >= r.alertMin && s.P95() > r.alertAfter {
+		e.alerted = true
+		cp := e.stats.clone()
+		fire = &cp
+	}
+	r.mu.Unlock()
+
+	if fire != nil {
+		r.alert(*fire)
+	}
+}
+
+// Snapshot returns a copy of the statistics for every observed shape, in no
+// particular order.
+func (r *ShapeRegistry) Snapshot() []ShapeStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]ShapeStats, 0, len(r.shapes))
+	for _, e := range r.shapes {
+		out = append(out, e.stats.clone())
+	}
+	return out
+}
+
+// Slowest returns up to n shapes ordered by descending P95 latency, breaking
+// ties by mean latency. A non-positive n returns every shape.
+func (r *ShapeRegistry) Slowest(n int) []ShapeStats {
+	out := r.Snapshot()
+	sort.Slice(out, func(i, j int) bool {
+		pi, pj := out[i].P95(), out[j].P95()
+		if pi != pj {
+			return pi > pj
+		}
+		return out[i].Mean() > out[j].Mean()
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Dropped returns the number of operations not recorded because the registry
+// already tracked the maximum number of shapes.
+func (r *ShapeRegistry) Dropped() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dropped
+}
+
+// Reset discards all recorded observations and re-arms slow-shape alerts.
+func (r *ShapeRegistry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shapes = make(map[shapeKey]*shapeEntry)
+	r.dropped = 0
+}
+
+// shapeJSON is the HTTP representation of a ShapeStats.
+type shapeJSON struct {
+	Collection string  `json:"collection"`
+	Op         string  `json:"op"`
+	Shape      string  `json:"shape"`
+	Count      uint64  `json:"count"`
+	Errors     uint64  `json:"errors"`
+	Timeouts   uint64  `json:"timeouts"`
+	MeanMS     float64 `json:"mean_ms"`
+	P50MS      float64 `json:"p50_ms"`
+	P95MS      float64 `json:"p95_ms"`
+}
+
+// ServeHTTP writes the slowest shapes as a JSON array. The optional "limit"
+// query parameter caps the number of shapes (default 20; 0 for all).
+func (r *ShapeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	limit := 20
+	if v := req.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	stats := r.Slowest(limit)
+	out := make([]shapeJSON, len(stats))
+	for i, s := range stats {
+		out[i] = shapeJSON{
+			Collection: s.Collection,
+			Op:         s.Op,
+			Shape:      s.Shape,
+			Count:      s.Count,
+			Errors:     s.Errors,
+			Timeouts:   s.Timeouts,
+			MeanMS:     millis(s.Mean()),
+			P50MS:      millis(s.P50()),
+			P95MS:      millis(s.P95()),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (s ShapeStats) clone() ShapeStats {
+	s.Counts = append([]uint64(nil), s.Counts...)
+	return s
+}
+
+func millis(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
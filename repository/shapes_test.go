@@ -0,0 +1,104 @@
+package repository_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/repository"
+)
+
+func TestShapeRegistry_AggregatesPerShape(t *testing.T) {
+	r := repository.NewShapeRegistry(repository.WithShapeBuckets(10*time.Millisecond, 100*time.Millisecond, time.Second))
+
+	for i := 0; i < 10; i++ {
+		r.ObserveShape("orders", repository.OpFind, `{"status":?}`, 5*time.Millisecond, nil)
+	}
+	for i := 0; i < 4; i++ {
+		r.ObserveShape("orders", repository.OpFind, `{"total":{"$gt":?}}`, 500*time.Millisecond, nil)
+	}
+	r.ObserveShape("users", repository.OpCount, `{}`, 50*time.Millisecond, nil)
+
+	slowest := r.Slowest(2)
+	if len(slowest) != 2 {
+		t.Fatalf("expected 2 shapes, got %d", len(slowest))
+	}
+	if slowest[0].Shape != `{"total":{"$gt":?}}` || slowest[0].Count != 4 || slowest[0].P95() != time.Second {
+		t.Fatalf("unexpected slowest shape: %+v", slowest[0])
+	}
+	if slowest[1].Collection != "users" || slowest[1].P50() != 100*time.Millisecond {
+		t.Fatalf("unexpected second shape: %+v", slowest[1])
+	}
+	if got := len(r.Snapshot()); got != 3 {
+		t.Fatalf("expected 3 shapes in snapshot, got %d", got)
+	}
+}
+
+func TestShapeRegistry_SlowShapeAlertFiresOnce(t *testing.T) {
+	var alerts []repository.ShapeStats
+	r := repository.NewShapeRegistry(
+		repository.WithSlowShapeAlert(100*time.Millisecond, 3, func(s repository.ShapeStats) {
+			alerts = append(alerts, s)
+		}),
+	)
+
+	for i := 0; i < 5; i++ {
+		r.ObserveShape("orders", repository.OpAggregate, `[{"$match":{"status":?}}]`, 2*time.Second, nil)
+		r.ObserveShape("orders", repository.OpFind, `{}`, time.Millisecond, nil)
+	}
+
+	if len(alerts) != 1 {
+		t.Fatalf("expected one alert, got %d", len(alerts))
+	}
+	if alerts[0].Op != repository.OpAggregate || alerts[0].Count != 3 {
+		t.Fatalf("expected the alert after the third slow aggregate, got %+v", alerts[0])
+	}
+
+	r.Reset()
+	r.ObserveShape("orders", repository.OpAggregate, `[{"$match":{"status":?}}]`, 2*time.Second, nil)
+	if len(alerts) != 1 {
+		t.Fatal("expected minCount to apply again after Reset")
+	}
+}
+
+func TestShapeRegistry_MaxShapes(t *testing.T) {
+	r := repository.NewShapeRegistry(repository.WithMaxShapes(1))
+	r.ObserveShape("c", repository.OpFind, "a", time.Millisecond, nil)
+	r.ObserveShape("c", repository.OpFind, "b", time.Millisecond, nil)
+	r.ObserveShape("c", repository.OpFind, "a", time.Millisecond, nil)
+
+	if got := r.Snapshot(); len(got) != 1 || got[0].Count != 2 {
+		t.Fatalf("expected only the first shape to be tracked, got %+v", got)
+	}
+	if r.Dropped() != 1 {
+		t.Fatalf("Dropped = %d, want 1", r.Dropped())
+	}
+}
+
+func TestShapeRegistry_ServeHTTP(t *testing.T) {
+	r := repository.NewShapeRegistry()
+	r.ObserveShape("orders", repository.OpFind, "fast", time.Millisecond, nil)
+	r.ObserveShape("orders", repository.OpFind, "slow", 400*time.Millisecond, nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/query-shapes?limit=1", nil))
+
+	var body []struct {
+		Shape string  `json:"shape"`
+		Count uint64  `json:"count"`
+		P95MS float64 `json:"p95_ms"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(body) != 1 || body[0].Shape != "slow" || body[0].P95MS != 500 {
+		t.Fatalf("unexpected response: %+v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/?limit=x", nil))
+	if rec.Code != 400 {
+		t.Fatalf("expected 400 for an invalid limit, got %d", rec.Code)
+	}
+}
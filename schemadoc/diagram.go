@@ -0,0 +1,183 @@
+package schemadoc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// entity is a collection drawn in a diagram. Collections that only appear as
+// referencing collections are drawn without fields.
+type entity struct {
+	name   string
+	fields []Field
+	keys   map[string]string // field path -> PK, FK, or UK
+}
+
+// relation is an edge from a referencing collection to a referenced one.
+type relation struct {
+	from, to string
+	Relation
+}
+
+// graph collects the entities and relations of the schema. Only top-level
+// fields are included.
+func (s Schema) graph() ([]*entity, []relation) {
+	var entities []*entity
+	byName := map[string]*entity{}
+	get := func(name string) *entity {
+		if e, ok := byName[name]; ok {
+			return e
+		}
+		e := &entity{name: name, keys: map[string]string{}}
+		byName[name] = e
+		entities = append(entities, e)
+		return e
+	}
+
+	for _, c := range s.Collections {
+		e := get(c.Name)
+		for _, f := range c.Fields {
+			if !strings.Contains(f.Path, ".") {
+				e.fields = append(e.fields, f)
+			}
+		}
+		e.keys["_id"] = "PK"
+		for _, idx := range c.Indexes {
+			if idx.Unique && len(idx.Keys) == 1 && e.keys[idx.Keys[0].Field] == "" {
+				e.keys[idx.Keys[0].Field] = "UK"
+			}
+		}
+	}
+
+	var rels []relation
+	for _, c := range s.Collections {
+		for _, ref := range c.References {
+			from := get(ref.Collection)
+			from.keys[ref.ForeignKey] = "FK"
+			rels = append(rels, relation{from: ref.Collection, to: c.Name, Relation: ref})
+		}
+	}
+	return entities, rels
+}
+
+type attribute struct {
+	typ, name, key string
+}
+
+// attributes returns the rows drawn for e: its top-level fields, or for a
+// collection that was not registered, its known keys with an unknown type.
+func (e *entity) attributes() []attribute {
+	var out []attribute
+	if len(e.fields) == 0 {
+		for _, name := range slices.Sorted(maps.Keys(e.keys)) {
+			out = append(out, attribute{typ: "any", name: name, key: e.keys[name]})
+		}
+		return out
+	}
+	for _, f := range e.fields {
+		out = append(out, attribute{typ: f.BSONType, name: f.Path, key: e.keys[f.Path]})
+	}
+	return out
+}
+
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// ident turns a collection or type name into a diagram identifier.
+func ident(s string) string {
+	return nonIdent.ReplaceAllString(s, "_")
+}
+
+// WriteMermaid writes the collections and their references as a Mermaid
+// erDiagram, which renders in GitHub and GitLab Markdown inside a
+// ```mermaid block. References are drawn from the referencing collection as
+// many-to-one, optional when the reference is set to null on delete.
+//
+// Example output:
+//
+//	erDiagram
+//	    users {
+//	        objectId _id PK
+//	        string email UK
+//	    }
+//	    orders }o--|| users : "user_id (restrict)"
+func (r *Registry) WriteMermaid(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	entities, rels := r.Schema().graph()
+
+	fmt.Fprintln(bw, "erDiagram")
+	for _, e := range entities {
+		fmt.Fprintf(bw, "    %s[\"%s\"] {\n", ident(e.name), e.name)
+		for _, a := range e.attributes() {
+			line := fmt.Sprintf("        %s %s", mermaidType(a.typ), ident(a.name))
+			if a.key != "" {
+				line += " " + a.key
+			}
+			fmt.Fprintln(bw, line)
+		}
+		fmt.Fprintln(bw, "    }")
+	}
+	for _, rel := range rels {
+		to := "||"
+		if rel.OnDelete == "set null" {
+			to = "o|"
+		}
+		fmt.Fprintf(bw, "    %s }o--%s %s : \"%s (%s)\"\n", ident(rel.from), to, ident(rel.to), rel.ForeignKey, rel.OnDelete)
+	}
+	return bw.Flush()
+}
+
+// mermaidType shortens a BSON type to the single word Mermaid accepts, e.g.
+// "array<string>" to "array".
+func mermaidType(t string) string {
+	if i := strings.IndexAny(t, "< /"); i > 0 {
+		t = t[:i]
+	}
+	return ident(t)
+}
+
+// WriteDOT writes the collections and their references as a Graphviz digraph
+// with one record node per collection. Render it with e.g.
+// `dot -Tsvg schema.dot -o schema.svg`.
+func (r *Registry) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	entities, rels := r.Schema().graph()
+
+	fmt.Fprintln(bw, "digraph schema {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=record, fontname=\"Helvetica\"];")
+	fmt.Fprintln(bw, "  edge [fontname=\"Helvetica\", fontsize=10];")
+	for _, e := range entities {
+		var rows []string
+		for _, a := range e.attributes() {
+			row := a.name + " : " + a.typ
+			if a.key != "" {
+				row += " (" + a.key + ")"
+			}
+			rows = append(rows, dotEscape(row))
+		}
+		label := dotEscape(e.name)
+		if len(rows) > 0 {
+			label += "|" + strings.Join(rows, `\l`) + `\l`
+		}
+		fmt.Fprintf(bw, "  %q [label=\"{%s}\"];\n", e.name, label)
+	}
+	for _, rel := range rels {
+		style := ""
+		if rel.OnDelete == "set null" {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(bw, "  %q -> %q [label=%q%s];\n", rel.from, rel.to, rel.ForeignKey+" ("+rel.OnDelete+")", style)
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotEscape escapes the characters that are special in record labels.
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "{", `\{`, "}", `\}`, "|", `\|`, "<", `\<`, ">", `\>`).Replace(s)
+}
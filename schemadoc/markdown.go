@@ -0,0 +1,109 @@
+package schemadoc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// WriteMarkdown writes the schema as a Markdown document with one section per
+// collection.
+func (r *Registry) WriteMarkdown(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	schema := r.Schema()
+
+	fmt.Fprintln(bw, "# Schema")
+	for _, c := range schema.Collections {
+		writeCollection(bw, c)
+	}
+	return bw.Flush()
+}
+
+func writeCollection(w io.Writer, c Collection) {
+	fmt.Fprintf(w, "\n## %s\n\n", c.Name)
+	fmt.Fprintf(w, "Document type `%s`.", c.Type)
+	if c.SoftDelete {
+		fmt.Fprint(w, " Soft-deletable.")
+	}
+	fmt.Fprintln(w)
+	if c.Description != "" {
+		fmt.Fprintf(w, "\n%s\n", c.Description)
+	}
+
+	fmt.Fprint(w, "\n### Fields\n\n| Field | BSON type | Go type | Notes |\n| --- | --- | --- | --- |\n")
+	for _, f := range c.Fields {
+		var notes []string
+		if f.OmitEmpty {
+			notes = append(notes, "omitted when empty")
+		}
+		if f.Description != "" {
+			notes = append(notes, f.Description)
+		}
+		fmt.Fprintf(w, "| `%s` | %s | `%s` | %s |\n", f.Path, cell(f.BSONType), f.GoType, cell(strings.Join(notes, "; ")))
+	}
+
+	if len(c.Indexes) > 0 {
+		fmt.Fprint(w, "\n### Indexes\n\n| Name | Keys | Options |\n| --- | --- | --- |\n")
+		for _, idx := range c.Indexes {
+			keys := make([]string, len(idx.Keys))
+			for i, k := range idx.Keys {
+				keys[i] = fmt.Sprintf("`%s`: %v", k.Field, k.Order)
+			}
+			var opts []string
+			if idx.Unique {
+				opts = append(opts, "unique")
+			}
+			if idx.Sparse {
+				opts = append(opts, "sparse")
+			}
+			if idx.TTL != "" {
+				opts = append(opts, "TTL "+idx.TTL)
+			}
+			if idx.Partial != nil {
+				opts = append(opts, "partial `"+extJSON(idx.Partial)+"`")
+			}
+			fmt.Fprintf(w, "| %s | %s | %s |\n", cell(idx.Name), strings.Join(keys, ", "), cell(strings.Join(opts, ", ")))
+		}
+	}
+
+	if len(c.Validation) > 0 {
+		fmt.Fprint(w, "\n### Validation\n\n")
+		for _, rule := range c.Validation {
+			if rule.Field != "" {
+				fmt.Fprintf(w, "- `%s`: %s\n", rule.Field, rule.Message)
+			} else {
+				fmt.Fprintf(w, "- %s\n", rule.Message)
+			}
+		}
+	}
+
+	if len(c.References) > 0 {
+		fmt.Fprint(w, "\n### Referenced by\n\n| Collection | Field | References | On delete |\n| --- | --- | --- | --- |\n")
+		for _, ref := range c.References {
+			fmt.Fprintf(w, "| %s | `%s` | `%s` | %s |\n", ref.Collection, ref.ForeignKey, ref.LocalKey, ref.OnDelete)
+		}
+	}
+
+	if len(c.Hooks) > 0 {
+		fmt.Fprintf(w, "\n### Hooks\n\n%s\n", strings.Join(c.Hooks, ", "))
+	}
+}
+
+// cell escapes text for a Markdown table cell, using "-" for empty cells.
+func cell(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+func extJSON(v bson.M) string {
+	b, err := bson.MarshalExtJSON(v, false, false)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
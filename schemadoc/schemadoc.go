@@ -0,0 +1,357 @@
+// Package schemadoc generates documentation of document types from the same
+// metadata the repositories use at runtime: bson struct tags, indexes declared
+// with document.Indexed, Validate rules, lifecycle hooks, soft delete, and
+// references declared with mongorepo.WithReferences.
+//
+// Register each document type with its collection, then write the schema as
+// Markdown for humans, JSON for tools, or an entity-relationship diagram of
+// the collections and their references in Mermaid or Graphviz format. A small program in the application
+// that registers its types can run as a go:generate step so the documentation
+// never drifts from the code.
+//
+// Field descriptions come from an optional `doc` struct tag. Validation rules
+// are the messages Validate reports for an empty document, i.e. the required
+// fields; rules that depend on values are not visible.
+//
+// Example:
+//
+//	reg := schemadoc.New()
+//	schemadoc.Register[User](reg, "users",
+//	    schemadoc.WithDescription("Registered accounts"),
+//	    schemadoc.WithReferences(userRefs...), // the refs passed to mongorepo.WithReferences
+//	)
+//	schemadoc.Register[Order](reg, "orders")
+//
+//	_ = reg.WriteMarkdown(os.Stdout)
+package schemadoc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Schema describes all registered collections.
+type Schema struct {
+	Collections []Collection `json:"collections"`
+}
+
+// Collection describes one collection and its document type.
+type Collection struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Description string     `json:"description,omitempty"`
+	Fields      []Field    `json:"fields"`
+	Indexes     []Index    `json:"indexes,omitempty"`
+	Validation  []Rule     `json:"validation,omitempty"`
+	Hooks       []string   `json:"hooks,omitempty"`
+	SoftDelete  bool       `json:"soft_delete,omitempty"`
+	References  []Relation `json:"references,omitempty"`
+}
+
+// Field describes one stored field. Fields of subdocuments and of arrays of
+// subdocuments are listed with their dotted path.
+type Field struct {
+	Path        string `json:"path"`
+	BSONType    string `json:"bson_type"`
+	GoType      string `json:"go_type"`
+	OmitEmpty   bool   `json:"omit_empty,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Index describes an index declared by the document type.
+type Index struct {
+	Name    string     `json:"name,omitempty"`
+	Keys    []IndexKey `json:"keys"`
+	Unique  bool       `json:"unique,omitempty"`
+	Sparse  bool       `json:"sparse,omitempty"`
+	TTL     string     `json:"ttl,omitempty"`
+	Partial bson.M     `json:"partial,omitempty"`
+}
+
+// IndexKey is one key of an index: 1, -1, or a special type such as "text".
+type IndexKey struct {
+	Field string `json:"field"`
+	Order any    `json:"order"`
+}
+
+// Rule is a validation message Validate reports for a field.
+type Rule struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Relation describes documents in another collection that reference this one.
+type Relation struct {
+	Collection string `json:"collection"`
+	ForeignKey string `json:"foreign_key"`
+	LocalKey   string `json:"local_key"`
+	OnDelete   string `json:"on_delete"`
+}
+
+// Option configures a registered collection.
+type Option func(*Collection)
+
+// WithDescription sets the collection's description.
+func WithDescription(text string) Option {
+	return func(c *Collection) { c.Description = text }
+}
+
+// WithReferences documents the references of the collection, as passed to
+// mongorepo.WithReferences.
+func WithReferences(refs ...mongorepo.Reference) Option {
+	return func(c *Collection) {
+		for _, ref := range refs {
+			rel := Relation{ForeignKey: ref.ForeignKey, LocalKey: ref.LocalKey, OnDelete: ref.OnDelete.String()}
+			if ref.Collection != nil {
+				rel.Collection = ref.Collection.Name()
+			}
+			if rel.LocalKey == "" {
+				rel.LocalKey = "_id"
+			}
+			c.References = append(c.References, rel)
+		}
+	}
+}
+
+// Registry collects document types to document. It is safe for concurrent use.
+type Registry struct {
+	mu          sync.Mutex
+	collections []Collection
+}
+
+// New returns an empty Registry.
+func New() *Registry {
+	return &Registry{}
+}
+
+// Register adds the document type T stored in the named collection.
+// Registering a collection again replaces it.
+func Register[T any](r *Registry, collection string, opts ...Option) {
+	c := describe[T](collection)
+	for _, o := range opts {
+		if o != nil {
+			o(&c)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.collections {
+		if r.collections[i].Name == collection {
+			r.collections[i] = c
+			return
+		}
+	}
+	r.collections = append(r.collections, c)
+}
+
+// Schema returns the registered collections in registration order.
+func (r *Registry) Schema() Schema {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Schema{Collections: append([]Collection(nil), r.collections...)}
+}
+
+// WriteJSON writes the schema as indented JSON.
+func (r *Registry) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Schema())
+}
+
+func describe[T any](collection string) Collection {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	c := Collection{Name: collection, Type: t.String()}
+	c.Fields = fieldsOf(t, "", map[reflect.Type]bool{})
+
+	var zero T
+	ptr := any(&zero)
+	if ix, ok := ptr.(document.Indexed); ok {
+		for _, idx := range ix.Indexes() {
+			c.Indexes = append(c.Indexes, indexOf(idx))
+		}
+	}
+	if _, ok := ptr.(document.BeforeSave); ok {
+		c.Hooks = append(c.Hooks, "BeforeSave")
+	}
+	if _, ok := ptr.(document.AfterLoad); ok {
+		c.Hooks = append(c.Hooks, "AfterLoad")
+	}
+	if v, ok := ptr.(document.Validatable); ok {
+		c.Hooks = append(c.Hooks, "Validate")
+		c.Validation = rulesOf(v)
+	}
+	_, c.SoftDelete = ptr.(document.SoftDeletableDoc)
+	return c
+}
+
+func indexOf(idx document.Index) Index {
+	out := Index{Name: idx.Name, Unique: idx.Unique, Sparse: idx.Sparse, Partial: idx.PartialFilterExpression}
+	for _, k := range idx.Keys {
+		out.Keys = append(out.Keys, IndexKey{Field: k.Key, Order: k.Value})
+	}
+	if idx.TTL != nil {
+		out.TTL = idx.TTL.String()
+	}
+	return out
+}
+
+// rulesOf returns the messages Validate reports for an empty document.
+func rulesOf(v document.Validatable) (rules []Rule) {
+	defer func() {
+		if recover() != nil {
+			rules = nil // Validate does not expect an empty document
+		}
+	}()
+
+	err := v.Validate()
+	var multi document.MultiValidationError
+	var single document.ValidationError
+	switch {
+	case err == nil:
+	case errors.As(err, &multi):
+		for _, e := range multi {
+			rules = append(rules, Rule{Field: e.Field, Message: e.Message})
+		}
+	case errors.As(err, &single):
+		rules = append(rules, Rule{Field: single.Field, Message: single.Message})
+	default:
+		rules = append(rules, Rule{Message: err.Error()})
+	}
+	return rules
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	objectIDType   = reflect.TypeOf(primitive.ObjectID{})
+	decimalType    = reflect.TypeOf(primitive.Decimal128{})
+	dateTimeType   = reflect.TypeOf(primitive.DateTime(0))
+	bsonDType      = reflect.TypeOf(bson.D{})
+	bsonRawType    = reflect.TypeOf(bson.Raw{})
+	emptyIfaceType = reflect.TypeOf((*any)(nil)).Elem()
+)
+
+// fieldsOf lists the stored fields of struct type t, following the driver's
+// naming rules: the bson tag name or the lowercased Go name, `bson:"-"` fields
+// skipped, and `bson:",inline"` structs flattened. seen stops recursive types.
+func fieldsOf(t reflect.Type, prefix string, seen map[reflect.Type]bool) []Field {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	var out []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("bson")
+		if tag == "-" || (!sf.IsExported() && !sf.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		opts = "," + opts + ","
+		if strings.Contains(opts, ",inline,") {
+			out = append(out, fieldsOf(sf.Type, prefix, seen)...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(sf.Name)
+		}
+
+		path := prefix + name
+		out = append(out, Field{
+			Path:        path,
+			BSONType:    bsonType(sf.Type),
+			GoType:      sf.Type.String(),
+			OmitEmpty:   strings.Contains(opts, ",omitempty,"),
+			Description: sf.Tag.Get("doc"),
+		})
+		if sub := subdocument(sf.Type); sub != nil {
+			out = append(out, fieldsOf(sub, path+".", seen)...)
+		}
+	}
+	return out
+}
+
+// subdocument returns the struct type stored as a subdocument (or array of
+// subdocuments) by a field of type t, or nil.
+func subdocument(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || leafType(t) != "" {
+		return nil
+	}
+	return t
+}
+
+func leafType(t reflect.Type) string {
+	switch t {
+	case timeType, dateTimeType:
+		return "date"
+	case objectIDType:
+		return "objectId"
+	case decimalType:
+		return "decimal"
+	case bsonDType, bsonRawType:
+		return "object"
+	case emptyIfaceType:
+		return "any"
+	}
+	return ""
+}
+
+// bsonType names the BSON type a Go type is stored as by default.
+func bsonType(t reflect.Type) string {
+	if s := leafType(t); s != "" {
+		return s
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return bsonType(t.Elem()) + " (nullable)"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return "int"
+	case reflect.Int:
+		return "int/long" // int32 when the value fits
+	case reflect.Int64, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return "long"
+	case reflect.Float32, reflect.Float64:
+		return "double"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "binData"
+		}
+		return "array<" + bsonType(t.Elem()) + ">"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Interface:
+		return "any"
+	default:
+		return fmt.Sprint(t.Kind())
+	}
+}
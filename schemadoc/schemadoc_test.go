@@ -0,0 +1,194 @@
+package schemadoc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	mongorepo "github.com/dElCIoGio/mongox/repository/mongo"
+	"github.com/dElCIoGio/mongox/schemadoc"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type Address struct {
+	City string `bson:"city"`
+}
+
+type User struct {
+	document.Base          `bson:",inline"`
+	document.SoftDeletable `bson:",inline"`
+
+	Email     string    `bson:"email" doc:"Login address"`
+	Age       int       `bson:"age,omitempty"`
+	Addresses []Address `bson:"addresses"`
+	Manager   *User     `bson:"manager,omitempty"`
+	Secret    string    `bson:"-"`
+}
+
+func (User) Indexes() []document.Index {
+	ttl := time.Hour
+	return []document.Index{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Unique: true, Name: "unique_email"},
+		{Keys: bson.D{{Key: "deleted_at", Value: 1}}, TTL: &ttl},
+	}
+}
+
+func (u *User) Validate() error {
+	var errs document.MultiValidationError
+	if u.Email == "" {
+		errs = append(errs, document.ValidationError{Field: "email", Message: "required"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (u *User) BeforeSave(context.Context) error { return nil }
+
+func TestRegister_DescribesDocumentType(t *testing.T) {
+	reg := schemadoc.New()
+	schemadoc.Register[User](reg, "users",
+		schemadoc.WithDescription("Registered accounts"),
+		schemadoc.WithReferences(mongorepo.Reference{
+			Collection: testutil.LazyCollection(t, "orders"), ForeignKey: "user_id", OnDelete: mongorepo.OnDeleteRestrict,
+		}),
+	)
+
+	schema := reg.Schema()
+	if len(schema.Collections) != 1 {
+		t.Fatalf("got %d collections, want 1", len(schema.Collections))
+	}
+	c := schema.Collections[0]
+
+	var paths []string
+	types := map[string]string{}
+	for _, f := range c.Fields {
+		paths = append(paths, f.Path)
+		types[f.Path] = f.BSONType
+	}
+	wantPaths := []string{"_id", "created_at", "updated_at", "deleted_at", "email", "age", "addresses", "addresses.city", "manager"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("field paths = %v, want %v", paths, wantPaths)
+	}
+	if types["_id"] != "objectId" || types["created_at"] != "date" || types["addresses"] != "array<object>" || types["manager"] != "object (nullable)" {
+		t.Fatalf("unexpected BSON types: %v", types)
+	}
+
+	if len(c.Indexes) != 2 || !c.Indexes[0].Unique || c.Indexes[1].TTL != "1h0m0s" {
+		t.Fatalf("unexpected indexes: %+v", c.Indexes)
+	}
+	if !reflect.DeepEqual(c.Validation, []schemadoc.Rule{{Field: "email", Message: "required"}}) {
+		t.Fatalf("unexpected validation rules: %+v", c.Validation)
+	}
+	if !reflect.DeepEqual(c.Hooks, []string{"BeforeSave", "Validate"}) || !c.SoftDelete {
+		t.Fatalf("unexpected hooks %v or soft delete %v", c.Hooks, c.SoftDelete)
+	}
+	want := schemadoc.Relation{Collection: "orders", ForeignKey: "user_id", LocalKey: "_id", OnDelete: "restrict"}
+	if len(c.References) != 1 || c.References[0] != want {
+		t.Fatalf("unexpected references: %+v", c.References)
+	}
+}
+
+func TestRegistry_WriteMarkdownAndJSON(t *testing.T) {
+	reg := schemadoc.New()
+	schemadoc.Register[Address](reg, "addresses")
+	schemadoc.Register[User](reg, "users")
+	schemadoc.Register[User](reg, "users", schemadoc.WithDescription("replaced"))
+
+	var md bytes.Buffer
+	if err := reg.WriteMarkdown(&md); err != nil {
+		t.Fatalf("WriteMarkdown: %v", err)
+	}
+	for _, want := range []string{
+		"## addresses",
+		"## users",
+		"replaced",
+		"| `email` | string | `string` | Login address |",
+		"| unique_email | `email`: 1 | unique |",
+		"- `email`: required",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("markdown is missing %q:\n%s", want, md.String())
+		}
+	}
+
+	var js bytes.Buffer
+	if err := reg.WriteJSON(&js); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var decoded schemadoc.Schema
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode JSON: %v", err)
+	}
+	if len(decoded.Collections) != 2 || decoded.Collections[1].Description != "replaced" {
+		t.Fatalf("unexpected JSON schema: %+v", decoded)
+	}
+}
+
+type Order struct {
+	document.Base `bson:",inline"`
+
+	UserID   string   `bson:"user_id"`
+	Reviewer *string  `bson:"reviewer_id"`
+	Tags     []string `bson:"tags"`
+}
+
+func TestRegistry_WriteDiagrams(t *testing.T) {
+	reg := schemadoc.New()
+	schemadoc.Register[User](reg, "users", schemadoc.WithReferences(
+		mongorepo.Reference{Collection: testutil.LazyCollection(t, "orders"), ForeignKey: "user_id", OnDelete: mongorepo.OnDeleteCascade},
+		mongorepo.Reference{Collection: testutil.LazyCollection(t, "orders"), ForeignKey: "reviewer_id", OnDelete: mongorepo.OnDeleteSetNull},
+		mongorepo.Reference{Collection: testutil.LazyCollection(t, "audit.log"), ForeignKey: "actor_id", OnDelete: mongorepo.OnDeleteRestrict},
+	))
+	schemadoc.Register[Order](reg, "orders")
+
+	var mermaid bytes.Buffer
+	if err := reg.WriteMermaid(&mermaid); err != nil {
+		t.Fatalf("WriteMermaid: %v", err)
+	}
+	for _, want := range []string{
+		"erDiagram\n",
+		`    users["users"] {`,
+		"        objectId _id PK\n",
+		"        string email UK\n",
+		"        array addresses\n",
+		"        string user_id FK\n",
+		`    audit_log["audit.log"] {`,
+		"        any actor_id FK\n",
+		`    orders }o--|| users : "user_id (cascade)"`,
+		`    orders }o--o| users : "reviewer_id (set null)"`,
+		`    audit_log }o--|| users : "actor_id (restrict)"`,
+	} {
+		if !strings.Contains(mermaid.String(), want) {
+			t.Errorf("mermaid is missing %q:\n%s", want, mermaid.String())
+		}
+	}
+	if strings.Contains(mermaid.String(), "addresses_city") {
+		t.Errorf("mermaid should only list top-level fields:\n%s", mermaid.String())
+	}
+
+	var dot bytes.Buffer
+	if err := reg.WriteDOT(&dot); err != nil {
+		t.Fatalf("WriteDOT: %v", err)
+	}
+	for _, want := range []string{
+		"digraph schema {\n",
+		`"users" [label="{users|_id : objectId (PK)\l`,
+		`addresses : array\<object\>\l`,
+		`"orders" -> "users" [label="user_id (cascade)"];`,
+		`"orders" -> "users" [label="reviewer_id (set null)", style=dashed];`,
+		`"audit.log" [label="{audit.log|actor_id : any (FK)\l}"];`,
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("dot is missing %q:\n%s", want, dot.String())
+		}
+	}
+}
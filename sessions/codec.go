@@ -0,0 +1,40 @@
+package sessions
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Codec encodes session values, together with the session's absolute deadline,
+// for storage. Its method set matches the scs Codec interface.
+type Codec interface {
+	Encode(deadline time.Time, values map[string]any) ([]byte, error)
+	Decode(data []byte) (deadline time.Time, values map[string]any, err error)
+}
+
+// BSONCodec is the default Codec. Values must be marshalable to BSON; they are
+// decoded as BSON types (e.g. int32 and bson.M), which Get converts back.
+type BSONCodec struct{}
+
+type bsonEnvelope struct {
+	Deadline time.Time `bson:"deadline"`
+	Values   bson.M    `bson:"values"`
+}
+
+// Encode implements Codec.
+func (BSONCodec) Encode(deadline time.Time, values map[string]any) ([]byte, error) {
+	return bson.Marshal(bsonEnvelope{Deadline: deadline, Values: values})
+}
+
+// Decode implements Codec.
+func (BSONCodec) Decode(data []byte) (time.Time, map[string]any, error) {
+	var env bsonEnvelope
+	if err := bson.Unmarshal(data, &env); err != nil {
+		return time.Time{}, nil, err
+	}
+	if env.Values == nil {
+		env.Values = bson.M{}
+	}
+	return env.Deadline, env.Values, nil
+}
@@ -0,0 +1,363 @@
+// Package sessions provides HTTP sessions stored in MongoDB.
+//
+// Store keeps encoded sessions in a collection with a TTL index, and can be
+// used on its own or as the store of another session library. Manager adds
+// net/http integration: its middleware loads the session named by a cookie,
+// exposes it to handlers through the request context, and saves it before the
+// response is written. Sessions expire after an idle timeout that is extended
+// by every request (rolling expiry), and never outlive an absolute lifetime.
+//
+// Example:
+//
+//	store := sessions.NewStore(db.Collection("sessions"))
+//	_ = store.EnsureIndexes(ctx)
+//	manager := sessions.NewManager(store, sessions.WithIdleTimeout(30*time.Minute))
+//
+//	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+//	    s := sessions.FromContext(r.Context())
+//	    s.RenewToken() // prevent session fixation
+//	    s.Put("user_id", user.ID.Hex())
+//	})
+//	mux.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {
+//	    userID := sessions.Get(sessions.FromContext(r.Context()), "user_id", "")
+//	    ...
+//	})
+//	http.ListenAndServe(":8080", manager.Middleware(mux))
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Option configures a Manager.
+type Option func(*config)
+
+type config struct {
+	cookieName string
+	cookie     func(*http.Cookie)
+	idle       time.Duration
+	lifetime   time.Duration
+	codec      Codec
+	onError    func(err error)
+}
+
+// WithCookieName sets the name of the session cookie. Defaults to "session".
+func WithCookieName(name string) Option {
+	return func(c *config) { c.cookieName = name }
+}
+
+// WithCookie registers a function that adjusts the session cookie before it is
+// sent, e.g. to set Domain, Path, or SameSite. Cookies are HttpOnly, Secure,
+// SameSite=Lax, and scoped to "/" by default.
+func WithCookie(fn func(*http.Cookie)) Option {
+	return func(c *config) { c.cookie = fn }
+}
+
+// WithIdleTimeout sets how long a session lasts without requests. Every
+// request extends it. Defaults to 0, which disables the idle timeout.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(c *config) { c.idle = d }
+}
+
+// WithLifetime sets the absolute lifetime of a session from its creation.
+// Defaults to 24h.
+func WithLifetime(d time.Duration) Option {
+	return func(c *config) { c.lifetime = d }
+}
+
+// WithCodec sets how session values are encoded. Defaults to BSONCodec.
+func WithCodec(codec Codec) Option {
+	return func(c *config) { c.codec = codec }
+}
+
+// WithErrorHandler registers a callback for store and codec errors. Loading
+// errors also fail the request with 500 Internal Server Error.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) { c.onError = fn }
+}
+
+// Manager loads and saves sessions for HTTP handlers.
+// It is safe for concurrent use.
+type Manager struct {
+	store *Store
+	cfg   config
+}
+
+// NewManager creates a Manager that keeps sessions in store.
+func NewManager(store *Store, opts ...Option) *Manager {
+	cfg := config{cookieName: "session", lifetime: 24 * time.Hour, codec: BSONCodec{}}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.cookieName == "" {
+		cfg.cookieName = "session"
+	}
+	if cfg.lifetime <= 0 {
+		cfg.lifetime = 24 * time.Hour
+	}
+	if cfg.codec == nil {
+		cfg.codec = BSONCodec{}
+	}
+	return &Manager{store: store, cfg: cfg}
+}
+
+// Session is the session of one request. Its methods are safe for concurrent use.
+type Session struct {
+	mu        sync.Mutex
+	token     string // empty for sessions not stored yet
+	oldToken  string // token to delete after RenewToken
+	deadline  time.Time
+	values    map[string]any
+	modified  bool
+	destroyed bool
+}
+
+type contextKey struct{}
+
+// FromContext returns the session of a request handled by Manager.Middleware,
+// or nil outside of it.
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(contextKey{}).(*Session)
+	return s
+}
+
+// Token returns the session's token, or "" for a new session not saved yet.
+func (s *Session) Token() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// Value returns the value stored under key.
+func (s *Session) Value(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Put stores value under key.
+func (s *Session) Put(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.modified = true
+}
+
+// Remove deletes key from the session.
+func (s *Session) Remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Destroy deletes the session from the store and expires its cookie. Values
+// put afterwards start a new session.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]any)
+	s.destroyed = true
+	s.modified = false
+}
+
+// RenewToken gives the session a new token, keeping its values, and deletes the
+// old one. Call it when privileges change, e.g. on login, to prevent session
+// fixation.
+func (s *Session) RenewToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.oldToken == "" {
+		s.oldToken = s.token
+	}
+	s.token = ""
+	s.modified = true
+}
+
+// Get returns the value stored under key converted to V, or def when the key is
+// missing or the value does not convert. Values decoded by BSONCodec come back
+// as BSON types; Get converts them, e.g. int32 to int or bson.M to a struct.
+func Get[V any](s *Session, key string, def V) V {
+	if s == nil {
+		return def
+	}
+	v, ok := s.Value(key)
+	if !ok {
+		return def
+	}
+	if typed, ok := v.(V); ok {
+		return typed
+	}
+	b, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		return def
+	}
+	var out struct {
+		V V `bson:"v"`
+	}
+	if err := bson.Unmarshal(b, &out); err != nil {
+		return def
+	}
+	return out.V
+}
+
+// Middleware loads the session of each request into its context and saves it,
+// if it changed or has an idle timeout to extend, before the response is written.
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := m.load(r)
+		if err != nil {
+			m.reportError(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		sw := &sessionWriter{ResponseWriter: w, save: func() { m.save(r.Context(), w, s) }}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), contextKey{}, s)))
+		sw.commit()
+	})
+}
+
+func (m *Manager) load(r *http.Request) (*Session, error) {
+	s := &Session{values: make(map[string]any)}
+	c, err := r.Cookie(m.cfg.cookieName)
+	if err != nil || c.Value == "" {
+		return s, nil
+	}
+	data, found, err := m.store.FindCtx(r.Context(), c.Value)
+	if err != nil || !found {
+		return s, err
+	}
+	deadline, values, err := m.cfg.codec.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if !deadline.After(time.Now()) {
+		return s, nil
+	}
+	s.token, s.deadline, s.values = c.Value, deadline, values
+	return s, nil
+}
+
+// save stores s and sets or clears its cookie.
+func (m *Manager) save(ctx context.Context, w http.ResponseWriter, s *Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.oldToken != "" {
+		if err := m.store.DeleteCtx(ctx, s.oldToken); err != nil {
+			m.reportError(err)
+		}
+		s.oldToken = ""
+	}
+	if s.destroyed {
+		stored := s.token != ""
+		if stored {
+			if err := m.store.DeleteCtx(ctx, s.token); err != nil {
+				m.reportError(err)
+			}
+		}
+		s.token, s.deadline, s.destroyed = "", time.Time{}, false
+		if !s.modified {
+			if stored {
+				http.SetCookie(w, m.cookie("", time.Unix(1, 0)))
+			}
+			return
+		}
+	}
+
+	// A session is stored once it has values, and touched on every request
+	// while the idle timeout is enabled.
+	if !s.modified && (s.token == "" || m.cfg.idle <= 0) {
+		return
+	}
+
+	now := time.Now()
+	if s.token == "" {
+		s.token = newToken()
+	}
+	if s.deadline.IsZero() {
+		s.deadline = now.Add(m.cfg.lifetime)
+	}
+	expiry := s.deadline
+	if m.cfg.idle > 0 && now.Add(m.cfg.idle).Before(expiry) {
+		expiry = now.Add(m.cfg.idle)
+	}
+
+	data, err := m.cfg.codec.Encode(s.deadline, s.values)
+	if err == nil {
+		err = m.store.CommitCtx(ctx, s.token, data, expiry)
+	}
+	if err != nil {
+		m.reportError(err)
+		return
+	}
+	http.SetCookie(w, m.cookie(s.token, expiry))
+}
+
+func (m *Manager) cookie(token string, expiry time.Time) *http.Cookie {
+	c := &http.Cookie{
+		Name:     m.cfg.cookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiry.UTC(),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if token == "" {
+		c.MaxAge = -1
+	}
+	if m.cfg.cookie != nil {
+		m.cfg.cookie(c)
+	}
+	return c
+}
+
+func (m *Manager) reportError(err error) {
+	if err != nil && m.cfg.onError != nil {
+		m.cfg.onError(err)
+	}
+}
+
+// sessionWriter saves the session before the first byte of the response, while
+// the cookie can still be set.
+type sessionWriter struct {
+	http.ResponseWriter
+	save func()
+	once sync.Once
+}
+
+func (w *sessionWriter) commit() { w.once.Do(w.save) }
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *sessionWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// newToken returns a random, URL-safe session token.
+func newToken() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
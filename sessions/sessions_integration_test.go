@@ -0,0 +1,154 @@
+//go:build integration
+
+package sessions_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestStore_FindCommitDelete(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	store := sessions.NewStore(client.Database("testdb").Collection("sessions"))
+	if err := store.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes: %v", err)
+	}
+
+	if err := store.CommitCtx(ctx, "live", []byte("a"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := store.CommitCtx(ctx, "expired", []byte("b"), time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if data, found, err := store.FindCtx(ctx, "live"); err != nil || !found || string(data) != "a" {
+		t.Fatalf("Find live = %q, %v, %v", data, found, err)
+	}
+	if _, found, err := store.FindCtx(ctx, "expired"); err != nil || found {
+		t.Fatalf("expected the expired session to be hidden, found=%v err=%v", found, err)
+	}
+	all, err := store.AllCtx(ctx)
+	if err != nil || len(all) != 1 {
+		t.Fatalf("All = %v, %v; want only the live session", all, err)
+	}
+	if err := store.DeleteCtx(ctx, "live"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, found, _ := store.FindCtx(ctx, "live"); found {
+		t.Fatal("expected the deleted session to be gone")
+	}
+}
+
+func TestManager_LoginVisitLogout(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	coll := client.Database("testdb").Collection("sessions")
+	m := sessions.NewManager(sessions.NewStore(coll), sessions.WithIdleTimeout(time.Minute))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		s := sessions.FromContext(r.Context())
+		s.RenewToken()
+		s.Put("user_id", "u1")
+		s.Put("visits", 0)
+	})
+	mux.HandleFunc("/visit", func(w http.ResponseWriter, r *http.Request) {
+		s := sessions.FromContext(r.Context())
+		s.Put("visits", sessions.Get(s, "visits", 0)+1)
+		_, _ = w.Write([]byte(sessions.Get(s, "user_id", "anonymous")))
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		sessions.FromContext(r.Context()).Destroy()
+	})
+	handler := m.Middleware(mux)
+
+	do := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	cookieOf := func(rec *httptest.ResponseRecorder) *http.Cookie {
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == "session" {
+				return c
+			}
+		}
+		return nil
+	}
+
+	login := cookieOf(do("/login", nil))
+	if login == nil || login.Value == "" || !login.HttpOnly {
+		t.Fatalf("expected a session cookie after login, got %+v", login)
+	}
+
+	rec := do("/visit", login)
+	if rec.Body.String() != "u1" {
+		t.Fatalf("expected the logged-in user, got %q", rec.Body.String())
+	}
+	visit := cookieOf(rec)
+	if visit == nil || visit.Value != login.Value || !visit.Expires.After(time.Now()) {
+		t.Fatalf("expected the rolling expiry to refresh the cookie, got %+v", visit)
+	}
+
+	var doc struct {
+		Data []byte `bson:"data"`
+	}
+	if err := coll.FindOne(ctx, bson.M{"_id": login.Value}).Decode(&doc); err != nil {
+		t.Fatalf("load stored session: %v", err)
+	}
+	_, values, err := sessions.BSONCodec{}.Decode(doc.Data)
+	if err != nil || values["visits"] != int32(1) {
+		t.Fatalf("expected visits=1 stored, got %v (%v)", values, err)
+	}
+
+	logout := cookieOf(do("/logout", login))
+	if logout == nil || logout.MaxAge >= 0 {
+		t.Fatalf("expected logout to expire the cookie, got %+v", logout)
+	}
+	if rec := do("/visit", login); rec.Body.String() != "anonymous" {
+		t.Fatalf("expected the destroyed session to be gone, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,85 @@
+package sessions_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	"github.com/dElCIoGio/mongox/sessions"
+)
+
+type profile struct {
+	Name   string `bson:"name"`
+	Visits int    `bson:"visits"`
+}
+
+func TestGet_ConvertsDecodedValues(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	data, err := sessions.BSONCodec{}.Encode(deadline, map[string]any{
+		"count":   3,
+		"profile": profile{Name: "Ada", Visits: 2},
+		"user_id": "u1",
+	})
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	gotDeadline, values, err := sessions.BSONCodec{}.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !gotDeadline.Equal(deadline) {
+		t.Fatalf("deadline = %v, want %v", gotDeadline, deadline)
+	}
+
+	if got := sessions.Get[int](nil, "count", -1); got != -1 {
+		t.Fatalf("Get on a nil session = %d, want the default", got)
+	}
+
+	m := sessions.NewManager(sessions.NewStore(testutil.LazyCollection(t, "sessions")))
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := sessions.FromContext(r.Context())
+		for k, v := range values {
+			s.Put(k, v)
+		}
+		if got := sessions.Get(s, "count", 0); got != 3 {
+			t.Errorf("Get count = %d, want 3", got)
+		}
+		if got := sessions.Get(s, "profile", profile{}); got != (profile{Name: "Ada", Visits: 2}) {
+			t.Errorf("Get profile = %+v", got)
+		}
+		if got := sessions.Get(s, "user_id", 0); got != 0 {
+			t.Errorf("Get of a string as int = %d, want the default", got)
+		}
+		if got := sessions.Get(s, "missing", "none"); got != "none" {
+			t.Errorf("Get missing = %q, want the default", got)
+		}
+		// Destroying the unsaved session keeps the store untouched.
+		s.Destroy()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestMiddleware_UnmodifiedSessionSetsNoCookie(t *testing.T) {
+	m := sessions.NewManager(sessions.NewStore(testutil.LazyCollection(t, "sessions")), sessions.WithIdleTimeout(time.Minute))
+
+	var token string
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = sessions.FromContext(r.Context()).Token()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if token != "" {
+		t.Fatalf("expected a new session without a token, got %q", token)
+	}
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Set-Cookie") != "" {
+		t.Fatalf("expected no cookie for an empty session, got %d %q", rec.Code, rec.Header().Get("Set-Cookie"))
+	}
+	if sessions.FromContext(context.Background()) != nil {
+		t.Fatal("expected no session outside the middleware")
+	}
+}
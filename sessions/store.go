@@ -0,0 +1,112 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Store keeps encoded sessions in a collection, one document per token:
+// {_id: token, data: <bytes>, expires_at: <date>}.
+//
+// Its Find, Commit, Delete, and All methods (and their Ctx variants) match the
+// store interfaces of github.com/alexedwards/scs, so a Store can also back an
+// scs session manager. It is safe for concurrent use.
+type Store struct {
+	coll *mongo.Collection
+	now  func() time.Time
+}
+
+// NewStore creates a Store backed by coll. Call EnsureIndexes once so expired
+// sessions are removed by the server.
+func NewStore(coll *mongo.Collection) *Store {
+	return &Store{coll: coll, now: time.Now}
+}
+
+// EnsureIndexes creates the TTL index that deletes sessions once they expire.
+// Expired sessions are never returned, even before the server removes them.
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	_, err := s.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: mopt.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+type sessionDoc struct {
+	Token     string    `bson:"_id"`
+	Data      []byte    `bson:"data"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// FindCtx returns the data of the session token. found is false if the session
+// does not exist or has expired.
+func (s *Store) FindCtx(ctx context.Context, token string) (data []byte, found bool, err error) {
+	var doc sessionDoc
+	err = s.coll.FindOne(ctx, bson.M{"_id": token, "expires_at": bson.M{"$gt": s.now()}}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return doc.Data, true, nil
+}
+
+// CommitCtx stores data for the session token until expiry, replacing any
+// previous data.
+func (s *Store) CommitCtx(ctx context.Context, token string, data []byte, expiry time.Time) error {
+	_, err := s.coll.ReplaceOne(ctx,
+		bson.M{"_id": token},
+		sessionDoc{Token: token, Data: data, ExpiresAt: expiry.UTC()},
+		mopt.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// DeleteCtx removes the session token. Deleting a missing session is not an error.
+func (s *Store) DeleteCtx(ctx context.Context, token string) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": token})
+	return err
+}
+
+// AllCtx returns the data of every unexpired session, keyed by token.
+func (s *Store) AllCtx(ctx context.Context) (map[string][]byte, error) {
+	cur, err := s.coll.Find(ctx, bson.M{"expires_at": bson.M{"$gt": s.now()}})
+	if err != nil {
+		return nil, err
+	}
+	var docs []sessionDoc
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	out := make(map[string][]byte, len(docs))
+	for _, d := range docs {
+		out[d.Token] = d.Data
+	}
+	return out, nil
+}
+
+// Find is FindCtx with a background context.
+func (s *Store) Find(token string) ([]byte, bool, error) {
+	return s.FindCtx(context.Background(), token)
+}
+
+// Commit is CommitCtx with a background context.
+func (s *Store) Commit(token string, data []byte, expiry time.Time) error {
+	return s.CommitCtx(context.Background(), token, data, expiry)
+}
+
+// Delete is DeleteCtx with a background context.
+func (s *Store) Delete(token string) error {
+	return s.DeleteCtx(context.Background(), token)
+}
+
+// All is AllCtx with a background context.
+func (s *Store) All() (map[string][]byte, error) {
+	return s.AllCtx(context.Background())
+}
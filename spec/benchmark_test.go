@@ -207,7 +207,7 @@ func BenchmarkSimplePipeline(b *testing.B) {
 		pipeline := spec.NewPipeline().
 			Match(spec.Eq("status", "active")).
 			Limit(10)
-		_ = pipeline.ToPipeline()
+		_, _ = pipeline.ToPipeline()
 	}
 }
 
@@ -225,7 +225,7 @@ func BenchmarkComplexPipeline(b *testing.B) {
 			}).
 			SortBy("total", -1).
 			Limit(10)
-		_ = pipeline.ToPipeline()
+		_, _ = pipeline.ToPipeline()
 	}
 }
 
@@ -242,7 +242,7 @@ func BenchmarkPipelineWithLookup(b *testing.B) {
 			}).
 			SortBy("totalSpent", -1).
 			Limit(10)
-		_ = pipeline.ToPipeline()
+		_, _ = pipeline.ToPipeline()
 	}
 }
 
@@ -280,6 +280,110 @@ func BenchmarkPipelineAllocation(b *testing.B) {
 			GroupBy("$category", bson.M{"count": spec.Sum(1)}).
 			SortBy("count", -1).
 			Limit(10)
-		_ = pipeline.ToPipeline()
+		_, _ = pipeline.ToPipeline()
+	}
+}
+
+// ========== RAW ENCODING BENCHMARKS ==========
+
+func BenchmarkEqMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = bson.Marshal(spec.Eq("status", "active").ToMongo())
+	}
+}
+
+func BenchmarkEqAppendFilter(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]byte, 0, 128)
+	filter := spec.Eq("status", "active")
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		raw, _ := spec.AppendFilter(buf[:0], filter)
+		buf = raw
+	}
+}
+
+func BenchmarkAndMarshal(b *testing.B) {
+	b.ReportAllocs()
+	filter := spec.And(spec.Eq("tenant_id", "t1"), spec.Gte("age", 18), spec.In("status", []string{"a", "b"}))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = bson.Marshal(filter.ToMongo())
+	}
+}
+
+func BenchmarkAndAppendFilter(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]byte, 0, 256)
+	filter := spec.And(spec.Eq("tenant_id", "t1"), spec.Gte("age", 18), spec.In("status", []string{"a", "b"}))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		raw, _ := spec.AppendFilter(buf[:0], filter)
+		buf = raw
+	}
+}
+
+func BenchmarkSetMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = bson.Marshal(spec.Set("status", "active").ToBsonUpdate())
+	}
+}
+
+func BenchmarkSetAppendUpdate(b *testing.B) {
+	b.ReportAllocs()
+	buf := make([]byte, 0, 128)
+	update := spec.Set("status", "active")
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		raw, _ := spec.AppendUpdate(buf[:0], update)
+		buf = raw
+	}
+}
+
+// ========== POOLING BENCHMARKS ==========
+
+func BenchmarkPipelinePooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := spec.AcquirePipeline()
+		p.Match(spec.Eq("status", "active")).
+			SortBy("created_at", -1).
+			Limit(20)
+		_, _ = p.ToPipeline()
+		spec.ReleasePipeline(p)
+	}
+}
+
+func BenchmarkUpdateDocPooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		u := spec.AcquireUpdateDoc()
+		u.Add(spec.Set("name", "John"), spec.Set("age", 30), spec.Inc("visits", 1))
+		_ = u.ToBsonUpdate()
+		spec.ReleaseUpdateDoc(u)
+	}
+}
+
+// ========== COMPILED FILTER BENCHMARKS ==========
+
+func BenchmarkCompiledFilter(b *testing.B) {
+	b.ReportAllocs()
+	compiled := spec.MustCompile(spec.And(
+		spec.Eq("tenant_id", "t1"),
+		spec.Gte("age", 18),
+		spec.In("status", []string{"a", "b"}),
+	))
+	buf := make([]byte, 0, 256)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		raw, _ := spec.AppendFilter(buf[:0], compiled)
+		buf = raw
 	}
 }
@@ -0,0 +1,81 @@
+package spec
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CompiledFilter is an immutable, pre-encoded filter produced by Compile.
+// It is safe for concurrent use and can be used anywhere a Filter is accepted,
+// including inside And, Or, and Not.
+//
+// Compiling avoids re-walking the filter tree and re-encoding it to BSON on every
+// call, which matters for hot static filters (tenant scoping, status checks, etc.).
+type CompiledFilter struct {
+	doc bson.D
+	raw bson.Raw
+}
+
+// Compile converts a filter tree to its final BSON form once.
+// The filter must not contain values that are mutated after compilation.
+// A nil filter compiles to the empty document.
+//
+// Example:
+//
+//	var activeUsers = spec.MustCompile(spec.And(
+//	    spec.Eq("status", "active"),
+//	    spec.Exists("deleted_at", false),
+//	))
+//
+//	func listActive(ctx context.Context) ([]User, error) {
+//	    return repo.Find(ctx, activeUsers)
+//	}
+func Compile(filter Filter) (*CompiledFilter, error) {
+	raw, err := AppendFilter(nil, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	return &CompiledFilter{doc: doc, raw: raw}, nil
+}
+
+// MustCompile is like Compile but panics if the filter cannot be encoded.
+// It is intended for package-level filter variables.
+func MustCompile(filter Filter) *CompiledFilter {
+	c, err := Compile(filter)
+	if err != nil {
+		panic("spec: Compile: " + err.Error())
+	}
+	return c
+}
+
+// ToMongo returns the filter as bson.M. The top-level map is a fresh copy; nested
+// values are shared with the compiled filter and must be treated as read-only.
+func (c *CompiledFilter) ToMongo() bson.M {
+	m := make(bson.M, len(c.doc))
+	for _, e := range c.doc {
+		m[e.Key] = e.Value
+	}
+	return m
+}
+
+// D returns the compiled filter as an ordered document.
+// The returned value is shared and must be treated as read-only.
+func (c *CompiledFilter) D() bson.D {
+	return c.doc
+}
+
+// Raw returns the compiled filter as encoded BSON.
+// The returned value is shared and must be treated as read-only.
+func (c *CompiledFilter) Raw() bson.Raw {
+	return c.raw
+}
+
+// AppendBSON appends the pre-encoded filter to dst.
+func (c *CompiledFilter) AppendBSON(dst []byte) ([]byte, bool) {
+	return append(dst, c.raw...), true
+}
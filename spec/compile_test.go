@@ -0,0 +1,84 @@
+package spec_test
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCompile(t *testing.T) {
+	filter := spec.And(spec.Eq("status", "active"), spec.Gte("age", int32(18)))
+	compiled := spec.MustCompile(filter)
+
+	if !reflect.DeepEqual(compiled.ToMongo(), bson.M{"$and": bson.A{
+		bson.D{{Key: "status", Value: "active"}},
+		bson.D{{Key: "age", Value: bson.D{{Key: "$gte", Value: int32(18)}}}},
+	}}) {
+		t.Fatalf("unexpected compiled ToMongo: %#v", compiled.ToMongo())
+	}
+
+	want, err := bson.Marshal(filter.ToMongo())
+	if err != nil {
+		t.Fatalf("bson.Marshal: %v", err)
+	}
+	if !bytes.Equal(compiled.Raw(), want) {
+		t.Fatalf("compiled Raw mismatch.\n got: %v\nwant: %v", compiled.Raw(), bson.Raw(want))
+	}
+}
+
+func TestCompileToMongoIsolated(t *testing.T) {
+	compiled := spec.MustCompile(spec.Eq("status", "active"))
+
+	m := compiled.ToMongo()
+	m["status"] = "mutated"
+
+	if compiled.ToMongo()["status"] != "active" {
+		t.Fatal("mutating ToMongo result must not affect the compiled filter")
+	}
+}
+
+func TestCompileNil(t *testing.T) {
+	compiled, err := spec.Compile(nil)
+	if err != nil {
+		t.Fatalf("Compile(nil) returned error: %v", err)
+	}
+	if len(compiled.ToMongo()) != 0 {
+		t.Fatalf("expected empty filter, got %#v", compiled.ToMongo())
+	}
+}
+
+func TestCompiledFilterComposes(t *testing.T) {
+	compiled := spec.MustCompile(spec.Eq("tenant_id", "t1"))
+	filter := spec.And(compiled, spec.Eq("paid", true))
+
+	got, err := spec.AppendFilter(nil, filter)
+	if err != nil {
+		t.Fatalf("AppendFilter: %v", err)
+	}
+	want, _ := bson.Marshal(bson.M{"$and": []bson.M{{"tenant_id": "t1"}, {"paid": true}}})
+	if !bytes.Equal(got, want) {
+		t.Fatalf("composed filter mismatch.\n got: %v\nwant: %v", got, bson.Raw(want))
+	}
+}
+
+func TestCompiledFilterConcurrentUse(t *testing.T) {
+	compiled := spec.MustCompile(spec.In("status", []string{"a", "b"}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = compiled.ToMongo()
+				_, _ = spec.AppendFilter(nil, compiled)
+			}
+		}()
+	}
+	wg.Wait()
+}
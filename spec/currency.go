@@ -0,0 +1,131 @@
+package spec
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CurrencyTotal is a result document of SumByCurrency and SumInCurrency.
+type CurrencyTotal struct {
+	Currency string               `bson:"_id"`
+	Total    primitive.Decimal128 `bson:"total"`
+	Count    int64                `bson:"count"`
+}
+
+// Rates maps currency codes to exchange rates into a reporting currency: the
+// number of reporting-currency units per unit of the currency.
+type Rates map[string]primitive.Decimal128
+
+// RateProvider supplies exchange rates into a target currency, e.g. from a
+// rates API or a table kept up to date by a job.
+type RateProvider interface {
+	Rates(ctx context.Context, target string) (Rates, error)
+}
+
+// RateProviderFunc adapts a function to RateProvider.
+type RateProviderFunc func(ctx context.Context, target string) (Rates, error)
+
+// Rates implements RateProvider.
+func (f RateProviderFunc) Rates(ctx context.Context, target string) (Rates, error) {
+	return f(ctx, target)
+}
+
+// RatesFor returns the rates of provider into target, with target itself at a
+// rate of 1 when the provider omits it.
+func RatesFor(ctx context.Context, provider RateProvider, target string) (Rates, error) {
+	rates, err := provider.Rates(ctx, target)
+	if err != nil {
+		return nil, fmt.Errorf("spec: rates into %s: %w", target, err)
+	}
+	out := make(Rates, len(rates)+1)
+	for k, v := range rates {
+		out[k] = v
+	}
+	if _, ok := out[target]; !ok {
+		one, _ := primitive.ParseDecimal128("1")
+		out[target] = one
+	}
+	return out, nil
+}
+
+// SumByCurrency adds stages that total amountField per value of currencyField,
+// as CurrencyTotal documents sorted by currency. Amounts should be stored as
+// Decimal128; other numeric types are converted to Decimal128 so totals are
+// exact.
+//
+// Example:
+//
+//	pipeline.Match(spec.Eq("status", "paid")).SumByCurrency("amount", "currency")
+//	// [{_id: "EUR", total: 120.50, count: 3}, {_id: "USD", total: 80.00, count: 2}]
+func (p *Pipeline) SumByCurrency(amountField, currencyField string) *Pipeline {
+	p.add(
+		bson.M{"$group": bson.M{
+			"_id":   "$" + currencyField,
+			"total": bson.M{"$sum": bson.M{"$toDecimal": "$" + amountField}},
+			"count": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	)
+	return p
+}
+
+// ConvertCurrency adds a $set stage that stores amountField converted with
+// rates into the field as. Documents in a currency missing from rates make the
+// aggregation fail rather than being left out of totals.
+//
+// Example:
+//
+//	pipeline.ConvertCurrency("amount", "currency", "amount_usd", rates)
+func (p *Pipeline) ConvertCurrency(amountField, currencyField, as string, rates Rates) *Pipeline {
+	currencies := make([]string, 0, len(rates))
+	for c := range rates {
+		currencies = append(currencies, c)
+	}
+	sort.Strings(currencies)
+
+	branches := make(bson.A, 0, len(currencies))
+	for _, c := range currencies {
+		branches = append(branches, bson.M{
+			"case": bson.M{"$eq": bson.A{"$" + currencyField, c}},
+			"then": rates[c],
+		})
+	}
+
+	// $switch without a default fails on unmatched documents.
+	p.add(bson.M{"$set": bson.M{
+		as: bson.M{"$multiply": bson.A{
+			bson.M{"$toDecimal": "$" + amountField},
+			bson.M{"$switch": bson.M{"branches": branches}},
+		}},
+	}})
+	return p
+}
+
+// SumInCurrency adds stages that convert amountField with rates and total it
+// into a single CurrencyTotal in the target currency. Get rates from a
+// RateProvider with RatesFor.
+//
+// Example:
+//
+//	rates, err := spec.RatesFor(ctx, provider, "USD")
+//	if err != nil {
+//	    return err
+//	}
+//	pipeline := spec.NewPipeline().
+//	    Match(spec.Eq("status", "paid")).
+//	    SumInCurrency("amount", "currency", "USD", rates)
+//	// [{_id: "USD", total: 215.37, count: 5}]
+func (p *Pipeline) SumInCurrency(amountField, currencyField, target string, rates Rates) *Pipeline {
+	const converted = "__converted_amount"
+	p.ConvertCurrency(amountField, currencyField, converted, rates)
+	p.add(bson.M{"$group": bson.M{
+		"_id":   bson.M{"$literal": target},
+		"total": bson.M{"$sum": "$" + converted},
+		"count": bson.M{"$sum": 1},
+	}})
+	return p
+}
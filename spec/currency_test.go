@@ -0,0 +1,87 @@
+package spec_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func dec(t *testing.T, s string) primitive.Decimal128 {
+	t.Helper()
+	d, err := primitive.ParseDecimal128(s)
+	if err != nil {
+		t.Fatalf("ParseDecimal128(%q): %v", s, err)
+	}
+	return d
+}
+
+func TestPipelineSumByCurrency(t *testing.T) {
+	got, err := spec.NewPipeline().SumByCurrency("amount", "currency").ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{
+		{"$group": bson.M{
+			"_id":   "$currency",
+			"total": bson.M{"$sum": bson.M{"$toDecimal": "$amount"}},
+			"count": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SumByCurrency mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPipelineSumInCurrency(t *testing.T) {
+	rates := spec.Rates{"USD": dec(t, "1"), "EUR": dec(t, "1.08")}
+	got, err := spec.NewPipeline().SumInCurrency("amount", "currency", "USD", rates).ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{
+		{"$set": bson.M{"__converted_amount": bson.M{"$multiply": bson.A{
+			bson.M{"$toDecimal": "$amount"},
+			bson.M{"$switch": bson.M{"branches": bson.A{
+				bson.M{"case": bson.M{"$eq": bson.A{"$currency", "EUR"}}, "then": rates["EUR"]},
+				bson.M{"case": bson.M{"$eq": bson.A{"$currency", "USD"}}, "then": rates["USD"]},
+			}}},
+		}}}},
+		{"$group": bson.M{
+			"_id":   bson.M{"$literal": "USD"},
+			"total": bson.M{"$sum": "$__converted_amount"},
+			"count": bson.M{"$sum": 1},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SumInCurrency mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestRatesFor(t *testing.T) {
+	ctx := context.Background()
+	provider := spec.RateProviderFunc(func(_ context.Context, target string) (spec.Rates, error) {
+		if target != "USD" {
+			return nil, errors.New("unsupported")
+		}
+		return spec.Rates{"EUR": dec(t, "1.08")}, nil
+	})
+
+	rates, err := spec.RatesFor(ctx, provider, "USD")
+	if err != nil {
+		t.Fatalf("RatesFor: %v", err)
+	}
+	if len(rates) != 2 || rates["USD"].String() != "1" || rates["EUR"].String() != "1.08" {
+		t.Fatalf("RatesFor = %v, want EUR and an identity USD rate", rates)
+	}
+
+	if _, err := spec.RatesFor(ctx, provider, "GBP"); err == nil {
+		t.Fatal("RatesFor should return the provider's error")
+	}
+}
@@ -0,0 +1,137 @@
+package spec
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// datePart is one calendar component of a GroupByDate key.
+type datePart struct {
+	name string
+	expr func(date string) any
+}
+
+func dateOperator(op string) func(string) any {
+	return func(date string) any { return bson.M{op: date} }
+}
+
+var (
+	partYear        = datePart{"year", dateOperator("$year")}
+	partQuarter     = datePart{"quarter", func(date string) any { return bson.M{"$ceil": bson.M{"$divide": bson.A{bson.M{"$month": date}, 3}}} }}
+	partMonth       = datePart{"month", dateOperator("$month")}
+	partISOWeekYear = datePart{"year", dateOperator("$isoWeekYear")}
+	partISOWeek     = datePart{"week", dateOperator("$isoWeek")}
+	partDay         = datePart{"day", dateOperator("$dayOfMonth")}
+	partHour        = datePart{"hour", dateOperator("$hour")}
+	partMinute      = datePart{"minute", dateOperator("$minute")}
+	partSecond      = datePart{"second", dateOperator("$second")}
+	partMillisecond = datePart{"millisecond", dateOperator("$millisecond")}
+)
+
+// dateParts returns the components identifying a period of unit, most
+// significant first.
+func dateParts(unit TimeUnit) []datePart {
+	switch unit {
+	case Year:
+		return []datePart{partYear}
+	case Quarter:
+		return []datePart{partYear, partQuarter}
+	case Month:
+		return []datePart{partYear, partMonth}
+	case Week:
+		return []datePart{partISOWeekYear, partISOWeek}
+	case Day:
+		return []datePart{partYear, partMonth, partDay}
+	case Hour:
+		return []datePart{partYear, partMonth, partDay, partHour}
+	case Minute:
+		return []datePart{partYear, partMonth, partDay, partHour, partMinute}
+	case Second:
+		return []datePart{partYear, partMonth, partDay, partHour, partMinute, partSecond}
+	case Millisecond:
+		return []datePart{partYear, partMonth, partDay, partHour, partMinute, partSecond, partMillisecond}
+	}
+	panic(fmt.Sprintf("spec: unknown time unit %q", unit))
+}
+
+// GroupByDate returns a $group key that buckets documents by the unit period
+// of the date in field, in UTC, as a document of calendar parts: {year} for
+// Year, {year, month} for Month, {year, week} for Week (ISO 8601 weeks and
+// week-numbering years), {year, month, day} for Day, and so on down to
+// Millisecond; Quarter gives {year, quarter}. It panics on an unknown unit.
+//
+// Example:
+//
+//	pipeline.GroupBy(spec.GroupByDate("sale_date", spec.Month), bson.M{"total": spec.Sum("$total")})
+//	// _id: {year: {$year: "$sale_date"}, month: {$month: "$sale_date"}}
+func GroupByDate(field string, unit TimeUnit) bson.M {
+	key := bson.M{}
+	for _, p := range dateParts(unit) {
+		key[p.name] = p.expr("$" + field)
+	}
+	return key
+}
+
+// DateTrunc returns the date in field truncated to the start of its unit
+// period, e.g. midnight for Day, as a date. Unlike GroupByDate keys, the
+// result sorts and formats as a date. Requires MongoDB 5.0 or later; see
+// expr.DateTrunc for expressions, bin sizes, and time zones.
+//
+// Example:
+//
+//	pipeline.GroupBy(spec.DateTrunc("created_at", spec.Week), bson.M{"signups": spec.Sum(1)})
+func DateTrunc(field string, unit TimeUnit) bson.M {
+	return bson.M{"$dateTrunc": bson.M{"date": "$" + field, "unit": string(unit)}}
+}
+
+// DateToParts returns the date in field as a document of its UTC calendar
+// parts: year, month, day, hour, minute, second, and millisecond.
+//
+// Example:
+//
+//	pipeline.AddFields(bson.M{"parts": spec.DateToParts("created_at")})
+func DateToParts(field string) bson.M {
+	return bson.M{"$dateToParts": bson.M{"date": "$" + field}}
+}
+
+// GroupByPeriod groups documents by the unit period of the date in field with
+// GroupByDate and sorts the groups chronologically. It panics on an unknown
+// unit.
+//
+// Example:
+//
+//	pipeline.GroupByPeriod("sale_date", spec.Quarter, bson.M{"revenue": spec.Sum("$total")})
+func (p *Pipeline) GroupByPeriod(field string, unit TimeUnit, accumulators bson.M) *Pipeline {
+	parts := dateParts(unit)
+	sort := make(bson.D, len(parts))
+	for i, part := range parts {
+		sort[i] = bson.E{Key: "_id." + part.name, Value: 1}
+	}
+	return p.GroupBy(GroupByDate(field, unit), accumulators).Sort(sort)
+}
+
+// GroupByYear is GroupByPeriod by Year.
+func (p *Pipeline) GroupByYear(field string, accumulators bson.M) *Pipeline {
+	return p.GroupByPeriod(field, Year, accumulators)
+}
+
+// GroupByMonth is GroupByPeriod by Month.
+//
+// Example:
+//
+//	spec.NewPipeline().GroupByMonth("sale_date", bson.M{"totalSales": spec.Sum("$total")})
+//	// [{_id: {year: 2024, month: 1}, totalSales: ...}, {_id: {year: 2024, month: 2}, ...}]
+func (p *Pipeline) GroupByMonth(field string, accumulators bson.M) *Pipeline {
+	return p.GroupByPeriod(field, Month, accumulators)
+}
+
+// GroupByWeek is GroupByPeriod by ISO Week.
+func (p *Pipeline) GroupByWeek(field string, accumulators bson.M) *Pipeline {
+	return p.GroupByPeriod(field, Week, accumulators)
+}
+
+// GroupByDay is GroupByPeriod by Day.
+func (p *Pipeline) GroupByDay(field string, accumulators bson.M) *Pipeline {
+	return p.GroupByPeriod(field, Day, accumulators)
+}
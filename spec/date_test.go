@@ -0,0 +1,88 @@
+package spec_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGroupByDate(t *testing.T) {
+	tests := []struct {
+		unit spec.TimeUnit
+		want bson.M
+	}{
+		{spec.Year, bson.M{"year": bson.M{"$year": "$d"}}},
+		{spec.Quarter, bson.M{
+			"year":    bson.M{"$year": "$d"},
+			"quarter": bson.M{"$ceil": bson.M{"$divide": bson.A{bson.M{"$month": "$d"}, 3}}},
+		}},
+		{spec.Month, bson.M{"year": bson.M{"$year": "$d"}, "month": bson.M{"$month": "$d"}}},
+		{spec.Week, bson.M{"year": bson.M{"$isoWeekYear": "$d"}, "week": bson.M{"$isoWeek": "$d"}}},
+		{spec.Day, bson.M{"year": bson.M{"$year": "$d"}, "month": bson.M{"$month": "$d"}, "day": bson.M{"$dayOfMonth": "$d"}}},
+		{spec.Hour, bson.M{
+			"year": bson.M{"$year": "$d"}, "month": bson.M{"$month": "$d"},
+			"day": bson.M{"$dayOfMonth": "$d"}, "hour": bson.M{"$hour": "$d"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.unit), func(t *testing.T) {
+			if got := spec.GroupByDate("d", tt.unit); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GroupByDate mismatch.\n got: %#v\nwant: %#v", got, tt.want)
+			}
+		})
+	}
+	if n := len(spec.GroupByDate("d", spec.Millisecond)); n != 7 {
+		t.Fatalf("expected 7 parts for Millisecond, got %d", n)
+	}
+}
+
+func TestGroupByDatePanicsOnUnknownUnit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	spec.GroupByDate("d", "fortnight")
+}
+
+func TestDateTruncAndDateToParts(t *testing.T) {
+	if got, want := spec.DateTrunc("created_at", spec.Week), (bson.M{"$dateTrunc": bson.M{"date": "$created_at", "unit": "week"}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DateTrunc mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+	if got, want := spec.DateToParts("created_at"), (bson.M{"$dateToParts": bson.M{"date": "$created_at"}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DateToParts mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPipelineGroupByMonth(t *testing.T) {
+	got, err := spec.NewPipeline().
+		GroupByMonth("sale_date", bson.M{"totalSales": spec.Sum("$total")}).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bson.M{
+		{"$group": bson.M{
+			"_id":        bson.M{"year": bson.M{"$year": "$sale_date"}, "month": bson.M{"$month": "$sale_date"}},
+			"totalSales": bson.M{"$sum": "$total"},
+		}},
+		{"$sort": bson.D{{Key: "_id.year", Value: 1}, {Key: "_id.month", Value: 1}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline GroupByMonth mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	for name, p := range map[string]*spec.Pipeline{
+		"year": spec.NewPipeline().GroupByYear("d", nil),
+		"week": spec.NewPipeline().GroupByWeek("d", nil),
+		"day":  spec.NewPipeline().GroupByDay("d", nil),
+	} {
+		if stages, err := p.ToPipeline(); err != nil || len(stages) != 2 {
+			t.Errorf("GroupBy %s: expected $group and $sort, got %v (%v)", name, stages, err)
+		}
+	}
+}
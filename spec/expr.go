@@ -0,0 +1,76 @@
+package spec
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// Var returns a reference to the aggregation variable name, e.g. a variable
+// bound by the let of LookupWithPipeline. Variables can only be read inside
+// aggregation expressions, so compare them with the Expr filters rather than
+// Eq and friends.
+//
+// Example:
+//
+//	spec.Var("orderId") // "$$orderId"
+func Var(name string) string {
+	return "$$" + name
+}
+
+type exprFilter struct {
+	expr any
+}
+
+func (f exprFilter) ToMongo() bson.M {
+	return bson.M{"$expr": f.expr}
+}
+
+// Expr creates a filter from an aggregation expression.
+//
+// MongoDB equivalent: {$expr: expr}
+//
+// Example:
+//
+//	Expr(bson.M{"$gt": bson.A{"$spent", "$budget"}}) // Documents over budget
+func Expr(expr any) Filter {
+	return exprFilter{expr: expr}
+}
+
+func exprCompare(op, field string, value any) Filter {
+	return exprFilter{expr: bson.M{op: bson.A{"$" + field, value}}}
+}
+
+// ExprEq creates an $expr filter that matches documents where field equals
+// value, which may be an expression such as a variable from Var or another
+// field ("$other").
+//
+// MongoDB equivalent: {$expr: {$eq: ["$field", value]}}
+//
+// Example:
+//
+//	ExprEq("order_id", Var("orderId")) // {"$expr": {"$eq": ["$order_id", "$$orderId"]}}
+func ExprEq(field string, value any) Filter {
+	return exprCompare("$eq", field, value)
+}
+
+// ExprNe is the $ne counterpart of ExprEq.
+func ExprNe(field string, value any) Filter {
+	return exprCompare("$ne", field, value)
+}
+
+// ExprGt is the $gt counterpart of ExprEq.
+func ExprGt(field string, value any) Filter {
+	return exprCompare("$gt", field, value)
+}
+
+// ExprGte is the $gte counterpart of ExprEq.
+func ExprGte(field string, value any) Filter {
+	return exprCompare("$gte", field, value)
+}
+
+// ExprLt is the $lt counterpart of ExprEq.
+func ExprLt(field string, value any) Filter {
+	return exprCompare("$lt", field, value)
+}
+
+// ExprLte is the $lte counterpart of ExprEq.
+func ExprLte(field string, value any) Filter {
+	return exprCompare("$lte", field, value)
+}
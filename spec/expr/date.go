@@ -0,0 +1,62 @@
+package expr
+
+import (
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DateOption configures a date expression.
+type DateOption func(bson.M)
+
+// Timezone evaluates a date expression in tz, an Olson name such as
+// "Europe/Lisbon" or an offset such as "+01:00". The default is UTC.
+func Timezone(tz string) DateOption {
+	return func(m bson.M) { m["timezone"] = tz }
+}
+
+// BinSize makes DateTrunc truncate to multiples of n units, e.g. 15 minutes.
+func BinSize(n int) DateOption {
+	return func(m bson.M) { m["binSize"] = n }
+}
+
+// StartOfWeek sets the first day of the week for week units, e.g. "monday".
+// The default is "sunday".
+func StartOfWeek(day string) DateOption {
+	return func(m bson.M) { m["startOfWeek"] = day }
+}
+
+func dateOp(name string, args bson.M, opts []DateOption) bson.M {
+	for _, o := range opts {
+		if o != nil {
+			o(args)
+		}
+	}
+	return bson.M{name: args}
+}
+
+// DateTrunc truncates date to the start of its unit, e.g. the start of its
+// day or week, to group by period. Requires MongoDB 5.0 or later.
+//
+// Example:
+//
+//	expr.DateTrunc(expr.Field("created_at"), spec.Minute, expr.BinSize(15))
+func DateTrunc(date any, unit spec.TimeUnit, opts ...DateOption) bson.M {
+	return dateOp("$dateTrunc", bson.M{"date": date, "unit": string(unit)}, opts)
+}
+
+// DateAdd adds amount units to date. Requires MongoDB 5.0 or later.
+func DateAdd(date any, unit spec.TimeUnit, amount any, opts ...DateOption) bson.M {
+	return dateOp("$dateAdd", bson.M{"startDate": date, "unit": string(unit), "amount": amount}, opts)
+}
+
+// DateDiff returns the number of unit boundaries between start and end.
+// Requires MongoDB 5.0 or later.
+func DateDiff(start, end any, unit spec.TimeUnit, opts ...DateOption) bson.M {
+	return dateOp("$dateDiff", bson.M{"startDate": start, "endDate": end, "unit": string(unit)}, opts)
+}
+
+// DateToString formats date with format, e.g. "%Y-%m-%d".
+func DateToString(date any, format string, opts ...DateOption) bson.M {
+	return dateOp("$dateToString", bson.M{"date": date, "format": format}, opts)
+}
@@ -0,0 +1,189 @@
+// Package expr builds aggregation expressions for pipeline stages such as
+// Group, Project, AddFields, and SetWindowFields, so computed fields read as
+// Go calls rather than nested bson.M literals.
+//
+// Every function returns a bson.M (or, for Field, a string) that can be used
+// wherever the driver accepts an expression, and arguments may be field
+// references from Field, variables from spec.Var, literals, or other
+// expressions.
+//
+// Example:
+//
+//	spec.NewPipeline().
+//	    AddFields(bson.M{
+//	        "total": expr.Multiply(expr.Field("price"), expr.Field("qty")),
+//	        "label": expr.Concat(expr.ToUpper(expr.Field("sku")), "-", expr.ToString(expr.Field("size"))),
+//	        "tier":  expr.Cond(expr.Gte(expr.Field("total"), 100), "gold", "standard"),
+//	    }).
+//	    GroupBy(expr.DateTrunc(expr.Field("created_at"), spec.Week), bson.M{
+//	        "revenue": spec.Sum(expr.Field("total")),
+//	    })
+package expr
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Field returns a reference to the value of a document field, e.g. "$price"
+// for "price". Use dotted paths for embedded fields.
+func Field(path string) string {
+	return "$" + path
+}
+
+// Literal makes v a constant rather than an expression, for strings that
+// start with "$" or documents that look like operators.
+func Literal(v any) bson.M {
+	return bson.M{"$literal": v}
+}
+
+// op builds {name: [args...]}.
+func op(name string, args ...any) bson.M {
+	return bson.M{name: bson.A(args)}
+}
+
+// ---- Arithmetic ----
+
+// Add returns the sum of the arguments. With a date argument, numbers are
+// milliseconds added to it.
+func Add(args ...any) bson.M { return op("$add", args...) }
+
+// Subtract returns a - b. Subtracting two dates returns milliseconds.
+func Subtract(a, b any) bson.M { return op("$subtract", a, b) }
+
+// Multiply returns the product of the arguments.
+func Multiply(args ...any) bson.M { return op("$multiply", args...) }
+
+// Divide returns a / b.
+func Divide(a, b any) bson.M { return op("$divide", a, b) }
+
+// Mod returns the remainder of a / b.
+func Mod(a, b any) bson.M { return op("$mod", a, b) }
+
+// Abs returns the absolute value of x.
+func Abs(x any) bson.M { return bson.M{"$abs": x} }
+
+// Round rounds x to place decimal places; negative places round to the left
+// of the decimal point.
+func Round(x any, place int) bson.M { return op("$round", x, place) }
+
+// ---- Comparison and logic ----
+
+// Eq returns whether a equals b.
+func Eq(a, b any) bson.M { return op("$eq", a, b) }
+
+// Ne returns whether a does not equal b.
+func Ne(a, b any) bson.M { return op("$ne", a, b) }
+
+// Gt returns whether a is greater than b.
+func Gt(a, b any) bson.M { return op("$gt", a, b) }
+
+// Gte returns whether a is greater than or equal to b.
+func Gte(a, b any) bson.M { return op("$gte", a, b) }
+
+// Lt returns whether a is less than b.
+func Lt(a, b any) bson.M { return op("$lt", a, b) }
+
+// Lte returns whether a is less than or equal to b.
+func Lte(a, b any) bson.M { return op("$lte", a, b) }
+
+// And returns whether every argument is true.
+func And(args ...any) bson.M { return op("$and", args...) }
+
+// Or returns whether any argument is true.
+func Or(args ...any) bson.M { return op("$or", args...) }
+
+// Not returns the negation of x.
+func Not(x any) bson.M { return op("$not", x) }
+
+// ---- Conditionals ----
+
+// Cond returns then when cond is true and otherwise when it is not.
+func Cond(cond, then, otherwise any) bson.M {
+	return bson.M{"$cond": bson.M{"if": cond, "then": then, "else": otherwise}}
+}
+
+// IfNull returns x, or replacement when x is null or missing.
+func IfNull(x, replacement any) bson.M { return op("$ifNull", x, replacement) }
+
+// Case is one branch of Switch.
+type Case struct {
+	If   any
+	Then any
+}
+
+// Switch returns the Then of the first case whose If is true, or otherwise
+// when none is.
+//
+// Example:
+//
+//	expr.Switch("small",
+//	    expr.Case{If: expr.Gte(expr.Field("qty"), 100), Then: "bulk"},
+//	    expr.Case{If: expr.Gte(expr.Field("qty"), 10), Then: "medium"},
+//	)
+func Switch(otherwise any, cases ...Case) bson.M {
+	branches := make(bson.A, len(cases))
+	for i, c := range cases {
+		branches[i] = bson.M{"case": c.If, "then": c.Then}
+	}
+	return bson.M{"$switch": bson.M{"branches": branches, "default": otherwise}}
+}
+
+// ---- Strings ----
+
+// Concat joins strings.
+func Concat(args ...any) bson.M { return op("$concat", args...) }
+
+// ToLower converts s to lowercase.
+func ToLower(s any) bson.M { return bson.M{"$toLower": s} }
+
+// ToUpper converts s to uppercase.
+func ToUpper(s any) bson.M { return bson.M{"$toUpper": s} }
+
+// Trim removes leading and trailing whitespace from s.
+func Trim(s any) bson.M { return bson.M{"$trim": bson.M{"input": s}} }
+
+// Substr returns length characters of s starting at character start.
+func Substr(s any, start, length int) bson.M { return op("$substrCP", s, start, length) }
+
+// ---- Arrays ----
+
+// Size returns the number of elements of arr.
+func Size(arr any) bson.M { return bson.M{"$size": arr} }
+
+// In returns whether x is an element of arr.
+func In(x, arr any) bson.M { return op("$in", x, arr) }
+
+// ArrayElemAt returns the element of arr at index; negative indexes count
+// from the end.
+func ArrayElemAt(arr any, index int) bson.M { return op("$arrayElemAt", arr, index) }
+
+// Filter returns the elements of input for which cond is true. cond refers to
+// the element as spec.Var(as).
+//
+// Example:
+//
+//	expr.Filter(expr.Field("items"), "item", expr.Gt("$$item.price", 100))
+func Filter(input any, as string, cond any) bson.M {
+	return bson.M{"$filter": bson.M{"input": input, "as": as, "cond": cond}}
+}
+
+// Map returns in evaluated for each element of input, which it refers to as
+// spec.Var(as).
+//
+// Example:
+//
+//	expr.Map(expr.Field("items"), "item", expr.Multiply("$$item.price", "$$item.qty"))
+func Map(input any, as string, in any) bson.M {
+	return bson.M{"$map": bson.M{"input": input, "as": as, "in": in}}
+}
+
+// ---- Type conversion ----
+
+// ToString converts x to a string.
+func ToString(x any) bson.M { return bson.M{"$toString": x} }
+
+// ToDouble converts x to a double.
+func ToDouble(x any) bson.M { return bson.M{"$toDouble": x} }
+
+// ToDecimal converts x to a Decimal128, e.g. before arithmetic on money.
+func ToDecimal(x any) bson.M { return bson.M{"$toDecimal": x} }
@@ -0,0 +1,86 @@
+package expr_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+	"github.com/dElCIoGio/mongox/spec/expr"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestExpressions(t *testing.T) {
+	price, qty := expr.Field("price"), expr.Field("qty")
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"Field", price, "$price"},
+		{"Literal", expr.Literal("$5"), bson.M{"$literal": "$5"}},
+		{"Add", expr.Add(price, 1, 2), bson.M{"$add": bson.A{"$price", 1, 2}}},
+		{"Subtract", expr.Subtract(price, 1), bson.M{"$subtract": bson.A{"$price", 1}}},
+		{"Multiply", expr.Multiply(price, qty), bson.M{"$multiply": bson.A{"$price", "$qty"}}},
+		{"Divide", expr.Divide(price, 100), bson.M{"$divide": bson.A{"$price", 100}}},
+		{"Mod", expr.Mod(qty, 2), bson.M{"$mod": bson.A{"$qty", 2}}},
+		{"Abs", expr.Abs(price), bson.M{"$abs": "$price"}},
+		{"Round", expr.Round(price, 2), bson.M{"$round": bson.A{"$price", 2}}},
+		{"Gte", expr.Gte(qty, 10), bson.M{"$gte": bson.A{"$qty", 10}}},
+		{"And", expr.And(expr.Gt(qty, 0), expr.Ne(price, nil)),
+			bson.M{"$and": bson.A{bson.M{"$gt": bson.A{"$qty", 0}}, bson.M{"$ne": bson.A{"$price", nil}}}}},
+		{"Not", expr.Not(expr.Eq(qty, 0)), bson.M{"$not": bson.A{bson.M{"$eq": bson.A{"$qty", 0}}}}},
+		{"Cond", expr.Cond(expr.Lt(qty, 10), "small", "large"),
+			bson.M{"$cond": bson.M{"if": bson.M{"$lt": bson.A{"$qty", 10}}, "then": "small", "else": "large"}}},
+		{"IfNull", expr.IfNull(expr.Field("nickname"), expr.Field("name")), bson.M{"$ifNull": bson.A{"$nickname", "$name"}}},
+		{"Switch", expr.Switch("small", expr.Case{If: expr.Gte(qty, 100), Then: "bulk"}),
+			bson.M{"$switch": bson.M{"branches": bson.A{bson.M{"case": bson.M{"$gte": bson.A{"$qty", 100}}, "then": "bulk"}}, "default": "small"}}},
+		{"Concat", expr.Concat(expr.Field("first"), " ", expr.Field("last")), bson.M{"$concat": bson.A{"$first", " ", "$last"}}},
+		{"ToUpper", expr.ToUpper(expr.Field("sku")), bson.M{"$toUpper": "$sku"}},
+		{"Trim", expr.Trim(expr.Field("name")), bson.M{"$trim": bson.M{"input": "$name"}}},
+		{"Substr", expr.Substr(expr.Field("name"), 0, 3), bson.M{"$substrCP": bson.A{"$name", 0, 3}}},
+		{"Size", expr.Size(expr.Field("items")), bson.M{"$size": "$items"}},
+		{"In", expr.In("red", expr.Field("colors")), bson.M{"$in": bson.A{"red", "$colors"}}},
+		{"ArrayElemAt", expr.ArrayElemAt(expr.Field("items"), -1), bson.M{"$arrayElemAt": bson.A{"$items", -1}}},
+		{"Filter", expr.Filter(expr.Field("items"), "item", expr.Gt(spec.Var("item.price"), 100)),
+			bson.M{"$filter": bson.M{"input": "$items", "as": "item", "cond": bson.M{"$gt": bson.A{"$$item.price", 100}}}}},
+		{"Map", expr.Map(expr.Field("items"), "item", expr.Multiply("$$item.price", "$$item.qty")),
+			bson.M{"$map": bson.M{"input": "$items", "as": "item", "in": bson.M{"$multiply": bson.A{"$$item.price", "$$item.qty"}}}}},
+		{"ToString", expr.ToString(qty), bson.M{"$toString": "$qty"}},
+		{"ToDecimal", expr.ToDecimal(price), bson.M{"$toDecimal": "$price"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Fatalf("%s mismatch.\n got: %#v\nwant: %#v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateExpressions(t *testing.T) {
+	created := expr.Field("created_at")
+	tests := []struct {
+		name string
+		got  bson.M
+		want bson.M
+	}{
+		{"DateTrunc", expr.DateTrunc(created, spec.Week, expr.StartOfWeek("monday"), expr.Timezone("Europe/Lisbon")),
+			bson.M{"$dateTrunc": bson.M{"date": "$created_at", "unit": "week", "startOfWeek": "monday", "timezone": "Europe/Lisbon"}}},
+		{"DateTrunc bins", expr.DateTrunc(created, spec.Minute, expr.BinSize(15)),
+			bson.M{"$dateTrunc": bson.M{"date": "$created_at", "unit": "minute", "binSize": 15}}},
+		{"DateAdd", expr.DateAdd(created, spec.Day, 30),
+			bson.M{"$dateAdd": bson.M{"startDate": "$created_at", "unit": "day", "amount": 30}}},
+		{"DateDiff", expr.DateDiff(created, "$$NOW", spec.Hour),
+			bson.M{"$dateDiff": bson.M{"startDate": "$created_at", "endDate": "$$NOW", "unit": "hour"}}},
+		{"DateToString", expr.DateToString(created, "%Y-%m-%d"),
+			bson.M{"$dateToString": bson.M{"date": "$created_at", "format": "%Y-%m-%d"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Fatalf("%s mismatch.\n got: %#v\nwant: %#v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package spec
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldCache maps a fieldKey to its resolved BSON path.
+var fieldCache sync.Map
+
+type fieldKey struct {
+	t    reflect.Type
+	path string
+}
+
+// Field returns the BSON path of the Go field path goPath in document type T,
+// so filters and updates can refer to fields without hard-coding their names.
+// goPath uses Go field names separated by dots; fields of inline structs (such
+// as document.Base) are addressed directly, and paths may continue through
+// pointers, slices, and maps of structs.
+//
+// Field panics if goPath does not name an exported, serialized field of T, so
+// a misspelled name fails on the first run instead of silently matching
+// nothing. Use LookupField to handle the error instead. Results are cached.
+//
+// Example:
+//
+//	type User struct {
+//	    document.Base `bson:",inline"`
+//	    Email   string  `bson:"email_address"`
+//	    Address Address `bson:"address"`
+//	}
+//
+//	spec.Eq(spec.Field[User]("Email"), "ada@example.com")  // {"email_address": ...}
+//	spec.Eq(spec.Field[User]("Address.City"), "Lisbon")     // {"address.city": ...}
+//	spec.Gt(spec.Field[User]("CreatedAt"), since)           // {"created_at": ...}
+func Field[T any](goPath string) string {
+	name, err := LookupField[T](goPath)
+	if err != nil {
+		panic(err.Error())
+	}
+	return name
+}
+
+// LookupField is like Field but returns an error for unknown fields.
+func LookupField[T any](goPath string) (string, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	key := fieldKey{t: t, path: goPath}
+	if cached, ok := fieldCache.Load(key); ok {
+		return cached.(string), nil
+	}
+
+	parts := strings.Split(goPath, ".")
+	names := make([]string, 0, len(parts))
+	cur := t
+	for _, part := range parts {
+		name, next, ok := bsonField(cur, part)
+		if !ok {
+			return "", fmt.Errorf("spec: %s has no BSON field %q (in %q)", t, part, goPath)
+		}
+		names = append(names, name)
+		cur = next
+	}
+
+	name := strings.Join(names, ".")
+	fieldCache.Store(key, name)
+	return name, nil
+}
+
+// bsonField finds the exported Go field goName in t, looking through inline
+// structs, and returns its BSON name and type. Pointer, slice, array, and map
+// types are unwrapped to their element type first.
+func bsonField(t reflect.Type, goName string) (string, reflect.Type, bool) {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+	if t.Kind() != reflect.Struct || goName == "" {
+		return "", nil, false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("bson")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(","+opts+",", ",inline,") {
+			if name, typ, ok := bsonField(field.Type, goName); ok {
+				return name, typ, true
+			}
+			continue
+		}
+		if field.Name != goName || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		return name, field.Type, true
+	}
+	return "", nil, false
+}
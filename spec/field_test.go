@@ -0,0 +1,70 @@
+package spec_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fieldBase struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+}
+
+type fieldAddress struct {
+	City string `bson:"city"`
+	Zip  string
+}
+
+type fieldUser struct {
+	fieldBase `bson:",inline"`
+
+	Email     string          `bson:"email_address,omitempty"`
+	Address   fieldAddress    `bson:"address"`
+	Previous  []*fieldAddress `bson:"previous"`
+	Nickname  string
+	Secret    string `bson:"-"`
+	unexposed string
+}
+
+func TestField(t *testing.T) {
+	tests := map[string]string{
+		"Email":         "email_address",
+		"ID":            "_id",
+		"CreatedAt":     "created_at",
+		"Address.City":  "address.city",
+		"Address.Zip":   "address.zip",
+		"Previous.City": "previous.city",
+		"Nickname":      "nickname",
+	}
+	for path, want := range tests {
+		if got := spec.Field[fieldUser](path); got != want {
+			t.Errorf("Field(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	// Pointer document types resolve the same way.
+	if got := spec.Field[*fieldUser]("Address.City"); got != "address.city" {
+		t.Errorf("Field on a pointer type = %q, want address.city", got)
+	}
+}
+
+func TestLookupField_Unknown(t *testing.T) {
+	for _, path := range []string{"Emial", "Secret", "unexposed", "Address.Street", "Email.Domain", "", "Address."} {
+		if _, err := spec.LookupField[fieldUser](path); err == nil {
+			t.Errorf("LookupField(%q) succeeded, want an error", path)
+		}
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(r.(string), "Emial") {
+			t.Fatalf("Field panic = %v, want a message naming the field", r)
+		}
+	}()
+	spec.Field[fieldUser]("Emial")
+}
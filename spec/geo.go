@@ -0,0 +1,273 @@
+package spec
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// earthRadiusMeters is the equatorial radius MongoDB uses to convert
+// $centerSphere radians to distances.
+const earthRadiusMeters = 6378100.0
+
+// Geometry is a GeoJSON geometry accepted by GeoIntersects: a Point or a
+// Polygon.
+type Geometry interface {
+	geometry()
+}
+
+// GeoShape is an area accepted by GeoWithin: a Polygon (including one built by
+// NewBox) or a Circle.
+type GeoShape interface {
+	withinSpec() bson.M
+}
+
+// Point is a GeoJSON point. Besides being used in geo filters, it can be
+// stored in documents as the value of a field with a 2dsphere index (see
+// document.GeoIndex). Create points with NewPoint.
+type Point struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewPoint creates a GeoJSON point. GeoJSON orders coordinates longitude
+// first.
+//
+// Example:
+//
+//	spec.NewPoint(-9.1393, 38.7223) // Lisbon
+func NewPoint(lng, lat float64) Point {
+	return Point{Type: "Point", Coordinates: []float64{lng, lat}}
+}
+
+func (Point) geometry() {}
+
+// Polygon is a GeoJSON polygon with a single outer ring. Create polygons with
+// NewPolygon or NewBox.
+type Polygon struct {
+	Type        string        `bson:"type" json:"type"`
+	Coordinates [][][]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewPolygon creates a GeoJSON polygon from the vertices of its outer ring.
+// The ring is closed automatically when the last vertex differs from the first.
+//
+// Example:
+//
+//	spec.NewPolygon(spec.NewPoint(0, 0), spec.NewPoint(10, 0), spec.NewPoint(10, 10), spec.NewPoint(0, 10))
+func NewPolygon(vertices ...Point) Polygon {
+	ring := make([][]float64, 0, len(vertices)+1)
+	for _, v := range vertices {
+		ring = append(ring, []float64{v.Coordinates[0], v.Coordinates[1]})
+	}
+	if n := len(ring); n > 0 && (ring[0][0] != ring[n-1][0] || ring[0][1] != ring[n-1][1]) {
+		ring = append(ring, ring[0])
+	}
+	return Polygon{Type: "Polygon", Coordinates: [][][]float64{ring}}
+}
+
+// NewBox creates the rectangular polygon with the given south-west and
+// north-east corners. Its edges are geodesics, so very large boxes bulge
+// slightly compared to lines of constant latitude.
+//
+// Example:
+//
+//	spec.NewBox(spec.NewPoint(-9.25, 38.69), spec.NewPoint(-9.09, 38.80))
+func NewBox(southWest, northEast Point) Polygon {
+	w, s := southWest.Coordinates[0], southWest.Coordinates[1]
+	e, n := northEast.Coordinates[0], northEast.Coordinates[1]
+	return NewPolygon(NewPoint(w, s), NewPoint(e, s), NewPoint(e, n), NewPoint(w, n))
+}
+
+func (Polygon) geometry() {}
+
+func (p Polygon) withinSpec() bson.M {
+	return bson.M{"$geometry": p}
+}
+
+// Circle is the area within Radius meters of Center, measured on the sphere.
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+func (c Circle) withinSpec() bson.M {
+	return bson.M{"$centerSphere": bson.A{
+		bson.A{c.Center.Coordinates[0], c.Center.Coordinates[1]},
+		c.Radius / earthRadiusMeters,
+	}}
+}
+
+// NearOption configures a Near or NearSphere filter.
+type NearOption func(bson.M)
+
+// MaxDistance limits Near and NearSphere to documents at most meters away.
+func MaxDistance(meters float64) NearOption {
+	return func(m bson.M) { m["$maxDistance"] = meters }
+}
+
+// MinDistance limits Near and NearSphere to documents at least meters away.
+func MinDistance(meters float64) NearOption {
+	return func(m bson.M) { m["$minDistance"] = meters }
+}
+
+type nearFilter struct {
+	field string
+	op    string
+	point Point
+	opts  []NearOption
+}
+
+func (f nearFilter) ToMongo() bson.M {
+	near := bson.M{"$geometry": f.point}
+	for _, opt := range f.opts {
+		opt(near)
+	}
+	return bson.M{f.field: bson.M{f.op: near}}
+}
+
+// Near creates a filter that matches documents whose GeoJSON field is near
+// point, returning them nearest first. The field needs a 2dsphere index.
+//
+// $near sorts the results itself, so it cannot be combined with a sort, and
+// it is not supported by Count; use GeoWithin with a Circle to count the
+// documents within a distance.
+//
+// MongoDB equivalent: {field: {$near: {$geometry: point, $maxDistance: m, $minDistance: m}}}
+//
+// Example:
+//
+//	Near("location", NewPoint(-9.1393, 38.7223), MaxDistance(2000))
+func Near(field string, point Point, opts ...NearOption) Filter {
+	return nearFilter{field: field, op: "$near", point: point, opts: opts}
+}
+
+// NearSphere is like Near but always computes distances on a sphere, which
+// also applies to fields with a legacy 2d index.
+//
+// MongoDB equivalent: {field: {$nearSphere: {$geometry: point, ...}}}
+func NearSphere(field string, point Point, opts ...NearOption) Filter {
+	return nearFilter{field: field, op: "$nearSphere", point: point, opts: opts}
+}
+
+// GeoWithin creates a filter that matches documents whose GeoJSON field lies
+// entirely within shape. Unlike Near it does not sort, and a 2dsphere index is
+// used but not required.
+//
+// MongoDB equivalent: {field: {$geoWithin: {$geometry: polygon}}} or
+// {field: {$geoWithin: {$centerSphere: [[lng, lat], radians]}}}
+//
+// Example:
+//
+//	GeoWithin("location", NewBox(NewPoint(-9.25, 38.69), NewPoint(-9.09, 38.80)))
+//	GeoWithin("location", Circle{Center: NewPoint(-9.1393, 38.7223), Radius: 5000})
+func GeoWithin(field string, shape GeoShape) Filter {
+	return opFilter{field: field, op: "$geoWithin", value: shape.withinSpec()}
+}
+
+// GeoIntersects creates a filter that matches documents whose GeoJSON field
+// intersects geometry, e.g. delivery zones (polygons) that contain an address.
+//
+// MongoDB equivalent: {field: {$geoIntersects: {$geometry: geometry}}}
+//
+// Example:
+//
+//	GeoIntersects("zone", NewPoint(-9.1393, 38.7223))
+func GeoIntersects(field string, geometry Geometry) Filter {
+	return opFilter{field: field, op: "$geoIntersects", value: bson.M{"$geometry": geometry}}
+}
+
+// GeoNearOptions configures a $geoNear stage.
+type GeoNearOptions struct {
+	// Near is the point to measure distances from. Required.
+	Near Point
+
+	// DistanceField is the output field that receives each document's
+	// distance from Near, in meters. Required.
+	DistanceField string
+
+	// Key is the geo-indexed field to use. Required only when the collection
+	// has more than one geospatial index.
+	Key string
+
+	// MaxDistance and MinDistance bound the distance in meters. 0 means no
+	// bound.
+	MaxDistance float64
+	MinDistance float64
+
+	// Query limits the documents considered, like a $match before the stage.
+	// It cannot contain $near or $text.
+	Query Filter
+
+	// Spherical computes distances on a sphere. GeoJSON points always use
+	// spherical geometry; it matters for fields with a legacy 2d index.
+	Spherical bool
+
+	// DistanceMultiplier scales the distances, e.g. 0.001 for kilometers.
+	// 0 means 1.
+	DistanceMultiplier float64
+
+	// IncludeLocs is the output field that receives the location used to
+	// compute the distance, useful for documents with several locations.
+	IncludeLocs string
+}
+
+// GeoNear adds a $geoNear stage, which returns documents ordered by distance
+// from opts.Near and records the distance in opts.DistanceField. The
+// collection needs a geospatial index.
+//
+// $geoNear must be the first stage of a pipeline, so Validate reports a
+// GeoNear added after other stages, as well as an opts.Near that is not a
+// point created with NewPoint, an empty opts.DistanceField, a negative
+// distance bound, and a MinDistance above MaxDistance.
+//
+// Example:
+//
+//	spec.NewPipeline().
+//	    GeoNear(spec.GeoNearOptions{
+//	        Near:          spec.NewPoint(-9.1393, 38.7223),
+//	        DistanceField: "distance",
+//	        MaxDistance:   5000,
+//	        Query:         spec.Eq("open", true),
+//	    }).
+//	    Limit(10)
+func (p *Pipeline) GeoNear(opts GeoNearOptions) *Pipeline {
+	switch {
+	case len(p.stages) > 0:
+		p.fail("GeoNear: $geoNear must be the first stage")
+		return p
+	case opts.Near.Type != "Point" || len(opts.Near.Coordinates) != 2:
+		p.fail("GeoNear: Near must be a point created with NewPoint")
+		return p
+	case opts.DistanceField == "":
+		p.fail("GeoNear: DistanceField is required")
+		return p
+	case opts.MaxDistance < 0 || opts.MinDistance < 0:
+		p.fail("GeoNear: distances must not be negative")
+		return p
+	case opts.MaxDistance > 0 && opts.MinDistance > opts.MaxDistance:
+		p.fail("GeoNear: MinDistance exceeds MaxDistance")
+		return p
+	}
+
+	stage := bson.M{"near": opts.Near, "distanceField": opts.DistanceField}
+	if opts.Key != "" {
+		stage["key"] = opts.Key
+	}
+	if opts.MaxDistance > 0 {
+		stage["maxDistance"] = opts.MaxDistance
+	}
+	if opts.MinDistance > 0 {
+		stage["minDistance"] = opts.MinDistance
+	}
+	if opts.Query != nil {
+		stage["query"] = opts.Query.ToMongo()
+	}
+	if opts.Spherical {
+		stage["spherical"] = true
+	}
+	if opts.DistanceMultiplier != 0 {
+		stage["distanceMultiplier"] = opts.DistanceMultiplier
+	}
+	if opts.IncludeLocs != "" {
+		stage["includeLocs"] = opts.IncludeLocs
+	}
+	p.add(bson.M{"$geoNear": stage})
+	return p
+}
@@ -0,0 +1,125 @@
+package spec_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNear(t *testing.T) {
+	lisbon := spec.NewPoint(-9.1393, 38.7223)
+	got := spec.Near("location", lisbon, spec.MaxDistance(2000), spec.MinDistance(10)).ToMongo()
+	want := bson.M{"location": bson.M{"$near": bson.M{
+		"$geometry":    spec.Point{Type: "Point", Coordinates: []float64{-9.1393, 38.7223}},
+		"$maxDistance": 2000.0,
+		"$minDistance": 10.0,
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Near mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	sphere := spec.NearSphere("location", lisbon).ToMongo()
+	if _, ok := sphere["location"].(bson.M)["$nearSphere"]; !ok {
+		t.Fatalf("NearSphere: got %#v", sphere)
+	}
+}
+
+func TestGeoWithin(t *testing.T) {
+	box := spec.GeoWithin("location", spec.NewBox(spec.NewPoint(0, 0), spec.NewPoint(2, 1))).ToMongo()
+	want := bson.M{"location": bson.M{"$geoWithin": bson.M{"$geometry": spec.Polygon{
+		Type:        "Polygon",
+		Coordinates: [][][]float64{{{0, 0}, {2, 0}, {2, 1}, {0, 1}, {0, 0}}},
+	}}}}
+	if !reflect.DeepEqual(box, want) {
+		t.Fatalf("GeoWithin box mismatch.\n got: %#v\nwant: %#v", box, want)
+	}
+
+	circle := spec.GeoWithin("location", spec.Circle{Center: spec.NewPoint(1, 2), Radius: 6378.1}).ToMongo()
+	cs := circle["location"].(bson.M)["$geoWithin"].(bson.M)["$centerSphere"].(bson.A)
+	if !reflect.DeepEqual(cs[0], bson.A{1.0, 2.0}) || math.Abs(cs[1].(float64)-0.001) > 1e-12 {
+		t.Fatalf("GeoWithin circle: got %#v", cs)
+	}
+}
+
+func TestNewPolygon_KeepsClosedRing(t *testing.T) {
+	p := spec.NewPolygon(spec.NewPoint(0, 0), spec.NewPoint(1, 0), spec.NewPoint(1, 1), spec.NewPoint(0, 0))
+	if len(p.Coordinates[0]) != 4 {
+		t.Fatalf("ring = %v, want 4 vertices", p.Coordinates[0])
+	}
+}
+
+func TestGeoIntersects_Encodes(t *testing.T) {
+	raw, err := spec.AppendFilter(nil, spec.GeoIntersects("zone", spec.NewPoint(1.5, 2.5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got bson.D
+	if err := bson.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := bson.D{{Key: "zone", Value: bson.D{{Key: "$geoIntersects", Value: bson.D{{Key: "$geometry", Value: bson.D{
+		{Key: "type", Value: "Point"},
+		{Key: "coordinates", Value: bson.A{1.5, 2.5}},
+	}}}}}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GeoIntersects mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPipelineGeoNear(t *testing.T) {
+	got, err := spec.NewPipeline().
+		GeoNear(spec.GeoNearOptions{
+			Near:          spec.NewPoint(1, 2),
+			DistanceField: "distance",
+			MaxDistance:   5000,
+			Query:         spec.Eq("open", true),
+			Spherical:     true,
+		}).
+		Limit(10).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{
+		{"$geoNear": bson.M{
+			"near":          spec.Point{Type: "Point", Coordinates: []float64{1, 2}},
+			"distanceField": "distance",
+			"maxDistance":   5000.0,
+			"query":         bson.M{"open": true},
+			"spherical":     true,
+		}},
+		{"$limit": int64(10)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline GeoNear mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPipelineGeoNear_Validates(t *testing.T) {
+	valid := spec.GeoNearOptions{Near: spec.NewPoint(1, 2), DistanceField: "distance"}
+	negative := valid
+	negative.MaxDistance = -1
+	inverted := valid
+	inverted.MinDistance, inverted.MaxDistance = 10, 5
+
+	tests := []struct {
+		name string
+		p    *spec.Pipeline
+	}{
+		{"not first", spec.NewPipeline().Match(spec.Eq("open", true)).GeoNear(valid)},
+		{"zero point", spec.NewPipeline().GeoNear(spec.GeoNearOptions{DistanceField: "d"})},
+		{"no distance field", spec.NewPipeline().GeoNear(spec.GeoNearOptions{Near: valid.Near})},
+		{"negative distance", spec.NewPipeline().GeoNear(negative)},
+		{"min above max", spec.NewPipeline().GeoNear(inverted)},
+	}
+	for _, tt := range tests {
+		if err := tt.p.Validate(); !errors.Is(err, spec.ErrInvalidPipeline) {
+			t.Errorf("%s: expected ErrInvalidPipeline, got %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package spec
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GraphLookupOption configures a GraphLookup stage.
+type GraphLookupOption func(bson.M)
+
+// MaxDepth limits a GraphLookup to depth recursive steps after the first
+// match; 0 returns only the documents matched directly by startWith.
+// GraphLookup rejects it if depth is negative.
+func MaxDepth(depth int) GraphLookupOption {
+	if depth < 0 {
+		return func(m bson.M) {
+			m["maxDepth"] = invalidArg{errors.New("MaxDepth: depth must not be negative")}
+		}
+	}
+	return func(m bson.M) { m["maxDepth"] = depth }
+}
+
+// DepthField makes a GraphLookup store each found document's recursion depth,
+// starting at 0, in field.
+func DepthField(field string) GraphLookupOption {
+	return func(m bson.M) { m["depthField"] = field }
+}
+
+// RestrictSearch makes a GraphLookup only traverse documents of from that
+// match filter.
+func RestrictSearch(filter Filter) GraphLookupOption {
+	return func(m bson.M) {
+		if filter != nil {
+			m["restrictSearchWithMatch"] = filter.ToMongo()
+		}
+	}
+}
+
+// GraphLookup adds a $graphLookup stage, which recursively searches from for
+// hierarchies such as org charts and category trees. Starting with the value
+// of the startWith expression, it finds documents whose connectTo field
+// matches, then follows their connectFrom field, and stores every document
+// found in the as array.
+//
+// Example:
+//
+//	// Every manager above each employee, with how far up they are.
+//	pipeline.GraphLookup("employees", "$reports_to", "reports_to", "_id", "managers",
+//	    spec.DepthField("level"),
+//	    spec.MaxDepth(5),
+//	)
+func (p *Pipeline) GraphLookup(from, startWith, connectFrom, connectTo, as string, opts ...GraphLookupOption) *Pipeline {
+	stage := bson.M{
+		"from":             from,
+		"startWith":        startWith,
+		"connectFromField": connectFrom,
+		"connectToField":   connectTo,
+		"as":               as,
+	}
+	for _, o := range opts {
+		if o != nil {
+			o(stage)
+		}
+	}
+	if err := invalid(stage); err != nil {
+		p.fail("GraphLookup: %v", err)
+		return p
+	}
+	p.add(bson.M{"$graphLookup": stage})
+	return p
+}
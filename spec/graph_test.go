@@ -0,0 +1,58 @@
+package spec_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPipelineGraphLookup(t *testing.T) {
+	got, err := spec.NewPipeline().
+		GraphLookup("employees", "$reports_to", "reports_to", "_id", "managers",
+			spec.MaxDepth(2),
+			spec.DepthField("level"),
+			spec.RestrictSearch(spec.Eq("active", true)),
+		).
+		GraphLookup("categories", "$parent", "parent", "_id", "ancestors").
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bson.M{
+		{"$graphLookup": bson.M{
+			"from":                    "employees",
+			"startWith":               "$reports_to",
+			"connectFromField":        "reports_to",
+			"connectToField":          "_id",
+			"as":                      "managers",
+			"maxDepth":                2,
+			"depthField":              "level",
+			"restrictSearchWithMatch": bson.M{"active": true},
+		}},
+		{"$graphLookup": bson.M{
+			"from":             "categories",
+			"startWith":        "$parent",
+			"connectFromField": "parent",
+			"connectToField":   "_id",
+			"as":               "ancestors",
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline GraphLookup mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestGraphLookupRejectsNegativeMaxDepth(t *testing.T) {
+	p := spec.NewPipeline().GraphLookup("employees", "$reports_to", "reports_to", "_id", "managers", spec.MaxDepth(-1))
+	if err := p.Validate(); !errors.Is(err, spec.ErrInvalidPipeline) {
+		t.Fatalf("expected ErrInvalidPipeline, got %v", err)
+	}
+	if got, err := p.ToPipeline(); err == nil || got != nil {
+		t.Fatalf("expected ToPipeline to fail without stages, got %v (%v)", got, err)
+	}
+}
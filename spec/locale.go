@@ -0,0 +1,34 @@
+package spec
+
+// LocaleField returns the path of one locale of a document.Localized field.
+//
+// Example:
+//
+//	LocaleField("name", "pt-BR")  // "name.pt-BR"
+//	Regex(LocaleField("name", "en"), "^shoe", "i")
+func LocaleField(field, locale string) string {
+	return field + "." + locale
+}
+
+// EqLocale matches documents whose localized field has value in the given locale.
+//
+// MongoDB equivalent: {"field.locale": value}
+//
+// Example:
+//
+//	EqLocale("name", "en", "Shoes")  // {"name.en": "Shoes"}
+func EqLocale(field, locale string, value any) Filter {
+	return Eq(LocaleField(field, locale), value)
+}
+
+// HasLocale matches documents whose localized field has a value for the given
+// locale, e.g. to find content that still needs translating with Not.
+//
+// MongoDB equivalent: {"field.locale": {$exists: true}}
+//
+// Example:
+//
+//	Not(HasLocale("name", "pt"))  // products without a Portuguese name
+func HasLocale(field, locale string) Filter {
+	return Exists(LocaleField(field, locale), true)
+}
@@ -1,6 +1,11 @@
 package spec
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
 
 // Filter represents a MongoDB query filter that can be translated to bson.M.
 // Filters are composable building blocks for constructing MongoDB queries
@@ -199,6 +204,37 @@ func (f regexFilter) ToMongo() bson.M {
 	return bson.M{f.field: bson.M{"$regex": f.pattern, "$options": f.options}}
 }
 
+// SearchAcross creates the filter behind a typical admin "search box": it
+// matches documents where any of fields starts with term, ignoring case.
+// Regex metacharacters in term are escaped, so user input is matched literally.
+//
+// Behavior:
+//   - Leading and trailing whitespace in term is ignored
+//   - Returns nil for an empty term or no fields, so And(...) skips it
+//   - Case-insensitive regexes scan the index or collection, so prefer a text
+//     index for large collections
+//
+// MongoDB equivalent: {$or: [{field1: {$regex: "^term", $options: "i"}}, ...]}
+//
+// Example:
+//
+//	filter := And(
+//	    Eq("tenant_id", tenantID),
+//	    SearchAcross([]string{"name", "email", "company"}, r.URL.Query().Get("q")),
+//	)
+func SearchAcross(fields []string, term string) Filter {
+	term = strings.TrimSpace(term)
+	if term == "" || len(fields) == 0 {
+		return nil
+	}
+	pattern := "^" + regexp.QuoteMeta(term)
+	filters := make([]Filter, len(fields))
+	for i, field := range fields {
+		filters[i] = Regex(field, pattern, "i")
+	}
+	return Or(filters...)
+}
+
 // All creates a filter that matches documents where the array field contains all specified values.
 // The order of values doesn't matter, but all values must be present.
 //
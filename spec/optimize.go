@@ -0,0 +1,355 @@
+package spec
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Rewrite describes one change made by Pipeline.Optimize.
+type Rewrite struct {
+	// Rule names the rewrite: "match-before-sort", "match-before-project",
+	// "match-before-set", "match-before-unset", or "merge-match".
+	Rule string
+
+	// Stage is the index, before the rewrite, of the $match stage it moved or
+	// merged.
+	Stage int
+}
+
+// String describes the rewrite, e.g. "moved $match at stage 2 before $sort".
+func (r Rewrite) String() string {
+	if r.Rule == "merge-match" {
+		return fmt.Sprintf("merged $match at stage %d into the previous $match", r.Stage)
+	}
+	return fmt.Sprintf("moved $match at stage %d before $%s", r.Stage, strings.TrimPrefix(r.Rule, "match-before-"))
+}
+
+// Optimize rewrites the pipeline in place so that documents are filtered as
+// early as possible, and returns the changes it made. Filtering first lets the
+// server use an index for the leading $match and keeps later stages small.
+//
+// Only rewrites that cannot change the result are applied:
+//   - a $match is moved before a preceding $sort
+//   - a $match is moved before a preceding $project, $addFields/$set, or $unset
+//     when every field it reads passes through that stage unchanged
+//   - consecutive $match stages are merged with $and
+//
+// A $match that uses $expr, $where, or other top-level operators whose field
+// dependencies cannot be determined is never moved. Stages are never moved
+// past $limit, $skip, $group, $unwind, $lookup, or stages added with Raw other
+// than those listed above.
+//
+// Example:
+//
+//	p := spec.NewPipeline().
+//	    SortBy("created_at", -1).
+//	    Project(bson.M{"status": 1, "created_at": 1}).
+//	    Match(spec.Eq("status", "active"))
+//	for _, r := range p.Optimize() {
+//	    log.Println(r) // moved $match at stage 2 before $project, ...
+//	}
+//	// [{$match: {status: "active"}}, {$sort: ...}, {$project: ...}]
+func (p *Pipeline) Optimize() []Rewrite {
+	var rewrites []Rewrite
+	// origin tracks the original index of each stage for the report.
+	origin := make([]int, len(p.stages))
+	for i := range origin {
+		origin[i] = i
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := 1; i < len(p.stages); i++ {
+			filter, ok := matchFilter(p.stages[i])
+			if !ok {
+				continue
+			}
+			prev := p.stages[i-1]
+			if prevFilter, ok := matchFilter(prev); ok {
+				p.stages[i-1] = bson.M{"$match": mergeFilters(prevFilter, filter)}
+				rewrites = append(rewrites, Rewrite{Rule: "merge-match", Stage: origin[i]})
+				p.stages = append(p.stages[:i], p.stages[i+1:]...)
+				origin = append(origin[:i], origin[i+1:]...)
+				changed = true
+				break
+			}
+			if rule, ok := canPushMatch(prev, filter); ok {
+				p.stages[i-1], p.stages[i] = p.stages[i], prev
+				origin[i-1], origin[i] = origin[i], origin[i-1]
+				rewrites = append(rewrites, Rewrite{Rule: rule, Stage: origin[i-1]})
+				changed = true
+				break
+			}
+		}
+	}
+	return rewrites
+}
+
+// matchFilter returns the filter of a $match stage.
+func matchFilter(stage bson.M) (any, bool) {
+	if len(stage) != 1 {
+		return nil, false
+	}
+	f, ok := stage["$match"]
+	return f, ok
+}
+
+// mergeFilters combines two filters with $and, flattening existing $and lists.
+func mergeFilters(a, b any) bson.M {
+	var clauses bson.A
+	for _, f := range []any{a, b} {
+		if m, ok := f.(bson.M); ok && len(m) == 1 {
+			if and, ok := filterList(m["$and"]); ok {
+				clauses = append(clauses, and...)
+				continue
+			}
+		}
+		clauses = append(clauses, f)
+	}
+	return bson.M{"$and": clauses}
+}
+
+// canPushMatch reports whether a $match with filter can move before stage, and
+// the rule that allows it.
+func canPushMatch(stage bson.M, filter any) (string, bool) {
+	if len(stage) != 1 {
+		return "", false
+	}
+	for op, spec := range stage {
+		if op == "$sort" {
+			return "match-before-sort", true
+		}
+		fields, ok := filterFields(filter)
+		if !ok {
+			return "", false
+		}
+		switch op {
+		case "$project":
+			return "match-before-project", projectionPassesThrough(spec, fields)
+		case "$addFields", "$set":
+			added, ok := stageKeys(spec)
+			return "match-before-set", ok && !anyOverlap(fields, added)
+		case "$unset":
+			removed, ok := unsetFields(spec)
+			return "match-before-unset", ok && !anyOverlap(fields, removed)
+		}
+	}
+	return "", false
+}
+
+// filterFields returns the field paths a filter reads. It reports false for
+// filters whose dependencies cannot be determined.
+func filterFields(filter any) ([]string, bool) {
+	pairs, ok := docPairs(filter)
+	if !ok {
+		return nil, false
+	}
+	var fields []string
+	for _, kv := range pairs {
+		if !strings.HasPrefix(kv.Key, "$") {
+			fields = append(fields, kv.Key)
+			continue
+		}
+		switch kv.Key {
+		case "$and", "$or", "$nor":
+		default:
+			return nil, false
+		}
+		clauses, ok := filterList(kv.Value)
+		if !ok {
+			return nil, false
+		}
+		for _, c := range clauses {
+			sub, ok := filterFields(c)
+			if !ok {
+				return nil, false
+			}
+			fields = append(fields, sub...)
+		}
+	}
+	return fields, true
+}
+
+// filterList returns the clauses of an $and, $or, or $nor.
+func filterList(v any) (bson.A, bool) {
+	switch l := v.(type) {
+	case bson.A:
+		return l, true
+	case []any:
+		return l, true
+	case []bson.M:
+		out := make(bson.A, len(l))
+		for i, f := range l {
+			out[i] = f
+		}
+		return out, true
+	case []bson.D:
+		out := make(bson.A, len(l))
+		for i, f := range l {
+			out[i] = f
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// projectionPassesThrough reports whether every field survives a $project
+// unchanged.
+func projectionPassesThrough(projection any, fields []string) bool {
+	pairs, ok := docPairs(projection)
+	if !ok {
+		return false
+	}
+	included := map[string]bool{}
+	excluded := map[string]bool{}
+	var computed []string
+	idExcluded := false
+	for _, kv := range pairs {
+		switch projectionFlag(kv.Value) {
+		case 1:
+			included[kv.Key] = true
+		case 0:
+			if kv.Key == "_id" {
+				idExcluded = true
+			} else {
+				excluded[kv.Key] = true
+			}
+		default:
+			computed = append(computed, kv.Key)
+		}
+	}
+	if len(included) > 0 || len(computed) > 0 {
+		if !idExcluded {
+			included["_id"] = true
+		}
+		for _, f := range fields {
+			if !coveredBy(f, included) || anyOverlap([]string{f}, computed) || hasSubpath(f, included) {
+				return false
+			}
+		}
+		return true
+	}
+	if idExcluded {
+		excluded["_id"] = true
+	}
+	for f := range excluded {
+		if anyOverlap(fields, []string{f}) {
+			return false
+		}
+	}
+	return true
+}
+
+// projectionFlag returns 1 for inclusion, 0 for exclusion, and -1 for computed
+// projection values.
+func projectionFlag(v any) int {
+	switch n := v.(type) {
+	case bool:
+		return flag(n)
+	case int:
+		return flag(n != 0)
+	case int32:
+		return flag(n != 0)
+	case int64:
+		return flag(n != 0)
+	case float64:
+		return flag(n != 0)
+	}
+	return -1
+}
+
+func flag(include bool) int {
+	if include {
+		return 1
+	}
+	return 0
+}
+
+// coveredBy reports whether f or one of its parents is in set.
+func coveredBy(f string, set map[string]bool) bool {
+	for p := f; ; {
+		if set[p] {
+			return true
+		}
+		i := strings.LastIndex(p, ".")
+		if i < 0 {
+			return false
+		}
+		p = p[:i]
+	}
+}
+
+// hasSubpath reports whether set contains a strict subpath of f, which means
+// only part of f is included.
+func hasSubpath(f string, set map[string]bool) bool {
+	for k := range set {
+		if strings.HasPrefix(k, f+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// anyOverlap reports whether a path in a equals, contains, or is contained by
+// a path in b.
+func anyOverlap(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y || strings.HasPrefix(x, y+".") || strings.HasPrefix(y, x+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stageKeys returns the top-level keys of a stage document.
+func stageKeys(spec any) ([]string, bool) {
+	pairs, ok := docPairs(spec)
+	if !ok {
+		return nil, false
+	}
+	keys := make([]string, len(pairs))
+	for i, kv := range pairs {
+		keys[i] = kv.Key
+	}
+	return keys, true
+}
+
+// unsetFields returns the fields removed by an $unset stage.
+func unsetFields(spec any) ([]string, bool) {
+	switch v := spec.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	case bson.A:
+		out := make([]string, 0, len(v))
+		for _, f := range v {
+			s, ok := f.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, s)
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// docPairs returns the elements of a bson.M or bson.D.
+func docPairs(doc any) (bson.D, bool) {
+	switch d := doc.(type) {
+	case bson.D:
+		return d, true
+	case bson.M:
+		out := make(bson.D, 0, len(d))
+		for k, v := range d {
+			out = append(out, bson.E{Key: k, Value: v})
+		}
+		return out, true
+	}
+	return nil, false
+}
@@ -0,0 +1,127 @@
+package spec_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestOptimize_PushesMatchBeforeSortAndProject(t *testing.T) {
+	p := spec.NewPipeline().
+		SortBy("created_at", -1).
+		Project(bson.M{"status": 1, "created_at": 1}).
+		Match(spec.Eq("status", "active"))
+
+	rewrites := p.Optimize()
+
+	want := []bson.M{
+		{"$match": bson.M{"status": "active"}},
+		{"$sort": bson.M{"created_at": -1}},
+		{"$project": bson.M{"status": 1, "created_at": 1}},
+	}
+	if got := stages(t, p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Optimize mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+	wantRewrites := []spec.Rewrite{
+		{Rule: "match-before-project", Stage: 2},
+		{Rule: "match-before-sort", Stage: 2},
+	}
+	if !reflect.DeepEqual(rewrites, wantRewrites) {
+		t.Fatalf("rewrites mismatch.\n got: %v\nwant: %v", rewrites, wantRewrites)
+	}
+	if s := rewrites[1].String(); s != "moved $match at stage 2 before $sort" {
+		t.Fatalf("unexpected description %q", s)
+	}
+}
+
+func TestOptimize_MergesConsecutiveMatches(t *testing.T) {
+	p := spec.NewPipeline().
+		Match(spec.And(spec.Eq("a", 1), spec.Eq("b", 2))).
+		SortBy("a", 1).
+		Match(spec.Eq("c", 3))
+
+	rewrites := p.Optimize()
+
+	want := []bson.M{
+		{"$match": bson.M{"$and": bson.A{bson.M{"a": 1}, bson.M{"b": 2}, bson.M{"c": 3}}}},
+		{"$sort": bson.M{"a": 1}},
+	}
+	if got := stages(t, p); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Optimize mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+	if len(rewrites) != 2 || rewrites[1].Rule != "merge-match" || rewrites[1].Stage != 2 {
+		t.Fatalf("unexpected rewrites %v", rewrites)
+	}
+	if s := rewrites[1].String(); s != "merged $match at stage 2 into the previous $match" {
+		t.Fatalf("unexpected description %q", s)
+	}
+}
+
+func TestOptimize_KeepsUnsafeOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline *spec.Pipeline
+	}{
+		{"computed field", spec.NewPipeline().
+			Project(bson.M{"total": bson.M{"$sum": "$items.price"}}).
+			Match(spec.Gt("total", 100))},
+		{"excluded field", spec.NewPipeline().
+			Project(bson.M{"secret": 0}).
+			Match(spec.Exists("secret", false))},
+		{"not included", spec.NewPipeline().
+			Project(bson.M{"name": 1}).
+			Match(spec.Exists("email", false))},
+		{"partially included", spec.NewPipeline().
+			Project(bson.M{"address.city": 1}).
+			Match(spec.Eq("address", bson.M{"city": "Lisbon"}))},
+		{"excluded _id", spec.NewPipeline().
+			Project(bson.M{"name": 1, "_id": 0}).
+			Match(spec.Exists("_id", false))},
+		{"overwritten field", spec.NewPipeline().
+			AddFields(bson.M{"profile.age": 1}).
+			Match(spec.Gt("profile", nil))},
+		{"unset field", spec.NewPipeline().
+			Unset("token").
+			Match(spec.Exists("token", false))},
+		{"limit", spec.NewPipeline().
+			Limit(10).
+			Match(spec.Eq("status", "active"))},
+		{"group", spec.NewPipeline().
+			GroupBy("$status", bson.M{"n": spec.Sum(1)}).
+			Match(spec.Gt("n", 1))},
+		{"expr", spec.NewPipeline().
+			Set(bson.M{"x": 1}).
+			Match(spec.ExprGt("y", 1))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := append([]bson.M(nil), stages(t, tt.pipeline)...)
+			if rewrites := tt.pipeline.Optimize(); len(rewrites) != 0 {
+				t.Fatalf("expected no rewrites, got %v", rewrites)
+			}
+			if got := stages(t, tt.pipeline); !reflect.DeepEqual(got, before) {
+				t.Fatalf("pipeline changed.\n got: %#v\nwant: %#v", got, before)
+			}
+		})
+	}
+}
+
+func TestOptimize_PushesPastUnrelatedStages(t *testing.T) {
+	p := spec.NewPipeline().
+		Project(bson.M{"password": 0}).
+		Set(bson.M{"full_name": bson.M{"$concat": bson.A{"$first", " ", "$last"}}}).
+		Unset("internal").
+		Match(spec.Or(spec.Eq("status", "active"), spec.Eq("address.city", "Lisbon")))
+
+	rewrites := p.Optimize()
+
+	if len(rewrites) != 3 {
+		t.Fatalf("expected 3 rewrites, got %v", rewrites)
+	}
+	if got := stages(t, p); got[0]["$match"] == nil {
+		t.Fatalf("expected $match first, got %#v", got)
+	}
+}
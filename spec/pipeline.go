@@ -1,10 +1,26 @@
 package spec
 
-import "go.mongodb.org/mongo-driver/bson"
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// ErrInvalidPipeline is returned by Pipeline.ToPipeline and Pipeline.Validate,
+// and by the repositories' Aggregate methods, for a pipeline a builder method
+// rejected.
+var ErrInvalidPipeline = errors.New("spec: invalid pipeline")
 
 // Pipeline represents a MongoDB aggregation pipeline.
+//
+// Builder methods do not panic on invalid arguments: the first one rejected is
+// recorded, and ToPipeline and Validate return the error. The repositories'
+// Aggregate methods refuse to run such a pipeline.
 type Pipeline struct {
 	stages []bson.M
+	err    error
 }
 
 // NewPipeline creates a new empty aggregation pipeline.
@@ -12,9 +28,73 @@ func NewPipeline() *Pipeline {
 	return &Pipeline{stages: make([]bson.M, 0)}
 }
 
-// ToPipeline returns the pipeline as []bson.M for use with MongoDB driver.
-func (p *Pipeline) ToPipeline() []bson.M {
-	return p.stages
+// ToPipeline returns the pipeline as []bson.M for use with MongoDB driver. It
+// returns no stages and an error wrapping ErrInvalidPipeline if a builder
+// method rejected its arguments, so an invalid pipeline never runs.
+func (p *Pipeline) ToPipeline() ([]bson.M, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p.stages, nil
+}
+
+// Validate returns the error of the first builder method that rejected its
+// arguments, wrapping ErrInvalidPipeline, or nil.
+func (p *Pipeline) Validate() error {
+	if p.err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPipeline, p.err)
+	}
+	return nil
+}
+
+// fail records an invalid builder call, keeping the first one.
+func (p *Pipeline) fail(format string, args ...any) {
+	if p.err == nil {
+		p.err = fmt.Errorf(format, args...)
+	}
+}
+
+// add appends stages. It records an error instead if the pipeline already
+// ends with $out or $merge, which must be the last stage.
+func (p *Pipeline) add(stages ...bson.M) {
+	if n := len(p.stages); n > 0 {
+		if op, ok := terminalStage(p.stages[n-1]); ok {
+			p.fail("cannot add a stage after %s, which must be the last stage", op)
+			return
+		}
+	}
+	p.stages = append(p.stages, stages...)
+}
+
+// invalidArg stands in for a value a helper such as MovingAvg or MaxDepth
+// could not build. The builder method its document is passed to records the
+// error, and it fails to encode, so it never reaches the server even when
+// the document is used directly.
+type invalidArg struct{ err error }
+
+// MarshalBSONValue implements bson.ValueMarshaler by returning the error.
+func (a invalidArg) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return 0, nil, fmt.Errorf("%w: %v", ErrInvalidPipeline, a.err)
+}
+
+// invalid returns the error of an invalidArg among the values of m, if any.
+func invalid(m bson.M) error {
+	for _, v := range m {
+		if a, ok := v.(invalidArg); ok {
+			return a.err
+		}
+	}
+	return nil
+}
+
+// terminalStage reports whether stage is an $out or $merge stage.
+func terminalStage(stage bson.M) (string, bool) {
+	for _, op := range []string{"$out", "$merge"} {
+		if _, ok := stage[op]; ok {
+			return op, true
+		}
+	}
+	return "", false
 }
 
 // Match adds a $match stage to filter documents.
@@ -24,7 +104,7 @@ func (p *Pipeline) ToPipeline() []bson.M {
 //	pipeline.Match(spec.Eq("status", "active"))
 func (p *Pipeline) Match(filter Filter) *Pipeline {
 	if filter != nil {
-		p.stages = append(p.stages, bson.M{"$match": filter.ToMongo()})
+		p.add(bson.M{"$match": filter.ToMongo()})
 	}
 	return p
 }
@@ -32,7 +112,7 @@ func (p *Pipeline) Match(filter Filter) *Pipeline {
 // MatchRaw adds a $match stage with a raw bson.M filter.
 func (p *Pipeline) MatchRaw(filter bson.M) *Pipeline {
 	if filter != nil {
-		p.stages = append(p.stages, bson.M{"$match": filter})
+		p.add(bson.M{"$match": filter})
 	}
 	return p
 }
@@ -43,7 +123,7 @@ func (p *Pipeline) MatchRaw(filter bson.M) *Pipeline {
 //
 //	pipeline.Project(bson.M{"name": 1, "total": 1, "_id": 0})
 func (p *Pipeline) Project(projection bson.M) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$project": projection})
+	p.add(bson.M{"$project": projection})
 	return p
 }
 
@@ -57,7 +137,7 @@ func (p *Pipeline) Project(projection bson.M) *Pipeline {
 //	    "count": bson.M{"$sum": 1},
 //	})
 func (p *Pipeline) Group(groupSpec bson.M) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$group": groupSpec})
+	p.add(bson.M{"$group": groupSpec})
 	return p
 }
 
@@ -73,7 +153,7 @@ func (p *Pipeline) GroupBy(idExpr any, accumulators bson.M) *Pipeline {
 	for k, v := range accumulators {
 		groupSpec[k] = v
 	}
-	p.stages = append(p.stages, bson.M{"$group": groupSpec})
+	p.add(bson.M{"$group": groupSpec})
 	return p
 }
 
@@ -83,7 +163,7 @@ func (p *Pipeline) GroupBy(idExpr any, accumulators bson.M) *Pipeline {
 //
 //	pipeline.Sort(bson.D{{"total", -1}, {"name", 1}})
 func (p *Pipeline) Sort(sort any) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$sort": sort})
+	p.add(bson.M{"$sort": sort})
 	return p
 }
 
@@ -94,19 +174,19 @@ func (p *Pipeline) Sort(sort any) *Pipeline {
 //
 //	pipeline.SortBy("created_at", -1)  // Most recent first
 func (p *Pipeline) SortBy(field string, order int) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$sort": bson.M{field: order}})
+	p.add(bson.M{"$sort": bson.M{field: order}})
 	return p
 }
 
 // Limit adds a $limit stage to restrict the number of documents.
 func (p *Pipeline) Limit(n int64) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$limit": n})
+	p.add(bson.M{"$limit": n})
 	return p
 }
 
 // Skip adds a $skip stage to skip a number of documents.
 func (p *Pipeline) Skip(n int64) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$skip": n})
+	p.add(bson.M{"$skip": n})
 	return p
 }
 
@@ -116,7 +196,7 @@ func (p *Pipeline) Skip(n int64) *Pipeline {
 //
 //	pipeline.Unwind("$items")
 func (p *Pipeline) Unwind(path string) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$unwind": path})
+	p.add(bson.M{"$unwind": path})
 	return p
 }
 
@@ -133,7 +213,7 @@ func (p *Pipeline) UnwindWithOptions(path string, preserveNullAndEmpty bool, inc
 	if includeArrayIndex != "" {
 		unwindSpec["includeArrayIndex"] = includeArrayIndex
 	}
-	p.stages = append(p.stages, bson.M{"$unwind": unwindSpec})
+	p.add(bson.M{"$unwind": unwindSpec})
 	return p
 }
 
@@ -143,7 +223,7 @@ func (p *Pipeline) UnwindWithOptions(path string, preserveNullAndEmpty bool, inc
 //
 //	pipeline.Lookup("orders", "customer_id", "_id", "customerOrders")
 func (p *Pipeline) Lookup(from, localField, foreignField, as string) *Pipeline {
-	p.stages = append(p.stages, bson.M{
+	p.add(bson.M{
 		"$lookup": bson.M{
 			"from":         from,
 			"localField":   localField,
@@ -154,17 +234,96 @@ func (p *Pipeline) Lookup(from, localField, foreignField, as string) *Pipeline {
 	return p
 }
 
-// LookupWithPipeline adds a $lookup stage with a sub-pipeline.
-func (p *Pipeline) LookupWithPipeline(from string, let bson.M, pipeline []bson.M, as string) *Pipeline {
+// LookupWithPipeline adds a $lookup stage with a sub-pipeline. The
+// sub-pipeline may be a *Pipeline, a Filter (run as a single $match stage), a
+// []bson.M, or nil to join every document of from. Use Var and the Expr
+// filters to refer to let variables from the sub-pipeline.
+//
+// Example:
+//
+//	pipeline.LookupWithPipeline("orders",
+//	    bson.M{"customerId": "$_id"},
+//	    spec.NewPipeline().
+//	        Match(spec.And(spec.ExprEq("customer_id", spec.Var("customerId")), spec.Eq("status", "paid"))).
+//	        SortBy("created_at", -1),
+//	    "paidOrders",
+//	)
+func (p *Pipeline) LookupWithPipeline(from string, let bson.M, pipeline any, as string) *Pipeline {
+	stages, err := subPipeline(pipeline)
+	if err != nil {
+		p.fail("LookupWithPipeline: %v", err)
+		return p
+	}
 	lookupSpec := bson.M{
 		"from":     from,
-		"pipeline": pipeline,
+		"pipeline": stages,
 		"as":       as,
 	}
 	if let != nil {
 		lookupSpec["let"] = let
 	}
-	p.stages = append(p.stages, bson.M{"$lookup": lookupSpec})
+	p.add(bson.M{"$lookup": lookupSpec})
+	return p
+}
+
+// subPipeline converts a LookupWithPipeline or UnionWith sub-pipeline to stages.
+func subPipeline(pipeline any) ([]bson.M, error) {
+	switch v := pipeline.(type) {
+	case nil:
+		return []bson.M{}, nil
+	case *Pipeline:
+		if v == nil {
+			return []bson.M{}, nil
+		}
+		if v.err != nil {
+			return nil, v.err
+		}
+		return v.stages, nil
+	case Filter:
+		return []bson.M{{"$match": v.ToMongo()}}, nil
+	case []bson.M:
+		if v == nil {
+			return []bson.M{}, nil
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported sub-pipeline %T", pipeline)
+	}
+}
+
+// UnionWith adds a $unionWith stage, which appends the documents of another
+// collection in the same database to the results, such as an archive of the
+// current collection. Optional pipelines, accepted in the same forms as in
+// LookupWithPipeline, run on that collection first and are concatenated.
+// They must not contain $out or $merge.
+//
+// Example:
+//
+//	pipeline := spec.NewPipeline().
+//	    Match(spec.Eq("customer_id", id)).
+//	    UnionWith("orders_archive", spec.Eq("customer_id", id)).
+//	    SortBy("created_at", -1)
+func (p *Pipeline) UnionWith(collection string, pipeline ...any) *Pipeline {
+	if len(pipeline) == 0 {
+		p.add(bson.M{"$unionWith": collection})
+		return p
+	}
+	stages := []bson.M{}
+	for _, sub := range pipeline {
+		s, err := subPipeline(sub)
+		if err != nil {
+			p.fail("UnionWith: %v", err)
+			return p
+		}
+		stages = append(stages, s...)
+	}
+	for _, stage := range stages {
+		if op, ok := terminalStage(stage); ok {
+			p.fail("UnionWith: %s is not allowed in a $unionWith pipeline", op)
+			return p
+		}
+	}
+	p.add(bson.M{"$unionWith": bson.M{"coll": collection, "pipeline": stages}})
 	return p
 }
 
@@ -174,13 +333,13 @@ func (p *Pipeline) LookupWithPipeline(from string, let bson.M, pipeline []bson.M
 //
 //	pipeline.AddFields(bson.M{"fullName": bson.M{"$concat": []string{"$firstName", " ", "$lastName"}}})
 func (p *Pipeline) AddFields(fields bson.M) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$addFields": fields})
+	p.add(bson.M{"$addFields": fields})
 	return p
 }
 
 // Set is an alias for AddFields (MongoDB 4.2+).
 func (p *Pipeline) Set(fields bson.M) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$set": fields})
+	p.add(bson.M{"$set": fields})
 	return p
 }
 
@@ -191,9 +350,9 @@ func (p *Pipeline) Set(fields bson.M) *Pipeline {
 //	pipeline.Unset("password", "internalField")
 func (p *Pipeline) Unset(fields ...string) *Pipeline {
 	if len(fields) == 1 {
-		p.stages = append(p.stages, bson.M{"$unset": fields[0]})
+		p.add(bson.M{"$unset": fields[0]})
 	} else {
-		p.stages = append(p.stages, bson.M{"$unset": fields})
+		p.add(bson.M{"$unset": fields})
 	}
 	return p
 }
@@ -204,7 +363,32 @@ func (p *Pipeline) Unset(fields ...string) *Pipeline {
 //
 //	pipeline.ReplaceRoot("$embedded")
 func (p *Pipeline) ReplaceRoot(newRoot any) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$replaceRoot": bson.M{"newRoot": newRoot}})
+	p.add(bson.M{"$replaceRoot": bson.M{"newRoot": newRoot}})
+	return p
+}
+
+// ReplaceWith adds a $replaceWith stage that replaces each document with the
+// result of expr. It is shorthand for ReplaceRoot.
+//
+// Example:
+//
+//	pipeline.ReplaceWith("$profile")
+//	pipeline.ReplaceWith(bson.M{"$mergeObjects": bson.A{"$defaults", "$$ROOT"}})
+func (p *Pipeline) ReplaceWith(expr any) *Pipeline {
+	p.add(bson.M{"$replaceWith": expr})
+	return p
+}
+
+// Redact adds a $redact stage that walks each document and decides, level by
+// level, whether to keep, prune, or descend into it. expr must evaluate to
+// Descend, Prune, or Keep; build it with RedactIf or RedactByLabels.
+//
+// Example:
+//
+//	// Drop whole sections (and documents) the caller's roles can't see
+//	pipeline.Redact(spec.RedactByLabels("acl", user.Roles...))
+func (p *Pipeline) Redact(expr any) *Pipeline {
+	p.add(bson.M{"$redact": expr})
 	return p
 }
 
@@ -214,7 +398,19 @@ func (p *Pipeline) ReplaceRoot(newRoot any) *Pipeline {
 //
 //	pipeline.Count("total")
 func (p *Pipeline) Count(field string) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$count": field})
+	p.add(bson.M{"$count": field})
+	return p
+}
+
+// SortByCount adds a $sortByCount stage that groups documents by expr and
+// sorts the groups by count, largest first. Each result is {_id: value,
+// count: n}.
+//
+// Example:
+//
+//	pipeline.SortByCount("$category")
+func (p *Pipeline) SortByCount(expr any) *Pipeline {
+	p.add(bson.M{"$sortByCount": expr})
 	return p
 }
 
@@ -227,7 +423,7 @@ func (p *Pipeline) Count(field string) *Pipeline {
 //	    "byStatus": []bson.M{{"$group": bson.M{"_id": "$status"}}},
 //	})
 func (p *Pipeline) Facet(facets bson.M) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$facet": facets})
+	p.add(bson.M{"$facet": facets})
 	return p
 }
 
@@ -243,44 +439,111 @@ func (p *Pipeline) Bucket(groupBy any, boundaries []any, defaultBucket any, outp
 	if output != nil {
 		bucketSpec["output"] = output
 	}
-	p.stages = append(p.stages, bson.M{"$bucket": bucketSpec})
+	p.add(bson.M{"$bucket": bucketSpec})
 	return p
 }
 
 // Sample adds a $sample stage to randomly select documents.
 func (p *Pipeline) Sample(size int64) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$sample": bson.M{"size": size}})
+	p.add(bson.M{"$sample": bson.M{"size": size}})
 	return p
 }
 
-// Out adds an $out stage to write results to a collection.
-// Note: This must be the last stage in the pipeline.
+// Out adds an $out stage to write results to a collection, replacing it.
+// It must be the last stage: Validate reports a stage added after it.
 func (p *Pipeline) Out(collection string) *Pipeline {
-	p.stages = append(p.stages, bson.M{"$out": collection})
+	p.add(bson.M{"$out": collection})
 	return p
 }
 
-// Merge adds a $merge stage to write results to a collection (MongoDB 4.2+).
-// Note: This must be the last stage in the pipeline.
-func (p *Pipeline) Merge(into string, on []string, whenMatched, whenNotMatched string) *Pipeline {
+// WhenMatched is the $merge action for results that match an existing
+// document.
+type WhenMatched string
+
+// WhenMatched actions.
+const (
+	WhenMatchedReplace      WhenMatched = "replace"      // replace the existing document
+	WhenMatchedKeepExisting WhenMatched = "keepExisting" // keep the existing document
+	WhenMatchedMerge        WhenMatched = "merge"        // merge the result into the existing document
+	WhenMatchedFail         WhenMatched = "fail"         // stop the aggregation with an error
+)
+
+// WhenNotMatched is the $merge action for results that match no existing
+// document.
+type WhenNotMatched string
+
+// WhenNotMatched actions.
+const (
+	WhenNotMatchedInsert  WhenNotMatched = "insert"  // insert the result
+	WhenNotMatchedDiscard WhenNotMatched = "discard" // drop the result
+	WhenNotMatchedFail    WhenNotMatched = "fail"    // stop the aggregation with an error
+)
+
+// MergeTarget is the collection a $merge stage writes to. DB defaults to the
+// database the aggregation runs in.
+type MergeTarget struct {
+	DB   string
+	Coll string
+}
+
+// Merge adds a $merge stage that writes results to a collection in the same
+// database (MongoDB 4.2+). Empty actions use the server defaults (merge and
+// insert). It must be the last stage: Validate reports a stage added after it.
+//
+// Example:
+//
+//	pipeline.Merge("daily_totals", []string{"_id"}, spec.WhenMatchedReplace, spec.WhenNotMatchedInsert)
+func (p *Pipeline) Merge(into string, on []string, whenMatched WhenMatched, whenNotMatched WhenNotMatched) *Pipeline {
+	return p.MergeInto(MergeTarget{Coll: into}, on, whenMatched, whenNotMatched)
+}
+
+// MergeInto is like Merge but can write to a collection in another database.
+// Validate reports a target without a collection and unknown actions.
+//
+// Example:
+//
+//	pipeline.MergeInto(spec.MergeTarget{DB: "reporting", Coll: "daily_totals"},
+//	    []string{"_id"}, spec.WhenMatchedReplace, spec.WhenNotMatchedInsert)
+func (p *Pipeline) MergeInto(target MergeTarget, on []string, whenMatched WhenMatched, whenNotMatched WhenNotMatched) *Pipeline {
+	if target.Coll == "" {
+		p.fail("$merge target has no collection")
+		return p
+	}
+	switch whenMatched {
+	case "", WhenMatchedReplace, WhenMatchedKeepExisting, WhenMatchedMerge, WhenMatchedFail:
+	default:
+		p.fail("unknown $merge whenMatched action %q", whenMatched)
+		return p
+	}
+	switch whenNotMatched {
+	case "", WhenNotMatchedInsert, WhenNotMatchedDiscard, WhenNotMatchedFail:
+	default:
+		p.fail("unknown $merge whenNotMatched action %q", whenNotMatched)
+		return p
+	}
+
+	var into any = target.Coll
+	if target.DB != "" {
+		into = bson.M{"db": target.DB, "coll": target.Coll}
+	}
 	mergeSpec := bson.M{"into": into}
 	if len(on) > 0 {
 		mergeSpec["on"] = on
 	}
 	if whenMatched != "" {
-		mergeSpec["whenMatched"] = whenMatched
+		mergeSpec["whenMatched"] = string(whenMatched)
 	}
 	if whenNotMatched != "" {
-		mergeSpec["whenNotMatched"] = whenNotMatched
+		mergeSpec["whenNotMatched"] = string(whenNotMatched)
 	}
-	p.stages = append(p.stages, bson.M{"$merge": mergeSpec})
+	p.add(bson.M{"$merge": mergeSpec})
 	return p
 }
 
 // Raw adds a raw stage to the pipeline.
 // Use this for stages not covered by the builder.
 func (p *Pipeline) Raw(stage bson.M) *Pipeline {
-	p.stages = append(p.stages, stage)
+	p.add(stage)
 	return p
 }
 
@@ -330,3 +593,59 @@ func PushAcc(expr any) bson.M {
 func AddToSetAcc(expr any) bson.M {
 	return bson.M{"$addToSet": expr}
 }
+
+// ---- $redact helpers for use with Redact ----
+
+// Results of a $redact expression.
+const (
+	// Descend keeps the fields at the current level and evaluates the
+	// expression again for each embedded document.
+	Descend = "$$DESCEND"
+
+	// Prune removes the current level, including its embedded documents.
+	Prune = "$$PRUNE"
+
+	// Keep keeps the current level, including its embedded documents,
+	// without evaluating them.
+	Keep = "$$KEEP"
+)
+
+// RedactIf creates a $redact expression that results in then when cond is
+// true and in otherwise when it is not.
+//
+// Example:
+//
+//	spec.RedactIf(bson.M{"$eq": bson.A{"$tenant_id", tenantID}}, spec.Descend, spec.Prune)
+func RedactIf(cond any, then, otherwise string) bson.M {
+	return bson.M{"$cond": bson.M{"if": cond, "then": then, "else": otherwise}}
+}
+
+// RedactByLabels creates the usual field-level access $redact expression: at
+// every level, field holds an array of labels (roles, groups, clearances), and
+// the level is pruned unless it shares at least one label with allowed.
+// Levels without field are not restricted, so unlabeled documents and
+// subdocuments stay visible. With no allowed labels, every labeled level is
+// pruned.
+//
+// Example:
+//
+//	// {title: "Q3", acl: ["staff"], financials: {acl: ["finance"], revenue: 10}}
+//	spec.RedactByLabels("acl", "staff") // keeps the title, removes financials
+func RedactByLabels(field string, allowed ...string) bson.M {
+	labels := make(bson.A, 0, len(allowed))
+	for _, l := range allowed {
+		labels = append(labels, l)
+	}
+	visible := bson.M{"$gt": bson.A{
+		bson.M{"$size": bson.M{"$setIntersection": bson.A{
+			bson.M{"$ifNull": bson.A{"$" + field, labels}},
+			labels,
+		}}},
+		0,
+	}}
+	if len(allowed) == 0 {
+		// $ifNull's fallback is empty, so check for the field explicitly.
+		visible = bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$" + field, nil}}, nil}}
+	}
+	return RedactIf(visible, Descend, Prune)
+}
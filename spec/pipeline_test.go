@@ -1,7 +1,9 @@
 package spec_test
 
 import (
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/dElCIoGio/mongox/spec"
@@ -13,7 +15,10 @@ func TestPipelineMatch(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Match(spec.Eq("status", "active"))
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$match": bson.M{"status": "active"}},
 	}
@@ -27,7 +32,10 @@ func TestPipelineMatchWithNil(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Match(nil)
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	if len(got) != 0 {
 		t.Fatalf("Pipeline Match(nil) should add no stages, got: %#v", got)
 	}
@@ -37,7 +45,10 @@ func TestPipelineProject(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Project(bson.M{"name": 1, "_id": 0})
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$project": bson.M{"name": 1, "_id": 0}},
 	}
@@ -54,7 +65,10 @@ func TestPipelineGroup(t *testing.T) {
 			"total": bson.M{"$sum": "$amount"},
 		})
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$group": bson.M{
 			"_id":   "$category",
@@ -74,7 +88,10 @@ func TestPipelineGroupBy(t *testing.T) {
 			"total": spec.Sum("$amount"),
 		})
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// Check that the stage has $group with _id and accumulators
 	if len(got) != 1 {
@@ -99,7 +116,10 @@ func TestPipelineSort(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Sort(bson.D{{"total", -1}, {"name", 1}})
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$sort": bson.D{{"total", -1}, {"name", 1}}},
 	}
@@ -113,7 +133,10 @@ func TestPipelineSortBy(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		SortBy("created_at", -1)
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$sort": bson.M{"created_at": -1}},
 	}
@@ -128,7 +151,10 @@ func TestPipelineLimitSkip(t *testing.T) {
 		Skip(10).
 		Limit(5)
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$skip": int64(10)},
 		{"$limit": int64(5)},
@@ -143,7 +169,10 @@ func TestPipelineUnwind(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Unwind("$items")
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$unwind": "$items"},
 	}
@@ -157,7 +186,10 @@ func TestPipelineLookup(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Lookup("orders", "customer_id", "_id", "customerOrders")
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$lookup": bson.M{
 			"from":         "orders",
@@ -172,11 +204,80 @@ func TestPipelineLookup(t *testing.T) {
 	}
 }
 
+func TestPipelineLookupWithPipeline(t *testing.T) {
+	sub := spec.NewPipeline().
+		Match(spec.And(spec.ExprEq("customer_id", spec.Var("customerId")), spec.Eq("status", "paid"))).
+		Limit(5)
+	got, err := spec.NewPipeline().
+		LookupWithPipeline("orders", bson.M{"customerId": "$_id"}, sub, "paidOrders").
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bson.M{
+		{"$lookup": bson.M{
+			"from": "orders",
+			"let":  bson.M{"customerId": "$_id"},
+			"pipeline": []bson.M{
+				{"$match": bson.M{"$and": []bson.M{
+					{"$expr": bson.M{"$eq": bson.A{"$customer_id", "$$customerId"}}},
+					{"status": "paid"},
+				}}},
+				{"$limit": int64(5)},
+			},
+			"as": "paidOrders",
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("LookupWithPipeline mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPipelineLookupWithPipeline_SubPipelineForms(t *testing.T) {
+	raw := []bson.M{{"$match": bson.M{"status": "paid"}}}
+	tests := []struct {
+		name     string
+		pipeline any
+		want     []bson.M
+	}{
+		{"filter", spec.Eq("status", "paid"), raw},
+		{"raw stages", raw, raw},
+		{"nil", nil, []bson.M{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stage := stages(t, spec.NewPipeline().LookupWithPipeline("orders", nil, tt.pipeline, "orders"))[0]
+			lookup := stage["$lookup"].(bson.M)
+			if _, ok := lookup["let"]; ok {
+				t.Fatalf("unexpected let: %#v", lookup)
+			}
+			if !reflect.DeepEqual(lookup["pipeline"], tt.want) {
+				t.Fatalf("pipeline = %#v, want %#v", lookup["pipeline"], tt.want)
+			}
+		})
+	}
+}
+
+func TestExprFilters(t *testing.T) {
+	got := spec.ExprLte("shipped_at", "$due_at").ToMongo()
+	want := bson.M{"$expr": bson.M{"$lte": bson.A{"$shipped_at", "$due_at"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ExprLte mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+	if v := spec.Var("orderId"); v != "$$orderId" {
+		t.Fatalf("Var = %q", v)
+	}
+}
+
 func TestPipelineAddFields(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		AddFields(bson.M{"fullName": bson.M{"$concat": []string{"$first", " ", "$last"}}})
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$addFields": bson.M{"fullName": bson.M{"$concat": []string{"$first", " ", "$last"}}}},
 	}
@@ -190,7 +291,10 @@ func TestPipelineCount(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Count("total")
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$count": "total"},
 	}
@@ -200,11 +304,50 @@ func TestPipelineCount(t *testing.T) {
 	}
 }
 
+func TestPipelineSortByCount(t *testing.T) {
+	pipeline := spec.NewPipeline().
+		Unwind("$tags").
+		SortByCount("$tags")
+
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{
+		{"$unwind": "$tags"},
+		{"$sortByCount": "$tags"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline SortByCount mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPipelineReplaceWith(t *testing.T) {
+	pipeline := spec.NewPipeline().
+		ReplaceWith(bson.M{"$mergeObjects": bson.A{"$defaults", "$$ROOT"}})
+
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{
+		{"$replaceWith": bson.M{"$mergeObjects": bson.A{"$defaults", "$$ROOT"}}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline ReplaceWith mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
 func TestPipelineSample(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Sample(10)
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$sample": bson.M{"size": int64(10)}},
 	}
@@ -224,7 +367,10 @@ func TestPipelineChaining(t *testing.T) {
 		SortBy("total", -1).
 		Limit(10)
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	if len(got) != 4 {
 		t.Fatalf("expected 4 stages, got %d", len(got))
@@ -275,7 +421,10 @@ func TestPipelineRaw(t *testing.T) {
 	pipeline := spec.NewPipeline().
 		Raw(bson.M{"$customStage": bson.M{"option": true}})
 
-	got := pipeline.ToPipeline()
+	got, err := pipeline.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
 	want := []bson.M{
 		{"$customStage": bson.M{"option": true}},
 	}
@@ -284,3 +433,169 @@ func TestPipelineRaw(t *testing.T) {
 		t.Fatalf("Pipeline Raw mismatch.\n got: %#v\nwant: %#v", got, want)
 	}
 }
+
+func TestPipelineRedact(t *testing.T) {
+	got, err := spec.NewPipeline().
+		Redact(spec.RedactIf(bson.M{"$eq": bson.A{"$tenant_id", "t1"}}, spec.Descend, spec.Prune)).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{
+		{"$redact": bson.M{"$cond": bson.M{
+			"if":   bson.M{"$eq": bson.A{"$tenant_id", "t1"}},
+			"then": "$$DESCEND",
+			"else": "$$PRUNE",
+		}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline Redact mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestRedactByLabels(t *testing.T) {
+	got := spec.RedactByLabels("acl", "staff", "finance")
+	labels := bson.A{"staff", "finance"}
+	want := bson.M{"$cond": bson.M{
+		"if": bson.M{"$gt": bson.A{
+			bson.M{"$size": bson.M{"$setIntersection": bson.A{bson.M{"$ifNull": bson.A{"$acl", labels}}, labels}}},
+			0,
+		}},
+		"then": spec.Descend,
+		"else": spec.Prune,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("RedactByLabels mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	none := spec.RedactByLabels("acl")["$cond"].(bson.M)["if"]
+	if !reflect.DeepEqual(none, bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{"$acl", nil}}, nil}}) {
+		t.Fatalf("RedactByLabels without labels: got %#v", none)
+	}
+}
+
+func TestPipelineMerge(t *testing.T) {
+	got, err := spec.NewPipeline().
+		Merge("daily_totals", []string{"_id"}, spec.WhenMatchedReplace, spec.WhenNotMatchedInsert).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{{"$merge": bson.M{
+		"into":           "daily_totals",
+		"on":             []string{"_id"},
+		"whenMatched":    "replace",
+		"whenNotMatched": "insert",
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Merge mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	got, err = spec.NewPipeline().
+		MergeInto(spec.MergeTarget{DB: "reporting", Coll: "daily_totals"}, nil, "", spec.WhenNotMatchedDiscard).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []bson.M{{"$merge": bson.M{
+		"into":           bson.M{"db": "reporting", "coll": "daily_totals"},
+		"whenNotMatched": "discard",
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("MergeInto mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+// stages returns the stages of p, failing the test if p is invalid.
+func stages(t *testing.T, p *spec.Pipeline) []bson.M {
+	t.Helper()
+	s, err := p.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestPipelineTerminalStagesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *spec.Pipeline
+	}{
+		{"stage after $out", spec.NewPipeline().Out("archive").Limit(1)},
+		{"stage after $merge", spec.NewPipeline().Merge("totals", nil, "", "").Match(spec.Eq("a", 1))},
+		{"raw stage after $out", spec.NewPipeline().Raw(bson.M{"$out": "archive"}).Out("again")},
+		{"no collection", spec.NewPipeline().MergeInto(spec.MergeTarget{DB: "reporting"}, nil, "", "")},
+		{"unknown whenMatched", spec.NewPipeline().Merge("totals", nil, "upsert", "")},
+		{"unknown whenNotMatched", spec.NewPipeline().Merge("totals", nil, "", "skip")},
+	}
+	for _, tt := range tests {
+		if err := tt.p.Validate(); !errors.Is(err, spec.ErrInvalidPipeline) {
+			t.Errorf("%s: expected ErrInvalidPipeline, got %v", tt.name, err)
+		}
+	}
+
+	p := spec.NewPipeline().Out("archive").Limit(1).Merge("totals", nil, "upsert", "")
+	if err := p.Validate(); err == nil || !strings.Contains(err.Error(), "after $out") {
+		t.Fatalf("expected the first error to be kept, got %v", err)
+	}
+	if got, err := p.ToPipeline(); got != nil || !errors.Is(err, spec.ErrInvalidPipeline) {
+		t.Fatalf("expected ToPipeline to return ErrInvalidPipeline and no stages, got %v (%v)", got, err)
+	}
+	if err := spec.NewPipeline().Match(spec.Eq("a", 1)).Out("archive").Validate(); err != nil {
+		t.Fatalf("unexpected error for a valid pipeline: %v", err)
+	}
+}
+
+func TestPipelineUnionWith(t *testing.T) {
+	got, err := spec.NewPipeline().
+		UnionWith("orders_archive").
+		UnionWith("invoices_archive", spec.Eq("paid", true), []bson.M{{"$limit": int64(5)}}).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bson.M{
+		{"$unionWith": "orders_archive"},
+		{"$unionWith": bson.M{
+			"coll":     "invoices_archive",
+			"pipeline": []bson.M{{"$match": bson.M{"paid": true}}, {"$limit": int64(5)}},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline UnionWith mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPipelineUnionWithRejectsTerminalStages(t *testing.T) {
+	p := spec.NewPipeline().UnionWith("archive", spec.NewPipeline().Out("copy"))
+	if err := p.Validate(); !errors.Is(err, spec.ErrInvalidPipeline) {
+		t.Fatalf("expected ErrInvalidPipeline for $out in a $unionWith pipeline, got %v", err)
+	}
+}
+
+func TestPipelineInvalidArgsDoNotEncode(t *testing.T) {
+	// Helpers that reject their arguments return values that cannot be sent
+	// to the server, even when used outside the stage that validates them.
+	p := spec.NewPipeline().AddFields(bson.M{"avg": spec.MovingAvg("$x", 0)})
+	stages, err := p.ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bson.Marshal(stages[0]); !errors.Is(err, spec.ErrInvalidPipeline) {
+		t.Fatalf("expected encoding to fail with ErrInvalidPipeline, got %v", err)
+	}
+}
+
+func TestPipelineInvalidSubPipeline(t *testing.T) {
+	tests := map[string]*spec.Pipeline{
+		"invalid lookup pipeline": spec.NewPipeline().LookupWithPipeline("orders", nil, spec.NewPipeline().Merge("t", nil, "upsert", ""), "orders"),
+		"unsupported lookup type": spec.NewPipeline().LookupWithPipeline("orders", nil, 42, "orders"),
+		"invalid union pipeline":  spec.NewPipeline().UnionWith("archive", spec.NewPipeline().Out("a").Limit(1)),
+	}
+	for name, p := range tests {
+		if err := p.Validate(); !errors.Is(err, spec.ErrInvalidPipeline) {
+			t.Errorf("%s: expected ErrInvalidPipeline, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package spec
+
+import (
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var pipelinePool = sync.Pool{
+	New: func() any { return &Pipeline{stages: make([]bson.M, 0, 8)} },
+}
+
+// AcquirePipeline returns an empty Pipeline from a shared pool.
+// Services that build thousands of pipelines per second can use it together with
+// ReleasePipeline to reuse stage slices and reduce GC pressure.
+//
+// The pipeline (and any slice returned by its ToPipeline method) must not be used
+// after it has been released.
+//
+// Example:
+//
+//	p := spec.AcquirePipeline()
+//	defer spec.ReleasePipeline(p)
+//
+//	p.Match(spec.Eq("status", "active")).SortBy("created_at", -1).Limit(20)
+//	results, err := repo.Aggregate(ctx, p)
+func AcquirePipeline() *Pipeline {
+	return pipelinePool.Get().(*Pipeline)
+}
+
+// ReleasePipeline resets p and returns it to the shared pool.
+// Releasing a nil pipeline is a no-op.
+func ReleasePipeline(p *Pipeline) {
+	if p == nil {
+		return
+	}
+	p.Reset()
+	pipelinePool.Put(p)
+}
+
+// Reset removes all stages, and any recorded error, from the pipeline while
+// keeping the allocated capacity, so the pipeline can be rebuilt without
+// reallocating its stage slice.
+func (p *Pipeline) Reset() *Pipeline {
+	clear(p.stages)
+	p.stages = p.stages[:0]
+	p.err = nil
+	return p
+}
+
+// UpdateDoc is a reusable accumulator for combined updates.
+// Unlike Combine, which allocates a fresh result document each time ToBsonUpdate is
+// called, UpdateDoc merges updates into operator documents it keeps across Reset
+// calls. Use AcquireUpdateDoc and ReleaseUpdateDoc to share instances via a pool.
+//
+// UpdateDoc implements Update and can be passed anywhere an update is accepted.
+// It is not safe for concurrent use.
+//
+// Example:
+//
+//	u := spec.AcquireUpdateDoc()
+//	defer spec.ReleaseUpdateDoc(u)
+//
+//	u.Add(spec.Set("status", "shipped"), spec.Inc("version", 1))
+//	_, _, err := repo.UpdateOne(ctx, spec.Eq("_id", id), u)
+type UpdateDoc struct {
+	doc bson.M
+	ops map[string]bson.M
+}
+
+var updateDocPool = sync.Pool{
+	New: func() any { return &UpdateDoc{doc: bson.M{}, ops: map[string]bson.M{}} },
+}
+
+// AcquireUpdateDoc returns an empty UpdateDoc from a shared pool.
+// The UpdateDoc (and any document returned by its ToBsonUpdate method) must not be
+// used after it has been released.
+func AcquireUpdateDoc() *UpdateDoc {
+	return updateDocPool.Get().(*UpdateDoc)
+}
+
+// ReleaseUpdateDoc resets u and returns it to the shared pool.
+// Releasing a nil UpdateDoc is a no-op.
+func ReleaseUpdateDoc(u *UpdateDoc) {
+	if u == nil {
+		return
+	}
+	u.Reset()
+	updateDocPool.Put(u)
+}
+
+// Add merges updates into the document. Operators of the same type are merged
+// field by field, with later updates overwriting earlier ones, matching Combine.
+// Nil updates are ignored.
+func (u *UpdateDoc) Add(updates ...Update) *UpdateDoc {
+	if u.doc == nil {
+		u.doc = bson.M{}
+		u.ops = map[string]bson.M{}
+	}
+	for _, update := range updates {
+		if update == nil {
+			continue
+		}
+		for op, v := range update.ToBsonUpdate() {
+			fields, ok := v.(bson.M)
+			if !ok {
+				u.doc[op] = v
+				continue
+			}
+			target, ok := u.ops[op]
+			if !ok {
+				target = bson.M{}
+				u.ops[op] = target
+			}
+			for field, val := range fields {
+				target[field] = val
+			}
+			u.doc[op] = target
+		}
+	}
+	return u
+}
+
+// ToBsonUpdate returns the accumulated update document.
+// The returned document is owned by the UpdateDoc and is cleared by Reset.
+func (u *UpdateDoc) ToBsonUpdate() bson.M {
+	if u.doc == nil {
+		return bson.M{}
+	}
+	return u.doc
+}
+
+// Reset clears all accumulated operations while keeping allocated maps for reuse.
+func (u *UpdateDoc) Reset() *UpdateDoc {
+	clear(u.doc)
+	for _, fields := range u.ops {
+		clear(fields)
+	}
+	return u
+}
@@ -0,0 +1,78 @@
+package spec_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPipelineReset(t *testing.T) {
+	p := spec.NewPipeline().
+		Match(spec.Eq("status", "active")).
+		Out("archive").
+		Limit(10)
+
+	p.Reset()
+	if n := len(stages(t, p)); n != 0 {
+		t.Fatalf("expected no stages after Reset, got %d", n)
+	}
+
+	got, err := p.SortBy("created_at", -1).ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bson.M{{"$sort": bson.M{"created_at": -1}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline after Reset mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestAcquireReleasePipeline(t *testing.T) {
+	p := spec.AcquirePipeline()
+	p.Match(spec.Eq("a", 1))
+	spec.ReleasePipeline(p)
+
+	p = spec.AcquirePipeline()
+	defer spec.ReleasePipeline(p)
+	if n := len(stages(t, p)); n != 0 {
+		t.Fatalf("expected acquired pipeline to be empty, got %d stages", n)
+	}
+
+	spec.ReleasePipeline(nil)
+}
+
+func TestUpdateDoc(t *testing.T) {
+	u := spec.AcquireUpdateDoc()
+	defer spec.ReleaseUpdateDoc(u)
+
+	got := u.Add(
+		spec.Set("name", "John"),
+		nil,
+		spec.Set("age", 30),
+		spec.Inc("visits", 1),
+	).ToBsonUpdate()
+
+	want := spec.Combine(
+		spec.Set("name", "John"),
+		spec.Set("age", 30),
+		spec.Inc("visits", 1),
+	).ToBsonUpdate()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("UpdateDoc mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	u.Reset()
+	if n := len(u.ToBsonUpdate()); n != 0 {
+		t.Fatalf("expected empty update after Reset, got %#v", u.ToBsonUpdate())
+	}
+
+	got = u.Add(spec.Inc("visits", 2)).ToBsonUpdate()
+	want = bson.M{"$inc": bson.M{"visits": 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("UpdateDoc after Reset mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package spec
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// Projection builds a find projection that selects which fields are returned.
+// Pass it to repository.WithProjection. Combine with Field for field names
+// checked against the document type.
+//
+// MongoDB does not allow mixing included and excluded fields, except for
+// excluding _id from an inclusion projection.
+//
+// Example:
+//
+//	spec.Include("name", "email").Exclude("_id")        // {name: 1, email: 1, _id: 0}
+//	spec.Exclude("history", "attachments")              // everything else
+//	spec.Include(spec.Field[User]("Email")).Slice("tags", 5)
+type Projection struct {
+	fields bson.D
+}
+
+// Include starts a projection that returns only the given fields and _id.
+func Include(fields ...string) *Projection {
+	return (&Projection{}).Include(fields...)
+}
+
+// Exclude starts a projection that returns all fields except the given ones.
+func Exclude(fields ...string) *Projection {
+	return (&Projection{}).Exclude(fields...)
+}
+
+// Include adds fields to return.
+func (p *Projection) Include(fields ...string) *Projection {
+	return p.set(fields, 1)
+}
+
+// Exclude adds fields to leave out.
+func (p *Projection) Exclude(fields ...string) *Projection {
+	return p.set(fields, 0)
+}
+
+// Slice returns at most n elements of the array field: the first n for a
+// positive n, the last -n for a negative n.
+//
+// MongoDB equivalent: {field: {$slice: n}}
+func (p *Projection) Slice(field string, n int) *Projection {
+	return p.set([]string{field}, bson.M{"$slice": n})
+}
+
+// IncludeTextScore starts a projection that returns whole documents plus the
+// relevance score of a Text filter in field.
+func IncludeTextScore(field string) *Projection {
+	return (&Projection{}).IncludeTextScore(field)
+}
+
+// IncludeTextScore adds the relevance score of a Text filter as field. The
+// score field is allowed in both inclusion and exclusion projections.
+//
+// MongoDB equivalent: {field: {$meta: "textScore"}}
+//
+// Example:
+//
+//	spec.Include("title", "summary").IncludeTextScore("score")
+func (p *Projection) IncludeTextScore(field string) *Projection {
+	return p.set([]string{field}, bson.M{"$meta": "textScore"})
+}
+
+// ToProjection returns the projection document.
+func (p *Projection) ToProjection() bson.D {
+	return p.fields
+}
+
+// set adds or replaces the value of each field, keeping the first position.
+func (p *Projection) set(fields []string, value any) *Projection {
+outer:
+	for _, f := range fields {
+		for i := range p.fields {
+			if p.fields[i].Key == f {
+				p.fields[i].Value = value
+				continue outer
+			}
+		}
+		p.fields = append(p.fields, bson.E{Key: f, Value: value})
+	}
+	return p
+}
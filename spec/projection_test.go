@@ -0,0 +1,49 @@
+package spec_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestProjection(t *testing.T) {
+	tests := []struct {
+		name string
+		got  *spec.Projection
+		want bson.D
+	}{
+		{
+			name: "include without _id",
+			got:  spec.Include("name", "email").Exclude("_id"),
+			want: bson.D{{Key: "name", Value: 1}, {Key: "email", Value: 1}, {Key: "_id", Value: 0}},
+		},
+		{
+			name: "exclude",
+			got:  spec.Exclude("history"),
+			want: bson.D{{Key: "history", Value: 0}},
+		},
+		{
+			name: "slice",
+			got:  spec.Include("name").Slice("tags", -3),
+			want: bson.D{{Key: "name", Value: 1}, {Key: "tags", Value: bson.M{"$slice": -3}}},
+		},
+		{
+			name: "text score",
+			got:  spec.Include("title").IncludeTextScore("score"),
+			want: bson.D{{Key: "title", Value: 1}, {Key: "score", Value: bson.M{"$meta": "textScore"}}},
+		},
+		{
+			name: "repeated field keeps position",
+			got:  spec.Include("a", "b").Exclude("a"),
+			want: bson.D{{Key: "a", Value: 0}, {Key: "b", Value: 1}},
+		},
+	}
+	for _, tt := range tests {
+		if got := tt.got.ToProjection(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package spec
+
+import (
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// RawAppender is implemented by filters and updates that can encode themselves
+// directly to BSON bytes without building intermediate bson.M values.
+//
+// The simple comparison filters (Eq, Ne, Gt, In, ...), And/Or combinations of them,
+// and the single-field updates (Set, Inc, Push, ...) implement RawAppender. Use
+// AppendFilter and AppendUpdate rather than calling AppendBSON directly; they
+// fall back to the bson.M representation for everything else.
+type RawAppender interface {
+	// AppendBSON appends the BSON document to dst and returns the extended slice.
+	// ok is false if the value cannot be encoded on the fast path; dst is then
+	// returned unchanged.
+	AppendBSON(dst []byte) (out []byte, ok bool)
+}
+
+// AppendFilter appends the BSON encoding of filter to dst and returns the result
+// as bson.Raw. Filters implementing RawAppender are encoded without intermediate
+// allocations; other filters fall back to bson.Marshal(filter.ToMongo()).
+//
+// Reusing dst across calls (dst[:0]) makes repeated encoding of hot filters
+// allocation-free. A nil filter encodes as the empty document.
+//
+// Example:
+//
+//	var buf []byte
+//	for _, id := range ids {
+//	    raw, err := spec.AppendFilter(buf[:0], spec.Eq("_id", id))
+//	    ...
+//	    buf = raw
+//	}
+func AppendFilter(dst []byte, filter Filter) (bson.Raw, error) {
+	if filter == nil {
+		return bsoncore.BuildDocument(dst), nil
+	}
+	if a, ok := filter.(RawAppender); ok {
+		if out, ok := a.AppendBSON(dst); ok {
+			return out, nil
+		}
+	}
+	return bson.MarshalAppend(dst, filter.ToMongo())
+}
+
+// AppendUpdate is the Update counterpart of AppendFilter.
+func AppendUpdate(dst []byte, update Update) (bson.Raw, error) {
+	if update == nil {
+		return bsoncore.BuildDocument(dst), nil
+	}
+	if a, ok := update.(RawAppender); ok {
+		if out, ok := a.AppendBSON(dst); ok {
+			return out, nil
+		}
+	}
+	return bson.MarshalAppend(dst, update.ToBsonUpdate())
+}
+
+// ---- fast-path implementations ----
+
+func (f eqFilter) AppendBSON(dst []byte) ([]byte, bool) {
+	idx, out := bsoncore.AppendDocumentStart(dst)
+	out, ok := appendValueElement(out, f.field, f.value)
+	return endDocument(dst, out, idx, ok)
+}
+
+func (f opFilter) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendNested(dst, f.field, f.op, f.value)
+}
+
+func (f andFilter) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendLogical(dst, "$and", f.filters)
+}
+
+func (f orFilter) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendLogical(dst, "$or", f.filters)
+}
+
+func appendLogical(dst []byte, op string, filters []Filter) ([]byte, bool) {
+	nonNil := 0
+	var single Filter
+	for _, flt := range filters {
+		if flt != nil {
+			nonNil++
+			single = flt
+		}
+	}
+	if nonNil == 1 {
+		if a, ok := single.(RawAppender); ok {
+			return a.AppendBSON(dst)
+		}
+		return dst, false
+	}
+
+	idx, out := bsoncore.AppendDocumentStart(dst)
+	arrIdx, out := bsoncore.AppendArrayElementStart(out, op)
+	var key [20]byte
+	i := 0
+	for _, flt := range filters {
+		if flt == nil {
+			continue
+		}
+		a, ok := flt.(RawAppender)
+		if !ok {
+			return dst, false
+		}
+		out = bsoncore.AppendHeader(out, bsontype.EmbeddedDocument, arrayKey(key[:0], i))
+		if out, ok = a.AppendBSON(out); !ok {
+			return dst, false
+		}
+		i++
+	}
+	out, err := bsoncore.AppendArrayEnd(out, arrIdx)
+	if err != nil {
+		return dst, false
+	}
+	return endDocument(dst, out, idx, true)
+}
+
+func (u setUpdate) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendNested(dst, "$set", u.field, u.value)
+}
+
+func (u incUpdate) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendNested(dst, "$inc", u.field, u.value)
+}
+
+func (u pushUpdate) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendNested(dst, "$push", u.field, u.value)
+}
+
+func (u pullUpdate) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendNested(dst, "$pull", u.field, u.value)
+}
+
+func (u addToSetUpdate) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendNested(dst, "$addToSet", u.field, u.value)
+}
+
+func (u unsetUpdate) AppendBSON(dst []byte) ([]byte, bool) {
+	return appendNested(dst, "$unset", u.field, "")
+}
+
+// appendNested appends {outer: {inner: value}}.
+func appendNested(dst []byte, outer, inner string, value any) ([]byte, bool) {
+	idx, out := bsoncore.AppendDocumentStart(dst)
+	innerIdx, out := bsoncore.AppendDocumentElementStart(out, outer)
+	out, ok := appendValueElement(out, inner, value)
+	if !ok {
+		return dst, false
+	}
+	out, err := bsoncore.AppendDocumentEnd(out, innerIdx)
+	if err != nil {
+		return dst, false
+	}
+	return endDocument(dst, out, idx, true)
+}
+
+func endDocument(dst, out []byte, idx int32, ok bool) ([]byte, bool) {
+	if !ok {
+		return dst, false
+	}
+	out, err := bsoncore.AppendDocumentEnd(out, idx)
+	if err != nil {
+		return dst, false
+	}
+	return out, true
+}
+
+// appendValueElement appends a key/value element for the scalar and slice types
+// that dominate hot filters. It mirrors the driver's default encoding rules
+// (e.g. int is stored as int32 when it fits).
+func appendValueElement(dst []byte, key string, value any) ([]byte, bool) {
+	switch v := value.(type) {
+	case nil:
+		return bsoncore.AppendNullElement(dst, key), true
+	case string:
+		return bsoncore.AppendStringElement(dst, key, v), true
+	case bool:
+		return bsoncore.AppendBooleanElement(dst, key, v), true
+	case int:
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			return bsoncore.AppendInt32Element(dst, key, int32(v)), true
+		}
+		return bsoncore.AppendInt64Element(dst, key, int64(v)), true
+	case int32:
+		return bsoncore.AppendInt32Element(dst, key, v), true
+	case int64:
+		return bsoncore.AppendInt64Element(dst, key, v), true
+	case float64:
+		return bsoncore.AppendDoubleElement(dst, key, v), true
+	case primitive.ObjectID:
+		return bsoncore.AppendObjectIDElement(dst, key, v), true
+	case time.Time:
+		return bsoncore.AppendDateTimeElement(dst, key, v.Unix()*1000+int64(v.Nanosecond()/1e6)), true
+	case []string:
+		return appendArrayElement(dst, key, len(v), func(b []byte, k string, i int) ([]byte, bool) {
+			return bsoncore.AppendStringElement(b, k, v[i]), true
+		})
+	case []int:
+		return appendArrayElement(dst, key, len(v), func(b []byte, k string, i int) ([]byte, bool) {
+			return appendValueElement(b, k, v[i])
+		})
+	case []int64:
+		return appendArrayElement(dst, key, len(v), func(b []byte, k string, i int) ([]byte, bool) {
+			return bsoncore.AppendInt64Element(b, k, v[i]), true
+		})
+	case []primitive.ObjectID:
+		return appendArrayElement(dst, key, len(v), func(b []byte, k string, i int) ([]byte, bool) {
+			return bsoncore.AppendObjectIDElement(b, k, v[i]), true
+		})
+	case []any:
+		return appendArrayElement(dst, key, len(v), func(b []byte, k string, i int) ([]byte, bool) {
+			return appendValueElement(b, k, v[i])
+		})
+	default:
+		return dst, false
+	}
+}
+
+func appendArrayElement(dst []byte, key string, n int, elem func([]byte, string, int) ([]byte, bool)) ([]byte, bool) {
+	idx, out := bsoncore.AppendArrayElementStart(dst, key)
+	var buf [20]byte
+	for i := 0; i < n; i++ {
+		var ok bool
+		if out, ok = elem(out, arrayKey(buf[:0], i), i); !ok {
+			return dst, false
+		}
+	}
+	out, err := bsoncore.AppendArrayEnd(out, idx)
+	if err != nil {
+		return dst, false
+	}
+	return out, true
+}
+
+// arrayKey formats an array index key without allocating for small indexes.
+func arrayKey(buf []byte, i int) string {
+	if i < len(smallKeys) {
+		return smallKeys[i]
+	}
+	buf = appendInt(buf, i)
+	return string(buf)
+}
+
+var smallKeys = func() []string {
+	keys := make([]string, 64)
+	for i := range keys {
+		keys[i] = string(appendInt(nil, i))
+	}
+	return keys
+}()
+
+func appendInt(buf []byte, i int) []byte {
+	if i >= 10 {
+		buf = appendInt(buf, i/10)
+	}
+	return append(buf, byte('0'+i%10))
+}
@@ -0,0 +1,123 @@
+package spec_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAppendFilterMatchesMarshal(t *testing.T) {
+	oid := primitive.NewObjectID()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 6e6, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter spec.Filter
+	}{
+		{"eq string", spec.Eq("status", "active")},
+		{"eq int", spec.Eq("count", 42)},
+		{"eq large int", spec.Eq("count", 1<<40)},
+		{"eq object id", spec.Eq("_id", oid)},
+		{"eq nil", spec.Eq("deleted_at", nil)},
+		{"gte time", spec.Gte("created_at", now)},
+		{"in strings", spec.In("status", []string{"a", "b", "c"})},
+		{"in object ids", spec.In("_id", []primitive.ObjectID{oid, oid})},
+		{"exists", spec.Exists("email", true)},
+		{"and", spec.And(spec.Eq("a", 1), spec.Lt("b", 2.5))},
+		{"or", spec.Or(spec.Eq("a", 1), spec.Ne("b", "x"))},
+		{"nested and/or", spec.And(spec.Eq("a", 1), spec.Or(spec.Eq("b", 2), spec.Eq("c", 3)))},
+		{"fallback regex", spec.Regex("name", "^a", "i")},
+		{"fallback mixed and", spec.And(spec.Eq("a", 1), spec.Regex("name", "^a"))},
+		{"fallback value type", spec.Eq("tags", bson.M{"x": 1})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := spec.AppendFilter(nil, tt.filter)
+			if err != nil {
+				t.Fatalf("AppendFilter returned error: %v", err)
+			}
+			want, err := bson.Marshal(tt.filter.ToMongo())
+			if err != nil {
+				t.Fatalf("bson.Marshal returned error: %v", err)
+			}
+
+			// Compare decoded documents: fallback encodings of multi-key bson.M
+			// values have no stable key order.
+			var gotDoc, wantDoc bson.M
+			if err := bson.Unmarshal(got, &gotDoc); err != nil {
+				t.Fatalf("unmarshal AppendFilter output: %v", err)
+			}
+			if err := bson.Unmarshal(want, &wantDoc); err != nil {
+				t.Fatalf("unmarshal bson.Marshal output: %v", err)
+			}
+			if !reflect.DeepEqual(gotDoc, wantDoc) {
+				t.Fatalf("AppendFilter mismatch.\n got: %v\nwant: %v", got, bson.Raw(want))
+			}
+		})
+	}
+}
+
+func TestAppendUpdateMatchesMarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		update spec.Update
+	}{
+		{"set", spec.Set("status", "active")},
+		{"inc", spec.Inc("count", 1)},
+		{"push", spec.Push("tags", "new")},
+		{"pull", spec.Pull("tags", "old")},
+		{"addToSet", spec.AddToSet("roles", "admin")},
+		{"unset", spec.Unset("tmp")},
+		{"fallback mul", spec.Mul("price", 1.1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := spec.AppendUpdate(nil, tt.update)
+			if err != nil {
+				t.Fatalf("AppendUpdate returned error: %v", err)
+			}
+			want, err := bson.Marshal(tt.update.ToBsonUpdate())
+			if err != nil {
+				t.Fatalf("bson.Marshal returned error: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Fatalf("AppendUpdate mismatch.\n got: %v\nwant: %v", got, bson.Raw(want))
+			}
+		})
+	}
+}
+
+func TestAppendFilterReusesBuffer(t *testing.T) {
+	buf := make([]byte, 0, 256)
+	filter := spec.And(spec.Eq("tenant_id", "t1"), spec.In("status", []string{"a", "b"}))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		raw, err := spec.AppendFilter(buf[:0], filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf = raw
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations with a reused buffer, got %v", allocs)
+	}
+}
+
+func TestAppendFilterNil(t *testing.T) {
+	got, err := spec.AppendFilter(nil, nil)
+	if err != nil {
+		t.Fatalf("AppendFilter(nil) returned error: %v", err)
+	}
+	want, _ := bson.Marshal(bson.M{})
+	if !bytes.Equal(got, want) {
+		t.Fatalf("expected empty document, got %v", got)
+	}
+}
@@ -0,0 +1,196 @@
+package spec
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ScoreExpr represents a numeric aggregation expression that contributes to a
+// computed relevance score. Score expressions are composable: combine signals
+// with ScoreSum or ScoreProduct and scale them with Weighted.
+//
+// Example:
+//
+//	score := spec.ScoreSum(
+//	    spec.Weighted(spec.TextScore(), 2),
+//	    spec.RecencyDecay("published_at", 7*24*time.Hour),
+//	    spec.Weighted(spec.LogScore("views"), 0.5),
+//	)
+//	pipeline.MatchRaw(bson.M{"$text": bson.M{"$search": "mongodb"}}).RankBy("score", score)
+type ScoreExpr interface {
+	// ToExpr converts the score to a MongoDB aggregation expression.
+	ToExpr() any
+}
+
+type exprScore struct {
+	expr any
+	err  error // set by a constructor that rejected its arguments
+}
+
+func (s exprScore) ToExpr() any {
+	return s.expr
+}
+
+// scoreErr returns the error of an invalid score, such as a RecencyDecay with
+// a non-positive half-life.
+func scoreErr(s ScoreExpr) error {
+	if e, ok := s.(exprScore); ok {
+		return e.err
+	}
+	return nil
+}
+
+// TextScore creates a score from the relevance computed by a $text query.
+// The pipeline must contain a $match stage with a $text filter before the score is used.
+//
+// MongoDB equivalent: {$meta: "textScore"}
+func TextScore() ScoreExpr {
+	return exprScore{expr: bson.M{"$meta": "textScore"}}
+}
+
+// FieldScore creates a score from a numeric document field.
+// Missing or null values contribute 0.
+//
+// MongoDB equivalent: {$ifNull: ["$field", 0]}
+//
+// Example:
+//
+//	FieldScore("rating")       // {"$ifNull": ["$rating", 0]}
+func FieldScore(field string) ScoreExpr {
+	return exprScore{expr: bson.M{"$ifNull": []any{"$" + field, 0}}}
+}
+
+// LogScore creates a dampened score from a numeric field using ln(1 + value).
+// Useful for popularity signals (views, likes) where large values should not dominate.
+//
+// MongoDB equivalent: {$ln: {$add: [1, {$max: [0, {$ifNull: ["$field", 0]}]}]}}
+//
+// Example:
+//
+//	LogScore("views")
+func LogScore(field string) ScoreExpr {
+	value := bson.M{"$max": []any{0, bson.M{"$ifNull": []any{"$" + field, 0}}}}
+	return exprScore{expr: bson.M{"$ln": bson.M{"$add": []any{1, value}}}}
+}
+
+// RecencyDecay creates a score in (0, 1] that halves every halfLife since the
+// date stored in field. Documents without the field score 0, and dates in the
+// future score 1. halfLife must be positive: otherwise RankBy rejects the
+// score, and any score combined from it, and its expression cannot be encoded.
+//
+// MongoDB equivalent: {$exp: {$multiply: [-ln(2)/halfLifeMillis, {$max: [0, {$subtract: ["$$NOW", "$field"]}]}]}}
+//
+// Example:
+//
+//	RecencyDecay("published_at", 24*time.Hour)  // 1.0 now, 0.5 after a day
+func RecencyDecay(field string, halfLife time.Duration) ScoreExpr {
+	if halfLife <= 0 {
+		err := errors.New("RecencyDecay: halfLife must be positive")
+		return exprScore{expr: invalidArg{err}, err: err}
+	}
+	rate := -math.Ln2 / (float64(halfLife) / float64(time.Millisecond))
+	age := bson.M{"$max": []any{0, bson.M{"$subtract": []any{"$$NOW", "$" + field}}}}
+	decay := bson.M{"$exp": bson.M{"$multiply": []any{rate, age}}}
+	return exprScore{expr: bson.M{"$cond": bson.M{
+		"if":   bson.M{"$eq": []any{bson.M{"$type": "$" + field}, "date"}},
+		"then": decay,
+		"else": 0,
+	}}}
+}
+
+// ConstScore creates a constant score.
+func ConstScore(value float64) ScoreExpr {
+	return exprScore{expr: value}
+}
+
+// RawScore wraps an arbitrary aggregation expression as a score.
+// Use this for signals not covered by the builder.
+func RawScore(expr any) ScoreExpr {
+	return exprScore{expr: expr}
+}
+
+// Weighted scales a score by the given weight.
+//
+// MongoDB equivalent: {$multiply: [weight, expr]}
+func Weighted(score ScoreExpr, weight float64) ScoreExpr {
+	if score == nil {
+		return nil
+	}
+	if err := scoreErr(score); err != nil {
+		return exprScore{expr: invalidArg{err}, err: err}
+	}
+	return exprScore{expr: bson.M{"$multiply": []any{weight, score.ToExpr()}}}
+}
+
+// ScoreSum adds multiple scores together.
+//
+// Behavior:
+//   - Nil scores are automatically ignored
+//   - If only one non-nil score is provided, it is returned directly
+//   - Returns nil if all scores are nil
+//
+// MongoDB equivalent: {$add: [expr1, expr2, ...]}
+func ScoreSum(scores ...ScoreExpr) ScoreExpr {
+	return combineScores("$add", scores)
+}
+
+// ScoreProduct multiplies multiple scores together.
+// Useful for boosting one signal by another (e.g. text score by recency).
+//
+// Behavior matches ScoreSum for nil handling.
+//
+// MongoDB equivalent: {$multiply: [expr1, expr2, ...]}
+func ScoreProduct(scores ...ScoreExpr) ScoreExpr {
+	return combineScores("$multiply", scores)
+}
+
+func combineScores(op string, scores []ScoreExpr) ScoreExpr {
+	exprs := make([]any, 0, len(scores))
+	var last ScoreExpr
+	for _, s := range scores {
+		if s == nil {
+			continue
+		}
+		if err := scoreErr(s); err != nil {
+			return exprScore{expr: invalidArg{err}, err: err}
+		}
+		exprs = append(exprs, s.ToExpr())
+		last = s
+	}
+	if len(exprs) == 0 {
+		return nil
+	}
+	if len(exprs) == 1 {
+		return last
+	}
+	return exprScore{expr: bson.M{op: exprs}}
+}
+
+// RankBy adds an $addFields stage that stores the computed score in field,
+// followed by a $sort stage ordering documents by that score (highest first).
+// A nil score adds no stages, and Validate reports an invalid one.
+//
+// Example:
+//
+//	pipeline.RankBy("score", spec.ScoreSum(
+//	    spec.TextScore(),
+//	    spec.RecencyDecay("created_at", 72*time.Hour),
+//	))
+//	// [{"$addFields": {"score": {...}}}, {"$sort": {"score": -1}}]
+func (p *Pipeline) RankBy(field string, score ScoreExpr) *Pipeline {
+	if score == nil {
+		return p
+	}
+	if err := scoreErr(score); err != nil {
+		p.fail("RankBy: %v", err)
+		return p
+	}
+	p.add(
+		bson.M{"$addFields": bson.M{field: score.ToExpr()}},
+		bson.M{"$sort": bson.M{field: -1}},
+	)
+	return p
+}
@@ -0,0 +1,117 @@
+package spec_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTextScore(t *testing.T) {
+	got := spec.TextScore().ToExpr()
+	want := bson.M{"$meta": "textScore"}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("TextScore mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestFieldScore(t *testing.T) {
+	got := spec.FieldScore("rating").ToExpr()
+	want := bson.M{"$ifNull": []any{"$rating", 0}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("FieldScore mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestWeighted(t *testing.T) {
+	got := spec.Weighted(spec.FieldScore("rating"), 2).ToExpr()
+	want := bson.M{"$multiply": []any{2.0, bson.M{"$ifNull": []any{"$rating", 0}}}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Weighted mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestScoreSum(t *testing.T) {
+	t.Run("multiple scores", func(t *testing.T) {
+		got := spec.ScoreSum(spec.TextScore(), spec.ConstScore(1)).ToExpr()
+		want := bson.M{"$add": []any{bson.M{"$meta": "textScore"}, 1.0}}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("ScoreSum mismatch.\n got: %#v\nwant: %#v", got, want)
+		}
+	})
+
+	t.Run("single score returned directly", func(t *testing.T) {
+		got := spec.ScoreSum(nil, spec.TextScore()).ToExpr()
+		want := bson.M{"$meta": "textScore"}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("ScoreSum(single) mismatch.\n got: %#v\nwant: %#v", got, want)
+		}
+	})
+
+	t.Run("all nil", func(t *testing.T) {
+		if got := spec.ScoreSum(nil, nil); got != nil {
+			t.Fatalf("expected nil, got %#v", got)
+		}
+	})
+}
+
+func TestRecencyDecay(t *testing.T) {
+	got, ok := spec.RecencyDecay("published_at", time.Hour).ToExpr().(bson.M)
+	if !ok {
+		t.Fatal("expected bson.M expression")
+	}
+	if _, ok := got["$cond"]; !ok {
+		t.Fatalf("expected $cond expression, got %#v", got)
+	}
+
+	decay := got["$cond"].(bson.M)["then"].(bson.M)["$exp"].(bson.M)["$multiply"].([]any)
+	age, ok := decay[1].(bson.M)["$max"].([]any)
+	if !ok || age[0] != 0 {
+		t.Fatalf("expected age clamped at 0 with $max, got %#v", decay[1])
+	}
+
+	tiny := spec.RecencyDecay("published_at", 500*time.Microsecond).ToExpr().(bson.M)
+	rate := tiny["$cond"].(bson.M)["then"].(bson.M)["$exp"].(bson.M)["$multiply"].([]any)[0]
+	if want := -math.Ln2 / 0.5; rate != want {
+		t.Fatalf("expected rate %v for a sub-millisecond half-life, got %v", want, rate)
+	}
+
+	for _, halfLife := range []time.Duration{0, -time.Hour} {
+		score := spec.ScoreSum(spec.TextScore(), spec.Weighted(spec.RecencyDecay("published_at", halfLife), 2))
+		if err := spec.NewPipeline().RankBy("score", score).Validate(); !errors.Is(err, spec.ErrInvalidPipeline) {
+			t.Fatalf("halfLife %v: expected ErrInvalidPipeline, got %v", halfLife, err)
+		}
+	}
+}
+
+func TestPipelineRankBy(t *testing.T) {
+	got, err := spec.NewPipeline().
+		RankBy("score", spec.FieldScore("likes")).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bson.M{
+		{"$addFields": bson.M{"score": bson.M{"$ifNull": []any{"$likes", 0}}}},
+		{"$sort": bson.M{"score": -1}},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline RankBy mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	if n := len(stages(t, spec.NewPipeline().RankBy("score", nil))); n != 0 {
+		t.Fatalf("RankBy(nil) should add no stages, got %d", n)
+	}
+}
@@ -262,3 +262,58 @@ func TestBetween(t *testing.T) {
 		t.Fatalf("Between mismatch.\n got: %#v\nwant: %#v", got, want)
 	}
 }
+
+func TestLocaleFilters(t *testing.T) {
+	if got := spec.LocaleField("name", "pt-BR"); got != "name.pt-BR" {
+		t.Fatalf("LocaleField = %q", got)
+	}
+	if got, want := spec.EqLocale("name", "en", "Shoes").ToMongo(), (bson.M{"name.en": "Shoes"}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("EqLocale = %v, want %v", got, want)
+	}
+	want := bson.M{"name.pt": bson.M{"$exists": true}}
+	if got := spec.HasLocale("name", "pt").ToMongo(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("HasLocale = %v, want %v", got, want)
+	}
+}
+
+func TestSearchAcross(t *testing.T) {
+	got := spec.SearchAcross([]string{"name", "email"}, "  a.b+c ").ToMongo()
+	want := bson.M{"$or": []bson.M{
+		{"name": bson.M{"$regex": `^a\.b\+c`, "$options": "i"}},
+		{"email": bson.M{"$regex": `^a\.b\+c`, "$options": "i"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("SearchAcross mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	single := spec.SearchAcross([]string{"name"}, "ada").ToMongo()
+	if !reflect.DeepEqual(single, bson.M{"name": bson.M{"$regex": "^ada", "$options": "i"}}) {
+		t.Fatalf("single field: got %#v", single)
+	}
+
+	if f := spec.SearchAcross([]string{"name"}, "   "); f != nil {
+		t.Fatalf("expected nil filter for blank term, got %#v", f.ToMongo())
+	}
+	if f := spec.SearchAcross(nil, "ada"); f != nil {
+		t.Fatalf("expected nil filter without fields, got %#v", f.ToMongo())
+	}
+}
+
+func TestText(t *testing.T) {
+	got := spec.Text("coffee -decaf").ToMongo()
+	want := bson.M{"$text": bson.M{"$search": "coffee -decaf"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Text mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+
+	got = spec.Text("café", spec.TextLanguage("french"), spec.TextCaseSensitive(), spec.TextDiacriticSensitive()).ToMongo()
+	want = bson.M{"$text": bson.M{
+		"$search":             "café",
+		"$language":           "french",
+		"$caseSensitive":      true,
+		"$diacriticSensitive": true,
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Text with options mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
@@ -0,0 +1,448 @@
+package spectest
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+
+	"github.com/dElCIoGio/mongox/internal/match"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FilterCase is a generated filter together with the Go model of its semantics.
+type FilterCase struct {
+	// Filter is the generated spec filter.
+	Filter spec.Filter
+
+	// Want reports whether the model expects doc to match Filter.
+	Want func(doc bson.M) bool
+
+	// Desc is a human-readable description used in failure messages.
+	Desc string
+}
+
+// UpdateCase is a generated update together with the Go model of its effect.
+type UpdateCase struct {
+	// Update is the generated spec update.
+	Update spec.Update
+
+	// Apply mutates doc the way the model expects Update to.
+	Apply func(doc bson.M)
+
+	// Desc is a human-readable description used in failure messages.
+	Desc string
+}
+
+// Generator produces random documents, filters, and updates over a small,
+// fixed schema so that generated filters frequently match generated documents:
+//
+//	{age: int, name: string, active: bool, tags: [string], profile: {score: int}}
+//
+// Any field may be absent. A Generator is not safe for concurrent use.
+type Generator struct {
+	r *rand.Rand
+}
+
+// NewGenerator creates a Generator with a deterministic seed, so failures can be reproduced.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{r: rand.New(rand.NewSource(seed))}
+}
+
+var (
+	genNames = []string{"alice", "bob", "carol", "dave", "Eve"}
+	genTags  = []string{"go", "mongo", "db", "web"}
+)
+
+func (g *Generator) age() int          { return g.r.Intn(6) * 10 }
+func (g *Generator) name() string      { return genNames[g.r.Intn(len(genNames))] }
+func (g *Generator) tag() string       { return genTags[g.r.Intn(len(genTags))] }
+func (g *Generator) chance(n int) bool { return g.r.Intn(n) == 0 }
+
+func (g *Generator) tagList(max int) []string {
+	n := g.r.Intn(max + 1)
+	out := make([]string, n)
+	for i := range out {
+		out[i] = g.tag()
+	}
+	return out
+}
+
+// Doc generates a random document.
+func (g *Generator) Doc() bson.M {
+	doc := bson.M{}
+	if !g.chance(5) {
+		doc["age"] = g.age()
+	}
+	if !g.chance(5) {
+		doc["name"] = g.name()
+	}
+	if !g.chance(5) {
+		doc["active"] = g.chance(2)
+	}
+	if !g.chance(4) {
+		doc["tags"] = primitive.A(toAny(g.tagList(3)))
+	}
+	if !g.chance(3) {
+		doc["profile"] = bson.M{"score": g.age()}
+	}
+	return doc
+}
+
+// Filter generates a random filter. depth bounds the nesting of logical combinators.
+func (g *Generator) Filter(depth int) FilterCase {
+	if depth > 0 && g.chance(3) {
+		return g.logical(depth - 1)
+	}
+	return g.leaf()
+}
+
+func (g *Generator) logical(depth int) FilterCase {
+	switch g.r.Intn(3) {
+	case 0, 1:
+		n := 2 + g.r.Intn(2)
+		cases := make([]FilterCase, n)
+		filters := make([]spec.Filter, n)
+		descs := make([]string, n)
+		for i := range cases {
+			cases[i] = g.Filter(depth)
+			filters[i] = cases[i].Filter
+			descs[i] = cases[i].Desc
+		}
+		isAnd := g.chance(2)
+		if isAnd {
+			return FilterCase{
+				Filter: spec.And(filters...),
+				Desc:   "And(" + strings.Join(descs, ", ") + ")",
+				Want: func(doc bson.M) bool {
+					for _, c := range cases {
+						if !c.Want(doc) {
+							return false
+						}
+					}
+					return true
+				},
+			}
+		}
+		return FilterCase{
+			Filter: spec.Or(filters...),
+			Desc:   "Or(" + strings.Join(descs, ", ") + ")",
+			Want: func(doc bson.M) bool {
+				for _, c := range cases {
+					if c.Want(doc) {
+						return true
+					}
+				}
+				return false
+			},
+		}
+	default:
+		inner := g.Filter(depth)
+		return FilterCase{
+			Filter: spec.Not(inner.Filter),
+			Desc:   "Not(" + inner.Desc + ")",
+			Want:   func(doc bson.M) bool { return !inner.Want(doc) },
+		}
+	}
+}
+
+func (g *Generator) leaf() FilterCase {
+	switch g.r.Intn(14) {
+	case 0:
+		v := g.age()
+		return intCase(fmt.Sprintf("Eq(age, %d)", v), spec.Eq("age", v), func(a int, ok bool) bool { return ok && a == v })
+	case 1:
+		v := g.age()
+		return intCase(fmt.Sprintf("Ne(age, %d)", v), spec.Ne("age", v), func(a int, ok bool) bool { return !ok || a != v })
+	case 2:
+		v := g.age()
+		return intCase(fmt.Sprintf("Gt(age, %d)", v), spec.Gt("age", v), func(a int, ok bool) bool { return ok && a > v })
+	case 3:
+		v := g.age()
+		return intCase(fmt.Sprintf("Gte(age, %d)", v), spec.Gte("age", v), func(a int, ok bool) bool { return ok && a >= v })
+	case 4:
+		v := g.age()
+		return intCase(fmt.Sprintf("Lt(age, %d)", v), spec.Lt("age", v), func(a int, ok bool) bool { return ok && a < v })
+	case 5:
+		v := g.age()
+		return intCase(fmt.Sprintf("Lte(age, %d)", v), spec.Lte("age", v), func(a int, ok bool) bool { return ok && a <= v })
+	case 6:
+		lo, hi := g.age(), g.age()
+		return intCase(fmt.Sprintf("Between(age, %d, %d)", lo, hi), spec.Between("age", lo, hi), func(a int, ok bool) bool { return ok && a >= lo && a <= hi })
+	case 7:
+		vals := []string{g.name(), g.name()}
+		return FilterCase{
+			Filter: spec.In("name", vals),
+			Desc:   fmt.Sprintf("In(name, %q)", vals),
+			Want: func(doc bson.M) bool {
+				n, ok := doc["name"].(string)
+				return ok && (n == vals[0] || n == vals[1])
+			},
+		}
+	case 8:
+		vals := []string{g.name(), g.name()}
+		return FilterCase{
+			Filter: spec.NotIn("name", vals),
+			Desc:   fmt.Sprintf("NotIn(name, %q)", vals),
+			Want: func(doc bson.M) bool {
+				n, ok := doc["name"].(string)
+				return !ok || (n != vals[0] && n != vals[1])
+			},
+		}
+	case 9:
+		field := []string{"age", "name", "active", "tags", "profile"}[g.r.Intn(5)]
+		want := g.chance(2)
+		return FilterCase{
+			Filter: spec.Exists(field, want),
+			Desc:   fmt.Sprintf("Exists(%s, %v)", field, want),
+			Want: func(doc bson.M) bool {
+				_, ok := doc[field]
+				return ok == want
+			},
+		}
+	case 10:
+		tag := g.tag()
+		return FilterCase{
+			Filter: spec.Eq("tags", tag),
+			Desc:   fmt.Sprintf("Eq(tags, %q)", tag),
+			Want: func(doc bson.M) bool {
+				for _, t := range docTags(doc) {
+					if t == tag {
+						return true
+					}
+				}
+				return false
+			},
+		}
+	case 11:
+		want := g.tagList(2)
+		return FilterCase{
+			Filter: spec.All("tags", want),
+			Desc:   fmt.Sprintf("All(tags, %q)", want),
+			Want: func(doc bson.M) bool {
+				if _, ok := doc["tags"]; !ok || len(want) == 0 {
+					return false
+				}
+				have := docTags(doc)
+				for _, w := range want {
+					found := false
+					for _, h := range have {
+						if h == w {
+							found = true
+						}
+					}
+					if !found {
+						return false
+					}
+				}
+				return true
+			},
+		}
+	case 12:
+		size := g.r.Intn(4)
+		return FilterCase{
+			Filter: spec.Size("tags", size),
+			Desc:   fmt.Sprintf("Size(tags, %d)", size),
+			Want: func(doc bson.M) bool {
+				_, ok := doc["tags"]
+				return ok && len(docTags(doc)) == size
+			},
+		}
+	default:
+		prefix := g.name()[:1]
+		opts := ""
+		if g.chance(2) {
+			opts = "i"
+		}
+		re := regexp.MustCompile("^" + prefix)
+		if opts == "i" {
+			re = regexp.MustCompile("(?i)^" + prefix)
+		}
+		return FilterCase{
+			Filter: spec.Regex("name", "^"+prefix, opts),
+			Desc:   fmt.Sprintf("Regex(name, ^%s, %q)", prefix, opts),
+			Want: func(doc bson.M) bool {
+				n, ok := doc["name"].(string)
+				return ok && re.MatchString(n)
+			},
+		}
+	}
+}
+
+func intCase(desc string, f spec.Filter, want func(age int, ok bool) bool) FilterCase {
+	return FilterCase{
+		Filter: f,
+		Desc:   desc,
+		Want: func(doc bson.M) bool {
+			v, ok := doc["age"]
+			if !ok {
+				return want(0, false)
+			}
+			age, isInt := v.(int)
+			if !isInt {
+				// Decoded documents store small integers as int32.
+				if a32, ok := v.(int32); ok {
+					return want(int(a32), true)
+				}
+				return false
+			}
+			return want(age, true)
+		},
+	}
+}
+
+func docTags(doc bson.M) []string {
+	arr, _ := match.ToSlice(doc["tags"])
+	out := make([]string, 0, len(arr))
+	for _, v := range arr {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func toAny(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// Update generates a random update combining one to three operations on distinct fields.
+func (g *Generator) Update() UpdateCase {
+	fields := g.r.Perm(5)[:1+g.r.Intn(3)]
+	cases := make([]UpdateCase, len(fields))
+	updates := make([]spec.Update, len(fields))
+	descs := make([]string, len(fields))
+	for i, f := range fields {
+		cases[i] = g.updateFor(f)
+		updates[i] = cases[i].Update
+		descs[i] = cases[i].Desc
+	}
+	return UpdateCase{
+		Update: spec.Combine(updates...),
+		Desc:   "Combine(" + strings.Join(descs, ", ") + ")",
+		Apply: func(doc bson.M) {
+			for _, c := range cases {
+				c.Apply(doc)
+			}
+		},
+	}
+}
+
+// updateFor generates an update targeting the schema field with the given index,
+// choosing only operators that are valid for that field's type.
+func (g *Generator) updateFor(field int) UpdateCase {
+	switch field {
+	case 0: // age
+		switch g.r.Intn(4) {
+		case 0:
+			v := g.age()
+			return UpdateCase{Update: spec.Set("age", v), Desc: fmt.Sprintf("Set(age, %d)", v), Apply: func(d bson.M) { d["age"] = v }}
+		case 1:
+			v := g.r.Intn(7) - 3
+			return UpdateCase{Update: spec.Inc("age", v), Desc: fmt.Sprintf("Inc(age, %d)", v), Apply: func(d bson.M) {
+				if cur, ok := intValue(d["age"]); ok {
+					d["age"] = cur + v
+				} else {
+					d["age"] = v
+				}
+			}}
+		case 2:
+			v := g.age()
+			return UpdateCase{Update: spec.Max("age", v), Desc: fmt.Sprintf("Max(age, %d)", v), Apply: func(d bson.M) {
+				if cur, ok := intValue(d["age"]); !ok || v > cur {
+					d["age"] = v
+				}
+			}}
+		default:
+			v := g.age()
+			return UpdateCase{Update: spec.Min("age", v), Desc: fmt.Sprintf("Min(age, %d)", v), Apply: func(d bson.M) {
+				if cur, ok := intValue(d["age"]); !ok || v < cur {
+					d["age"] = v
+				}
+			}}
+		}
+	case 1: // name
+		if g.chance(2) {
+			return UpdateCase{Update: spec.Unset("name"), Desc: "Unset(name)", Apply: func(d bson.M) { delete(d, "name") }}
+		}
+		v := g.name()
+		return UpdateCase{Update: spec.Set("name", v), Desc: fmt.Sprintf("Set(name, %q)", v), Apply: func(d bson.M) { d["name"] = v }}
+	case 2: // active
+		v := g.chance(2)
+		return UpdateCase{Update: spec.SetFields(bson.M{"active": v}), Desc: fmt.Sprintf("SetFields(active: %v)", v), Apply: func(d bson.M) { d["active"] = v }}
+	case 3: // tags
+		v := g.tag()
+		switch g.r.Intn(5) {
+		case 0:
+			return UpdateCase{Update: spec.Push("tags", v), Desc: fmt.Sprintf("Push(tags, %q)", v), Apply: func(d bson.M) {
+				d["tags"] = append(primitive.A(toAny(docTags(d))), v)
+			}}
+		case 1:
+			return UpdateCase{Update: spec.AddToSet("tags", v), Desc: fmt.Sprintf("AddToSet(tags, %q)", v), Apply: func(d bson.M) {
+				tags := docTags(d)
+				for _, t := range tags {
+					if t == v {
+						d["tags"] = primitive.A(toAny(tags))
+						return
+					}
+				}
+				d["tags"] = append(primitive.A(toAny(tags)), v)
+			}}
+		case 2:
+			return UpdateCase{Update: spec.Pull("tags", v), Desc: fmt.Sprintf("Pull(tags, %q)", v), Apply: func(d bson.M) {
+				if _, ok := d["tags"]; !ok {
+					return
+				}
+				kept := primitive.A{}
+				for _, t := range docTags(d) {
+					if t != v {
+						kept = append(kept, t)
+					}
+				}
+				d["tags"] = kept
+			}}
+		case 3:
+			return UpdateCase{Update: spec.PopFirst("tags"), Desc: "PopFirst(tags)", Apply: func(d bson.M) {
+				if tags := docTags(d); len(tags) > 0 {
+					d["tags"] = primitive.A(toAny(tags[1:]))
+				}
+			}}
+		default:
+			return UpdateCase{Update: spec.PopLast("tags"), Desc: "PopLast(tags)", Apply: func(d bson.M) {
+				if tags := docTags(d); len(tags) > 0 {
+					d["tags"] = primitive.A(toAny(tags[:len(tags)-1]))
+				}
+			}}
+		}
+	default: // profile.score
+		v := g.age()
+		return UpdateCase{Update: spec.Set("profile.score", v), Desc: fmt.Sprintf("Set(profile.score, %d)", v), Apply: func(d bson.M) {
+			profile, ok := match.ToMap(d["profile"])
+			if !ok {
+				profile = bson.M{}
+			}
+			profile["score"] = v
+			d["profile"] = profile
+		}}
+	}
+}
+
+func intValue(v any) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
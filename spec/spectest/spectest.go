@@ -0,0 +1,191 @@
+// Package spectest provides property-based testing helpers for spec filters and updates.
+//
+// A Generator produces random documents together with random filters and updates.
+// Every generated filter carries an independent Go predicate describing its intended
+// semantics, and every generated update carries a Go function applying it. The
+// oracle functions (CheckFilters, CheckUpdates) translate the spec values to BSON
+// along every path the repository layer uses to send them to MongoDB (the bson.M
+// tree, the RawAppender fast path of AppendFilter and AppendUpdate, and the bytes
+// cached by Compile), evaluate each with an in-memory matcher, and compare the
+// outcome with the Go model. Any disagreement points at an operator translation
+// or encoding bug.
+//
+// Example:
+//
+//	func TestFilterTranslation(t *testing.T) {
+//	    spectest.CheckFilters(t, 42, 1000)
+//	    spectest.CheckUpdates(t, 42, 1000)
+//	}
+package spectest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/internal/match"
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Matches reports whether doc satisfies the filter using the in-memory matcher.
+// The filter may be a spec.Filter, bson.M, or bson.D.
+func Matches(filter any, doc bson.M) (bool, error) {
+	if f, ok := filter.(spec.Filter); ok {
+		filter = f.ToMongo()
+	}
+	return match.Matches(filter, doc)
+}
+
+// ApplyUpdate applies the update to doc in place using the in-memory evaluator.
+// The update may be a spec.Update, bson.M, or bson.D.
+func ApplyUpdate(doc bson.M, update any) error {
+	if u, ok := update.(spec.Update); ok {
+		update = u.ToBsonUpdate()
+	}
+	return match.Apply(doc, update, false)
+}
+
+// CheckFilters generates n random filters from seed and verifies that each one
+// selects exactly the documents its Go model predicts.
+func CheckFilters(t testing.TB, seed int64, n int) {
+	t.Helper()
+	g := NewGenerator(seed)
+
+	for i := 0; i < n; i++ {
+		fc := g.Filter(3)
+		encoded, err := filterEncodings(fc.Filter)
+		if err != nil {
+			t.Fatalf("spectest: seed %d case %d (%s): %v", seed, i, fc.Desc, err)
+		}
+
+		for j := 0; j < 8; j++ {
+			doc := g.Doc()
+			want := fc.Want(doc)
+			for _, enc := range encoded {
+				got, err := match.Matches(enc.value, doc)
+				if err != nil {
+					t.Fatalf("spectest: seed %d case %d (%s, %s): %v", seed, i, fc.Desc, enc.path, err)
+				}
+				if got != want {
+					t.Fatalf("spectest: seed %d case %d: filter %s via %s\n  mongo: %v\n  doc:   %v\n  got match=%v, model says %v",
+						seed, i, fc.Desc, enc.path, enc.value, doc, got, want)
+				}
+			}
+		}
+	}
+}
+
+// CheckUpdates generates n random updates from seed and verifies that applying
+// the translated update produces the same document as the Go model.
+func CheckUpdates(t testing.TB, seed int64, n int) {
+	t.Helper()
+	g := NewGenerator(seed)
+
+	for i := 0; i < n; i++ {
+		uc := g.Update()
+		encoded, err := updateEncodings(uc.Update)
+		if err != nil {
+			t.Fatalf("spectest: seed %d case %d (%s): %v", seed, i, uc.Desc, err)
+		}
+
+		doc := g.Doc()
+		want := cloneDoc(doc)
+		uc.Apply(want)
+
+		for _, enc := range encoded {
+			got := cloneDoc(doc)
+			if err := match.Apply(got, enc.value, false); err != nil {
+				t.Fatalf("spectest: seed %d case %d (%s, %s): %v", seed, i, uc.Desc, enc.path, err)
+			}
+			if !match.Equal(got, want) {
+				t.Fatalf("spectest: seed %d case %d: update %s via %s\n  mongo: %v\n  doc:   %v\n  got:   %v\n  model: %v",
+					seed, i, uc.Desc, enc.path, enc.value, doc, got, want)
+			}
+		}
+	}
+}
+
+// encoding is a filter or update as decoded from one of its BSON encodings.
+type encoding struct {
+	path  string
+	value bson.M
+}
+
+// filterEncodings encodes f along each path the repository layer may use: the
+// bson.M tree, AppendFilter (the RawAppender fast path for simple filters), and
+// the bytes of a CompiledFilter. Each is decoded back for the matcher.
+func filterEncodings(f spec.Filter) ([]encoding, error) {
+	tree, err := roundTrip(f.ToMongo())
+	if err != nil {
+		return nil, err
+	}
+	appended, err := spec.AppendFilter(nil, f)
+	if err != nil {
+		return nil, fmt.Errorf("AppendFilter: %w", err)
+	}
+	compiled, err := spec.Compile(f)
+	if err != nil {
+		return nil, fmt.Errorf("Compile: %w", err)
+	}
+
+	out := []encoding{{path: "ToMongo", value: tree}}
+	for _, e := range []struct {
+		path string
+		raw  bson.Raw
+	}{{"AppendFilter", appended}, {"CompiledFilter.Raw", compiled.Raw()}} {
+		v, err := decodeRaw(e.raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.path, err)
+		}
+		out = append(out, encoding{path: e.path, value: v})
+	}
+	return out, nil
+}
+
+// updateEncodings encodes u as the bson.M tree and through AppendUpdate.
+func updateEncodings(u spec.Update) ([]encoding, error) {
+	tree, err := roundTrip(u.ToBsonUpdate())
+	if err != nil {
+		return nil, err
+	}
+	appended, err := spec.AppendUpdate(nil, u)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUpdate: %w", err)
+	}
+	v, err := decodeRaw(appended)
+	if err != nil {
+		return nil, fmt.Errorf("AppendUpdate: %w", err)
+	}
+	return []encoding{{path: "ToBsonUpdate", value: tree}, {path: "AppendUpdate", value: v}}, nil
+}
+
+func decodeRaw(raw bson.Raw) (bson.M, error) {
+	var out bson.M
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	return out, nil
+}
+
+// roundTrip encodes v to BSON and decodes it back, as happens when a filter or
+// update is sent to the server. It fails for values the driver cannot encode.
+func roundTrip(v bson.M) (bson.M, error) {
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode: %w", err)
+	}
+	return decodeRaw(raw)
+}
+
+func cloneDoc(doc bson.M) bson.M {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+	var out bson.M
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		panic(err)
+	}
+	return out
+}
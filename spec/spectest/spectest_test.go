@@ -0,0 +1,75 @@
+package spectest_test
+
+import (
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+	"github.com/dElCIoGio/mongox/spec/spectest"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCheckFilters(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		spectest.CheckFilters(t, seed, 500)
+	}
+}
+
+func TestCheckUpdates(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		spectest.CheckUpdates(t, seed, 500)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	doc := bson.M{"status": "active", "age": 30, "tags": bson.A{"go", "mongo"}}
+
+	tests := []struct {
+		name   string
+		filter spec.Filter
+		want   bool
+	}{
+		{"eq", spec.Eq("status", "active"), true},
+		{"array contains", spec.Eq("tags", "go"), true},
+		{"gt false", spec.Gt("age", 30), false},
+		{"missing field ne", spec.Ne("deleted_at", "x"), true},
+		{"or", spec.Or(spec.Eq("status", "banned"), spec.Gte("age", 18)), true},
+		{"not", spec.Not(spec.Eq("status", "active")), false},
+		{"elemMatch scalars", spec.ElemMatch("tags", spec.Eq("$eq", "mongo")), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := spectest.Matches(tt.filter, doc)
+			if err != nil {
+				t.Fatalf("Matches returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Matches(%v) = %v, want %v", tt.filter.ToMongo(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyUpdate(t *testing.T) {
+	doc := bson.M{"count": 1, "tags": bson.A{"a"}}
+
+	err := spectest.ApplyUpdate(doc, spec.Combine(
+		spec.Inc("count", 2),
+		spec.AddToSet("tags", "b"),
+		spec.Set("nested.value", true),
+	))
+	if err != nil {
+		t.Fatalf("ApplyUpdate returned error: %v", err)
+	}
+
+	if doc["count"] != int64(3) {
+		t.Fatalf("expected count=3, got %#v", doc["count"])
+	}
+	if tags := doc["tags"].(bson.A); len(tags) != 2 {
+		t.Fatalf("expected 2 tags, got %#v", tags)
+	}
+	if nested := doc["nested"].(bson.M); nested["value"] != true {
+		t.Fatalf("expected nested.value=true, got %#v", nested)
+	}
+}
@@ -0,0 +1,55 @@
+package spec
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// TextOption configures a Text filter.
+type TextOption func(bson.M)
+
+// TextLanguage sets the language that determines the stop words and stemmer
+// for the search, e.g. "spanish" or "none" to disable stemming. The default is
+// the language of the text index.
+func TextLanguage(language string) TextOption {
+	return func(m bson.M) { m["$language"] = language }
+}
+
+// TextCaseSensitive makes the search distinguish between upper and lower case.
+func TextCaseSensitive() TextOption {
+	return func(m bson.M) { m["$caseSensitive"] = true }
+}
+
+// TextDiacriticSensitive makes the search distinguish between diacritics,
+// e.g. "café" and "cafe".
+func TextDiacriticSensitive() TextOption {
+	return func(m bson.M) { m["$diacriticSensitive"] = true }
+}
+
+type textFilter struct {
+	query string
+	opts  []TextOption
+}
+
+func (f textFilter) ToMongo() bson.M {
+	search := bson.M{"$search": f.query}
+	for _, opt := range f.opts {
+		opt(search)
+	}
+	return bson.M{"$text": search}
+}
+
+// Text creates a full-text search filter. The collection needs a text index,
+// e.g. one declared with document.TextIndex. Words are ORed; quote phrases
+// ("\"exact phrase\"") and prefix words with - to exclude them.
+//
+// Sort by relevance with repository.WithTextScoreSort and return the score with
+// a projection from IncludeTextScore.
+//
+// MongoDB equivalent: {$text: {$search: query, ...options}}
+//
+// Example:
+//
+//	Text("coffee shop")                          // {"$text": {"$search": "coffee shop"}}
+//	Text("café", TextLanguage("french"), TextDiacriticSensitive())
+//	And(Text("-decaf coffee"), Eq("status", "published"))
+func Text(query string, opts ...TextOption) Filter {
+	return textFilter{query: query, opts: opts}
+}
@@ -0,0 +1,197 @@
+package spec
+
+import (
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TimeUnit is the unit of a date step or range in Densify and RangeWindow.
+type TimeUnit string
+
+// Time units accepted by the server.
+const (
+	Millisecond TimeUnit = "millisecond"
+	Second      TimeUnit = "second"
+	Minute      TimeUnit = "minute"
+	Hour        TimeUnit = "hour"
+	Day         TimeUnit = "day"
+	Week        TimeUnit = "week"
+	Month       TimeUnit = "month"
+	Quarter     TimeUnit = "quarter"
+	Year        TimeUnit = "year"
+)
+
+// DensifyBounds selects the range Densify fills. The zero value is
+// DensifyFull.
+type DensifyBounds struct {
+	bounds any
+}
+
+var (
+	// DensifyFull fills from the smallest to the largest value of the field
+	// across all documents.
+	DensifyFull = DensifyBounds{bounds: "full"}
+
+	// DensifyPartition fills each partition from its smallest to its largest
+	// value.
+	DensifyPartition = DensifyBounds{bounds: "partition"}
+)
+
+// DensifyBetween fills from lower (inclusive) to upper (exclusive), which are
+// numbers or, with a Unit, time.Time values.
+func DensifyBetween(lower, upper any) DensifyBounds {
+	return DensifyBounds{bounds: bson.A{lower, upper}}
+}
+
+// DensifyOptions configures a $densify stage.
+type DensifyOptions struct {
+	// Field is the numeric or date field to fill gaps in. Required.
+	Field string
+
+	// PartitionBy lists fields that group documents; each group is filled
+	// separately, and created documents copy the group's values.
+	PartitionBy []string
+
+	// Step is the distance between consecutive values. Required. With a Unit
+	// it must be a whole number.
+	Step float64
+
+	// Unit makes Step a number of time units, for date fields.
+	Unit TimeUnit
+
+	// Bounds is the range to fill. The default is DensifyFull.
+	Bounds DensifyBounds
+}
+
+// Densify adds a $densify stage, which creates documents for missing values
+// of a sequence, such as the hours without readings in a time series. Created
+// documents only have opts.Field and the opts.PartitionBy fields; use Fill to
+// give their other fields values. Requires MongoDB 5.1 or later.
+//
+// Validate reports an empty opts.Field, an opts.Step that is not positive, and
+// an opts.Step that is not a whole number with a Unit.
+//
+// Example:
+//
+//	spec.NewPipeline().
+//	    Densify(spec.DensifyOptions{
+//	        Field:       "ts",
+//	        PartitionBy: []string{"sensor_id"},
+//	        Step:        1,
+//	        Unit:        spec.Hour,
+//	        Bounds:      spec.DensifyBetween(from, to),
+//	    })
+func (p *Pipeline) Densify(opts DensifyOptions) *Pipeline {
+	switch {
+	case opts.Field == "":
+		p.fail("Densify: Field is required")
+		return p
+	case !(opts.Step > 0):
+		p.fail("Densify: Step must be positive")
+		return p
+	case opts.Unit != "" && opts.Step != math.Trunc(opts.Step):
+		p.fail("Densify: Step must be a whole number with a Unit")
+		return p
+	}
+
+	bounds := opts.Bounds.bounds
+	if bounds == nil {
+		bounds = DensifyFull.bounds
+	}
+	rng := bson.M{"bounds": bounds}
+	if opts.Unit != "" {
+		rng["step"] = int64(opts.Step)
+		rng["unit"] = string(opts.Unit)
+	} else {
+		rng["step"] = opts.Step
+	}
+	stage := bson.M{"field": opts.Field, "range": rng}
+	if len(opts.PartitionBy) > 0 {
+		stage["partitionByFields"] = opts.PartitionBy
+	}
+	p.add(bson.M{"$densify": stage})
+	return p
+}
+
+// FillMethod is how Fill computes a missing value.
+type FillMethod struct {
+	spec   bson.M
+	sorted bool // requires FillOptions.SortBy
+}
+
+// FillValue fills with the result of expr, e.g. 0 or "$default_price".
+func FillValue(expr any) FillMethod {
+	return FillMethod{spec: bson.M{"value": expr}}
+}
+
+// FillLocf fills with the last non-missing value before the document (last
+// observation carried forward).
+func FillLocf() FillMethod {
+	return FillMethod{spec: bson.M{"method": "locf"}, sorted: true}
+}
+
+// FillLinear fills by linear interpolation between the surrounding
+// non-missing values.
+func FillLinear() FillMethod {
+	return FillMethod{spec: bson.M{"method": "linear"}, sorted: true}
+}
+
+// FillOptions configures a $fill stage.
+type FillOptions struct {
+	// PartitionBy lists fields that group documents; FillLocf and FillLinear
+	// only use values from the same group.
+	PartitionBy []string
+
+	// SortBy orders the documents of each group. Required by FillLocf and
+	// FillLinear.
+	SortBy bson.D
+
+	// Output maps each field to fill to its method. Required.
+	Output map[string]FillMethod
+}
+
+// Fill adds a $fill stage, which sets fields that are null or missing, such
+// as those of documents created by Densify. Requires MongoDB 5.3 or later.
+//
+// Validate reports an empty opts.Output, and FillLocf or FillLinear used
+// without opts.SortBy.
+//
+// Example:
+//
+//	pipeline.Fill(spec.FillOptions{
+//	    PartitionBy: []string{"sensor_id"},
+//	    SortBy:      bson.D{{"ts", 1}},
+//	    Output: map[string]spec.FillMethod{
+//	        "temperature": spec.FillLinear(),
+//	        "status":      spec.FillLocf(),
+//	        "alerts":      spec.FillValue(0),
+//	    },
+//	})
+func (p *Pipeline) Fill(opts FillOptions) *Pipeline {
+	if len(opts.Output) == 0 {
+		p.fail("Fill: Output must have at least one field")
+		return p
+	}
+	output := bson.M{}
+	for field, m := range opts.Output {
+		if m.sorted && len(opts.SortBy) == 0 {
+			p.fail("Fill: SortBy is required for FillLocf and FillLinear")
+			return p
+		}
+		if m.spec == nil {
+			p.fail("Fill: the method of %s is not set", field)
+			return p
+		}
+		output[field] = m.spec
+	}
+	stage := bson.M{"output": output}
+	if len(opts.PartitionBy) > 0 {
+		stage["partitionByFields"] = opts.PartitionBy
+	}
+	if len(opts.SortBy) > 0 {
+		stage["sortBy"] = opts.SortBy
+	}
+	p.add(bson.M{"$fill": stage})
+	return p
+}
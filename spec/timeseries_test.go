@@ -0,0 +1,96 @@
+package spec_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPipelineDensify(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+	got, err := spec.NewPipeline().
+		Densify(spec.DensifyOptions{
+			Field:       "ts",
+			PartitionBy: []string{"sensor_id"},
+			Step:        1,
+			Unit:        spec.Hour,
+			Bounds:      spec.DensifyBetween(from, to),
+		}).
+		Densify(spec.DensifyOptions{Field: "n", Step: 0.5}).
+		Densify(spec.DensifyOptions{Field: "n", Step: 10, Bounds: spec.DensifyPartition}).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bson.M{
+		{"$densify": bson.M{
+			"field":             "ts",
+			"partitionByFields": []string{"sensor_id"},
+			"range":             bson.M{"step": int64(1), "unit": "hour", "bounds": bson.A{from, to}},
+		}},
+		{"$densify": bson.M{"field": "n", "range": bson.M{"step": 0.5, "bounds": "full"}}},
+		{"$densify": bson.M{"field": "n", "range": bson.M{"step": 10.0, "bounds": "partition"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline Densify mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestPipelineFill(t *testing.T) {
+	got, err := spec.NewPipeline().
+		Fill(spec.FillOptions{
+			PartitionBy: []string{"sensor_id"},
+			SortBy:      bson.D{{Key: "ts", Value: 1}},
+			Output: map[string]spec.FillMethod{
+				"temperature": spec.FillLinear(),
+				"status":      spec.FillLocf(),
+			},
+		}).
+		Fill(spec.FillOptions{Output: map[string]spec.FillMethod{"alerts": spec.FillValue(0)}}).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bson.M{
+		{"$fill": bson.M{
+			"partitionByFields": []string{"sensor_id"},
+			"sortBy":            bson.D{{Key: "ts", Value: 1}},
+			"output": bson.M{
+				"temperature": bson.M{"method": "linear"},
+				"status":      bson.M{"method": "locf"},
+			},
+		}},
+		{"$fill": bson.M{"output": bson.M{"alerts": bson.M{"value": 0}}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline Fill mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestTimeSeriesStagesInvalid(t *testing.T) {
+	tests := map[string]*spec.Pipeline{
+		"Densify without field":        spec.NewPipeline().Densify(spec.DensifyOptions{Step: 1}),
+		"Densify without step":         spec.NewPipeline().Densify(spec.DensifyOptions{Field: "ts"}),
+		"Densify fractional unit step": spec.NewPipeline().Densify(spec.DensifyOptions{Field: "ts", Step: 1.5, Unit: spec.Day}),
+		"Fill without output":          spec.NewPipeline().Fill(spec.FillOptions{}),
+		"Fill linear without sort": spec.NewPipeline().Fill(spec.FillOptions{
+			Output: map[string]spec.FillMethod{"x": spec.FillLinear()},
+		}),
+		"Fill zero method": spec.NewPipeline().Fill(spec.FillOptions{Output: map[string]spec.FillMethod{"x": {}}}),
+	}
+	for name, p := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := p.Validate(); !errors.Is(err, spec.ErrInvalidPipeline) {
+				t.Fatalf("expected ErrInvalidPipeline, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package spec
+
+import (
+	"errors"
+	"maps"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Window bounds for DocumentsWindow and RangeWindow.
+const (
+	// Unbounded is the first or last document of the partition.
+	Unbounded = "unbounded"
+
+	// Current is the current document.
+	Current = "current"
+)
+
+// SetWindowFields adds a $setWindowFields stage, which computes output fields
+// over a window of related documents without grouping them: rankings, running
+// totals, and moving averages.
+//
+// Documents are partitioned by partitionBy (an expression such as "$region",
+// or nil for a single partition) and ordered within each partition by sortBy
+// (e.g. bson.D{{"date", 1}}, or nil). Ranking operators and windows bounded by
+// position require sortBy. output maps each new field to a window operator;
+// see Rank, DenseRank, DocumentNumber, MovingAvg, RunningTotal, and Over.
+//
+// Example:
+//
+//	pipeline.SetWindowFields("$store", bson.D{{"date", 1}}, bson.M{
+//	    "rank":   spec.Rank(),
+//	    "avg7d":  spec.MovingAvg("$sales", 7),
+//	    "toDate": spec.RunningTotal("$sales"),
+//	})
+func (p *Pipeline) SetWindowFields(partitionBy, sortBy any, output bson.M) *Pipeline {
+	if len(output) == 0 {
+		p.fail("SetWindowFields: output must have at least one field")
+		return p
+	}
+	for _, v := range output {
+		if m, ok := v.(bson.M); ok {
+			if err := invalid(m); err != nil {
+				p.fail("SetWindowFields: %v", err)
+				return p
+			}
+		}
+	}
+	stage := bson.M{"output": output}
+	if partitionBy != nil {
+		stage["partitionBy"] = partitionBy
+	}
+	if sortBy != nil {
+		stage["sortBy"] = sortBy
+	}
+	p.add(bson.M{"$setWindowFields": stage})
+	return p
+}
+
+// Rank creates a $rank window operator: the position of the document in its
+// partition, with ties sharing a rank and leaving gaps (1, 2, 2, 4).
+func Rank() bson.M {
+	return bson.M{"$rank": bson.M{}}
+}
+
+// DenseRank creates a $denseRank window operator: like Rank, but without gaps
+// after ties (1, 2, 2, 3).
+func DenseRank() bson.M {
+	return bson.M{"$denseRank": bson.M{}}
+}
+
+// DocumentNumber creates a $documentNumber window operator: the position of
+// the document in its partition, with ties numbered in no particular order.
+func DocumentNumber() bson.M {
+	return bson.M{"$documentNumber": bson.M{}}
+}
+
+// DocumentsWindow creates a window of documents by position relative to the
+// current one: Unbounded, Current, or an offset such as -2 or 3.
+//
+// Example:
+//
+//	spec.DocumentsWindow(-2, spec.Current) // the current and two previous documents
+func DocumentsWindow(lower, upper any) bson.M {
+	return bson.M{"documents": bson.A{lower, upper}}
+}
+
+// RangeWindow creates a window of documents whose sortBy value is within a
+// range of the current document's: Unbounded, Current, or a number added to
+// it. With a unit such as Day, the sortBy field must be a date and the bounds
+// are in that unit.
+//
+// Example:
+//
+//	spec.RangeWindow(-30, 0, spec.Day) // documents from the previous 30 days
+func RangeWindow(lower, upper any, unit TimeUnit) bson.M {
+	w := bson.M{"range": bson.A{lower, upper}}
+	if unit != "" {
+		w["unit"] = string(unit)
+	}
+	return w
+}
+
+// Over applies an accumulator such as Sum or Avg to a window, for use as a
+// SetWindowFields output. acc is not modified.
+//
+// Example:
+//
+//	spec.Over(spec.MaxAcc("$price"), spec.RangeWindow(-7, 0, spec.Day))
+func Over(acc bson.M, window bson.M) bson.M {
+	out := maps.Clone(acc)
+	out["window"] = window
+	return out
+}
+
+// MovingAvg creates the average of expr over the current document and the
+// n-1 documents before it. If n is less than 1, SetWindowFields rejects it
+// and it cannot be encoded.
+func MovingAvg(expr any, n int) bson.M {
+	if n < 1 {
+		return bson.M{"$avg": invalidArg{errors.New("MovingAvg: window size must be at least 1")}}
+	}
+	return Over(Avg(expr), DocumentsWindow(1-n, Current))
+}
+
+// RunningTotal creates the sum of expr over the partition up to and including
+// the current document.
+func RunningTotal(expr any) bson.M {
+	return Over(Sum(expr), DocumentsWindow(Unbounded, Current))
+}
@@ -0,0 +1,85 @@
+package spec_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestPipelineSetWindowFields(t *testing.T) {
+	sortBy := bson.D{{Key: "date", Value: 1}}
+	got, err := spec.NewPipeline().
+		SetWindowFields("$store", sortBy, bson.M{
+			"rank":   spec.Rank(),
+			"avg7d":  spec.MovingAvg("$sales", 7),
+			"toDate": spec.RunningTotal("$sales"),
+		}).
+		SetWindowFields(nil, nil, bson.M{"n": spec.DocumentNumber()}).
+		ToPipeline()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []bson.M{
+		{"$setWindowFields": bson.M{
+			"partitionBy": "$store",
+			"sortBy":      sortBy,
+			"output": bson.M{
+				"rank":   bson.M{"$rank": bson.M{}},
+				"avg7d":  bson.M{"$avg": "$sales", "window": bson.M{"documents": bson.A{-6, "current"}}},
+				"toDate": bson.M{"$sum": "$sales", "window": bson.M{"documents": bson.A{"unbounded", "current"}}},
+			},
+		}},
+		{"$setWindowFields": bson.M{"output": bson.M{"n": bson.M{"$documentNumber": bson.M{}}}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Pipeline SetWindowFields mismatch.\n got: %#v\nwant: %#v", got, want)
+	}
+}
+
+func TestWindowHelpers(t *testing.T) {
+	acc := spec.MaxAcc("$price")
+	tests := []struct {
+		name string
+		got  bson.M
+		want bson.M
+	}{
+		{"DenseRank", spec.DenseRank(), bson.M{"$denseRank": bson.M{}}},
+		{"DocumentsWindow", spec.DocumentsWindow(-2, spec.Current), bson.M{"documents": bson.A{-2, "current"}}},
+		{"RangeWindow", spec.RangeWindow(-30, 0, "day"), bson.M{"range": bson.A{-30, 0}, "unit": "day"}},
+		{"RangeWindow numeric", spec.RangeWindow(-10, 10, ""), bson.M{"range": bson.A{-10, 10}}},
+		{"Over", spec.Over(acc, spec.RangeWindow(-7, 0, "day")),
+			bson.M{"$max": "$price", "window": bson.M{"range": bson.A{-7, 0}, "unit": "day"}}},
+		{"MovingAvg of one", spec.MovingAvg("$x", 1), bson.M{"$avg": "$x", "window": bson.M{"documents": bson.A{0, "current"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Fatalf("%s mismatch.\n got: %#v\nwant: %#v", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+	if _, ok := acc["window"]; ok {
+		t.Fatal("Over modified its accumulator")
+	}
+}
+
+func TestWindowInvalid(t *testing.T) {
+	for name, p := range map[string]*spec.Pipeline{
+		"empty output":     spec.NewPipeline().SetWindowFields(nil, nil, nil),
+		"MovingAvg size 0": spec.NewPipeline().SetWindowFields(nil, bson.D{{Key: "date", Value: 1}}, bson.M{"avg": spec.MovingAvg("$x", 0)}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := p.Validate(); !errors.Is(err, spec.ErrInvalidPipeline) {
+				t.Fatalf("expected ErrInvalidPipeline, got %v", err)
+			}
+			if got, err := p.ToPipeline(); err == nil || got != nil {
+				t.Fatalf("expected ToPipeline to fail without stages, got %v (%v)", got, err)
+			}
+		})
+	}
+}
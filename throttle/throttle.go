@@ -0,0 +1,262 @@
+// Package throttle counts events per key in fixed time windows stored in
+// MongoDB, for login throttling and abuse tracking across a fleet.
+//
+// Each key and window is one counter document that a TTL index removes once
+// the window is over, so the collection only holds current counters. A key is
+// blocked once its count in the current window exceeds the limit; with
+// WithBlockDuration it stays blocked for a fixed time instead, e.g. 15 minutes
+// after the sixth failed login in a minute.
+//
+// Example:
+//
+//	logins := throttle.New(db.Collection("login_attempts"), 5, time.Minute,
+//	    throttle.WithBlockDuration(15*time.Minute),
+//	)
+//	_ = logins.EnsureIndexes(ctx)
+//
+//	if blocked, until, _ := logins.IsBlocked(ctx, email); blocked {
+//	    return fmt.Errorf("too many attempts, retry after %s", until)
+//	}
+//	if !checkPassword(email, password) {
+//	    _, _ = logins.Hit(ctx, email)
+//	    return ErrInvalidCredentials
+//	}
+//	_ = logins.Reset(ctx, email)
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidLimit is returned by Limiter methods when the limit or window is
+// not positive.
+var ErrInvalidLimit = errors.New("throttle: limit and window must be positive")
+
+// Option configures a Limiter.
+type Option func(*config)
+
+type config struct {
+	block time.Duration
+}
+
+// WithBlockDuration blocks a key for d once it exceeds the limit, even after
+// its window ends. By default a key is blocked until the end of the window in
+// which it exceeded the limit.
+func WithBlockDuration(d time.Duration) Option {
+	return func(c *config) { c.block = d }
+}
+
+// Result is the state of a key after a Hit.
+type Result struct {
+	// Count is the number of events in the current window, including this one.
+	Count int64
+
+	// Remaining is how many more events the window allows before blocking.
+	Remaining int64
+
+	// ResetAt is when the current window ends.
+	ResetAt time.Time
+
+	// Blocked reports whether the key is blocked, and BlockedUntil until when.
+	Blocked      bool
+	BlockedUntil time.Time
+}
+
+// Limiter counts events per key. It is safe for concurrent use.
+type Limiter struct {
+	coll   *mongo.Collection
+	limit  int64
+	window time.Duration
+	cfg    config
+	now    func() time.Time
+}
+
+// New creates a Limiter that allows limit events per key in each window.
+func New(coll *mongo.Collection, limit int64, window time.Duration, opts ...Option) *Limiter {
+	var cfg config
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	return &Limiter{coll: coll, limit: limit, window: window, cfg: cfg, now: time.Now}
+}
+
+// EnsureIndexes creates the TTL index that removes counters and blocks once
+// they expire.
+func (l *Limiter) EnsureIndexes(ctx context.Context) error {
+	_, err := l.coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: mopt.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+type counterDoc struct {
+	Count int64 `bson:"count"`
+}
+
+type blockDoc struct {
+	Until time.Time `bson:"until"`
+}
+
+func (l *Limiter) valid() error {
+	if l.limit <= 0 || l.window <= 0 {
+		return ErrInvalidLimit
+	}
+	return nil
+}
+
+// windowOf returns the start and end of the window containing t.
+func (l *Limiter) windowOf(t time.Time) (time.Time, time.Time) {
+	start := t.Truncate(l.window)
+	return start, start.Add(l.window)
+}
+
+func counterID(key string, start time.Time) string {
+	return "c/" + key + "/" + strconv.FormatInt(start.UnixMilli(), 10)
+}
+
+func blockID(key string) string { return "b/" + key }
+
+// Hit records one event for key and reports whether the key is now blocked.
+func (l *Limiter) Hit(ctx context.Context, key string) (Result, error) {
+	if err := l.valid(); err != nil {
+		return Result{}, err
+	}
+	now := l.now().UTC()
+	start, end := l.windowOf(now)
+
+	var doc counterDoc
+	var err error
+	// A concurrent first hit can make the upsert fail with a duplicate key;
+	// the retry then finds the inserted counter.
+	for attempt := 0; attempt < 2; attempt++ {
+		err = l.coll.FindOneAndUpdate(ctx,
+			bson.M{"_id": counterID(key, start)},
+			bson.M{
+				"$inc":         bson.M{"count": 1},
+				"$setOnInsert": bson.M{"key": key, "window_start": start, "expires_at": end},
+			},
+			mopt.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(mopt.After),
+		).Decode(&doc)
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("throttle: hit %q: %w", key, err)
+	}
+
+	res := Result{Count: doc.Count, Remaining: max(l.limit-doc.Count, 0), ResetAt: end}
+	if doc.Count > l.limit {
+		res.Blocked, res.BlockedUntil = true, end
+		if l.cfg.block > 0 {
+			until := now.Add(l.cfg.block)
+			if err := l.block(ctx, key, until); err != nil {
+				return res, err
+			}
+			res.BlockedUntil = until
+		}
+		return res, nil
+	}
+
+	if l.cfg.block > 0 {
+		blocked, until, err := l.blockedUntil(ctx, key, now)
+		if err != nil {
+			return res, err
+		}
+		res.Blocked, res.BlockedUntil = blocked, until
+	}
+	return res, nil
+}
+
+// IsBlocked reports whether key is blocked and until when.
+func (l *Limiter) IsBlocked(ctx context.Context, key string) (bool, time.Time, error) {
+	if err := l.valid(); err != nil {
+		return false, time.Time{}, err
+	}
+	now := l.now().UTC()
+
+	if l.cfg.block > 0 {
+		if blocked, until, err := l.blockedUntil(ctx, key, now); err != nil || blocked {
+			return blocked, until, err
+		}
+	}
+
+	count, err := l.Count(ctx, key)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if count > l.limit {
+		_, end := l.windowOf(now)
+		return true, end, nil
+	}
+	return false, time.Time{}, nil
+}
+
+// Count returns the number of events for key in the current window.
+func (l *Limiter) Count(ctx context.Context, key string) (int64, error) {
+	if err := l.valid(); err != nil {
+		return 0, err
+	}
+	start, _ := l.windowOf(l.now().UTC())
+	var doc counterDoc
+	err := l.coll.FindOne(ctx, bson.M{"_id": counterID(key, start)}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("throttle: count %q: %w", key, err)
+	}
+	return doc.Count, nil
+}
+
+// Reset clears the counters and any block of key, e.g. after a successful login.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	_, err := l.coll.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"key": key},
+		bson.M{"_id": blockID(key)},
+	}})
+	if err != nil {
+		return fmt.Errorf("throttle: reset %q: %w", key, err)
+	}
+	return nil
+}
+
+// block records that key is blocked until the given time, keeping a later
+// existing block.
+func (l *Limiter) block(ctx context.Context, key string, until time.Time) error {
+	_, err := l.coll.UpdateOne(ctx,
+		bson.M{"_id": blockID(key)},
+		bson.M{
+			"$max":         bson.M{"until": until, "expires_at": until},
+			"$setOnInsert": bson.M{"blocked_key": key},
+		},
+		mopt.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("throttle: block %q: %w", key, err)
+	}
+	return nil
+}
+
+func (l *Limiter) blockedUntil(ctx context.Context, key string, now time.Time) (bool, time.Time, error) {
+	var doc blockDoc
+	err := l.coll.FindOne(ctx, bson.M{"_id": blockID(key), "until": bson.M{"$gt": now}}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, time.Time{}, nil
+	}
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("throttle: check block %q: %w", key, err)
+	}
+	return true, doc.Until, nil
+}
@@ -0,0 +1,122 @@
+//go:build integration
+
+package throttle_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/throttle"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestLimiter_BlocksAfterLimitAndResets(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	l := throttle.New(client.Database("testdb").Collection("attempts"), 3, time.Hour)
+	if err := l.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		res, err := l.Hit(ctx, "alice")
+		if err != nil {
+			t.Fatalf("Hit %d: %v", i, err)
+		}
+		if res.Count != int64(i) || res.Remaining != int64(3-i) || res.Blocked {
+			t.Fatalf("Hit %d = %+v", i, res)
+		}
+	}
+	if blocked, _, err := l.IsBlocked(ctx, "alice"); err != nil || blocked {
+		t.Fatalf("IsBlocked at the limit = %v, %v; want false", blocked, err)
+	}
+
+	res, err := l.Hit(ctx, "alice")
+	if err != nil {
+		t.Fatalf("Hit over limit: %v", err)
+	}
+	if !res.Blocked || !res.BlockedUntil.Equal(res.ResetAt) {
+		t.Fatalf("Hit over limit = %+v, want blocked until the window ends", res)
+	}
+	if blocked, _, err := l.IsBlocked(ctx, "alice"); err != nil || !blocked {
+		t.Fatalf("IsBlocked over limit = %v, %v; want true", blocked, err)
+	}
+	if blocked, _, _ := l.IsBlocked(ctx, "bob"); blocked {
+		t.Fatal("other keys must not be blocked")
+	}
+
+	if err := l.Reset(ctx, "alice"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if n, err := l.Count(ctx, "alice"); err != nil || n != 0 {
+		t.Fatalf("Count after Reset = %d, %v; want 0", n, err)
+	}
+}
+
+func TestLimiter_BlockDurationOutlivesWindow(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	l := throttle.New(client.Database("testdb").Collection("attempts"), 1, 200*time.Millisecond,
+		throttle.WithBlockDuration(time.Hour),
+	)
+
+	if _, err := l.Hit(ctx, "k"); err != nil {
+		t.Fatalf("Hit: %v", err)
+	}
+	res, err := l.Hit(ctx, "k")
+	if err != nil {
+		t.Fatalf("Hit: %v", err)
+	}
+	if !res.Blocked || res.BlockedUntil.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("Hit over limit = %+v, want blocked for an hour", res)
+	}
+
+	// A fresh window starts with a count of one, but the block still holds.
+	time.Sleep(250 * time.Millisecond)
+	res, err = l.Hit(ctx, "k")
+	if err != nil {
+		t.Fatalf("Hit in next window: %v", err)
+	}
+	if res.Count != 1 || !res.Blocked {
+		t.Fatalf("Hit in next window = %+v, want count 1 and blocked", res)
+	}
+
+	if err := l.Reset(ctx, "k"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if blocked, _, err := l.IsBlocked(ctx, "k"); err != nil || blocked {
+		t.Fatalf("IsBlocked after Reset = %v, %v; want false", blocked, err)
+	}
+}
@@ -0,0 +1,31 @@
+package throttle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	"github.com/dElCIoGio/mongox/throttle"
+)
+
+func TestLimiter_RejectsInvalidLimits(t *testing.T) {
+	ctx := context.Background()
+	coll := testutil.LazyCollection(t, "attempts")
+
+	for _, l := range []*throttle.Limiter{
+		throttle.New(coll, 0, time.Minute),
+		throttle.New(coll, 5, 0),
+	} {
+		if _, err := l.Hit(ctx, "k"); !errors.Is(err, throttle.ErrInvalidLimit) {
+			t.Fatalf("Hit err = %v, want ErrInvalidLimit", err)
+		}
+		if _, _, err := l.IsBlocked(ctx, "k"); !errors.Is(err, throttle.ErrInvalidLimit) {
+			t.Fatalf("IsBlocked err = %v, want ErrInvalidLimit", err)
+		}
+		if _, err := l.Count(ctx, "k"); !errors.Is(err, throttle.ErrInvalidLimit) {
+			t.Fatalf("Count err = %v, want ErrInvalidLimit", err)
+		}
+	}
+}
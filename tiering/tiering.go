@@ -0,0 +1,289 @@
+// Package tiering moves old documents from a hot collection to a cold one, so
+// the working set of the hot collection stays small while historical data
+// remains readable.
+//
+// A Tier copies every hot document whose age field is older than a threshold
+// to the cold collection, which may live in another database or cluster, and
+// replaces the hot document with a stub: its _id, age field, and any fields
+// listed with WithStubFields, plus a StubField marker holding the move time.
+// FindAnyTier reads the hot collection first and falls back to the cold one on
+// a miss, so callers need not know where a document lives.
+//
+// Stubs stay in the hot collection so references by _id remain resolvable and
+// unique indexes keep their values. Hot queries that must not see stubs should
+// add HotOnly to their filter.
+//
+// Example:
+//
+//	t := tiering.New[Order](db.Collection("orders"), archive.Collection("orders"), 180*24*time.Hour,
+//	    tiering.WithStubFields("customer_id", "number"),
+//	)
+//	go t.Run(ctx)
+//
+//	order, err := t.FindAnyTier(ctx, bson.M{"number": "A-1042"})
+package tiering
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dElCIoGio/mongox/spec"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StubField marks a hot document as a stub for a document moved to the cold
+// tier. It holds the time of the move.
+const StubField = "_tiered_at"
+
+var (
+	// ErrNotFound is returned by FindAnyTier when neither tier has a match.
+	ErrNotFound = errors.New("tiering: document not found")
+
+	// ErrInvalidThreshold is returned when the age threshold is not positive.
+	ErrInvalidThreshold = errors.New("tiering: threshold must be positive")
+)
+
+// HotOnly is a filter clause matching hot documents that are not stubs.
+// Combine it with other clauses using $and.
+var HotOnly = bson.M{StubField: bson.M{"$exists": false}}
+
+// Option configures a Tier.
+type Option func(*config)
+
+type config struct {
+	ageField   string
+	stubFields []string
+	batchSize  int
+	interval   time.Duration
+	onError    func(err error)
+}
+
+// WithAgeField sets the date field compared against the threshold. Defaults to
+// "created_at". Documents without the field are never moved.
+func WithAgeField(field string) Option {
+	return func(c *config) { c.ageField = field }
+}
+
+// WithStubFields keeps the given top-level fields in the stub, so hot queries
+// and unique indexes on them keep working after a move.
+func WithStubFields(fields ...string) Option {
+	return func(c *config) { c.stubFields = append(c.stubFields, fields...) }
+}
+
+// WithBatchSize sets how many documents are read per batch. Defaults to 500.
+func WithBatchSize(n int) Option {
+	return func(c *config) { c.batchSize = n }
+}
+
+// WithInterval sets how often Run moves documents. Defaults to one hour.
+func WithInterval(d time.Duration) Option {
+	return func(c *config) { c.interval = d }
+}
+
+// WithErrorHandler registers a callback for errors of scheduled moves.
+func WithErrorHandler(fn func(err error)) Option {
+	return func(c *config) { c.onError = fn }
+}
+
+// Tier moves documents of type T between a hot and a cold collection.
+type Tier[T any] struct {
+	hot, cold *mongo.Collection
+	olderThan time.Duration
+	cfg       config
+	now       func() time.Time
+}
+
+// New creates a Tier that moves hot documents older than olderThan to cold.
+func New[T any](hot, cold *mongo.Collection, olderThan time.Duration, opts ...Option) *Tier[T] {
+	cfg := config{ageField: "created_at", batchSize: 500, interval: time.Hour}
+	for _, o := range opts {
+		if o != nil {
+			o(&cfg)
+		}
+	}
+	if cfg.batchSize <= 0 {
+		cfg.batchSize = 500
+	}
+	if cfg.interval <= 0 {
+		cfg.interval = time.Hour
+	}
+	return &Tier[T]{
+		hot:       hot,
+		cold:      cold,
+		olderThan: olderThan,
+		cfg:       cfg,
+		now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// EnsureIndexes creates the hot index used to select documents to move.
+func (t *Tier[T]) EnsureIndexes(ctx context.Context) error {
+	_, err := t.hot.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: t.cfg.ageField, Value: 1}},
+		Options: mopt.Index().SetPartialFilterExpression(HotOnly),
+	})
+	return err
+}
+
+// Run moves due documents every interval until ctx is cancelled, then returns
+// ctx.Err(). Errors are passed to the handler set with WithErrorHandler.
+func (t *Tier[T]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(t.cfg.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := t.MoveOnce(ctx); err != nil && ctx.Err() == nil && t.cfg.onError != nil {
+			t.cfg.onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// MoveOnce moves every hot document older than the threshold to the cold tier
+// and returns the number moved.
+//
+// Each document is first upserted into the cold collection, then replaced by
+// its stub only if it is unchanged since it was read. A document modified in
+// between keeps its hot copy, its cold copy is removed, and it is reconsidered
+// on the next run. A crash between the two steps leaves a cold copy that the
+// next run overwrites, so no document is lost. Several processes may run
+// MoveOnce on the same collections concurrently.
+func (t *Tier[T]) MoveOnce(ctx context.Context) (int64, error) {
+	if t.olderThan <= 0 {
+		return 0, ErrInvalidThreshold
+	}
+	cutoff := t.now().Add(-t.olderThan)
+	filter := bson.M{
+		t.cfg.ageField: bson.M{"$lt": cutoff},
+		StubField:      bson.M{"$exists": false},
+	}
+	findOpts := mopt.Find().
+		SetSort(bson.D{{Key: t.cfg.ageField, Value: 1}}).
+		SetLimit(int64(t.cfg.batchSize))
+
+	var moved int64
+	for {
+		cur, err := t.hot.Find(ctx, filter, findOpts)
+		if err != nil {
+			return moved, fmt.Errorf("tiering: read hot documents: %w", err)
+		}
+		var batch []bson.Raw
+		err = cur.All(ctx, &batch)
+		if err != nil {
+			return moved, fmt.Errorf("tiering: read hot documents: %w", err)
+		}
+
+		progressed := false
+		for _, doc := range batch {
+			ok, err := t.move(ctx, doc)
+			if err != nil {
+				return moved, err
+			}
+			if ok {
+				moved++
+				progressed = true
+			}
+		}
+		// A short batch is the last one; a batch of only changed documents
+		// would be read again, so stop and leave them for the next run.
+		if len(batch) < t.cfg.batchSize || !progressed {
+			return moved, nil
+		}
+	}
+}
+
+// move copies doc to the cold tier and replaces it with a stub. It reports
+// false when doc changed since it was read.
+func (t *Tier[T]) move(ctx context.Context, doc bson.Raw) (bool, error) {
+	id := doc.Lookup("_id")
+
+	_, err := t.cold.ReplaceOne(ctx, bson.M{"_id": id}, doc, mopt.Replace().SetUpsert(true))
+	if err != nil {
+		return false, fmt.Errorf("tiering: copy %v to cold tier: %w", id, err)
+	}
+
+	// Replace only if the whole document is unchanged since it was read.
+	res, err := t.hot.ReplaceOne(ctx, unchanged(id, doc), t.stub(doc))
+	if err != nil {
+		return false, fmt.Errorf("tiering: stub %v: %w", id, err)
+	}
+	if res.MatchedCount == 1 {
+		return true, nil
+	}
+
+	// Another mover may have stubbed the document already; its cold copy is
+	// then the one just written and must stay.
+	n, err := t.hot.CountDocuments(ctx, bson.M{"_id": id, StubField: bson.M{"$exists": true}})
+	if err != nil {
+		return false, fmt.Errorf("tiering: check %v: %w", id, err)
+	}
+	if n > 0 {
+		return false, nil
+	}
+
+	// The document changed: drop the stale copy, unless a newer one replaced it.
+	if _, err := t.cold.DeleteOne(ctx, unchanged(id, doc)); err != nil {
+		return false, fmt.Errorf("tiering: discard cold copy of changed document %v: %w", id, err)
+	}
+	return false, nil
+}
+
+// unchanged matches the document with the given _id only if it equals doc.
+func unchanged(id bson.RawValue, doc bson.Raw) bson.M {
+	return bson.M{
+		"_id":   id,
+		"$expr": bson.M{"$eq": bson.A{"$$ROOT", bson.M{"$literal": doc}}},
+	}
+}
+
+// stub builds the hot placeholder for doc.
+func (t *Tier[T]) stub(doc bson.Raw) bson.D {
+	stub := bson.D{{Key: "_id", Value: doc.Lookup("_id")}}
+	for _, f := range append([]string{t.cfg.ageField}, t.cfg.stubFields...) {
+		if f == "_id" || f == StubField {
+			continue
+		}
+		if v, err := doc.LookupErr(f); err == nil {
+			stub = append(stub, bson.E{Key: f, Value: v})
+		}
+	}
+	return append(stub, bson.E{Key: StubField, Value: t.now()})
+}
+
+// FindAnyTier returns the first document matching filter, reading the hot
+// collection first and the cold collection on a miss. Hot stubs are never
+// returned. The filter may be a spec.Filter or any BSON document. It returns
+// ErrNotFound when neither tier has a match.
+func (t *Tier[T]) FindAnyTier(ctx context.Context, filter any, opts ...*mopt.FindOneOptions) (*T, error) {
+	switch f := filter.(type) {
+	case nil:
+		filter = bson.M{}
+	case spec.Filter:
+		filter = f.ToMongo()
+	}
+	var out T
+	err := t.hot.FindOne(ctx, bson.M{"$and": bson.A{filter, HotOnly}}, opts...).Decode(&out)
+	if err == nil {
+		return &out, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	err = t.cold.FindOne(ctx, filter, opts...).Decode(&out)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,120 @@
+//go:build integration
+
+package tiering_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/spec"
+	"github.com/dElCIoGio/mongox/tiering"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func TestMoveOnce_MovesOldDocumentsAndLeavesStubs(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	hot := client.Database("app").Collection("orders")
+	cold := client.Database("archive").Collection("orders")
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	var docs []any
+	for i, days := range []int{400, 300, 200, 10, 1} {
+		created := now.AddDate(0, 0, -days)
+		docs = append(docs, Order{
+			Base:     document.Base{ID: primitive.NewObjectID(), CreatedAt: created, UpdatedAt: created},
+			Number:   fmt.Sprintf("A-%d", i+1),
+			Customer: "c1",
+			Total:    i,
+		})
+	}
+	if _, err := hot.InsertMany(ctx, docs); err != nil {
+		t.Fatalf("InsertMany: %v", err)
+	}
+
+	tier := tiering.New[Order](hot, cold, 90*24*time.Hour,
+		tiering.WithStubFields("number"),
+		tiering.WithBatchSize(2),
+	)
+	if err := tier.EnsureIndexes(ctx); err != nil {
+		t.Fatalf("EnsureIndexes: %v", err)
+	}
+
+	moved, err := tier.MoveOnce(ctx)
+	if err != nil {
+		t.Fatalf("MoveOnce: %v", err)
+	}
+	if moved != 3 {
+		t.Fatalf("expected 3 documents moved, got %d", moved)
+	}
+	if n, _ := cold.CountDocuments(ctx, bson.M{}); n != 3 {
+		t.Fatalf("expected 3 cold documents, got %d", n)
+	}
+	if n, _ := hot.CountDocuments(ctx, tiering.HotOnly); n != 2 {
+		t.Fatalf("expected 2 hot documents, got %d", n)
+	}
+
+	var stub bson.M
+	if err := hot.FindOne(ctx, bson.M{"number": "A-1"}).Decode(&stub); err != nil {
+		t.Fatalf("find stub: %v", err)
+	}
+	if _, ok := stub[tiering.StubField]; !ok || stub["customer"] != nil || len(stub) != 4 {
+		t.Fatalf("unexpected stub: %v", stub)
+	}
+
+	// A second run has nothing left to move.
+	if moved, err := tier.MoveOnce(ctx); err != nil || moved != 0 {
+		t.Fatalf("expected nothing to move, got %d (%v)", moved, err)
+	}
+
+	// FindAnyTier reads through to the cold tier and never returns stubs.
+	got, err := tier.FindAnyTier(ctx, spec.Eq("number", "A-1"))
+	if err != nil {
+		t.Fatalf("FindAnyTier cold: %v", err)
+	}
+	if got.Customer != "c1" || got.Total != 0 {
+		t.Fatalf("expected the full cold document, got %+v", got)
+	}
+	if got, err := tier.FindAnyTier(ctx, bson.M{"number": "A-5"}); err != nil || got.Total != 4 {
+		t.Fatalf("FindAnyTier hot: %+v (%v)", got, err)
+	}
+	if _, err := tier.FindAnyTier(ctx, bson.M{"number": "missing"}); !errors.Is(err, tiering.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package tiering_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/document"
+	"github.com/dElCIoGio/mongox/internal/testutil"
+	"github.com/dElCIoGio/mongox/tiering"
+)
+
+type Order struct {
+	document.Base `bson:",inline"`
+
+	Number   string `bson:"number"`
+	Customer string `bson:"customer"`
+	Total    int    `bson:"total"`
+}
+
+func TestMoveOnce_RejectsNonPositiveThreshold(t *testing.T) {
+	tier := tiering.New[Order](testutil.LazyCollection(t, "orders"), testutil.LazyCollection(t, "orders_cold"), 0)
+	if _, err := tier.MoveOnce(context.Background()); !errors.Is(err, tiering.ErrInvalidThreshold) {
+		t.Fatalf("expected ErrInvalidThreshold, got %v", err)
+	}
+}
+
+func TestRun_StopsOnCancel(t *testing.T) {
+	var errs []error
+	tier := tiering.New[Order](testutil.LazyCollection(t, "orders"), testutil.LazyCollection(t, "orders_cold"), -time.Hour,
+		tiering.WithInterval(time.Millisecond),
+		tiering.WithErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tier.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+	if len(errs) == 0 || !errors.Is(errs[0], tiering.ErrInvalidThreshold) {
+		t.Fatalf("expected errors to reach the handler, got %v", errs)
+	}
+}
@@ -0,0 +1,347 @@
+// Package twophase coordinates best-effort two-phase commits across collections
+// that cannot share a MongoDB transaction, such as collections on different
+// clusters or deployments without replica-set transactions.
+//
+// Every transaction is recorded in a log collection. In the prepare phase each
+// write is applied and the affected document is marked with the transaction ID.
+// Once all writes are prepared the transaction is committed by recording the
+// decision and removing the markers. If a prepare fails, the prepared writes are
+// undone by their compensating updates. Recover finishes transactions left
+// behind by a crashed coordinator.
+//
+// Documents marked as pending are visible to other readers, so this is not
+// isolation: callers that need it should exclude documents with pending markers.
+//
+// Example:
+//
+//	coord := twophase.NewCoordinator(ops.Collection("transfers_log"))
+//	coord.Register("eu_accounts", eu.Collection("accounts"))
+//	coord.Register("us_accounts", us.Collection("accounts"))
+//
+//	_, err := coord.Execute(ctx,
+//	    twophase.Write{
+//	        Participant: "eu_accounts",
+//	        Filter:      bson.M{"_id": from, "balance": bson.M{"$gte": 100}},
+//	        Apply:       bson.M{"$inc": bson.M{"balance": -100}},
+//	        Compensate:  bson.M{"$inc": bson.M{"balance": 100}},
+//	    },
+//	    twophase.Write{
+//	        Participant: "us_accounts",
+//	        Filter:      bson.M{"_id": to},
+//	        Apply:       bson.M{"$inc": bson.M{"balance": 100}},
+//	        Compensate:  bson.M{"$inc": bson.M{"balance": -100}},
+//	    },
+//	)
+package twophase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// ErrUnknownParticipant is returned when a write names a participant that
+	// was not registered.
+	ErrUnknownParticipant = errors.New("twophase: unknown participant")
+
+	// ErrNotMatched is returned when a write's filter matches no document during
+	// prepare. The transaction is aborted.
+	ErrNotMatched = errors.New("twophase: write matched no document")
+
+	// ErrIncomplete is returned when the outcome of a transaction was decided
+	// but could not be fully applied. Recover finishes it.
+	ErrIncomplete = errors.New("twophase: transaction incomplete")
+)
+
+// State is the state of a logged transaction.
+type State string
+
+// Transaction states. Pending, Committing, and Aborting are in flight; Done and
+// Aborted are final.
+const (
+	Pending    State = "pending"
+	Committing State = "committing"
+	Done       State = "done"
+	Aborting   State = "aborting"
+	Aborted    State = "aborted"
+)
+
+// Write is one participant's part of a transaction.
+type Write struct {
+	// Participant is the name the target collection was registered under.
+	Participant string `bson:"participant"`
+
+	// Filter selects the single document to update.
+	Filter bson.M `bson:"filter"`
+
+	// Apply is the update applied in the prepare phase.
+	Apply bson.M `bson:"apply"`
+
+	// Compensate is the update that undoes Apply if the transaction aborts.
+	// Nil means the write needs no compensation.
+	Compensate bson.M `bson:"compensate,omitempty"`
+}
+
+// Record is the log entry of a transaction.
+type Record struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	State     State              `bson:"state"`
+	Writes    []Write            `bson:"writes"`
+	Error     string             `bson:"error,omitempty"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// Option configures a Coordinator.
+type Option func(*config)
+
+type config struct {
+	pendingField string
+}
+
+// WithPendingField sets the array field used to mark documents with the
+// transactions they take part in. The default is "pending_txns".
+func WithPendingField(name string) Option {
+	return func(c *config) { c.pendingField = name }
+}
+
+// Coordinator runs two-phase commits over registered participant collections.
+// It is safe for concurrent use.
+type Coordinator struct {
+	log   *mongo.Collection
+	cfg   config
+	mu    sync.RWMutex
+	colls map[string]*mongo.Collection
+}
+
+// NewCoordinator creates a Coordinator that records transactions in log.
+func NewCoordinator(log *mongo.Collection, opts ...Option) *Coordinator {
+	cfg := config{pendingField: "pending_txns"}
+	for _, fn := range opts {
+		if fn != nil {
+			fn(&cfg)
+		}
+	}
+	return &Coordinator{log: log, cfg: cfg, colls: make(map[string]*mongo.Collection)}
+}
+
+// Register makes coll available to writes under name. Names are stored in the
+// log, so they must stay stable for Recover to find the collections again.
+func (c *Coordinator) Register(name string, coll *mongo.Collection) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.colls[name] = coll
+}
+
+func (c *Coordinator) participant(name string) (*mongo.Collection, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	coll, ok := c.colls[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownParticipant, name)
+	}
+	return coll, nil
+}
+
+// Execute runs the writes as one transaction and returns its ID.
+//
+// Behavior:
+//   - Each write updates the first document its filter matches; if none matches, the
+//     transaction aborts with ErrNotMatched
+//   - If any prepare fails, prepared writes are compensated and the prepare error is returned
+//   - Once every write is prepared the transaction is committed; a failure after that
+//     point returns ErrIncomplete and leaves the commit for Recover
+func (c *Coordinator) Execute(ctx context.Context, writes ...Write) (primitive.ObjectID, error) {
+	for _, w := range writes {
+		if _, err := c.participant(w.Participant); err != nil {
+			return primitive.NilObjectID, err
+		}
+	}
+
+	now := time.Now().UTC()
+	rec := Record{ID: primitive.NewObjectID(), State: Pending, Writes: writes, CreatedAt: now, UpdatedAt: now}
+	if _, err := c.log.InsertOne(ctx, rec); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	for i, w := range writes {
+		if err := c.prepare(ctx, marker(rec.ID, i), w); err != nil {
+			err = fmt.Errorf("twophase: prepare write %d (%s): %w", i, w.Participant, err)
+			if abortErr := c.abort(ctx, rec, err.Error()); abortErr != nil {
+				return rec.ID, fmt.Errorf("%w (abort: %w)", err, abortErr)
+			}
+			return rec.ID, err
+		}
+	}
+
+	if err := c.setState(ctx, rec.ID, Committing, ""); err != nil {
+		// The decision was not recorded, so the transaction can still be aborted.
+		if abortErr := c.abort(ctx, rec, err.Error()); abortErr != nil {
+			return rec.ID, fmt.Errorf("%w: %w", ErrIncomplete, abortErr)
+		}
+		return rec.ID, err
+	}
+	if err := c.commit(ctx, rec); err != nil {
+		return rec.ID, fmt.Errorf("%w: %w", ErrIncomplete, err)
+	}
+	return rec.ID, nil
+}
+
+// Recover finishes transactions that have not been updated for longer than
+// olderThan: prepared but undecided transactions are aborted, and decided ones
+// are committed or aborted to completion. It returns the number of transactions
+// finished.
+func (c *Coordinator) Recover(ctx context.Context, olderThan time.Duration) (int, error) {
+	cutoff := time.Now().UTC().Add(-olderThan)
+	cur, err := c.log.Find(ctx, bson.M{
+		"state":      bson.M{"$in": []State{Pending, Committing, Aborting}},
+		"updated_at": bson.M{"$lt": cutoff},
+	})
+	if err != nil {
+		return 0, err
+	}
+	var recs []Record
+	if err := cur.All(ctx, &recs); err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	finished := 0
+	for _, rec := range recs {
+		var err error
+		switch rec.State {
+		case Pending:
+			err = c.abort(ctx, rec, "recovered: coordinator did not finish prepare")
+		case Committing:
+			err = c.commit(ctx, rec)
+		case Aborting:
+			err = c.abort(ctx, rec, rec.Error)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("twophase: recover %s: %w", rec.ID.Hex(), err))
+			continue
+		}
+		finished++
+	}
+	return finished, errors.Join(errs...)
+}
+
+// Get returns the log record of a transaction.
+func (c *Coordinator) Get(ctx context.Context, id primitive.ObjectID) (*Record, error) {
+	var rec Record
+	if err := c.log.FindOne(ctx, bson.M{"_id": id}).Decode(&rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// marker identifies write i of transaction id on the document it updates.
+func marker(id primitive.ObjectID, i int) string {
+	return id.Hex() + "." + strconv.Itoa(i)
+}
+
+// prepare applies w and marks the document with m. The marker makes prepare
+// idempotent and tells commit and abort which document to touch.
+func (c *Coordinator) prepare(ctx context.Context, m string, w Write) error {
+	coll, err := c.participant(w.Participant)
+	if err != nil {
+		return err
+	}
+	update, err := withOperator(w.Apply, "$addToSet", c.cfg.pendingField, m)
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"$and": []bson.M{w.Filter, {c.cfg.pendingField: bson.M{"$ne": m}}}}
+	res, err := coll.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotMatched
+	}
+	return nil
+}
+
+// commit removes the transaction markers and marks the record done.
+func (c *Coordinator) commit(ctx context.Context, rec Record) error {
+	for i, w := range rec.Writes {
+		coll, err := c.participant(w.Participant)
+		if err != nil {
+			return err
+		}
+		marked := bson.M{c.cfg.pendingField: marker(rec.ID, i)}
+		if _, err := coll.UpdateOne(ctx, marked, bson.M{"$pull": marked}); err != nil {
+			return err
+		}
+	}
+	return c.setState(ctx, rec.ID, Done, "")
+}
+
+// abort compensates every document still carrying one of the transaction's
+// markers and marks the record aborted. Documents without the marker were never prepared
+// (or were already compensated), so abort is safe to repeat.
+func (c *Coordinator) abort(ctx context.Context, rec Record, reason string) error {
+	if err := c.setState(ctx, rec.ID, Aborting, reason); err != nil {
+		return err
+	}
+	for i, w := range rec.Writes {
+		coll, err := c.participant(w.Participant)
+		if err != nil {
+			return err
+		}
+		m := marker(rec.ID, i)
+		update, err := withOperator(w.Compensate, "$pull", c.cfg.pendingField, m)
+		if err != nil {
+			return err
+		}
+		if _, err := coll.UpdateOne(ctx, bson.M{c.cfg.pendingField: m}, update); err != nil {
+			return err
+		}
+	}
+	return c.setState(ctx, rec.ID, Aborted, reason)
+}
+
+func (c *Coordinator) setState(ctx context.Context, id primitive.ObjectID, state State, reason string) error {
+	set := bson.M{"state": state, "updated_at": time.Now().UTC()}
+	if reason != "" {
+		set["error"] = reason
+	}
+	_, err := c.log.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
+
+// withOperator returns a copy of update with field: value added under op.
+func withOperator(update bson.M, op, field string, value any) (bson.M, error) {
+	out := make(bson.M, len(update)+1)
+	for k, v := range update {
+		out[k] = v
+	}
+	args := bson.M{}
+	switch existing := out[op].(type) {
+	case nil:
+	case bson.M:
+		for k, v := range existing {
+			args[k] = v
+		}
+	case bson.D:
+		for _, e := range existing {
+			args[e.Key] = e.Value
+		}
+	default:
+		return nil, fmt.Errorf("twophase: unsupported %s value of type %T", op, existing)
+	}
+	if _, ok := args[field]; ok {
+		return nil, fmt.Errorf("twophase: update must not modify %q", field)
+	}
+	args[field] = value
+	out[op] = args
+	return out, nil
+}
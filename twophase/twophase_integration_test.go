@@ -0,0 +1,161 @@
+//go:build integration
+
+package twophase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dElCIoGio/mongox/twophase"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+
+	mongodb "github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+func setupMongo(t *testing.T) *mongo.Client {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mongodb.Run(ctx, "mongo:7")
+	if err != nil {
+		t.Fatalf("start mongodb container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Terminate(ctx) })
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("get mongodb uri: %v", err)
+	}
+
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("connect mongo: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+
+	return client
+}
+
+func transfer(from, to string, amount int) []twophase.Write {
+	return []twophase.Write{
+		{
+			Participant: "eu",
+			Filter:      bson.M{"_id": from, "balance": bson.M{"$gte": amount}},
+			Apply:       bson.M{"$inc": bson.M{"balance": -amount}},
+			Compensate:  bson.M{"$inc": bson.M{"balance": amount}},
+		},
+		{
+			Participant: "us",
+			Filter:      bson.M{"_id": to},
+			Apply:       bson.M{"$inc": bson.M{"balance": amount}},
+			Compensate:  bson.M{"$inc": bson.M{"balance": -amount}},
+		},
+	}
+}
+
+func balance(t *testing.T, coll *mongo.Collection, id string) int {
+	t.Helper()
+	var doc struct {
+		Balance int      `bson:"balance"`
+		Pending []string `bson:"pending_txns"`
+	}
+	if err := coll.FindOne(context.Background(), bson.M{"_id": id}).Decode(&doc); err != nil {
+		t.Fatalf("load %s: %v", id, err)
+	}
+	if len(doc.Pending) != 0 {
+		t.Fatalf("expected no pending markers on %s, got %v", id, doc.Pending)
+	}
+	return doc.Balance
+}
+
+func TestExecute_CommitsAndCompensates(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	eu := client.Database("eu").Collection("accounts")
+	us := client.Database("us").Collection("accounts")
+	if _, err := eu.InsertOne(ctx, bson.M{"_id": "alice", "balance": 150}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := us.InsertOne(ctx, bson.M{"_id": "bob", "balance": 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	coord := twophase.NewCoordinator(client.Database("ops").Collection("txlog"))
+	coord.Register("eu", eu)
+	coord.Register("us", us)
+
+	id, err := coord.Execute(ctx, transfer("alice", "bob", 100)...)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if rec, err := coord.Get(ctx, id); err != nil || rec.State != twophase.Done {
+		t.Fatalf("expected done record, got %+v, %v", rec, err)
+	}
+	if a, b := balance(t, eu, "alice"), balance(t, us, "bob"); a != 50 || b != 100 {
+		t.Fatalf("unexpected balances after commit: alice=%d bob=%d", a, b)
+	}
+
+	// The second leg targets a missing account, so the first leg is compensated.
+	id, err = coord.Execute(ctx, transfer("alice", "carol", 50)...)
+	if !errors.Is(err, twophase.ErrNotMatched) {
+		t.Fatalf("expected ErrNotMatched, got %v", err)
+	}
+	if rec, err := coord.Get(ctx, id); err != nil || rec.State != twophase.Aborted {
+		t.Fatalf("expected aborted record, got %+v, %v", rec, err)
+	}
+	if a := balance(t, eu, "alice"); a != 50 {
+		t.Fatalf("expected alice's debit to be compensated, balance=%d", a)
+	}
+}
+
+func TestRecover_FinishesInterruptedTransactions(t *testing.T) {
+	client := setupMongo(t)
+
+	ctx := context.Background()
+	eu := client.Database("eu").Collection("accounts")
+	us := client.Database("us").Collection("accounts")
+	log := client.Database("ops").Collection("txlog")
+
+	coord := twophase.NewCoordinator(log)
+	coord.Register("eu", eu)
+	coord.Register("us", us)
+
+	// Simulate a coordinator that prepared the first write of one transaction
+	// and then crashed.
+	if _, err := eu.InsertOne(ctx, bson.M{"_id": "alice", "balance": 100}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := us.InsertOne(ctx, bson.M{"_id": "bob", "balance": 0}); err != nil {
+		t.Fatal(err)
+	}
+	stale := time.Now().UTC().Add(-time.Hour)
+	rec := twophase.Record{ID: primitive.NewObjectID(), State: twophase.Pending, Writes: transfer("alice", "bob", 40), CreatedAt: stale, UpdatedAt: stale}
+	if _, err := log.InsertOne(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := eu.UpdateOne(ctx, bson.M{"_id": "alice"}, bson.M{
+		"$inc":  bson.M{"balance": -40},
+		"$push": bson.M{"pending_txns": rec.ID.Hex() + ".0"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := coord.Recover(ctx, time.Minute)
+	if err != nil || n != 1 {
+		t.Fatalf("Recover: n=%d err=%v", n, err)
+	}
+	if got, _ := coord.Get(ctx, rec.ID); got.State != twophase.Aborted {
+		t.Fatalf("expected pending transaction to be aborted, got %s", got.State)
+	}
+	if a, b := balance(t, eu, "alice"), balance(t, us, "bob"); a != 100 || b != 0 {
+		t.Fatalf("expected the prepared debit to be compensated: alice=%d bob=%d", a, b)
+	}
+}
@@ -0,0 +1,39 @@
+package twophase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dElCIoGio/mongox/twophase"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopt "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func lazyClient(t *testing.T) *mongo.Client {
+	t.Helper()
+	ctx := context.Background()
+	// Connect is lazy; these tests never reach the server.
+	client, err := mongo.Connect(ctx, mopt.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(ctx) })
+	return client
+}
+
+func TestExecute_UnknownParticipant(t *testing.T) {
+	db := lazyClient(t).Database("testdb")
+	coord := twophase.NewCoordinator(db.Collection("txlog"))
+	coord.Register("accounts", db.Collection("accounts"))
+
+	_, err := coord.Execute(context.Background(),
+		twophase.Write{Participant: "accounts", Filter: bson.M{"_id": 1}, Apply: bson.M{"$inc": bson.M{"n": 1}}},
+		twophase.Write{Participant: "ledger", Filter: bson.M{"_id": 1}, Apply: bson.M{"$inc": bson.M{"n": 1}}},
+	)
+	if !errors.Is(err, twophase.ErrUnknownParticipant) {
+		t.Fatalf("expected ErrUnknownParticipant, got %v", err)
+	}
+}